@@ -0,0 +1,421 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"github.com/feimaomiao/stalka/client"
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/jobs"
+)
+
+const (
+	// HeaderActor names the person behind a request in the audit log.
+	HeaderActor = "X-Admin-Actor"
+	// DefaultActor is the actor of requests without HeaderActor.
+	DefaultActor = "admin"
+	// MaxActorLength matches ADMIN_AUDIT.actor.
+	MaxActorLength = 64
+	// DefaultListSize is the number of queue and audit entries returned when limit is unset.
+	DefaultListSize = 50
+	// MaxListSize caps the limit a client may ask for.
+	MaxListSize = 200
+	// MinTokenLength keeps admin_token from being guessable.
+	MinTokenLength = 32
+)
+
+// Actions of ADMIN_AUDIT.
+const (
+	ActionFetch  = "fetch"
+	ActionRun    = "run"
+	ActionPause  = "pause"
+	ActionResume = "resume"
+)
+
+// Outcomes of ADMIN_AUDIT.
+const (
+	OutcomeOK     = "ok"
+	OutcomeQueued = "queued"
+	OutcomeFailed = "failed"
+)
+
+// Server is the admin API of the syncing process: it fetches single entities
+// on demand, runs, pauses and resumes jobs and shows the request budget and
+// the retry queue. Every request needs the bearer token, and every action is
+// recorded in ADMIN_AUDIT.
+type Server struct {
+	// Queries reads the retry queue and writes the audit log.
+	Queries dbtypes.Querier
+	// Token is the bearer token every request must carry.
+	Token string
+	Jobs  *jobs.Scheduler
+	// Fetch fetches a single entity from PandaScore and writes it.
+	Fetch  func(id int, flag client.GetChoice) error
+	Budget *client.Budget
+	Logger *zap.SugaredLogger
+}
+
+// Job is a job as returned by the admin API.
+type Job struct {
+	Name            string     `json:"name"`
+	IntervalSeconds int64      `json:"interval_seconds"`
+	Paused          bool       `json:"paused"`
+	Running         bool       `json:"running"`
+	Queued          bool       `json:"queued"`
+	LastStartedAt   *time.Time `json:"last_started_at"`
+	LastFinishedAt  *time.Time `json:"last_finished_at"`
+	LastError       *string    `json:"last_error"`
+	NextRunAt       *time.Time `json:"next_run_at"`
+}
+
+// Budget is the PandaScore request budget as returned by the admin API.
+type Budget struct {
+	Requests   int        `json:"requests"`
+	Remaining  *int       `json:"remaining"`
+	ObservedAt *time.Time `json:"observed_at"`
+}
+
+// Queue is the retry queue as returned by the admin API.
+type Queue struct {
+	Total int64 `json:"total"`
+	// Exhausted counts the entries that used up their attempts and are only
+	// kept for inspection.
+	Exhausted int64        `json:"exhausted"`
+	Entries   []QueueEntry `json:"entries"`
+}
+
+// QueueEntry is an entity waiting for another attempt.
+type QueueEntry struct {
+	EntityType    string     `json:"entity_type"`
+	EntityID      int32      `json:"entity_id"`
+	Attempts      int32      `json:"attempts"`
+	LastError     *string    `json:"last_error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastAttemptAt *time.Time `json:"last_attempt_at"`
+}
+
+// AuditEntry is a recorded action.
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	Actor      string    `json:"actor"`
+	RemoteAddr string    `json:"remote_addr"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target"`
+	Outcome    string    `json:"outcome"`
+	Error      *string   `json:"error"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Result is the body of every action.
+type Result struct {
+	Action  string  `json:"action"`
+	Target  string  `json:"target"`
+	Outcome string  `json:"outcome"`
+	Error   *string `json:"error,omitempty"`
+}
+
+// errorBody is the body of every error response.
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// LoadToken reads admin_token.
+// @param getenv - looks up an environment variable, usually os.Getenv.
+// @returns the token and an error if it is shorter than MinTokenLength.
+func LoadToken(getenv func(string) string) (string, error) {
+	token := getenv("admin_token")
+	if len(token) < MinTokenLength {
+		return "", fmt.Errorf("admin_token must be at least %d characters long", MinTokenLength)
+	}
+	return token, nil
+}
+
+// Handler returns the routes of the admin API.
+// @returns the handler serving every endpoint under /admin/v1, behind the token.
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/v1/jobs", server.listJobs)
+	mux.HandleFunc("POST /admin/v1/jobs/{name}/run", server.runJob)
+	mux.HandleFunc("POST /admin/v1/jobs/{name}/pause", server.pauseJob)
+	mux.HandleFunc("POST /admin/v1/jobs/{name}/resume", server.resumeJob)
+	mux.HandleFunc("POST /admin/v1/entities/{entityType}/{id}/fetch", server.fetchEntity)
+	mux.HandleFunc("GET /admin/v1/budget", server.budget)
+	mux.HandleFunc("GET /admin/v1/queue", server.queue)
+	mux.HandleFunc("GET /admin/v1/audit", server.audit)
+	return server.authenticate(mux)
+}
+
+// authenticate rejects requests without the bearer token.
+func (server *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || server.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(server.Token)) != 1 {
+			server.Logger.Warnf("Rejected unauthenticated admin request %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="stalka admin"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listJobs serves the state of every job.
+func (server *Server) listJobs(w http.ResponseWriter, _ *http.Request) {
+	statuses := server.Jobs.Status()
+	jobList := make([]Job, 0, len(statuses))
+	for _, status := range statuses {
+		jobList = append(jobList, Job{
+			Name:            status.Name,
+			IntervalSeconds: int64(status.Interval / time.Second),
+			Paused:          status.Paused,
+			Running:         status.Running,
+			Queued:          status.Queued,
+			LastStartedAt:   status.LastStartedAt,
+			LastFinishedAt:  status.LastFinishedAt,
+			LastError:       status.LastError,
+			NextRunAt:       status.NextRunAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string][]Job{"jobs": jobList})
+}
+
+// runJob queues an immediate run of a job.
+func (server *Server) runJob(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	err := server.Jobs.Trigger(name)
+	server.respond(w, r, ActionRun, "jobs/"+name, OutcomeQueued, err)
+}
+
+// pauseJob makes a job skip its ticks.
+func (server *Server) pauseJob(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	err := server.Jobs.Pause(name)
+	server.respond(w, r, ActionPause, "jobs/"+name, OutcomeOK, err)
+}
+
+// resumeJob undoes pauseJob.
+func (server *Server) resumeJob(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	err := server.Jobs.Resume(name)
+	server.respond(w, r, ActionResume, "jobs/"+name, OutcomeOK, err)
+}
+
+// fetchEntity fetches a single entity from PandaScore and writes it, together
+// with the dependencies it is missing. The request returns once it is written.
+func (server *Server) fetchEntity(w http.ResponseWriter, r *http.Request) {
+	entityType := r.PathValue("entityType")
+	target := entityType + "/" + r.PathValue("id")
+	flag, err := client.ParseEntityType(entityType)
+	if err != nil {
+		server.respond(w, r, ActionFetch, target, OutcomeOK, errBadRequest{err})
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil || id < 1 {
+		server.respond(w, r, ActionFetch, target, OutcomeOK, errBadRequest{errors.New("id must be a positive 32-bit integer")})
+		return
+	}
+	err = server.Fetch(int(id), flag)
+	server.respond(w, r, ActionFetch, target, OutcomeOK, err)
+}
+
+// budget serves the PandaScore request budget.
+func (server *Server) budget(w http.ResponseWriter, _ *http.Request) {
+	status := server.Budget.Status()
+	writeJSON(w, http.StatusOK, Budget{
+		Requests:   status.Requests,
+		Remaining:  status.Remaining,
+		ObservedAt: status.ObservedAt,
+	})
+}
+
+// queue serves the oldest entries of the retry queue.
+func (server *Server) queue(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	counts, err := server.Queries.CountResolveRetries(r.Context(), client.MaxRetryAttempts)
+	if err != nil {
+		server.internalError(w, "retry queue", err)
+		return
+	}
+	rows, err := server.Queries.GetResolveRetries(r.Context(), limit)
+	if err != nil {
+		server.internalError(w, "retry queue", err)
+		return
+	}
+	entries := make([]QueueEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, QueueEntry{
+			EntityType:    row.EntityType,
+			EntityID:      row.EntityID,
+			Attempts:      row.Attempts,
+			LastError:     text(row.LastError),
+			CreatedAt:     row.CreatedAt.Time.UTC(),
+			LastAttemptAt: timestamp(row.LastAttemptAt),
+		})
+	}
+	writeJSON(w, http.StatusOK, Queue{Total: counts.Total, Exhausted: counts.Exhausted, Entries: entries})
+}
+
+// audit serves the most recent actions, newest first.
+func (server *Server) audit(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rows, err := server.Queries.GetAdminAudit(r.Context(), limit)
+	if err != nil {
+		server.internalError(w, "audit log", err)
+		return
+	}
+	entries := make([]AuditEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, AuditEntry{
+			ID:         row.ID,
+			Actor:      row.Actor,
+			RemoteAddr: row.RemoteAddr,
+			Action:     row.Action,
+			Target:     row.Target,
+			Outcome:    row.Outcome,
+			Error:      text(row.Error),
+			CreatedAt:  row.CreatedAt.Time.UTC(),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string][]AuditEntry{"entries": entries})
+}
+
+// errBadRequest marks an action that was rejected for its parameters.
+type errBadRequest struct {
+	err error
+}
+
+func (e errBadRequest) Error() string {
+	return e.err.Error()
+}
+
+// respond records an action in the audit log and answers it.
+// @param action - the action, one of the Action constants.
+// @param target - what the action was taken on, e.g. "jobs/matches".
+// @param outcome - the outcome when err is nil.
+// @param err - the error of the action, nil if it succeeded.
+func (server *Server) respond(w http.ResponseWriter, r *http.Request, action, target, outcome string, err error) {
+	status := http.StatusOK
+	if outcome == OutcomeQueued {
+		status = http.StatusAccepted
+	}
+	var badRequest errBadRequest
+	switch {
+	case err == nil:
+	case errors.As(err, &badRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, jobs.ErrUnknownJob), errors.Is(err, client.ErrNotFound):
+		status = http.StatusNotFound
+	default:
+		status = http.StatusBadGateway
+	}
+	result := Result{Action: action, Target: target, Outcome: outcome, Error: nil}
+	if err != nil {
+		message := err.Error()
+		result.Outcome = OutcomeFailed
+		result.Error = &message
+	}
+	server.record(r, result)
+	writeJSON(w, status, result)
+}
+
+// record writes an action to ADMIN_AUDIT. The action already happened, so a
+// failed write is logged with every detail of the entry instead.
+func (server *Server) record(r *http.Request, result Result) {
+	actor := strings.TrimSpace(r.Header.Get(HeaderActor))
+	if actor == "" {
+		actor = DefaultActor
+	}
+	if len(actor) > MaxActorLength {
+		actor = actor[:MaxActorLength]
+	}
+	var auditErr pgtype.Text
+	if result.Error != nil {
+		auditErr = pgtype.Text{String: *result.Error, Valid: true}
+	}
+	server.Logger.Infof("Admin %s from %s: %s %s, %s", actor, r.RemoteAddr, result.Action, result.Target, result.Outcome)
+	// The audit entry is written even when the client hangs up early.
+	err := server.Queries.InsertAdminAudit(context.WithoutCancel(r.Context()), dbtypes.InsertAdminAuditParams{
+		Actor:      actor,
+		RemoteAddr: r.RemoteAddr,
+		Action:     result.Action,
+		Target:     result.Target,
+		Outcome:    result.Outcome,
+		Error:      auditErr,
+	})
+	if err != nil {
+		server.Logger.Errorf("Error recording admin %s %s by %s from %s (%s): %v",
+			result.Action, result.Target, actor, r.RemoteAddr, result.Outcome, err)
+	}
+}
+
+// internalError logs a failed query and answers with a generic 500.
+func (server *Server) internalError(w http.ResponseWriter, what string, err error) {
+	server.Logger.Errorf("Error reading %s: %v", what, err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+// parseLimit reads limit from the query string.
+// @returns the limit, DefaultListSize when unset, and an error if it is out of range.
+func parseLimit(r *http.Request) (int32, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return DefaultListSize, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || limit < 1 || limit > MaxListSize {
+		return 0, fmt.Errorf("limit must be an integer between 1 and %d", MaxListSize)
+	}
+	return int32(limit), nil
+}
+
+// writeJSON writes v as the JSON body of a response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// writeError writes an error response.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorBody{Error: message})
+}
+
+func text(value pgtype.Text) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func timestamp(value pgtype.Timestamptz) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	utc := value.Time.UTC()
+	return &utc
+}
@@ -0,0 +1,297 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/feimaomiao/stalka/client"
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/jobs"
+)
+
+const testToken = "0123456789abcdef0123456789abcdef"
+
+// fetched records the calls of the Fetch stub.
+type fetched struct {
+	id   int
+	flag client.GetChoice
+}
+
+func newTestServer(t *testing.T, fetchErr error) (http.Handler, pgxmock.PgxPoolIface, *[]fetched) {
+	t.Helper()
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	t.Cleanup(mockDB.Close)
+	logger := zaptest.NewLogger(t).Sugar()
+	scheduler := jobs.NewScheduler(logger)
+	scheduler.Add(jobs.Job{Name: "matches", Interval: time.Hour, Run: func() error { return nil }})
+	var calls []fetched
+	server := &Server{
+		Queries: dbtypes.New(mockDB),
+		Token:   testToken,
+		Jobs:    scheduler,
+		Fetch: func(id int, flag client.GetChoice) error {
+			calls = append(calls, fetched{id: id, flag: flag})
+			return fetchErr
+		},
+		Budget: client.NewBudget(),
+		Logger: logger,
+	}
+	return server.Handler(), mockDB, &calls
+}
+
+func do(handler http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// expectAudit registers the ADMIN_AUDIT row of an action.
+func expectAudit(mockDB pgxmock.PgxPoolIface, actor, action, target, outcome string, auditErr pgtype.Text) {
+	mockDB.ExpectExec("INSERT INTO admin_audit").
+		WithArgs(actor, pgxmock.AnyArg(), action, target, outcome, auditErr).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+}
+
+func decode[T any](t *testing.T, recorder *httptest.ResponseRecorder) T {
+	t.Helper()
+	var body T
+	st.Assert(t, json.Unmarshal(recorder.Body.Bytes(), &body), nil)
+	return body
+}
+
+func TestAuthentication(t *testing.T) {
+	handler, mockDB, _ := newTestServer(t, nil)
+	for name, header := range map[string]string{
+		"Missing token": "",
+		"Wrong token":   "Bearer " + strings.Repeat("x", len(testToken)),
+		"Wrong scheme":  "Basic " + testToken,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/v1/jobs/matches/run", nil)
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			st.Expect(t, recorder.Code, http.StatusUnauthorized)
+			st.Expect(t, recorder.Header().Get("WWW-Authenticate"), `Bearer realm="stalka admin"`)
+		})
+	}
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestLoadToken(t *testing.T) {
+	token, err := LoadToken(func(string) string { return testToken })
+	st.Expect(t, err, nil)
+	st.Expect(t, token, testToken)
+
+	_, err = LoadToken(func(string) string { return "short" })
+	st.Reject(t, err, nil)
+}
+
+func TestJobs(t *testing.T) {
+	handler, mockDB, _ := newTestServer(t, nil)
+
+	t.Run("Pausing is audited with the actor", func(t *testing.T) {
+		expectAudit(mockDB, "mao", ActionPause, "jobs/matches", OutcomeOK, pgtype.Text{})
+		recorder := do(handler, http.MethodPost, "/admin/v1/jobs/matches/pause", http.Header{HeaderActor: {"mao"}})
+		st.Expect(t, recorder.Code, http.StatusOK)
+		st.Expect(t, decode[Result](t, recorder), Result{
+			Action: ActionPause, Target: "jobs/matches", Outcome: OutcomeOK, Error: nil,
+		})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+
+		jobList := decode[map[string][]Job](t, do(handler, http.MethodGet, "/admin/v1/jobs", nil))["jobs"]
+		st.Assert(t, len(jobList), 1)
+		st.Expect(t, jobList[0].Name, "matches")
+		st.Expect(t, jobList[0].IntervalSeconds, int64(3600))
+		st.Expect(t, jobList[0].Paused, true)
+	})
+
+	t.Run("Resuming", func(t *testing.T) {
+		expectAudit(mockDB, DefaultActor, ActionResume, "jobs/matches", OutcomeOK, pgtype.Text{})
+		st.Expect(t, do(handler, http.MethodPost, "/admin/v1/jobs/matches/resume", nil).Code, http.StatusOK)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Runs are queued", func(t *testing.T) {
+		expectAudit(mockDB, DefaultActor, ActionRun, "jobs/matches", OutcomeQueued, pgtype.Text{})
+		st.Expect(t, do(handler, http.MethodPost, "/admin/v1/jobs/matches/run", nil).Code, http.StatusAccepted)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - unknown jobs are audited too", func(t *testing.T) {
+		expectAudit(mockDB, DefaultActor, ActionRun, "jobs/bogus", OutcomeFailed,
+			pgtype.Text{String: jobs.ErrUnknownJob.Error(), Valid: true})
+		recorder := do(handler, http.MethodPost, "/admin/v1/jobs/bogus/run", nil)
+		st.Expect(t, recorder.Code, http.StatusNotFound)
+		st.Expect(t, decode[Result](t, recorder).Outcome, OutcomeFailed)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("A failed audit write does not undo the action", func(t *testing.T) {
+		mockDB.ExpectExec("INSERT INTO admin_audit").
+			WithArgs(anyArgs(6)...).
+			WillReturnError(errors.New("connection refused"))
+		st.Expect(t, do(handler, http.MethodPost, "/admin/v1/jobs/matches/pause", nil).Code, http.StatusOK)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestFetchEntity(t *testing.T) {
+	t.Run("The entity is fetched and written", func(t *testing.T) {
+		handler, mockDB, calls := newTestServer(t, nil)
+		expectAudit(mockDB, DefaultActor, ActionFetch, "matches/1234", OutcomeOK, pgtype.Text{})
+		st.Expect(t, do(handler, http.MethodPost, "/admin/v1/entities/matches/1234/fetch", nil).Code, http.StatusOK)
+		st.Expect(t, *calls, []fetched{{id: 1234, flag: client.FlagMatch}})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Entities PandaScore does not know are not found", func(t *testing.T) {
+		handler, mockDB, _ := newTestServer(t, client.ErrNotFound)
+		expectAudit(mockDB, DefaultActor, ActionFetch, "teams/7", OutcomeFailed,
+			pgtype.Text{String: client.ErrNotFound.Error(), Valid: true})
+		st.Expect(t, do(handler, http.MethodPost, "/admin/v1/entities/teams/7/fetch", nil).Code, http.StatusNotFound)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("A dependency gone upstream is not a missing entity", func(t *testing.T) {
+		// what GetOne returns for a match whose tournament PandaScore dropped
+		dependencyErr := &client.DependencyError{
+			Flag: client.FlagTournament,
+			ID:   9,
+			Err:  fmt.Errorf("%w: tournaments 9", client.ErrNotFound),
+		}
+		handler, mockDB, _ := newTestServer(t, dependencyErr)
+		expectAudit(mockDB, DefaultActor, ActionFetch, "matches/1234", OutcomeFailed,
+			pgtype.Text{String: dependencyErr.Error(), Valid: true})
+		st.Expect(t, do(handler, http.MethodPost, "/admin/v1/entities/matches/1234/fetch", nil).Code,
+			http.StatusBadGateway)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Upstream failures are bad gateways", func(t *testing.T) {
+		handler, mockDB, _ := newTestServer(t, errors.New("status 503"))
+		expectAudit(mockDB, DefaultActor, ActionFetch, "teams/7", OutcomeFailed,
+			pgtype.Text{String: "status 503", Valid: true})
+		st.Expect(t, do(handler, http.MethodPost, "/admin/v1/entities/teams/7/fetch", nil).Code, http.StatusBadGateway)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	for name, target := range map[string]string{
+		"Error - unknown entity type": "/admin/v1/entities/bogus/1/fetch",
+		"Error - invalid ID":          "/admin/v1/entities/matches/0/fetch",
+		"Error - ID out of range":     "/admin/v1/entities/matches/2147483648/fetch",
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler, mockDB, calls := newTestServer(t, nil)
+			mockDB.ExpectExec("INSERT INTO admin_audit").
+				WithArgs(DefaultActor, pgxmock.AnyArg(), ActionFetch, pgxmock.AnyArg(), OutcomeFailed, pgxmock.AnyArg()).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			st.Expect(t, do(handler, http.MethodPost, target, nil).Code, http.StatusBadRequest)
+			st.Expect(t, len(*calls), 0)
+			st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	handler, _, _ := newTestServer(t, nil)
+	budget := decode[Budget](t, do(handler, http.MethodGet, "/admin/v1/budget", nil))
+	st.Expect(t, budget, Budget{Requests: 0, Remaining: nil, ObservedAt: nil})
+}
+
+func TestQueue(t *testing.T) {
+	handler, mockDB, _ := newTestServer(t, nil)
+	created := time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+
+	t.Run("Success", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT COUNT").
+			WithArgs(int32(client.MaxRetryAttempts)).
+			WillReturnRows(pgxmock.NewRows([]string{"total", "exhausted"}).AddRow(int64(2), int64(1)))
+		mockDB.ExpectQuery("SELECT .+ FROM resolve_retries").
+			WithArgs(int32(10)).
+			WillReturnRows(pgxmock.NewRows([]string{
+				"entity_type", "entity_id", "attempts", "last_error", "created_at", "last_attempt_at",
+			}).AddRow(
+				"tournaments", int32(5), int32(1), pgtype.Text{String: "status 500", Valid: true},
+				pgtype.Timestamptz{Time: created, Valid: true, InfinityModifier: 0}, pgtype.Timestamptz{},
+			))
+
+		recorder := do(handler, http.MethodGet, "/admin/v1/queue?limit=10", nil)
+		st.Expect(t, recorder.Code, http.StatusOK)
+		queue := decode[Queue](t, recorder)
+		st.Expect(t, queue.Total, int64(2))
+		st.Expect(t, queue.Exhausted, int64(1))
+		st.Assert(t, len(queue.Entries), 1)
+		st.Expect(t, queue.Entries[0].EntityType, "tournaments")
+		st.Expect(t, *queue.Entries[0].LastError, "status 500")
+		st.Expect(t, queue.Entries[0].CreatedAt, created)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - invalid limit", func(t *testing.T) {
+		st.Expect(t, do(handler, http.MethodGet, "/admin/v1/queue?limit=1000", nil).Code, http.StatusBadRequest)
+	})
+
+	t.Run("Error - the queue cannot be read", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT COUNT").
+			WithArgs(int32(client.MaxRetryAttempts)).
+			WillReturnError(errors.New("connection refused"))
+		st.Expect(t, do(handler, http.MethodGet, "/admin/v1/queue", nil).Code, http.StatusInternalServerError)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestAudit(t *testing.T) {
+	handler, mockDB, _ := newTestServer(t, nil)
+	created := time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+	mockDB.ExpectQuery("SELECT .+ FROM admin_audit").
+		WithArgs(int32(DefaultListSize)).
+		WillReturnRows(pgxmock.NewRows([]string{
+			"id", "actor", "remote_addr", "action", "target", "outcome", "error", "created_at",
+		}).AddRow(
+			int64(3), "mao", "10.0.0.2:51234", ActionFetch, "matches/1234", OutcomeOK, pgtype.Text{},
+			pgtype.Timestamptz{Time: created, Valid: true, InfinityModifier: 0},
+		))
+
+	recorder := do(handler, http.MethodGet, "/admin/v1/audit", nil)
+	st.Expect(t, recorder.Code, http.StatusOK)
+	entries := decode[map[string][]AuditEntry](t, recorder)["entries"]
+	st.Expect(t, entries, []AuditEntry{{
+		ID:         3,
+		Actor:      "mao",
+		RemoteAddr: "10.0.0.2:51234",
+		Action:     ActionFetch,
+		Target:     "matches/1234",
+		Outcome:    OutcomeOK,
+		Error:      nil,
+		CreatedAt:  created,
+	}})
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func anyArgs(n int) []any {
+	args := make([]any, n)
+	for i := range args {
+		args[i] = pgxmock.AnyArg()
+	}
+	return args
+}
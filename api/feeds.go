@@ -0,0 +1,190 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/ics"
+	"github.com/feimaomiao/stalka/pandatypes"
+	"github.com/feimaomiao/stalka/shortlink"
+)
+
+const (
+	// FeedHistory is how far back a calendar feed lists matches.
+	FeedHistory = 30 * 24 * time.Hour
+	// FeedMaxEvents caps the events of a calendar feed.
+	FeedMaxEvents = 1000
+	// GameDuration is the expected length of one game of a match; an unfinished
+	// match lasts GameDuration per game of its series in the calendar.
+	GameDuration = time.Hour
+)
+
+// feedScope selects the matches of a feed; a match is listed when any of the
+// IDs matches.
+type feedScope struct {
+	games   []int32
+	leagues []int32
+	teams   []int32
+}
+
+// calendarName names the calendar of the matches of a feed.
+type calendarName func(rows []dbtypes.GetCalendarMatchesRow) string
+
+// gameFeed serves the calendar of every match of a game.
+func (server *Server) gameFeed(w http.ResponseWriter, r *http.Request) {
+	id, ok := feedID(w, r)
+	if !ok {
+		return
+	}
+	server.writeFeed(w, r, feedScope{games: []int32{id}, leagues: []int32{}, teams: []int32{}},
+		func(rows []dbtypes.GetCalendarMatchesRow) string { return rows[0].GameName })
+}
+
+// leagueFeed serves the calendar of every match of a league.
+func (server *Server) leagueFeed(w http.ResponseWriter, r *http.Request) {
+	id, ok := feedID(w, r)
+	if !ok {
+		return
+	}
+	server.writeFeed(w, r, feedScope{games: []int32{}, leagues: []int32{id}, teams: []int32{}},
+		func(rows []dbtypes.GetCalendarMatchesRow) string { return rows[0].LeagueName })
+}
+
+// teamFeed serves the calendar of every match a team plays.
+func (server *Server) teamFeed(w http.ResponseWriter, r *http.Request) {
+	id, ok := feedID(w, r)
+	if !ok {
+		return
+	}
+	server.writeFeed(w, r, feedScope{games: []int32{}, leagues: []int32{}, teams: []int32{id}},
+		func(rows []dbtypes.GetCalendarMatchesRow) string {
+			for _, row := range rows {
+				if row.Team1ID == id && row.Team1Name.Valid {
+					return row.Team1Name.String
+				}
+				if row.Team2ID == id && row.Team2Name.Valid {
+					return row.Team2Name.String
+				}
+			}
+			return ""
+		})
+}
+
+// selectionFeed serves the calendar of a selection saved in URL_MAPPINGS. A
+// calendar app polling the feed counts as an access, so subscribed selections
+// are not expired by the retention job.
+func (server *Server) selectionFeed(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimSuffix(r.PathValue("feed"), ".ics")
+	items, ok := server.resolveSelection(w, r, key)
+	if !ok {
+		return
+	}
+	scope := feedScope{games: []int32{}, leagues: []int32{}, teams: []int32{}}
+	for _, item := range items {
+		switch item.Type {
+		case shortlink.TypeGame:
+			scope.games = append(scope.games, item.ID)
+		case shortlink.TypeLeague:
+			scope.leagues = append(scope.leagues, item.ID)
+		case shortlink.TypeTeam:
+			scope.teams = append(scope.teams, item.ID)
+		default:
+			server.Logger.Warnf("Ignoring %q entry of selection %s", item.Type, key)
+		}
+	}
+	server.writeFeed(w, r, scope, func([]dbtypes.GetCalendarMatchesRow) string { return "Esports matches" })
+}
+
+// writeFeed renders the matches of a scope as an ICS feed.
+// @param w - the response writer.
+// @param r - the request.
+// @param scope - the matches to list.
+// @param name - names the calendar, called with at least one row.
+func (server *Server) writeFeed(w http.ResponseWriter, r *http.Request, scope feedScope, name calendarName) {
+	rows, err := server.Queries.GetCalendarMatches(r.Context(), dbtypes.GetCalendarMatchesParams{
+		Since:     pgtype.Timestamptz{Time: time.Now().Add(-FeedHistory), InfinityModifier: pgtype.Finite, Valid: true},
+		GameIds:   scope.games,
+		LeagueIds: scope.leagues,
+		TeamIds:   scope.teams,
+		MaxRows:   FeedMaxEvents,
+	})
+	if err != nil {
+		server.internalError(w, "calendar", err)
+		return
+	}
+	calendar := ics.Calendar{Name: "", Events: make([]ics.Event, 0, len(rows))}
+	if len(rows) > 0 {
+		calendar.Name = name(rows)
+	}
+	for _, row := range rows {
+		calendar.Events = append(calendar.Events, eventFromRow(row))
+	}
+	writeTagged(w, r, "text/calendar; charset=utf-8", calendar.Encode())
+}
+
+// eventFromRow converts a match into a calendar event. The event starts at
+// begin_at once the match began and at expected_start_time before, and ends at
+// end_at once the match finished. Its sequence grows with every change of its
+// start, end or status: the query counts the recorded reschedules, status and
+// length changes, and the switches to begin_at and end_at add one each.
+func eventFromRow(row dbtypes.GetCalendarMatchesRow) ics.Event {
+	sequence := row.Sequence
+	start := row.ExpectedStartTime.Time
+	if row.BeginAt.Valid {
+		start = row.BeginAt.Time
+		sequence++
+	}
+	end := start.Add(time.Duration(max(row.AmountOfGames, 1)) * GameDuration)
+	if row.EndAt.Valid {
+		sequence++
+		if row.EndAt.Time.After(start) {
+			end = row.EndAt.Time
+		}
+	}
+	// modified_at is unset for matches PandaScore never versioned. The start
+	// time keeps the stamp, and with it the ETag of the feed, stable.
+	stamp := start
+	if row.ModifiedAt.Valid {
+		stamp = row.ModifiedAt.Time
+	}
+	summary := row.Name
+	if row.Team1Name.Valid && row.Team2Name.Valid {
+		summary = row.Team1Name.String + " vs " + row.Team2Name.String
+	}
+	description := []string{row.LeagueName + " - " + row.TournamentName}
+	if row.AmountOfGames > 1 {
+		description = append(description, "Best of "+strconv.Itoa(int(row.AmountOfGames)))
+	}
+	if row.StreamURL.Valid {
+		description = append(description, "Stream: "+row.StreamURL.String)
+	}
+	return ics.Event{
+		UID:         fmt.Sprintf("match-%d@stalka", row.ID),
+		Sequence:    sequence,
+		Stamp:       stamp,
+		Start:       start,
+		End:         end,
+		Summary:     summary + " (" + row.LeagueName + ")",
+		Description: strings.Join(description, "\n"),
+		URL:         row.StreamURL.String,
+		Cancelled:   row.Status.Valid && row.Status.String == pandatypes.MatchStatusCanceled,
+	}
+}
+
+// feedID reads the ID of a game, league or team feed, with or without .ics,
+// and answers 400 itself when it is invalid.
+// @returns the ID and whether the request may proceed.
+func feedID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	id, err := strconv.ParseInt(strings.TrimSuffix(r.PathValue("feed"), ".ics"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "feed ID must be an integer")
+		return 0, false
+	}
+	return int32(id), true
+}
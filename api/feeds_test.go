@@ -0,0 +1,154 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/pandatypes"
+)
+
+var calendarColumns = []string{
+	"id", "name", "status", "expected_start_time", "begin_at", "end_at", "amount_of_games", "stream_url", "modified_at",
+	"team1_id", "team1_name", "team2_id", "team2_name", "game_name", "league_name", "tournament_name", "sequence",
+}
+
+// calendarRow is a scheduled best of three between T1 and Gen.G.
+func calendarRow(start time.Time) dbtypes.GetCalendarMatchesRow {
+	return dbtypes.GetCalendarMatchesRow{
+		ID:                10,
+		Name:              "Match 1",
+		ExpectedStartTime: pgtype.Timestamptz{Time: start, Valid: true, InfinityModifier: pgtype.Finite},
+		AmountOfGames:     3,
+		Team1ID:           1,
+		Team1Name:         pgtype.Text{String: "T1", Valid: true},
+		Team2ID:           2,
+		Team2Name:         pgtype.Text{String: "Gen.G", Valid: true},
+		GameName:          "LoL",
+		LeagueName:        "LCK",
+		TournamentName:    "Playoffs",
+	}
+}
+
+func TestFeeds(t *testing.T) {
+	handler, mockDB := newTestServer(t)
+	start := time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+	calendarRows := func() *pgxmock.Rows {
+		return pgxmock.NewRows(calendarColumns).
+			AddRow(int32(10), "Match 1", pgtype.Text{String: "not_started", Valid: true},
+				pgtype.Timestamptz{Time: start, Valid: true}, pgtype.Timestamptz{}, pgtype.Timestamptz{},
+				int32(3), pgtype.Text{String: "https://twitch.tv/lck", Valid: true}, pgtype.Timestamptz{},
+				int32(1), pgtype.Text{String: "T1", Valid: true}, int32(2), pgtype.Text{String: "Gen.G", Valid: true},
+				"LoL", "LCK", "Playoffs", int32(1)).
+			AddRow(int32(11), "Match 2", pgtype.Text{String: "canceled", Valid: true},
+				pgtype.Timestamptz{Time: start, Valid: true}, pgtype.Timestamptz{}, pgtype.Timestamptz{},
+				int32(1), pgtype.Text{}, pgtype.Timestamptz{},
+				int32(0), pgtype.Text{}, int32(0), pgtype.Text{},
+				"LoL", "LCK", "Playoffs", int32(0))
+	}
+
+	t.Run("Team feeds list the matches of the team", func(t *testing.T) {
+		mockDB.ExpectQuery("FROM matches m").
+			WithArgs(pgxmock.AnyArg(), []int32{}, []int32{}, []int32{2}, int32(FeedMaxEvents)).
+			WillReturnRows(calendarRows())
+
+		rec := get(handler, "/api/v1/calendars/teams/2.ics", nil)
+		st.Assert(t, rec.Code, http.StatusOK)
+		st.Expect(t, rec.Header().Get("Content-Type"), "text/calendar; charset=utf-8")
+		st.Reject(t, rec.Header().Get("ETag"), "")
+		body := rec.Body.String()
+		st.Expect(t, strings.Contains(body, "\r\nX-WR-CALNAME:Gen.G\r\n"), true)
+		st.Expect(t, strings.Contains(body, "\r\nUID:match-10@stalka\r\nSEQUENCE:1\r\n"), true)
+		st.Expect(t, strings.Contains(body, "\r\nDTSTART:20251001T080000Z\r\nDTEND:20251001T110000Z\r\n"), true)
+		st.Expect(t, strings.Contains(body, "\r\nSUMMARY:T1 vs Gen.G (LCK)\r\n"), true)
+		st.Expect(t, strings.Contains(body, "Stream: https://twitch.tv/lck"), true)
+		st.Expect(t, strings.Contains(body, "\r\nSUMMARY:Match 2 (LCK)\r\n"), true)
+		st.Expect(t, strings.Contains(body, "\r\nSTATUS:CANCELLED\r\n"), true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Selections are read from URL mappings", func(t *testing.T) {
+		mockDB.ExpectQuery("UPDATE url_mappings SET access_count").WithArgs("abcdef0123456789").
+			WillReturnRows(pgxmock.NewRows([]string{"value_list"}).
+				AddRow([]byte(`[{"type":"game","id":1},{"type":"team","id":2},{"type":"team","id":3}]`)))
+		mockDB.ExpectQuery("FROM matches m").
+			WithArgs(pgxmock.AnyArg(), []int32{1}, []int32{}, []int32{2, 3}, int32(FeedMaxEvents)).
+			WillReturnRows(calendarRows())
+
+		rec := get(handler, "/api/v1/calendars/selections/abcdef0123456789.ics", nil)
+		st.Expect(t, rec.Code, http.StatusOK)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - unknown selection", func(t *testing.T) {
+		mockDB.ExpectQuery("UPDATE url_mappings SET access_count").WithArgs("missing").
+			WillReturnError(pgx.ErrNoRows)
+
+		rec := get(handler, "/api/v1/calendars/selections/missing", nil)
+		st.Expect(t, rec.Code, http.StatusNotFound)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - invalid feed ID", func(t *testing.T) {
+		rec := get(handler, "/api/v1/calendars/leagues/lck.ics", nil)
+		st.Expect(t, rec.Code, http.StatusBadRequest)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestEventFromRow(t *testing.T) {
+	start := time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+	row := calendarRow(start)
+
+	t.Run("Scheduled matches last one hour per game", func(t *testing.T) {
+		event := eventFromRow(row)
+		st.Expect(t, event.Start, start)
+		st.Expect(t, event.End, start.Add(3*time.Hour))
+		st.Expect(t, event.Stamp, start)
+	})
+
+	t.Run("Played matches use their real times", func(t *testing.T) {
+		played := row
+		played.BeginAt = pgtype.Timestamptz{Time: start.Add(20 * time.Minute), Valid: true}
+		played.EndAt = pgtype.Timestamptz{Time: start.Add(2 * time.Hour), Valid: true}
+		played.ModifiedAt = pgtype.Timestamptz{Time: start.Add(3 * time.Hour), Valid: true}
+		event := eventFromRow(played)
+		st.Expect(t, event.Start, played.BeginAt.Time)
+		st.Expect(t, event.End, played.EndAt.Time)
+		st.Expect(t, event.Stamp, played.ModifiedAt.Time)
+	})
+
+	t.Run("Every change of the start, end or status bumps the sequence", func(t *testing.T) {
+		// one recorded reschedule
+		scheduled := row
+		scheduled.Sequence = 1
+		st.Expect(t, eventFromRow(scheduled).Sequence, int32(1))
+
+		// the status went to running, and the event moved to begin_at
+		running := scheduled
+		running.Sequence = 2
+		running.BeginAt = pgtype.Timestamptz{Time: start.Add(20 * time.Minute), Valid: true}
+		st.Expect(t, eventFromRow(running).Sequence, int32(3))
+
+		// the status went to finished, and the event ends at end_at
+		finished := running
+		finished.Sequence = 3
+		finished.EndAt = pgtype.Timestamptz{Time: start.Add(2 * time.Hour), Valid: true}
+		st.Expect(t, eventFromRow(finished).Sequence, int32(5))
+
+		// a cancellation is a status change
+		canceled := scheduled
+		canceled.Sequence = 2
+		canceled.Status = pgtype.Text{String: pandatypes.MatchStatusCanceled, Valid: true}
+		event := eventFromRow(canceled)
+		st.Expect(t, event.Cancelled, true)
+		st.Expect(t, event.Sequence > eventFromRow(scheduled).Sequence, true)
+	})
+}
@@ -0,0 +1,615 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+)
+
+// MaxGraphQLRequestSize caps the body of a GraphQL request.
+const MaxGraphQLRequestSize = 64 << 10
+
+// errInternal is the error a resolver reports for a failed query; the details
+// are only logged.
+var errInternal = errors.New("internal error")
+
+// graphQLRequest is the body of a GraphQL request.
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// graphQLErrors is the body of a GraphQL request that was rejected before it
+// ran: one that is malformed, invalid or over the limits.
+type graphQLErrors struct {
+	Errors []gqlerrors.FormattedError `json:"errors"`
+}
+
+// graphQLResolver is the state of one GraphQL request: the loaders batching
+// every relation of the schema.
+type graphQLResolver struct {
+	server            *Server
+	games             *loader[Game]
+	leagues           *loader[League]
+	series            *loader[Series]
+	tournaments       *loader[Tournament]
+	matches           *loader[Match]
+	teams             *loader[Team]
+	gameLeagues       *loader[[]League]
+	leagueSeries      *loader[[]Series]
+	seriesTournaments *loader[[]Tournament]
+	tournamentMatches *loader[[]Match]
+}
+
+// resolverKey is the context key of the graphQLResolver of a request.
+type resolverKey struct{}
+
+// graphQLSchema is built on first use; it does not change.
+var graphQLSchema = sync.OnceValue(func() graphql.Schema {
+	schema, err := newGraphQLSchema()
+	if err != nil {
+		// The schema is static, so this is a programming error.
+		panic(err)
+	}
+	return schema
+})
+
+// graphQL serves a GraphQL query posted as {"query", "operationName",
+// "variables"}. Malformed and invalid queries and queries over the depth or
+// complexity limits are answered with 400; the errors of a query that ran are
+// part of its 200 response.
+func (server *Server) graphQL(w http.ResponseWriter, r *http.Request) {
+	var request graphQLRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxGraphQLRequestSize)).Decode(&request)
+	if err != nil || request.Query == "" {
+		writeGraphQLErrors(w, errors.New("the body must be a JSON object with a query"))
+		return
+	}
+	document, err := parser.Parse(parser.ParseParams{Source: request.Query, Options: parser.ParseOptions{}})
+	if err != nil {
+		writeGraphQLErrors(w, err)
+		return
+	}
+	schema := graphQLSchema()
+	validation := graphql.ValidateDocument(&schema, document, nil)
+	if !validation.IsValid {
+		writeGraphQL(w, http.StatusBadRequest, graphQLErrors{Errors: validation.Errors})
+		return
+	}
+	if err = checkQueryLimits(schema, document, request.OperationName, request.Variables); err != nil {
+		writeGraphQLErrors(w, err)
+		return
+	}
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        schema,
+		Root:          nil,
+		AST:           document,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       context.WithValue(r.Context(), resolverKey{}, server.newResolver()),
+	})
+	writeGraphQL(w, http.StatusOK, result)
+}
+
+// writeGraphQLErrors rejects a GraphQL request with 400.
+func writeGraphQLErrors(w http.ResponseWriter, err error) {
+	writeGraphQL(w, http.StatusBadRequest, graphQLErrors{Errors: gqlerrors.FormatErrors(err)})
+}
+
+// writeGraphQL writes the body of a GraphQL response.
+func writeGraphQL(w http.ResponseWriter, status int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(data, '\n'))
+}
+
+// resolverFrom returns the graphQLResolver of a request.
+func resolverFrom(ctx context.Context) *graphQLResolver {
+	resolver, _ := ctx.Value(resolverKey{}).(*graphQLResolver)
+	return resolver
+}
+
+// newResolver creates the loaders of a GraphQL request. Each relation is
+// fetched with one query per level of the query, whatever the number of parents.
+func (server *Server) newResolver() *graphQLResolver {
+	queries := server.Queries
+	return &graphQLResolver{
+		server: server,
+		games: newLoader(func(ctx context.Context, ids []int32) (map[int32]Game, error) {
+			rows, err := queries.GetGamesByIDs(ctx, ids)
+			if err != nil {
+				return nil, server.loadError("games", err)
+			}
+			return byKey(rows, gameFromRow, func(game Game) int32 { return game.ID }), nil
+		}),
+		leagues: newLoader(func(ctx context.Context, ids []int32) (map[int32]League, error) {
+			rows, err := queries.GetLeaguesByIDs(ctx, ids)
+			if err != nil {
+				return nil, server.loadError("leagues", err)
+			}
+			return byKey(rows, leagueFromRow, func(league League) int32 { return league.ID }), nil
+		}),
+		series: newLoader(func(ctx context.Context, ids []int32) (map[int32]Series, error) {
+			rows, err := queries.GetSeriesByIDs(ctx, ids)
+			if err != nil {
+				return nil, server.loadError("series", err)
+			}
+			return byKey(rows, seriesFromRow, func(series Series) int32 { return series.ID }), nil
+		}),
+		tournaments: newLoader(func(ctx context.Context, ids []int32) (map[int32]Tournament, error) {
+			rows, err := queries.GetTournamentsByIDs(ctx, ids)
+			if err != nil {
+				return nil, server.loadError("tournaments", err)
+			}
+			return byKey(rows, tournamentFromRow, func(tournament Tournament) int32 { return tournament.ID }), nil
+		}),
+		matches: newLoader(func(ctx context.Context, ids []int32) (map[int32]Match, error) {
+			rows, err := queries.GetMatchesByIDs(ctx, ids)
+			if err != nil {
+				return nil, server.loadError("matches", err)
+			}
+			rows = slices.DeleteFunc(rows, func(row dbtypes.Match) bool { return row.DeletedAt.Valid })
+			return byKey(rows, matchFromRow, func(match Match) int32 { return match.ID }), nil
+		}),
+		teams: newLoader(func(ctx context.Context, ids []int32) (map[int32]Team, error) {
+			rows, err := queries.GetTeamsByIDs(ctx, ids)
+			if err != nil {
+				return nil, server.loadError("teams", err)
+			}
+			return byKey(rows, teamFromRow, func(team Team) int32 { return team.ID }), nil
+		}),
+		gameLeagues: newLoader(func(ctx context.Context, ids []int32) (map[int32][]League, error) {
+			rows, err := queries.GetLeaguesByGameIDs(ctx, ids)
+			if err != nil {
+				return nil, server.loadError("leagues", err)
+			}
+			return grouped(ids, rows, leagueFromRow, func(league League) int32 { return league.GameID }), nil
+		}),
+		leagueSeries: newLoader(func(ctx context.Context, ids []int32) (map[int32][]Series, error) {
+			rows, err := queries.GetSeriesByLeagueIDs(ctx, ids)
+			if err != nil {
+				return nil, server.loadError("series", err)
+			}
+			return grouped(ids, rows, seriesFromRow, func(series Series) int32 { return series.LeagueID }), nil
+		}),
+		seriesTournaments: newLoader(func(ctx context.Context, ids []int32) (map[int32][]Tournament, error) {
+			rows, err := queries.GetTournamentsBySeriesIDs(ctx, ids)
+			if err != nil {
+				return nil, server.loadError("tournaments", err)
+			}
+			return grouped(ids, rows, tournamentFromRow, func(tournament Tournament) int32 { return tournament.SeriesID }), nil
+		}),
+		tournamentMatches: newLoader(func(ctx context.Context, ids []int32) (map[int32][]Match, error) {
+			rows, err := queries.GetMatchesByTournamentIDs(ctx, ids)
+			if err != nil {
+				return nil, server.loadError("matches", err)
+			}
+			return grouped(ids, rows, matchFromRow, func(match Match) int32 { return match.TournamentID }), nil
+		}),
+	}
+}
+
+// loadError logs a failed query of a resolver and hides its details from the client.
+func (server *Server) loadError(resource string, err error) error {
+	server.Logger.Errorf("Error resolving %s: %v", resource, err)
+	return errInternal
+}
+
+// related resolves a to-one relation through a loader of the request. An ID
+// of 0 marks a missing relation, e.g. team2 of a battle royale match.
+// @param pick - selects the loader.
+// @param key - returns the ID of the related entity.
+// @returns the resolver.
+func related[V any](pick func(*graphQLResolver) *loader[V], key func(graphql.ResolveParams) int32) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		id := key(p)
+		if id == 0 {
+			return nil, nil
+		}
+		return pick(resolverFrom(p.Context)).load(p.Context, id), nil
+	}
+}
+
+// children resolves a to-many relation through a loader of the request and
+// cuts it to the limit argument.
+// @param pick - selects the loader.
+// @param key - returns the ID of the parent.
+// @param keep - filters the children by the arguments of the field, nil keeps every child.
+// @returns the resolver.
+func children[V any](
+	pick func(*graphQLResolver) *loader[[]V],
+	key func(graphql.ResolveParams) int32,
+	keep func(graphql.ResolveParams, V) bool,
+) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		page, err := graphQLWindow(p)
+		if err != nil {
+			return nil, err
+		}
+		thunk := pick(resolverFrom(p.Context)).load(p.Context, key(p))
+		return func() (any, error) {
+			value, err := thunk()
+			if err != nil {
+				return nil, err
+			}
+			all, _ := value.([]V)
+			kept := make([]V, 0, min(len(all), page.Limit))
+			for _, child := range all {
+				if len(kept) == page.Limit {
+					break
+				}
+				if keep == nil || keep(p, child) {
+					kept = append(kept, child)
+				}
+			}
+			return kept, nil
+		}, nil
+	}
+}
+
+// graphQLWindow reads the limit and offset arguments of a list field.
+// @returns the window and an error if a value is out of range.
+func graphQLWindow(p graphql.ResolveParams) (pageParams, error) {
+	page := pageParams{Limit: DefaultPageSize, Offset: 0}
+	if limit, ok := p.Args["limit"].(int); ok {
+		if limit < 1 || limit > MaxPageSize {
+			return page, errors.New("limit must be an integer between 1 and " + strconv.Itoa(MaxPageSize))
+		}
+		page.Limit = limit
+	}
+	if offset, ok := p.Args["offset"].(int); ok {
+		if offset < 0 {
+			return page, errors.New("offset must be a non-negative 32-bit integer")
+		}
+		page.Offset = offset
+	}
+	return page, nil
+}
+
+// argID returns the id argument of a field. GraphQL Ints are 32-bit.
+func argID(p graphql.ResolveParams) int32 {
+	id, _ := p.Args["id"].(int)
+	return int32(id) //nolint:gosec // GraphQL Ints are 32-bit
+}
+
+// resolveGames resolves the games root field.
+func resolveGames(p graphql.ResolveParams) (any, error) {
+	page, err := graphQLWindow(p)
+	if err != nil {
+		return nil, err
+	}
+	server := resolverFrom(p.Context).server
+	rows, err := server.Queries.ListGames(p.Context, dbtypes.ListGamesParams{
+		MaxRows: page.maxRows(), SkipRows: page.skipRows(),
+	})
+	if err != nil {
+		return nil, server.loadError("games", err)
+	}
+	games := make([]Game, 0, len(rows))
+	for _, row := range rows {
+		games = append(games, gameFromRow(row))
+	}
+	return windowed(games, page).Data, nil
+}
+
+// resolveMatches resolves the matches root field with the filters of the
+// matches endpoint.
+func resolveMatches(p graphql.ResolveParams) (any, error) {
+	page, err := graphQLWindow(p)
+	if err != nil {
+		return nil, err
+	}
+	var params dbtypes.ListMatchesParams
+	for key, target := range map[string]*pgtype.Timestamptz{"from": &params.StartsAfter, "to": &params.StartsBefore} {
+		if value, ok := p.Args[key].(time.Time); ok {
+			*target = pgtype.Timestamptz{Time: value, InfinityModifier: pgtype.Finite, Valid: true}
+		}
+	}
+	for key, target := range map[string]*pgtype.Int4{
+		"gameId": &params.GameID, "leagueId": &params.LeagueID, "teamId": &params.TeamID,
+	} {
+		if value, ok := p.Args[key].(int); ok {
+			*target = pgtype.Int4{Int32: int32(value), Valid: true} //nolint:gosec // GraphQL Ints are 32-bit
+		}
+	}
+	if live, ok := p.Args["live"].(bool); ok {
+		params.IsLive = pgtype.Bool{Bool: live, Valid: true}
+	}
+	params.MaxRows = int32(page.Limit)   //nolint:gosec // bounded by MaxPageSize
+	params.SkipRows = int32(page.Offset) //nolint:gosec // GraphQL Ints are 32-bit
+	server := resolverFrom(p.Context).server
+	rows, err := server.Queries.ListMatches(p.Context, params)
+	if err != nil {
+		return nil, server.loadError("matches", err)
+	}
+	matches := make([]Match, 0, len(rows))
+	for _, row := range rows {
+		matches = append(matches, matchFromRow(row))
+	}
+	return matches, nil
+}
+
+// keepMatch filters the matches of a tournament by the live and finished arguments.
+func keepMatch(p graphql.ResolveParams, match Match) bool {
+	if live, ok := p.Args["live"].(bool); ok && match.IsLive != live {
+		return false
+	}
+	if finished, ok := p.Args["finished"].(bool); ok && match.Finished != finished {
+		return false
+	}
+	return true
+}
+
+// newGraphQLSchema builds the schema of the GraphQL endpoint. Field names are
+// the camel case forms of the JSON fields of the REST endpoints, and resolve
+// to the same values.
+func newGraphQLSchema() (graphql.Schema, error) {
+	var gameType, leagueType, seriesType, tournamentType, matchType, teamType *graphql.Object
+	nonNull := graphql.NewNonNull
+	listOf := func(item graphql.Type) graphql.Output {
+		return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(item)))
+	}
+	limitArgs := func() graphql.FieldConfigArgument {
+		return graphql.FieldConfigArgument{
+			"limit": {Type: graphql.Int, DefaultValue: DefaultPageSize, Description: "At most " + strconv.Itoa(MaxPageSize)},
+		}
+	}
+	windowArgs := func() graphql.FieldConfigArgument {
+		args := limitArgs()
+		args["offset"] = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0}
+		return args
+	}
+	idArgs := graphql.FieldConfigArgument{"id": {Type: nonNull(graphql.Int)}}
+	games := func(r *graphQLResolver) *loader[Game] { return r.games }
+	leagues := func(r *graphQLResolver) *loader[League] { return r.leagues }
+	series := func(r *graphQLResolver) *loader[Series] { return r.series }
+	tournaments := func(r *graphQLResolver) *loader[Tournament] { return r.tournaments }
+	teams := func(r *graphQLResolver) *loader[Team] { return r.teams }
+
+	gameType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Game",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":   {Type: nonNull(graphql.Int)},
+				"name": {Type: nonNull(graphql.String)},
+				"slug": {Type: graphql.String},
+				"leagues": {
+					Type:        listOf(leagueType),
+					Args:        limitArgs(),
+					Description: "Leagues of the game by name",
+					Resolve: children(
+						func(r *graphQLResolver) *loader[[]League] { return r.gameLeagues },
+						func(p graphql.ResolveParams) int32 { return p.Source.(Game).ID }, nil,
+					),
+				},
+			}
+		}),
+	})
+	leagueType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "League",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":        {Type: nonNull(graphql.Int)},
+				"name":      {Type: nonNull(graphql.String)},
+				"slug":      {Type: graphql.String},
+				"imageLink": {Type: graphql.String},
+				"gameId":    {Type: nonNull(graphql.Int)},
+				"game": {
+					Type:    gameType,
+					Resolve: related(games, func(p graphql.ResolveParams) int32 { return p.Source.(League).GameID }),
+				},
+				"series": {
+					Type:        listOf(seriesType),
+					Args:        limitArgs(),
+					Description: "Series of the league by name",
+					Resolve: children(
+						func(r *graphQLResolver) *loader[[]Series] { return r.leagueSeries },
+						func(p graphql.ResolveParams) int32 { return p.Source.(League).ID }, nil,
+					),
+				},
+			}
+		}),
+	})
+	seriesType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Series",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":       {Type: nonNull(graphql.Int)},
+				"name":     {Type: nonNull(graphql.String)},
+				"slug":     {Type: graphql.String},
+				"gameId":   {Type: nonNull(graphql.Int)},
+				"leagueId": {Type: nonNull(graphql.Int)},
+				"game": {
+					Type:    gameType,
+					Resolve: related(games, func(p graphql.ResolveParams) int32 { return p.Source.(Series).GameID }),
+				},
+				"league": {
+					Type:    leagueType,
+					Resolve: related(leagues, func(p graphql.ResolveParams) int32 { return p.Source.(Series).LeagueID }),
+				},
+				"tournaments": {
+					Type:        listOf(tournamentType),
+					Args:        limitArgs(),
+					Description: "Tournaments of the series by name",
+					Resolve: children(
+						func(r *graphQLResolver) *loader[[]Tournament] { return r.seriesTournaments },
+						func(p graphql.ResolveParams) int32 { return p.Source.(Series).ID }, nil,
+					),
+				},
+			}
+		}),
+	})
+	tournamentType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Tournament",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			matchArgs := limitArgs()
+			matchArgs["live"] = &graphql.ArgumentConfig{Type: graphql.Boolean}
+			matchArgs["finished"] = &graphql.ArgumentConfig{Type: graphql.Boolean}
+			return graphql.Fields{
+				"id":       {Type: nonNull(graphql.Int)},
+				"name":     {Type: nonNull(graphql.String)},
+				"slug":     {Type: graphql.String},
+				"tier":     {Type: graphql.Int},
+				"gameId":   {Type: nonNull(graphql.Int)},
+				"leagueId": {Type: nonNull(graphql.Int)},
+				"seriesId": {Type: nonNull(graphql.Int)},
+				"game": {
+					Type:    gameType,
+					Resolve: related(games, func(p graphql.ResolveParams) int32 { return p.Source.(Tournament).GameID }),
+				},
+				"league": {
+					Type:    leagueType,
+					Resolve: related(leagues, func(p graphql.ResolveParams) int32 { return p.Source.(Tournament).LeagueID }),
+				},
+				"series": {
+					Type:    seriesType,
+					Resolve: related(series, func(p graphql.ResolveParams) int32 { return p.Source.(Tournament).SeriesID }),
+				},
+				"matches": {
+					Type:        listOf(matchType),
+					Args:        matchArgs,
+					Description: "Matches of the tournament by expected start time",
+					Resolve: children(
+						func(r *graphQLResolver) *loader[[]Match] { return r.tournamentMatches },
+						func(p graphql.ResolveParams) int32 { return p.Source.(Tournament).ID }, keepMatch,
+					),
+				},
+			}
+		}),
+	})
+	matchType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Match",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":                  {Type: nonNull(graphql.Int)},
+				"name":                {Type: nonNull(graphql.String)},
+				"slug":                {Type: graphql.String},
+				"status":              {Type: graphql.String},
+				"finished":            {Type: nonNull(graphql.Boolean)},
+				"isLive":              {Type: nonNull(graphql.Boolean)},
+				"expectedStartTime":   {Type: graphql.DateTime},
+				"originalScheduledAt": {Type: graphql.DateTime},
+				"rescheduled":         {Type: nonNull(graphql.Boolean)},
+				"beginAt":             {Type: graphql.DateTime},
+				"endAt":               {Type: graphql.DateTime},
+				"amountOfGames":       {Type: nonNull(graphql.Int)},
+				"team1Id":             {Type: nonNull(graphql.Int)},
+				"team1Score":          {Type: nonNull(graphql.Int)},
+				"team2Id":             {Type: nonNull(graphql.Int)},
+				"team2Score":          {Type: nonNull(graphql.Int)},
+				"winnerId":            {Type: graphql.Int},
+				"winnerType":          {Type: graphql.String},
+				"forfeit":             {Type: nonNull(graphql.Boolean)},
+				"draw":                {Type: nonNull(graphql.Boolean)},
+				"streamUrl":           {Type: graphql.String},
+				"gameId":              {Type: nonNull(graphql.Int)},
+				"leagueId":            {Type: nonNull(graphql.Int)},
+				"seriesId":            {Type: nonNull(graphql.Int)},
+				"tournamentId":        {Type: nonNull(graphql.Int)},
+				"game": {
+					Type:    gameType,
+					Resolve: related(games, func(p graphql.ResolveParams) int32 { return p.Source.(Match).GameID }),
+				},
+				"league": {
+					Type:    leagueType,
+					Resolve: related(leagues, func(p graphql.ResolveParams) int32 { return p.Source.(Match).LeagueID }),
+				},
+				"series": {
+					Type:    seriesType,
+					Resolve: related(series, func(p graphql.ResolveParams) int32 { return p.Source.(Match).SeriesID }),
+				},
+				"tournament": {
+					Type: tournamentType,
+					Resolve: related(tournaments, func(p graphql.ResolveParams) int32 {
+						return p.Source.(Match).TournamentID
+					}),
+				},
+				"team1": {
+					Type:        teamType,
+					Description: "Only set for two-team matches",
+					Resolve:     related(teams, func(p graphql.ResolveParams) int32 { return p.Source.(Match).Team1ID }),
+				},
+				"team2": {
+					Type:        teamType,
+					Description: "Only set for two-team matches",
+					Resolve:     related(teams, func(p graphql.ResolveParams) int32 { return p.Source.(Match).Team2ID }),
+				},
+			}
+		}),
+	})
+	teamType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Team",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":        {Type: nonNull(graphql.Int)},
+				"name":      {Type: nonNull(graphql.String)},
+				"slug":      {Type: graphql.String},
+				"acronym":   {Type: graphql.String},
+				"imageLink": {Type: graphql.String},
+				"gameId":    {Type: nonNull(graphql.Int)},
+				"game": {
+					Type:    gameType,
+					Resolve: related(games, func(p graphql.ResolveParams) int32 { return p.Source.(Team).GameID }),
+				},
+			}
+		}),
+	})
+
+	matchesArgs := windowArgs()
+	for name, argType := range map[string]graphql.Input{
+		"from": graphql.DateTime, "to": graphql.DateTime,
+		"gameId": graphql.Int, "leagueId": graphql.Int, "teamId": graphql.Int, "live": graphql.Boolean,
+	} {
+		matchesArgs[name] = &graphql.ArgumentConfig{Type: argType}
+	}
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"games": {Type: listOf(gameType), Args: windowArgs(), Resolve: resolveGames},
+				"game":  {Type: gameType, Args: idArgs, Resolve: related(games, argID)},
+				"league": {
+					Type: leagueType, Args: idArgs, Resolve: related(leagues, argID),
+				},
+				"series": {
+					Type: seriesType, Args: idArgs, Resolve: related(series, argID),
+				},
+				"tournament": {
+					Type: tournamentType, Args: idArgs, Resolve: related(tournaments, argID),
+				},
+				"match": {
+					Type: matchType, Args: idArgs,
+					Resolve: related(func(r *graphQLResolver) *loader[Match] { return r.matches }, argID),
+				},
+				"team": {
+					Type: teamType, Args: idArgs, Resolve: related(teams, argID),
+				},
+				"matches": {
+					Type:        listOf(matchType),
+					Args:        matchesArgs,
+					Description: "Matches by expected start time; from and to (exclusive) bound the expected start time",
+					Resolve:     resolveMatches,
+				},
+			},
+		}),
+	})
+}
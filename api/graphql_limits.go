@@ -0,0 +1,160 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	// MaxQueryDepth caps how deeply the fields of a GraphQL query nest.
+	MaxQueryDepth = 10
+	// MaxQueryComplexity caps the complexity of a GraphQL query: every field
+	// costs 1, and the fields below a list count once per item the list may
+	// return, i.e. its limit argument.
+	MaxQueryComplexity = 50000
+)
+
+// queryWalker computes the depth and complexity of a query.
+type queryWalker struct {
+	schema    graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+// checkQueryLimits rejects queries over MaxQueryDepth or MaxQueryComplexity
+// before they run. Introspection fields are not counted.
+// @param schema - the schema the document was validated against.
+// @param document - the parsed and validated query.
+// @param operationName - the operation to run, empty if the document has one.
+// @param variables - the variables of the request.
+// @returns an error naming the exceeded limit.
+func checkQueryLimits(schema graphql.Schema, document *ast.Document, operationName string, variables map[string]any) error {
+	walker := queryWalker{schema: schema, fragments: make(map[string]*ast.FragmentDefinition), variables: variables}
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		case *ast.FragmentDefinition:
+			walker.fragments[definition.Name.Value] = definition
+		}
+	}
+	// Other operations are refused by graphql.Execute.
+	if operation == nil || operation.Operation != ast.OperationTypeQuery {
+		return nil
+	}
+	complexity, depth := walker.selections(schema.QueryType(), operation.SelectionSet, 1)
+	if depth > MaxQueryDepth {
+		return fmt.Errorf("query depth %d exceeds the maximum of %d", depth, MaxQueryDepth)
+	}
+	if complexity > MaxQueryComplexity {
+		return fmt.Errorf("query complexity exceeds the maximum of %d", MaxQueryComplexity)
+	}
+	return nil
+}
+
+// selections computes the complexity and depth of a selection set. The
+// complexity saturates above MaxQueryComplexity, so it cannot overflow.
+// @param parent - the type the selections are made on.
+// @param set - the selections.
+// @param depth - the depth of the fields of the set.
+// @returns the complexity and the depth of the deepest field.
+func (walker queryWalker) selections(parent *graphql.Object, set *ast.SelectionSet, depth int) (int, int) {
+	complexity, deepest := 0, depth
+	if set == nil {
+		return complexity, deepest
+	}
+	add := func(fieldComplexity, fieldDepth int) {
+		complexity = min(complexity+fieldComplexity, MaxQueryComplexity+1)
+		deepest = max(deepest, fieldDepth)
+	}
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			add(walker.field(parent, selection, depth))
+		case *ast.InlineFragment:
+			add(walker.selections(walker.condition(parent, selection.TypeCondition), selection.SelectionSet, depth))
+		case *ast.FragmentSpread:
+			if fragment, ok := walker.fragments[selection.Name.Value]; ok {
+				add(walker.selections(walker.condition(parent, fragment.TypeCondition), fragment.SelectionSet, depth))
+			}
+		}
+	}
+	return complexity, deepest
+}
+
+// field computes the complexity and depth of a field and its selections.
+func (walker queryWalker) field(parent *graphql.Object, field *ast.Field, depth int) (int, int) {
+	definition, ok := parent.Fields()[field.Name.Value]
+	if !ok || strings.HasPrefix(field.Name.Value, "__") || field.SelectionSet == nil {
+		return 1, depth
+	}
+	fieldType, list := unwrapType(definition.Type)
+	object, ok := fieldType.(*graphql.Object)
+	if !ok {
+		return 1, depth
+	}
+	complexity, deepest := walker.selections(object, field.SelectionSet, depth+1)
+	if list {
+		complexity *= walker.limit(field)
+	}
+	return min(1+complexity, MaxQueryComplexity+1), deepest
+}
+
+// condition returns the type of a fragment, the parent when it has no type condition.
+func (walker queryWalker) condition(parent *graphql.Object, condition *ast.Named) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+	if object, ok := walker.schema.Type(condition.Name.Value).(*graphql.Object); ok {
+		return object
+	}
+	return parent
+}
+
+// limit returns the limit argument of a list field within 1 and MaxPageSize,
+// DefaultPageSize when it is unset. Limits outside the range are refused by
+// the resolver.
+func (walker queryWalker) limit(field *ast.Field) int {
+	limit := DefaultPageSize
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "limit" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if parsed, err := strconv.Atoi(value.Value); err == nil {
+				limit = parsed
+			}
+		case *ast.Variable:
+			// JSON numbers are decoded as float64.
+			if variable, ok := walker.variables[value.Name.Value].(float64); ok {
+				limit = int(variable)
+			}
+		}
+	}
+	return min(max(limit, 1), MaxPageSize)
+}
+
+// unwrapType strips the non-null and list wrappers of a type.
+// @returns the named type and whether a list wrapped it.
+func unwrapType(fieldType graphql.Type) (graphql.Type, bool) {
+	list := false
+	for {
+		switch wrapped := fieldType.(type) {
+		case *graphql.NonNull:
+			fieldType = wrapped.OfType
+		case *graphql.List:
+			list = true
+			fieldType = wrapped.OfType
+		default:
+			return fieldType, list
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var (
+	gameColumns   = []string{"id", "name", "slug", "last_seen_at", "deleted_at"}
+	leagueColumns = []string{
+		"id", "name", "slug", "game_id", "image_link", "last_seen_at", "deleted_at", "modified_at", "search_vector",
+	}
+	seriesColumns = []string{
+		"id", "name", "slug", "game_id", "league_id", "last_seen_at", "deleted_at", "modified_at",
+	}
+	tournamentColumns = []string{
+		"id", "name", "slug", "tier", "game_id", "league_id", "serie_id",
+		"last_seen_at", "deleted_at", "modified_at", "search_vector",
+	}
+	teamColumns = []string{
+		"id", "name", "slug", "acronym", "image_link", "game_id", "last_seen_at", "deleted_at", "modified_at", "search_vector",
+	}
+)
+
+// postGraphQL posts a GraphQL request.
+func postGraphQL(handler http.Handler, query string, variables map[string]any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/graphql", strings.NewReader(string(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// graphQLResponse decodes the body of a GraphQL response.
+func graphQLResponse(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	st.Assert(t, json.Unmarshal(rec.Body.Bytes(), &body), nil)
+	return body
+}
+
+// expectedJSON decodes an expected body the way graphQLResponse does, so the
+// two compare.
+func expectedJSON(t *testing.T, body string) map[string]any {
+	t.Helper()
+	var expected map[string]any
+	st.Assert(t, json.Unmarshal([]byte(body), &expected), nil)
+	return expected
+}
+
+// errorMessages returns the messages of the errors of a GraphQL response.
+func errorMessages(body map[string]any) []string {
+	var messages []string
+	errs, _ := body["errors"].([]any)
+	for _, err := range errs {
+		message, _ := err.(map[string]any)["message"].(string)
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func TestGraphQLNestedQueryIsBatched(t *testing.T) {
+	handler, mockDB := newTestServer(t)
+	noTime := pgtype.Timestamptz{}
+	slug := func(value string) pgtype.Text { return pgtype.Text{String: value, Valid: true} }
+
+	mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games").
+		WithArgs(int32(3), int32(0)).
+		WillReturnRows(pgxmock.NewRows(gameColumns).
+			AddRow(int32(1), "LoL", slug("league-of-legends"), noTime, noTime).
+			AddRow(int32(3), "CS2", slug("cs-go"), noTime, noTime).
+			AddRow(int32(4), "Dota 2", slug("dota-2"), noTime, noTime))
+	// One query per level, whatever the number of parents.
+	mockDB.ExpectQuery("FROM leagues\\s+WHERE game_id = ANY").
+		WithArgs([]int32{1, 3}).
+		WillReturnRows(pgxmock.NewRows(leagueColumns).
+			AddRow(int32(10), "LCK", slug("lck"), int32(1), pgtype.Text{}, noTime, noTime, noTime, nil).
+			AddRow(int32(11), "ESL Pro League", slug("esl"), int32(3), pgtype.Text{}, noTime, noTime, noTime, nil))
+	mockDB.ExpectQuery("FROM series\\s+WHERE league_id = ANY").
+		WithArgs([]int32{10, 11}).
+		WillReturnRows(pgxmock.NewRows(seriesColumns).
+			AddRow(int32(20), "LCK Spring 2026", slug("lck-spring-2026"), int32(1), int32(10), noTime, noTime, noTime).
+			AddRow(int32(21), "Season 23", slug("season-23"), int32(3), int32(11), noTime, noTime, noTime))
+	mockDB.ExpectQuery("FROM tournaments\\s+WHERE serie_id = ANY").
+		WithArgs([]int32{20, 21}).
+		WillReturnRows(pgxmock.NewRows(tournamentColumns).
+			AddRow(int32(30), "Playoffs", slug("playoffs"), pgtype.Int4{Int32: 1, Valid: true},
+				int32(1), int32(10), int32(20), noTime, noTime, noTime, nil))
+	first := fixtureMatch(40, time.Date(2026, 4, 1, 8, 0, 0, 0, time.UTC))
+	first.TournamentID = 30
+	second := fixtureMatch(41, time.Date(2026, 4, 2, 8, 0, 0, 0, time.UTC))
+	second.TournamentID = 30
+	second.Name = "GEN vs T1"
+	second.Team1ID, second.Team2ID = 2, 1
+	mockDB.ExpectQuery("FROM matches\\s+WHERE tournament_id = ANY").
+		WithArgs([]int32{30}).
+		WillReturnRows(matchRows(first, second))
+	mockDB.ExpectQuery("FROM teams WHERE id = ANY").
+		WithArgs([]int32{1, 2}).
+		WillReturnRows(pgxmock.NewRows(teamColumns).
+			AddRow(int32(1), "T1", slug("t1"), slug("T1"), pgtype.Text{}, int32(1), noTime, noTime, noTime, nil).
+			AddRow(int32(2), "Gen.G", slug("geng"), slug("GEN"), pgtype.Text{}, int32(1), noTime, noTime, noTime, nil))
+
+	rec := postGraphQL(handler, `{
+		games(limit: 2) {
+			name
+			leagues(limit: 5) {
+				name
+				series(limit: 5) {
+					name
+					tournaments(limit: 5) {
+						name
+						tier
+						matches(limit: 10) { id expectedStartTime team1 { acronym } team2 { acronym } }
+					}
+				}
+			}
+		}
+	}`, nil)
+	st.Expect(t, rec.Code, http.StatusOK)
+	st.Expect(t, rec.Header().Get("Content-Type"), "application/json")
+	st.Expect(t, graphQLResponse(t, rec), expectedJSON(t, `{"data": {"games": [
+		{"name": "LoL", "leagues": [{"name": "LCK", "series": [{"name": "LCK Spring 2026", "tournaments": [
+			{"name": "Playoffs", "tier": 1, "matches": [
+				{"id": 40, "expectedStartTime": "2026-04-01T08:00:00Z", "team1": {"acronym": "T1"}, "team2": {"acronym": "GEN"}},
+				{"id": 41, "expectedStartTime": "2026-04-02T08:00:00Z", "team1": {"acronym": "GEN"}, "team2": {"acronym": "T1"}}
+			]}
+		]}]}]},
+		{"name": "CS2", "leagues": [{"name": "ESL Pro League", "series": [{"name": "Season 23", "tournaments": []}]}]}
+	]}}`))
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestGraphQLFilters(t *testing.T) {
+	handler, mockDB := newTestServer(t)
+	from := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Root matches take the filters of the matches endpoint", func(t *testing.T) {
+		live := fixtureMatch(40, from.Add(time.Hour))
+		live.IsLive = true
+		mockDB.ExpectQuery("SELECT .+ FROM matches m").
+			WithArgs(
+				pgtype.Timestamptz{Time: from, InfinityModifier: pgtype.Finite, Valid: true}, pgtype.Timestamptz{},
+				pgtype.Int4{Int32: 1, Valid: true}, pgtype.Int4{}, pgtype.Int4{}, pgtype.Bool{Bool: true, Valid: true},
+				int32(10), int32(0),
+			).
+			WillReturnRows(matchRows(live))
+		rec := postGraphQL(handler, `query($game: Int) {
+			matches(gameId: $game, live: true, limit: 10, from: "2026-04-01T00:00:00Z") { id isLive }
+		}`, map[string]any{"game": 1})
+		st.Expect(t, rec.Code, http.StatusOK)
+		st.Expect(t, graphQLResponse(t, rec), expectedJSON(t, `{"data": {"matches": [{"id": 40, "isLive": true}]}}`))
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Nested matches are filtered after they are loaded", func(t *testing.T) {
+		finished := fixtureMatch(40, from)
+		finished.Finished = true
+		upcoming := fixtureMatch(41, from.Add(24*time.Hour))
+		mockDB.ExpectQuery("FROM tournaments WHERE id = ANY").
+			WithArgs([]int32{5}).
+			WillReturnRows(pgxmock.NewRows(tournamentColumns).
+				AddRow(int32(5), "Playoffs", pgtype.Text{}, pgtype.Int4{}, int32(1), int32(3), int32(4),
+					pgtype.Timestamptz{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}, nil))
+		mockDB.ExpectQuery("FROM matches\\s+WHERE tournament_id = ANY").
+			WithArgs([]int32{5}).
+			WillReturnRows(matchRows(finished, upcoming))
+		rec := postGraphQL(handler, `{ tournament(id: 5) { tier matches(finished: false) { id } } }`, nil)
+		st.Expect(t, graphQLResponse(t, rec), expectedJSON(t,
+			`{"data": {"tournament": {"tier": null, "matches": [{"id": 41}]}}}`))
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Deleted and unknown entities are null", func(t *testing.T) {
+		deleted := fixtureMatch(40, from)
+		deleted.DeletedAt = pgtype.Timestamptz{Time: from, InfinityModifier: pgtype.Finite, Valid: true}
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs([]int32{40}).
+			WillReturnRows(matchRows(deleted))
+		rec := postGraphQL(handler, `{ match(id: 40) { id } }`, nil)
+		st.Expect(t, graphQLResponse(t, rec), expectedJSON(t, `{"data": {"match": null}}`))
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestGraphQLLimits(t *testing.T) {
+	handler, mockDB := newTestServer(t)
+
+	t.Run("Error - too deep", func(t *testing.T) {
+		rec := postGraphQL(handler, `{ game(id: 1) { leagues { game { leagues { game { leagues {
+			game { leagues { game { leagues { id } } } } } } } } } } }`, nil)
+		st.Expect(t, rec.Code, http.StatusBadRequest)
+		st.Expect(t, errorMessages(graphQLResponse(t, rec)), []string{"query depth 11 exceeds the maximum of 10"})
+	})
+
+	t.Run("Error - too complex", func(t *testing.T) {
+		rec := postGraphQL(handler, `{ games(limit: 200) { leagues(limit: 200) { series(limit: 2) { id } } } }`, nil)
+		st.Expect(t, rec.Code, http.StatusBadRequest)
+		st.Expect(t, errorMessages(graphQLResponse(t, rec)), []string{"query complexity exceeds the maximum of 50000"})
+	})
+
+	t.Run("Error - limits passed as variables count too", func(t *testing.T) {
+		rec := postGraphQL(handler, `query($n: Int) { games(limit: $n) { leagues(limit: $n) { series(limit: 2) { id } } } }`,
+			map[string]any{"n": 200})
+		st.Expect(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("Introspection is not limited", func(t *testing.T) {
+		rec := postGraphQL(handler, `{ __schema { queryType { name fields { name type { ofType { ofType { name } } } } } } }`, nil)
+		st.Expect(t, rec.Code, http.StatusOK)
+		st.Expect(t, errorMessages(graphQLResponse(t, rec)), []string(nil))
+	})
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	handler, mockDB := newTestServer(t)
+
+	t.Run("Error - not JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/graphql", strings.NewReader("{ games { id } }"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		st.Expect(t, rec.Code, http.StatusBadRequest)
+		st.Expect(t, errorMessages(graphQLResponse(t, rec)), []string{"the body must be a JSON object with a query"})
+	})
+
+	t.Run("Error - syntax error", func(t *testing.T) {
+		rec := postGraphQL(handler, `{ games { id }`, nil)
+		st.Expect(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("Error - unknown field", func(t *testing.T) {
+		rec := postGraphQL(handler, `{ games { pandascoreSecret } }`, nil)
+		st.Expect(t, rec.Code, http.StatusBadRequest)
+		st.Expect(t, len(errorMessages(graphQLResponse(t, rec))), 1)
+	})
+
+	t.Run("Error - invalid limit", func(t *testing.T) {
+		rec := postGraphQL(handler, `{ games(limit: 0) { id } }`, nil)
+		st.Expect(t, rec.Code, http.StatusOK)
+		st.Expect(t, errorMessages(graphQLResponse(t, rec)), []string{"limit must be an integer between 1 and 200"})
+	})
+
+	t.Run("Error - failed queries are not leaked", func(t *testing.T) {
+		mockDB.ExpectQuery("FROM teams WHERE id = ANY").
+			WithArgs([]int32{7}).
+			WillReturnError(errors.New("connection refused"))
+		rec := postGraphQL(handler, `{ team(id: 7) { name } }`, nil)
+		st.Expect(t, rec.Code, http.StatusOK)
+		body := graphQLResponse(t, rec)
+		st.Expect(t, errorMessages(body), []string{"internal error"})
+		st.Expect(t, body["data"], map[string]any{"team": nil})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
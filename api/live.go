@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"github.com/feimaomiao/stalka/live"
+)
+
+// LiveHeartbeat is how often an idle event stream sends a comment, so proxies
+// do not close it.
+const LiveHeartbeat = 30 * time.Second
+
+// liveEvents streams live events as Server-Sent Events. A reconnecting
+// EventSource sends Last-Event-ID and gets the events it missed.
+func (server *Server) liveEvents(w http.ResponseWriter, r *http.Request) {
+	filter, lastEventID, err := parseLiveRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keeps nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	replay, events, unsubscribe := server.Live.Subscribe(filter, lastEventID)
+	defer unsubscribe()
+	for _, event := range replay {
+		if err = writeServerSentEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err = controller.Flush(); err != nil {
+		return
+	}
+	heartbeat := time.NewTicker(LiveHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			// A closed channel means the client fell behind; it resumes from its
+			// last event when the EventSource reconnects.
+			if !ok {
+				return
+			}
+			err = writeServerSentEvent(w, event)
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err == nil {
+			err = controller.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeServerSentEvent writes one event of an event stream.
+func writeServerSentEvent(w http.ResponseWriter, event live.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// liveSocket streams live events as JSON text messages over a WebSocket.
+// Clients resume with the last_event_id query parameter.
+func (server *Server) liveSocket(w http.ResponseWriter, r *http.Request) {
+	filter, lastEventID, err := parseLiveRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		// The stream is public read-only data and carries no credentials, so
+		// pages of any origin may subscribe.
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		server.Logger.Debugf("Error accepting live WebSocket: %v", err)
+		return
+	}
+	defer conn.CloseNow()
+	// Clients only ever send control frames; CloseRead handles them and cancels
+	// ctx once the client goes away.
+	ctx := conn.CloseRead(r.Context())
+
+	replay, events, unsubscribe := server.Live.Subscribe(filter, lastEventID)
+	defer unsubscribe()
+	for _, event := range replay {
+		if err = wsjson.Write(ctx, conn, event); err != nil {
+			return
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				_ = conn.Close(websocket.StatusTryAgainLater, "fell behind, resume with last_event_id")
+				return
+			}
+			if err = wsjson.Write(ctx, conn, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseLiveRequest reads the filters of a live stream, game_id and team_id,
+// each repeatable or comma separated, and the event to resume after, from
+// Last-Event-ID or last_event_id.
+// @param r - the request.
+// @returns the filter, the last event ID (0 when unset) and an error naming the invalid parameter.
+func parseLiveRequest(r *http.Request) (live.Filter, uint64, error) {
+	var filter live.Filter
+	query := r.URL.Query()
+	for key, target := range map[string]*[]int32{"game_id": &filter.GameIDs, "team_id": &filter.TeamIDs} {
+		for _, values := range query[key] {
+			for value := range strings.SplitSeq(values, ",") {
+				id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+				if err != nil {
+					return filter, 0, fmt.Errorf("%s must be a list of integers", key)
+				}
+				*target = append(*target, int32(id))
+			}
+		}
+	}
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = query.Get("last_event_id")
+	}
+	if value == "" {
+		return filter, 0, nil
+	}
+	lastEventID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return filter, 0, errors.New("last event ID must be a non-negative integer")
+	}
+	return filter, lastEventID, nil
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/nbio/st"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/feimaomiao/stalka/live"
+	"github.com/feimaomiao/stalka/pandatypes"
+)
+
+func liveSnapshot(matchID int32, score int32) live.Snapshot {
+	return live.Snapshot{
+		MatchID:      matchID,
+		GameID:       1,
+		OpponentType: pandatypes.OpponentTypeTeam,
+		OpponentIDs:  []int32{1, 2},
+		Scores:       []live.Score{{OpponentID: 1, Score: score}, {OpponentID: 2, Score: 0}},
+		Games:        nil,
+	}
+}
+
+func newLiveServer(t *testing.T) (*httptest.Server, *live.Broadcaster) {
+	t.Helper()
+	broadcaster := live.NewBroadcaster(time.Now())
+	broadcaster.Observe(nil, nil, time.Now())
+	server := &Server{Queries: nil, Live: broadcaster, Logger: zaptest.NewLogger(t).Sugar()}
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+	return httpServer, broadcaster
+}
+
+// readServerSentEvent reads the next event of a stream, skipping heartbeats.
+func readServerSentEvent(t *testing.T, reader *bufio.Reader) (uint64, string, live.Event) {
+	t.Helper()
+	var id uint64
+	var eventType string
+	var event live.Event
+	for {
+		line, err := reader.ReadString('\n')
+		st.Assert(t, err, nil)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id, err = strconv.ParseUint(strings.TrimPrefix(line, "id: "), 10, 64)
+			st.Assert(t, err, nil)
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			st.Assert(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event), nil)
+		case line == "" && eventType != "":
+			return id, eventType, event
+		}
+	}
+}
+
+// waitForSubscribers polls until a handler registered its subscription, so
+// events observed afterwards reach it.
+func waitForSubscribers(broadcaster *live.Broadcaster, count int) {
+	for range 100 {
+		if broadcaster.Subscribers() >= count {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLiveEvents(t *testing.T) {
+	httpServer, broadcaster := newLiveServer(t)
+
+	t.Run("Events are streamed and resumed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+"/api/v1/live/events?team_id=2,5", nil)
+		st.Assert(t, err, nil)
+		resp, err := http.DefaultClient.Do(req)
+		st.Assert(t, err, nil)
+		st.Expect(t, resp.Header.Get("Content-Type"), "text/event-stream")
+		waitForSubscribers(broadcaster, 1)
+
+		broadcaster.Observe([]live.Snapshot{liveSnapshot(1, 0)}, nil, time.Now())
+		broadcaster.Observe([]live.Snapshot{liveSnapshot(1, 1)}, nil, time.Now())
+		reader := bufio.NewReader(resp.Body)
+		firstID, eventType, event := readServerSentEvent(t, reader)
+		st.Expect(t, eventType, live.MatchStarted)
+		st.Expect(t, event.MatchID, int32(1))
+		_, eventType, _ = readServerSentEvent(t, reader)
+		st.Expect(t, eventType, live.ScoreChanged)
+		cancel()
+		resp.Body.Close()
+
+		req, err = http.NewRequestWithContext(t.Context(), http.MethodGet, httpServer.URL+"/api/v1/live/events", nil)
+		st.Assert(t, err, nil)
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(firstID, 10))
+		resp, err = http.DefaultClient.Do(req)
+		st.Assert(t, err, nil)
+		defer resp.Body.Close()
+		id, eventType, _ := readServerSentEvent(t, bufio.NewReader(resp.Body))
+		st.Expect(t, id, firstID+1)
+		st.Expect(t, eventType, live.ScoreChanged)
+	})
+
+	t.Run("Error - invalid filter", func(t *testing.T) {
+		resp, err := http.Get(httpServer.URL + "/api/v1/live/events?game_id=lol")
+		st.Assert(t, err, nil)
+		resp.Body.Close()
+		st.Expect(t, resp.StatusCode, http.StatusBadRequest)
+	})
+}
+
+func TestLiveSocket(t *testing.T) {
+	httpServer, broadcaster := newLiveServer(t)
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/api/v1/live/ws?game_id=1"
+	conn, _, err := websocket.Dial(t.Context(), url, nil) //nolint:bodyclose // the upgrade response has no body
+	st.Assert(t, err, nil)
+	defer conn.CloseNow()
+	waitForSubscribers(broadcaster, 1)
+
+	broadcaster.Observe([]live.Snapshot{liveSnapshot(1, 0)}, nil, time.Now())
+	broadcaster.Observe(nil, nil, time.Now())
+	var event live.Event
+	st.Assert(t, wsjson.Read(t.Context(), conn, &event), nil)
+	st.Expect(t, event.Type, live.MatchStarted)
+	st.Assert(t, wsjson.Read(t.Context(), conn, &event), nil)
+	st.Expect(t, event.Type, live.MatchEnded)
+	st.Expect(t, conn.Close(websocket.StatusNormalClosure, ""), nil)
+}
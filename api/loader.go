@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"maps"
+	"slices"
+)
+
+// loader batches the lookups of one relation within a GraphQL request, like a
+// dataloader: every key asked for while a level of the query is resolved is
+// fetched with a single query once the first of them is needed. Results are
+// kept for the rest of the request. A loader belongs to one request and is not
+// safe for concurrent use; graphql-go resolves a request on one goroutine.
+type loader[V any] struct {
+	fetch   func(ctx context.Context, keys []int32) (map[int32]V, error)
+	pending map[int32]struct{}
+	results map[int32]loaded[V]
+}
+
+// loaded is the outcome of fetching one key.
+type loaded[V any] struct {
+	value V
+	found bool
+	err   error
+}
+
+// newLoader creates a loader.
+// @param fetch - fetches the values of a batch of keys; keys without a value
+// are left out of the map.
+// @returns the loader.
+func newLoader[V any](fetch func(ctx context.Context, keys []int32) (map[int32]V, error)) *loader[V] {
+	return &loader[V]{fetch: fetch, pending: make(map[int32]struct{}), results: make(map[int32]loaded[V])}
+}
+
+// load queues a key and returns a thunk resolving to its value. graphql-go
+// calls the thunks of a level after every resolver of that level ran, so the
+// keys queued by them share one fetch.
+// @param ctx - the context of the request.
+// @param key - the key to look up.
+// @returns a thunk returning the value, nil if the key has none, or the error
+// of the fetch.
+func (l *loader[V]) load(ctx context.Context, key int32) func() (any, error) {
+	if _, ok := l.results[key]; !ok {
+		l.pending[key] = struct{}{}
+	}
+	return func() (any, error) {
+		if _, ok := l.results[key]; !ok {
+			l.flush(ctx)
+		}
+		result := l.results[key]
+		if result.err != nil {
+			return nil, result.err
+		}
+		if !result.found {
+			return nil, nil
+		}
+		return result.value, nil
+	}
+}
+
+// flush fetches every pending key at once.
+func (l *loader[V]) flush(ctx context.Context) {
+	keys := slices.Sorted(maps.Keys(l.pending))
+	clear(l.pending)
+	values, err := l.fetch(ctx, keys)
+	for _, key := range keys {
+		value, found := values[key]
+		l.results[key] = loaded[V]{value: value, found: found, err: err}
+	}
+}
+
+// byKey indexes converted rows by their key.
+// @param rows - the rows of a batch query.
+// @param convert - converts a row.
+// @param key - the key of a converted row.
+// @returns the converted rows by key.
+func byKey[R, V any](rows []R, convert func(R) V, key func(V) int32) map[int32]V {
+	values := make(map[int32]V, len(rows))
+	for _, row := range rows {
+		value := convert(row)
+		values[key(value)] = value
+	}
+	return values
+}
+
+// grouped groups converted rows by the key of their parent, in the order of
+// the rows. Every requested key gets a list, so parents without children
+// resolve to an empty list instead of null.
+// @param keys - the requested parent keys.
+// @param rows - the rows of a batch query.
+// @param convert - converts a row.
+// @param parent - the parent key of a converted row.
+// @returns the converted rows by parent key.
+func grouped[R, V any](keys []int32, rows []R, convert func(R) V, parent func(V) int32) map[int32][]V {
+	values := make(map[int32][]V, len(keys))
+	for _, key := range keys {
+		values[key] = []V{}
+	}
+	for _, row := range rows {
+		value := convert(row)
+		values[parent(value)] = append(values[parent(value)], value)
+	}
+	return values
+}
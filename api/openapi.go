@@ -0,0 +1,403 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification the document of
+// the API follows.
+const OpenAPIVersion = "3.0.3"
+
+// route is an endpoint of the API. Handler registers the routes and OpenAPI
+// documents the same ones, so every endpoint served is documented.
+type route struct {
+	method      string
+	path        string
+	handler     http.HandlerFunc
+	summary     string
+	description string
+	parameters  []parameter
+	// request is a value of the JSON body the endpoint reads, nil for none.
+	request any
+	// success is the response of a handled request.
+	success response
+	// tagged endpoints send an ETag and answer a matching If-None-Match with 304.
+	tagged bool
+	errors []response
+}
+
+// parameter is a path, query or header parameter of a route.
+type parameter struct {
+	name        string
+	in          string
+	description string
+	schema      *openAPISchema
+}
+
+// response is a response of a route.
+type response struct {
+	status      int
+	description string
+	// contentType is the media type of the body, "" for none.
+	contentType string
+	// body is a value of the JSON body, or the schema of a body of another type.
+	body any
+	// message is a value of the JSON messages of a stream, nil for other routes.
+	message any
+}
+
+// openAPIDocument is the OpenAPI document of the API.
+type openAPIDocument struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       openAPIInfo                            `json:"info"`
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components openAPIComponents                      `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Description string                     `json:"description,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Headers     map[string]openAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+	// Message is the schema of the messages of an event stream or a WebSocket,
+	// which OpenAPI has no field for.
+	Message *openAPISchema `json:"x-message-schema,omitempty"`
+}
+
+type openAPIHeader struct {
+	Description string         `json:"description"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+// openAPISchema is the subset of the OpenAPI schema object the API needs.
+type openAPISchema struct {
+	Ref         string                    `json:"$ref,omitempty"`
+	AllOf       []*openAPISchema          `json:"allOf,omitempty"`
+	Type        string                    `json:"type,omitempty"`
+	Format      string                    `json:"format,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	Nullable    bool                      `json:"nullable,omitempty"`
+	Minimum     *int64                    `json:"minimum,omitempty"`
+	Maximum     *int64                    `json:"maximum,omitempty"`
+	Default     any                       `json:"default,omitempty"`
+	Items       *openAPISchema            `json:"items,omitempty"`
+	Properties  map[string]*openAPISchema `json:"properties,omitempty"`
+	Required    []string                  `json:"required,omitempty"`
+	// AdditionalProperties is false for the structs of the API, the schema of
+	// the values of a map, or nil when any property may be sent.
+	AdditionalProperties any `json:"additionalProperties,omitempty"`
+}
+
+// Schemas of parameters and of bodies that are not derived from a Go type.
+var (
+	idSchema      = &openAPISchema{Type: "integer", Format: "int32"}
+	timeSchema    = &openAPISchema{Type: "string", Format: "date-time"}
+	booleanSchema = &openAPISchema{Type: "boolean"}
+	stringSchema  = &openAPISchema{Type: "string"}
+	eventIDSchema = &openAPISchema{Type: "integer", Format: "int64", Minimum: bound(0)}
+	// graphQLResponseSchema is the body of a GraphQL response. Its data follows
+	// the query; the GraphQL schema itself is served by introspection.
+	graphQLResponseSchema = &openAPISchema{
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"data": {Type: "object", Nullable: true},
+			"errors": {Type: "array", Items: &openAPISchema{
+				Type:       "object",
+				Properties: map[string]*openAPISchema{"message": stringSchema},
+				Required:   []string{"message"},
+			}},
+		},
+	}
+	documentSchema = &openAPISchema{Type: "object", Description: "An OpenAPI " + OpenAPIVersion + " document."}
+)
+
+// pageParameters are the window of every list endpoint, see parsePage.
+var pageParameters = []parameter{
+	{name: "limit", in: "query", description: "How many items to return.", schema: &openAPISchema{
+		Type: "integer", Minimum: bound(1), Maximum: bound(MaxPageSize), Default: DefaultPageSize,
+	}},
+	{name: "offset", in: "query", description: "How many items to skip.", schema: &openAPISchema{
+		Type: "integer", Format: "int32", Minimum: bound(0), Default: 0,
+	}},
+}
+
+// errorResponse documents a response written by writeError.
+func errorResponse(status int, description string) response {
+	return jsonResponse(status, description, errorBody{})
+}
+
+// jsonResponse documents a JSON response.
+func jsonResponse(status int, description string, body any) response {
+	return response{status: status, description: description, contentType: "application/json", body: body, message: nil}
+}
+
+// OpenAPI generates the OpenAPI document of the endpoints of the server. The
+// schemas of the bodies are derived from the Go types the handlers encode.
+// @returns the document as JSON.
+func (server *Server) OpenAPI() []byte {
+	// The document only holds strings, numbers, maps and slices, so it always marshals.
+	document, _ := json.Marshal(openAPIOf(server.routes()))
+	return document
+}
+
+// serveOpenAPI serves the OpenAPI document of the server.
+func (server *Server) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeTagged(w, r, "application/json", append(server.OpenAPI(), '\n'))
+}
+
+// openAPIOf generates the document of a list of routes.
+func openAPIOf(routes []route) openAPIDocument {
+	generator := schemaGenerator{components: make(map[string]*openAPISchema), types: make(map[string]reflect.Type)}
+	document := openAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info: openAPIInfo{
+			Title:       "stalka",
+			Description: "Read API over the esports data stalka syncs from PandaScore.",
+			Version:     "v1",
+		},
+		Paths:      make(map[string]map[string]openAPIOperation),
+		Components: openAPIComponents{Schemas: generator.components},
+	}
+	for _, r := range routes {
+		if document.Paths[r.path] == nil {
+			document.Paths[r.path] = make(map[string]openAPIOperation)
+		}
+		document.Paths[r.path][strings.ToLower(r.method)] = generator.operation(r)
+	}
+	return document
+}
+
+// schemaGenerator derives schemas from Go types. Named structs become
+// components, referenced by their name.
+type schemaGenerator struct {
+	components map[string]*openAPISchema
+	types      map[string]reflect.Type
+}
+
+// operation documents a route.
+func (g schemaGenerator) operation(r route) openAPIOperation {
+	operation := openAPIOperation{
+		OperationID: operationID(r),
+		Summary:     r.summary,
+		Description: r.description,
+		Parameters:  nil,
+		RequestBody: nil,
+		Responses:   make(map[string]openAPIResponse),
+	}
+	for _, param := range r.parameters {
+		operation.Parameters = append(operation.Parameters, openAPIParameter{
+			Name: param.name, In: param.in, Description: param.description,
+			Required: param.in == "path", Schema: param.schema,
+		})
+	}
+	if r.request != nil {
+		operation.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  map[string]openAPIMediaType{"application/json": {Schema: g.bodySchema(r.request)}},
+		}
+	}
+	success := g.response(r.success)
+	if r.tagged {
+		operation.Parameters = append(operation.Parameters, openAPIParameter{
+			Name: "If-None-Match", In: "header", Description: "The ETag of a previous response.",
+			Required: false, Schema: stringSchema,
+		})
+		success.Headers = map[string]openAPIHeader{"ETag": {Description: "The tag of the body.", Schema: stringSchema}}
+		operation.Responses[strconv.Itoa(http.StatusNotModified)] = openAPIResponse{
+			Description: "The body still has the tag of If-None-Match.", Headers: nil, Content: nil, Message: nil,
+		}
+	}
+	operation.Responses[strconv.Itoa(r.success.status)] = success
+	for _, failure := range r.errors {
+		operation.Responses[strconv.Itoa(failure.status)] = g.response(failure)
+	}
+	return operation
+}
+
+// response documents a response.
+func (g schemaGenerator) response(r response) openAPIResponse {
+	documented := openAPIResponse{Description: r.description, Headers: nil, Content: nil, Message: nil}
+	if r.contentType != "" {
+		documented.Content = map[string]openAPIMediaType{r.contentType: {Schema: g.bodySchema(r.body)}}
+	}
+	if r.message != nil {
+		documented.Message = g.schemaOf(reflect.TypeOf(r.message))
+	}
+	return documented
+}
+
+// bodySchema returns the schema of a body: the schema itself, or the one
+// derived from the type of a value.
+func (g schemaGenerator) bodySchema(body any) *openAPISchema {
+	if schema, ok := body.(*openAPISchema); ok {
+		return schema
+	}
+	return g.schemaOf(reflect.TypeOf(body))
+}
+
+// schemaOf derives the schema of the JSON encoding of a type.
+func (g schemaGenerator) schemaOf(t reflect.Type) *openAPISchema {
+	if t == reflect.TypeFor[time.Time]() {
+		return timeSchema
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		// A nil pointer is encoded as null. A reference cannot be marked
+		// nullable itself, so it is wrapped in allOf.
+		elem := g.schemaOf(t.Elem())
+		if elem.Ref != "" {
+			return &openAPISchema{AllOf: []*openAPISchema{elem}, Nullable: true}
+		}
+		nullable := *elem
+		nullable.Nullable = true
+		return &nullable
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int32:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64", Minimum: bound(0)}
+	case reflect.Float64:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case reflect.Slice:
+		return &openAPISchema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	default:
+		// Interfaces hold any JSON value.
+		return &openAPISchema{}
+	}
+}
+
+// structSchema derives the schema of a struct. Named structs are added to the
+// components; generic ones, such as Page[Game], are inlined.
+func (g schemaGenerator) structSchema(t reflect.Type) *openAPISchema {
+	name := componentName(t)
+	if name == "" {
+		return g.objectSchema(t)
+	}
+	reference := &openAPISchema{Ref: "#/components/schemas/" + name}
+	if known, ok := g.types[name]; ok {
+		if known != t {
+			// The types of the API are fixed, so this is a programming error.
+			panic("openapi: two types are named " + name)
+		}
+		return reference
+	}
+	g.types[name] = t
+	g.components[name] = g.objectSchema(t)
+	return reference
+}
+
+// objectSchema lists the fields of a struct as encoding/json encodes them.
+// Fields without omitempty are always sent, and no other fields are.
+func (g schemaGenerator) objectSchema(t reflect.Type) *openAPISchema {
+	schema := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema), AdditionalProperties: false}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = g.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	slices.Sort(schema.Required)
+	return schema
+}
+
+// componentName names the component of a struct after its type, capitalized
+// and prefixed with its package outside of api, e.g. LiveEvent for live.Event.
+// @returns the name, or "" for anonymous and generic structs.
+func componentName(t reflect.Type) string {
+	name := t.Name()
+	if name == "" || strings.Contains(name, "[") {
+		return ""
+	}
+	if t.PkgPath() != reflect.TypeFor[Server]().PkgPath() {
+		name = capitalize(path.Base(t.PkgPath())) + capitalize(name)
+	}
+	return capitalize(name)
+}
+
+// operationID derives the operationId of a route from its method and path,
+// e.g. getGamesGameIDLeagues for GET /api/v1/games/{gameID}/leagues.
+func operationID(r route) string {
+	id := strings.ToLower(r.method)
+	for segment := range strings.SplitSeq(strings.TrimPrefix(r.path, "/api/v1"), "/") {
+		segment = strings.Trim(strings.TrimSuffix(segment, ".json"), "{}")
+		if segment != "" {
+			id += capitalize(segment)
+		}
+	}
+	return id
+}
+
+// capitalize upper-cases the first letter of a name.
+func capitalize(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// bound returns a pointer to a minimum or maximum.
+func bound(value int64) *int64 {
+	return &value
+}
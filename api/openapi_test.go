@@ -0,0 +1,507 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/live"
+)
+
+// pathParameter matches the path parameters of a path template.
+var pathParameter = regexp.MustCompile(`\{[^}]+\}`)
+
+// newContractServer serves every endpoint, reading from a mock database.
+func newContractServer(t *testing.T) (*http.ServeMux, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	t.Cleanup(mockDB.Close)
+	server := &Server{
+		Queries: dbtypes.New(mockDB), Live: live.NewBroadcaster(time.Now()), SelectionToken: testSelectionToken,
+		Logger: zaptest.NewLogger(t).Sugar(),
+	}
+	mux, ok := server.Handler().(*http.ServeMux)
+	st.Assert(t, ok, true)
+	return mux, mockDB
+}
+
+// openAPIDocumentOf fetches the document a handler serves, decoded the way a
+// client decodes it.
+func openAPIDocumentOf(t *testing.T, handler http.Handler) map[string]any {
+	t.Helper()
+	rec := get(handler, "/openapi.json", nil)
+	st.Assert(t, rec.Code, http.StatusOK)
+	st.Expect(t, rec.Header().Get("Content-Type"), "application/json")
+	var document map[string]any
+	st.Assert(t, json.Unmarshal(rec.Body.Bytes(), &document), nil)
+	st.Expect(t, document["openapi"], OpenAPIVersion)
+	return document
+}
+
+// object returns the member of a JSON object, failing the test if it is missing.
+func object(t *testing.T, value any, keys ...string) map[string]any {
+	t.Helper()
+	for _, key := range keys {
+		parent, ok := value.(map[string]any)
+		st.Assert(t, ok, true)
+		value, ok = parent[key]
+		if !ok {
+			t.Fatalf("the document has no %s", strings.Join(keys, "."))
+		}
+	}
+	member, ok := value.(map[string]any)
+	st.Assert(t, ok, true)
+	return member
+}
+
+// operations lists the operations of a document as "METHOD /path" with the operation.
+func operations(t *testing.T, document map[string]any) map[string]map[string]any {
+	t.Helper()
+	listed := make(map[string]map[string]any)
+	for path, item := range object(t, document, "paths") {
+		for method, operation := range item.(map[string]any) {
+			listed[strings.ToUpper(method)+" "+path] = operation.(map[string]any)
+		}
+	}
+	return listed
+}
+
+// validate checks a decoded JSON value against a schema of the document.
+// @returns a description of every mismatch, empty when the value conforms.
+func validate(document map[string]any, schema map[string]any, value any, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		component, _ := document["components"].(map[string]any)["schemas"].(map[string]any)[name].(map[string]any)
+		if component == nil {
+			return []string{at + ": unknown schema " + ref}
+		}
+		return validate(document, component, value, at)
+	}
+	if value == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{at + ": null is not nullable"}
+	}
+	var mismatches []string
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, sub := range allOf {
+			mismatches = append(mismatches, validate(document, sub.(map[string]any), value, at)...)
+		}
+	}
+	switch schema["type"] {
+	case "object":
+		members, ok := value.(map[string]any)
+		if !ok {
+			return append(mismatches, fmt.Sprintf("%s: %v is not an object", at, value))
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, member := range members {
+			property, known := properties[name].(map[string]any)
+			if !known {
+				if additional, isSchema := schema["additionalProperties"].(map[string]any); isSchema {
+					mismatches = append(mismatches, validate(document, additional, member, at+"."+name)...)
+				} else if schema["additionalProperties"] == false {
+					mismatches = append(mismatches, at+": undocumented property "+name)
+				}
+				continue
+			}
+			mismatches = append(mismatches, validate(document, property, member, at+"."+name)...)
+		}
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok = members[name.(string)]; !ok {
+				mismatches = append(mismatches, fmt.Sprintf("%s: missing required property %s", at, name))
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return append(mismatches, fmt.Sprintf("%s: %v is not an array", at, value))
+		}
+		itemSchema, _ := schema["items"].(map[string]any)
+		for i, item := range items {
+			mismatches = append(mismatches, validate(document, itemSchema, item, at+"["+strconv.Itoa(i)+"]")...)
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return append(mismatches, fmt.Sprintf("%s: %v is not an integer", at, value))
+		}
+		if minimum, hasMinimum := schema["minimum"].(float64); hasMinimum && number < minimum {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %v is below %v", at, number, minimum))
+		}
+		if maximum, hasMaximum := schema["maximum"].(float64); hasMaximum && number > maximum {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %v is above %v", at, number, maximum))
+		}
+		if schema["format"] == "int32" && (number < math.MinInt32 || number > math.MaxInt32) {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %v is not an int32", at, number))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %v is not a number", at, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %v is not a boolean", at, value))
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return append(mismatches, fmt.Sprintf("%s: %v is not a string", at, value))
+		}
+		if _, err := time.Parse(time.RFC3339, text); schema["format"] == "date-time" && err != nil {
+			mismatches = append(mismatches, fmt.Sprintf("%s: %q is not a date-time", at, text))
+		}
+	}
+	return mismatches
+}
+
+// checkResponse checks a response against the operation of the route that
+// served it: its status must be documented, and its body must match the
+// documented schema.
+func checkResponse(t *testing.T, document map[string]any, mux *http.ServeMux, req *http.Request,
+	rec *httptest.ResponseRecorder) {
+	t.Helper()
+	_, pattern := mux.Handler(req)
+	operation, ok := operations(t, document)[pattern]
+	if !ok {
+		t.Fatalf("%s is not documented", pattern)
+	}
+	documented, ok := object(t, operation, "responses")[strconv.Itoa(rec.Code)].(map[string]any)
+	if !ok {
+		t.Fatalf("%s answered %d, which is not documented", pattern, rec.Code)
+	}
+	content, _ := documented["content"].(map[string]any)
+	if len(content) == 0 {
+		st.Expect(t, rec.Body.Len(), 0)
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	st.Assert(t, err, nil)
+	media, ok := content[mediaType].(map[string]any)
+	if !ok {
+		t.Fatalf("%s answered %d with %s, which is not documented", pattern, rec.Code, mediaType)
+	}
+	if mediaType != "application/json" {
+		return
+	}
+	var body any
+	st.Assert(t, json.Unmarshal(rec.Body.Bytes(), &body), nil)
+	st.Expect(t, validate(document, media["schema"].(map[string]any), body, "body"), []string(nil))
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	t.Run("Every documented operation is served by its route", func(t *testing.T) {
+		mux, _ := newContractServer(t)
+		listed := operations(t, openAPIDocumentOf(t, mux))
+		for operation := range listed {
+			method, path, _ := strings.Cut(operation, " ")
+			req := httptest.NewRequest(method, pathParameter.ReplaceAllString(path, "1"), nil)
+			_, pattern := mux.Handler(req)
+			st.Expect(t, pattern, operation)
+		}
+		// Handler only registers the routes, so this covers every endpoint served.
+		server := &Server{
+			Queries: dbtypes.New(nil), Live: live.NewBroadcaster(time.Now()), SelectionToken: testSelectionToken,
+			Logger: nil,
+		}
+		st.Expect(t, len(listed), len(server.routes()))
+	})
+
+	t.Run("Endpoints that are not served are not documented", func(t *testing.T) {
+		server := &Server{Queries: nil, Live: nil, Logger: zaptest.NewLogger(t).Sugar()}
+		listed := operations(t, openAPIDocumentOf(t, server.Handler()))
+		st.Expect(t, len(listed), 1)
+		_, ok := listed["GET /openapi.json"]
+		st.Expect(t, ok, true)
+	})
+
+	t.Run("Operation IDs are unique", func(t *testing.T) {
+		mux, _ := newContractServer(t)
+		seen := make(map[string]bool)
+		for _, operation := range operations(t, openAPIDocumentOf(t, mux)) {
+			id, _ := operation["operationId"].(string)
+			st.Expect(t, seen[id], false)
+			seen[id] = true
+		}
+	})
+}
+
+func TestOpenAPIResponsesMatchSchemas(t *testing.T) {
+	mux, mockDB := newContractServer(t)
+	document := openAPIDocumentOf(t, mux)
+	start := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	finished := fixtureMatch(2, start)
+	finished.Finished = true
+	finished.WinnerID = pgtype.Int4{Int32: 1, Valid: true}
+	finished.BeginAt = pgtype.Timestamptz{Time: start, InfinityModifier: pgtype.Finite, Valid: true}
+	valueList := []byte(`[{"type":"game","id":1}]`)
+
+	for _, test := range []struct {
+		name   string
+		method string
+		target string
+		body   string
+		header http.Header
+		expect func()
+		status int
+	}{
+		{
+			name: "games", method: http.MethodGet, target: "/api/v1/games", status: http.StatusOK,
+			expect: func() {
+				mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games").WithArgs(int32(DefaultPageSize+1), int32(0)).WillReturnRows(
+					pgxmock.NewRows(gameColumns).
+						AddRow(int32(1), "LoL", pgtype.Text{String: "league-of-legends", Valid: true},
+							pgtype.Timestamptz{}, pgtype.Timestamptz{}).
+						AddRow(int32(4), "Dota 2", pgtype.Text{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}))
+			},
+		},
+		{
+			name: "leagues", method: http.MethodGet, target: "/api/v1/games/1/leagues", status: http.StatusOK,
+			expect: func() {
+				mockDB.ExpectQuery("SELECT l.id, l.name").WithArgs(int32(1)).WillReturnRows(
+					pgxmock.NewRows(leagueColumns).AddRow(int32(3), "LCK", pgtype.Text{}, int32(1),
+						pgtype.Text{String: "https://cdn/lck.png", Valid: true},
+						pgtype.Timestamptz{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}, nil))
+			},
+		},
+		{
+			name: "series", method: http.MethodGet, target: "/api/v1/games/1/series", status: http.StatusOK,
+			expect: func() {
+				mockDB.ExpectQuery("FROM series").WithArgs(int32(1), int32(DefaultPageSize+1), int32(0)).WillReturnRows(
+					pgxmock.NewRows(seriesColumns).
+						AddRow(int32(4), "Spring 2026", pgtype.Text{}, int32(1), int32(3),
+							pgtype.Timestamptz{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}))
+			},
+		},
+		{
+			name: "tournaments", method: http.MethodGet, target: "/api/v1/games/1/tournaments", status: http.StatusOK,
+			expect: func() {
+				mockDB.ExpectQuery("FROM tournaments WHERE game_id").WithArgs(int32(1), int32(DefaultPageSize+1), int32(0)).WillReturnRows(
+					pgxmock.NewRows(tournamentColumns).
+						AddRow(int32(5), "Playoffs", pgtype.Text{}, pgtype.Int4{Int32: 1, Valid: true},
+							int32(1), int32(3), int32(4), pgtype.Timestamptz{}, pgtype.Timestamptz{},
+							pgtype.Timestamptz{}, nil).
+						AddRow(int32(6), "Groups", pgtype.Text{}, pgtype.Int4{}, int32(1), int32(3), int32(4),
+							pgtype.Timestamptz{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}, nil))
+			},
+		},
+		{
+			name: "matches", method: http.MethodGet, target: "/api/v1/matches?limit=2", status: http.StatusOK,
+			expect: func() {
+				mockDB.ExpectQuery("FROM matches").WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+					pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+					WillReturnRows(matchRows(fixtureMatch(1, start), finished))
+			},
+		},
+		{
+			name: "invalid window", method: http.MethodGet, target: "/api/v1/games?limit=0",
+			status: http.StatusBadRequest, expect: func() {},
+		},
+		{
+			name: "database failure", method: http.MethodGet, target: "/api/v1/games",
+			status: http.StatusInternalServerError,
+			expect: func() {
+				mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games").
+					WithArgs(int32(DefaultPageSize+1), int32(0)).
+					WillReturnError(errors.New("database error"))
+			},
+		},
+		{
+			name: "unchanged games", method: http.MethodGet, target: "/api/v1/games", status: http.StatusNotModified,
+			header: http.Header{"If-None-Match": {"*"}},
+			expect: func() {
+				mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games").
+					WithArgs(int32(DefaultPageSize+1), int32(0)).
+					WillReturnRows(pgxmock.NewRows(gameColumns))
+			},
+		},
+		{
+			name: "GraphQL query", method: http.MethodPost, target: "/api/v1/graphql", status: http.StatusOK,
+			body: `{"query": "{ games { id name } }"}`,
+			expect: func() {
+				mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games").
+					WithArgs(int32(DefaultPageSize+1), int32(0)).
+					WillReturnRows(pgxmock.NewRows(gameColumns).
+						AddRow(int32(1), "LoL", pgtype.Text{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}))
+			},
+		},
+		{
+			name: "malformed GraphQL query", method: http.MethodPost, target: "/api/v1/graphql",
+			status: http.StatusBadRequest, body: `{"query": "{ games {"}`, expect: func() {},
+		},
+		{
+			name: "saved selection", method: http.MethodPost, target: "/api/v1/selections", status: http.StatusCreated,
+			body:   `{"items": [{"type": "game", "id": 1}]}`,
+			header: http.Header{"Authorization": {"Bearer " + testSelectionToken}},
+			expect: func() {
+				mockDB.ExpectQuery("SELECT id FROM games").WithArgs([]int32{1}).
+					WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(1)))
+				mockDB.ExpectQuery("INSERT INTO url_mappings").WithArgs(pgxmock.AnyArg(), valueList).
+					WillReturnRows(pgxmock.NewRows([]string{"value_list"}).AddRow(valueList))
+			},
+		},
+		{
+			name: "invalid selection", method: http.MethodPost, target: "/api/v1/selections",
+			status: http.StatusBadRequest, body: `{"items": []}`,
+			header: http.Header{"Authorization": {"Bearer " + testSelectionToken}}, expect: func() {},
+		},
+		{
+			name: "unauthenticated selection", method: http.MethodPost, target: "/api/v1/selections",
+			status: http.StatusUnauthorized, body: `{"items": [{"type": "game", "id": 1}]}`, expect: func() {},
+		},
+		{
+			name: "selection", method: http.MethodGet, target: "/api/v1/selections/21380158dc3c4474",
+			status: http.StatusOK,
+			expect: func() {
+				mockDB.ExpectQuery("UPDATE url_mappings").WithArgs("21380158dc3c4474").
+					WillReturnRows(pgxmock.NewRows([]string{"value_list"}).AddRow(valueList))
+				mockDB.ExpectQuery("SELECT id FROM games").WithArgs([]int32{1}).
+					WillReturnRows(pgxmock.NewRows([]string{"id"}))
+			},
+		},
+		{
+			name: "unknown selection", method: http.MethodGet, target: "/api/v1/selections/missing",
+			status: http.StatusNotFound,
+			expect: func() {
+				mockDB.ExpectQuery("UPDATE url_mappings").WithArgs("missing").WillReturnError(pgx.ErrNoRows)
+			},
+		},
+		{
+			name: "calendar", method: http.MethodGet, target: "/api/v1/calendars/games/1.ics", status: http.StatusOK,
+			expect: func() {
+				mockDB.ExpectQuery("FROM matches").WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+					pgxmock.AnyArg(), pgxmock.AnyArg()).WillReturnRows(pgxmock.NewRows(calendarColumns))
+			},
+		},
+		{
+			name: "OpenAPI document", method: http.MethodGet, target: "/openapi.json", status: http.StatusOK,
+			expect: func() {},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.expect()
+			req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			for key, values := range test.header {
+				req.Header[key] = values
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			st.Assert(t, rec.Code, test.status)
+			checkResponse(t, document, mux, req, rec)
+			st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+		})
+	}
+
+	t.Run("Error - bodies that drifted from the schema", func(t *testing.T) {
+		game := map[string]any{"$ref": "#/components/schemas/Game"}
+		for body, mismatch := range map[string]string{
+			`{"id": 1, "name": "LoL"}`:                          "body: missing required property slug",
+			`{"id": 1, "name": "LoL", "slug": null, "tier": 1}`: "body: undocumented property tier",
+			`{"id": "1", "name": "LoL", "slug": null}`:          "body.id: 1 is not an integer",
+			`{"id": 1, "name": null, "slug": null}`:             "body.name: null is not nullable",
+			`{"id": 4294967296, "name": "LoL", "slug": "lol"}`:  "body.id: 4.294967296e+09 is not an int32",
+		} {
+			var decoded any
+			st.Assert(t, json.Unmarshal([]byte(body), &decoded), nil)
+			st.Expect(t, validate(document, game, decoded, "body"), []string{mismatch})
+		}
+	})
+
+	t.Run("live events", func(t *testing.T) {
+		broadcaster := live.NewBroadcaster(start)
+		// The first poll only sets the baseline.
+		broadcaster.Observe(nil, nil, start)
+		events := broadcaster.Observe([]live.Snapshot{liveSnapshot(1, 0)}, nil, start)
+		events = append(events, broadcaster.Observe([]live.Snapshot{liveSnapshot(1, 1)}, nil, start)...)
+		events = append(events, broadcaster.Observe(nil, nil, start)...)
+		st.Assert(t, len(events) >= 3, true)
+		operation := operations(t, document)["GET /api/v1/live/events"]
+		schema := object(t, operation, "responses", "200", "x-message-schema")
+		for _, event := range events {
+			encoded, err := json.Marshal(event)
+			st.Assert(t, err, nil)
+			var body any
+			st.Assert(t, json.Unmarshal(encoded, &body), nil)
+			st.Expect(t, validate(document, schema, body, event.Type), []string(nil))
+		}
+	})
+}
+
+func TestOpenAPIParametersAreValidated(t *testing.T) {
+	mux, mockDB := newContractServer(t)
+	document := openAPIDocumentOf(t, mux)
+
+	// Every typed parameter a client may send is checked before any query runs.
+	for name, operation := range operations(t, document) {
+		method, path, _ := strings.Cut(name, " ")
+		if method != http.MethodGet {
+			continue
+		}
+		parameters, _ := operation["parameters"].([]any)
+		for _, value := range parameters {
+			param := value.(map[string]any)
+			schema := param["schema"].(map[string]any)
+			if items, ok := schema["items"].(map[string]any); ok {
+				schema = items
+			}
+			if schema["type"] != "integer" && schema["type"] != "boolean" && schema["format"] != "date-time" {
+				continue
+			}
+			paramName, _ := param["name"].(string)
+			t.Run(name+" "+paramName, func(t *testing.T) {
+				target := pathParameter.ReplaceAllStringFunc(path, func(segment string) string {
+					if segment == "{"+paramName+"}" {
+						return "invalid"
+					}
+					return "1"
+				})
+				req := httptest.NewRequest(method, target, nil)
+				switch param["in"] {
+				case "query":
+					req.URL.RawQuery = paramName + "=invalid"
+				case "header":
+					req.Header.Set(paramName, "invalid")
+				}
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+				st.Expect(t, rec.Code, http.StatusBadRequest)
+				checkResponse(t, document, mux, req, rec)
+				st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+			})
+		}
+	}
+
+	// Every documented page size is accepted and the next one is not.
+	limit := object(t, document, "paths", "/api/v1/games", "get")["parameters"].([]any)[0].(map[string]any)
+	st.Expect(t, limit["name"], "limit")
+	maximum, _ := object(t, limit, "schema")["maximum"].(float64)
+	for size, status := range map[int]int{int(maximum): http.StatusOK, int(maximum) + 1: http.StatusBadRequest} {
+		if status == http.StatusOK {
+			mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games").
+				WithArgs(int32(size+1), int32(0)).
+				WillReturnRows(pgxmock.NewRows(gameColumns))
+		}
+		rec := get(mux, "/api/v1/games?limit="+strconv.Itoa(size), nil)
+		st.Expect(t, rec.Code, status)
+	}
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// DefaultPageSize is the page size of a list endpoint when limit is unset.
+	DefaultPageSize = 50
+	// MaxPageSize caps the limit a client may ask for.
+	MaxPageSize = 200
+)
+
+// Page is the envelope of every list endpoint.
+type Page[T any] struct {
+	Data    []T  `json:"data"`
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	HasMore bool `json:"has_more"`
+}
+
+// pageParams is the window of a list endpoint, read from limit and offset.
+type pageParams struct {
+	Limit  int
+	Offset int
+}
+
+// errorBody is the body of every error response.
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// parsePage reads limit and offset from the query string.
+// @param r - the request.
+// @returns the window and an error if a value is not a valid non-negative integer.
+func parsePage(r *http.Request) (pageParams, error) {
+	page := pageParams{Limit: DefaultPageSize, Offset: 0}
+	query := r.URL.Query()
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxPageSize {
+			return page, errors.New("limit must be an integer between 1 and " + strconv.Itoa(MaxPageSize))
+		}
+		page.Limit = limit
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || offset < 0 {
+			return page, errors.New("offset must be a non-negative 32-bit integer")
+		}
+		page.Offset = int(offset)
+	}
+	return page, nil
+}
+
+// maxRows is the row limit of a query building the page with windowed: one row
+// more than the page tells whether another page follows.
+func (page pageParams) maxRows() int32 {
+	return int32(page.Limit + 1) //nolint:gosec // bounded by MaxPageSize
+}
+
+// skipRows is the row offset of a query building the page with windowed.
+func (page pageParams) skipRows() int32 {
+	return int32(page.Offset) //nolint:gosec // parsePage and graphQLWindow keep it within int32
+}
+
+// paginate cuts the window out of a complete list, for the short lists that are
+// read whole, like the leagues of a game.
+// @param items - every item, in order.
+// @param page - the window.
+// @returns the page of items.
+func paginate[T any](items []T, page pageParams) Page[T] {
+	start := min(page.Offset, len(items))
+	end := min(start+page.Limit, len(items))
+	return Page[T]{Data: items[start:end], Limit: page.Limit, Offset: page.Offset, HasMore: end < len(items)}
+}
+
+// windowed builds the page of a query that was asked for one row more than the
+// limit, so whether another page follows is known without counting.
+// @param items - up to page.Limit+1 items starting at page.Offset.
+// @param page - the window.
+// @returns the page of items.
+func windowed[T any](items []T, page pageParams) Page[T] {
+	hasMore := len(items) > page.Limit
+	if hasMore {
+		items = items[:page.Limit]
+	}
+	return Page[T]{Data: items, Limit: page.Limit, Offset: page.Offset, HasMore: hasMore}
+}
+
+// writeJSON writes v as the JSON body of a response, tagged with an ETag of its
+// content. A request whose If-None-Match carries the same tag gets an empty 304.
+// @param w - the response writer.
+// @param r - the request.
+// @param v - the body.
+func writeJSON(w http.ResponseWriter, r *http.Request, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	writeTagged(w, r, "application/json", append(body, '\n'))
+}
+
+// writeTagged writes a body tagged with an ETag of its content. A request whose
+// If-None-Match carries the same tag gets an empty 304.
+// @param w - the response writer.
+// @param r - the request.
+// @param contentType - the media type of the body.
+// @param body - the body.
+func writeTagged(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(body)
+}
+
+// etagMatches reports whether an If-None-Match header lists the tag. Weak
+// comparison is used, as RFC 9110 asks for If-None-Match.
+func etagMatches(header string, etag string) bool {
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError writes an error response.
+// @param w - the response writer.
+// @param status - the HTTP status code.
+// @param message - the message shown to the client.
+func writeError(w http.ResponseWriter, status int, message string) {
+	body, _ := json.Marshal(errorBody{Error: message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// text returns the value of a nullable text column, nil when NULL.
+func text(value pgtype.Text) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+// int4 returns the value of a nullable integer column, nil when NULL.
+func int4(value pgtype.Int4) *int32 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Int32
+}
+
+// timestamp returns the value of a nullable time column in UTC, nil when NULL.
+func timestamp(value pgtype.Timestamptz) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	utc := value.Time.UTC()
+	return &utc
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/feimaomiao/stalka/shortlink"
+)
+
+const (
+	// MaxSelectionRequestSize caps the body of a request saving a selection.
+	MaxSelectionRequestSize = 16 << 10
+	// MinSelectionTokenLength keeps selection_token from being guessable.
+	MinSelectionTokenLength = 32
+)
+
+// Selection is a selection saved in URL_MAPPINGS as returned by the API.
+type Selection struct {
+	Key   string           `json:"key"`
+	Items []shortlink.Item `json:"items"`
+	// Missing lists the items whose game, league or team no longer exists.
+	Missing []shortlink.Item `json:"missing"`
+	// Feed is the path of the calendar feed of the selection.
+	Feed string `json:"feed"`
+}
+
+// selectionRequest is the body of a request saving a selection.
+type selectionRequest struct {
+	Items []shortlink.Item `json:"items"`
+}
+
+// LoadSelectionToken reads selection_token.
+// @param getenv - looks up an environment variable, usually os.Getenv.
+// @returns the token, empty when unset, and an error if it is shorter than MinSelectionTokenLength.
+func LoadSelectionToken(getenv func(string) string) (string, error) {
+	token := getenv("selection_token")
+	if token != "" && len(token) < MinSelectionTokenLength {
+		return "", fmt.Errorf("selection_token must be at least %d characters long", MinSelectionTokenLength)
+	}
+	return token, nil
+}
+
+// createSelection saves the selection of the body under its hashed key. The
+// key only depends on the selection, so saving it again returns the same key.
+// Only clients with the SelectionToken may write to URL_MAPPINGS.
+func (server *Server) createSelection(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || server.SelectionToken == "" ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(server.SelectionToken)) != 1 {
+		server.Logger.Warnf("Rejected unauthenticated selection request from %s", r.RemoteAddr)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="stalka selections"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid token")
+		return
+	}
+	var request selectionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxSelectionRequestSize)).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "the body must be a JSON object with items")
+		return
+	}
+	if _, err := shortlink.Normalize(request.Items); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	key, items, err := shortlink.Store{Queries: server.Queries}.Save(r.Context(), request.Items)
+	var missing *shortlink.MissingError
+	switch {
+	case errors.As(err, &missing):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case errors.Is(err, shortlink.ErrCollision):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		server.internalError(w, "selection", err)
+		return
+	}
+	body, err := json.Marshal(selectionOf(key, items, []shortlink.Item{}))
+	if err != nil {
+		server.internalError(w, "selection "+key, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// getSelection serves a saved selection and the items of it that no longer
+// exist. Like a fetch of its feed, it counts as an access of the selection.
+func (server *Server) getSelection(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	items, ok := server.resolveSelection(w, r, key)
+	if !ok {
+		return
+	}
+	missing, err := shortlink.Store{Queries: server.Queries}.Missing(r.Context(), items)
+	if err != nil {
+		server.internalError(w, "selection "+key, err)
+		return
+	}
+	if missing == nil {
+		missing = []shortlink.Item{}
+	}
+	writeJSON(w, r, selectionOf(key, items, missing))
+}
+
+// resolveSelection reads a saved selection, and answers 404 or 500 itself
+// when it cannot.
+// @returns the selection and whether the request may proceed.
+func (server *Server) resolveSelection(w http.ResponseWriter, r *http.Request, key string) ([]shortlink.Item, bool) {
+	items, err := shortlink.Store{Queries: server.Queries}.Resolve(r.Context(), key)
+	if errors.Is(err, shortlink.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		server.internalError(w, "selection "+key, err)
+		return nil, false
+	}
+	return items, true
+}
+
+// selectionOf builds the response of a selection.
+func selectionOf(key string, items []shortlink.Item, missing []shortlink.Item) Selection {
+	return Selection{Key: key, Items: items, Missing: missing, Feed: "/api/v1/calendars/selections/" + key + ".ics"}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/shortlink"
+)
+
+// testSelectionToken is the SelectionToken of the test servers.
+const testSelectionToken = "0123456789abcdef0123456789abcdef"
+
+// postSelection posts the body of a request saving a selection with the token.
+func postSelection(handler http.Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/selections", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testSelectionToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeSelection(t *testing.T, rec *httptest.ResponseRecorder) Selection {
+	t.Helper()
+	var selection Selection
+	st.Assert(t, json.Unmarshal(rec.Body.Bytes(), &selection), nil)
+	return selection
+}
+
+func TestCreateSelection(t *testing.T) {
+	handler, mockDB := newTestServer(t)
+	valueList := []byte(`[{"type":"game","id":1},{"type":"team","id":126061}]`)
+
+	t.Run("Saves the selection under its key", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT id FROM games").WithArgs([]int32{1}).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(1)))
+		mockDB.ExpectQuery("SELECT id FROM teams").WithArgs([]int32{126061}).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(126061)))
+		mockDB.ExpectQuery("INSERT INTO url_mappings").WithArgs("21380158dc3c4474", valueList).
+			WillReturnRows(pgxmock.NewRows([]string{"value_list"}).AddRow(valueList))
+
+		rec := postSelection(handler, `{"items": [{"type": "team", "id": 126061}, {"type": "game", "id": 1}]}`)
+		st.Assert(t, rec.Code, http.StatusCreated)
+		st.Expect(t, decodeSelection(t, rec), Selection{
+			Key:     "21380158dc3c4474",
+			Items:   []shortlink.Item{{Type: "game", ID: 1}, {Type: "team", ID: 126061}},
+			Missing: []shortlink.Item{},
+			Feed:    "/api/v1/calendars/selections/21380158dc3c4474.ics",
+		})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - unknown IDs", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT id FROM teams").WithArgs([]int32{5}).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+		rec := postSelection(handler, `{"items": [{"type": "team", "id": 5}]}`)
+		st.Expect(t, rec.Code, http.StatusUnprocessableEntity)
+		st.Expect(t, strings.Contains(rec.Body.String(), "unknown team 5"), true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - failed query", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT id FROM games").WithArgs([]int32{1}).WillReturnError(errors.New("connection reset"))
+
+		rec := postSelection(handler, `{"items": [{"type": "game", "id": 1}]}`)
+		st.Expect(t, rec.Code, http.StatusInternalServerError)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	for name, header := range map[string]string{
+		"missing token": "",
+		"wrong token":   "Bearer " + strings.Repeat("x", MinSelectionTokenLength),
+		"not a bearer":  "Basic " + testSelectionToken,
+	} {
+		t.Run("Error - "+name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/selections",
+				strings.NewReader(`{"items": [{"type": "game", "id": 1}]}`))
+			req.Header.Set("Authorization", header)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			st.Expect(t, rec.Code, http.StatusUnauthorized)
+			st.Expect(t, rec.Header().Get("WWW-Authenticate"), `Bearer realm="stalka selections"`)
+			// nothing is looked up or saved
+			st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+		})
+	}
+
+	t.Run("Saving is not served without a token", func(t *testing.T) {
+		server := &Server{Queries: dbtypes.New(mockDB), Logger: zaptest.NewLogger(t).Sugar()}
+		rec := postSelection(server.Handler(), `{"items": [{"type": "game", "id": 1}]}`)
+		st.Expect(t, rec.Code, http.StatusNotFound)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	for name, body := range map[string]string{
+		"not JSON":     `items`,
+		"no items":     `{"items": []}`,
+		"unknown type": `{"items": [{"type": "player", "id": 1}]}`,
+		"invalid ID":   `{"items": [{"type": "game", "id": 0}]}`,
+	} {
+		t.Run("Error - "+name, func(t *testing.T) {
+			rec := postSelection(handler, body)
+			st.Expect(t, rec.Code, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestGetSelection(t *testing.T) {
+	handler, mockDB := newTestServer(t)
+
+	t.Run("Resolves the selection and lists the missing items", func(t *testing.T) {
+		mockDB.ExpectQuery("UPDATE url_mappings SET access_count").WithArgs("21380158dc3c4474").
+			WillReturnRows(pgxmock.NewRows([]string{"value_list"}).
+				AddRow([]byte(`[{"type":"game","id":1},{"type":"team","id":126061}]`)))
+		mockDB.ExpectQuery("SELECT id FROM games").WithArgs([]int32{1}).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(1)))
+		mockDB.ExpectQuery("SELECT id FROM teams").WithArgs([]int32{126061}).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+		rec := get(handler, "/api/v1/selections/21380158dc3c4474", nil)
+		st.Assert(t, rec.Code, http.StatusOK)
+		st.Expect(t, decodeSelection(t, rec), Selection{
+			Key:     "21380158dc3c4474",
+			Items:   []shortlink.Item{{Type: "game", ID: 1}, {Type: "team", ID: 126061}},
+			Missing: []shortlink.Item{{Type: "team", ID: 126061}},
+			Feed:    "/api/v1/calendars/selections/21380158dc3c4474.ics",
+		})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - unknown selection", func(t *testing.T) {
+		mockDB.ExpectQuery("UPDATE url_mappings SET access_count").WithArgs("missing").
+			WillReturnError(pgx.ErrNoRows)
+
+		rec := get(handler, "/api/v1/selections/missing", nil)
+		st.Expect(t, rec.Code, http.StatusNotFound)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestLoadSelectionToken(t *testing.T) {
+	token, err := LoadSelectionToken(func(string) string { return testSelectionToken })
+	st.Expect(t, err, nil)
+	st.Expect(t, token, testSelectionToken)
+
+	// unset leaves saving selections out
+	token, err = LoadSelectionToken(func(string) string { return "" })
+	st.Expect(t, err, nil)
+	st.Expect(t, token, "")
+
+	_, err = LoadSelectionToken(func(string) string { return "short" })
+	st.Reject(t, err, nil)
+}
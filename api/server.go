@@ -0,0 +1,523 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/live"
+)
+
+// Server serves the synced data as read-only JSON, so consumers do not need
+// database credentials or knowledge of the schema.
+type Server struct {
+	// Queries reads the data endpoints and calendar feeds. Nil leaves them out.
+	Queries dbtypes.Querier
+	// Live pushes the live event streams. Nil leaves them out.
+	Live *live.Broadcaster
+	// SelectionToken is the bearer token saving a selection requires. Empty
+	// leaves POST /api/v1/selections out, so the API only reads.
+	SelectionToken string
+	Logger         *zap.SugaredLogger
+}
+
+// Game is a game as returned by the API.
+type Game struct {
+	ID   int32   `json:"id"`
+	Name string  `json:"name"`
+	Slug *string `json:"slug"`
+}
+
+// League is a league as returned by the API.
+type League struct {
+	ID        int32   `json:"id"`
+	Name      string  `json:"name"`
+	Slug      *string `json:"slug"`
+	GameID    int32   `json:"game_id"`
+	ImageLink *string `json:"image_link"`
+}
+
+// Series is a series as returned by the API.
+type Series struct {
+	ID       int32   `json:"id"`
+	Name     string  `json:"name"`
+	Slug     *string `json:"slug"`
+	GameID   int32   `json:"game_id"`
+	LeagueID int32   `json:"league_id"`
+}
+
+// Tournament is a tournament as returned by the API.
+type Tournament struct {
+	ID       int32   `json:"id"`
+	Name     string  `json:"name"`
+	Slug     *string `json:"slug"`
+	Tier     *int32  `json:"tier"`
+	GameID   int32   `json:"game_id"`
+	LeagueID int32   `json:"league_id"`
+	SeriesID int32   `json:"series_id"`
+}
+
+// Team is a team as returned by the API.
+type Team struct {
+	ID        int32   `json:"id"`
+	Name      string  `json:"name"`
+	Slug      *string `json:"slug"`
+	Acronym   *string `json:"acronym"`
+	ImageLink *string `json:"image_link"`
+	GameID    int32   `json:"game_id"`
+}
+
+// Match is a match as returned by the API. team1_id and team2_id are only set
+// for two-team matches.
+type Match struct {
+	ID                  int32      `json:"id"`
+	Name                string     `json:"name"`
+	Slug                *string    `json:"slug"`
+	Status              *string    `json:"status"`
+	Finished            bool       `json:"finished"`
+	IsLive              bool       `json:"is_live"`
+	ExpectedStartTime   *time.Time `json:"expected_start_time"`
+	OriginalScheduledAt *time.Time `json:"original_scheduled_at"`
+	Rescheduled         bool       `json:"rescheduled"`
+	BeginAt             *time.Time `json:"begin_at"`
+	EndAt               *time.Time `json:"end_at"`
+	AmountOfGames       int32      `json:"amount_of_games"`
+	Team1ID             int32      `json:"team1_id"`
+	Team1Score          int32      `json:"team1_score"`
+	Team2ID             int32      `json:"team2_id"`
+	Team2Score          int32      `json:"team2_score"`
+	WinnerID            *int32     `json:"winner_id"`
+	WinnerType          *string    `json:"winner_type"`
+	Forfeit             bool       `json:"forfeit"`
+	Draw                bool       `json:"draw"`
+	StreamURL           *string    `json:"stream_url"`
+	GameID              int32      `json:"game_id"`
+	LeagueID            int32      `json:"league_id"`
+	SeriesID            int32      `json:"series_id"`
+	TournamentID        int32      `json:"tournament_id"`
+}
+
+// Handler returns the routes of the API and its OpenAPI document.
+// @returns the handler serving every endpoint under /api/v1 and /openapi.json.
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, r := range server.routes() {
+		mux.HandleFunc(r.method+" "+r.path, r.handler)
+	}
+	return mux
+}
+
+// routes lists the endpoints of the server: those reading the database when
+// Queries is set, saving selections when SelectionToken is set too, the live
+// streams when Live is set, and the OpenAPI document.
+func (server *Server) routes() []route { //nolint:funlen // one entry per endpoint
+	listErrors := []response{
+		errorResponse(http.StatusBadRequest, "A parameter is invalid."),
+		errorResponse(http.StatusInternalServerError, "The data could not be read."),
+	}
+	gameID := parameter{name: "gameID", in: "path", description: "The ID of the game.", schema: idSchema}
+	feedParameter := parameter{
+		name: "feed", in: "path", description: "The ID, optionally followed by .ics.", schema: stringSchema,
+	}
+	calendar := response{
+		status: http.StatusOK, description: "An iCalendar feed of the matches.",
+		contentType: "text/calendar", body: stringSchema, message: nil,
+	}
+	var routes []route
+	if server.Queries != nil {
+		routes = append(routes,
+			route{
+				method: http.MethodGet, path: "/api/v1/games", handler: server.listGames,
+				summary: "List the games", description: "",
+				parameters: pageParameters, request: nil,
+				success: jsonResponse(http.StatusOK, "A page of games.", Page[Game]{}),
+				tagged:  true, errors: listErrors,
+			},
+			route{
+				method: http.MethodGet, path: "/api/v1/games/{gameID}/leagues", handler: server.listLeagues,
+				summary: "List the leagues of a game", description: "Leagues are sorted best tier first.",
+				parameters: append([]parameter{gameID}, pageParameters...), request: nil,
+				success: jsonResponse(http.StatusOK, "A page of leagues.", Page[League]{}),
+				tagged:  true, errors: listErrors,
+			},
+			route{
+				method: http.MethodGet, path: "/api/v1/games/{gameID}/series", handler: server.listSeries,
+				summary: "List the series of a game", description: "",
+				parameters: append([]parameter{gameID}, pageParameters...), request: nil,
+				success: jsonResponse(http.StatusOK, "A page of series.", Page[Series]{}),
+				tagged:  true, errors: listErrors,
+			},
+			route{
+				method: http.MethodGet, path: "/api/v1/games/{gameID}/tournaments", handler: server.listTournaments,
+				summary: "List the tournaments of a game", description: "",
+				parameters: append([]parameter{gameID}, pageParameters...), request: nil,
+				success: jsonResponse(http.StatusOK, "A page of tournaments.", Page[Tournament]{}),
+				tagged:  true, errors: listErrors,
+			},
+			route{
+				method: http.MethodGet, path: "/api/v1/matches", handler: server.listMatches,
+				summary: "List matches", description: "Matches are sorted by expected start time.",
+				parameters: append([]parameter{
+					{name: "from", in: "query", description: "The earliest expected start time.", schema: timeSchema},
+					{name: "to", in: "query", description: "The start time to stop before.", schema: timeSchema},
+					{name: "game_id", in: "query", description: "The ID of the game.", schema: idSchema},
+					{name: "league_id", in: "query", description: "The ID of the league.", schema: idSchema},
+					{name: "team_id", in: "query", description: "The ID of a team playing.", schema: idSchema},
+					{name: "live", in: "query", description: "Whether the match is live.", schema: booleanSchema},
+				}, pageParameters...),
+				request: nil,
+				success: jsonResponse(http.StatusOK, "A page of matches.", Page[Match]{}),
+				tagged:  true, errors: listErrors,
+			},
+			route{
+				method: http.MethodPost, path: "/api/v1/graphql", handler: server.graphQL,
+				summary: "Run a GraphQL query", description: "The errors of a query that ran are part of its response.",
+				parameters: nil, request: graphQLRequest{},
+				success: jsonResponse(http.StatusOK, "The result of the query.", graphQLResponseSchema),
+				tagged:  false,
+				errors: []response{jsonResponse(http.StatusBadRequest,
+					"The query is malformed, invalid or over the depth or complexity limits.", graphQLResponseSchema)},
+			},
+			route{
+				method: http.MethodGet, path: "/api/v1/selections/{key}", handler: server.getSelection,
+				summary: "Get a saved selection", description: "",
+				parameters: []parameter{
+					{name: "key", in: "path", description: "The key of the selection.", schema: stringSchema},
+				},
+				request: nil,
+				success: jsonResponse(http.StatusOK, "The selection.", Selection{}),
+				tagged:  true,
+				errors: []response{
+					errorResponse(http.StatusNotFound, "The selection is unknown."),
+					errorResponse(http.StatusInternalServerError, "The selection could not be read."),
+				},
+			},
+		)
+		for _, feed := range []struct {
+			kind    string
+			handler http.HandlerFunc
+		}{{"games", server.gameFeed}, {"leagues", server.leagueFeed}, {"teams", server.teamFeed}} {
+			routes = append(routes, route{
+				method: http.MethodGet, path: "/api/v1/calendars/" + feed.kind + "/{feed}", handler: feed.handler,
+				summary: "Subscribe to the matches of " + feed.kind, description: "",
+				parameters: []parameter{feedParameter}, request: nil, success: calendar, tagged: true,
+				errors: []response{
+					errorResponse(http.StatusBadRequest, "The ID is invalid."),
+					errorResponse(http.StatusInternalServerError, "The matches could not be read."),
+				},
+			})
+		}
+		if server.SelectionToken != "" {
+			routes = append(routes, route{
+				method: http.MethodPost, path: "/api/v1/selections", handler: server.createSelection,
+				summary: "Save a selection",
+				description: "Requires `Authorization: Bearer <selection_token>`. " +
+					"Saving a selection again returns the same key.",
+				parameters: nil, request: selectionRequest{},
+				success: jsonResponse(http.StatusCreated, "The saved selection.", Selection{}),
+				tagged:  false,
+				errors: []response{
+					errorResponse(http.StatusBadRequest, "The selection is invalid."),
+					errorResponse(http.StatusUnauthorized, "The token is missing or invalid."),
+					errorResponse(http.StatusConflict, "The key of the selection is taken."),
+					errorResponse(http.StatusUnprocessableEntity, "The selection names unknown IDs."),
+					errorResponse(http.StatusInternalServerError, "The selection could not be saved."),
+				},
+			})
+		}
+		routes = append(routes, route{
+			method: http.MethodGet, path: "/api/v1/calendars/selections/{feed}", handler: server.selectionFeed,
+			summary: "Subscribe to the matches of a saved selection", description: "",
+			parameters: []parameter{{
+				name: "feed", in: "path", description: "The key, optionally followed by .ics.", schema: stringSchema,
+			}},
+			request: nil, success: calendar, tagged: true,
+			errors: []response{
+				errorResponse(http.StatusNotFound, "The selection is unknown."),
+				errorResponse(http.StatusInternalServerError, "The matches could not be read."),
+			},
+		})
+	}
+	if server.Live != nil {
+		liveParameters := []parameter{
+			{name: "game_id", in: "query", description: "The IDs of the games, repeated or comma separated.",
+				schema: &openAPISchema{Type: "array", Items: idSchema}},
+			{name: "team_id", in: "query", description: "The IDs of the teams, repeated or comma separated.",
+				schema: &openAPISchema{Type: "array", Items: idSchema}},
+			{name: "last_event_id", in: "query", description: "The ID of the last event received.",
+				schema: eventIDSchema},
+		}
+		liveErrors := []response{errorResponse(http.StatusBadRequest, "A filter is invalid.")}
+		routes = append(routes,
+			route{
+				method: http.MethodGet, path: "/api/v1/live/events", handler: server.liveEvents,
+				summary:     "Stream live events",
+				description: "Each event is sent as JSON in the data of a Server-Sent Event.",
+				parameters: append(liveParameters, parameter{
+					name: "Last-Event-ID", in: "header", description: "The ID of the last event received.",
+					schema: eventIDSchema,
+				}),
+				request: nil,
+				success: response{
+					status: http.StatusOK, description: "The event stream.",
+					contentType: "text/event-stream", body: stringSchema, message: live.Event{},
+				},
+				tagged: false, errors: liveErrors,
+			},
+			route{
+				method: http.MethodGet, path: "/api/v1/live/ws", handler: server.liveSocket,
+				summary:     "Stream live events over a WebSocket",
+				description: "Each event is sent as a JSON text message.",
+				parameters:  liveParameters, request: nil,
+				success: response{
+					status: http.StatusSwitchingProtocols, description: "The WebSocket is open.",
+					contentType: "", body: nil, message: live.Event{},
+				},
+				tagged: false, errors: liveErrors,
+			},
+		)
+	}
+	return append(routes, route{
+		method: http.MethodGet, path: "/openapi.json", handler: server.serveOpenAPI,
+		summary: "Describe the API", description: "Only the endpoints this server serves are listed.",
+		parameters: nil, request: nil,
+		success: jsonResponse(http.StatusOK, "The OpenAPI document.", documentSchema),
+		tagged:  true, errors: nil,
+	})
+}
+
+// listGames serves every game.
+func (server *Server) listGames(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rows, err := server.Queries.ListGames(r.Context(), dbtypes.ListGamesParams{
+		MaxRows: page.maxRows(), SkipRows: page.skipRows(),
+	})
+	if err != nil {
+		server.internalError(w, "games", err)
+		return
+	}
+	games := make([]Game, 0, len(rows))
+	for _, row := range rows {
+		games = append(games, gameFromRow(row))
+	}
+	writeJSON(w, r, windowed(games, page))
+}
+
+// listLeagues serves the leagues of a game, best tier first.
+func (server *Server) listLeagues(w http.ResponseWriter, r *http.Request) {
+	gameID, page, ok := gameRequest(w, r)
+	if !ok {
+		return
+	}
+	rows, err := server.Queries.GetLeaguesByGameID(r.Context(), gameID)
+	if err != nil {
+		server.internalError(w, "leagues", err)
+		return
+	}
+	leagues := make([]League, 0, len(rows))
+	for _, row := range rows {
+		leagues = append(leagues, leagueFromRow(row))
+	}
+	writeJSON(w, r, paginate(leagues, page))
+}
+
+// listSeries serves the series of a game by name.
+func (server *Server) listSeries(w http.ResponseWriter, r *http.Request) {
+	gameID, page, ok := gameRequest(w, r)
+	if !ok {
+		return
+	}
+	rows, err := server.Queries.ListSeriesByGameID(r.Context(), dbtypes.ListSeriesByGameIDParams{
+		GameID: gameID, MaxRows: page.maxRows(), SkipRows: page.skipRows(),
+	})
+	if err != nil {
+		server.internalError(w, "series", err)
+		return
+	}
+	series := make([]Series, 0, len(rows))
+	for _, row := range rows {
+		series = append(series, seriesFromRow(row))
+	}
+	writeJSON(w, r, windowed(series, page))
+}
+
+// listTournaments serves the tournaments of a game by name.
+func (server *Server) listTournaments(w http.ResponseWriter, r *http.Request) {
+	gameID, page, ok := gameRequest(w, r)
+	if !ok {
+		return
+	}
+	rows, err := server.Queries.ListTournamentsByGameID(r.Context(), dbtypes.ListTournamentsByGameIDParams{
+		GameID: gameID, MaxRows: page.maxRows(), SkipRows: page.skipRows(),
+	})
+	if err != nil {
+		server.internalError(w, "tournaments", err)
+		return
+	}
+	tournaments := make([]Tournament, 0, len(rows))
+	for _, row := range rows {
+		tournaments = append(tournaments, tournamentFromRow(row))
+	}
+	writeJSON(w, r, windowed(tournaments, page))
+}
+
+// listMatches serves the matches matching the filters of the query string by
+// expected start time: from and to (RFC 3339, to is exclusive), game_id,
+// league_id, team_id and live.
+func (server *Server) listMatches(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	params, err := parseMatchFilters(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	params.MaxRows = page.maxRows()
+	params.SkipRows = page.skipRows()
+	rows, err := server.Queries.ListMatches(r.Context(), params)
+	if err != nil {
+		server.internalError(w, "matches", err)
+		return
+	}
+	matches := make([]Match, 0, len(rows))
+	for _, row := range rows {
+		matches = append(matches, matchFromRow(row))
+	}
+	writeJSON(w, r, windowed(matches, page))
+}
+
+// parseMatchFilters reads the filters of the matches endpoint.
+// @param r - the request.
+// @returns the query parameters without the window, and an error naming the invalid filter.
+func parseMatchFilters(r *http.Request) (dbtypes.ListMatchesParams, error) {
+	var params dbtypes.ListMatchesParams
+	query := r.URL.Query()
+	for key, target := range map[string]*pgtype.Timestamptz{"from": &params.StartsAfter, "to": &params.StartsBefore} {
+		if value := query.Get(key); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return params, fmt.Errorf("%s must be an RFC 3339 time", key)
+			}
+			*target = pgtype.Timestamptz{Time: parsed, InfinityModifier: pgtype.Finite, Valid: true}
+		}
+	}
+	for key, target := range map[string]*pgtype.Int4{
+		"game_id": &params.GameID, "league_id": &params.LeagueID, "team_id": &params.TeamID,
+	} {
+		if value := query.Get(key); value != "" {
+			id, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return params, fmt.Errorf("%s must be an integer", key)
+			}
+			*target = pgtype.Int4{Int32: int32(id), Valid: true}
+		}
+	}
+	if value := query.Get("live"); value != "" {
+		live, err := strconv.ParseBool(value)
+		if err != nil {
+			return params, errors.New("live must be true or false")
+		}
+		params.IsLive = pgtype.Bool{Bool: live, Valid: true}
+	}
+	return params, nil
+}
+
+// gameFromRow converts a stored game.
+func gameFromRow(row dbtypes.Game) Game {
+	return Game{ID: row.ID, Name: row.Name, Slug: text(row.Slug)}
+}
+
+// leagueFromRow converts a stored league.
+func leagueFromRow(row dbtypes.League) League {
+	return League{
+		ID: row.ID, Name: row.Name, Slug: text(row.Slug), GameID: row.GameID, ImageLink: text(row.ImageLink),
+	}
+}
+
+// seriesFromRow converts a stored series.
+func seriesFromRow(row dbtypes.Series) Series {
+	return Series{ID: row.ID, Name: row.Name, Slug: text(row.Slug), GameID: row.GameID, LeagueID: row.LeagueID}
+}
+
+// tournamentFromRow converts a stored tournament.
+func tournamentFromRow(row dbtypes.Tournament) Tournament {
+	return Tournament{
+		ID: row.ID, Name: row.Name, Slug: text(row.Slug), Tier: int4(row.Tier),
+		GameID: row.GameID, LeagueID: row.LeagueID, SeriesID: row.SerieID,
+	}
+}
+
+// teamFromRow converts a stored team.
+func teamFromRow(row dbtypes.Team) Team {
+	return Team{
+		ID: row.ID, Name: row.Name, Slug: text(row.Slug), Acronym: text(row.Acronym),
+		ImageLink: text(row.ImageLink), GameID: row.GameID,
+	}
+}
+
+// matchFromRow converts a stored match.
+func matchFromRow(row dbtypes.Match) Match {
+	return Match{
+		ID:                  row.ID,
+		Name:                row.Name,
+		Slug:                text(row.Slug),
+		Status:              text(row.Status),
+		Finished:            row.Finished,
+		IsLive:              row.IsLive,
+		ExpectedStartTime:   timestamp(row.ExpectedStartTime),
+		OriginalScheduledAt: timestamp(row.OriginalScheduledAt),
+		Rescheduled:         row.Rescheduled,
+		BeginAt:             timestamp(row.BeginAt),
+		EndAt:               timestamp(row.EndAt),
+		AmountOfGames:       row.AmountOfGames,
+		Team1ID:             row.Team1ID,
+		Team1Score:          row.Team1Score,
+		Team2ID:             row.Team2ID,
+		Team2Score:          row.Team2Score,
+		WinnerID:            int4(row.WinnerID),
+		WinnerType:          text(row.WinnerType),
+		Forfeit:             row.Forfeit,
+		Draw:                row.Draw,
+		StreamURL:           text(row.StreamURL),
+		GameID:              row.GameID,
+		LeagueID:            row.LeagueID,
+		SeriesID:            row.SeriesID,
+		TournamentID:        row.TournamentID,
+	}
+}
+
+// gameRequest reads the game ID and the window of a per-game endpoint, and
+// answers 400 itself when either is invalid.
+// @returns the game ID, the window and whether the request may proceed.
+func gameRequest(w http.ResponseWriter, r *http.Request) (int32, pageParams, bool) {
+	gameID, err := strconv.ParseInt(r.PathValue("gameID"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "game ID must be an integer")
+		return 0, pageParams{}, false
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return 0, pageParams{}, false
+	}
+	return int32(gameID), page, true
+}
+
+// internalError logs a failed query and answers 500 without its details.
+func (server *Server) internalError(w http.ResponseWriter, resource string, err error) {
+	server.Logger.Errorf("Error serving %s: %v", resource, err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
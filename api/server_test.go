@@ -0,0 +1,268 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+)
+
+var matchColumns = []string{
+	"id", "name", "slug", "finished", "expected_start_time", "actual_game_time",
+	"team1_id", "team1_score", "team2_id", "team2_score", "amount_of_games", "is_live",
+	"stream_url", "status", "forfeit", "draw", "rescheduled", "original_scheduled_at",
+	"begin_at", "end_at", "winner_id", "winner_type", "game_id", "league_id", "series_id", "tournament_id",
+	"last_seen_at", "deleted_at", "modified_at",
+}
+
+func matchRows(matches ...dbtypes.Match) *pgxmock.Rows {
+	rows := pgxmock.NewRows(matchColumns)
+	for _, m := range matches {
+		rows.AddRow(
+			m.ID, m.Name, m.Slug, m.Finished, m.ExpectedStartTime, m.ActualGameTime,
+			m.Team1ID, m.Team1Score, m.Team2ID, m.Team2Score, m.AmountOfGames, m.IsLive,
+			m.StreamURL, m.Status, m.Forfeit, m.Draw, m.Rescheduled, m.OriginalScheduledAt,
+			m.BeginAt, m.EndAt, m.WinnerID, m.WinnerType, m.GameID, m.LeagueID, m.SeriesID, m.TournamentID,
+			m.LastSeenAt, m.DeletedAt, m.ModifiedAt,
+		)
+	}
+	return rows
+}
+
+// fixtureMatch is a scheduled two-team match starting at start.
+func fixtureMatch(id int32, start time.Time) dbtypes.Match {
+	return dbtypes.Match{
+		ID:                id,
+		Name:              "T1 vs GEN",
+		Slug:              pgtype.Text{String: "t1-vs-gen", Valid: true},
+		ExpectedStartTime: pgtype.Timestamptz{Time: start, Valid: true, InfinityModifier: pgtype.Finite},
+		Team1ID:           1,
+		Team2ID:           2,
+		AmountOfGames:     5,
+		Status:            pgtype.Text{String: "not_started", Valid: true},
+		GameID:            1,
+		LeagueID:          3,
+		SeriesID:          4,
+		TournamentID:      5,
+	}
+}
+
+func newTestServer(t *testing.T) (http.Handler, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	t.Cleanup(mockDB.Close)
+	server := &Server{
+		Queries: dbtypes.New(mockDB), SelectionToken: testSelectionToken, Logger: zaptest.NewLogger(t).Sugar(),
+	}
+	return server.Handler(), mockDB
+}
+
+func get(handler http.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) Page[T] {
+	t.Helper()
+	var page Page[T]
+	st.Assert(t, json.Unmarshal(rec.Body.Bytes(), &page), nil)
+	return page
+}
+
+func TestGames(t *testing.T) {
+	handler, mockDB := newTestServer(t)
+	gameRows := func() *pgxmock.Rows {
+		return pgxmock.NewRows(gameColumns).
+			AddRow(int32(1), "LoL", pgtype.Text{String: "league-of-legends", Valid: true},
+				pgtype.Timestamptz{}, pgtype.Timestamptz{}).
+			AddRow(int32(3), "CS2", pgtype.Text{String: "cs-go", Valid: true},
+				pgtype.Timestamptz{}, pgtype.Timestamptz{}).
+			AddRow(int32(4), "Dota 2", pgtype.Text{}, pgtype.Timestamptz{}, pgtype.Timestamptz{})
+	}
+
+	t.Run("Pages are read from the database one row past the limit", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games .+ LIMIT").
+			WithArgs(int32(3), int32(1)).
+			WillReturnRows(pgxmock.NewRows(gameColumns).
+				AddRow(int32(3), "CS2", pgtype.Text{String: "cs-go", Valid: true},
+					pgtype.Timestamptz{}, pgtype.Timestamptz{}).
+				AddRow(int32(4), "Dota 2", pgtype.Text{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}))
+
+		rec := get(handler, "/api/v1/games?limit=2&offset=1", nil)
+		st.Assert(t, rec.Code, http.StatusOK)
+		page := decode[Game](t, rec)
+		st.Assert(t, len(page.Data), 2)
+		st.Expect(t, page.Data[0].ID, int32(3))
+		st.Expect(t, page.Data[1].Slug, (*string)(nil))
+		st.Expect(t, page.HasMore, false)
+		st.Expect(t, page.Offset, 1)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("The row past the limit tells that another page follows", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games").
+			WithArgs(int32(3), int32(0)).WillReturnRows(gameRows())
+
+		rec := get(handler, "/api/v1/games?limit=2", nil)
+		st.Assert(t, rec.Code, http.StatusOK)
+		page := decode[Game](t, rec)
+		st.Assert(t, len(page.Data), 2)
+		st.Expect(t, page.Data[1].ID, int32(3))
+		st.Expect(t, page.HasMore, true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Unchanged responses are not sent again", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games").WithArgs(int32(DefaultPageSize+1), int32(0)).WillReturnRows(gameRows())
+		mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games").WithArgs(int32(DefaultPageSize+1), int32(0)).WillReturnRows(gameRows())
+
+		first := get(handler, "/api/v1/games", nil)
+		etag := first.Header().Get("ETag")
+		st.Reject(t, etag, "")
+		second := get(handler, "/api/v1/games", http.Header{"If-None-Match": {`W/"other", ` + etag}})
+		st.Expect(t, second.Code, http.StatusNotModified)
+		st.Expect(t, second.Body.Len(), 0)
+		st.Expect(t, second.Header().Get("ETag"), etag)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - invalid window", func(t *testing.T) {
+		for _, query := range []string{"limit=0", "limit=1000", "offset=-1", "offset=x"} {
+			rec := get(handler, "/api/v1/games?"+query, nil)
+			st.Expect(t, rec.Code, http.StatusBadRequest)
+		}
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - database failure", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games").
+			WithArgs(int32(DefaultPageSize+1), int32(0)).
+			WillReturnError(errors.New("database error"))
+
+		rec := get(handler, "/api/v1/games", nil)
+		st.Expect(t, rec.Code, http.StatusInternalServerError)
+		st.Expect(t, rec.Body.String(), "{\"error\":\"internal error\"}\n")
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestGameResources(t *testing.T) {
+	handler, mockDB := newTestServer(t)
+
+	t.Run("Leagues reuse the tier ranking", func(t *testing.T) {
+		leagueRows := func() *pgxmock.Rows {
+			return pgxmock.NewRows([]string{
+				"id", "name", "slug", "game_id", "image_link", "last_seen_at", "deleted_at", "modified_at", "search_vector",
+			}).AddRow(int32(3), "LCK", pgtype.Text{String: "lck", Valid: true}, int32(1), pgtype.Text{},
+				pgtype.Timestamptz{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}, nil).
+				AddRow(int32(4), "LPL", pgtype.Text{String: "lpl", Valid: true}, int32(1), pgtype.Text{},
+					pgtype.Timestamptz{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}, nil)
+		}
+		mockDB.ExpectQuery("FROM LEAGUES l .+ ORDER BY MIN\\(t.tier\\) ASC, l.name ASC").WithArgs(int32(1)).
+			WillReturnRows(leagueRows())
+
+		rec := get(handler, "/api/v1/games/1/leagues", nil)
+		st.Assert(t, rec.Code, http.StatusOK)
+		page := decode[League](t, rec)
+		st.Assert(t, len(page.Data), 2)
+		st.Expect(t, page.Data[0].Name, "LCK")
+		st.Expect(t, page.HasMore, false)
+
+		mockDB.ExpectQuery("SELECT l.id, l.name").WithArgs(int32(1)).WillReturnRows(leagueRows())
+		rec = get(handler, "/api/v1/games/1/leagues?limit=1&offset=1", nil)
+		st.Assert(t, rec.Code, http.StatusOK)
+		page = decode[League](t, rec)
+		st.Assert(t, len(page.Data), 1)
+		st.Expect(t, page.Data[0].Name, "LPL")
+		st.Expect(t, page.HasMore, false)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Tournaments", func(t *testing.T) {
+		mockDB.ExpectQuery("FROM tournaments WHERE game_id").WithArgs(int32(1), int32(DefaultPageSize+1), int32(0)).
+			WillReturnRows(pgxmock.NewRows(tournamentColumns).
+				AddRow(int32(5), "Playoffs", pgtype.Text{}, pgtype.Int4{Int32: 1, Valid: true}, int32(1), int32(3),
+					int32(4), pgtype.Timestamptz{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}, nil))
+
+		rec := get(handler, "/api/v1/games/1/tournaments", nil)
+		st.Assert(t, rec.Code, http.StatusOK)
+		page := decode[Tournament](t, rec)
+		st.Assert(t, len(page.Data), 1)
+		st.Expect(t, *page.Data[0].Tier, int32(1))
+		st.Expect(t, page.Data[0].SeriesID, int32(4))
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - invalid game ID", func(t *testing.T) {
+		rec := get(handler, "/api/v1/games/lol/series", nil)
+		st.Expect(t, rec.Code, http.StatusBadRequest)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestMatches(t *testing.T) {
+	handler, mockDB := newTestServer(t)
+	start := time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+
+	t.Run("Filters are passed to the query", func(t *testing.T) {
+		mockDB.ExpectQuery("FROM matches m").
+			WithArgs(
+				pgtype.Timestamptz{Time: start, Valid: true, InfinityModifier: pgtype.Finite},
+				pgtype.Timestamptz{},
+				pgtype.Int4{Int32: 1, Valid: true},
+				pgtype.Int4{},
+				pgtype.Int4{Int32: 2, Valid: true},
+				pgtype.Bool{Bool: false, Valid: true},
+				int32(3), int32(0),
+			).
+			WillReturnRows(matchRows(fixtureMatch(10, start)))
+
+		rec := get(handler, "/api/v1/matches?from=2025-10-01T08:00:00Z&game_id=1&team_id=2&live=false&limit=2", nil)
+		st.Assert(t, rec.Code, http.StatusOK)
+		page := decode[Match](t, rec)
+		st.Assert(t, len(page.Data), 1)
+		st.Expect(t, page.Data[0].ExpectedStartTime.Equal(start), true)
+		st.Expect(t, *page.Data[0].Status, "not_started")
+		st.Expect(t, page.Data[0].WinnerID, (*int32)(nil))
+		st.Expect(t, page.HasMore, false)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("The extra row only signals another page", func(t *testing.T) {
+		mockDB.ExpectQuery("FROM matches m").
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+				pgxmock.AnyArg(), pgxmock.AnyArg(), int32(2), int32(4)).
+			WillReturnRows(matchRows(fixtureMatch(10, start), fixtureMatch(11, start)))
+
+		rec := get(handler, "/api/v1/matches?limit=1&offset=4", nil)
+		st.Assert(t, rec.Code, http.StatusOK)
+		page := decode[Match](t, rec)
+		st.Assert(t, len(page.Data), 1)
+		st.Expect(t, page.Data[0].ID, int32(10))
+		st.Expect(t, page.HasMore, true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - invalid filters", func(t *testing.T) {
+		for _, query := range []string{"from=yesterday", "league_id=lck", "live=maybe", "team_id=99999999999"} {
+			rec := get(handler, "/api/v1/matches?"+query, nil)
+			st.Expect(t, rec.Code, http.StatusBadRequest)
+		}
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HeaderRateLimitRemaining is the header PandaScore reports the requests left
+// in the current hour with.
+const HeaderRateLimitRemaining = "X-Rate-Limit-Remaining"
+
+// Budget tracks the PandaScore request budget as reported by the responses.
+// A nil *Budget ignores responses. It is safe for concurrent use, so the copies
+// of a client made for page transactions share it.
+type Budget struct {
+	mu         sync.Mutex
+	requests   int
+	remaining  int
+	observed   bool
+	observedAt time.Time
+}
+
+// BudgetStatus is a snapshot of a Budget.
+type BudgetStatus struct {
+	// Requests counts the requests made since the process started.
+	Requests int
+	// Remaining is the last reported number of requests left, nil before the
+	// first response carrying it.
+	Remaining *int
+	// ObservedAt is when Remaining was reported, nil with it.
+	ObservedAt *time.Time
+}
+
+// NewBudget creates a budget without any observed response.
+func NewBudget() *Budget {
+	return &Budget{mu: sync.Mutex{}, requests: 0, remaining: 0, observed: false, observedAt: time.Time{}}
+}
+
+// Observe counts a response and records the remaining budget it reports.
+// @param header - the headers of the response.
+// @param now - when the response arrived.
+func (budget *Budget) Observe(header http.Header, now time.Time) {
+	if budget == nil {
+		return
+	}
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	budget.requests++
+	remaining, err := strconv.Atoi(header.Get(HeaderRateLimitRemaining))
+	if err != nil {
+		return
+	}
+	budget.remaining = remaining
+	budget.observed = true
+	budget.observedAt = now
+}
+
+// Status returns a snapshot of the budget.
+func (budget *Budget) Status() BudgetStatus {
+	status := BudgetStatus{Requests: 0, Remaining: nil, ObservedAt: nil}
+	if budget == nil {
+		return status
+	}
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	status.Requests = budget.requests
+	if budget.observed {
+		remaining, observedAt := budget.remaining, budget.observedAt
+		status.Remaining = &remaining
+		status.ObservedAt = &observedAt
+	}
+	return status
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+)
+
+func TestBudget(t *testing.T) {
+	now := time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+
+	t.Run("Nothing observed yet", func(t *testing.T) {
+		status := NewBudget().Status()
+		st.Expect(t, status, BudgetStatus{Requests: 0, Remaining: nil, ObservedAt: nil})
+	})
+
+	t.Run("The last reported budget wins", func(t *testing.T) {
+		budget := NewBudget()
+		budget.Observe(http.Header{HeaderRateLimitRemaining: {"998"}}, now)
+		budget.Observe(http.Header{HeaderRateLimitRemaining: {"997"}}, now.Add(time.Second))
+		// responses without the header still count as requests
+		budget.Observe(http.Header{}, now.Add(2*time.Second))
+		status := budget.Status()
+		st.Expect(t, status.Requests, 3)
+		st.Assert(t, status.Remaining != nil, true)
+		st.Expect(t, *status.Remaining, 997)
+		st.Expect(t, *status.ObservedAt, now.Add(time.Second))
+	})
+
+	t.Run("A nil budget ignores responses", func(t *testing.T) {
+		var budget *Budget
+		budget.Observe(http.Header{HeaderRateLimitRemaining: {"1"}}, now)
+		st.Expect(t, budget.Status().Remaining, (*int)(nil))
+	})
+}
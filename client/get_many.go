@@ -6,10 +6,11 @@ import (
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
 	"encoding/json"
 
-	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/live"
 	"github.com/feimaomiao/stalka/pandatypes"
 
 	// loads .env file automatically.
@@ -44,15 +45,17 @@ func (client *PandaClient) UpdateGames() error {
 		client.Logger.Errorf("Error unmarshalling response: %v", err)
 		return err
 	}
-	for _, game := range result {
+	return client.WritePage(FlagGame, len(result), func(page *PandaClient, i int) error {
+		game := result[i]
 		client.Logger.Debugf("Writing game %s", game.Name)
-		err = game.ToRow().WriteToDB(client.Ctx, client.DBConnector)
+		err = page.writeRow(game.ToRow())
 		if err != nil {
 			client.Logger.Errorf("Error writing game %s to database: %v", game.Name, err)
 			return err
 		}
-	}
-	return nil
+		page.remember(FlagGame, game.ID)
+		return nil
+	})
 }
 
 // GetLeagues gets the first leagues from the Pandascore API.
@@ -82,13 +85,18 @@ func (client *PandaClient) GetLeagues(setup bool) error {
 			client.Logger.Errorf("Error unmarshalling response: %v", err)
 			return err
 		}
-		for _, league := range result {
+		err = client.WritePage(FlagLeague, len(result), func(page *PandaClient, i int) error {
+			league := result[i]
 			client.Logger.Debugf("Writing league %s", league.Name)
-			err = league.ToRow().WriteToDB(client.Ctx, client.DBConnector)
-			if err != nil {
-				client.Logger.Errorf("Error writing league %s to database: %v", league.Name, err)
-				return err
+			if writeErr := page.writeRow(league.ToRow()); writeErr != nil {
+				return writeErr
 			}
+			page.remember(FlagLeague, league.ID)
+			return nil
+		})
+		if err != nil {
+			client.Logger.Errorf("Error writing leagues page %d to database: %v", i, err)
+			return err
 		}
 		if !setup {
 			break
@@ -125,24 +133,32 @@ func (client *PandaClient) GetSeries(setup bool) error {
 			client.Logger.Errorf("Error unmarshalling response: %v", err)
 			return err
 		}
-		var exists bool
+		leagueIDs := make([]int, 0, len(result))
 		for _, series := range result {
-			exists, err = client.ExistCheck(series.League.ID, FlagLeague)
-			if err != nil {
-				continue
-			}
-			if !exists {
-				err = client.GetOne(series.League.ID, FlagLeague)
-				if err != nil {
-					continue
+			leagueIDs = append(leagueIDs, series.League.ID)
+		}
+		missing, err := client.MissingIDs(FlagLeague, leagueIDs)
+		if err != nil {
+			return err
+		}
+		err = client.WritePage(FlagSeries, len(result), func(page *PandaClient, j int) error {
+			series := result[j]
+			// an earlier row of the page may have fetched the league already
+			if missing[series.League.ID] && !page.knows(FlagLeague, series.League.ID) {
+				if getErr := page.GetOne(series.League.ID, FlagLeague); getErr != nil {
+					return &UnresolvedError{Flag: FlagSeries, ID: series.ID, Err: getErr}
 				}
 			}
 			client.Logger.Debugf("Writing series %s, with league_id %d", series.Name, series.LeagueID)
-			err = series.ToRow().WriteToDB(client.Ctx, client.DBConnector)
-			if err != nil {
-				client.Logger.Errorf("Error writing series %s to database: %v", series.Name, err)
-				return err
+			if writeErr := page.writeRow(series.ToRow()); writeErr != nil {
+				return writeErr
 			}
+			page.remember(FlagSeries, series.ID)
+			return nil
+		})
+		if err != nil {
+			client.Logger.Errorf("Error writing series page %d to database: %v", i, err)
+			return err
 		}
 		if !setup {
 			break
@@ -177,25 +193,33 @@ func (client *PandaClient) GetTournaments(setup bool) error {
 		if err != nil {
 			return err
 		}
-		var exists bool
+		seriesIDs := make([]int, 0, len(result))
 		for _, tournament := range result {
-			exists, err = client.ExistCheck(tournament.SerieID, FlagSeries)
-			if err != nil {
-				continue
-			}
-			if !exists {
+			seriesIDs = append(seriesIDs, tournament.SerieID)
+		}
+		missing, err := client.MissingIDs(FlagSeries, seriesIDs)
+		if err != nil {
+			return err
+		}
+		err = client.WritePage(FlagTournament, len(result), func(page *PandaClient, j int) error {
+			tournament := result[j]
+			if missing[tournament.SerieID] && !page.knows(FlagSeries, tournament.SerieID) {
 				client.Logger.Debugf("Serie %d does not exist, getting", tournament.SerieID)
-				err = client.GetOne(tournament.SerieID, FlagSeries)
-				if err != nil {
-					continue
+				if getErr := page.GetOne(tournament.SerieID, FlagSeries); getErr != nil {
+					return &UnresolvedError{Flag: FlagTournament, ID: tournament.ID, Err: getErr}
 				}
 			}
 			client.Logger.Debugf("Writing tournament %s", tournament.Name)
-			err = tournament.ToRow().WriteToDB(client.Ctx, client.DBConnector)
-			if err != nil {
-				client.Logger.Errorf("Error writing tournament %s to database: %v", tournament.Name, err)
-				return err
+			if writeErr := page.writeRow(tournament.ToRow()); writeErr != nil {
+				return writeErr
 			}
+			page.remember(FlagTournament, tournament.ID)
+			page.writeMissingEntities(tournament.TeamRows(), nil)
+			return nil
+		})
+		if err != nil {
+			client.Logger.Errorf("Error writing tournaments page %d to database: %v", i, err)
+			return err
 		}
 		if !setup {
 			break
@@ -251,7 +275,6 @@ func (client *PandaClient) getMatchPage(page int, wg *sync.WaitGroup, ch chan<-
 // @returns an error if one occurred.
 func (client *PandaClient) GetMatches(setup bool) error {
 	client.Logger.Info("Getting matches")
-	var result pandatypes.MatchLikes
 	var wg sync.WaitGroup
 	var pageCount int
 	if setup {
@@ -266,13 +289,13 @@ func (client *PandaClient) GetMatches(setup bool) error {
 	}
 	wg.Wait()
 	close(varChan)
+	// every page is written as one batch
 	for res := range varChan {
 		if res.Err != nil {
 			continue
 		}
-		result = append(result, res.Matches...)
+		client.WriteMatches(res.Matches)
 	}
-	client.WriteMatches(result)
 	return nil
 }
 
@@ -302,13 +325,18 @@ func (client *PandaClient) GetTeams(setup bool) error {
 			client.Logger.Errorf("Error unmarshalling response: %v", err)
 			return err
 		}
-		for _, teams := range result {
-			client.Logger.Debugf("Writing team %s in game %d", teams.Name, teams.CurrentVideogame.ID)
-			err = teams.ToRow().WriteToDB(client.Ctx, client.DBConnector)
-			if err != nil {
-				client.Logger.Errorf("Error writing team to database: %v", err)
-				return err
+		err = client.WritePage(FlagTeam, len(result), func(page *PandaClient, j int) error {
+			team := result[j]
+			client.Logger.Debugf("Writing team %s in game %d", team.Name, team.CurrentVideogame.ID)
+			if writeErr := page.writeRow(team.ToRow()); writeErr != nil {
+				return writeErr
 			}
+			page.remember(FlagTeam, team.ID)
+			return nil
+		})
+		if err != nil {
+			client.Logger.Errorf("Error writing teams page %d to database: %v", i, err)
+			return err
 		}
 		if !setup {
 			break
@@ -368,10 +396,7 @@ func (client *PandaClient) GetLives() error {
 
 	// Update is_live for live matches
 	if len(liveIDs) > 0 {
-		err = client.DBConnector.UpdateMatchesIsLiveByIDs(client.Ctx, dbtypes.UpdateMatchesIsLiveByIDsParams{
-			IsLive:  true,
-			Column2: liveIDs,
-		})
+		err = client.LiveFlags.MarkLive(client.Ctx, liveIDs)
 		if err != nil {
 			client.Logger.Errorf("Error updating is_live for live matches: %v", err)
 			return err
@@ -380,12 +405,37 @@ func (client *PandaClient) GetLives() error {
 	}
 
 	// Clear is_live for non-live matches
-	err = client.DBConnector.ClearMatchesIsLiveExceptIDs(client.Ctx, liveIDs)
+	err = client.LiveFlags.ClearLiveExcept(client.Ctx, liveIDs)
 	if err != nil {
 		client.Logger.Errorf("Error clearing is_live for non-live matches: %v", err)
 		return err
 	}
 	client.Logger.Debug("Cleared is_live for non-live matches")
 
+	client.observeLives(result)
 	return nil
 }
+
+// observeLives hands a live poll to the live broadcaster once it is stored,
+// so subscribers are only told about changes readers can already see.
+// @param matches - every running match.
+func (client *PandaClient) observeLives(matches pandatypes.MatchLikes) {
+	if client.Live == nil {
+		return
+	}
+	snapshots := make([]live.Snapshot, 0, len(matches))
+	var unreadable []int32
+	for _, match := range matches {
+		snapshot, err := live.SnapshotOf(match)
+		if err != nil {
+			client.Logger.Errorf("Error reading live state of match %d: %v", match.ID, err)
+			if id, idErr := pandatypes.SafeIntToInt32(match.ID); idErr == nil {
+				unreadable = append(unreadable, id)
+			}
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	events := client.Live.Observe(snapshots, unreadable, time.Now())
+	client.Logger.Debugf("Pushed %d live events", len(events))
+}
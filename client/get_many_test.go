@@ -10,8 +10,10 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/live"
 	"github.com/feimaomiao/stalka/pandatypes"
 	"github.com/h2non/gock"
 	"github.com/nbio/st"
@@ -32,7 +34,7 @@ func TestUpdateGames(t *testing.T) {
 		Pandasecret: "fakesecret",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -104,7 +106,7 @@ func TestGetLeagues(t *testing.T) {
 		Pandasecret: "fakesecret",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -124,7 +126,7 @@ func TestGetLeagues(t *testing.T) {
 			BodyString("[" + string(leagueData) + "]")
 
 		mockDB.ExpectExec("INSERT INTO leagues").
-			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
 			WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
 		err = client.GetLeagues(false)
@@ -156,7 +158,7 @@ func TestGetSeries(t *testing.T) {
 		Pandasecret: "fakesecret",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -177,13 +179,11 @@ func TestGetSeries(t *testing.T) {
 			Reply(200).
 			BodyString("[" + string(seriesData) + "]")
 
-		// Mock LeagueExist to return that league exists
-		mockDB.ExpectQuery("SELECT COUNT").
-			WithArgs(int32(seriesResponse.LeagueID)).
-			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(1)))
+		// The league of the page exists
+		expectExisting(mockDB, "leagues", []int32{int32(seriesResponse.LeagueID)}, int32(seriesResponse.LeagueID))
 
 		mockDB.ExpectExec("INSERT INTO series").
-			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
 			WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
 		err = client.GetSeries(false)
@@ -215,7 +215,7 @@ func TestGetTournaments(t *testing.T) {
 		Pandasecret: "fakesecret",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -238,17 +238,25 @@ func TestGetTournaments(t *testing.T) {
 			Reply(200).
 			BodyString("[" + string(tournamentData) + "]")
 
-		// Mock SeriesExist to return that series exists
-		mockDB.ExpectQuery("SELECT COUNT").
-			WithArgs(int32(tournamentResponse.SerieID)).
-			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(1)))
+		// The series of the page exists
+		expectExisting(mockDB, "series", []int32{int32(tournamentResponse.SerieID)}, int32(tournamentResponse.SerieID))
 
 		mockDB.ExpectExec("INSERT INTO tournaments").
-			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
 			WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
+		// the teams taking part are created in one batch
+		teams := mockDB.ExpectBatch()
+		for _, team := range tournamentResponse.Teams {
+			teams.ExpectExec("INSERT INTO teams .+ DO UPDATE SET last_seen_at").
+				WithArgs(int32(team.ID), team.Name, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+					int32(tournamentResponse.Videogame.ID), pgxmock.AnyArg()).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+
 		err = client.GetTournaments(false)
 		st.Expect(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
 	})
 
 	t.Run("Error - MakeRequest fails", func(t *testing.T) {
@@ -276,7 +284,7 @@ func TestGetMatchPage(t *testing.T) {
 		Pandasecret: "fakesecret",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -392,7 +400,7 @@ func TestGetMatches(t *testing.T) {
 		Pandasecret: "fakesecret",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -424,19 +432,16 @@ func TestGetMatches(t *testing.T) {
 			}
 		}
 
-		// Mock database expectations for tournament existence check and match write
+		// Every page is one batch: tournament lookup, stored match lookup, then the batches
+		tournamentID := int32(matchResponse.TournamentID)
 		for i := 0; i < Pages; i++ {
-			mockDB.ExpectQuery("SELECT COUNT").
-				WithArgs(int32(matchResponse.TournamentID)).
-				WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(1)))
-
-			mockDB.ExpectExec("INSERT INTO matches").
-				WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
-				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			expectExisting(mockDB, "tournaments", []int32{tournamentID}, tournamentID)
+			expectMatchPage(mockDB, matchResponse)
 		}
 
 		err = client.GetMatches(false)
 		st.Expect(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
 	})
 }
 
@@ -452,7 +457,7 @@ func TestGetTeams(t *testing.T) {
 		Pandasecret: "fakesecret",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -470,7 +475,7 @@ func TestGetTeams(t *testing.T) {
 			BodyString("[" + string(teamData) + "]")
 
 		mockDB.ExpectExec("INSERT INTO teams").
-			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
 			WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
 		err = client.GetTeams(false)
@@ -515,7 +520,7 @@ func TestWriteMatches(t *testing.T) {
 		Pandasecret: "fakesecret",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -528,25 +533,12 @@ func TestWriteMatches(t *testing.T) {
 		err = json.Unmarshal(matchData, &match)
 		st.Assert(t, err, nil)
 
-		matches := pandatypes.MatchLikes{match}
+		// the same tournament is only checked once per page
+		matches := pandatypes.MatchLikes{match, match}
 
-		// Mock TournamentExist to return that tournament exists
-		mockDB.ExpectQuery("SELECT COUNT").
-			WithArgs(int32(match.TournamentID)).
-			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(1)))
+		expectExisting(mockDB, "tournaments", []int32{int32(match.TournamentID)}, int32(match.TournamentID))
 
-		mockDB.ExpectExec("INSERT INTO matches").
-			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
-			WillReturnResult(pgxmock.NewResult("INSERT", 1))
-
-		// Expect team checks for both teams
-		mockDB.ExpectQuery("SELECT COUNT").
-			WithArgs(int32(match.Opponents[0].Opponent.ID)).
-			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(1)))
-
-		mockDB.ExpectQuery("SELECT COUNT").
-			WithArgs(int32(match.Opponents[1].Opponent.ID)).
-			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(1)))
+		expectMatchPage(mockDB, match, match)
 
 		client.WriteMatches(matches)
 		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
@@ -572,7 +564,7 @@ func TestGetMatchPageReadError(t *testing.T) {
 		Pandasecret: "fakesecret",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -659,7 +651,7 @@ func TestGetLives(t *testing.T) {
 		Pandasecret: "test_secret",
 		Logger:      zap.NewNop().Sugar(),
 		HTTPClient:  &http.Client{},
-		DBConnector: &dbtypes.Queries{}, // Placeholder; in real tests, use pgxmock
+		Store:       pandatypes.NewPostgresSink(&dbtypes.Queries{}, nil), // Placeholder; in real tests, use pgxmock
 		Run:         0,
 		Ctx:         ctx,
 	}
@@ -675,18 +667,142 @@ func TestGetLives(t *testing.T) {
 
 	// Call GetLives (will fail at DB write, but that's OK for now)
 	// We're primarily testing the HTTP fetch and parsing
-	// Recover from any DB-related panics since DBConnector is mocked
+	// Recover from any DB-related panics since the Sink is mocked
 	defer func() {
 		if r := recover(); r != nil {
-			// Expected: panic from nil DBConnector operations
+			// Expected: panic from nil Sink operations
 			// The key test is that the HTTP request was made successfully
 		}
 	}()
 	_ = client.GetLives()
 
-	// For this test, we expect an error because DBConnector is not properly mocked
+	// For this test, we expect an error because the Sink is not properly mocked
 	// The key is that the HTTP request was made and parsed correctly
 	if !gock.IsDone() {
 		t.Fatalf("HTTP mock not called; GetLives may not be fetching from /lives")
 	}
 }
+
+func TestObserveLives(t *testing.T) {
+	client := &PandaClient{
+		Logger: zaptest.NewLogger(t).Sugar(),
+		Live:   live.NewBroadcaster(time.Now()),
+	}
+	poll := func(body string) pandatypes.MatchLikes {
+		var matches pandatypes.MatchLikes
+		st.Assert(t, json.Unmarshal([]byte(body), &matches), nil)
+		return matches
+	}
+	_, events, unsubscribe := client.Live.Subscribe(live.Filter{GameIDs: nil, TeamIDs: []int32{102}}, 0)
+	defer unsubscribe()
+
+	client.observeLives(poll(`[]`))
+	client.observeLives(poll(`[{"id": 1, "videogame": {"id": 1},
+		"opponents": [{"type": "Team", "opponent": {"id": 101}}, {"type": "Team", "opponent": {"id": 102}}],
+		"results": [{"team_id": 101, "score": 0}, {"team_id": 102, "score": 0}]}]`))
+	client.observeLives(poll(`[{"id": 1, "videogame": {"id": 1},
+		"opponents": [{"type": "Team", "opponent": {"id": 101}}, {"type": "Team", "opponent": {"id": 102}}],
+		"results": [{"team_id": 101, "score": 0}, {"team_id": 102, "score": 1}]}]`))
+
+	st.Expect(t, (<-events).Type, live.MatchStarted)
+	changed := <-events
+	st.Expect(t, changed.Type, live.ScoreChanged)
+	st.Expect(t, changed.Scores[1], live.Score{OpponentID: 102, Score: 1})
+
+	// a game position out of range makes the snapshot of the poll unreadable
+	client.observeLives(poll(`[{"id": 1, "videogame": {"id": 1},
+		"opponents": [{"type": "Team", "opponent": {"id": 101}}, {"type": "Team", "opponent": {"id": 102}}],
+		"results": [{"team_id": 101, "score": 0}, {"team_id": 102, "score": 1}],
+		"games": [{"id": 10, "position": 4294967296}]}]`))
+	client.observeLives(poll(`[{"id": 1, "videogame": {"id": 1},
+		"opponents": [{"type": "Team", "opponent": {"id": 101}}, {"type": "Team", "opponent": {"id": 102}}],
+		"results": [{"team_id": 101, "score": 1}, {"team_id": 102, "score": 1}]}]`))
+	changed = <-events
+	st.Expect(t, changed.Type, live.ScoreChanged)
+	st.Expect(t, changed.Scores[0], live.Score{OpponentID: 101, Score: 1})
+	st.Expect(t, len(events), 0)
+}
+
+// expectExisting expects one bulk existence check of table for ids that finds existing.
+func expectExisting(mockDB pgxmock.PgxPoolIface, table string, ids []int32, existing ...int32) {
+	rows := pgxmock.NewRows([]string{"id"})
+	for _, id := range existing {
+		rows.AddRow(id)
+	}
+	mockDB.ExpectQuery("SELECT id FROM " + table + " WHERE id = ANY").WithArgs(ids).WillReturnRows(rows)
+}
+
+// anyArgs builds n pgxmock.AnyArg matchers for wide inserts.
+func anyArgs(n int) []any {
+	args := make([]any, n)
+	for i := range args {
+		args[i] = pgxmock.AnyArg()
+	}
+	return args
+}
+
+// expectOutboxBatch registers the OUTBOX batch of a page of new matches: every
+// match is created, and the ones that ended also finished.
+func expectOutboxBatch(mockDB pgxmock.PgxPoolIface, matches ...pandatypes.MatchLike) {
+	events := mockDB.ExpectBatch()
+	for _, match := range matches {
+		events.ExpectExec("INSERT INTO outbox").
+			WithArgs(pandatypes.EventMatchCreated, int32(match.ID), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		if !match.EndAt.IsZero() {
+			events.ExpectExec("INSERT INTO outbox").
+				WithArgs(pandatypes.EventMatchFinished, int32(match.ID), pgxmock.AnyArg()).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+	}
+}
+
+// expectNotArchived registers the archive lookup of matches that were never archived.
+func expectNotArchived(mockDB pgxmock.PgxPoolIface, ids any) {
+	mockDB.ExpectQuery("FROM matches_archive").WithArgs(ids).WillReturnRows(pgxmock.NewRows([]string{"id"}))
+}
+
+// expectMatchPage registers the batched writes of a page of new team matches.
+func expectMatchPage(mockDB pgxmock.PgxPoolIface, matches ...pandatypes.MatchLike) {
+	mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+		WithArgs(pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}))
+	expectNotArchived(mockDB, pgxmock.AnyArg())
+	upserts := mockDB.ExpectBatch()
+	for _, match := range matches {
+		upserts.ExpectQuery("INSERT INTO matches").
+			WithArgs(anyArgs(26)...).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(match.ID)))
+	}
+	expectOutboxBatch(mockDB, matches...)
+	opponents := mockDB.ExpectBatch()
+	for _, match := range matches {
+		for range match.Opponents {
+			opponents.ExpectExec("INSERT INTO match_opponents").
+				WithArgs(anyArgs(6)...).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+	}
+	trims := mockDB.ExpectBatch()
+	for _, match := range matches {
+		trims.ExpectExec("DELETE FROM match_opponents").
+			WithArgs(int32(match.ID), int32(len(match.Opponents))).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	}
+	mockDB.ExpectExec("SELECT pg_notify").
+		WithArgs(pandatypes.ChannelMatchesChanged, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	teams := mockDB.ExpectBatch()
+	seen := make(map[int]bool)
+	for _, match := range matches {
+		for _, opponent := range match.Opponents {
+			if seen[opponent.Opponent.ID] {
+				continue
+			}
+			seen[opponent.Opponent.ID] = true
+			teams.ExpectExec("INSERT INTO teams .+ DO UPDATE SET last_seen_at").
+				WithArgs(anyArgs(7)...).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+	}
+}
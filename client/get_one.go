@@ -4,14 +4,27 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"strconv"
 
 	"github.com/feimaomiao/stalka/pandatypes"
-	"github.com/jackc/pgx/v5"
 )
 
+// ErrNotFound is returned by GetOne when PandaScore no longer knows the requested entity.
+var ErrNotFound = errors.New("entity not found upstream")
+
+// DependencyError is returned by GetOne when PandaScore served the requested
+// entity but one of the entities it depends on could not be resolved. It does
+// not unwrap to Err: a dependency that is gone upstream says nothing about the
+// requested entity, so the error never matches ErrNotFound.
+type DependencyError struct {
+	Flag GetChoice
+	ID   int
+	Err  error
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("dependency %d for flag %d could not be resolved: %v", e.ID, e.Flag, e.Err)
+}
+
 // converts the flag to a pandaapi recognized string.
 // @param flag - the flag to convert.
 // @returns the string representation of the flag and an error if one occurred.
@@ -29,6 +42,8 @@ func flagToString(flag GetChoice) (string, error) {
 		return matchesEndpoint, nil
 	case FlagTeam:
 		return "teams", nil
+	case FlagPlayer:
+		return "players", nil
 	default:
 		return "", fmt.Errorf("invalid flag: %d", flag)
 	}
@@ -82,6 +97,10 @@ func (client *PandaClient) unmarshalByFlag(body []byte, flag GetChoice) (pandaty
 		var r pandatypes.TeamLike
 		err = json.Unmarshal(body, &r)
 		result = r
+	case FlagPlayer:
+		var r pandatypes.PlayerLike
+		err = json.Unmarshal(body, &r)
+		result = r
 	default:
 		return nil, fmt.Errorf("invalid flag: %d", flag)
 	}
@@ -94,24 +113,18 @@ func (client *PandaClient) unmarshalByFlag(body []byte, flag GetChoice) (pandaty
 	return result, nil
 }
 
-// ensureDependencies checks and creates dependencies for the parsed entity.
+// ensureDependencies resolves the parent of the parsed entity, which in turn
+// resolves its own parent, see Resolve. Failures are returned as a
+// DependencyError.
 func (client *PandaClient) ensureDependencies(result pandatypes.PandaDataLike, flag GetChoice) error {
 	dep := client.getDependency(result, flag)
 	if dep == nil {
 		return nil // No dependencies to check
 	}
 
-	exists, err := client.ExistCheck(dep.id, dep.flag)
-	if err != nil {
-		client.Logger.Errorf("Error checking if %s %d exists: %v", dep.name, dep.id, err)
-		return err
-	}
-
-	if !exists {
-		if err = client.GetOne(dep.id, dep.flag); err != nil {
-			client.Logger.Errorf("Error getting %s %d: %v", dep.name, dep.id, err)
-			return err
-		}
+	if err := client.Resolve(dep.id, dep.flag); err != nil {
+		client.Logger.Errorf("Error resolving %s %d: %v", dep.name, dep.id, err)
+		return &DependencyError{Flag: dep.flag, ID: dep.id, Err: err}
 	}
 
 	return nil
@@ -159,39 +172,26 @@ func (client *PandaClient) getDependency(result pandatypes.PandaDataLike, flag G
 				name: "tournament",
 			}
 		}
-	// No dependencies for FlagGame and FlagTeam
+	// No dependencies for FlagGame, FlagTeam and FlagPlayer
 	case FlagTeam:
 		return nil
+	case FlagPlayer:
+		return nil
 	case FlagGame:
 		return nil
 	}
 	return nil
 }
 
-// GetOne gets a single entity from the Pandascore API.
+// GetOne gets a single entity from the Pandascore API and writes it together
+// with its missing dependencies and the teams and players embedded in it.
 // @param id - the ID of the entity to get.
 // @param flag - the type of entity to get.
-// @returns an error if one occurred.
+// @returns ErrNotFound if PandaScore no longer knows the entity, a
+// DependencyError if one of its dependencies could not be resolved, or any
+// other error that occurred.
 func (client *PandaClient) GetOne(id int, flag GetChoice) error {
-	searchString, err := flagToString(flag)
-	if err != nil {
-		client.Logger.Error("Error converting flag to string: %v", err)
-		return err
-	}
-	client.Logger.Debugf("Getting %s %d", searchString, id)
-	resp, err := client.MakeRequest([]string{searchString, strconv.Itoa(id)}, nil)
-	if err != nil {
-		client.Logger.Error("Error making request to Pandascore API: %v", err)
-		return err
-	}
-	// Check if the response status code is 200 OK
-	if resp.StatusCode != http.StatusOK {
-		client.Logger.Error("Error: received status code %d", resp.StatusCode)
-		return fmt.Errorf("received status code %d", resp.StatusCode)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := client.fetchOne(id, flag)
 	if err != nil {
 		return err
 	}
@@ -199,91 +199,163 @@ func (client *PandaClient) GetOne(id int, flag GetChoice) error {
 	result, err := client.ParseResponse(body, flag)
 	if err != nil {
 		client.Logger.Error("Error parsing response: %v", err)
+		return err
 	}
 
-	err = result.ToRow().WriteToDB(client.Ctx, client.DBConnector)
+	err = client.writeRow(result.ToRow())
 	if err != nil {
 		return err
 	}
+	client.remember(flag, id)
+	client.writeEmbedded(result)
 	return nil
 }
 
-// WriteMatches writes the matches to the database.
+// WriteMatches writes one page of matches to the database in batches, inside one
+// transaction together with the tournaments and opponents it pulls in.
+// Missing tournaments are resolved with one lookup for the whole page and fetched
+// once each, under their own savepoint; matches whose tournament cannot be fetched
+// are skipped and queued for a retry. Failing rows are logged one by one and
+// skipped, following the SkipRow policy of matches.
 // @param matches - the matches to write.
 func (client *PandaClient) WriteMatches(matches pandatypes.MatchLikes) {
+	tournamentIDs := make([]int, 0, len(matches))
 	for _, match := range matches {
-		exists, err := client.ExistCheck(match.TournamentID, FlagTournament)
-		if err != nil {
-			client.Logger.Error(err)
-			continue
-		}
-		if !exists {
-			err = client.GetOne(match.TournamentID, FlagTournament)
+		tournamentIDs = append(tournamentIDs, match.TournamentID)
+	}
+	missing, err := client.MissingIDs(FlagTournament, tournamentIDs)
+	if err != nil {
+		client.Logger.Errorf("Skipping match page, tournaments could not be checked: %v", err)
+		return
+	}
+	var unresolved []*UnresolvedError
+	err = client.inTransaction(func(page *PandaClient) error {
+		unresolved = page.writeMatchPage(matches, missing)
+		return nil
+	})
+	if err != nil {
+		client.Logger.Errorf("Error committing match page: %v", err)
+		return
+	}
+	for _, match := range unresolved {
+		client.queueRetry(match)
+	}
+}
+
+// writeMatchPage writes the matches of one page, see WriteMatches.
+// @param missing - the tournaments of the page that are not in the database yet.
+// @returns the matches skipped because their tournament could not be resolved.
+func (client *PandaClient) writeMatchPage(matches pandatypes.MatchLikes, missing map[int]bool) []*UnresolvedError {
+	var unresolved []*UnresolvedError
+	unavailable := make(map[int]error)
+	rows := make([]pandatypes.MatchRow, 0, len(matches))
+	for _, match := range matches {
+		id := match.TournamentID
+		if missing[id] && unavailable[id] == nil && !client.knows(FlagTournament, id) {
+			err := client.savepoint(func() error { return client.GetOne(id, FlagTournament) })
 			if err != nil {
-				client.Logger.Error(err)
-				continue
+				client.Logger.Errorf("Error getting tournament %d: %v", id, err)
+				unavailable[id] = err
 			}
 		}
+		if err := unavailable[id]; err != nil {
+			unresolved = append(unresolved, &UnresolvedError{Flag: FlagMatch, ID: match.ID, Err: err})
+			continue
+		}
 		client.Logger.Debugf("Writing match %s", match.Name)
 		row, success := match.ToRow().(pandatypes.MatchRow)
 		if !success {
 			client.Logger.Errorf("Error converting match row to match row (??), %v", row)
 			continue
 		}
-		err = row.WriteToDB(client.Ctx, client.DBConnector)
-		if err != nil {
-			client.Logger.Error(err)
-			continue
-		}
-		client.checkTeam(match)
+		rows = append(rows, row)
 	}
+	failed := make(map[int]bool)
+	counts, rowErrs := client.Store.WriteMatchPage(client.Ctx, rows)
+	client.Writes.Add(counts)
+	for _, rowErr := range rowErrs {
+		client.Logger.Errorf("Error writing match %d: %v", rowErr.ID, rowErr.Err)
+		failed[rowErr.ID] = true
+	}
+	for _, match := range unresolved {
+		failed[match.ID] = true
+	}
+	client.writeMissingEntities(client.opponentEntities(matches, failed))
+	return unresolved
 }
 
-// checkTeam checks if the teams in the match exist in the database.
-// @param match - the match to check.
-func (client *PandaClient) checkTeam(match pandatypes.MatchLike) {
-	if match.WinnerType != "Team" {
-		client.Logger.Debugf("Match %d is not a team match, but is a %s match", match.ID, match.WinnerType)
-		return
-	}
-	for _, opponent := range match.Opponents {
-		exists, err := client.ExistCheck(opponent.Opponent.ID, FlagTeam)
-		if err != nil {
-			client.Logger.Error(err)
+// opponentEntities collects the distinct teams and players of the written matches,
+// so every MATCH_OPPONENTS row can be resolved.
+// @param matches - the matches of the page.
+// @param failed - the IDs of matches that could not be written.
+// @returns the team and player rows to create if missing.
+func (client *PandaClient) opponentEntities(
+	matches pandatypes.MatchLikes,
+	failed map[int]bool,
+) ([]pandatypes.TeamRow, []pandatypes.PlayerRow) {
+	var teams []pandatypes.TeamRow
+	var players []pandatypes.PlayerRow
+	seenTeams := make(map[int]bool)
+	seenPlayers := make(map[int]bool)
+	for _, match := range matches {
+		if failed[match.ID] {
 			continue
 		}
-		if !exists {
-			client.Logger.Debugf("Team %s does not exist", opponent.Opponent.Name)
-			err = pandatypes.TeamRow{
-				ID:        opponent.Opponent.ID,
-				GameID:    match.Videogame.ID,
-				Name:      opponent.Opponent.Name,
-				Acronym:   opponent.Opponent.Acronym,
-				Slug:      opponent.Opponent.Slug,
-				ImageLink: opponent.Opponent.ImageURL,
-			}.WriteToDB(client.Ctx, client.DBConnector)
-			if err != nil {
-				client.Logger.Error(err)
-				continue
+		for _, opponent := range match.Opponents {
+			switch opponent.Type {
+			case pandatypes.OpponentTypeTeam:
+				if seenTeams[opponent.Opponent.ID] {
+					continue
+				}
+				seenTeams[opponent.Opponent.ID] = true
+				teams = append(teams, pandatypes.TeamRow{
+					ID:         opponent.Opponent.ID,
+					GameID:     match.Videogame.ID,
+					Name:       opponent.Opponent.Name,
+					Acronym:    opponent.Opponent.Acronym,
+					Slug:       opponent.Opponent.Slug,
+					ImageLink:  opponent.Opponent.ImageURL,
+					ModifiedAt: opponent.Opponent.ModifiedAt,
+				})
+			case pandatypes.OpponentTypePlayer:
+				if seenPlayers[opponent.Opponent.ID] {
+					continue
+				}
+				seenPlayers[opponent.Opponent.ID] = true
+				players = append(players, pandatypes.PlayerRow{
+					ID:          opponent.Opponent.ID,
+					GameID:      match.Videogame.ID,
+					Name:        opponent.Opponent.Name,
+					FirstName:   opponent.Opponent.FirstName,
+					LastName:    opponent.Opponent.LastName,
+					Nationality: opponent.Opponent.Nationality,
+					Slug:        opponent.Opponent.Slug,
+					ImageLink:   opponent.Opponent.ImageURL,
+					ModifiedAt:  opponent.Opponent.ModifiedAt,
+				})
+			default:
+				client.Logger.Debugf("Match %d has an opponent of unknown type %s", match.ID, opponent.Type)
 			}
-		} else {
-			client.Logger.Debugf("Team %s exists", opponent.Opponent.Name)
 		}
 	}
+	return teams, players
 }
 
-// ExistCheck checks if an entity exists in the database.
+// ExistCheck checks if an entity exists in the database, answering from the ID
+// cache when it can. Tombstoned entities count as missing.
 // @param id - the ID of the entity to check.
 // @param flag - the type of entity to check.
 // @returns an error if one occurred.
 func (client *PandaClient) ExistCheck(id int, flag GetChoice) (bool, error) {
-	var dbResult int64
-	var err error
 	stringFlag, err := flagToString(flag)
 	if err != nil {
 		client.Logger.Error("Error converting flag to string: %v", err)
 		return false, err
 	}
+	if client.knows(flag, id) {
+		client.Logger.Debugf("%s with ID %d is cached", stringFlag, id)
+		return true, nil
+	}
 	client.Logger.Debugf("Checking if %s with ID %d exists", stringFlag, id)
 
 	// Safely convert int to int32
@@ -293,32 +365,16 @@ func (client *PandaClient) ExistCheck(id int, flag GetChoice) (bool, error) {
 		return false, err
 	}
 
-	switch flag {
-	case FlagGame:
-		dbResult, err = client.DBConnector.GameExist(client.Ctx, id32)
-	case FlagLeague:
-		dbResult, err = client.DBConnector.LeagueExist(client.Ctx, id32)
-	case FlagSeries:
-		dbResult, err = client.DBConnector.SeriesExist(client.Ctx, id32)
-	case FlagTournament:
-		dbResult, err = client.DBConnector.TournamentExist(client.Ctx, id32)
-	case FlagMatch:
-		dbResult, err = client.DBConnector.MatchExist(client.Ctx, id32)
-	case FlagTeam:
-		dbResult, err = client.DBConnector.TeamExist(client.Ctx, id32)
-	// this would never happen as we vet the flags before calling
-	default:
-		client.Logger.Error("Invalid flag")
-		return false, fmt.Errorf("invalid flag: %d", flag)
-	}
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+	exists, err := client.Store.Exists(client.Ctx, flag, id32)
+	if err != nil {
 		client.Logger.Errorf("Error checking if entity exists: %v", err)
 		return false, err
 	}
-	if dbResult == 0 {
+	if !exists {
 		client.Logger.Debugf("%s with ID %d does not exist", stringFlag, id)
 		return false, nil
 	}
 	client.Logger.Debugf("%s with ID %d exists", stringFlag, id)
+	client.remember(flag, id)
 	return true, nil
 }
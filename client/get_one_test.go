@@ -13,7 +13,7 @@ import (
 	"github.com/feimaomiao/stalka/dbtypes"
 	"github.com/feimaomiao/stalka/pandatypes"
 	"github.com/h2non/gock"
-	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5"
 	"github.com/nbio/st"
 	"github.com/pashagolub/pgxmock/v4"
 	"go.uber.org/zap/zaptest"
@@ -62,6 +62,12 @@ func TestFlagToString(t *testing.T) {
 			expectedString: "teams",
 			expectError:    false,
 		},
+		{
+			name:           "Player flag",
+			flag:           FlagPlayer,
+			expectedString: "players",
+			expectError:    false,
+		},
 		{
 			name:           "Invalid flag",
 			flag:           GetChoice(999),
@@ -325,6 +331,7 @@ func TestGetChoiceConstants(t *testing.T) {
 	st.Expect(t, FlagTournament, GetChoice(3))
 	st.Expect(t, FlagMatch, GetChoice(4))
 	st.Expect(t, FlagTeam, GetChoice(5))
+	st.Expect(t, FlagPlayer, GetChoice(6))
 }
 
 func TestExistCheck(t *testing.T) {
@@ -340,7 +347,7 @@ func TestExistCheck(t *testing.T) {
 		Pandasecret: "",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         t.Context(),
 	}
@@ -465,6 +472,15 @@ func TestExistCheck(t *testing.T) {
 	val, err = client.ExistCheck(1, GetChoice(5))
 	st.Assert(t, err, nil)
 	st.Expect(t, val, true)
+
+	// check player existence
+	mockDB.ExpectQuery("SELECT COUNT\\(\\*\\) FROM players").
+		WithArgs(expectedArgs).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int32(1)))
+
+	val, err = client.ExistCheck(1, FlagPlayer)
+	st.Assert(t, err, nil)
+	st.Expect(t, val, true)
 }
 
 func TestParseResponse(t *testing.T) {
@@ -479,7 +495,7 @@ func TestParseResponse(t *testing.T) {
 		Pandasecret: "",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -543,7 +559,7 @@ func TestEnsureDependencies(t *testing.T) {
 		Pandasecret: "",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -648,7 +664,7 @@ func TestGetOne(t *testing.T) {
 		Pandasecret: "fakesecret",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -699,12 +715,14 @@ func TestWriteMatchesErrorPaths(t *testing.T) {
 	defer mockDB.Close()
 	mockQueries := dbtypes.New(mockDB)
 
+	sink := pandatypes.NewPostgresSink(mockQueries, nil)
 	client := &PandaClient{
 		BaseURL:     "",
 		Pandasecret: "",
 		Logger:      logger,
 		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
+		Store:       sink,
+		Retries:     sink,
 		Run:         0,
 		Ctx:         context.Background(),
 	}
@@ -719,9 +737,9 @@ func TestWriteMatchesErrorPaths(t *testing.T) {
 
 		matches := pandatypes.MatchLikes{match}
 
-		// Mock TournamentExist error
-		mockDB.ExpectQuery("SELECT COUNT").
-			WithArgs(int32(match.TournamentID)).
+		// The tournament lookup fails, the page is skipped
+		mockDB.ExpectQuery("SELECT id FROM tournaments WHERE id = ANY").
+			WithArgs([]int32{int32(match.TournamentID)}).
 			WillReturnError(fmt.Errorf("database error"))
 
 		client.WriteMatches(matches)
@@ -739,9 +757,7 @@ func TestWriteMatchesErrorPaths(t *testing.T) {
 		matches := pandatypes.MatchLikes{match}
 
 		// Mock that tournament doesn't exist
-		mockDB.ExpectQuery("SELECT COUNT").
-			WithArgs(int32(match.TournamentID)).
-			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		expectExisting(mockDB, "tournaments", []int32{int32(match.TournamentID)})
 
 		// GetOne will fail because there's no HTTP mock
 		client.WriteMatches(matches)
@@ -759,96 +775,92 @@ func TestWriteMatchesErrorPaths(t *testing.T) {
 		matches := pandatypes.MatchLikes{match}
 
 		// Mock that tournament exists
-		mockDB.ExpectQuery("SELECT COUNT").
-			WithArgs(int32(match.TournamentID)).
-			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(1)))
-
-		// Mock WriteToDB error
+		expectExisting(mockDB, "tournaments", []int32{int32(match.TournamentID)}, int32(match.TournamentID))
+
+		// The batch is rejected, so the match is retried on its own and fails again
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+		expectNotArchived(mockDB, pgxmock.AnyArg())
+		mockDB.ExpectBatch().
+			ExpectQuery("INSERT INTO matches").
+			WithArgs(anyArgs(26)...).
+			WillReturnError(fmt.Errorf("database error"))
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").
+			WithArgs(int32(match.ID)).
+			WillReturnError(pgx.ErrNoRows)
+		expectNotArchived(mockDB, []int32{int32(match.ID)})
 		mockDB.ExpectExec("INSERT INTO matches").
-			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WithArgs(anyArgs(26)...).
 			WillReturnError(fmt.Errorf("database error"))
 
+		// no opponents are created for a match that was not written
 		client.WriteMatches(matches)
-		// Should continue on error, no assertion needed
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
 	})
 }
 
-func TestCheckTeam(t *testing.T) {
-	//create logger
-	logger := zaptest.NewLogger(t).Sugar()
-	mockDB, err := pgxmock.NewPool()
-	st.Assert(t, err, nil)
-	defer mockDB.Close()
-	mockQueries := dbtypes.New(mockDB)
-
+func TestOpponentEntities(t *testing.T) {
 	client := &PandaClient{
-		BaseURL:     "",
-		Pandasecret: "",
-		Logger:      logger,
-		HTTPClient:  &http.Client{},
-		DBConnector: mockQueries,
-		Run:         0,
-		Ctx:         t.Context(),
+		Logger: zaptest.NewLogger(t).Sugar(),
+		Ctx:    t.Context(),
 	}
 
-	//setup MatchLike material by opening static/fetch_data/matches.json
 	matchData, err := os.ReadFile("../static/fetch_data/matches.json")
 	st.Assert(t, err, nil)
-	pdDataLike, err := client.unmarshalByFlag(matchData, FlagMatch)
+	var match pandatypes.MatchLike
+	err = json.Unmarshal(matchData, &match)
 	st.Assert(t, err, nil)
-	st.Reject(t, pdDataLike, nil)
-	match, ok := pdDataLike.(pandatypes.MatchLike)
-	st.Assert(t, ok, true)
-	st.Reject(t, match, nil)
-	//we first run the test where the winner type is not a team
-	match.WinnerType = "Player"
-	// this has no outputs
-	client.checkTeam(match)
-	r := recover()
-	st.Expect(t, r, nil)
-	match, ok = pdDataLike.(pandatypes.MatchLike)
-	st.Assert(t, ok, true)
-	st.Reject(t, match, nil)
-
-	// we first set two cases where the request errors
-	cancelContext, cancel := context.WithCancel(context.Background())
-	client.Ctx = cancelContext
-	// the first case fails because the client.ExistCheck fails (out of range)
-	match.Opponents[0].Opponent.ID = math.MaxInt32 + 1
-	client.checkTeam(match)
-	r = recover()
-	st.Expect(t, r, nil)
-	match.Opponents[0].Opponent.ID = 1
-
-	mockDB.ExpectQuery("SELECT COUNT").WithArgs(int32(1)).
-		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int32(1)))
-	mockDB.ExpectQuery("SELECT COUNT").WithArgs(int32(match.Opponents[1].Opponent.ID)).
-		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int32(0)))
-	client.checkTeam(match)
-	cancel()
-	r = recover()
-	st.Expect(t, r, nil)
-	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
-
-	client.Ctx = context.Background()
-	match = pdDataLike.(pandatypes.MatchLike)
-	mockDB.ExpectQuery("SELECT COUNT").WithArgs(int32(1)).
-		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int32(1)))
-	mockDB.ExpectQuery("SELECT COUNT").WithArgs(int32(match.Opponents[1].Opponent.ID)).
-		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int32(0)))
-	mockDB.ExpectExec("INSERT INTO teams").
-		WithArgs(
-			int32(match.Opponents[1].Opponent.ID),
-			match.Opponents[1].Opponent.Name,
-			pgtype.Text{String: match.Opponents[1].Opponent.Slug, Valid: match.Opponents[1].Opponent.Slug != ""},
-			pgtype.Text{String: match.Opponents[1].Opponent.Acronym,
-				Valid: match.Opponents[1].Opponent.Acronym != ""},
-			pgtype.Text{String: match.Opponents[1].Opponent.ImageURL, Valid: match.Opponents[1].Opponent.ImageURL != ""},
-			int32(match.Videogame.ID)).
-		WillReturnResult(pgxmock.NewResult("INSERT", 1))
-	t.Log(match.Opponents)
-	client.checkTeam(match)
-	r = recover()
-	st.Expect(t, r, nil)
-	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+
+	t.Run("Teams are deduplicated across the page", func(t *testing.T) {
+		teams, players := client.opponentEntities(pandatypes.MatchLikes{match, match}, map[int]bool{})
+		st.Assert(t, len(teams), 2)
+		st.Expect(t, len(players), 0)
+		st.Expect(t, teams[1], pandatypes.TeamRow{
+			ID:         match.Opponents[1].Opponent.ID,
+			GameID:     match.Videogame.ID,
+			Name:       match.Opponents[1].Opponent.Name,
+			Acronym:    match.Opponents[1].Opponent.Acronym,
+			Slug:       match.Opponents[1].Opponent.Slug,
+			ImageLink:  match.Opponents[1].Opponent.ImageURL,
+			ModifiedAt: match.Opponents[1].Opponent.ModifiedAt,
+		})
+	})
+
+	t.Run("Failed matches and unknown types are skipped", func(t *testing.T) {
+		teams, players := client.opponentEntities(pandatypes.MatchLikes{match}, map[int]bool{match.ID: true})
+		st.Expect(t, len(teams), 0)
+		st.Expect(t, len(players), 0)
+
+		unknown := match
+		unknown.Opponents = append(match.Opponents[:0:0], match.Opponents...)
+		unknown.Opponents[0].Type = "Unknown"
+		teams, _ = client.opponentEntities(pandatypes.MatchLikes{unknown}, map[int]bool{})
+		st.Expect(t, len(teams), 1)
+	})
+
+	t.Run("Player opponents become players", func(t *testing.T) {
+		player := match
+		player.Opponents = append(match.Opponents[:0:0], match.Opponents[:1]...)
+		player.Opponents[0].Type = pandatypes.OpponentTypePlayer
+		player.Opponents[0].Opponent.ID = 42
+		player.Opponents[0].Opponent.Name = "Tokido"
+		player.Opponents[0].Opponent.FirstName = "Hajime"
+		player.Opponents[0].Opponent.LastName = "Taniguchi"
+		player.Opponents[0].Opponent.Nationality = "JP"
+		teams, players := client.opponentEntities(pandatypes.MatchLikes{player}, map[int]bool{})
+		st.Expect(t, len(teams), 0)
+		st.Assert(t, len(players), 1)
+		st.Expect(t, players[0], pandatypes.PlayerRow{
+			ID:          42,
+			GameID:      match.Videogame.ID,
+			Name:        "Tokido",
+			FirstName:   "Hajime",
+			LastName:    "Taniguchi",
+			Nationality: "JP",
+			Slug:        player.Opponents[0].Opponent.Slug,
+			ImageLink:   player.Opponents[0].Opponent.ImageURL,
+			ModifiedAt:  player.Opponents[0].Opponent.ModifiedAt,
+		})
+	})
 }
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/feimaomiao/stalka/pandatypes"
+)
+
+// IDCacheSize is the number of known IDs kept per entity type.
+const IDCacheSize = 50000
+
+// IDCache remembers which entity IDs exist in the database, so that resolving
+// dependencies does not cost a query per foreign key. Every entity type keeps
+// at most capacity IDs and evicts the least recently used one when full.
+// A nil *IDCache knows nothing and ignores writes. It is safe for concurrent use.
+type IDCache struct {
+	mu       sync.Mutex
+	capacity int
+	sets     map[GetChoice]*idSet
+}
+
+// idSet is a least recently used set of IDs of one entity type.
+type idSet struct {
+	order    *list.List
+	elements map[int]*list.Element
+}
+
+// NewIDCache creates an empty cache.
+// @param capacity - the number of IDs kept per entity type.
+// @returns the cache.
+func NewIDCache(capacity int) *IDCache {
+	return &IDCache{
+		mu:       sync.Mutex{},
+		capacity: capacity,
+		sets:     make(map[GetChoice]*idSet),
+	}
+}
+
+// Has reports whether the ID is known to exist and marks it as recently used.
+func (cache *IDCache) Has(flag GetChoice, id int) bool {
+	if cache == nil {
+		return false
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	set, ok := cache.sets[flag]
+	if !ok {
+		return false
+	}
+	element, ok := set.elements[id]
+	if ok {
+		set.order.MoveToFront(element)
+	}
+	return ok
+}
+
+// Add records the IDs as existing, evicting the least recently used ones when full.
+func (cache *IDCache) Add(flag GetChoice, ids ...int) {
+	if cache == nil || cache.capacity <= 0 {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	set, ok := cache.sets[flag]
+	if !ok {
+		set = &idSet{order: list.New(), elements: make(map[int]*list.Element)}
+		cache.sets[flag] = set
+	}
+	for _, id := range ids {
+		if element, found := set.elements[id]; found {
+			set.order.MoveToFront(element)
+			continue
+		}
+		set.elements[id] = set.order.PushFront(id)
+		if set.order.Len() > cache.capacity {
+			oldest := set.order.Back()
+			set.order.Remove(oldest)
+			delete(set.elements, oldest.Value.(int)) //nolint:forcetypeassert // only ints are pushed
+		}
+	}
+}
+
+// Forget drops an ID, e.g. after it was tombstoned.
+func (cache *IDCache) Forget(flag GetChoice, id int) {
+	if cache == nil {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	set, ok := cache.sets[flag]
+	if !ok {
+		return
+	}
+	if element, found := set.elements[id]; found {
+		set.order.Remove(element)
+		delete(set.elements, id)
+	}
+}
+
+// Len returns the number of IDs known for an entity type.
+func (cache *IDCache) Len(flag GetChoice) int {
+	if cache == nil {
+		return 0
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if set, ok := cache.sets[flag]; ok {
+		return set.order.Len()
+	}
+	return 0
+}
+
+// knownID is an ID written inside a page transaction that is not committed yet.
+type knownID struct {
+	flag GetChoice
+	id   int
+}
+
+// cachedFlags lists the entity types that are dependencies of others and are
+// therefore warmed at startup.
+func cachedFlags() []GetChoice {
+	return []GetChoice{FlagGame, FlagLeague, FlagSeries, FlagTournament, FlagTeam, FlagPlayer}
+}
+
+// WarmIDCache loads the most recently seen IDs of every cached entity type.
+// @returns an error if one of the lookups fails.
+func (client *PandaClient) WarmIDCache() error {
+	if client.IDs == nil {
+		return nil
+	}
+	limit, err := pandatypes.SafeIntToInt32(client.IDs.capacity)
+	if err != nil {
+		return err
+	}
+	for _, flag := range cachedFlags() {
+		ids, err := client.Store.KnownIDs(client.Ctx, flag, limit)
+		if err != nil {
+			return err
+		}
+		client.IDs.Add(flag, toInts(ids)...)
+		client.Logger.Infof("Warmed ID cache for flag %d with %d IDs", flag, len(ids))
+	}
+	return nil
+}
+
+// knows reports whether an entity is known to exist, either from the cache or
+// from a write of the current page transaction.
+func (client *PandaClient) knows(flag GetChoice, id int) bool {
+	if client.staged != nil {
+		for _, known := range *client.staged {
+			if known.flag == flag && known.id == id {
+				return true
+			}
+		}
+	}
+	return client.IDs.Has(flag, id)
+}
+
+// remember records entities as existing. Inside a page transaction they are
+// staged and only reach the cache once the transaction commits.
+func (client *PandaClient) remember(flag GetChoice, ids ...int) {
+	if client.staged == nil {
+		client.IDs.Add(flag, ids...)
+		return
+	}
+	for _, id := range ids {
+		*client.staged = append(*client.staged, knownID{flag: flag, id: id})
+	}
+}
+
+// MissingIDs resolves which entities are not in the database with a single
+// query for the IDs the cache does not know. Tombstoned entities count as
+// missing, so they are fetched again and resurrected if PandaScore still has them.
+// @param flag - the type of the entities.
+// @param ids - the IDs to check, duplicates are allowed.
+// @returns the set of missing IDs and an error if the lookup failed.
+func (client *PandaClient) MissingIDs(flag GetChoice, ids []int) (map[int]bool, error) {
+	missing := make(map[int]bool)
+	unknown := make([]int32, 0, len(ids))
+	for _, id := range ids {
+		if missing[id] || client.knows(flag, id) {
+			continue
+		}
+		id32, err := pandatypes.SafeIntToInt32(id)
+		if err != nil {
+			return nil, err
+		}
+		missing[id] = true
+		unknown = append(unknown, id32)
+	}
+	if len(unknown) == 0 {
+		return missing, nil
+	}
+	existing, err := client.Store.ExistingIDs(client.Ctx, flag, unknown)
+	if err != nil {
+		client.Logger.Errorf("Error checking which of %d IDs exist for flag %d: %v", len(unknown), flag, err)
+		return nil, err
+	}
+	for _, id := range existing {
+		delete(missing, int(id))
+	}
+	client.remember(flag, toInts(existing)...)
+	client.Logger.Debugf("%d of %d IDs for flag %d are missing", len(missing), len(unknown), flag)
+	return missing, nil
+}
+
+func toInts(ids []int32) []int {
+	out := make([]int, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, int(id))
+	}
+	return out
+}
@@ -0,0 +1,269 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"encoding/json"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/pandatypes"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+	"go.uber.org/zap/zaptest"
+)
+
+func newCachedClient(t *testing.T, mockDB pgxmock.PgxPoolIface) *PandaClient {
+	sink := pandatypes.NewPostgresSink(dbtypes.New(mockDB), mockDB)
+	return &PandaClient{
+		BaseURL:     "https://api.pandascore.io",
+		Pandasecret: "fakesecret",
+		Logger:      zaptest.NewLogger(t).Sugar(),
+		HTTPClient:  &http.Client{},
+		Store:       sink,
+		Retries:     sink,
+		IDs:         NewIDCache(IDCacheSize),
+		Run:         0,
+		Writes:      NewWriteTally(),
+		Ctx:         context.Background(),
+	}
+}
+
+func TestIDCache(t *testing.T) {
+	t.Run("Evicts the least recently used ID", func(t *testing.T) {
+		cache := NewIDCache(2)
+		cache.Add(FlagLeague, 1, 2)
+		st.Expect(t, cache.Has(FlagLeague, 1), true)
+		cache.Add(FlagLeague, 3)
+		st.Expect(t, cache.Has(FlagLeague, 1), true)
+		st.Expect(t, cache.Has(FlagLeague, 2), false)
+		st.Expect(t, cache.Has(FlagLeague, 3), true)
+		st.Expect(t, cache.Len(FlagLeague), 2)
+	})
+
+	t.Run("Entity types are kept apart", func(t *testing.T) {
+		cache := NewIDCache(IDCacheSize)
+		cache.Add(FlagTeam, 1)
+		st.Expect(t, cache.Has(FlagTeam, 1), true)
+		st.Expect(t, cache.Has(FlagPlayer, 1), false)
+		cache.Forget(FlagTeam, 1)
+		st.Expect(t, cache.Has(FlagTeam, 1), false)
+		cache.Forget(FlagPlayer, 1)
+	})
+
+	t.Run("Nil cache knows nothing", func(t *testing.T) {
+		var cache *IDCache
+		cache.Add(FlagGame, 1)
+		cache.Forget(FlagGame, 1)
+		st.Expect(t, cache.Has(FlagGame, 1), false)
+		st.Expect(t, cache.Len(FlagGame), 0)
+	})
+}
+
+func TestMissingIDs(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+	client.IDs.Add(FlagLeague, 1)
+
+	t.Run("Only unknown IDs are looked up, once each", func(t *testing.T) {
+		expectExisting(mockDB, "leagues", []int32{2, 3}, 2)
+
+		missing, err := client.MissingIDs(FlagLeague, []int{1, 2, 3, 3})
+		st.Assert(t, err, nil)
+		st.Expect(t, missing, map[int]bool{3: true})
+		st.Expect(t, client.IDs.Has(FlagLeague, 2), true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Known IDs need no query", func(t *testing.T) {
+		missing, err := client.MissingIDs(FlagLeague, []int{1, 2})
+		st.Assert(t, err, nil)
+		st.Expect(t, len(missing), 0)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - lookup fails", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT id FROM series WHERE id = ANY").
+			WithArgs([]int32{7}).
+			WillReturnError(fmt.Errorf("database error"))
+
+		_, err := client.MissingIDs(FlagSeries, []int{7})
+		st.Reject(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - matches have no bulk check", func(t *testing.T) {
+		_, err := client.MissingIDs(FlagMatch, []int{1})
+		st.Reject(t, err, nil)
+	})
+}
+
+func TestExistCheckUsesCache(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+
+	mockDB.ExpectQuery("SELECT COUNT").
+		WithArgs(int32(5)).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(1)))
+
+	for range 2 {
+		exists, err := client.ExistCheck(5, FlagTournament)
+		st.Assert(t, err, nil)
+		st.Expect(t, exists, true)
+	}
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+
+	// tombstoning invalidates the cached ID
+	mockDB.ExpectExec("UPDATE tournaments SET deleted_at").
+		WithArgs(int32(5)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	st.Expect(t, client.Tombstone(5, FlagTournament), nil)
+	st.Expect(t, client.IDs.Has(FlagTournament, 5), false)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestWarmIDCache(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+
+	for _, table := range []string{"games", "leagues", "series", "tournaments", "teams", "players"} {
+		mockDB.ExpectQuery("SELECT id FROM " + table + " WHERE deleted_at IS NULL").
+			WithArgs(int32(IDCacheSize)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(1)).AddRow(int32(2)))
+	}
+
+	st.Expect(t, client.WarmIDCache(), nil)
+	for _, flag := range cachedFlags() {
+		st.Expect(t, client.IDs.Len(flag), 2)
+	}
+	st.Expect(t, client.IDs.Len(FlagMatch), 0)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+
+	t.Run("Error - lookup fails", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT id FROM games").
+			WithArgs(int32(IDCacheSize)).
+			WillReturnError(fmt.Errorf("database error"))
+		st.Reject(t, client.WarmIDCache(), nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Without a cache nothing is loaded", func(t *testing.T) {
+		uncached := *client
+		uncached.IDs = nil
+		st.Expect(t, uncached.WarmIDCache(), nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestStagedIDs(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+
+	t.Run("IDs of rolled back rows never reach the cache", func(t *testing.T) {
+		mockDB.ExpectBegin()
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		mockDB.ExpectCommit()
+
+		err := client.WritePage(FlagLeague, 2, func(page *PandaClient, i int) error {
+			page.remember(FlagLeague, 10+i)
+			st.Expect(t, page.knows(FlagLeague, 10+i), true)
+			// nothing is visible to other clients before the commit
+			st.Expect(t, client.IDs.Has(FlagLeague, 10+i), false)
+			if i == 1 {
+				return fmt.Errorf("foreign key violation")
+			}
+			return nil
+		})
+		st.Expect(t, err, nil)
+		st.Expect(t, client.IDs.Has(FlagLeague, 10), true)
+		st.Expect(t, client.IDs.Has(FlagLeague, 11), false)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("IDs of an aborted page never reach the cache", func(t *testing.T) {
+		mockDB.ExpectBegin()
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		mockDB.ExpectRollback()
+
+		err := client.WritePage(FlagGame, 2, func(page *PandaClient, i int) error {
+			page.remember(FlagGame, 20+i)
+			if i == 1 {
+				return fmt.Errorf("database error")
+			}
+			return nil
+		})
+		st.Reject(t, err, nil)
+		st.Expect(t, client.IDs.Has(FlagGame, 20), false)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestWriteMatchesWithCache(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+
+	matchData, err := os.ReadFile("../static/fetch_data/matches.json")
+	st.Assert(t, err, nil)
+	var match pandatypes.MatchLike
+	st.Assert(t, json.Unmarshal(matchData, &match), nil)
+
+	// the tournament and the teams are known, so only the match itself is written
+	client.IDs.Add(FlagTournament, match.TournamentID)
+	for _, opponent := range match.Opponents {
+		client.IDs.Add(FlagTeam, opponent.Opponent.ID)
+	}
+	mockDB.ExpectBegin()
+	mockDB.ExpectBegin()
+	mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+		WithArgs(pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}))
+	mockDB.ExpectCommit()
+	mockDB.ExpectBegin()
+	expectNotArchived(mockDB, pgxmock.AnyArg())
+	mockDB.ExpectCommit()
+	// the upsert and its follow-ups share a savepoint
+	mockDB.ExpectBegin()
+	mockDB.ExpectBegin()
+	mockDB.ExpectBatch().ExpectQuery("INSERT INTO matches").
+		WithArgs(anyArgs(26)...).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(match.ID)))
+	mockDB.ExpectCommit()
+	mockDB.ExpectBegin()
+	expectOutboxBatch(mockDB, match)
+	mockDB.ExpectCommit()
+	mockDB.ExpectBegin()
+	opponents := mockDB.ExpectBatch()
+	for range match.Opponents {
+		opponents.ExpectExec("INSERT INTO match_opponents").
+			WithArgs(anyArgs(6)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+	mockDB.ExpectBatch().ExpectExec("DELETE FROM match_opponents").
+		WithArgs(int32(match.ID), int32(len(match.Opponents))).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+	mockDB.ExpectCommit()
+	mockDB.ExpectCommit()
+	mockDB.ExpectCommit()
+
+	client.WriteMatches(pandatypes.MatchLikes{match})
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
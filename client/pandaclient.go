@@ -4,21 +4,25 @@ import (
 	"context"
 	"net/http"
 	"net/url"
+	"time"
 
 	"go.uber.org/zap"
 
-	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/live"
+	"github.com/feimaomiao/stalka/pandatypes"
 )
 
-type GetChoice int
+// GetChoice selects the entity type of a request.
+type GetChoice = pandatypes.Entity
 
 const (
-	FlagGame GetChoice = iota
-	FlagLeague
-	FlagSeries
-	FlagTournament
-	FlagMatch
-	FlagTeam
+	FlagGame       = pandatypes.EntityGame
+	FlagLeague     = pandatypes.EntityLeague
+	FlagSeries     = pandatypes.EntitySeries
+	FlagTournament = pandatypes.EntityTournament
+	FlagMatch      = pandatypes.EntityMatch
+	FlagTeam       = pandatypes.EntityTeam
+	FlagPlayer     = pandatypes.EntityPlayer
 )
 
 const (
@@ -32,15 +36,40 @@ type PandaClient struct {
 	Pandasecret string
 	Logger      *zap.SugaredLogger
 	HTTPClient  *http.Client
-	DBConnector *dbtypes.Queries
-	Run         int
-	Ctx         context.Context
+	// Store writes and looks up the fetched entities. Inside of a page
+	// transaction it is bound to it.
+	Store pandatypes.Store
+	// LiveFlags records which matches GetLives found live.
+	LiveFlags pandatypes.LiveFlags
+	// Retries queues the rows skipped for unresolved dependencies.
+	Retries pandatypes.RetryQueue
+	// Retention expires the rows ApplyRetention removes.
+	Retention pandatypes.Retention
+	// Publisher is told when a sync job has committed its writes.
+	Publisher pandatypes.Publisher
+	// IDs caches the entities known to exist. Every check hits the database when nil.
+	IDs *IDCache
+	Run int
+	// Budget tracks the PandaScore request budget. Nil disables it.
+	Budget *Budget
+	// Live is told about every live poll to push score events. Nil disables them.
+	Live *live.Broadcaster
+	// Writes counts the rows written and skipped as unchanged since the last
+	// LogWrites. Nil disables the counts.
+	Writes *WriteTally
+	Ctx    context.Context
+	// staged holds the IDs written by the page transaction of this client copy.
+	staged *[]knownID
 }
 
 // Startup performs the initial setup for the PandaClient, which includes
 // updating games, leagues, series, tournaments, and matches.
 // @returns an error if any of the requests fail.
 func (client *PandaClient) Startup() error {
+	// A cold cache only costs queries, so a failed warm-up does not block startup.
+	if err := client.WarmIDCache(); err != nil {
+		client.Logger.Errorf("Error warming the ID cache: %v", err)
+	}
 	err := client.UpdateGames()
 	if err != nil {
 		return err
@@ -72,6 +101,7 @@ func (client *PandaClient) Startup() error {
 		client.Logger.Errorf("Initial /lives fetch failed (will retry on ticker): %v", liveErr)
 	}
 	client.Logger.Infof("Done with initial setup, made %d requests", client.Run)
+	client.LogWrites("initial setup")
 	return nil
 }
 
@@ -106,5 +136,6 @@ func (client *PandaClient) MakeRequest(paths []string, params map[string]string)
 		return nil, err
 	}
 	client.Run++
+	client.Budget.Observe(resp.Header, time.Now())
 	return resp, nil
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/pandatypes"
 	"github.com/h2non/gock"
 	"github.com/nbio/st"
 	"go.uber.org/zap/zaptest"
@@ -19,7 +20,7 @@ func TestMakeRequest(t *testing.T) {
 		BaseURL:     "https://api.pandascore.io",
 		Pandasecret: "fakesecret",
 		HTTPClient:  &http.Client{},
-		DBConnector: &dbtypes.Queries{},
+		Store:       pandatypes.NewPostgresSink(&dbtypes.Queries{}, nil),
 		Run:         0,
 		Ctx:         t.Context(),
 	}
@@ -58,7 +59,7 @@ func TestMakeRequestError(t *testing.T) {
 		BaseURL:     "#$%^&*($#$%%^(",
 		Pandasecret: "fakesecret",
 		HTTPClient:  &http.Client{},
-		DBConnector: &dbtypes.Queries{},
+		Store:       pandatypes.NewPostgresSink(&dbtypes.Queries{}, nil),
 		Run:         0,
 		Ctx:         nil,
 	}
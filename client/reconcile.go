@@ -0,0 +1,80 @@
+package client
+
+import (
+	"errors"
+	"time"
+
+	"github.com/feimaomiao/stalka/pandatypes"
+)
+
+const (
+	// ReconcileWindow is how long an entity may go unseen before it is re-checked upstream.
+	ReconcileWindow = 72 * time.Hour
+	// ReconcileBatch caps the GetOne calls per entity type and run, keeping the
+	// reconciliation job well inside the hourly PandaScore budget.
+	ReconcileBatch = 25
+)
+
+// reconciledFlags lists the entity types the reconciliation job re-checks.
+func reconciledFlags() []GetChoice {
+	return []GetChoice{FlagTournament, FlagMatch, FlagTeam}
+}
+
+// Reconcile re-checks the tournaments, matches and teams that have not been seen
+// within ReconcileWindow. Entities PandaScore still serves are rewritten (which
+// refreshes last_seen_at), entities answered with a 404 are tombstoned.
+// @returns an error if the stale entities could not be listed.
+func (client *PandaClient) Reconcile() error {
+	client.Logger.Info("Reconciling stale entities")
+	cutoff := time.Now().UTC().Add(-ReconcileWindow)
+	for _, flag := range reconciledFlags() {
+		ids, err := client.Store.StaleIDs(client.Ctx, flag, cutoff, ReconcileBatch)
+		if err != nil {
+			client.Logger.Errorf("Error listing stale entities for flag %d: %v", flag, err)
+			return err
+		}
+		client.Logger.Infof("Re-checking %d stale entities for flag %d", len(ids), flag)
+		for _, id := range ids {
+			client.reconcileOne(int(id), flag)
+		}
+	}
+	return nil
+}
+
+// reconcileOne re-fetches a single stale entity and tombstones it if it is gone upstream.
+// Other failures, including a 404 of one of its dependencies, are logged and
+// left for the next run.
+func (client *PandaClient) reconcileOne(id int, flag GetChoice) {
+	err := client.GetOne(id, flag)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, ErrNotFound) {
+		client.Logger.Errorf("Error re-checking entity %d for flag %d: %v", id, flag, err)
+		return
+	}
+	client.Logger.Infof("Entity %d for flag %d was removed upstream, tombstoning", id, flag)
+	err = client.Tombstone(id, flag)
+	if err != nil {
+		client.Logger.Errorf("Error tombstoning entity %d for flag %d: %v", id, flag, err)
+	}
+}
+
+// Tombstone soft-deletes an entity by setting its deleted_at column and drops it
+// from the ID cache.
+// @param id - the ID of the entity to tombstone.
+// @param flag - the type of the entity.
+// @returns an error if one occurred.
+func (client *PandaClient) Tombstone(id int, flag GetChoice) error {
+	id32, err := pandatypes.SafeIntToInt32(id)
+	if err != nil {
+		return err
+	}
+	err = client.Store.Tombstone(client.Ctx, flag, id32)
+	if err != nil {
+		return err
+	}
+	// tombstoned entities count as missing for the dependency checks
+	client.IDs.Forget(flag, id)
+	return nil
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/pandatypes"
+	"github.com/h2non/gock"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestGetOneNotFound(t *testing.T) {
+	client := &PandaClient{
+		BaseURL:     "https://api.pandascore.io",
+		Pandasecret: "fakesecret",
+		Logger:      zaptest.NewLogger(t).Sugar(),
+		HTTPClient:  &http.Client{},
+		Store:       pandatypes.NewPostgresSink(&dbtypes.Queries{}, nil),
+		Run:         0,
+		Ctx:         context.Background(),
+	}
+	gock.InterceptClient(client.HTTPClient)
+	defer gock.Off()
+
+	gock.New("https://api.pandascore.io").
+		Get("/matches/42").
+		Reply(404)
+
+	err := client.GetOne(42, FlagMatch)
+	st.Reject(t, err, nil)
+	st.Expect(t, errors.Is(err, ErrNotFound), true)
+
+	gock.New("https://api.pandascore.io").
+		Get("/matches/42").
+		Reply(500)
+
+	err = client.GetOne(42, FlagMatch)
+	st.Reject(t, err, nil)
+	st.Expect(t, errors.Is(err, ErrNotFound), false)
+}
+
+func TestReconcile(t *testing.T) {
+	logger := zaptest.NewLogger(t).Sugar()
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	mockQueries := dbtypes.New(mockDB)
+
+	client := &PandaClient{
+		BaseURL:     "https://api.pandascore.io",
+		Pandasecret: "fakesecret",
+		Logger:      logger,
+		HTTPClient:  &http.Client{},
+		Store:       pandatypes.NewPostgresSink(mockQueries, nil),
+		Run:         0,
+		Ctx:         context.Background(),
+	}
+
+	t.Run("Success - gone entities are tombstoned, live ones rewritten", func(t *testing.T) {
+		gock.InterceptClient(client.HTTPClient)
+		defer gock.Off()
+
+		teamData, err := os.ReadFile("../static/fetch_data/teams.json")
+		st.Assert(t, err, nil)
+
+		gock.New("https://api.pandascore.io").
+			Get("/tournaments/7").
+			Reply(404)
+		gock.New("https://api.pandascore.io").
+			Get("/teams/127652").
+			Reply(200).
+			BodyString(string(teamData))
+
+		mockDB.ExpectQuery("SELECT id FROM tournaments").
+			WithArgs(pgxmock.AnyArg(), int32(ReconcileBatch)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(7)))
+		mockDB.ExpectExec("UPDATE tournaments SET deleted_at").
+			WithArgs(int32(7)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDB.ExpectQuery("SELECT id FROM matches").
+			WithArgs(pgxmock.AnyArg(), int32(ReconcileBatch)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+		mockDB.ExpectQuery("SELECT id FROM teams").
+			WithArgs(pgxmock.AnyArg(), int32(ReconcileBatch)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(127652)))
+		mockDB.ExpectExec("INSERT INTO teams").
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		err = client.Reconcile()
+		st.Expect(t, err, nil)
+		st.Expect(t, gock.IsDone(), true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - listing stale entities fails", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT id FROM tournaments").
+			WithArgs(pgxmock.AnyArg(), int32(ReconcileBatch)).
+			WillReturnError(fmt.Errorf("database error"))
+
+		err := client.Reconcile()
+		st.Reject(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - upstream failure is not tombstoned", func(t *testing.T) {
+		gock.InterceptClient(client.HTTPClient)
+		defer gock.Off()
+
+		gock.New("https://api.pandascore.io").
+			Get("/matches/9").
+			Reply(503)
+
+		// no tombstone expectation: a 503 must leave the match alone
+		client.reconcileOne(9, FlagMatch)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+	t.Run("Error - a parent gone upstream does not tombstone the child", func(t *testing.T) {
+		gock.InterceptClient(client.HTTPClient)
+		defer gock.Off()
+
+		tournamentData, err := os.ReadFile("../static/fetch_data/tournaments.json")
+		st.Assert(t, err, nil)
+		gock.New("https://api.pandascore.io").
+			Get("/tournaments/17283").
+			Reply(200).
+			BodyString(string(tournamentData))
+		gock.New("https://api.pandascore.io").
+			Get("/series/9555").
+			Reply(404)
+		mockDB.ExpectQuery("SELECT COUNT\\(\\*\\) FROM series").WithArgs(int32(9555)).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(0)))
+
+		err = client.GetOne(17283, FlagTournament)
+		var dependencyErr *DependencyError
+		st.Assert(t, errors.As(err, &dependencyErr), true)
+		st.Expect(t, dependencyErr.Flag, FlagSeries)
+		st.Expect(t, dependencyErr.ID, 9555)
+		st.Expect(t, errors.Is(err, ErrNotFound), false)
+
+		gock.New("https://api.pandascore.io").
+			Get("/tournaments/17283").
+			Reply(200).
+			BodyString(string(tournamentData))
+		gock.New("https://api.pandascore.io").
+			Get("/series/9555").
+			Reply(404)
+		mockDB.ExpectQuery("SELECT COUNT\\(\\*\\) FROM series").WithArgs(int32(9555)).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(0)))
+
+		// no tombstone expectation: PandaScore still serves the tournament
+		client.reconcileOne(17283, FlagTournament)
+		st.Expect(t, gock.IsDone(), true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestTombstone(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+
+	client := &PandaClient{
+		Logger: zaptest.NewLogger(t).Sugar(),
+		Store:  pandatypes.NewPostgresSink(dbtypes.New(mockDB), nil),
+		Ctx:    t.Context(),
+	}
+
+	mockDB.ExpectExec("UPDATE matches SET deleted_at").
+		WithArgs(int32(1)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockDB.ExpectExec("SELECT pg_notify").
+		WithArgs(pandatypes.ChannelMatchesChanged, `{"ids":[1],"changes":{"deleted":[1]}}`).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	st.Expect(t, client.Tombstone(1, FlagMatch), nil)
+
+	mockDB.ExpectExec("UPDATE teams SET deleted_at").
+		WithArgs(int32(2)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	st.Expect(t, client.Tombstone(2, FlagTeam), nil)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+
+	st.Reject(t, client.Tombstone(1, FlagGame), nil)
+	st.Reject(t, client.Tombstone(1, GetChoice(999)), nil)
+	st.Reject(t, client.Tombstone(math.MaxInt32+1, FlagMatch), nil)
+
+	_, err = client.Store.StaleIDs(client.Ctx, FlagLeague, time.Time{}, ReconcileBatch)
+	st.Reject(t, err, nil)
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/feimaomiao/stalka/pandatypes"
+	"golang.org/x/sync/singleflight"
+)
+
+// fetchGroup dedupes concurrent fetches of the same entity, e.g. two match
+// pages of the same tournament. Only the HTTP call is shared: every caller
+// writes the entity itself, because the page transactions of the callers do
+// not see each other's writes.
+var fetchGroup singleflight.Group //nolint:gochecknoglobals // shared by every client copy
+
+// fetchOne requests a single entity from PandaScore, sharing the request with
+// concurrent callers asking for the same entity.
+// @param id - the ID of the entity to get.
+// @param flag - the type of entity to get.
+// @returns the response body, ErrNotFound if PandaScore no longer knows the entity.
+func (client *PandaClient) fetchOne(id int, flag GetChoice) ([]byte, error) {
+	searchString, err := flagToString(flag)
+	if err != nil {
+		client.Logger.Errorf("Error converting flag to string: %v", err)
+		return nil, err
+	}
+	key := client.BaseURL + searchString + "/" + strconv.Itoa(id)
+	body, err, shared := fetchGroup.Do(key, func() (any, error) {
+		client.Logger.Debugf("Getting %s %d", searchString, id)
+		resp, requestErr := client.MakeRequest([]string{searchString, strconv.Itoa(id)}, nil)
+		if requestErr != nil {
+			client.Logger.Errorf("Error making request to Pandascore API: %v", requestErr)
+			return nil, requestErr
+		}
+		defer resp.Body.Close()
+		// PandaScore answers 404 for entities it deleted, callers tombstone those.
+		if resp.StatusCode == http.StatusNotFound {
+			client.Logger.Debugf("%s %d not found upstream", searchString, id)
+			return nil, fmt.Errorf("%w: %s %d", ErrNotFound, searchString, id)
+		}
+		// Check if the response status code is 200 OK
+		if resp.StatusCode != http.StatusOK {
+			client.Logger.Errorf("Error: received status code %d", resp.StatusCode)
+			return nil, fmt.Errorf("received status code %d", resp.StatusCode)
+		}
+		return io.ReadAll(resp.Body)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		client.Logger.Debugf("Shared the request for %s %d with a concurrent caller", searchString, id)
+	}
+	return body.([]byte), nil //nolint:forcetypeassert // the function above only returns []byte
+}
+
+// Resolve makes sure an entity exists in the database. A missing entity is
+// fetched together with everything it depends on, walking the dependency graph
+// game → league → series → tournament → match recursively; the teams and
+// players embedded in tournaments and matches are created along the way.
+// @param id - the ID of the entity.
+// @param flag - the type of the entity.
+// @returns an error if the entity or one of its dependencies could not be resolved.
+func (client *PandaClient) Resolve(id int, flag GetChoice) error {
+	exists, err := client.ExistCheck(id, flag)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return client.GetOne(id, flag)
+}
+
+// writeEmbedded creates the teams and players embedded in a fetched entity.
+// They are leaves of the dependency graph, so failures are only logged.
+func (client *PandaClient) writeEmbedded(result pandatypes.PandaDataLike) {
+	switch entity := result.(type) {
+	case pandatypes.TournamentLike:
+		client.writeMissingEntities(entity.TeamRows(), nil)
+	case pandatypes.MatchLike:
+		client.writeMissingEntities(client.opponentEntities(pandatypes.MatchLikes{entity}, nil))
+	}
+}
+
+// writeMissingEntities creates the teams and players that do not exist yet and
+// remembers the ones that were written. Known ones are written as well: the
+// store only refreshes their last_seen_at, which keeps reconciliation from
+// taking teams that still play for stale.
+func (client *PandaClient) writeMissingEntities(teams []pandatypes.TeamRow, players []pandatypes.PlayerRow) {
+	if len(teams) == 0 && len(players) == 0 {
+		return
+	}
+	failed := make(map[int]bool)
+	rowErrs := client.Store.WriteMissingOpponents(client.Ctx, teams, players)
+	for _, rowErr := range rowErrs {
+		client.Logger.Errorf("Error writing opponent %d: %v", rowErr.ID, rowErr.Err)
+		failed[rowErr.ID] = true
+	}
+	for _, team := range teams {
+		if !failed[team.ID] && !client.knows(FlagTeam, team.ID) {
+			client.remember(FlagTeam, team.ID)
+		}
+	}
+	for _, player := range players {
+		if !failed[player.ID] && !client.knows(FlagPlayer, player.ID) {
+			client.remember(FlagPlayer, player.ID)
+		}
+	}
+}
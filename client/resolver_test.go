@@ -0,0 +1,150 @@
+package client
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/pandatypes"
+	"github.com/h2non/gock"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestFetchOne(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+
+	t.Run("Concurrent fetches of one entity share the request", func(t *testing.T) {
+		gock.InterceptClient(client.HTTPClient)
+		defer gock.Off()
+
+		gameData, err := os.ReadFile("../static/fetch_data/videogames.json")
+		st.Assert(t, err, nil)
+		gock.New("https://api.pandascore.io").
+			Get("/videogames/34").
+			Times(1).
+			Reply(200).
+			Delay(100 * time.Millisecond).
+			BodyString(string(gameData))
+
+		var wg sync.WaitGroup
+		bodies := make([][]byte, 2)
+		errs := make([]error, 2)
+		for i := range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				bodies[i], errs[i] = client.fetchOne(34, FlagGame)
+			}()
+		}
+		wg.Wait()
+		for i := range 2 {
+			st.Expect(t, errs[i], nil)
+			st.Expect(t, string(bodies[i]), string(gameData))
+		}
+		st.Expect(t, client.Run, 1)
+		st.Expect(t, gock.IsDone(), true)
+	})
+
+	t.Run("Error - Not found upstream", func(t *testing.T) {
+		gock.InterceptClient(client.HTTPClient)
+		defer gock.Off()
+
+		gock.New("https://api.pandascore.io").
+			Get("/leagues/1").
+			Reply(404)
+
+		_, err := client.fetchOne(1, FlagLeague)
+		st.Expect(t, errors.Is(err, ErrNotFound), true)
+	})
+
+	t.Run("Error - Invalid flag", func(t *testing.T) {
+		_, err := client.fetchOne(1, GetChoice(999))
+		st.Reject(t, err, nil)
+	})
+}
+
+func TestResolve(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+
+	t.Run("Known entities are not fetched", func(t *testing.T) {
+		client.IDs.Add(FlagLeague, 1)
+		st.Expect(t, client.Resolve(1, FlagLeague), nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Missing entities are fetched and remembered", func(t *testing.T) {
+		gock.InterceptClient(client.HTTPClient)
+		defer gock.Off()
+
+		gameData, err := os.ReadFile("../static/fetch_data/videogames.json")
+		st.Assert(t, err, nil)
+		gock.New("https://api.pandascore.io").
+			Get("/videogames/34").
+			Reply(200).
+			BodyString(string(gameData))
+
+		mockDB.ExpectQuery("SELECT COUNT").
+			WithArgs(int32(34)).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		mockDB.ExpectExec("INSERT INTO games").
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		st.Expect(t, client.Resolve(34, FlagGame), nil)
+		st.Expect(t, client.IDs.Has(FlagGame, 34), true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestWriteMissingEntities(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+	client.Store = pandatypes.NewPostgresSink(dbtypes.New(mockDB), nil)
+	client.IDs.Add(FlagTeam, 1)
+
+	teams := []pandatypes.TeamRow{
+		{ID: 1, GameID: 1, Name: "Known", Acronym: "K", Slug: "known", ImageLink: ""},
+		{ID: 2, GameID: 1, Name: "Unknown", Acronym: "U", Slug: "unknown", ImageLink: ""},
+	}
+
+	t.Run("Cached teams are written as well to refresh their last_seen_at", func(t *testing.T) {
+		batch := mockDB.ExpectBatch()
+		batch.ExpectExec("INSERT INTO teams .+ DO UPDATE SET last_seen_at").
+			WithArgs(int32(1), "Known", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), int32(1), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		batch.ExpectExec("INSERT INTO teams .+ DO UPDATE SET last_seen_at").
+			WithArgs(int32(2), "Unknown", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), int32(1), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		client.writeMissingEntities(teams, nil)
+		st.Expect(t, client.IDs.Has(FlagTeam, 1), true)
+		st.Expect(t, client.IDs.Has(FlagTeam, 2), true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Failed teams are not remembered", func(t *testing.T) {
+		failing := []pandatypes.TeamRow{{ID: 3, GameID: 1, Name: "Failing", Acronym: "F", Slug: "failing", ImageLink: ""}}
+		mockDB.ExpectBatch().ExpectExec("INSERT INTO teams").
+			WithArgs(int32(3), "Failing", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), int32(1), pgxmock.AnyArg()).
+			WillReturnError(errors.New("database error"))
+		mockDB.ExpectBatch().ExpectExec("INSERT INTO teams").
+			WithArgs(int32(3), "Failing", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), int32(1), pgxmock.AnyArg()).
+			WillReturnError(errors.New("database error"))
+
+		client.writeMissingEntities(failing, nil)
+		st.Expect(t, client.IDs.Has(FlagTeam, 3), false)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
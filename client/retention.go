@@ -0,0 +1,132 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/feimaomiao/stalka/pandatypes"
+)
+
+const (
+	// RetentionBatch caps the rows a policy removes per statement, so a first
+	// run over a large table does not hold its locks for long.
+	RetentionBatch = 1000
+	// DefaultMatchRetentionMonths is how long finished matches stay in MATCHES.
+	DefaultMatchRetentionMonths = 12
+	// DefaultURLMappingRetentionDays is how long an unused URL mapping is kept.
+	DefaultURLMappingRetentionDays = 90
+)
+
+// RetentionPolicy expires the rows of a target that are older than Months
+// months and Days days.
+type RetentionPolicy struct {
+	Name   string
+	Target pandatypes.RetentionTarget
+	Months int
+	Days   int
+}
+
+// Cutoff returns the instant before which rows are expired.
+// @param now - the time of the run.
+// @returns now minus the age of the policy.
+func (policy RetentionPolicy) Cutoff(now time.Time) time.Time {
+	return now.UTC().AddDate(0, -policy.Months, -policy.Days)
+}
+
+// RetentionReport is the outcome of one policy. In a dry run Rows is the number
+// of rows that would have been removed.
+type RetentionReport struct {
+	Policy RetentionPolicy
+	Cutoff time.Time
+	Rows   int64
+	DryRun bool
+}
+
+// LoadRetentionPolicies reads the retention policies from the environment:
+// retention_match_months and retention_url_mapping_days set the age of the
+// match archive and URL mapping policies, 0 disables a policy.
+// @param getenv - looks up an environment variable, usually os.Getenv.
+// @returns the enabled policies and an error if a value is not a non-negative integer.
+func LoadRetentionPolicies(getenv func(string) string) ([]RetentionPolicy, error) {
+	months, err := retentionAge(getenv, "retention_match_months", DefaultMatchRetentionMonths)
+	if err != nil {
+		return nil, err
+	}
+	days, err := retentionAge(getenv, "retention_url_mapping_days", DefaultURLMappingRetentionDays)
+	if err != nil {
+		return nil, err
+	}
+	var policies []RetentionPolicy
+	if months > 0 {
+		policies = append(policies, RetentionPolicy{
+			Name: "archive finished matches", Target: pandatypes.RetainMatches, Months: months, Days: 0,
+		})
+	}
+	if days > 0 {
+		policies = append(policies, RetentionPolicy{
+			Name: "delete unused URL mappings", Target: pandatypes.RetainURLMappings, Months: 0, Days: days,
+		})
+	}
+	return policies, nil
+}
+
+// retentionAge parses a retention age variable, fallback when it is unset.
+func retentionAge(getenv func(string) string, key string, fallback int) (int, error) {
+	value := getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	age, err := strconv.Atoi(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", key, value)
+	}
+	return age, nil
+}
+
+// ApplyRetention runs the given policies. A dry run only counts the rows every
+// policy would remove; otherwise they are removed in batches of RetentionBatch.
+// A failing policy is logged and does not stop the others.
+// @param policies - the policies to apply.
+// @param dryRun - whether to report instead of removing.
+// @returns one report per policy that ran, and the error of the last failing policy.
+func (client *PandaClient) ApplyRetention(policies []RetentionPolicy, dryRun bool) ([]RetentionReport, error) {
+	now := time.Now()
+	reports := make([]RetentionReport, 0, len(policies))
+	var lastErr error
+	for _, policy := range policies {
+		report := RetentionReport{Policy: policy, Cutoff: policy.Cutoff(now), Rows: 0, DryRun: dryRun}
+		var err error
+		if dryRun {
+			report.Rows, err = client.Retention.CountExpired(client.Ctx, policy.Target, report.Cutoff)
+		} else {
+			report.Rows, err = client.expireAll(policy.Target, report.Cutoff)
+		}
+		if err != nil {
+			client.Logger.Errorf("Error applying retention policy %q: %v", policy.Name, err)
+			lastErr = err
+			continue
+		}
+		if dryRun {
+			client.Logger.Infof("Dry run: retention policy %q would remove %d rows older than %s",
+				policy.Name, report.Rows, report.Cutoff.Format(time.RFC3339))
+		} else {
+			client.Logger.Infof("Retention policy %q removed %d rows older than %s",
+				policy.Name, report.Rows, report.Cutoff.Format(time.RFC3339))
+		}
+		reports = append(reports, report)
+	}
+	return reports, lastErr
+}
+
+// expireAll expires batches until one comes back short.
+func (client *PandaClient) expireAll(target pandatypes.RetentionTarget, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		removed, err := client.Retention.Expire(client.Ctx, target, cutoff, RetentionBatch)
+		total += removed
+		if err != nil || removed < RetentionBatch {
+			return total, err
+		}
+	}
+}
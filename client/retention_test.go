@@ -0,0 +1,105 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/pandatypes"
+	"github.com/nbio/st"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestLoadRetentionPolicies(t *testing.T) {
+	env := func(values map[string]string) func(string) string {
+		return func(key string) string { return values[key] }
+	}
+
+	t.Run("Defaults enable both policies", func(t *testing.T) {
+		policies, err := LoadRetentionPolicies(env(nil))
+		st.Expect(t, err, nil)
+		st.Assert(t, len(policies), 2)
+		st.Expect(t, policies[0].Target, pandatypes.RetainMatches)
+		st.Expect(t, policies[0].Months, DefaultMatchRetentionMonths)
+		st.Expect(t, policies[1].Target, pandatypes.RetainURLMappings)
+		st.Expect(t, policies[1].Days, DefaultURLMappingRetentionDays)
+	})
+
+	t.Run("Zero disables a policy", func(t *testing.T) {
+		policies, err := LoadRetentionPolicies(env(map[string]string{
+			"retention_match_months":     "0",
+			"retention_url_mapping_days": "30",
+		}))
+		st.Expect(t, err, nil)
+		st.Assert(t, len(policies), 1)
+		st.Expect(t, policies[0].Days, 30)
+	})
+
+	t.Run("Error - invalid age", func(t *testing.T) {
+		_, err := LoadRetentionPolicies(env(map[string]string{"retention_match_months": "-1"}))
+		st.Reject(t, err, nil)
+		_, err = LoadRetentionPolicies(env(map[string]string{"retention_url_mapping_days": "soon"}))
+		st.Reject(t, err, nil)
+	})
+}
+
+func TestRetentionPolicyCutoff(t *testing.T) {
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+	policy := RetentionPolicy{Name: "matches", Target: pandatypes.RetainMatches, Months: 1, Days: 0}
+	// AddDate normalizes February 31st to March 3rd
+	st.Expect(t, policy.Cutoff(now), time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC))
+	policy = RetentionPolicy{Name: "urls", Target: pandatypes.RetainURLMappings, Months: 0, Days: 90}
+	st.Expect(t, policy.Cutoff(now), time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC))
+}
+
+func TestApplyRetention(t *testing.T) {
+	sink := newMemSink()
+	client := &PandaClient{
+		Logger:    zaptest.NewLogger(t).Sugar(),
+		Retention: sink,
+		Ctx:       t.Context(),
+	}
+	policies := []RetentionPolicy{
+		{Name: "archive finished matches", Target: pandatypes.RetainMatches, Months: 12, Days: 0},
+		{Name: "delete unused URL mappings", Target: pandatypes.RetainURLMappings, Months: 0, Days: 90},
+	}
+
+	t.Run("Dry run only counts", func(t *testing.T) {
+		sink.expirable[pandatypes.RetainMatches] = 7
+		sink.expirable[pandatypes.RetainURLMappings] = 3
+
+		reports, err := client.ApplyRetention(policies, true)
+		st.Expect(t, err, nil)
+		st.Assert(t, len(reports), 2)
+		st.Expect(t, reports[0].Rows, int64(7))
+		st.Expect(t, reports[0].DryRun, true)
+		st.Expect(t, reports[1].Rows, int64(3))
+		st.Expect(t, sink.expirable[pandatypes.RetainMatches], int64(7))
+		st.Expect(t, len(sink.expireCalls), 0)
+	})
+
+	t.Run("Rows are removed in batches until one comes back short", func(t *testing.T) {
+		sink.expirable[pandatypes.RetainMatches] = RetentionBatch + 2
+		sink.expirable[pandatypes.RetainURLMappings] = 0
+
+		reports, err := client.ApplyRetention(policies, false)
+		st.Expect(t, err, nil)
+		st.Assert(t, len(reports), 2)
+		st.Expect(t, reports[0].Rows, int64(RetentionBatch+2))
+		st.Expect(t, reports[1].Rows, int64(0))
+		st.Expect(t, sink.expireCalls[pandatypes.RetainMatches], 2)
+		st.Expect(t, sink.expireCalls[pandatypes.RetainURLMappings], 1)
+		st.Expect(t, sink.expirable[pandatypes.RetainMatches], int64(0))
+	})
+
+	t.Run("Error - a failing policy does not stop the others", func(t *testing.T) {
+		sink.expireErrs[pandatypes.RetainMatches] = errors.New("database error")
+		sink.expirable[pandatypes.RetainURLMappings] = 1
+
+		reports, err := client.ApplyRetention(policies, true)
+		st.Reject(t, err, nil)
+		st.Assert(t, len(reports), 1)
+		st.Expect(t, reports[0].Policy.Target, pandatypes.RetainURLMappings)
+		st.Expect(t, reports[0].Rows, int64(1))
+	})
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/feimaomiao/stalka/pandatypes"
+)
+
+const (
+	// RetryBatch caps the queued entities re-attempted per run.
+	RetryBatch = 25
+	// MaxRetryAttempts is how often a queued entity is re-attempted. After that
+	// it stays in RESOLVE_RETRIES for inspection only.
+	MaxRetryAttempts = 5
+)
+
+// UnresolvedError reports an entity that was skipped because one of its
+// dependencies could not be resolved. Skipped entities are queued for a retry.
+type UnresolvedError struct {
+	Flag GetChoice
+	ID   int
+	Err  error
+}
+
+func (e *UnresolvedError) Error() string {
+	return fmt.Sprintf("unresolved dependency of entity %d for flag %d: %v", e.ID, e.Flag, e.Err)
+}
+
+func (e *UnresolvedError) Unwrap() error {
+	return e.Err
+}
+
+// queueRetry records an unresolved entity in RESOLVE_RETRIES, so the next run
+// re-attempts it instead of dropping it. Failures are logged only.
+func (client *PandaClient) queueRetry(unresolved *UnresolvedError) {
+	entityType, err := flagToString(unresolved.Flag)
+	if err != nil {
+		client.Logger.Error(err)
+		return
+	}
+	id, err := pandatypes.SafeIntToInt32(unresolved.ID)
+	if err != nil {
+		client.Logger.Error(err)
+		return
+	}
+	client.Logger.Infof("Queueing %s %d for a retry: %v", entityType, unresolved.ID, unresolved.Err)
+	err = client.Retries.EnqueueRetry(client.Ctx, entityType, id, unresolved.Err.Error())
+	if err != nil {
+		client.Logger.Errorf("Error queueing %s %d for a retry: %v", entityType, unresolved.ID, err)
+	}
+}
+
+// RetryUnresolved re-attempts at most RetryBatch queued entities. Each one is
+// fetched again together with its dependencies in its own transaction. Entities
+// that are written, or that PandaScore no longer knows, leave the queue.
+// @returns an error if the queue could not be read.
+func (client *PandaClient) RetryUnresolved() error {
+	retries, err := client.Retries.DueRetries(client.Ctx, MaxRetryAttempts, RetryBatch)
+	if err != nil {
+		client.Logger.Errorf("Error listing queued retries: %v", err)
+		return err
+	}
+	client.Logger.Infof("Retrying %d unresolved entities", len(retries))
+	for _, retry := range retries {
+		client.retryOne(retry)
+	}
+	return nil
+}
+
+// retryOne re-attempts a single queued entity and updates the queue. The entry
+// is dropped once the entity is written or PandaScore no longer knows it; a
+// dependency that is gone upstream keeps it queued, see DependencyError.
+func (client *PandaClient) retryOne(retry pandatypes.Retry) {
+	flag, err := ParseEntityType(retry.EntityType)
+	if err == nil {
+		err = client.inTransaction(func(page *PandaClient) error {
+			return page.GetOne(int(retry.EntityID), flag)
+		})
+	}
+	if err == nil || errors.Is(err, ErrNotFound) {
+		if err != nil {
+			client.Logger.Infof("%s %d is gone upstream, dropping its retry", retry.EntityType, retry.EntityID)
+		}
+		if deleteErr := client.Retries.DeleteRetry(client.Ctx, retry.EntityType, retry.EntityID); deleteErr != nil {
+			client.Logger.Errorf("Error dropping retry of %s %d: %v", retry.EntityType, retry.EntityID, deleteErr)
+		}
+		return
+	}
+	client.Logger.Errorf("Retry %d of %s %d failed: %v", retry.Attempts+1, retry.EntityType, retry.EntityID, err)
+	err = client.Retries.MarkRetryFailed(client.Ctx, retry.EntityType, retry.EntityID, err.Error())
+	if err != nil {
+		client.Logger.Errorf("Error recording retry of %s %d: %v", retry.EntityType, retry.EntityID, err)
+	}
+}
+
+// ParseEntityType converts the name of a PandaScore endpoint, as stored in the
+// retry queue and accepted by the admin API, back to its flag. It is the
+// inverse of flagToString, so it accepts "videogames", "leagues", "series",
+// "tournaments", "matches", "teams" and "players".
+// @param entityType - the endpoint name to convert.
+// @returns the flag of the entity type, or an "invalid entity type" error for any other name.
+func ParseEntityType(entityType string) (GetChoice, error) {
+	for _, flag := range []GetChoice{FlagGame, FlagLeague, FlagSeries, FlagTournament, FlagMatch, FlagTeam, FlagPlayer} {
+		if name, _ := flagToString(flag); name == entityType {
+			return flag, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid entity type: %s", entityType)
+}
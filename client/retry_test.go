@@ -0,0 +1,151 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/pandatypes"
+	"github.com/h2non/gock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func retryRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{
+		"entity_type", "entity_id", "attempts", "last_error", "created_at", "last_attempt_at",
+	})
+}
+
+func TestRetryUnresolved(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+	sink := pandatypes.NewPostgresSink(dbtypes.New(mockDB), nil)
+	client.Store = sink
+	client.Retries = sink
+
+	t.Run("Resolved and vanished entities leave the queue, failures stay", func(t *testing.T) {
+		gock.InterceptClient(client.HTTPClient)
+		defer gock.Off()
+
+		gameData, err := os.ReadFile("../static/fetch_data/videogames.json")
+		st.Assert(t, err, nil)
+		gock.New("https://api.pandascore.io").
+			Get("/videogames/34").
+			Reply(200).
+			BodyString(string(gameData))
+		gock.New("https://api.pandascore.io").
+			Get("/tournaments/5").
+			Reply(404)
+		gock.New("https://api.pandascore.io").
+			Get("/series/6").
+			Reply(500)
+
+		none := pgtype.Timestamptz{}
+		mockDB.ExpectQuery("SELECT .+ FROM resolve_retries").
+			WithArgs(int32(MaxRetryAttempts), int32(RetryBatch)).
+			WillReturnRows(retryRows().
+				AddRow("videogames", int32(34), int32(0), pgtype.Text{}, none, none).
+				AddRow("tournaments", int32(5), int32(1), pgtype.Text{}, none, none).
+				AddRow("series", int32(6), int32(2), pgtype.Text{}, none, none).
+				AddRow("bogus", int32(7), int32(0), pgtype.Text{}, none, none))
+		mockDB.ExpectExec("INSERT INTO games").
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDB.ExpectExec("DELETE FROM resolve_retries").
+			WithArgs("videogames", int32(34)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mockDB.ExpectExec("DELETE FROM resolve_retries").
+			WithArgs("tournaments", int32(5)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mockDB.ExpectExec("UPDATE resolve_retries").
+			WithArgs("series", int32(6), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDB.ExpectExec("UPDATE resolve_retries").
+			WithArgs("bogus", int32(7), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		st.Expect(t, client.RetryUnresolved(), nil)
+		st.Expect(t, client.IDs.Has(FlagGame, 34), true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("An entity whose parent vanished upstream stays queued", func(t *testing.T) {
+		gock.InterceptClient(client.HTTPClient)
+		defer gock.Off()
+
+		tournamentData, err := os.ReadFile("../static/fetch_data/tournaments.json")
+		st.Assert(t, err, nil)
+		gock.New("https://api.pandascore.io").
+			Get("/tournaments/17283").
+			Reply(200).
+			BodyString(string(tournamentData))
+		gock.New("https://api.pandascore.io").
+			Get("/series/9555").
+			Reply(404)
+
+		none := pgtype.Timestamptz{}
+		mockDB.ExpectQuery("SELECT .+ FROM resolve_retries").
+			WithArgs(int32(MaxRetryAttempts), int32(RetryBatch)).
+			WillReturnRows(retryRows().
+				AddRow("tournaments", int32(17283), int32(0), pgtype.Text{}, none, none))
+		mockDB.ExpectQuery("SELECT COUNT\\(\\*\\) FROM series").WithArgs(int32(9555)).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		// PandaScore still serves the tournament, so its retry is kept
+		mockDB.ExpectExec("UPDATE resolve_retries").
+			WithArgs("tournaments", int32(17283), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		st.Expect(t, client.RetryUnresolved(), nil)
+		st.Expect(t, gock.IsDone(), true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - queue lookup fails", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM resolve_retries").
+			WithArgs(int32(MaxRetryAttempts), int32(RetryBatch)).
+			WillReturnError(fmt.Errorf("database error"))
+
+		st.Reject(t, client.RetryUnresolved(), nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestWritePageQueuesUnresolved(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+	sink := pandatypes.NewPostgresSink(dbtypes.New(mockDB), nil)
+	client.Store = sink
+	client.Retries = sink
+
+	mockDB.ExpectExec("INSERT INTO resolve_retries").
+		WithArgs("tournaments", int32(9), pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	err = client.WritePage(FlagTournament, 2, func(_ *PandaClient, i int) error {
+		if i == 1 {
+			return &UnresolvedError{Flag: FlagTournament, ID: 9, Err: fmt.Errorf("series 3 not found")}
+		}
+		return fmt.Errorf("plain row error")
+	})
+	st.Expect(t, err, nil)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestParseEntityType(t *testing.T) {
+	for _, flag := range []GetChoice{FlagGame, FlagLeague, FlagSeries, FlagTournament, FlagMatch, FlagTeam, FlagPlayer} {
+		name, err := flagToString(flag)
+		st.Assert(t, err, nil)
+		got, err := ParseEntityType(name)
+		st.Expect(t, err, nil)
+		st.Expect(t, got, flag)
+	}
+	_, err := ParseEntityType("bogus")
+	st.Reject(t, err, nil)
+}
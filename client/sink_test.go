@@ -0,0 +1,441 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/pandatypes"
+	"github.com/h2non/gock"
+	"github.com/nbio/st"
+	"go.uber.org/zap/zaptest"
+	_ "modernc.org/sqlite"
+)
+
+// memSink is an in-memory Sink writing in autocommit mode.
+type memSink struct {
+	stored  map[GetChoice]map[int32]bool
+	live    map[int32]bool
+	retries map[string]pandatypes.Retry
+	// expirable holds the rows of every retention target that are past any cutoff.
+	expirable map[pandatypes.RetentionTarget]int64
+	// expireErrs fails the retention calls of a target.
+	expireErrs map[pandatypes.RetentionTarget]error
+	// expireCalls counts the Expire batches of every retention target.
+	expireCalls map[pandatypes.RetentionTarget]int
+}
+
+func newMemSink() *memSink {
+	return &memSink{
+		stored:      make(map[GetChoice]map[int32]bool),
+		live:        make(map[int32]bool),
+		retries:     make(map[string]pandatypes.Retry),
+		expirable:   make(map[pandatypes.RetentionTarget]int64),
+		expireErrs:  make(map[pandatypes.RetentionTarget]error),
+		expireCalls: make(map[pandatypes.RetentionTarget]int),
+	}
+}
+
+func (sink *memSink) put(entity GetChoice, id int) (bool, error) {
+	id32, err := pandatypes.SafeIntToInt32(id)
+	if err != nil {
+		return false, err
+	}
+	if sink.stored[entity] == nil {
+		sink.stored[entity] = make(map[int32]bool)
+	}
+	sink.stored[entity][id32] = true
+	return true, nil
+}
+
+func (sink *memSink) UpsertGame(_ context.Context, row pandatypes.GameRow) (bool, error) {
+	return sink.put(FlagGame, row.ID)
+}
+
+func (sink *memSink) UpsertLeague(_ context.Context, row pandatypes.LeagueRow) (bool, error) {
+	return sink.put(FlagLeague, row.ID)
+}
+
+func (sink *memSink) UpsertSeries(_ context.Context, row pandatypes.SeriesRow) (bool, error) {
+	return sink.put(FlagSeries, row.ID)
+}
+
+func (sink *memSink) UpsertTournament(_ context.Context, row pandatypes.TournamentRow) (bool, error) {
+	return sink.put(FlagTournament, row.ID)
+}
+
+func (sink *memSink) UpsertMatch(_ context.Context, row pandatypes.MatchRow) (bool, error) {
+	return sink.put(FlagMatch, row.ID)
+}
+
+func (sink *memSink) UpsertTeam(_ context.Context, row pandatypes.TeamRow) (bool, error) {
+	return sink.put(FlagTeam, row.ID)
+}
+
+func (sink *memSink) UpsertPlayer(_ context.Context, row pandatypes.PlayerRow) (bool, error) {
+	return sink.put(FlagPlayer, row.ID)
+}
+
+func (sink *memSink) WriteMatchPage(
+	ctx context.Context,
+	rows []pandatypes.MatchRow,
+) (pandatypes.WriteCounts, []pandatypes.RowError) {
+	var counts pandatypes.WriteCounts
+	var rowErrs []pandatypes.RowError
+	for _, row := range rows {
+		written, err := sink.UpsertMatch(ctx, row)
+		if err != nil {
+			rowErrs = append(rowErrs, pandatypes.RowError{ID: row.ID, Err: err})
+			continue
+		}
+		counts.Count(written)
+	}
+	return counts, rowErrs
+}
+
+func (sink *memSink) WriteMissingOpponents(
+	_ context.Context,
+	teams []pandatypes.TeamRow,
+	players []pandatypes.PlayerRow,
+) []pandatypes.RowError {
+	for _, team := range teams {
+		_, _ = sink.put(FlagTeam, team.ID)
+	}
+	for _, player := range players {
+		_, _ = sink.put(FlagPlayer, player.ID)
+	}
+	return nil
+}
+
+func (sink *memSink) Exists(_ context.Context, entity GetChoice, id int32) (bool, error) {
+	return sink.stored[entity][id], nil
+}
+
+func (sink *memSink) ExistingIDs(_ context.Context, entity GetChoice, ids []int32) ([]int32, error) {
+	var existing []int32
+	for _, id := range ids {
+		if sink.stored[entity][id] {
+			existing = append(existing, id)
+		}
+	}
+	return existing, nil
+}
+
+func (sink *memSink) KnownIDs(_ context.Context, entity GetChoice, _ int32) ([]int32, error) {
+	var known []int32
+	for id := range sink.stored[entity] {
+		known = append(known, id)
+	}
+	return known, nil
+}
+
+func (sink *memSink) StaleIDs(context.Context, GetChoice, time.Time, int32) ([]int32, error) {
+	return nil, nil
+}
+
+func (sink *memSink) Tombstone(_ context.Context, entity GetChoice, id int32) error {
+	delete(sink.stored[entity], id)
+	return nil
+}
+
+func (sink *memSink) MarkLive(_ context.Context, ids []int32) error {
+	for _, id := range ids {
+		sink.live[id] = true
+	}
+	return nil
+}
+
+func (sink *memSink) ClearLiveExcept(_ context.Context, ids []int32) error {
+	keep := make(map[int32]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = sink.live[id]
+	}
+	sink.live = keep
+	return nil
+}
+
+func (sink *memSink) EnqueueRetry(_ context.Context, entityType string, id int32, lastError string) error {
+	key := fmt.Sprintf("%s/%d", entityType, id)
+	retry := sink.retries[key]
+	retry.EntityType, retry.EntityID, retry.LastError = entityType, id, lastError
+	sink.retries[key] = retry
+	return nil
+}
+
+func (sink *memSink) DueRetries(_ context.Context, maxAttempts int32, _ int32) ([]pandatypes.Retry, error) {
+	var due []pandatypes.Retry
+	for _, retry := range sink.retries {
+		if retry.Attempts < maxAttempts {
+			due = append(due, retry)
+		}
+	}
+	return due, nil
+}
+
+func (sink *memSink) MarkRetryFailed(_ context.Context, entityType string, id int32, lastError string) error {
+	key := fmt.Sprintf("%s/%d", entityType, id)
+	retry := sink.retries[key]
+	retry.Attempts++
+	retry.LastError = lastError
+	sink.retries[key] = retry
+	return nil
+}
+
+func (sink *memSink) DeleteRetry(_ context.Context, entityType string, id int32) error {
+	delete(sink.retries, fmt.Sprintf("%s/%d", entityType, id))
+	return nil
+}
+
+func (sink *memSink) CountExpired(_ context.Context, target pandatypes.RetentionTarget, _ time.Time) (int64, error) {
+	return sink.expirable[target], sink.expireErrs[target]
+}
+
+func (sink *memSink) Expire(
+	_ context.Context,
+	target pandatypes.RetentionTarget,
+	_ time.Time,
+	limit int32,
+) (int64, error) {
+	sink.expireCalls[target]++
+	if err := sink.expireErrs[target]; err != nil {
+		return 0, err
+	}
+	removed := min(sink.expirable[target], int64(limit))
+	sink.expirable[target] -= removed
+	return removed, nil
+}
+
+func (sink *memSink) RefreshViews(context.Context) error {
+	return nil
+}
+
+func (sink *memSink) SyncCompleted(context.Context, string) error {
+	return nil
+}
+
+func (sink *memSink) Begin(context.Context) (pandatypes.TxSink, error) {
+	return nil, pandatypes.ErrNoTransactions
+}
+
+func (sink *memSink) Savepoint(_ context.Context, fn func() error) error {
+	return fn()
+}
+
+func TestClientWithFakeSink(t *testing.T) {
+	sink := newMemSink()
+	client := &PandaClient{
+		BaseURL:     "https://api.pandascore.io",
+		Pandasecret: "fakesecret",
+		Logger:      zaptest.NewLogger(t).Sugar(),
+		HTTPClient:  &http.Client{},
+		Store:       sink,
+		LiveFlags:   sink,
+		Retries:     sink,
+		Retention:   sink,
+		Publisher:   sink,
+		Writes:      NewWriteTally(),
+		Ctx:         t.Context(),
+	}
+
+	t.Run("Fetched entities are written to the sink", func(t *testing.T) {
+		gock.InterceptClient(client.HTTPClient)
+		defer gock.Off()
+		gameData, err := os.ReadFile("../static/fetch_data/videogames.json")
+		st.Assert(t, err, nil)
+		gock.New("https://api.pandascore.io").
+			Get("/videogames/34").
+			Reply(200).
+			BodyString(string(gameData))
+
+		st.Expect(t, client.GetOne(34, FlagGame), nil)
+		exists, err := client.ExistCheck(34, FlagGame)
+		st.Expect(t, err, nil)
+		st.Expect(t, exists, true)
+		st.Expect(t, client.Writes.Counts(), pandatypes.WriteCounts{Updated: 1, Skipped: 0, Refreshed: 0})
+	})
+
+	t.Run("Tombstoned entities count as missing", func(t *testing.T) {
+		_, _ = sink.put(FlagTeam, 7)
+		st.Expect(t, client.Tombstone(7, FlagTeam), nil)
+		missing, err := client.MissingIDs(FlagTeam, []int{7})
+		st.Expect(t, err, nil)
+		st.Expect(t, missing[7], true)
+	})
+
+	t.Run("Skipped rows are queued and retried", func(t *testing.T) {
+		err := client.WritePage(FlagTournament, 1, func(_ *PandaClient, _ int) error {
+			return &UnresolvedError{Flag: FlagTournament, ID: 9, Err: fmt.Errorf("series 3 not found")}
+		})
+		st.Expect(t, err, nil)
+		st.Assert(t, len(sink.retries), 1)
+
+		gock.InterceptClient(client.HTTPClient)
+		defer gock.Off()
+		gock.New("https://api.pandascore.io").
+			Get("/tournaments/9").
+			Reply(500)
+
+		st.Expect(t, client.RetryUnresolved(), nil)
+		st.Expect(t, sink.retries["tournaments/9"].Attempts, int32(1))
+	})
+}
+
+// fixtureWithID returns the fixture of a single entity with its ID replaced.
+func fixtureWithID(t *testing.T, name string, id int) string {
+	data, err := os.ReadFile("../static/fetch_data/" + name)
+	st.Assert(t, err, nil)
+	var entity map[string]any
+	st.Assert(t, json.Unmarshal(data, &entity), nil)
+	entity["id"] = id
+	data, err = json.Marshal(entity)
+	st.Assert(t, err, nil)
+	return string(data)
+}
+
+func TestClientWithSQLiteSink(t *testing.T) {
+	schema, err := os.ReadFile("../static/sqlite_schema.sql")
+	st.Assert(t, err, nil)
+	db, err := pandatypes.OpenSQLite(t.Context(), filepath.Join(t.TempDir(), "stalka.db"), string(schema))
+	st.Assert(t, err, nil)
+	defer db.Close()
+	sink := pandatypes.NewSQLiteSink(db)
+	client := &PandaClient{
+		BaseURL:     "https://api.pandascore.io",
+		Pandasecret: "fakesecret",
+		Logger:      zaptest.NewLogger(t).Sugar(),
+		HTTPClient:  &http.Client{},
+		Store:       sink,
+		LiveFlags:   sink,
+		Retries:     sink,
+		Retention:   sink,
+		Publisher:   sink,
+		Ctx:         t.Context(),
+	}
+
+	t.Run("Missing dependencies are fetched into the page transaction", func(t *testing.T) {
+		gock.InterceptClient(client.HTTPClient)
+		defer gock.Off()
+		leagueData, err := os.ReadFile("../static/fetch_data/leagues.json")
+		st.Assert(t, err, nil)
+		gock.New("https://api.pandascore.io").
+			Get("/leagues/289").
+			Reply(200).
+			BodyString(string(leagueData))
+		gock.New("https://api.pandascore.io").
+			Get("/videogames/1").
+			Reply(200).
+			BodyString(fixtureWithID(t, "videogames.json", 1))
+
+		err = client.WritePage(FlagLeague, 1, func(page *PandaClient, _ int) error {
+			return page.GetOne(289, FlagLeague)
+		})
+		st.Expect(t, err, nil)
+		st.Expect(t, gock.IsDone(), true)
+		for flag, id := range map[GetChoice]int{FlagGame: 1, FlagLeague: 289} {
+			exists, existsErr := client.ExistCheck(id, flag)
+			st.Expect(t, existsErr, nil)
+			st.Expect(t, exists, true)
+		}
+	})
+
+	t.Run("Skipped rows are queued once the page is committed", func(t *testing.T) {
+		err := client.WritePage(FlagTournament, 1, func(_ *PandaClient, _ int) error {
+			return &UnresolvedError{Flag: FlagTournament, ID: 9, Err: fmt.Errorf("series 3 not found")}
+		})
+		st.Expect(t, err, nil)
+		retries, err := client.Retries.DueRetries(client.Ctx, MaxRetryAttempts, RetryBatch)
+		st.Expect(t, err, nil)
+		st.Expect(t, len(retries), 1)
+	})
+}
+
+// pageFixture returns a page holding the fixture of a single entity once per ID.
+func pageFixture(t *testing.T, name string, ids ...int) string {
+	entities := make([]json.RawMessage, 0, len(ids))
+	for _, id := range ids {
+		entities = append(entities, json.RawMessage(fixtureWithID(t, name, id)))
+	}
+	data, err := json.Marshal(entities)
+	st.Assert(t, err, nil)
+	return string(data)
+}
+
+func TestStartupWithSQLiteSink(t *testing.T) {
+	schema, err := os.ReadFile("../static/sqlite_schema.sql")
+	st.Assert(t, err, nil)
+	db, err := pandatypes.OpenSQLite(t.Context(), filepath.Join(t.TempDir(), "stalka.db"), string(schema))
+	st.Assert(t, err, nil)
+	defer db.Close()
+	sink := pandatypes.NewSQLiteSink(db)
+	client := &PandaClient{
+		BaseURL:     "https://api.pandascore.io",
+		Pandasecret: "fakesecret",
+		Logger:      zaptest.NewLogger(t).Sugar(),
+		HTTPClient:  &http.Client{},
+		Store:       sink,
+		LiveFlags:   sink,
+		Retries:     sink,
+		Retention:   sink,
+		Publisher:   sink,
+		Writes:      NewWriteTally(),
+		Ctx:         t.Context(),
+	}
+	gock.InterceptClient(client.HTTPClient)
+	defer gock.Off()
+	// Every list serves the entities the match of matches.json refers to, except
+	// for tournament 696, which is fetched on its own when the match is written.
+	match := fixtureWithID(t, "matches.json", 21655)
+	for path, body := range map[string]string{
+		"^/videogames/$":              pageFixture(t, "videogames.json", 1, 4, 34),
+		"^/leagues/$":                 pageFixture(t, "leagues.json", 289, 291, 299, 4106),
+		"^/series/$":                  pageFixture(t, "series.json", 346, 1352, 9555),
+		"^/tournaments/$":             pageFixture(t, "tournaments.json", 17283),
+		"^/tournaments/696/$":         fixtureWithID(t, "tournaments.json", 696),
+		"^/teams/$":                   pageFixture(t, "teams.json", 1575, 127652),
+		"^/matches/(upcoming|past)/$": "[" + match + "]",
+		"^/matches/running/$":         "[" + match + "]",
+	} {
+		gock.New("https://api.pandascore.io").Get(path).Persist().Reply(200).BodyString(body)
+	}
+
+	t.Run("Startup syncs every entity type into SQLite", func(t *testing.T) {
+		st.Expect(t, client.Startup(), nil)
+		// the tournaments bring 16 teams of their own, the match its opponent 1573
+		for table, want := range map[string]int{
+			"games": 3, "leagues": 4, "series": 3, "tournaments": 2, "teams": 19, "matches": 1, "match_opponents": 2,
+		} {
+			var count int
+			st.Expect(t, db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM "+table).Scan(&count), nil)
+			st.Expect(t, count, want)
+		}
+		var isLive bool
+		st.Expect(t, db.QueryRowContext(t.Context(), "SELECT is_live FROM matches WHERE id = 21655").Scan(&isLive), nil)
+		st.Expect(t, isLive, true)
+		retries, err := client.Retries.DueRetries(client.Ctx, MaxRetryAttempts, RetryBatch)
+		st.Expect(t, err, nil)
+		st.Expect(t, len(retries), 0)
+	})
+
+	t.Run("Unchanged matches are skipped by the next sync", func(t *testing.T) {
+		client.Writes.Take()
+		st.Expect(t, client.GetMatches(false), nil)
+		counts := client.Writes.Take()
+		st.Expect(t, counts.Updated, 0)
+		st.Expect(t, counts.Skipped, Pages)
+	})
+
+	t.Run("Matches no longer running are no longer live", func(t *testing.T) {
+		gock.Off()
+		gock.InterceptClient(client.HTTPClient)
+		gock.New("https://api.pandascore.io").Get("^/matches/running/$").Reply(200).BodyString("[]")
+		st.Expect(t, client.GetLives(), nil)
+		var isLive bool
+		st.Expect(t, db.QueryRowContext(t.Context(), "SELECT is_live FROM matches WHERE id = 21655").Scan(&isLive), nil)
+		st.Expect(t, isLive, false)
+	})
+}
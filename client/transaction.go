@@ -0,0 +1,127 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/feimaomiao/stalka/pandatypes"
+)
+
+// PagePolicy decides what a failing row does to the rest of its page.
+type PagePolicy int
+
+const (
+	// SkipRow rolls the failing row, together with the dependencies it pulled
+	// in, back to its savepoint and keeps writing the rest of the page.
+	SkipRow PagePolicy = iota
+	// AbortPage rolls the whole page back and returns the error of the row.
+	AbortPage
+)
+
+// pagePolicy returns the policy of an entity type. Games are the root of every
+// other entity and the list is small, so it is written completely or not at all.
+// Everything else skips bad rows: one broken league must not hold back a page.
+// @param flag - the entity type of the page.
+// @returns the policy to apply to failing rows.
+func pagePolicy(flag GetChoice) PagePolicy {
+	if flag == FlagGame {
+		return AbortPage
+	}
+	return SkipRow
+}
+
+// inTransaction runs write with a copy of the client whose Store is bound to a
+// new transaction, and commits it when write succeeds. Dependencies fetched
+// through the copy, e.g. by GetOne, land in the same transaction, and the IDs it
+// learns only reach the cache after the commit.
+// When the store does not support transactions the copy is the client itself, so
+// writes run in autocommit mode.
+// @param write - the writes of one page.
+// @returns the error of write, or an error if the transaction could not be started or committed.
+func (client *PandaClient) inTransaction(write func(page *PandaClient) error) error {
+	tx, err := client.Store.Begin(client.Ctx)
+	if errors.Is(err, pandatypes.ErrNoTransactions) {
+		return write(client)
+	}
+	if err != nil {
+		client.Logger.Errorf("Error starting page transaction: %v", err)
+		return err
+	}
+	start := client.Run
+	page := *client
+	page.Store = tx
+	page.staged = &[]knownID{}
+	page.Writes = NewWriteTally()
+	err = write(&page)
+	// requests made on the copy still count against the budget
+	client.Run += page.Run - start
+	if err != nil {
+		if rollbackErr := tx.Rollback(client.Ctx); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	if err = tx.Commit(client.Ctx); err != nil {
+		return err
+	}
+	// like the staged IDs, the write counts only count once committed; other
+	// jobs count into the same tally, so the page adds to it
+	client.Writes.Add(page.Writes.Counts())
+	for _, known := range *page.staged {
+		client.IDs.Add(known.flag, known.id)
+	}
+	return nil
+}
+
+// savepoint runs fn under a savepoint of the store and forgets the IDs fn staged
+// and the writes it counted when it is rolled back. Outside of a transaction fn
+// runs directly, and whatever it wrote before failing stays written and counted.
+func (client *PandaClient) savepoint(fn func() error) error {
+	if client.staged == nil {
+		return client.Store.Savepoint(client.Ctx, fn)
+	}
+	mark := len(*client.staged)
+	// the tally of a page copy is its own, so nothing counts into it meanwhile
+	writes := client.Writes.Counts()
+	err := client.Store.Savepoint(client.Ctx, fn)
+	if err != nil {
+		client.Writes.reset(writes)
+		*client.staged = (*client.staged)[:mark]
+	}
+	return err
+}
+
+// WritePage writes the rows of one page in a single transaction. Every row runs
+// under its own savepoint, together with the dependencies it pulls in, and a
+// failing row is handled according to the policy of the entity type. Rows
+// skipped because of an UnresolvedError are queued for a retry.
+// @param flag - the entity type of the page.
+// @param n - the number of rows on the page.
+// @param write - writes row i through the given client.
+// @returns the error of the failing row when the page was aborted, or a transaction error.
+func (client *PandaClient) WritePage(flag GetChoice, n int, write func(page *PandaClient, i int) error) error {
+	policy := pagePolicy(flag)
+	var skipped []*UnresolvedError
+	err := client.inTransaction(func(page *PandaClient) error {
+		for i := range n {
+			err := page.savepoint(func() error { return write(page, i) })
+			if err == nil {
+				continue
+			}
+			if policy == AbortPage {
+				return err
+			}
+			client.Logger.Errorf("Skipping row %d of the page: %v", i, err)
+			var unresolved *UnresolvedError
+			if errors.As(err, &unresolved) {
+				skipped = append(skipped, unresolved)
+			}
+		}
+		return nil
+	})
+	// queued outside of the page transaction, like WriteMatches does: a sink
+	// with a single writer, such as SQLite, would otherwise wait on itself
+	for _, unresolved := range skipped {
+		client.queueRetry(unresolved)
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/pandatypes"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestPagePolicy(t *testing.T) {
+	st.Expect(t, pagePolicy(FlagGame), AbortPage)
+	for _, flag := range []GetChoice{FlagLeague, FlagSeries, FlagTournament, FlagMatch, FlagTeam, FlagPlayer} {
+		st.Expect(t, pagePolicy(flag), SkipRow)
+	}
+}
+
+func TestWritePage(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+
+	client := &PandaClient{
+		BaseURL:     "https://api.pandascore.io",
+		Pandasecret: "fakesecret",
+		Logger:      zaptest.NewLogger(t).Sugar(),
+		HTTPClient:  &http.Client{},
+		Store:       pandatypes.NewPostgresSink(dbtypes.New(mockDB), mockDB),
+		Run:         0,
+		Ctx:         context.Background(),
+	}
+	// writeRow tombstones team i, any single statement is enough to drive the mock.
+	writeRow := func(page *PandaClient, i int) error {
+		return page.Store.Tombstone(page.Ctx, FlagTeam, int32(i))
+	}
+	expectRow := func(i int, rowErr error) {
+		mockDB.ExpectBegin()
+		exec := mockDB.ExpectExec("UPDATE teams").WithArgs(int32(i))
+		if rowErr != nil {
+			exec.WillReturnError(rowErr)
+			mockDB.ExpectRollback()
+			return
+		}
+		exec.WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockDB.ExpectCommit()
+	}
+
+	t.Run("SkipRow rolls back to the savepoint and commits the rest", func(t *testing.T) {
+		mockDB.ExpectBegin()
+		expectRow(0, nil)
+		expectRow(1, fmt.Errorf("foreign key violation"))
+		expectRow(2, nil)
+		mockDB.ExpectCommit()
+
+		err := client.WritePage(FlagLeague, 3, writeRow)
+		st.Expect(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("AbortPage rolls the whole page back", func(t *testing.T) {
+		mockDB.ExpectBegin()
+		expectRow(0, nil)
+		expectRow(1, fmt.Errorf("foreign key violation"))
+		mockDB.ExpectRollback()
+
+		err := client.WritePage(FlagGame, 3, writeRow)
+		st.Reject(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - transaction cannot be started", func(t *testing.T) {
+		mockDB.ExpectBegin().WillReturnError(fmt.Errorf("connection refused"))
+
+		err := client.WritePage(FlagLeague, 1, writeRow)
+		st.Reject(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Requests made inside the page are counted", func(t *testing.T) {
+		mockDB.ExpectBegin()
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		mockDB.ExpectCommit()
+		before := client.Run
+
+		err := client.WritePage(FlagTeam, 1, func(page *PandaClient, _ int) error {
+			page.Run += 2
+			return nil
+		})
+		st.Expect(t, err, nil)
+		st.Expect(t, client.Run, before+2)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Without transactions rows are written in autocommit mode", func(t *testing.T) {
+		autocommit := *client
+		autocommit.Store = pandatypes.NewPostgresSink(dbtypes.New(mockDB), nil)
+		mockDB.ExpectExec("UPDATE teams").WithArgs(int32(0)).
+			WillReturnError(fmt.Errorf("database error"))
+		mockDB.ExpectExec("UPDATE teams").WithArgs(int32(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		err := autocommit.WritePage(FlagLeague, 2, writeRow)
+		st.Expect(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
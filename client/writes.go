@@ -0,0 +1,122 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/feimaomiao/stalka/pandatypes"
+)
+
+// WriteTally counts the rows written and skipped as unchanged. A nil
+// *WriteTally ignores the counts. It is safe for concurrent use, so the jobs
+// sharing a client count into the same tally.
+type WriteTally struct {
+	mu     sync.Mutex
+	counts pandatypes.WriteCounts
+}
+
+// NewWriteTally creates an empty tally.
+func NewWriteTally() *WriteTally {
+	return &WriteTally{mu: sync.Mutex{}, counts: pandatypes.WriteCounts{}}
+}
+
+// Add merges counts into the tally.
+// @param counts - the counts to add.
+func (tally *WriteTally) Add(counts pandatypes.WriteCounts) {
+	if tally == nil {
+		return
+	}
+	tally.mu.Lock()
+	defer tally.mu.Unlock()
+	tally.counts.Add(counts)
+}
+
+// Count records the outcome of a single row.
+// @param written - whether the row was inserted or updated.
+func (tally *WriteTally) Count(written bool) {
+	var counts pandatypes.WriteCounts
+	counts.Count(written)
+	tally.Add(counts)
+}
+
+// Counts returns the counts so far.
+// @returns a snapshot of the counts.
+func (tally *WriteTally) Counts() pandatypes.WriteCounts {
+	if tally == nil {
+		return pandatypes.WriteCounts{}
+	}
+	tally.mu.Lock()
+	defer tally.mu.Unlock()
+	return tally.counts
+}
+
+// Take returns the counts so far and resets the tally.
+// @returns the counts since the last Take.
+func (tally *WriteTally) Take() pandatypes.WriteCounts {
+	return tally.reset(pandatypes.WriteCounts{})
+}
+
+// reset replaces the counts, e.g. with a snapshot taken before a rolled back
+// savepoint.
+// @param counts - the counts to keep.
+// @returns the replaced counts.
+func (tally *WriteTally) reset(counts pandatypes.WriteCounts) pandatypes.WriteCounts {
+	if tally == nil {
+		return pandatypes.WriteCounts{}
+	}
+	tally.mu.Lock()
+	defer tally.mu.Unlock()
+	previous := tally.counts
+	tally.counts = counts
+	return previous
+}
+
+// writeRow upserts a row through the client and counts whether it was written
+// or skipped because the stored row was already up to date.
+// @param row - the row to write.
+// @returns an error if the row could not be written.
+func (client *PandaClient) writeRow(row pandatypes.RowLike) error {
+	written, err := row.WriteToDB(client.Ctx, client.Store)
+	if err != nil {
+		return err
+	}
+	client.Writes.Count(written)
+	return nil
+}
+
+// LogWrites reports how many rows a run wrote, how many unchanged rows it only
+// refreshed and how many it skipped as unchanged, and resets the counts for the
+// next run.
+// @param run - the name of the run, e.g. "match update".
+func (client *PandaClient) LogWrites(run string) {
+	counts := client.Writes.Take()
+	client.Logger.Infof("Done with %s: %d rows updated, %d unchanged rows refreshed, %d unchanged rows skipped",
+		run, counts.Updated, counts.Refreshed, counts.Skipped)
+}
+
+// Scoped returns a copy of the client with its own write tally and request
+// count, for writes made outside of the scheduler jobs, e.g. admin fetches.
+// LogWrites on the copy reports only its own writes and leaves the counts of a
+// running job alone. The copy shares the store, the ID cache and the budget.
+// @returns the copy.
+func (client *PandaClient) Scoped() *PandaClient {
+	scoped := *client
+	scoped.Run = 0
+	scoped.Writes = NewWriteTally()
+	return &scoped
+}
+
+// RefreshViews brings the read-side views up to date after a sync job has
+// committed its writes, then tells the readers that the job completed.
+// Failures are logged only, readers keep the previous contents until the next
+// refresh.
+// @param run - the name of the run, e.g. "match update".
+func (client *PandaClient) RefreshViews(run string) {
+	if err := client.Publisher.RefreshViews(client.Ctx); err != nil {
+		client.Logger.Errorf("Error refreshing views after %s: %v", run, err)
+		return
+	}
+	client.Logger.Infof("Refreshed views after %s", run)
+	if err := client.Publisher.SyncCompleted(client.Ctx, run); err != nil {
+		client.Logger.Errorf("Error notifying the completion of %s: %v", run, err)
+	}
+}
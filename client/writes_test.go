@@ -0,0 +1,168 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/pandatypes"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestWriteCounts(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+	teams := []pandatypes.TeamRow{{ID: 1, Name: "T1", GameID: 1}, {ID: 2, Name: "Gen.G", GameID: 1}}
+
+	t.Run("Written and skipped rows of a committed page are counted", func(t *testing.T) {
+		mockDB.ExpectBegin()
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec("INSERT INTO teams").WithArgs(anyArgs(7)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec("INSERT INTO teams").WithArgs(anyArgs(7)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 0))
+		mockDB.ExpectCommit()
+		mockDB.ExpectCommit()
+
+		err := client.WritePage(FlagTeam, len(teams), func(page *PandaClient, i int) error {
+			return page.writeRow(teams[i])
+		})
+		st.Expect(t, err, nil)
+		st.Expect(t, client.Writes.Counts(), pandatypes.WriteCounts{Updated: 1, Skipped: 1, Refreshed: 0})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Rolled back rows are not counted", func(t *testing.T) {
+		mockDB.ExpectBegin()
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec("INSERT INTO teams").WithArgs(anyArgs(7)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDB.ExpectRollback()
+		mockDB.ExpectCommit()
+
+		err := client.WritePage(FlagTeam, 1, func(page *PandaClient, i int) error {
+			if writeErr := page.writeRow(teams[i]); writeErr != nil {
+				return writeErr
+			}
+			return fmt.Errorf("dependency failed")
+		})
+		st.Expect(t, err, nil)
+		st.Expect(t, client.Writes.Counts(), pandatypes.WriteCounts{Updated: 1, Skipped: 1, Refreshed: 0})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("A committed page adds to the counts of other jobs", func(t *testing.T) {
+		// another job counted into the shared tally while the page was open
+		mockDB.ExpectBegin()
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec("INSERT INTO teams").WithArgs(anyArgs(7)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDB.ExpectCommit()
+		mockDB.ExpectCommit()
+
+		err := client.WritePage(FlagTeam, 1, func(page *PandaClient, i int) error {
+			client.Writes.Add(pandatypes.WriteCounts{Updated: 2, Skipped: 3, Refreshed: 0})
+			return page.writeRow(teams[i])
+		})
+		st.Expect(t, err, nil)
+		st.Expect(t, client.Writes.Counts(), pandatypes.WriteCounts{Updated: 4, Skipped: 4, Refreshed: 0})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Logging the counts starts a new run", func(t *testing.T) {
+		client.LogWrites("test")
+		st.Expect(t, client.Writes.Counts(), pandatypes.WriteCounts{})
+	})
+}
+
+func TestWriteTally(t *testing.T) {
+	t.Run("Concurrent jobs count into the same tally", func(t *testing.T) {
+		tally := NewWriteTally()
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Go(func() {
+				for i := range 100 {
+					tally.Count(i%2 == 0)
+				}
+			})
+		}
+		wg.Wait()
+		st.Expect(t, tally.Take(), pandatypes.WriteCounts{Updated: 400, Skipped: 400, Refreshed: 0})
+		st.Expect(t, tally.Counts(), pandatypes.WriteCounts{})
+	})
+
+	t.Run("A nil tally ignores the counts", func(t *testing.T) {
+		var tally *WriteTally
+		tally.Count(true)
+		tally.Add(pandatypes.WriteCounts{Updated: 1, Skipped: 1, Refreshed: 0})
+		st.Expect(t, tally.Take(), pandatypes.WriteCounts{})
+	})
+}
+
+func TestScoped(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+	client.Run = 7
+	team := pandatypes.TeamRow{ID: 1, Name: "T1", GameID: 1}
+
+	t.Run("Writes of the copy do not reach the tally of the jobs", func(t *testing.T) {
+		// a job is halfway through its run
+		client.Writes.Add(pandatypes.WriteCounts{Updated: 2, Skipped: 3, Refreshed: 0})
+		mockDB.ExpectExec("INSERT INTO teams").WithArgs(anyArgs(7)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		scoped := client.Scoped()
+		st.Expect(t, scoped.Run, 0)
+		st.Expect(t, scoped.writeRow(team), nil)
+		scoped.LogWrites("admin fetch")
+		st.Expect(t, scoped.Writes.Counts(), pandatypes.WriteCounts{})
+		st.Expect(t, client.Writes.Counts(), pandatypes.WriteCounts{Updated: 2, Skipped: 3, Refreshed: 0})
+		st.Expect(t, client.Run, 7)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("The copy shares the ID cache", func(t *testing.T) {
+		client.Scoped().remember(FlagTeam, 1)
+		st.Expect(t, client.IDs.Has(FlagTeam, 1), true)
+	})
+}
+
+func TestRefreshViews(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	client := newCachedClient(t, mockDB)
+	client.Publisher = pandatypes.NewPostgresSink(dbtypes.New(mockDB), mockDB)
+
+	t.Run("Both views are refreshed concurrently before the job is announced", func(t *testing.T) {
+		mockDB.ExpectExec("REFRESH MATERIALIZED VIEW CONCURRENTLY upcoming_matches_view").
+			WillReturnResult(pgxmock.NewResult("REFRESH", 0))
+		mockDB.ExpectExec("REFRESH MATERIALIZED VIEW CONCURRENTLY ranked_leagues_view").
+			WillReturnResult(pgxmock.NewResult("REFRESH", 0))
+		mockDB.ExpectExec("SELECT pg_notify").
+			WithArgs(pandatypes.ChannelSyncCompleted, `{"job":"match update"}`).
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+		client.RefreshViews("match update")
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("A failing view does not keep the other one stale", func(t *testing.T) {
+		// readers are not told about a job whose views are stale
+		mockDB.ExpectExec("REFRESH MATERIALIZED VIEW CONCURRENTLY upcoming_matches_view").
+			WillReturnError(fmt.Errorf("database error"))
+		mockDB.ExpectExec("REFRESH MATERIALIZED VIEW CONCURRENTLY ranked_leagues_view").
+			WillReturnResult(pgxmock.NewResult("REFRESH", 0))
+
+		client.RefreshViews("match update")
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
@@ -8,71 +8,259 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AdminAudit struct {
+	ID         int64
+	Actor      string
+	RemoteAddr string
+	Action     string
+	Target     string
+	Outcome    string
+	Error      pgtype.Text
+	CreatedAt  pgtype.Timestamptz
+}
+
 type Game struct {
-	ID   int32
-	Name string
-	Slug pgtype.Text
+	ID         int32
+	Name       string
+	Slug       pgtype.Text
+	LastSeenAt pgtype.Timestamptz
+	DeletedAt  pgtype.Timestamptz
 }
 
 type League struct {
-	ID        int32
-	Name      string
-	Slug      pgtype.Text
-	GameID    int32
-	ImageLink pgtype.Text
+	ID           int32
+	Name         string
+	Slug         pgtype.Text
+	GameID       int32
+	ImageLink    pgtype.Text
+	LastSeenAt   pgtype.Timestamptz
+	DeletedAt    pgtype.Timestamptz
+	ModifiedAt   pgtype.Timestamptz
+	SearchVector interface{}
 }
 
 type Match struct {
+	ID                  int32
+	Name                string
+	Slug                pgtype.Text
+	Finished            bool
+	ExpectedStartTime   pgtype.Timestamptz
+	ActualGameTime      float64
+	Team1ID             int32
+	Team1Score          int32
+	Team2ID             int32
+	Team2Score          int32
+	AmountOfGames       int32
+	IsLive              bool
+	StreamURL           pgtype.Text
+	Status              pgtype.Text
+	Forfeit             bool
+	Draw                bool
+	Rescheduled         bool
+	OriginalScheduledAt pgtype.Timestamptz
+	BeginAt             pgtype.Timestamptz
+	EndAt               pgtype.Timestamptz
+	WinnerID            pgtype.Int4
+	WinnerType          pgtype.Text
+	GameID              int32
+	LeagueID            int32
+	SeriesID            int32
+	TournamentID        int32
+	LastSeenAt          pgtype.Timestamptz
+	DeletedAt           pgtype.Timestamptz
+	ModifiedAt          pgtype.Timestamptz
+}
+
+type MatchChange struct {
+	ID         int64
+	MatchID    int32
+	Field      string
+	OldValue   pgtype.Text
+	NewValue   pgtype.Text
+	DetectedAt pgtype.Timestamptz
+}
+
+type MatchOpponent struct {
+	MatchID      int32
+	Slot         int32
+	OpponentType string
+	OpponentID   int32
+	Score        int32
+	Placement    pgtype.Int4
+}
+
+type MatchesArchive struct {
+	ID                  int32
+	Name                string
+	Slug                pgtype.Text
+	Finished            bool
+	ExpectedStartTime   pgtype.Timestamptz
+	ActualGameTime      float64
+	Team1ID             int32
+	Team1Score          int32
+	Team2ID             int32
+	Team2Score          int32
+	AmountOfGames       int32
+	IsLive              bool
+	StreamURL           pgtype.Text
+	Status              pgtype.Text
+	Forfeit             bool
+	Draw                bool
+	Rescheduled         bool
+	OriginalScheduledAt pgtype.Timestamptz
+	BeginAt             pgtype.Timestamptz
+	EndAt               pgtype.Timestamptz
+	WinnerID            pgtype.Int4
+	WinnerType          pgtype.Text
+	GameID              int32
+	LeagueID            int32
+	SeriesID            int32
+	TournamentID        int32
+	LastSeenAt          pgtype.Timestamptz
+	DeletedAt           pgtype.Timestamptz
+	ModifiedAt          pgtype.Timestamptz
+	PlayedAt            pgtype.Timestamptz
+	Opponents           []byte
+	Changes             []byte
+	ArchivedAt          pgtype.Timestamptz
+}
+
+type Outbox struct {
+	ID        int64
+	EventType string
+	MatchID   int32
+	Data      []byte
+	CreatedAt pgtype.Timestamptz
+}
+
+type Player struct {
+	ID           int32
+	Name         string
+	Slug         pgtype.Text
+	FirstName    pgtype.Text
+	LastName     pgtype.Text
+	Nationality  pgtype.Text
+	ImageLink    pgtype.Text
+	GameID       int32
+	LastSeenAt   pgtype.Timestamptz
+	DeletedAt    pgtype.Timestamptz
+	ModifiedAt   pgtype.Timestamptz
+	SearchVector interface{}
+}
+
+type RankedLeaguesView struct {
+	ID              int32
+	Name            string
+	Slug            pgtype.Text
+	GameID          int32
+	ImageLink       pgtype.Text
+	BestTier        pgtype.Int4
+	TournamentCount int64
+	GameRank        int64
+}
+
+type ResolveRetry struct {
+	EntityType    string
+	EntityID      int32
+	Attempts      int32
+	LastError     pgtype.Text
+	CreatedAt     pgtype.Timestamptz
+	LastAttemptAt pgtype.Timestamptz
+}
+
+type Series struct {
+	ID         int32
+	Name       string
+	Slug       pgtype.Text
+	GameID     int32
+	LeagueID   int32
+	LastSeenAt pgtype.Timestamptz
+	DeletedAt  pgtype.Timestamptz
+	ModifiedAt pgtype.Timestamptz
+}
+
+type Team struct {
+	ID           int32
+	Name         string
+	Slug         pgtype.Text
+	Acronym      pgtype.Text
+	ImageLink    pgtype.Text
+	GameID       int32
+	LastSeenAt   pgtype.Timestamptz
+	DeletedAt    pgtype.Timestamptz
+	ModifiedAt   pgtype.Timestamptz
+	SearchVector interface{}
+}
+
+type Tournament struct {
+	ID           int32
+	Name         string
+	Slug         pgtype.Text
+	Tier         pgtype.Int4
+	GameID       int32
+	LeagueID     int32
+	SerieID      int32
+	LastSeenAt   pgtype.Timestamptz
+	DeletedAt    pgtype.Timestamptz
+	ModifiedAt   pgtype.Timestamptz
+	SearchVector interface{}
+}
+
+type UpcomingMatchesView struct {
 	ID                int32
 	Name              string
 	Slug              pgtype.Text
-	Finished          bool
-	ExpectedStartTime pgtype.Timestamp
-	ActualGameTime    float64
+	Status            pgtype.Text
+	IsLive            bool
+	Rescheduled       bool
+	ExpectedStartTime pgtype.Timestamptz
+	BeginAt           pgtype.Timestamptz
+	StreamURL         pgtype.Text
+	AmountOfGames     int32
 	Team1ID           int32
+	Team1Name         pgtype.Text
+	Team1Acronym      pgtype.Text
+	Team1ImageLink    pgtype.Text
 	Team1Score        int32
 	Team2ID           int32
+	Team2Name         pgtype.Text
+	Team2Acronym      pgtype.Text
+	Team2ImageLink    pgtype.Text
 	Team2Score        int32
-	AmountOfGames     int32
-	IsLive            bool
-	StreamURL         pgtype.Text
 	GameID            int32
+	GameName          string
+	GameSlug          pgtype.Text
 	LeagueID          int32
+	LeagueName        string
+	LeagueImageLink   pgtype.Text
 	SeriesID          int32
+	SeriesName        string
 	TournamentID      int32
-}
-
-type Series struct {
-	ID       int32
-	Name     string
-	Slug     pgtype.Text
-	GameID   int32
-	LeagueID int32
-}
-
-type Team struct {
-	ID        int32
-	Name      string
-	Slug      pgtype.Text
-	Acronym   pgtype.Text
-	ImageLink pgtype.Text
-	GameID    int32
-}
-
-type Tournament struct {
-	ID       int32
-	Name     string
-	Slug     pgtype.Text
-	Tier     pgtype.Int4
-	GameID   int32
-	LeagueID int32
-	SerieID  int32
+	TournamentName    string
+	TournamentTier    pgtype.Int4
 }
 
 type UrlMapping struct {
 	HashedKey   string
 	ValueList   []byte
 	AccessCount int32
-	CreatedAt   pgtype.Timestamp
-	AccessedAt  pgtype.Timestamp
+	CreatedAt   pgtype.Timestamptz
+	AccessedAt  pgtype.Timestamptz
+}
+
+type WebhookDelivery struct {
+	Subscriber    string
+	EventID       int64
+	Status        string
+	Attempts      int32
+	LastError     pgtype.Text
+	NextAttemptAt pgtype.Timestamptz
+	LastAttemptAt pgtype.Timestamptz
+	DeliveredAt   pgtype.Timestamptz
+}
+
+type WebhookSubscriber struct {
+	Name      string
+	URL       string
+	CreatedAt pgtype.Timestamptz
 }
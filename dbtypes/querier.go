@@ -6,26 +6,115 @@ package dbtypes
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
-	ClearMatchesIsLiveExceptIDs(ctx context.Context, dollar_1 []int32) error
+	ArchiveMatches(ctx context.Context, arg ArchiveMatchesParams) (int64, error)
+	ClearMatchesIsLiveExceptIDs(ctx context.Context, dollar_1 []int32) ([]int32, error)
+	CountArchivableMatches(ctx context.Context, cutoff pgtype.Timestamptz) (int64, error)
+	CountExpiredURLMappings(ctx context.Context, accessedAt pgtype.Timestamptz) (int64, error)
+	CountResolveRetries(ctx context.Context, maxAttempts int32) (CountResolveRetriesRow, error)
+	DeleteExpiredURLMappings(ctx context.Context, arg DeleteExpiredURLMappingsParams) (int64, error)
+	DeleteOldOutboxEvents(ctx context.Context, cutoff pgtype.Timestamptz) (int64, error)
+	DeleteResolveRetry(ctx context.Context, arg DeleteResolveRetryParams) error
+	DeleteWebhookSubscribersExcept(ctx context.Context, names []string) error
+	EnqueueResolveRetry(ctx context.Context, arg EnqueueResolveRetryParams) error
+	EnsureMatchesArchivePartition(ctx context.Context, archiveYear int32) error
 	GameExist(ctx context.Context, id int32) (int64, error)
+	GetAdminAudit(ctx context.Context, maxRows int32) ([]AdminAudit, error)
 	GetAllGames(ctx context.Context) ([]Game, error)
+	GetArchivableMatchYears(ctx context.Context, cutoff pgtype.Timestamptz) ([]int32, error)
+	GetArchivedMatchesByIDs(ctx context.Context, ids []int32) ([]MatchesArchive, error)
+	GetCalendarMatches(ctx context.Context, arg GetCalendarMatchesParams) ([]GetCalendarMatchesRow, error)
+	GetDueResolveRetries(ctx context.Context, arg GetDueResolveRetriesParams) ([]ResolveRetry, error)
+	GetDueWebhookDeliveries(ctx context.Context, arg GetDueWebhookDeliveriesParams) ([]GetDueWebhookDeliveriesRow, error)
+	GetExistingGameIDs(ctx context.Context, ids []int32) ([]int32, error)
+	GetExistingLeagueIDs(ctx context.Context, ids []int32) ([]int32, error)
+	GetExistingPlayerIDs(ctx context.Context, ids []int32) ([]int32, error)
+	GetExistingSeriesIDs(ctx context.Context, ids []int32) ([]int32, error)
+	GetExistingTeamIDs(ctx context.Context, ids []int32) ([]int32, error)
+	GetExistingTournamentIDs(ctx context.Context, ids []int32) ([]int32, error)
+	GetGamesByIDs(ctx context.Context, ids []int32) ([]Game, error)
+	GetKnownGameIDs(ctx context.Context, limit int32) ([]int32, error)
+	GetKnownLeagueIDs(ctx context.Context, limit int32) ([]int32, error)
+	GetKnownPlayerIDs(ctx context.Context, limit int32) ([]int32, error)
+	GetKnownSeriesIDs(ctx context.Context, limit int32) ([]int32, error)
+	GetKnownTeamIDs(ctx context.Context, limit int32) ([]int32, error)
+	GetKnownTournamentIDs(ctx context.Context, limit int32) ([]int32, error)
 	GetLeaguesByGameID(ctx context.Context, gameID int32) ([]League, error)
+	GetLeaguesByGameIDs(ctx context.Context, gameIds []int32) ([]League, error)
+	GetLeaguesByIDs(ctx context.Context, ids []int32) ([]League, error)
+	GetMatchByID(ctx context.Context, id int32) (Match, error)
+	GetMatchChangesByFieldSince(ctx context.Context, arg GetMatchChangesByFieldSinceParams) ([]MatchChange, error)
+	GetMatchChangesByMatchID(ctx context.Context, matchID int32) ([]MatchChange, error)
+	GetMatchChangesSince(ctx context.Context, detectedAt pgtype.Timestamptz) ([]MatchChange, error)
+	GetMatchIDsByOpponent(ctx context.Context, arg GetMatchIDsByOpponentParams) ([]int32, error)
+	GetMatchOpponentsByMatchID(ctx context.Context, matchID int32) ([]MatchOpponent, error)
+	GetMatchOpponentsByMatchIDs(ctx context.Context, matchIds []int32) ([]MatchOpponent, error)
+	GetMatchesByIDs(ctx context.Context, ids []int32) ([]Match, error)
+	GetMatchesByTournamentIDs(ctx context.Context, tournamentIds []int32) ([]Match, error)
+	GetPlayersByIDs(ctx context.Context, ids []int32) ([]Player, error)
+	GetRankedLeaguesByGameID(ctx context.Context, gameID int32) ([]RankedLeaguesView, error)
+	GetResolveRetries(ctx context.Context, maxRows int32) ([]ResolveRetry, error)
 	GetSeriesByGameID(ctx context.Context, gameID int32) ([]Series, error)
+	GetSeriesByIDs(ctx context.Context, ids []int32) ([]Series, error)
+	GetSeriesByLeagueIDs(ctx context.Context, leagueIds []int32) ([]Series, error)
+	GetStaleMatchIDs(ctx context.Context, arg GetStaleMatchIDsParams) ([]int32, error)
+	GetStaleTeamIDs(ctx context.Context, arg GetStaleTeamIDsParams) ([]int32, error)
+	GetStaleTournamentIDs(ctx context.Context, arg GetStaleTournamentIDsParams) ([]int32, error)
+	GetTeamsByIDs(ctx context.Context, ids []int32) ([]Team, error)
+	GetTournamentsByGameID(ctx context.Context, gameID int32) ([]Tournament, error)
+	GetTournamentsByIDs(ctx context.Context, ids []int32) ([]Tournament, error)
+	GetTournamentsBySeriesIDs(ctx context.Context, seriesIds []int32) ([]Tournament, error)
+	GetUpcomingMatchesByGameID(ctx context.Context, arg GetUpcomingMatchesByGameIDParams) ([]UpcomingMatchesView, error)
+	GetWebhookDeliveryStatus(ctx context.Context) ([]GetWebhookDeliveryStatusRow, error)
+	InsertAdminAudit(ctx context.Context, arg InsertAdminAuditParams) error
+	InsertMatchChange(ctx context.Context, arg InsertMatchChangeParams) error
+	InsertMatchChangesBatch(ctx context.Context, arg []InsertMatchChangesBatchParams) *InsertMatchChangesBatchBatchResults
+	InsertMatchOpponent(ctx context.Context, arg InsertMatchOpponentParams) error
+	InsertMatchOpponentsBatch(ctx context.Context, arg []InsertMatchOpponentsBatchParams) *InsertMatchOpponentsBatchBatchResults
+	InsertMissingPlayersBatch(ctx context.Context, arg []InsertMissingPlayersBatchParams) *InsertMissingPlayersBatchBatchResults
+	InsertMissingTeamsBatch(ctx context.Context, arg []InsertMissingTeamsBatchParams) *InsertMissingTeamsBatchBatchResults
+	InsertOutboxEvent(ctx context.Context, arg InsertOutboxEventParams) error
+	InsertOutboxEventsBatch(ctx context.Context, arg []InsertOutboxEventsBatchParams) *InsertOutboxEventsBatchBatchResults
 	InsertToGames(ctx context.Context, arg InsertToGamesParams) error
-	InsertToLeagues(ctx context.Context, arg InsertToLeaguesParams) error
-	InsertToMatches(ctx context.Context, arg InsertToMatchesParams) error
-	InsertToSeries(ctx context.Context, arg InsertToSeriesParams) error
-	InsertToTeams(ctx context.Context, arg InsertToTeamsParams) error
-	InsertToTournaments(ctx context.Context, arg InsertToTournamentsParams) error
+	InsertToLeagues(ctx context.Context, arg InsertToLeaguesParams) (int64, error)
+	InsertToMatches(ctx context.Context, arg InsertToMatchesParams) (int64, error)
+	InsertToPlayers(ctx context.Context, arg InsertToPlayersParams) (int64, error)
+	InsertToSeries(ctx context.Context, arg InsertToSeriesParams) (int64, error)
+	InsertToTeams(ctx context.Context, arg InsertToTeamsParams) (int64, error)
+	InsertToTournaments(ctx context.Context, arg InsertToTournamentsParams) (int64, error)
 	LeagueExist(ctx context.Context, id int32) (int64, error)
+	ListGames(ctx context.Context, arg ListGamesParams) ([]Game, error)
+	ListMatches(ctx context.Context, arg ListMatchesParams) ([]Match, error)
+	ListSeriesByGameID(ctx context.Context, arg ListSeriesByGameIDParams) ([]Series, error)
+	ListTournamentsByGameID(ctx context.Context, arg ListTournamentsByGameIDParams) ([]Tournament, error)
+	MarkMatchesLive(ctx context.Context, ids []int32) ([]int32, error)
+	MarkResolveRetryFailed(ctx context.Context, arg MarkResolveRetryFailedParams) error
+	MarkWebhookDelivered(ctx context.Context, arg MarkWebhookDeliveredParams) error
+	MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error
 	MatchExist(ctx context.Context, id int32) (int64, error)
+	Notify(ctx context.Context, arg NotifyParams) error
+	PlayerExist(ctx context.Context, id int32) (int64, error)
+	RefreshRankedLeaguesView(ctx context.Context) error
+	RefreshUpcomingMatchesView(ctx context.Context) error
+	RequeueDeadWebhookDeliveries(ctx context.Context, subscriber string) (int64, error)
+	SaveURLMapping(ctx context.Context, arg SaveURLMappingParams) ([]byte, error)
+	SearchEntities(ctx context.Context, arg SearchEntitiesParams) ([]SearchEntitiesRow, error)
 	SeriesExist(ctx context.Context, id int32) (int64, error)
 	TeamExist(ctx context.Context, id int32) (int64, error)
+	TombstoneMatch(ctx context.Context, id int32) error
+	TombstoneTeam(ctx context.Context, id int32) error
+	TombstoneTournament(ctx context.Context, id int32) error
+	TouchURLMapping(ctx context.Context, hashedKey string) ([]byte, error)
 	TournamentExist(ctx context.Context, id int32) (int64, error)
+	TrimMatchOpponents(ctx context.Context, arg TrimMatchOpponentsParams) error
+	TrimMatchOpponentsBatch(ctx context.Context, arg []TrimMatchOpponentsBatchParams) *TrimMatchOpponentsBatchBatchResults
 	UpdateMatchesIsLiveByIDs(ctx context.Context, arg UpdateMatchesIsLiveByIDsParams) error
+	UpsertMatchesBatch(ctx context.Context, arg []UpsertMatchesBatchParams) *UpsertMatchesBatchBatchResults
+	UpsertWebhookSubscriber(ctx context.Context, arg UpsertWebhookSubscriberParams) error
 }
 
 var _ Querier = (*Queries)(nil)
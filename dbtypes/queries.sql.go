@@ -11,40 +11,2502 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const clearMatchesIsLiveExceptIDs = `-- name: ClearMatchesIsLiveExceptIDs :exec
-UPDATE MATCHES SET is_live = false WHERE id != ALL($1::int[])
+const archiveMatches = `-- name: ArchiveMatches :execrows
+WITH moved AS (
+    DELETE FROM matches
+    WHERE id IN (
+        SELECT id FROM matches
+        WHERE finished AND COALESCE(end_at, begin_at, expected_start_time) < $1::timestamptz
+        ORDER BY COALESCE(end_at, begin_at, expected_start_time) ASC
+        LIMIT $2
+    )
+    RETURNING
+        id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score,
+        team2_id, team2_score, amount_of_games, is_live, stream_url, status, forfeit, draw, rescheduled,
+        original_scheduled_at, begin_at, end_at, winner_id, winner_type, game_id, league_id, series_id,
+        tournament_id, last_seen_at, deleted_at, modified_at
+),
+-- A match archived before is only synced back into MATCHES once it is no
+-- longer finished. Archiving it again replaces every previous copy: the one of
+-- the same played_at through ON CONFLICT, the ones left in other partitions
+-- when its times changed here. Their changes are carried over below, as every
+-- statement of the query reads the archive as it was before the deletion.
+superseded AS (
+    DELETE FROM matches_archive a
+    USING moved m
+    WHERE a.id = m.id AND a.played_at <> COALESCE(m.end_at, m.begin_at, m.expected_start_time)
+)
+INSERT INTO matches_archive (
+    id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score,
+    team2_id, team2_score, amount_of_games, is_live, stream_url, status, forfeit, draw, rescheduled,
+    original_scheduled_at, begin_at, end_at, winner_id, winner_type, game_id, league_id, series_id,
+    tournament_id, last_seen_at, deleted_at, modified_at, played_at, opponents, changes
+)
+SELECT
+    m.id, m.name, m.slug, m.finished, m.expected_start_time, m.actual_game_time, m.team1_id, m.team1_score,
+    m.team2_id, m.team2_score, m.amount_of_games, m.is_live, m.stream_url, m.status, m.forfeit, m.draw, m.rescheduled,
+    m.original_scheduled_at, m.begin_at, m.end_at, m.winner_id, m.winner_type, m.game_id, m.league_id, m.series_id,
+    m.tournament_id, m.last_seen_at, m.deleted_at, m.modified_at,
+    COALESCE(m.end_at, m.begin_at, m.expected_start_time),
+    COALESCE((SELECT json_agg(o ORDER BY o.slot) FROM match_opponents o WHERE o.match_id = m.id), '[]'),
+    COALESCE((
+        SELECT json_agg(merged.c ORDER BY (merged.c->>'detected_at')::timestamptz) FROM (
+            SELECT json_array_elements(p.changes) AS c FROM (
+                SELECT a.changes FROM matches_archive a WHERE a.id = m.id ORDER BY a.archived_at DESC LIMIT 1
+            ) p
+            UNION ALL
+            SELECT row_to_json(c) FROM match_changes c WHERE c.match_id = m.id
+        ) merged
+    ), '[]')
+FROM moved m
+ON CONFLICT (id, played_at) DO UPDATE SET
+    name = EXCLUDED.name, slug = EXCLUDED.slug, finished = EXCLUDED.finished,
+    expected_start_time = EXCLUDED.expected_start_time, actual_game_time = EXCLUDED.actual_game_time,
+    team1_id = EXCLUDED.team1_id, team1_score = EXCLUDED.team1_score, team2_id = EXCLUDED.team2_id,
+    team2_score = EXCLUDED.team2_score, amount_of_games = EXCLUDED.amount_of_games,
+    is_live = EXCLUDED.is_live, stream_url = EXCLUDED.stream_url, status = EXCLUDED.status,
+    forfeit = EXCLUDED.forfeit, draw = EXCLUDED.draw, rescheduled = EXCLUDED.rescheduled,
+    original_scheduled_at = EXCLUDED.original_scheduled_at, begin_at = EXCLUDED.begin_at,
+    end_at = EXCLUDED.end_at, winner_id = EXCLUDED.winner_id, winner_type = EXCLUDED.winner_type,
+    game_id = EXCLUDED.game_id, league_id = EXCLUDED.league_id, series_id = EXCLUDED.series_id,
+    tournament_id = EXCLUDED.tournament_id, last_seen_at = EXCLUDED.last_seen_at,
+    deleted_at = EXCLUDED.deleted_at, modified_at = EXCLUDED.modified_at, opponents = EXCLUDED.opponents,
+    changes = EXCLUDED.changes, archived_at = CURRENT_TIMESTAMP
+`
+
+type ArchiveMatchesParams struct {
+	Cutoff  pgtype.Timestamptz
+	MaxRows int32
+}
+
+func (q *Queries) ArchiveMatches(ctx context.Context, arg ArchiveMatchesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, archiveMatches, arg.Cutoff, arg.MaxRows)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const clearMatchesIsLiveExceptIDs = `-- name: ClearMatchesIsLiveExceptIDs :many
+UPDATE MATCHES SET is_live = false WHERE is_live AND id != ALL($1::int[])
+RETURNING id
+`
+
+func (q *Queries) ClearMatchesIsLiveExceptIDs(ctx context.Context, dollar_1 []int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, clearMatchesIsLiveExceptIDs, dollar_1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countArchivableMatches = `-- name: CountArchivableMatches :one
+SELECT COUNT(*) FROM matches
+WHERE finished AND COALESCE(end_at, begin_at, expected_start_time) < $1::timestamptz
+`
+
+func (q *Queries) CountArchivableMatches(ctx context.Context, cutoff pgtype.Timestamptz) (int64, error) {
+	row := q.db.QueryRow(ctx, countArchivableMatches, cutoff)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countExpiredURLMappings = `-- name: CountExpiredURLMappings :one
+SELECT COUNT(*) FROM url_mappings WHERE accessed_at < $1
+`
+
+func (q *Queries) CountExpiredURLMappings(ctx context.Context, accessedAt pgtype.Timestamptz) (int64, error) {
+	row := q.db.QueryRow(ctx, countExpiredURLMappings, accessedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countResolveRetries = `-- name: CountResolveRetries :one
+SELECT COUNT(*) AS total, COUNT(*) FILTER (WHERE attempts >= $1) AS exhausted
+FROM resolve_retries
+`
+
+type CountResolveRetriesRow struct {
+	Total     int64
+	Exhausted int64
+}
+
+func (q *Queries) CountResolveRetries(ctx context.Context, maxAttempts int32) (CountResolveRetriesRow, error) {
+	row := q.db.QueryRow(ctx, countResolveRetries, maxAttempts)
+	var i CountResolveRetriesRow
+	err := row.Scan(&i.Total, &i.Exhausted)
+	return i, err
+}
+
+const deleteExpiredURLMappings = `-- name: DeleteExpiredURLMappings :execrows
+DELETE FROM url_mappings
+WHERE hashed_key IN (
+    SELECT hashed_key FROM url_mappings
+    WHERE accessed_at < $1
+    ORDER BY accessed_at ASC
+    LIMIT $2
+)
+`
+
+type DeleteExpiredURLMappingsParams struct {
+	AccessedAt pgtype.Timestamptz
+	Limit      int32
+}
+
+func (q *Queries) DeleteExpiredURLMappings(ctx context.Context, arg DeleteExpiredURLMappingsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredURLMappings, arg.AccessedAt, arg.Limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOldOutboxEvents = `-- name: DeleteOldOutboxEvents :execrows
+DELETE FROM outbox o
+WHERE o.created_at < $1
+    AND NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.event_id = o.id AND d.status = 'pending')
+`
+
+func (q *Queries) DeleteOldOutboxEvents(ctx context.Context, cutoff pgtype.Timestamptz) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOldOutboxEvents, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteResolveRetry = `-- name: DeleteResolveRetry :exec
+DELETE FROM resolve_retries WHERE entity_type = $1 AND entity_id = $2
+`
+
+type DeleteResolveRetryParams struct {
+	EntityType string
+	EntityID   int32
+}
+
+func (q *Queries) DeleteResolveRetry(ctx context.Context, arg DeleteResolveRetryParams) error {
+	_, err := q.db.Exec(ctx, deleteResolveRetry, arg.EntityType, arg.EntityID)
+	return err
+}
+
+const deleteWebhookSubscribersExcept = `-- name: DeleteWebhookSubscribersExcept :exec
+DELETE FROM webhook_subscribers WHERE name != ALL($1::varchar[])
+`
+
+func (q *Queries) DeleteWebhookSubscribersExcept(ctx context.Context, names []string) error {
+	_, err := q.db.Exec(ctx, deleteWebhookSubscribersExcept, names)
+	return err
+}
+
+const enqueueResolveRetry = `-- name: EnqueueResolveRetry :exec
+INSERT INTO resolve_retries (entity_type, entity_id, last_error) VALUES ($1, $2, $3)
+ON CONFLICT (entity_type, entity_id) DO UPDATE SET last_error = EXCLUDED.last_error
+`
+
+type EnqueueResolveRetryParams struct {
+	EntityType string
+	EntityID   int32
+	LastError  pgtype.Text
+}
+
+func (q *Queries) EnqueueResolveRetry(ctx context.Context, arg EnqueueResolveRetryParams) error {
+	_, err := q.db.Exec(ctx, enqueueResolveRetry, arg.EntityType, arg.EntityID, arg.LastError)
+	return err
+}
+
+const ensureMatchesArchivePartition = `-- name: EnsureMatchesArchivePartition :exec
+SELECT ensure_matches_archive_partition($1::int)
+`
+
+func (q *Queries) EnsureMatchesArchivePartition(ctx context.Context, archiveYear int32) error {
+	_, err := q.db.Exec(ctx, ensureMatchesArchivePartition, archiveYear)
+	return err
+}
+
+const gameExist = `-- name: GameExist :one
+SELECT COUNT(*) FROM games WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GameExist(ctx context.Context, id int32) (int64, error) {
+	row := q.db.QueryRow(ctx, gameExist, id)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const getAdminAudit = `-- name: GetAdminAudit :many
+SELECT id, actor, remote_addr, action, target, outcome, error, created_at FROM admin_audit
+ORDER BY id DESC
+LIMIT $1
+`
+
+func (q *Queries) GetAdminAudit(ctx context.Context, maxRows int32) ([]AdminAudit, error) {
+	rows, err := q.db.Query(ctx, getAdminAudit, maxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AdminAudit
+	for rows.Next() {
+		var i AdminAudit
+		if err := rows.Scan(
+			&i.ID,
+			&i.Actor,
+			&i.RemoteAddr,
+			&i.Action,
+			&i.Target,
+			&i.Outcome,
+			&i.Error,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllGames = `-- name: GetAllGames :many
+SELECT id, name, slug, last_seen_at, deleted_at FROM games WHERE (id != 14) AND deleted_at IS NULL ORDER BY id ASC
+`
+
+func (q *Queries) GetAllGames(ctx context.Context) ([]Game, error) {
+	rows, err := q.db.Query(ctx, getAllGames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Game
+	for rows.Next() {
+		var i Game
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getArchivableMatchYears = `-- name: GetArchivableMatchYears :many
+SELECT DISTINCT EXTRACT(YEAR FROM COALESCE(end_at, begin_at, expected_start_time) AT TIME ZONE 'UTC')::int AS archive_year
+FROM matches
+WHERE finished AND COALESCE(end_at, begin_at, expected_start_time) < $1::timestamptz
+`
+
+func (q *Queries) GetArchivableMatchYears(ctx context.Context, cutoff pgtype.Timestamptz) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getArchivableMatchYears, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var archive_year int32
+		if err := rows.Scan(&archive_year); err != nil {
+			return nil, err
+		}
+		items = append(items, archive_year)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getArchivedMatchesByIDs = `-- name: GetArchivedMatchesByIDs :many
+SELECT DISTINCT ON (id) id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score, team2_id, team2_score, amount_of_games, is_live, stream_url, status, forfeit, draw, rescheduled, original_scheduled_at, begin_at, end_at, winner_id, winner_type, game_id, league_id, series_id, tournament_id, last_seen_at, deleted_at, modified_at, played_at, opponents, changes, archived_at FROM matches_archive
+WHERE id = ANY($1::int[])
+ORDER BY id, archived_at DESC
+`
+
+func (q *Queries) GetArchivedMatchesByIDs(ctx context.Context, ids []int32) ([]MatchesArchive, error) {
+	rows, err := q.db.Query(ctx, getArchivedMatchesByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MatchesArchive
+	for rows.Next() {
+		var i MatchesArchive
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.Finished,
+			&i.ExpectedStartTime,
+			&i.ActualGameTime,
+			&i.Team1ID,
+			&i.Team1Score,
+			&i.Team2ID,
+			&i.Team2Score,
+			&i.AmountOfGames,
+			&i.IsLive,
+			&i.StreamURL,
+			&i.Status,
+			&i.Forfeit,
+			&i.Draw,
+			&i.Rescheduled,
+			&i.OriginalScheduledAt,
+			&i.BeginAt,
+			&i.EndAt,
+			&i.WinnerID,
+			&i.WinnerType,
+			&i.GameID,
+			&i.LeagueID,
+			&i.SeriesID,
+			&i.TournamentID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+			&i.PlayedAt,
+			&i.Opponents,
+			&i.Changes,
+			&i.ArchivedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCalendarMatches = `-- name: GetCalendarMatches :many
+SELECT
+    m.id, m.name, m.status, m.expected_start_time, m.begin_at, m.end_at, m.amount_of_games, m.stream_url, m.modified_at,
+    m.team1_id, t1.name AS team1_name, m.team2_id, t2.name AS team2_name,
+    g.name AS game_name, l.name AS league_name, tr.name AS tournament_name,
+    (
+        SELECT COUNT(*) FROM match_changes c
+        WHERE c.match_id = m.id AND c.field IN ('expected_start_time', 'status', 'amount_of_games')
+    )::int AS sequence
+FROM matches m
+JOIN games g ON g.id = m.game_id
+JOIN leagues l ON l.id = m.league_id
+JOIN tournaments tr ON tr.id = m.tournament_id
+LEFT JOIN teams t1 ON t1.id = m.team1_id
+LEFT JOIN teams t2 ON t2.id = m.team2_id
+WHERE m.deleted_at IS NULL
+    AND COALESCE(m.begin_at, m.expected_start_time) >= $1::timestamptz
+    AND (
+        m.game_id = ANY($2::int[])
+        OR m.league_id = ANY($3::int[])
+        OR EXISTS (
+            SELECT 1 FROM match_opponents mo
+            WHERE mo.match_id = m.id AND mo.opponent_type = 'Team' AND mo.opponent_id = ANY($4::int[])
+        )
+    )
+ORDER BY COALESCE(m.begin_at, m.expected_start_time) ASC, m.id ASC
+LIMIT $5
+`
+
+type GetCalendarMatchesParams struct {
+	Since     pgtype.Timestamptz
+	GameIds   []int32
+	LeagueIds []int32
+	TeamIds   []int32
+	MaxRows   int32
+}
+
+type GetCalendarMatchesRow struct {
+	ID                int32
+	Name              string
+	Status            pgtype.Text
+	ExpectedStartTime pgtype.Timestamptz
+	BeginAt           pgtype.Timestamptz
+	EndAt             pgtype.Timestamptz
+	AmountOfGames     int32
+	StreamURL         pgtype.Text
+	ModifiedAt        pgtype.Timestamptz
+	Team1ID           int32
+	Team1Name         pgtype.Text
+	Team2ID           int32
+	Team2Name         pgtype.Text
+	GameName          string
+	LeagueName        string
+	TournamentName    string
+	Sequence          int32
+}
+
+func (q *Queries) GetCalendarMatches(ctx context.Context, arg GetCalendarMatchesParams) ([]GetCalendarMatchesRow, error) {
+	rows, err := q.db.Query(ctx, getCalendarMatches,
+		arg.Since,
+		arg.GameIds,
+		arg.LeagueIds,
+		arg.TeamIds,
+		arg.MaxRows,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCalendarMatchesRow
+	for rows.Next() {
+		var i GetCalendarMatchesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Status,
+			&i.ExpectedStartTime,
+			&i.BeginAt,
+			&i.EndAt,
+			&i.AmountOfGames,
+			&i.StreamURL,
+			&i.ModifiedAt,
+			&i.Team1ID,
+			&i.Team1Name,
+			&i.Team2ID,
+			&i.Team2Name,
+			&i.GameName,
+			&i.LeagueName,
+			&i.TournamentName,
+			&i.Sequence,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDueResolveRetries = `-- name: GetDueResolveRetries :many
+SELECT entity_type, entity_id, attempts, last_error, created_at, last_attempt_at FROM resolve_retries
+WHERE attempts < $1
+ORDER BY last_attempt_at ASC NULLS FIRST, created_at ASC
+LIMIT $2
+`
+
+type GetDueResolveRetriesParams struct {
+	MaxAttempts int32
+	MaxRows     int32
+}
+
+func (q *Queries) GetDueResolveRetries(ctx context.Context, arg GetDueResolveRetriesParams) ([]ResolveRetry, error) {
+	rows, err := q.db.Query(ctx, getDueResolveRetries, arg.MaxAttempts, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ResolveRetry
+	for rows.Next() {
+		var i ResolveRetry
+		if err := rows.Scan(
+			&i.EntityType,
+			&i.EntityID,
+			&i.Attempts,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.LastAttemptAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDueWebhookDeliveries = `-- name: GetDueWebhookDeliveries :many
+SELECT
+    d.event_id, d.attempts, o.event_type, o.match_id, o.data, o.created_at,
+    m.name, m.status, m.game_id, m.league_id, m.tournament_id, m.team1_id, m.team2_id,
+    m.expected_start_time, m.begin_at, m.end_at, m.winner_id, m.stream_url
+FROM webhook_deliveries d
+JOIN outbox o ON o.id = d.event_id
+LEFT JOIN matches m ON m.id = o.match_id
+WHERE d.subscriber = $1 AND d.status = 'pending' AND d.next_attempt_at <= $2::timestamptz
+ORDER BY d.event_id ASC
+LIMIT $3
+`
+
+type GetDueWebhookDeliveriesParams struct {
+	Subscriber string
+	Now        pgtype.Timestamptz
+	MaxRows    int32
+}
+
+type GetDueWebhookDeliveriesRow struct {
+	EventID           int64
+	Attempts          int32
+	EventType         string
+	MatchID           int32
+	Data              []byte
+	CreatedAt         pgtype.Timestamptz
+	Name              pgtype.Text
+	Status            pgtype.Text
+	GameID            pgtype.Int4
+	LeagueID          pgtype.Int4
+	TournamentID      pgtype.Int4
+	Team1ID           pgtype.Int4
+	Team2ID           pgtype.Int4
+	ExpectedStartTime pgtype.Timestamptz
+	BeginAt           pgtype.Timestamptz
+	EndAt             pgtype.Timestamptz
+	WinnerID          pgtype.Int4
+	StreamURL         pgtype.Text
+}
+
+func (q *Queries) GetDueWebhookDeliveries(ctx context.Context, arg GetDueWebhookDeliveriesParams) ([]GetDueWebhookDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, getDueWebhookDeliveries, arg.Subscriber, arg.Now, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDueWebhookDeliveriesRow
+	for rows.Next() {
+		var i GetDueWebhookDeliveriesRow
+		if err := rows.Scan(
+			&i.EventID,
+			&i.Attempts,
+			&i.EventType,
+			&i.MatchID,
+			&i.Data,
+			&i.CreatedAt,
+			&i.Name,
+			&i.Status,
+			&i.GameID,
+			&i.LeagueID,
+			&i.TournamentID,
+			&i.Team1ID,
+			&i.Team2ID,
+			&i.ExpectedStartTime,
+			&i.BeginAt,
+			&i.EndAt,
+			&i.WinnerID,
+			&i.StreamURL,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExistingGameIDs = `-- name: GetExistingGameIDs :many
+SELECT id FROM games WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetExistingGameIDs(ctx context.Context, ids []int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getExistingGameIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExistingLeagueIDs = `-- name: GetExistingLeagueIDs :many
+SELECT id FROM leagues WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetExistingLeagueIDs(ctx context.Context, ids []int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getExistingLeagueIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExistingPlayerIDs = `-- name: GetExistingPlayerIDs :many
+SELECT id FROM players WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetExistingPlayerIDs(ctx context.Context, ids []int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getExistingPlayerIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExistingSeriesIDs = `-- name: GetExistingSeriesIDs :many
+SELECT id FROM series WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetExistingSeriesIDs(ctx context.Context, ids []int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getExistingSeriesIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExistingTeamIDs = `-- name: GetExistingTeamIDs :many
+SELECT id FROM teams WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetExistingTeamIDs(ctx context.Context, ids []int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getExistingTeamIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExistingTournamentIDs = `-- name: GetExistingTournamentIDs :many
+SELECT id FROM tournaments WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetExistingTournamentIDs(ctx context.Context, ids []int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getExistingTournamentIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGamesByIDs = `-- name: GetGamesByIDs :many
+SELECT id, name, slug, last_seen_at, deleted_at FROM games WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetGamesByIDs(ctx context.Context, ids []int32) ([]Game, error) {
+	rows, err := q.db.Query(ctx, getGamesByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Game
+	for rows.Next() {
+		var i Game
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getKnownGameIDs = `-- name: GetKnownGameIDs :many
+SELECT id FROM games WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1
+`
+
+func (q *Queries) GetKnownGameIDs(ctx context.Context, limit int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getKnownGameIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getKnownLeagueIDs = `-- name: GetKnownLeagueIDs :many
+SELECT id FROM leagues WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1
+`
+
+func (q *Queries) GetKnownLeagueIDs(ctx context.Context, limit int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getKnownLeagueIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getKnownPlayerIDs = `-- name: GetKnownPlayerIDs :many
+SELECT id FROM players WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1
+`
+
+func (q *Queries) GetKnownPlayerIDs(ctx context.Context, limit int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getKnownPlayerIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getKnownSeriesIDs = `-- name: GetKnownSeriesIDs :many
+SELECT id FROM series WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1
+`
+
+func (q *Queries) GetKnownSeriesIDs(ctx context.Context, limit int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getKnownSeriesIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getKnownTeamIDs = `-- name: GetKnownTeamIDs :many
+SELECT id FROM teams WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1
+`
+
+func (q *Queries) GetKnownTeamIDs(ctx context.Context, limit int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getKnownTeamIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getKnownTournamentIDs = `-- name: GetKnownTournamentIDs :many
+SELECT id FROM tournaments WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1
+`
+
+func (q *Queries) GetKnownTournamentIDs(ctx context.Context, limit int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getKnownTournamentIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLeaguesByGameID = `-- name: GetLeaguesByGameID :many
+SELECT l.id, l.name, l.slug, l.game_id, l.image_link, l.last_seen_at, l.deleted_at, l.modified_at, l.search_vector
+FROM LEAGUES l
+LEFT JOIN TOURNAMENTS t ON l.id = t.league_id AND t.deleted_at IS NULL
+WHERE l.game_id = $1 AND l.deleted_at IS NULL
+GROUP BY l.id, l.name, l.slug, l.game_id, l.image_link, l.last_seen_at, l.deleted_at, l.modified_at
+ORDER BY MIN(t.tier) ASC, l.name ASC
+`
+
+func (q *Queries) GetLeaguesByGameID(ctx context.Context, gameID int32) ([]League, error) {
+	rows, err := q.db.Query(ctx, getLeaguesByGameID, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []League
+	for rows.Next() {
+		var i League
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.GameID,
+			&i.ImageLink,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLeaguesByGameIDs = `-- name: GetLeaguesByGameIDs :many
+SELECT id, name, slug, game_id, image_link, last_seen_at, deleted_at, modified_at, search_vector FROM leagues
+WHERE game_id = ANY($1::int[]) AND deleted_at IS NULL
+ORDER BY name ASC, id ASC
+`
+
+func (q *Queries) GetLeaguesByGameIDs(ctx context.Context, gameIds []int32) ([]League, error) {
+	rows, err := q.db.Query(ctx, getLeaguesByGameIDs, gameIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []League
+	for rows.Next() {
+		var i League
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.GameID,
+			&i.ImageLink,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLeaguesByIDs = `-- name: GetLeaguesByIDs :many
+SELECT id, name, slug, game_id, image_link, last_seen_at, deleted_at, modified_at, search_vector FROM leagues WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetLeaguesByIDs(ctx context.Context, ids []int32) ([]League, error) {
+	rows, err := q.db.Query(ctx, getLeaguesByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []League
+	for rows.Next() {
+		var i League
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.GameID,
+			&i.ImageLink,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMatchByID = `-- name: GetMatchByID :one
+SELECT id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score, team2_id, team2_score, amount_of_games, is_live, stream_url, status, forfeit, draw, rescheduled, original_scheduled_at, begin_at, end_at, winner_id, winner_type, game_id, league_id, series_id, tournament_id, last_seen_at, deleted_at, modified_at FROM matches WHERE id = $1
+`
+
+func (q *Queries) GetMatchByID(ctx context.Context, id int32) (Match, error) {
+	row := q.db.QueryRow(ctx, getMatchByID, id)
+	var i Match
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Slug,
+		&i.Finished,
+		&i.ExpectedStartTime,
+		&i.ActualGameTime,
+		&i.Team1ID,
+		&i.Team1Score,
+		&i.Team2ID,
+		&i.Team2Score,
+		&i.AmountOfGames,
+		&i.IsLive,
+		&i.StreamURL,
+		&i.Status,
+		&i.Forfeit,
+		&i.Draw,
+		&i.Rescheduled,
+		&i.OriginalScheduledAt,
+		&i.BeginAt,
+		&i.EndAt,
+		&i.WinnerID,
+		&i.WinnerType,
+		&i.GameID,
+		&i.LeagueID,
+		&i.SeriesID,
+		&i.TournamentID,
+		&i.LastSeenAt,
+		&i.DeletedAt,
+		&i.ModifiedAt,
+	)
+	return i, err
+}
+
+const getMatchChangesByFieldSince = `-- name: GetMatchChangesByFieldSince :many
+SELECT id, match_id, field, old_value, new_value, detected_at FROM match_changes WHERE field = $1 AND detected_at >= $2 ORDER BY detected_at ASC, id ASC
+`
+
+type GetMatchChangesByFieldSinceParams struct {
+	Field      string
+	DetectedAt pgtype.Timestamptz
+}
+
+func (q *Queries) GetMatchChangesByFieldSince(ctx context.Context, arg GetMatchChangesByFieldSinceParams) ([]MatchChange, error) {
+	rows, err := q.db.Query(ctx, getMatchChangesByFieldSince, arg.Field, arg.DetectedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MatchChange
+	for rows.Next() {
+		var i MatchChange
+		if err := rows.Scan(
+			&i.ID,
+			&i.MatchID,
+			&i.Field,
+			&i.OldValue,
+			&i.NewValue,
+			&i.DetectedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMatchChangesByMatchID = `-- name: GetMatchChangesByMatchID :many
+SELECT id, match_id, field, old_value, new_value, detected_at FROM match_changes WHERE match_id = $1 ORDER BY detected_at DESC, id DESC
+`
+
+func (q *Queries) GetMatchChangesByMatchID(ctx context.Context, matchID int32) ([]MatchChange, error) {
+	rows, err := q.db.Query(ctx, getMatchChangesByMatchID, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MatchChange
+	for rows.Next() {
+		var i MatchChange
+		if err := rows.Scan(
+			&i.ID,
+			&i.MatchID,
+			&i.Field,
+			&i.OldValue,
+			&i.NewValue,
+			&i.DetectedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMatchChangesSince = `-- name: GetMatchChangesSince :many
+SELECT id, match_id, field, old_value, new_value, detected_at FROM match_changes WHERE detected_at >= $1 ORDER BY detected_at ASC, id ASC
+`
+
+func (q *Queries) GetMatchChangesSince(ctx context.Context, detectedAt pgtype.Timestamptz) ([]MatchChange, error) {
+	rows, err := q.db.Query(ctx, getMatchChangesSince, detectedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MatchChange
+	for rows.Next() {
+		var i MatchChange
+		if err := rows.Scan(
+			&i.ID,
+			&i.MatchID,
+			&i.Field,
+			&i.OldValue,
+			&i.NewValue,
+			&i.DetectedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMatchesByIDs = `-- name: GetMatchesByIDs :many
+SELECT id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score, team2_id, team2_score, amount_of_games, is_live, stream_url, status, forfeit, draw, rescheduled, original_scheduled_at, begin_at, end_at, winner_id, winner_type, game_id, league_id, series_id, tournament_id, last_seen_at, deleted_at, modified_at FROM matches WHERE id = ANY($1::int[])
+`
+
+func (q *Queries) GetMatchesByIDs(ctx context.Context, ids []int32) ([]Match, error) {
+	rows, err := q.db.Query(ctx, getMatchesByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Match
+	for rows.Next() {
+		var i Match
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.Finished,
+			&i.ExpectedStartTime,
+			&i.ActualGameTime,
+			&i.Team1ID,
+			&i.Team1Score,
+			&i.Team2ID,
+			&i.Team2Score,
+			&i.AmountOfGames,
+			&i.IsLive,
+			&i.StreamURL,
+			&i.Status,
+			&i.Forfeit,
+			&i.Draw,
+			&i.Rescheduled,
+			&i.OriginalScheduledAt,
+			&i.BeginAt,
+			&i.EndAt,
+			&i.WinnerID,
+			&i.WinnerType,
+			&i.GameID,
+			&i.LeagueID,
+			&i.SeriesID,
+			&i.TournamentID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMatchesByTournamentIDs = `-- name: GetMatchesByTournamentIDs :many
+SELECT id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score, team2_id, team2_score, amount_of_games, is_live, stream_url, status, forfeit, draw, rescheduled, original_scheduled_at, begin_at, end_at, winner_id, winner_type, game_id, league_id, series_id, tournament_id, last_seen_at, deleted_at, modified_at FROM matches
+WHERE tournament_id = ANY($1::int[]) AND deleted_at IS NULL
+ORDER BY expected_start_time ASC NULLS LAST, id ASC
+`
+
+func (q *Queries) GetMatchesByTournamentIDs(ctx context.Context, tournamentIds []int32) ([]Match, error) {
+	rows, err := q.db.Query(ctx, getMatchesByTournamentIDs, tournamentIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Match
+	for rows.Next() {
+		var i Match
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.Finished,
+			&i.ExpectedStartTime,
+			&i.ActualGameTime,
+			&i.Team1ID,
+			&i.Team1Score,
+			&i.Team2ID,
+			&i.Team2Score,
+			&i.AmountOfGames,
+			&i.IsLive,
+			&i.StreamURL,
+			&i.Status,
+			&i.Forfeit,
+			&i.Draw,
+			&i.Rescheduled,
+			&i.OriginalScheduledAt,
+			&i.BeginAt,
+			&i.EndAt,
+			&i.WinnerID,
+			&i.WinnerType,
+			&i.GameID,
+			&i.LeagueID,
+			&i.SeriesID,
+			&i.TournamentID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMatchIDsByOpponent = `-- name: GetMatchIDsByOpponent :many
+SELECT match_id FROM match_opponents WHERE opponent_type = $1 AND opponent_id = $2 ORDER BY match_id DESC LIMIT $3
+`
+
+type GetMatchIDsByOpponentParams struct {
+	OpponentType string
+	OpponentID   int32
+	Limit        int32
+}
+
+func (q *Queries) GetMatchIDsByOpponent(ctx context.Context, arg GetMatchIDsByOpponentParams) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getMatchIDsByOpponent, arg.OpponentType, arg.OpponentID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var match_id int32
+		if err := rows.Scan(&match_id); err != nil {
+			return nil, err
+		}
+		items = append(items, match_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMatchOpponentsByMatchID = `-- name: GetMatchOpponentsByMatchID :many
+SELECT match_id, slot, opponent_type, opponent_id, score, placement FROM match_opponents WHERE match_id = $1 ORDER BY slot ASC
+`
+
+func (q *Queries) GetMatchOpponentsByMatchID(ctx context.Context, matchID int32) ([]MatchOpponent, error) {
+	rows, err := q.db.Query(ctx, getMatchOpponentsByMatchID, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MatchOpponent
+	for rows.Next() {
+		var i MatchOpponent
+		if err := rows.Scan(
+			&i.MatchID,
+			&i.Slot,
+			&i.OpponentType,
+			&i.OpponentID,
+			&i.Score,
+			&i.Placement,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMatchOpponentsByMatchIDs = `-- name: GetMatchOpponentsByMatchIDs :many
+SELECT match_id, slot, opponent_type, opponent_id, score, placement FROM match_opponents WHERE match_id = ANY($1::int[]) ORDER BY match_id ASC, slot ASC
+`
+
+func (q *Queries) GetMatchOpponentsByMatchIDs(ctx context.Context, matchIds []int32) ([]MatchOpponent, error) {
+	rows, err := q.db.Query(ctx, getMatchOpponentsByMatchIDs, matchIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MatchOpponent
+	for rows.Next() {
+		var i MatchOpponent
+		if err := rows.Scan(
+			&i.MatchID,
+			&i.Slot,
+			&i.OpponentType,
+			&i.OpponentID,
+			&i.Score,
+			&i.Placement,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPlayersByIDs = `-- name: GetPlayersByIDs :many
+SELECT id, name, slug, first_name, last_name, nationality, image_link, game_id, last_seen_at, deleted_at, modified_at, search_vector FROM players WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetPlayersByIDs(ctx context.Context, ids []int32) ([]Player, error) {
+	rows, err := q.db.Query(ctx, getPlayersByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Player
+	for rows.Next() {
+		var i Player
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.FirstName,
+			&i.LastName,
+			&i.Nationality,
+			&i.ImageLink,
+			&i.GameID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRankedLeaguesByGameID = `-- name: GetRankedLeaguesByGameID :many
+SELECT id, name, slug, game_id, image_link, best_tier, tournament_count, game_rank
+FROM ranked_leagues_view
+WHERE game_id = $1
+ORDER BY game_rank ASC
+`
+
+func (q *Queries) GetRankedLeaguesByGameID(ctx context.Context, gameID int32) ([]RankedLeaguesView, error) {
+	rows, err := q.db.Query(ctx, getRankedLeaguesByGameID, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RankedLeaguesView
+	for rows.Next() {
+		var i RankedLeaguesView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.GameID,
+			&i.ImageLink,
+			&i.BestTier,
+			&i.TournamentCount,
+			&i.GameRank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getResolveRetries = `-- name: GetResolveRetries :many
+SELECT entity_type, entity_id, attempts, last_error, created_at, last_attempt_at FROM resolve_retries
+ORDER BY created_at ASC, entity_type ASC, entity_id ASC
+LIMIT $1
+`
+
+func (q *Queries) GetResolveRetries(ctx context.Context, maxRows int32) ([]ResolveRetry, error) {
+	rows, err := q.db.Query(ctx, getResolveRetries, maxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ResolveRetry
+	for rows.Next() {
+		var i ResolveRetry
+		if err := rows.Scan(
+			&i.EntityType,
+			&i.EntityID,
+			&i.Attempts,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.LastAttemptAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSeriesByGameID = `-- name: GetSeriesByGameID :many
+SELECT id, name, slug, game_id, league_id, last_seen_at, deleted_at, modified_at FROM series WHERE game_id = $1 AND deleted_at IS NULL ORDER BY name ASC
+`
+
+func (q *Queries) GetSeriesByGameID(ctx context.Context, gameID int32) ([]Series, error) {
+	rows, err := q.db.Query(ctx, getSeriesByGameID, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Series
+	for rows.Next() {
+		var i Series
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.GameID,
+			&i.LeagueID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSeriesByIDs = `-- name: GetSeriesByIDs :many
+SELECT id, name, slug, game_id, league_id, last_seen_at, deleted_at, modified_at FROM series WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetSeriesByIDs(ctx context.Context, ids []int32) ([]Series, error) {
+	rows, err := q.db.Query(ctx, getSeriesByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Series
+	for rows.Next() {
+		var i Series
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.GameID,
+			&i.LeagueID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSeriesByLeagueIDs = `-- name: GetSeriesByLeagueIDs :many
+SELECT id, name, slug, game_id, league_id, last_seen_at, deleted_at, modified_at FROM series
+WHERE league_id = ANY($1::int[]) AND deleted_at IS NULL
+ORDER BY name ASC, id ASC
+`
+
+func (q *Queries) GetSeriesByLeagueIDs(ctx context.Context, leagueIds []int32) ([]Series, error) {
+	rows, err := q.db.Query(ctx, getSeriesByLeagueIDs, leagueIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Series
+	for rows.Next() {
+		var i Series
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.GameID,
+			&i.LeagueID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStaleMatchIDs = `-- name: GetStaleMatchIDs :many
+SELECT id FROM matches
+WHERE deleted_at IS NULL AND finished = false AND last_seen_at < $1
+ORDER BY last_seen_at ASC
+LIMIT $2
+`
+
+type GetStaleMatchIDsParams struct {
+	LastSeenAt pgtype.Timestamptz
+	Limit      int32
+}
+
+func (q *Queries) GetStaleMatchIDs(ctx context.Context, arg GetStaleMatchIDsParams) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getStaleMatchIDs, arg.LastSeenAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStaleTeamIDs = `-- name: GetStaleTeamIDs :many
+SELECT id FROM teams
+WHERE deleted_at IS NULL AND last_seen_at < $1
+ORDER BY last_seen_at ASC
+LIMIT $2
+`
+
+type GetStaleTeamIDsParams struct {
+	LastSeenAt pgtype.Timestamptz
+	Limit      int32
+}
+
+func (q *Queries) GetStaleTeamIDs(ctx context.Context, arg GetStaleTeamIDsParams) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getStaleTeamIDs, arg.LastSeenAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStaleTournamentIDs = `-- name: GetStaleTournamentIDs :many
+SELECT id FROM tournaments
+WHERE deleted_at IS NULL AND last_seen_at < $1
+ORDER BY last_seen_at ASC
+LIMIT $2
+`
+
+type GetStaleTournamentIDsParams struct {
+	LastSeenAt pgtype.Timestamptz
+	Limit      int32
+}
+
+func (q *Queries) GetStaleTournamentIDs(ctx context.Context, arg GetStaleTournamentIDsParams) ([]int32, error) {
+	rows, err := q.db.Query(ctx, getStaleTournamentIDs, arg.LastSeenAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTeamsByIDs = `-- name: GetTeamsByIDs :many
+SELECT id, name, slug, acronym, image_link, game_id, last_seen_at, deleted_at, modified_at, search_vector FROM teams WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetTeamsByIDs(ctx context.Context, ids []int32) ([]Team, error) {
+	rows, err := q.db.Query(ctx, getTeamsByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Team
+	for rows.Next() {
+		var i Team
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.Acronym,
+			&i.ImageLink,
+			&i.GameID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTournamentsByGameID = `-- name: GetTournamentsByGameID :many
+SELECT id, name, slug, tier, game_id, league_id, serie_id, last_seen_at, deleted_at, modified_at, search_vector FROM tournaments WHERE game_id = $1 AND deleted_at IS NULL ORDER BY name ASC
+`
+
+func (q *Queries) GetTournamentsByGameID(ctx context.Context, gameID int32) ([]Tournament, error) {
+	rows, err := q.db.Query(ctx, getTournamentsByGameID, gameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tournament
+	for rows.Next() {
+		var i Tournament
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.Tier,
+			&i.GameID,
+			&i.LeagueID,
+			&i.SerieID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTournamentsByIDs = `-- name: GetTournamentsByIDs :many
+SELECT id, name, slug, tier, game_id, league_id, serie_id, last_seen_at, deleted_at, modified_at, search_vector FROM tournaments WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+func (q *Queries) GetTournamentsByIDs(ctx context.Context, ids []int32) ([]Tournament, error) {
+	rows, err := q.db.Query(ctx, getTournamentsByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tournament
+	for rows.Next() {
+		var i Tournament
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.Tier,
+			&i.GameID,
+			&i.LeagueID,
+			&i.SerieID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTournamentsBySeriesIDs = `-- name: GetTournamentsBySeriesIDs :many
+SELECT id, name, slug, tier, game_id, league_id, serie_id, last_seen_at, deleted_at, modified_at, search_vector FROM tournaments
+WHERE serie_id = ANY($1::int[]) AND deleted_at IS NULL
+ORDER BY name ASC, id ASC
+`
+
+func (q *Queries) GetTournamentsBySeriesIDs(ctx context.Context, seriesIds []int32) ([]Tournament, error) {
+	rows, err := q.db.Query(ctx, getTournamentsBySeriesIDs, seriesIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tournament
+	for rows.Next() {
+		var i Tournament
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.Tier,
+			&i.GameID,
+			&i.LeagueID,
+			&i.SerieID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUpcomingMatchesByGameID = `-- name: GetUpcomingMatchesByGameID :many
+SELECT
+    id, name, slug, status, is_live, rescheduled, expected_start_time, begin_at, stream_url, amount_of_games,
+    team1_id, team1_name, team1_acronym, team1_image_link, team1_score,
+    team2_id, team2_name, team2_acronym, team2_image_link, team2_score,
+    game_id, game_name, game_slug, league_id, league_name, league_image_link,
+    series_id, series_name, tournament_id, tournament_name, tournament_tier
+FROM upcoming_matches_view
+WHERE game_id = $1
+ORDER BY expected_start_time ASC NULLS LAST, id ASC
+LIMIT $2
+`
+
+type GetUpcomingMatchesByGameIDParams struct {
+	GameID int32
+	Limit  int32
+}
+
+func (q *Queries) GetUpcomingMatchesByGameID(ctx context.Context, arg GetUpcomingMatchesByGameIDParams) ([]UpcomingMatchesView, error) {
+	rows, err := q.db.Query(ctx, getUpcomingMatchesByGameID, arg.GameID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UpcomingMatchesView
+	for rows.Next() {
+		var i UpcomingMatchesView
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.Status,
+			&i.IsLive,
+			&i.Rescheduled,
+			&i.ExpectedStartTime,
+			&i.BeginAt,
+			&i.StreamURL,
+			&i.AmountOfGames,
+			&i.Team1ID,
+			&i.Team1Name,
+			&i.Team1Acronym,
+			&i.Team1ImageLink,
+			&i.Team1Score,
+			&i.Team2ID,
+			&i.Team2Name,
+			&i.Team2Acronym,
+			&i.Team2ImageLink,
+			&i.Team2Score,
+			&i.GameID,
+			&i.GameName,
+			&i.GameSlug,
+			&i.LeagueID,
+			&i.LeagueName,
+			&i.LeagueImageLink,
+			&i.SeriesID,
+			&i.SeriesName,
+			&i.TournamentID,
+			&i.TournamentName,
+			&i.TournamentTier,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookDeliveryStatus = `-- name: GetWebhookDeliveryStatus :many
+SELECT
+    s.name, s.url,
+    COUNT(d.event_id) FILTER (WHERE d.status = 'pending') AS pending,
+    COUNT(d.event_id) FILTER (WHERE d.status = 'delivered') AS delivered,
+    COUNT(d.event_id) FILTER (WHERE d.status = 'dead') AS dead,
+    MAX(d.delivered_at)::timestamptz AS last_delivered_at,
+    (
+        SELECT f.last_error FROM webhook_deliveries f
+        WHERE f.subscriber = s.name AND f.last_error IS NOT NULL
+        ORDER BY f.last_attempt_at DESC LIMIT 1
+    ) AS last_error
+FROM webhook_subscribers s
+LEFT JOIN webhook_deliveries d ON d.subscriber = s.name
+GROUP BY s.name, s.url
+ORDER BY s.name ASC
+`
+
+type GetWebhookDeliveryStatusRow struct {
+	Name            string
+	URL             string
+	Pending         int64
+	Delivered       int64
+	Dead            int64
+	LastDeliveredAt pgtype.Timestamptz
+	LastError       pgtype.Text
+}
+
+func (q *Queries) GetWebhookDeliveryStatus(ctx context.Context) ([]GetWebhookDeliveryStatusRow, error) {
+	rows, err := q.db.Query(ctx, getWebhookDeliveryStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetWebhookDeliveryStatusRow
+	for rows.Next() {
+		var i GetWebhookDeliveryStatusRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.URL,
+			&i.Pending,
+			&i.Delivered,
+			&i.Dead,
+			&i.LastDeliveredAt,
+			&i.LastError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const insertAdminAudit = `-- name: InsertAdminAudit :exec
+INSERT INTO admin_audit (actor, remote_addr, action, target, outcome, error)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type InsertAdminAuditParams struct {
+	Actor      string
+	RemoteAddr string
+	Action     string
+	Target     string
+	Outcome    string
+	Error      pgtype.Text
+}
+
+func (q *Queries) InsertAdminAudit(ctx context.Context, arg InsertAdminAuditParams) error {
+	_, err := q.db.Exec(ctx, insertAdminAudit,
+		arg.Actor,
+		arg.RemoteAddr,
+		arg.Action,
+		arg.Target,
+		arg.Outcome,
+		arg.Error,
+	)
+	return err
+}
+
+const insertMatchChange = `-- name: InsertMatchChange :exec
+INSERT INTO match_changes (match_id, field, old_value, new_value) VALUES ($1, $2, $3, $4)
+`
+
+type InsertMatchChangeParams struct {
+	MatchID  int32
+	Field    string
+	OldValue pgtype.Text
+	NewValue pgtype.Text
+}
+
+func (q *Queries) InsertMatchChange(ctx context.Context, arg InsertMatchChangeParams) error {
+	_, err := q.db.Exec(ctx, insertMatchChange,
+		arg.MatchID,
+		arg.Field,
+		arg.OldValue,
+		arg.NewValue,
+	)
+	return err
+}
+
+const insertMatchOpponent = `-- name: InsertMatchOpponent :exec
+INSERT INTO match_opponents (match_id, slot, opponent_type, opponent_id, score, placement) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (match_id, slot) DO UPDATE SET
+    opponent_type = EXCLUDED.opponent_type,
+    opponent_id = EXCLUDED.opponent_id,
+    score = EXCLUDED.score,
+    placement = EXCLUDED.placement
+`
+
+type InsertMatchOpponentParams struct {
+	MatchID      int32
+	Slot         int32
+	OpponentType string
+	OpponentID   int32
+	Score        int32
+	Placement    pgtype.Int4
+}
+
+func (q *Queries) InsertMatchOpponent(ctx context.Context, arg InsertMatchOpponentParams) error {
+	_, err := q.db.Exec(ctx, insertMatchOpponent,
+		arg.MatchID,
+		arg.Slot,
+		arg.OpponentType,
+		arg.OpponentID,
+		arg.Score,
+		arg.Placement,
+	)
+	return err
+}
+
+const insertOutboxEvent = `-- name: InsertOutboxEvent :exec
+WITH event AS (
+    INSERT INTO outbox (event_type, match_id, data)
+    SELECT $1::varchar, $2::int, $3::json
+    WHERE EXISTS (SELECT 1 FROM webhook_subscribers)
+    RETURNING id
+)
+INSERT INTO webhook_deliveries (subscriber, event_id)
+SELECT s.name, event.id FROM webhook_subscribers s CROSS JOIN event
+`
+
+type InsertOutboxEventParams struct {
+	EventType string
+	MatchID   int32
+	Data      []byte
+}
+
+func (q *Queries) InsertOutboxEvent(ctx context.Context, arg InsertOutboxEventParams) error {
+	_, err := q.db.Exec(ctx, insertOutboxEvent, arg.EventType, arg.MatchID, arg.Data)
+	return err
+}
+
+const insertToGames = `-- name: InsertToGames :exec
+INSERT INTO games (id, name, slug) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    slug = EXCLUDED.slug,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL
 `
 
-func (q *Queries) ClearMatchesIsLiveExceptIDs(ctx context.Context, dollar_1 []int32) error {
-	_, err := q.db.Exec(ctx, clearMatchesIsLiveExceptIDs, dollar_1)
+type InsertToGamesParams struct {
+	ID   int32
+	Name string
+	Slug pgtype.Text
+}
+
+func (q *Queries) InsertToGames(ctx context.Context, arg InsertToGamesParams) error {
+	_, err := q.db.Exec(ctx, insertToGames, arg.ID, arg.Name, arg.Slug)
 	return err
 }
 
-const gameExist = `-- name: GameExist :one
-SELECT COUNT(*) FROM games WHERE id = $1
+const insertToLeagues = `-- name: InsertToLeagues :execrows
+INSERT INTO leagues (id, name, slug, image_link, game_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    slug = EXCLUDED.slug,
+    image_link = EXCLUDED.image_link,
+    game_id = EXCLUDED.game_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE leagues.deleted_at IS NOT NULL
+    OR leagues.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > leagues.modified_at, true)
+`
+
+type InsertToLeaguesParams struct {
+	ID         int32
+	Name       string
+	Slug       pgtype.Text
+	ImageLink  pgtype.Text
+	GameID     int32
+	ModifiedAt pgtype.Timestamptz
+}
+
+func (q *Queries) InsertToLeagues(ctx context.Context, arg InsertToLeaguesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertToLeagues,
+		arg.ID,
+		arg.Name,
+		arg.Slug,
+		arg.ImageLink,
+		arg.GameID,
+		arg.ModifiedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertToMatches = `-- name: InsertToMatches :execrows
+INSERT INTO matches (id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score, team2_id, team2_score, amount_of_games, game_id, league_id, series_id, tournament_id, stream_url, status, forfeit, draw, rescheduled, original_scheduled_at, begin_at, end_at, winner_id, winner_type, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26) ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    slug = EXCLUDED.slug,
+    finished = EXCLUDED.finished,
+    expected_start_time = EXCLUDED.expected_start_time,
+    actual_game_time = EXCLUDED.actual_game_time,
+    team1_id = EXCLUDED.team1_id,
+    team1_score = EXCLUDED.team1_score,
+    team2_id = EXCLUDED.team2_id,
+    team2_score = EXCLUDED.team2_score,
+    amount_of_games = EXCLUDED.amount_of_games,
+    game_id = EXCLUDED.game_id,
+    league_id = EXCLUDED.league_id,
+    series_id = EXCLUDED.series_id,
+    tournament_id = EXCLUDED.tournament_id,
+    stream_url = EXCLUDED.stream_url,
+    status = EXCLUDED.status,
+    forfeit = EXCLUDED.forfeit,
+    draw = EXCLUDED.draw,
+    rescheduled = EXCLUDED.rescheduled,
+    original_scheduled_at = EXCLUDED.original_scheduled_at,
+    begin_at = EXCLUDED.begin_at,
+    end_at = EXCLUDED.end_at,
+    winner_id = EXCLUDED.winner_id,
+    winner_type = EXCLUDED.winner_type,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE matches.deleted_at IS NOT NULL
+    OR matches.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > matches.modified_at, true)
+`
+
+type InsertToMatchesParams struct {
+	ID                  int32
+	Name                string
+	Slug                pgtype.Text
+	Finished            bool
+	ExpectedStartTime   pgtype.Timestamptz
+	ActualGameTime      float64
+	Team1ID             int32
+	Team1Score          int32
+	Team2ID             int32
+	Team2Score          int32
+	AmountOfGames       int32
+	GameID              int32
+	LeagueID            int32
+	SeriesID            int32
+	TournamentID        int32
+	StreamURL           pgtype.Text
+	Status              pgtype.Text
+	Forfeit             bool
+	Draw                bool
+	Rescheduled         bool
+	OriginalScheduledAt pgtype.Timestamptz
+	BeginAt             pgtype.Timestamptz
+	EndAt               pgtype.Timestamptz
+	WinnerID            pgtype.Int4
+	WinnerType          pgtype.Text
+	ModifiedAt          pgtype.Timestamptz
+}
+
+func (q *Queries) InsertToMatches(ctx context.Context, arg InsertToMatchesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertToMatches,
+		arg.ID,
+		arg.Name,
+		arg.Slug,
+		arg.Finished,
+		arg.ExpectedStartTime,
+		arg.ActualGameTime,
+		arg.Team1ID,
+		arg.Team1Score,
+		arg.Team2ID,
+		arg.Team2Score,
+		arg.AmountOfGames,
+		arg.GameID,
+		arg.LeagueID,
+		arg.SeriesID,
+		arg.TournamentID,
+		arg.StreamURL,
+		arg.Status,
+		arg.Forfeit,
+		arg.Draw,
+		arg.Rescheduled,
+		arg.OriginalScheduledAt,
+		arg.BeginAt,
+		arg.EndAt,
+		arg.WinnerID,
+		arg.WinnerType,
+		arg.ModifiedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertToPlayers = `-- name: InsertToPlayers :execrows
+INSERT INTO players (id, name, slug, first_name, last_name, nationality, image_link, game_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    slug = EXCLUDED.slug,
+    first_name = EXCLUDED.first_name,
+    last_name = EXCLUDED.last_name,
+    nationality = EXCLUDED.nationality,
+    image_link = EXCLUDED.image_link,
+    game_id = EXCLUDED.game_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE players.deleted_at IS NOT NULL
+    OR players.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > players.modified_at, true)
+`
+
+type InsertToPlayersParams struct {
+	ID          int32
+	Name        string
+	Slug        pgtype.Text
+	FirstName   pgtype.Text
+	LastName    pgtype.Text
+	Nationality pgtype.Text
+	ImageLink   pgtype.Text
+	GameID      int32
+	ModifiedAt  pgtype.Timestamptz
+}
+
+func (q *Queries) InsertToPlayers(ctx context.Context, arg InsertToPlayersParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertToPlayers,
+		arg.ID,
+		arg.Name,
+		arg.Slug,
+		arg.FirstName,
+		arg.LastName,
+		arg.Nationality,
+		arg.ImageLink,
+		arg.GameID,
+		arg.ModifiedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertToSeries = `-- name: InsertToSeries :execrows
+INSERT INTO series (id, name, slug, game_id, league_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    slug = EXCLUDED.slug,
+    game_id = EXCLUDED.game_id,
+    league_id = EXCLUDED.league_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE series.deleted_at IS NOT NULL
+    OR series.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > series.modified_at, true)
+`
+
+type InsertToSeriesParams struct {
+	ID         int32
+	Name       string
+	Slug       pgtype.Text
+	GameID     int32
+	LeagueID   int32
+	ModifiedAt pgtype.Timestamptz
+}
+
+func (q *Queries) InsertToSeries(ctx context.Context, arg InsertToSeriesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertToSeries,
+		arg.ID,
+		arg.Name,
+		arg.Slug,
+		arg.GameID,
+		arg.LeagueID,
+		arg.ModifiedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertToTeams = `-- name: InsertToTeams :execrows
+INSERT INTO teams (id, name, slug, acronym, image_link, game_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    slug = EXCLUDED.slug,
+    acronym = EXCLUDED.acronym,
+    image_link = EXCLUDED.image_link,
+    game_id = EXCLUDED.game_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE teams.deleted_at IS NOT NULL
+    OR teams.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > teams.modified_at, true)
+`
+
+type InsertToTeamsParams struct {
+	ID         int32
+	Name       string
+	Slug       pgtype.Text
+	Acronym    pgtype.Text
+	ImageLink  pgtype.Text
+	GameID     int32
+	ModifiedAt pgtype.Timestamptz
+}
+
+func (q *Queries) InsertToTeams(ctx context.Context, arg InsertToTeamsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertToTeams,
+		arg.ID,
+		arg.Name,
+		arg.Slug,
+		arg.Acronym,
+		arg.ImageLink,
+		arg.GameID,
+		arg.ModifiedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertToTournaments = `-- name: InsertToTournaments :execrows
+INSERT INTO tournaments (id,name, slug,tier, game_id, league_id, serie_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    slug = EXCLUDED.slug,
+    tier = EXCLUDED.tier,
+    game_id = EXCLUDED.game_id,
+    league_id = EXCLUDED.league_id,
+    serie_id = EXCLUDED.serie_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE tournaments.deleted_at IS NOT NULL
+    OR tournaments.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > tournaments.modified_at, true)
+`
+
+type InsertToTournamentsParams struct {
+	ID         int32
+	Name       string
+	Slug       pgtype.Text
+	Tier       pgtype.Int4
+	GameID     int32
+	LeagueID   int32
+	SerieID    int32
+	ModifiedAt pgtype.Timestamptz
+}
+
+func (q *Queries) InsertToTournaments(ctx context.Context, arg InsertToTournamentsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, insertToTournaments,
+		arg.ID,
+		arg.Name,
+		arg.Slug,
+		arg.Tier,
+		arg.GameID,
+		arg.LeagueID,
+		arg.SerieID,
+		arg.ModifiedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const leagueExist = `-- name: LeagueExist :one
+SELECT COUNT(*) FROM leagues WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) LeagueExist(ctx context.Context, id int32) (int64, error) {
+	row := q.db.QueryRow(ctx, leagueExist, id)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const listGames = `-- name: ListGames :many
+SELECT id, name, slug, last_seen_at, deleted_at FROM games WHERE (id != 14) AND deleted_at IS NULL ORDER BY id ASC
+LIMIT $1 OFFSET $2
+`
+
+type ListGamesParams struct {
+	MaxRows  int32
+	SkipRows int32
+}
+
+func (q *Queries) ListGames(ctx context.Context, arg ListGamesParams) ([]Game, error) {
+	rows, err := q.db.Query(ctx, listGames, arg.MaxRows, arg.SkipRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Game
+	for rows.Next() {
+		var i Game
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMatches = `-- name: ListMatches :many
+SELECT m.id, m.name, m.slug, m.finished, m.expected_start_time, m.actual_game_time, m.team1_id, m.team1_score, m.team2_id, m.team2_score, m.amount_of_games, m.is_live, m.stream_url, m.status, m.forfeit, m.draw, m.rescheduled, m.original_scheduled_at, m.begin_at, m.end_at, m.winner_id, m.winner_type, m.game_id, m.league_id, m.series_id, m.tournament_id, m.last_seen_at, m.deleted_at, m.modified_at
+FROM matches m
+WHERE m.deleted_at IS NULL
+    AND ($1::timestamptz IS NULL OR m.expected_start_time >= $1)
+    AND ($2::timestamptz IS NULL OR m.expected_start_time < $2)
+    AND ($3::int IS NULL OR m.game_id = $3)
+    AND ($4::int IS NULL OR m.league_id = $4)
+    AND ($5::int IS NULL OR EXISTS (
+        SELECT 1 FROM match_opponents mo
+        WHERE mo.match_id = m.id AND mo.opponent_type = 'Team' AND mo.opponent_id = $5
+    ))
+    AND ($6::boolean IS NULL OR m.is_live = $6)
+ORDER BY m.expected_start_time ASC NULLS LAST, m.id ASC
+LIMIT $7 OFFSET $8
 `
 
-func (q *Queries) GameExist(ctx context.Context, id int32) (int64, error) {
-	row := q.db.QueryRow(ctx, gameExist, id)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
+type ListMatchesParams struct {
+	StartsAfter  pgtype.Timestamptz
+	StartsBefore pgtype.Timestamptz
+	GameID       pgtype.Int4
+	LeagueID     pgtype.Int4
+	TeamID       pgtype.Int4
+	IsLive       pgtype.Bool
+	MaxRows      int32
+	SkipRows     int32
 }
 
-const getAllGames = `-- name: GetAllGames :many
-SELECT id, name, slug FROM games WHERE (id != 14) ORDER BY id ASC
-`
-
-func (q *Queries) GetAllGames(ctx context.Context) ([]Game, error) {
-	rows, err := q.db.Query(ctx, getAllGames)
+func (q *Queries) ListMatches(ctx context.Context, arg ListMatchesParams) ([]Match, error) {
+	rows, err := q.db.Query(ctx, listMatches,
+		arg.StartsAfter,
+		arg.StartsBefore,
+		arg.GameID,
+		arg.LeagueID,
+		arg.TeamID,
+		arg.IsLive,
+		arg.MaxRows,
+		arg.SkipRows,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Game
+	var items []Match
 	for rows.Next() {
-		var i Game
-		if err := rows.Scan(&i.ID, &i.Name, &i.Slug); err != nil {
+		var i Match
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.Finished,
+			&i.ExpectedStartTime,
+			&i.ActualGameTime,
+			&i.Team1ID,
+			&i.Team1Score,
+			&i.Team2ID,
+			&i.Team2Score,
+			&i.AmountOfGames,
+			&i.IsLive,
+			&i.StreamURL,
+			&i.Status,
+			&i.Forfeit,
+			&i.Draw,
+			&i.Rescheduled,
+			&i.OriginalScheduledAt,
+			&i.BeginAt,
+			&i.EndAt,
+			&i.WinnerID,
+			&i.WinnerType,
+			&i.GameID,
+			&i.LeagueID,
+			&i.SeriesID,
+			&i.TournamentID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -55,30 +2517,35 @@ func (q *Queries) GetAllGames(ctx context.Context) ([]Game, error) {
 	return items, nil
 }
 
-const getLeaguesByGameID = `-- name: GetLeaguesByGameID :many
-SELECT l.id, l.name, l.slug, l.game_id, l.image_link
-FROM LEAGUES l
-LEFT JOIN TOURNAMENTS t ON l.id = t.league_id
-WHERE l.game_id = $1
-GROUP BY l.id, l.name, l.slug, l.game_id, l.image_link
-ORDER BY MIN(t.tier) ASC, l.name ASC
+const listSeriesByGameID = `-- name: ListSeriesByGameID :many
+SELECT id, name, slug, game_id, league_id, last_seen_at, deleted_at, modified_at FROM series WHERE game_id = $1 AND deleted_at IS NULL ORDER BY name ASC, id ASC
+LIMIT $2 OFFSET $3
 `
 
-func (q *Queries) GetLeaguesByGameID(ctx context.Context, gameID int32) ([]League, error) {
-	rows, err := q.db.Query(ctx, getLeaguesByGameID, gameID)
+type ListSeriesByGameIDParams struct {
+	GameID   int32
+	MaxRows  int32
+	SkipRows int32
+}
+
+func (q *Queries) ListSeriesByGameID(ctx context.Context, arg ListSeriesByGameIDParams) ([]Series, error) {
+	rows, err := q.db.Query(ctx, listSeriesByGameID, arg.GameID, arg.MaxRows, arg.SkipRows)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []League
+	var items []Series
 	for rows.Next() {
-		var i League
+		var i Series
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
 			&i.Slug,
 			&i.GameID,
-			&i.ImageLink,
+			&i.LeagueID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -90,25 +2557,38 @@ func (q *Queries) GetLeaguesByGameID(ctx context.Context, gameID int32) ([]Leagu
 	return items, nil
 }
 
-const getSeriesByGameID = `-- name: GetSeriesByGameID :many
-SELECT id, name, slug, game_id, league_id FROM series WHERE game_id = $1 ORDER BY name ASC
+const listTournamentsByGameID = `-- name: ListTournamentsByGameID :many
+SELECT id, name, slug, tier, game_id, league_id, serie_id, last_seen_at, deleted_at, modified_at, search_vector FROM tournaments WHERE game_id = $1 AND deleted_at IS NULL ORDER BY name ASC, id ASC
+LIMIT $2 OFFSET $3
 `
 
-func (q *Queries) GetSeriesByGameID(ctx context.Context, gameID int32) ([]Series, error) {
-	rows, err := q.db.Query(ctx, getSeriesByGameID, gameID)
+type ListTournamentsByGameIDParams struct {
+	GameID   int32
+	MaxRows  int32
+	SkipRows int32
+}
+
+func (q *Queries) ListTournamentsByGameID(ctx context.Context, arg ListTournamentsByGameIDParams) ([]Tournament, error) {
+	rows, err := q.db.Query(ctx, listTournamentsByGameID, arg.GameID, arg.MaxRows, arg.SkipRows)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Series
+	var items []Tournament
 	for rows.Next() {
-		var i Series
+		var i Tournament
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
 			&i.Slug,
+			&i.Tier,
 			&i.GameID,
 			&i.LeagueID,
+			&i.SerieID,
+			&i.LastSeenAt,
+			&i.DeletedAt,
+			&i.ModifiedAt,
+			&i.SearchVector,
 		); err != nil {
 			return nil, err
 		}
@@ -120,224 +2600,270 @@ func (q *Queries) GetSeriesByGameID(ctx context.Context, gameID int32) ([]Series
 	return items, nil
 }
 
-const insertToGames = `-- name: InsertToGames :exec
-INSERT INTO games (id, name, slug) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET
-    name = EXCLUDED.name,
-    slug = EXCLUDED.slug
+const markMatchesLive = `-- name: MarkMatchesLive :many
+WITH started AS (
+    UPDATE matches SET is_live = true WHERE id = ANY($1::int[]) AND NOT is_live
+    RETURNING id
+), event AS (
+    INSERT INTO outbox (event_type, match_id)
+    SELECT 'match_live', started.id FROM started
+    WHERE EXISTS (SELECT 1 FROM webhook_subscribers)
+    RETURNING id
+), delivery AS (
+    INSERT INTO webhook_deliveries (subscriber, event_id)
+    SELECT s.name, event.id FROM webhook_subscribers s CROSS JOIN event
+)
+SELECT id FROM started
 `
 
-type InsertToGamesParams struct {
-	ID   int32
-	Name string
-	Slug pgtype.Text
+func (q *Queries) MarkMatchesLive(ctx context.Context, ids []int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, markMatchesLive, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) InsertToGames(ctx context.Context, arg InsertToGamesParams) error {
-	_, err := q.db.Exec(ctx, insertToGames, arg.ID, arg.Name, arg.Slug)
+const markResolveRetryFailed = `-- name: MarkResolveRetryFailed :exec
+UPDATE resolve_retries
+SET attempts = attempts + 1, last_error = $3, last_attempt_at = CURRENT_TIMESTAMP
+WHERE entity_type = $1 AND entity_id = $2
+`
+
+type MarkResolveRetryFailedParams struct {
+	EntityType string
+	EntityID   int32
+	LastError  pgtype.Text
+}
+
+func (q *Queries) MarkResolveRetryFailed(ctx context.Context, arg MarkResolveRetryFailedParams) error {
+	_, err := q.db.Exec(ctx, markResolveRetryFailed, arg.EntityType, arg.EntityID, arg.LastError)
 	return err
 }
 
-const insertToLeagues = `-- name: InsertToLeagues :exec
-INSERT INTO leagues (id, name, slug, image_link, game_id) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (id) DO UPDATE SET
-    name = EXCLUDED.name,
-    slug = EXCLUDED.slug,
-    image_link = EXCLUDED.image_link,
-    game_id = EXCLUDED.game_id
+const markWebhookDelivered = `-- name: MarkWebhookDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'delivered', attempts = attempts + 1, last_error = NULL, last_attempt_at = $1, delivered_at = $1
+WHERE subscriber = $2 AND event_id = $3
 `
 
-type InsertToLeaguesParams struct {
-	ID        int32
-	Name      string
-	Slug      pgtype.Text
-	ImageLink pgtype.Text
-	GameID    int32
+type MarkWebhookDeliveredParams struct {
+	Now        pgtype.Timestamptz
+	Subscriber string
+	EventID    int64
 }
 
-func (q *Queries) InsertToLeagues(ctx context.Context, arg InsertToLeaguesParams) error {
-	_, err := q.db.Exec(ctx, insertToLeagues,
-		arg.ID,
-		arg.Name,
-		arg.Slug,
-		arg.ImageLink,
-		arg.GameID,
-	)
+func (q *Queries) MarkWebhookDelivered(ctx context.Context, arg MarkWebhookDeliveredParams) error {
+	_, err := q.db.Exec(ctx, markWebhookDelivered, arg.Now, arg.Subscriber, arg.EventID)
 	return err
 }
 
-const insertToMatches = `-- name: InsertToMatches :exec
-INSERT INTO matches (id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score, team2_id, team2_score, amount_of_games, game_id, league_id, series_id, tournament_id, stream_url) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) ON CONFLICT (id) DO UPDATE SET
-    name = EXCLUDED.name,
-    slug = EXCLUDED.slug,
-    finished = EXCLUDED.finished,
-    expected_start_time = EXCLUDED.expected_start_time,
-    actual_game_time = EXCLUDED.actual_game_time,
-    team1_id = EXCLUDED.team1_id,
-    team1_score = EXCLUDED.team1_score,
-    team2_id = EXCLUDED.team2_id,
-    team2_score = EXCLUDED.team2_score,
-    amount_of_games = EXCLUDED.amount_of_games,
-    game_id = EXCLUDED.game_id,
-    league_id = EXCLUDED.league_id,
-    series_id = EXCLUDED.series_id,
-    tournament_id = EXCLUDED.tournament_id,
-    stream_url = EXCLUDED.stream_url
+const markWebhookDeliveryFailed = `-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1, last_error = $1, last_attempt_at = $2, next_attempt_at = $3,
+    status = CASE WHEN attempts + 1 >= $4::int THEN 'dead' ELSE 'pending' END
+WHERE subscriber = $5 AND event_id = $6
 `
 
-type InsertToMatchesParams struct {
-	ID                int32
-	Name              string
-	Slug              pgtype.Text
-	Finished          bool
-	ExpectedStartTime pgtype.Timestamp
-	ActualGameTime    float64
-	Team1ID           int32
-	Team1Score        int32
-	Team2ID           int32
-	Team2Score        int32
-	AmountOfGames     int32
-	GameID            int32
-	LeagueID          int32
-	SeriesID          int32
-	TournamentID      int32
-	StreamURL         pgtype.Text
+type MarkWebhookDeliveryFailedParams struct {
+	LastError     pgtype.Text
+	Now           pgtype.Timestamptz
+	NextAttemptAt pgtype.Timestamptz
+	MaxAttempts   int32
+	Subscriber    string
+	EventID       int64
 }
 
-func (q *Queries) InsertToMatches(ctx context.Context, arg InsertToMatchesParams) error {
-	_, err := q.db.Exec(ctx, insertToMatches,
-		arg.ID,
-		arg.Name,
-		arg.Slug,
-		arg.Finished,
-		arg.ExpectedStartTime,
-		arg.ActualGameTime,
-		arg.Team1ID,
-		arg.Team1Score,
-		arg.Team2ID,
-		arg.Team2Score,
-		arg.AmountOfGames,
-		arg.GameID,
-		arg.LeagueID,
-		arg.SeriesID,
-		arg.TournamentID,
-		arg.StreamURL,
+func (q *Queries) MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error {
+	_, err := q.db.Exec(ctx, markWebhookDeliveryFailed,
+		arg.LastError,
+		arg.Now,
+		arg.NextAttemptAt,
+		arg.MaxAttempts,
+		arg.Subscriber,
+		arg.EventID,
 	)
 	return err
 }
 
-const insertToSeries = `-- name: InsertToSeries :exec
-INSERT INTO series (id, name, slug, game_id, league_id) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (id) DO UPDATE SET
-    name = EXCLUDED.name,
-    slug = EXCLUDED.slug,
-    game_id = EXCLUDED.game_id,
-    league_id = EXCLUDED.league_id
+const matchExist = `-- name: MatchExist :one
+SELECT COUNT(*) FROM matches WHERE id = $1 AND deleted_at IS NULL
 `
 
-type InsertToSeriesParams struct {
-	ID       int32
-	Name     string
-	Slug     pgtype.Text
-	GameID   int32
-	LeagueID int32
+func (q *Queries) MatchExist(ctx context.Context, id int32) (int64, error) {
+	row := q.db.QueryRow(ctx, matchExist, id)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
 }
 
-func (q *Queries) InsertToSeries(ctx context.Context, arg InsertToSeriesParams) error {
-	_, err := q.db.Exec(ctx, insertToSeries,
-		arg.ID,
-		arg.Name,
-		arg.Slug,
-		arg.GameID,
-		arg.LeagueID,
-	)
+const notify = `-- name: Notify :exec
+SELECT pg_notify($1::text, $2::text)
+`
+
+type NotifyParams struct {
+	Channel string
+	Payload string
+}
+
+func (q *Queries) Notify(ctx context.Context, arg NotifyParams) error {
+	_, err := q.db.Exec(ctx, notify, arg.Channel, arg.Payload)
 	return err
 }
 
-const insertToTeams = `-- name: InsertToTeams :exec
-INSERT INTO teams (id, name, slug, acronym, image_link, game_id) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (id) DO UPDATE SET
-    name = EXCLUDED.name,
-    slug = EXCLUDED.slug,
-    acronym = EXCLUDED.acronym,
-    image_link = EXCLUDED.image_link,
-    game_id = EXCLUDED.game_id
+const playerExist = `-- name: PlayerExist :one
+SELECT COUNT(*) FROM players WHERE id = $1 AND deleted_at IS NULL
 `
 
-type InsertToTeamsParams struct {
-	ID        int32
-	Name      string
-	Slug      pgtype.Text
-	Acronym   pgtype.Text
-	ImageLink pgtype.Text
-	GameID    int32
+func (q *Queries) PlayerExist(ctx context.Context, id int32) (int64, error) {
+	row := q.db.QueryRow(ctx, playerExist, id)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
 }
 
-func (q *Queries) InsertToTeams(ctx context.Context, arg InsertToTeamsParams) error {
-	_, err := q.db.Exec(ctx, insertToTeams,
-		arg.ID,
-		arg.Name,
-		arg.Slug,
-		arg.Acronym,
-		arg.ImageLink,
-		arg.GameID,
-	)
+const refreshRankedLeaguesView = `-- name: RefreshRankedLeaguesView :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY ranked_leagues_view
+`
+
+func (q *Queries) RefreshRankedLeaguesView(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, refreshRankedLeaguesView)
 	return err
 }
 
-const insertToTournaments = `-- name: InsertToTournaments :exec
-INSERT INTO tournaments (id,name, slug,tier, game_id, league_id, serie_id) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO UPDATE SET
-    name = EXCLUDED.name,
-    slug = EXCLUDED.slug,
-    tier = EXCLUDED.tier,
-    game_id = EXCLUDED.game_id,
-    league_id = EXCLUDED.league_id,
-    serie_id = EXCLUDED.serie_id
+const refreshUpcomingMatchesView = `-- name: RefreshUpcomingMatchesView :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY upcoming_matches_view
 `
 
-type InsertToTournamentsParams struct {
-	ID       int32
-	Name     string
-	Slug     pgtype.Text
-	Tier     pgtype.Int4
-	GameID   int32
-	LeagueID int32
-	SerieID  int32
+func (q *Queries) RefreshUpcomingMatchesView(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, refreshUpcomingMatchesView)
+	return err
 }
 
-func (q *Queries) InsertToTournaments(ctx context.Context, arg InsertToTournamentsParams) error {
-	_, err := q.db.Exec(ctx, insertToTournaments,
-		arg.ID,
-		arg.Name,
-		arg.Slug,
-		arg.Tier,
-		arg.GameID,
-		arg.LeagueID,
-		arg.SerieID,
-	)
-	return err
+const requeueDeadWebhookDeliveries = `-- name: RequeueDeadWebhookDeliveries :execrows
+UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+WHERE subscriber = $1 AND status = 'dead'
+`
+
+func (q *Queries) RequeueDeadWebhookDeliveries(ctx context.Context, subscriber string) (int64, error) {
+	result, err := q.db.Exec(ctx, requeueDeadWebhookDeliveries, subscriber)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const leagueExist = `-- name: LeagueExist :one
-SELECT COUNT(*) FROM leagues WHERE id = $1
+const saveURLMapping = `-- name: SaveURLMapping :one
+INSERT INTO url_mappings (hashed_key, value_list, access_count)
+VALUES ($1, $2, 0)
+ON CONFLICT (hashed_key) DO UPDATE SET accessed_at = CURRENT_TIMESTAMP
+RETURNING value_list
 `
 
-func (q *Queries) LeagueExist(ctx context.Context, id int32) (int64, error) {
-	row := q.db.QueryRow(ctx, leagueExist, id)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
+type SaveURLMappingParams struct {
+	HashedKey string
+	ValueList []byte
 }
 
-const matchExist = `-- name: MatchExist :one
-SELECT COUNT(*) FROM matches WHERE id = $1
+func (q *Queries) SaveURLMapping(ctx context.Context, arg SaveURLMappingParams) ([]byte, error) {
+	row := q.db.QueryRow(ctx, saveURLMapping, arg.HashedKey, arg.ValueList)
+	var value_list []byte
+	err := row.Scan(&value_list)
+	return value_list, err
+}
+
+const searchEntities = `-- name: SearchEntities :many
+WITH search AS (
+    SELECT websearch_to_tsquery('simple', stalka_unaccent($1::text)) AS tsq, stalka_unaccent($1::text) AS plain
+),
+hits AS (
+    SELECT 'teams'::text AS entity_type, t.id, t.name, t.slug, t.game_id, t.image_link,
+        CASE WHEN t.search_vector @@ s.tsq THEN 1 + ts_rank(t.search_vector, s.tsq)
+        ELSE similarity(stalka_unaccent(t.name), s.plain) END AS score
+    FROM teams t, search s
+    WHERE t.deleted_at IS NULL AND (t.search_vector @@ s.tsq OR stalka_unaccent(t.name) % s.plain)
+    UNION ALL
+    SELECT 'leagues'::text, l.id, l.name, l.slug, l.game_id, l.image_link,
+        CASE WHEN l.search_vector @@ s.tsq THEN 1 + ts_rank(l.search_vector, s.tsq)
+        ELSE similarity(stalka_unaccent(l.name), s.plain) END
+    FROM leagues l, search s
+    WHERE l.deleted_at IS NULL AND (l.search_vector @@ s.tsq OR stalka_unaccent(l.name) % s.plain)
+    UNION ALL
+    SELECT 'tournaments'::text, tr.id, tr.name, tr.slug, tr.game_id, NULL::varchar,
+        CASE WHEN tr.search_vector @@ s.tsq THEN 1 + ts_rank(tr.search_vector, s.tsq)
+        ELSE similarity(stalka_unaccent(tr.name), s.plain) END
+    FROM tournaments tr, search s
+    WHERE tr.deleted_at IS NULL AND (tr.search_vector @@ s.tsq OR stalka_unaccent(tr.name) % s.plain)
+    UNION ALL
+    SELECT 'players'::text, p.id, p.name, p.slug, p.game_id, p.image_link,
+        CASE WHEN p.search_vector @@ s.tsq THEN 1 + ts_rank(p.search_vector, s.tsq)
+        ELSE similarity(stalka_unaccent(p.name), s.plain) END
+    FROM players p, search s
+    WHERE p.deleted_at IS NULL AND (p.search_vector @@ s.tsq OR stalka_unaccent(p.name) % s.plain)
+)
+SELECT entity_type, id, name, slug, game_id, image_link, score::real AS score
+FROM hits
+ORDER BY score DESC, name ASC, id ASC
+LIMIT $2
 `
 
-func (q *Queries) MatchExist(ctx context.Context, id int32) (int64, error) {
-	row := q.db.QueryRow(ctx, matchExist, id)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
+type SearchEntitiesParams struct {
+	Query   string
+	MaxRows int32
+}
+
+type SearchEntitiesRow struct {
+	EntityType string
+	ID         int32
+	Name       string
+	Slug       pgtype.Text
+	GameID     int32
+	ImageLink  pgtype.Text
+	Score      float32
+}
+
+func (q *Queries) SearchEntities(ctx context.Context, arg SearchEntitiesParams) ([]SearchEntitiesRow, error) {
+	rows, err := q.db.Query(ctx, searchEntities, arg.Query, arg.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchEntitiesRow
+	for rows.Next() {
+		var i SearchEntitiesRow
+		if err := rows.Scan(
+			&i.EntityType,
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.GameID,
+			&i.ImageLink,
+			&i.Score,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const seriesExist = `-- name: SeriesExist :one
-SELECT COUNT(*) FROM series WHERE id = $1
+SELECT COUNT(*) FROM series WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) SeriesExist(ctx context.Context, id int32) (int64, error) {
@@ -348,7 +2874,7 @@ func (q *Queries) SeriesExist(ctx context.Context, id int32) (int64, error) {
 }
 
 const teamExist = `-- name: TeamExist :one
-SELECT COUNT(*) FROM teams WHERE id = $1
+SELECT COUNT(*) FROM teams WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) TeamExist(ctx context.Context, id int32) (int64, error) {
@@ -358,8 +2884,48 @@ func (q *Queries) TeamExist(ctx context.Context, id int32) (int64, error) {
 	return count, err
 }
 
+const tombstoneMatch = `-- name: TombstoneMatch :exec
+UPDATE matches SET deleted_at = CURRENT_TIMESTAMP, is_live = false WHERE id = $1
+`
+
+func (q *Queries) TombstoneMatch(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, tombstoneMatch, id)
+	return err
+}
+
+const tombstoneTeam = `-- name: TombstoneTeam :exec
+UPDATE teams SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1
+`
+
+func (q *Queries) TombstoneTeam(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, tombstoneTeam, id)
+	return err
+}
+
+const tombstoneTournament = `-- name: TombstoneTournament :exec
+UPDATE tournaments SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1
+`
+
+func (q *Queries) TombstoneTournament(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, tombstoneTournament, id)
+	return err
+}
+
+const touchURLMapping = `-- name: TouchURLMapping :one
+UPDATE url_mappings SET access_count = access_count + 1, accessed_at = CURRENT_TIMESTAMP
+WHERE hashed_key = $1
+RETURNING value_list
+`
+
+func (q *Queries) TouchURLMapping(ctx context.Context, hashedKey string) ([]byte, error) {
+	row := q.db.QueryRow(ctx, touchURLMapping, hashedKey)
+	var value_list []byte
+	err := row.Scan(&value_list)
+	return value_list, err
+}
+
 const tournamentExist = `-- name: TournamentExist :one
-SELECT COUNT(*) FROM tournaments WHERE id = $1
+SELECT COUNT(*) FROM tournaments WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) TournamentExist(ctx context.Context, id int32) (int64, error) {
@@ -369,6 +2935,20 @@ func (q *Queries) TournamentExist(ctx context.Context, id int32) (int64, error)
 	return count, err
 }
 
+const trimMatchOpponents = `-- name: TrimMatchOpponents :exec
+DELETE FROM match_opponents WHERE match_id = $1 AND slot >= $2
+`
+
+type TrimMatchOpponentsParams struct {
+	MatchID int32
+	Slot    int32
+}
+
+func (q *Queries) TrimMatchOpponents(ctx context.Context, arg TrimMatchOpponentsParams) error {
+	_, err := q.db.Exec(ctx, trimMatchOpponents, arg.MatchID, arg.Slot)
+	return err
+}
+
 const updateMatchesIsLiveByIDs = `-- name: UpdateMatchesIsLiveByIDs :exec
 UPDATE MATCHES SET is_live = $1 WHERE id = ANY($2::int[])
 `
@@ -382,3 +2962,18 @@ func (q *Queries) UpdateMatchesIsLiveByIDs(ctx context.Context, arg UpdateMatche
 	_, err := q.db.Exec(ctx, updateMatchesIsLiveByIDs, arg.IsLive, arg.Column2)
 	return err
 }
+
+const upsertWebhookSubscriber = `-- name: UpsertWebhookSubscriber :exec
+INSERT INTO webhook_subscribers (name, url) VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET url = EXCLUDED.url
+`
+
+type UpsertWebhookSubscriberParams struct {
+	Name string
+	URL  string
+}
+
+func (q *Queries) UpsertWebhookSubscriber(ctx context.Context, arg UpsertWebhookSubscriberParams) error {
+	_, err := q.db.Exec(ctx, upsertWebhookSubscriber, arg.Name, arg.URL)
+	return err
+}
@@ -0,0 +1,112 @@
+package ics
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// ProdID identifies stalka as the producer of a feed.
+	ProdID = "-//stalka//esports calendar//EN"
+	// maxLineOctets is the longest content line RFC 5545 allows before folding.
+	maxLineOctets = 75
+	utcFormat     = "20060102T150405Z"
+)
+
+// Calendar is an iCalendar (RFC 5545) feed of events that calendar apps can
+// subscribe to.
+type Calendar struct {
+	// Name is shown by calendar apps as the name of the subscription.
+	Name   string
+	Events []Event
+}
+
+// Event is a single VEVENT. UID must stay the same across renders of the same
+// event, and Sequence must grow whenever its start, end or status changes, so
+// subscribers update the event instead of duplicating or ignoring it.
+type Event struct {
+	UID         string
+	Sequence    int32
+	Stamp       time.Time
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	URL         string
+	Cancelled   bool
+}
+
+// Encode renders the calendar with CRLF line endings, folded lines and times
+// in UTC.
+// @returns the iCalendar document.
+func (calendar Calendar) Encode() []byte {
+	var buf bytes.Buffer
+	line := func(name string, value string) {
+		writeFolded(&buf, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", ProdID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if calendar.Name != "" {
+		line("X-WR-CALNAME", escape(calendar.Name))
+	}
+	for _, event := range calendar.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escape(event.UID))
+		line("SEQUENCE", strconv.Itoa(int(event.Sequence)))
+		line("DTSTAMP", event.Stamp.UTC().Format(utcFormat))
+		line("DTSTART", event.Start.UTC().Format(utcFormat))
+		line("DTEND", event.End.UTC().Format(utcFormat))
+		line("SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION", escape(event.Description))
+		}
+		if event.URL != "" {
+			line("URL", event.URL)
+		}
+		if event.Cancelled {
+			line("STATUS", "CANCELLED")
+		} else {
+			line("STATUS", "CONFIRMED")
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return buf.Bytes()
+}
+
+// escape escapes a TEXT value.
+func escape(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(value)
+}
+
+// writeFolded writes a content line, folding it into continuation lines of at
+// most maxLineOctets octets without splitting a UTF-8 sequence.
+func writeFolded(buf *bytes.Buffer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space of a continuation line counts towards its length.
+		limit = maxLineOctets - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
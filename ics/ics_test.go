@@ -0,0 +1,77 @@
+package ics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+)
+
+func TestEncode(t *testing.T) {
+	seoul := time.FixedZone("KST", 9*60*60)
+	calendar := Calendar{
+		Name: "LCK, Spring",
+		Events: []Event{{
+			UID:         "match-1@stalka",
+			Sequence:    2,
+			Stamp:       time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+			Start:       time.Date(2025, 3, 2, 17, 0, 0, 0, seoul),
+			End:         time.Date(2025, 3, 2, 20, 0, 0, 0, seoul),
+			Summary:     "T1 vs Gen.G; final",
+			Description: "LCK - Playoffs\nStream: https://twitch.tv/lck",
+			URL:         "https://twitch.tv/lck",
+			Cancelled:   true,
+		}},
+	}
+	out := string(calendar.Encode())
+
+	t.Run("Lines end with CRLF", func(t *testing.T) {
+		st.Expect(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"), true)
+		st.Expect(t, strings.HasSuffix(out, "END:VEVENT\r\nEND:VCALENDAR\r\n"), true)
+		st.Expect(t, strings.Count(out, "\n"), strings.Count(out, "\r\n"))
+	})
+
+	t.Run("Times are written in UTC", func(t *testing.T) {
+		st.Expect(t, strings.Contains(out, "\r\nDTSTART:20250302T080000Z\r\n"), true)
+		st.Expect(t, strings.Contains(out, "\r\nDTEND:20250302T110000Z\r\n"), true)
+	})
+
+	t.Run("Text values are escaped", func(t *testing.T) {
+		st.Expect(t, strings.Contains(out, "\r\nX-WR-CALNAME:LCK\\, Spring\r\n"), true)
+		st.Expect(t, strings.Contains(out, "\r\nSUMMARY:T1 vs Gen.G\\; final\r\n"), true)
+		st.Expect(t, strings.Contains(out, "\r\nDESCRIPTION:LCK - Playoffs\\nStream: https://twitch.tv/lck\r\n"), true)
+	})
+
+	t.Run("Identity and status", func(t *testing.T) {
+		st.Expect(t, strings.Contains(out, "\r\nUID:match-1@stalka\r\nSEQUENCE:2\r\n"), true)
+		st.Expect(t, strings.Contains(out, "\r\nSTATUS:CANCELLED\r\n"), true)
+	})
+}
+
+func TestWriteFolded(t *testing.T) {
+	t.Run("Long lines are folded at 75 octets", func(t *testing.T) {
+		var buf bytes.Buffer
+		writeFolded(&buf, "DESCRIPTION:"+strings.Repeat("a", 200))
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+		st.Assert(t, len(lines), 3)
+		for i, line := range lines {
+			st.Expect(t, len(line) <= maxLineOctets, true)
+			st.Expect(t, i == 0 || strings.HasPrefix(line, " "), true)
+		}
+		unfolded := strings.ReplaceAll(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n ", "")
+		st.Expect(t, unfolded, "DESCRIPTION:"+strings.Repeat("a", 200))
+	})
+
+	t.Run("Multi-byte characters are not split", func(t *testing.T) {
+		var buf bytes.Buffer
+		line := "SUMMARY:" + strings.Repeat("é", 60)
+		writeFolded(&buf, line)
+		for folded := range strings.SplitSeq(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+			st.Expect(t, len(folded) <= maxLineOctets, true)
+			st.Expect(t, strings.ToValidUTF8(folded, "?"), folded)
+		}
+		st.Expect(t, strings.ReplaceAll(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n ", ""), line)
+	})
+}
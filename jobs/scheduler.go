@@ -0,0 +1,231 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrUnknownJob is returned for a job name that was never added.
+var ErrUnknownJob = errors.New("unknown job")
+
+// Job is a named sync task that runs on an interval.
+type Job struct {
+	// Name identifies the job, e.g. in the admin API.
+	Name     string
+	Interval time.Duration
+	// Run performs one run of the job. Errors are logged by the scheduler.
+	Run func() error
+}
+
+// Status is a snapshot of a job.
+type Status struct {
+	Name     string
+	Interval time.Duration
+	// Paused jobs skip their ticks, but still run when triggered.
+	Paused  bool
+	Running bool
+	// Queued is true when a triggered run waits for the current one to finish.
+	Queued         bool
+	LastStartedAt  *time.Time
+	LastFinishedAt *time.Time
+	LastError      *string
+	NextRunAt      *time.Time
+}
+
+// entry is the state of an added job.
+type entry struct {
+	job     Job
+	paused  bool
+	running bool
+	// trigger holds at most one pending manual run.
+	trigger    chan struct{}
+	lastStart  time.Time
+	lastFinish time.Time
+	lastErr    error
+	next       time.Time
+}
+
+// Scheduler runs every job in a goroutine of its own, on its interval or when
+// triggered. A job never runs twice at the same time.
+type Scheduler struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	// order keeps the jobs in the order they were added.
+	order  []string
+	Logger *zap.SugaredLogger
+}
+
+// NewScheduler creates a scheduler without jobs.
+// @param logger - logs the runs of the jobs.
+// @returns the scheduler.
+func NewScheduler(logger *zap.SugaredLogger) *Scheduler {
+	return &Scheduler{mu: sync.Mutex{}, entries: make(map[string]*entry), order: nil, Logger: logger}
+}
+
+// Add registers a job. Jobs added after Start do not run.
+// @param job - the job, its name must be unique.
+func (scheduler *Scheduler) Add(job Job) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if _, ok := scheduler.entries[job.Name]; !ok {
+		scheduler.order = append(scheduler.order, job.Name)
+	}
+	scheduler.entries[job.Name] = &entry{
+		job:        job,
+		paused:     false,
+		running:    false,
+		trigger:    make(chan struct{}, 1),
+		lastStart:  time.Time{},
+		lastFinish: time.Time{},
+		lastErr:    nil,
+		next:       time.Time{},
+	}
+}
+
+// Start runs every job on its interval until ctx is done. The first tick of a
+// job comes one interval after Start.
+func (scheduler *Scheduler) Start(ctx context.Context) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	now := time.Now()
+	for _, name := range scheduler.order {
+		current := scheduler.entries[name]
+		current.next = now.Add(current.job.Interval)
+		go scheduler.loop(ctx, current)
+	}
+}
+
+// loop runs a job on every tick that finds it unpaused and on every trigger.
+func (scheduler *Scheduler) loop(ctx context.Context, current *entry) {
+	ticker := time.NewTicker(current.job.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tick := <-ticker.C:
+			scheduler.mu.Lock()
+			current.next = tick.Add(current.job.Interval)
+			paused := current.paused
+			scheduler.mu.Unlock()
+			if paused {
+				scheduler.Logger.Infof("Skipping paused job %s", current.job.Name)
+				continue
+			}
+			scheduler.run(current)
+		case <-current.trigger:
+			scheduler.Logger.Infof("Running job %s on demand", current.job.Name)
+			scheduler.run(current)
+		}
+	}
+}
+
+// run performs one run of a job and records its outcome.
+func (scheduler *Scheduler) run(current *entry) {
+	scheduler.mu.Lock()
+	current.running = true
+	current.lastStart = time.Now()
+	scheduler.mu.Unlock()
+	err := current.job.Run()
+	if err != nil {
+		scheduler.Logger.Errorf("Job %s failed: %v", current.job.Name, err)
+	}
+	scheduler.mu.Lock()
+	current.running = false
+	current.lastFinish = time.Now()
+	current.lastErr = err
+	scheduler.mu.Unlock()
+}
+
+// Trigger queues a run of a job right away, even when it is paused. A job that
+// is running runs again once it finished; triggering it again before that
+// does not queue a second run.
+// @param name - the name of the job.
+// @returns ErrUnknownJob if no job has that name.
+func (scheduler *Scheduler) Trigger(name string) error {
+	current, err := scheduler.entry(name)
+	if err != nil {
+		return err
+	}
+	select {
+	case current.trigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Pause makes a job skip its ticks until it is resumed. A running job finishes
+// its current run.
+// @param name - the name of the job.
+// @returns ErrUnknownJob if no job has that name.
+func (scheduler *Scheduler) Pause(name string) error {
+	return scheduler.setPaused(name, true)
+}
+
+// Resume undoes Pause.
+// @param name - the name of the job.
+// @returns ErrUnknownJob if no job has that name.
+func (scheduler *Scheduler) Resume(name string) error {
+	return scheduler.setPaused(name, false)
+}
+
+func (scheduler *Scheduler) setPaused(name string, paused bool) error {
+	current, err := scheduler.entry(name)
+	if err != nil {
+		return err
+	}
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	current.paused = paused
+	return nil
+}
+
+func (scheduler *Scheduler) entry(name string) (*entry, error) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	current, ok := scheduler.entries[name]
+	if !ok {
+		return nil, ErrUnknownJob
+	}
+	return current, nil
+}
+
+// Status returns a snapshot of every job in the order they were added.
+func (scheduler *Scheduler) Status() []Status {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	statuses := make([]Status, 0, len(scheduler.order))
+	for _, name := range scheduler.order {
+		current := scheduler.entries[name]
+		status := Status{
+			Name:           name,
+			Interval:       current.job.Interval,
+			Paused:         current.paused,
+			Running:        current.running,
+			Queued:         len(current.trigger) > 0,
+			LastStartedAt:  optionalTime(current.lastStart),
+			LastFinishedAt: optionalTime(current.lastFinish),
+			LastError:      nil,
+			NextRunAt:      optionalTime(current.next),
+		}
+		if current.lastErr != nil {
+			message := current.lastErr.Error()
+			status.LastError = &message
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// optionalTime returns nil for the zero time.
+func optionalTime(value time.Time) *time.Time {
+	if value.IsZero() {
+		return nil
+	}
+	utc := value.UTC()
+	return &utc
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	"go.uber.org/zap/zaptest"
+)
+
+// waitFor polls cond until it holds or a second passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	t.Run("Triggered jobs run right away, even when paused", func(t *testing.T) {
+		scheduler := NewScheduler(zaptest.NewLogger(t).Sugar())
+		runs := make(chan struct{}, 10)
+		scheduler.Add(Job{Name: "matches", Interval: time.Hour, Run: func() error {
+			runs <- struct{}{}
+			return errors.New("PandaScore is down")
+		}})
+		scheduler.Start(t.Context())
+
+		st.Expect(t, scheduler.Pause("matches"), nil)
+		st.Expect(t, scheduler.Trigger("matches"), nil)
+		<-runs
+		waitFor(t, func() bool { return scheduler.Status()[0].LastFinishedAt != nil })
+
+		status := scheduler.Status()[0]
+		st.Expect(t, status.Name, "matches")
+		st.Expect(t, status.Paused, true)
+		st.Expect(t, status.Running, false)
+		st.Expect(t, *status.LastError, "PandaScore is down")
+		st.Expect(t, status.NextRunAt.After(time.Now().Add(59*time.Minute)), true)
+	})
+
+	t.Run("Paused jobs skip their ticks until resumed", func(t *testing.T) {
+		scheduler := NewScheduler(zaptest.NewLogger(t).Sugar())
+		runs := make(chan struct{}, 100)
+		scheduler.Add(Job{Name: "lives", Interval: time.Millisecond, Run: func() error {
+			runs <- struct{}{}
+			return nil
+		}})
+		st.Expect(t, scheduler.Pause("lives"), nil)
+		scheduler.Start(t.Context())
+		time.Sleep(20 * time.Millisecond)
+		st.Expect(t, len(runs), 0)
+
+		st.Expect(t, scheduler.Resume("lives"), nil)
+		<-runs
+		st.Expect(t, scheduler.Status()[0].Paused, false)
+	})
+
+	t.Run("A running job queues one more run", func(t *testing.T) {
+		scheduler := NewScheduler(zaptest.NewLogger(t).Sugar())
+		release := make(chan struct{})
+		started := make(chan struct{}, 10)
+		scheduler.Add(Job{Name: "setup", Interval: time.Hour, Run: func() error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}})
+		scheduler.Start(t.Context())
+
+		st.Expect(t, scheduler.Trigger("setup"), nil)
+		<-started
+		st.Expect(t, scheduler.Trigger("setup"), nil)
+		st.Expect(t, scheduler.Trigger("setup"), nil)
+		status := scheduler.Status()[0]
+		st.Expect(t, status.Running, true)
+		st.Expect(t, status.Queued, true)
+
+		release <- struct{}{}
+		<-started
+		release <- struct{}{}
+		waitFor(t, func() bool { return !scheduler.Status()[0].Running })
+		st.Expect(t, len(started), 0)
+	})
+
+	t.Run("Error - unknown job", func(t *testing.T) {
+		scheduler := NewScheduler(zaptest.NewLogger(t).Sugar())
+		st.Expect(t, scheduler.Trigger("bogus"), ErrUnknownJob)
+		st.Expect(t, scheduler.Pause("bogus"), ErrUnknownJob)
+		st.Expect(t, scheduler.Resume("bogus"), ErrUnknownJob)
+		st.Expect(t, len(scheduler.Status()), 0)
+	})
+}
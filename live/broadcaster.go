@@ -0,0 +1,251 @@
+package live
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/feimaomiao/stalka/pandatypes"
+)
+
+// Event types pushed to subscribers.
+const (
+	MatchStarted = "match_started"
+	ScoreChanged = "score_changed"
+	GameFinished = "game_finished"
+	MatchEnded   = "match_ended"
+)
+
+const (
+	// HistorySize is how many events are kept for subscribers resuming with
+	// Last-Event-ID.
+	HistorySize = 1000
+	// subscriberBuffer is how many events a subscriber may fall behind before
+	// it is dropped; it resumes from its last event when it reconnects.
+	subscriberBuffer = 64
+)
+
+// Event is a change between two live polls.
+type Event struct {
+	// ID grows with every event, including across restarts, see NewBroadcaster.
+	ID      uint64    `json:"id"`
+	Type    string    `json:"type"`
+	MatchID int32     `json:"match_id"`
+	GameID  int32     `json:"game_id"`
+	At      time.Time `json:"at"`
+	// OpponentType tells whether OpponentIDs are team or player IDs, see Snapshot.
+	OpponentType string `json:"opponent_type"`
+	// OpponentIDs are the team or player IDs of the match.
+	OpponentIDs []int32 `json:"opponent_ids"`
+	Scores      []Score `json:"scores"`
+	// GamePosition and WinnerID describe the game of a game_finished event.
+	GamePosition int32 `json:"game_position,omitempty"`
+	WinnerID     int32 `json:"winner_id,omitempty"`
+}
+
+// Filter selects the events of a subscriber. An empty filter selects every
+// event; otherwise an event is selected when its game or any of its team
+// opponents is listed. Player IDs are never matched against TeamIDs, as they
+// are numbered independently of team IDs.
+type Filter struct {
+	GameIDs []int32
+	TeamIDs []int32
+}
+
+// Matches reports whether the filter selects an event.
+// @param event - the event.
+// @returns true if the subscriber wants the event.
+func (filter Filter) Matches(event Event) bool {
+	if len(filter.GameIDs) == 0 && len(filter.TeamIDs) == 0 {
+		return true
+	}
+	if slices.Contains(filter.GameIDs, event.GameID) {
+		return true
+	}
+	if event.OpponentType != pandatypes.OpponentTypeTeam {
+		return false
+	}
+	for _, id := range event.OpponentIDs {
+		if slices.Contains(filter.TeamIDs, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Broadcaster diffs every live poll against the previous one and pushes the
+// differences to its subscribers.
+type Broadcaster struct {
+	mu          sync.Mutex
+	previous    map[int32]Snapshot
+	lastID      uint64
+	history     []Event
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	filter Filter
+	events chan Event
+}
+
+// NewBroadcaster creates a broadcaster. Event IDs continue from the start
+// time in microseconds, so IDs a client saw before a restart are lower than
+// every new one and it resumes without missing events.
+// @param now - the start time.
+// @returns the broadcaster.
+func NewBroadcaster(now time.Time) *Broadcaster {
+	return &Broadcaster{
+		mu:          sync.Mutex{},
+		previous:    nil,
+		lastID:      uint64(now.UnixMicro()), //nolint:gosec // after 1970
+		history:     make([]Event, 0, HistorySize),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Observe diffs a poll of the running matches against the previous one and
+// pushes the resulting events. The first poll only records the state, so a
+// restart does not report every running match as started again.
+// @param snapshots - every running match whose state could be read.
+// @param unreadable - the running matches whose state could not be read. They
+// keep their previous state instead of ending and starting again next poll.
+// @param now - the time of the poll.
+// @returns the events of the poll.
+func (broadcaster *Broadcaster) Observe(snapshots []Snapshot, unreadable []int32, now time.Time) []Event {
+	broadcaster.mu.Lock()
+	defer broadcaster.mu.Unlock()
+	current := make(map[int32]Snapshot, len(snapshots)+len(unreadable))
+	for _, snapshot := range snapshots {
+		current[snapshot.MatchID] = snapshot
+	}
+	for _, id := range unreadable {
+		if previous, running := broadcaster.previous[id]; running {
+			current[id] = previous
+		}
+	}
+	if broadcaster.previous == nil {
+		broadcaster.previous = current
+		return nil
+	}
+	var events []Event
+	for _, snapshot := range snapshots {
+		events = append(events, diff(broadcaster.previous[snapshot.MatchID], snapshot, now)...)
+	}
+	var ended []int32
+	for id := range broadcaster.previous {
+		if _, running := current[id]; !running {
+			ended = append(ended, id)
+		}
+	}
+	// Ended matches come out of a map, sort them for a stable order.
+	slices.Sort(ended)
+	for _, id := range ended {
+		events = append(events, newEvent(MatchEnded, broadcaster.previous[id], now))
+	}
+	broadcaster.previous = current
+	for i := range events {
+		broadcaster.lastID++
+		events[i].ID = broadcaster.lastID
+		broadcaster.publish(events[i])
+	}
+	return events
+}
+
+// diff compares two polls of the same match. A zero previous snapshot means
+// the match was not running before.
+func diff(previous Snapshot, current Snapshot, now time.Time) []Event {
+	if previous.MatchID == 0 {
+		return []Event{newEvent(MatchStarted, current, now)}
+	}
+	var events []Event
+	finished := make(map[int32]bool, len(previous.Games))
+	for _, game := range previous.Games {
+		finished[game.ID] = game.Finished
+	}
+	for _, game := range current.Games {
+		if game.Finished && !finished[game.ID] {
+			event := newEvent(GameFinished, current, now)
+			event.GamePosition = game.Position
+			event.WinnerID = game.WinnerID
+			events = append(events, event)
+		}
+	}
+	if !slices.Equal(previous.Scores, current.Scores) {
+		events = append(events, newEvent(ScoreChanged, current, now))
+	}
+	return events
+}
+
+func newEvent(eventType string, snapshot Snapshot, now time.Time) Event {
+	return Event{
+		ID:           0,
+		Type:         eventType,
+		MatchID:      snapshot.MatchID,
+		GameID:       snapshot.GameID,
+		At:           now.UTC(),
+		OpponentType: snapshot.OpponentType,
+		OpponentIDs:  snapshot.OpponentIDs,
+		Scores:       snapshot.Scores,
+		GamePosition: 0,
+		WinnerID:     0,
+	}
+}
+
+// publish records an event and hands it to every subscriber that selects it.
+// Subscribers that fell subscriberBuffer events behind are dropped.
+// Called with the lock held.
+func (broadcaster *Broadcaster) publish(event Event) {
+	if len(broadcaster.history) == HistorySize {
+		broadcaster.history = append(broadcaster.history[:0], broadcaster.history[1:]...)
+	}
+	broadcaster.history = append(broadcaster.history, event)
+	for sub := range broadcaster.subscribers {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(broadcaster.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Subscribe registers a subscriber. Events after lastEventID that are still in
+// the history are returned for replay; 0 replays nothing.
+// @param filter - selects the events of the subscriber.
+// @param lastEventID - the ID of the last event the subscriber received.
+// @returns the events to replay, the channel of new events, closed when the
+// subscriber falls behind, and a function that unsubscribes.
+func (broadcaster *Broadcaster) Subscribe(filter Filter, lastEventID uint64) ([]Event, <-chan Event, func()) {
+	broadcaster.mu.Lock()
+	defer broadcaster.mu.Unlock()
+	var replay []Event
+	if lastEventID > 0 {
+		for _, event := range broadcaster.history {
+			if event.ID > lastEventID && filter.Matches(event) {
+				replay = append(replay, event)
+			}
+		}
+	}
+	sub := &subscriber{filter: filter, events: make(chan Event, subscriberBuffer)}
+	broadcaster.subscribers[sub] = struct{}{}
+	unsubscribe := func() {
+		broadcaster.mu.Lock()
+		defer broadcaster.mu.Unlock()
+		if _, ok := broadcaster.subscribers[sub]; ok {
+			delete(broadcaster.subscribers, sub)
+			close(sub.events)
+		}
+	}
+	return replay, sub.events, unsubscribe
+}
+
+// Subscribers returns the number of connected subscribers.
+// @returns the number of subscribers.
+func (broadcaster *Broadcaster) Subscribers() int {
+	broadcaster.mu.Lock()
+	defer broadcaster.mu.Unlock()
+	return len(broadcaster.subscribers)
+}
@@ -0,0 +1,194 @@
+package live
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+
+	"github.com/feimaomiao/stalka/pandatypes"
+)
+
+var pollTime = time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+
+// running is a best of three between teams 1 and 2 of game 1 with the given
+// score, where finishedGames games are over.
+func running(matchID int32, score1 int32, score2 int32, finishedGames int) Snapshot {
+	snapshot := Snapshot{
+		MatchID:      matchID,
+		GameID:       1,
+		OpponentType: pandatypes.OpponentTypeTeam,
+		OpponentIDs:  []int32{1, 2},
+		Scores:       []Score{{OpponentID: 1, Score: score1}, {OpponentID: 2, Score: score2}},
+		Games:        nil,
+	}
+	for position := range 3 {
+		snapshot.Games = append(snapshot.Games, GameState{
+			ID:       matchID*10 + int32(position), //nolint:gosec // small test values
+			Position: int32(position + 1),          //nolint:gosec // small test values
+			Finished: position < finishedGames,
+			WinnerID: 1,
+		})
+	}
+	return snapshot
+}
+
+func eventTypes(events []Event) []string {
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestObserve(t *testing.T) {
+	broadcaster := NewBroadcaster(pollTime)
+
+	t.Run("The first poll is the baseline", func(t *testing.T) {
+		events := broadcaster.Observe([]Snapshot{running(1, 0, 0, 0)}, nil, pollTime)
+		st.Expect(t, len(events), 0)
+	})
+
+	t.Run("Nothing changed", func(t *testing.T) {
+		events := broadcaster.Observe([]Snapshot{running(1, 0, 0, 0)}, nil, pollTime)
+		st.Expect(t, len(events), 0)
+	})
+
+	t.Run("Starts, finished games and scores", func(t *testing.T) {
+		events := broadcaster.Observe([]Snapshot{running(1, 1, 0, 1), running(2, 0, 0, 0)}, nil, pollTime)
+		st.Expect(t, eventTypes(events), []string{GameFinished, ScoreChanged, MatchStarted})
+		st.Expect(t, events[0].GamePosition, int32(1))
+		st.Expect(t, events[0].WinnerID, int32(1))
+		st.Expect(t, events[1].Scores[0], Score{OpponentID: 1, Score: 1})
+		st.Expect(t, events[1].ID, events[0].ID+1)
+		st.Expect(t, events[2].MatchID, int32(2))
+	})
+
+	t.Run("Matches leaving the running list ended", func(t *testing.T) {
+		events := broadcaster.Observe(nil, nil, pollTime)
+		st.Expect(t, eventTypes(events), []string{MatchEnded, MatchEnded})
+		st.Expect(t, events[0].MatchID, int32(1))
+		st.Expect(t, events[0].Scores[0].Score, int32(1))
+		st.Expect(t, events[1].MatchID, int32(2))
+	})
+
+	t.Run("Unreadable matches keep their state", func(t *testing.T) {
+		broadcaster.Observe([]Snapshot{running(4, 0, 0, 0)}, nil, pollTime)
+		events := broadcaster.Observe(nil, []int32{4}, pollTime)
+		st.Expect(t, len(events), 0)
+		events = broadcaster.Observe([]Snapshot{running(4, 1, 0, 1)}, nil, pollTime)
+		st.Expect(t, eventTypes(events), []string{GameFinished, ScoreChanged})
+		events = broadcaster.Observe(nil, nil, pollTime)
+		st.Expect(t, eventTypes(events), []string{MatchEnded})
+	})
+
+	t.Run("Unreadable matches that were not running do not start", func(t *testing.T) {
+		events := broadcaster.Observe(nil, []int32{5}, pollTime)
+		st.Expect(t, len(events), 0)
+	})
+
+	t.Run("IDs continue after the start time", func(t *testing.T) {
+		restarted := NewBroadcaster(pollTime.Add(time.Second))
+		restarted.Observe(nil, nil, pollTime)
+		events := restarted.Observe([]Snapshot{running(3, 0, 0, 0)}, nil, pollTime)
+		st.Assert(t, len(events), 1)
+		st.Expect(t, events[0].ID > uint64(pollTime.UnixMicro())+10, true)
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	broadcaster := NewBroadcaster(pollTime)
+	broadcaster.Observe(nil, nil, pollTime)
+
+	t.Run("Filters select games or opponents", func(t *testing.T) {
+		_, all, unsubscribeAll := broadcaster.Subscribe(Filter{GameIDs: nil, TeamIDs: nil}, 0)
+		defer unsubscribeAll()
+		_, team, unsubscribeTeam := broadcaster.Subscribe(Filter{GameIDs: nil, TeamIDs: []int32{2}}, 0)
+		defer unsubscribeTeam()
+		_, other, unsubscribeOther := broadcaster.Subscribe(Filter{GameIDs: []int32{3}, TeamIDs: []int32{7}}, 0)
+		defer unsubscribeOther()
+
+		broadcaster.Observe([]Snapshot{running(1, 0, 0, 0)}, nil, pollTime)
+		st.Expect(t, (<-all).Type, MatchStarted)
+		st.Expect(t, (<-team).Type, MatchStarted)
+		st.Expect(t, len(other), 0)
+	})
+
+	t.Run("Resuming replays the missed events", func(t *testing.T) {
+		events := broadcaster.Observe([]Snapshot{running(1, 1, 0, 1)}, nil, pollTime)
+		st.Assert(t, len(events), 2)
+
+		replay, _, unsubscribe := broadcaster.Subscribe(Filter{GameIDs: []int32{1}, TeamIDs: nil}, events[0].ID)
+		defer unsubscribe()
+		st.Assert(t, len(replay), 1)
+		st.Expect(t, replay[0].ID, events[1].ID)
+	})
+
+	t.Run("Subscribers falling behind are dropped", func(t *testing.T) {
+		_, slow, unsubscribe := broadcaster.Subscribe(Filter{GameIDs: nil, TeamIDs: nil}, 0)
+		defer unsubscribe()
+		for score := range int32(subscriberBuffer + 1) {
+			broadcaster.Observe([]Snapshot{running(1, score+2, 0, 1)}, nil, pollTime)
+		}
+		received := 0
+		for range slow {
+			received++
+		}
+		st.Expect(t, received, subscriberBuffer)
+	})
+}
+
+func TestFilterMatches(t *testing.T) {
+	teams := Event{
+		ID: 1, Type: MatchStarted, MatchID: 1, GameID: 1, At: pollTime,
+		OpponentType: pandatypes.OpponentTypeTeam, OpponentIDs: []int32{1, 2},
+		Scores: nil, GamePosition: 0, WinnerID: 0,
+	}
+	// player 2 of a free for all shares its ID with team 2
+	players := teams
+	players.MatchID = 2
+	players.OpponentType = pandatypes.OpponentTypePlayer
+	players.OpponentIDs = []int32{2, 3}
+
+	for name, test := range map[string]struct {
+		filter Filter
+		event  Event
+		want   bool
+	}{
+		"Empty filter selects everything":   {Filter{GameIDs: nil, TeamIDs: nil}, players, true},
+		"Listed team opponent":              {Filter{GameIDs: nil, TeamIDs: []int32{2}}, teams, true},
+		"Unlisted team opponents":           {Filter{GameIDs: nil, TeamIDs: []int32{7}}, teams, false},
+		"Player with the ID of a team":      {Filter{GameIDs: nil, TeamIDs: []int32{2}}, players, false},
+		"Player match of a listed game":     {Filter{GameIDs: []int32{1}, TeamIDs: []int32{2}}, players, true},
+		"Team match of another listed game": {Filter{GameIDs: []int32{3}, TeamIDs: nil}, teams, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			st.Expect(t, test.filter.Matches(test.event), test.want)
+		})
+	}
+}
+
+func TestSnapshotOf(t *testing.T) {
+	var match pandatypes.MatchLike
+	err := json.Unmarshal([]byte(`{
+		"id": 7,
+		"videogame": {"id": 1},
+		"opponents": [{"type": "Team", "opponent": {"id": 101}}, {"type": "Team", "opponent": {"id": 102}}],
+		"results": [{"team_id": 101, "score": 1}, {"team_id": 102, "score": 0}],
+		"games": [
+			{"id": 70, "position": 1, "finished": true, "winner": {"id": 101, "type": "Team"}},
+			{"id": 71, "position": 2, "finished": false, "winner": {"id": null, "type": "Team"}}
+		]
+	}`), &match)
+	st.Assert(t, err, nil)
+
+	snapshot, err := SnapshotOf(match)
+	st.Expect(t, err, nil)
+	st.Expect(t, snapshot.MatchID, int32(7))
+	st.Expect(t, snapshot.OpponentType, pandatypes.OpponentTypeTeam)
+	st.Expect(t, snapshot.OpponentIDs, []int32{101, 102})
+	st.Expect(t, snapshot.Scores, []Score{{OpponentID: 101, Score: 1}, {OpponentID: 102, Score: 0}})
+	st.Expect(t, snapshot.Games[0], GameState{ID: 70, Position: 1, Finished: true, WinnerID: 101})
+	st.Expect(t, snapshot.Games[1].Finished, false)
+}
@@ -0,0 +1,83 @@
+package live
+
+import (
+	"github.com/feimaomiao/stalka/pandatypes"
+)
+
+// Snapshot is the state of a running match as seen by one live poll.
+type Snapshot struct {
+	MatchID int32
+	GameID  int32
+	// OpponentType is pandatypes.OpponentTypeTeam or OpponentTypePlayer, the
+	// type of every opponent of the match, and empty without opponents.
+	OpponentType string
+	// OpponentIDs are the team or player IDs of the match in PandaScore order.
+	OpponentIDs []int32
+	Scores      []Score
+	Games       []GameState
+}
+
+// Score is the score of one opponent.
+type Score struct {
+	OpponentID int32 `json:"opponent_id"`
+	Score      int32 `json:"score"`
+}
+
+// GameState is one game of a running match.
+type GameState struct {
+	ID       int32
+	Position int32
+	Finished bool
+	WinnerID int32
+}
+
+// SnapshotOf reads the live state of a match of /matches/running.
+// @param match - the running match.
+// @returns the snapshot and an error if an ID does not fit into an int32.
+func SnapshotOf(match pandatypes.MatchLike) (Snapshot, error) {
+	var snapshot Snapshot
+	var err error
+	if snapshot.MatchID, err = pandatypes.SafeIntToInt32(match.ID); err != nil {
+		return snapshot, err
+	}
+	if snapshot.GameID, err = pandatypes.SafeIntToInt32(match.Videogame.ID); err != nil {
+		return snapshot, err
+	}
+	for _, opponent := range match.Opponents {
+		id, convErr := pandatypes.SafeIntToInt32(opponent.Opponent.ID)
+		if convErr != nil {
+			return snapshot, convErr
+		}
+		snapshot.OpponentType = opponent.Type
+		snapshot.OpponentIDs = append(snapshot.OpponentIDs, id)
+	}
+	for _, result := range match.Results {
+		opponentID := result.TeamID
+		if opponentID == 0 {
+			opponentID = result.PlayerID
+		}
+		id, convErr := pandatypes.SafeIntToInt32(opponentID)
+		if convErr != nil {
+			return snapshot, convErr
+		}
+		score, convErr := pandatypes.SafeIntToInt32(result.Score)
+		if convErr != nil {
+			return snapshot, convErr
+		}
+		snapshot.Scores = append(snapshot.Scores, Score{OpponentID: id, Score: score})
+	}
+	for _, game := range match.Games {
+		state := GameState{ID: 0, Position: 0, Finished: game.Finished, WinnerID: 0}
+		if state.ID, err = pandatypes.SafeIntToInt32(game.ID); err != nil {
+			return snapshot, err
+		}
+		if state.Position, err = pandatypes.SafeIntToInt32(game.Position); err != nil {
+			return snapshot, err
+		}
+		if state.WinnerID, err = pandatypes.SafeIntToInt32(game.Winner.ID); err != nil {
+			return snapshot, err
+		}
+		snapshot.Games = append(snapshot.Games, state)
+	}
+	return snapshot, nil
+}
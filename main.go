@@ -2,15 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"time"
 
+	"github.com/feimaomiao/stalka/admin"
+	"github.com/feimaomiao/stalka/api"
 	"github.com/feimaomiao/stalka/client"
 	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/jobs"
+	"github.com/feimaomiao/stalka/live"
+	"github.com/feimaomiao/stalka/pandatypes"
+	"github.com/feimaomiao/stalka/rpc"
+	"github.com/feimaomiao/stalka/stalkapb"
+	"github.com/feimaomiao/stalka/webhook"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
 
 	_ "embed"
 )
@@ -18,15 +29,56 @@ import (
 //go:embed static/schema.sql
 var schema string
 
+//go:embed static/sqlite_schema.sql
+var sqliteSchema string
+
+// Storage backends selected by the storage_backend environment variable.
+const (
+	BackendPostgres = "postgres"
+	BackendSQLite   = "sqlite"
+)
+
+// DefaultSQLitePath is the database file of the SQLite backend when sqlite_path is unset.
+const DefaultSQLitePath = "stalka.db"
+
 const LivesPollInterval = 5 * time.Minute
 
+// MatchInterval is how often the matches are updated.
+const MatchInterval = time.Hour
+
+// SetupInterval is how often games, leagues, series, teams and tournaments are refreshed.
+const SetupInterval = 24 * time.Hour
+
+// ReconcileInterval is how often stale entities are re-checked against PandaScore.
+const ReconcileInterval = 6 * time.Hour
+
+// RetentionInterval is how often the retention policies are applied.
+const RetentionInterval = 24 * time.Hour
+
+// DefaultServeAddr is the listen address of serve mode when serve_addr is unset.
+const DefaultServeAddr = ":8080"
+
+// Names of the sync jobs, as used by the admin API.
+const (
+	JobLives     = "lives"
+	JobMatches   = "matches"
+	JobSetup     = "setup"
+	JobReconcile = "reconcile"
+	JobRetention = "retention"
+	JobWebhooks  = "webhooks"
+)
+
 // DatabaseConnector is a struct that holds the database connection and the dbtypes.Queries object.
 // It is used to interact with the database.
-// @param Db - the database connection.
-// @param DbConn - the queries object interacting with the database (sqlc).
+// @param Db - the database connection, nil for the SQLite backend.
+// @param DbConn - the queries object interacting with the database (sqlc), nil for the SQLite backend.
+// @param Sink - the sink the client writes through.
+// @param Close - closes the database connection.
 type DatabaseConnector struct {
 	DB     *pgxpool.Pool
 	DBConn *dbtypes.Queries
+	Sink   pandatypes.Sink
+	Close  func()
 }
 
 // Init initializes the database connection and returns a DatabaseConnector.
@@ -62,9 +114,217 @@ func Init(ctx context.Context, log *zap.SugaredLogger) (DatabaseConnector, error
 	return DatabaseConnector{
 		DB:     db,
 		DBConn: dbConn,
+		Sink:   pandatypes.NewPostgresSink(dbConn, db),
+		Close:  db.Close,
+	}, nil
+}
+
+// InitSQLite opens the embedded SQLite database used for local development and
+// CI, creating it with static/sqlite_schema.sql when missing.
+// @param ctx - the context to use for the database connection.
+// @param log - the logger to use for logging.
+// @param path - the database file.
+// @returns a DatabaseConnector and an error if one occurred.
+func InitSQLite(ctx context.Context, log *zap.SugaredLogger, path string) (DatabaseConnector, error) {
+	log.Info("Opening SQLite database at ", path)
+	db, err := pandatypes.OpenSQLite(ctx, path, sqliteSchema)
+	if err != nil {
+		log.Error(err, "Failed to open SQLite database")
+		return DatabaseConnector{}, err
+	}
+	return DatabaseConnector{
+		DB:     nil,
+		DBConn: nil,
+		Sink:   pandatypes.NewSQLiteSink(db),
+		Close:  func() { _ = db.Close() },
+	}, nil
+}
+
+// initStorage connects to the backend selected by storage_backend, Postgres
+// when unset.
+// @returns a DatabaseConnector and an error if one occurred.
+func initStorage(ctx context.Context, log *zap.SugaredLogger) (DatabaseConnector, error) {
+	switch backend := os.Getenv("storage_backend"); backend {
+	case "", BackendPostgres:
+		return Init(ctx, log)
+	case BackendSQLite:
+		path := os.Getenv("sqlite_path")
+		if path == "" {
+			path = DefaultSQLitePath
+		}
+		return InitSQLite(ctx, log, path)
+	default:
+		return DatabaseConnector{}, fmt.Errorf("unknown storage backend: %s", backend)
+	}
+}
+
+// serve runs the read-only HTTP API of serve mode instead of syncing, and the
+// gRPC API without WatchMatches when grpc_addr is set.
+// @param log - the logger to use for logging.
+// @param database - the database to read from.
+// @returns the error that stopped the server.
+func serve(log *zap.SugaredLogger, database DatabaseConnector) error {
+	if database.DBConn == nil {
+		return errors.New("serve mode requires the postgres storage backend")
+	}
+	if grpcAddr := os.Getenv("grpc_addr"); grpcAddr != "" {
+		go func() {
+			log.Error(listenGRPC(log, grpcAddr, newRPCServer(log, database, nil)))
+		}()
+	}
+	addr := os.Getenv("serve_addr")
+	if addr == "" {
+		addr = DefaultServeAddr
+	}
+	selectionToken, err := api.LoadSelectionToken(os.Getenv)
+	if err != nil {
+		return err
+	}
+	return listen(log, addr, (&api.Server{
+		Queries: database.DBConn, Live: nil, SelectionToken: selectionToken, Logger: log,
+	}).Handler())
+}
+
+// webhooks runs the webhooks command: without arguments it prints the delivery
+// status of every subscriber, "requeue <name>" gives the dead lettered events
+// of a subscriber a new set of attempts.
+// @param ctx - the context of the command.
+// @param database - the database holding the outbox.
+// @param args - the arguments after "webhooks".
+// @returns an error if the arguments are invalid or a query fails.
+func webhooks(ctx context.Context, database DatabaseConnector, args []string) error {
+	if database.DBConn == nil {
+		return errors.New("webhooks require the postgres storage backend")
+	}
+	switch {
+	case len(args) == 0:
+		rows, err := database.DBConn.GetWebhookDeliveryStatus(ctx)
+		if err != nil {
+			return err
+		}
+		return webhook.WriteStatus(os.Stdout, rows)
+	case len(args) == 2 && args[0] == "requeue":
+		requeued, err := database.DBConn.RequeueDeadWebhookDeliveries(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Requeued %d dead lettered events of webhook %s\n", requeued, args[1])
+		return nil
+	default:
+		return errors.New("usage: stalka webhooks [requeue <name>]")
+	}
+}
+
+// newDispatcher creates the webhook dispatcher from the environment.
+// @param log - the logger to use for logging.
+// @param database - the database holding the outbox.
+// @returns the dispatcher, nil when no webhooks are configured, and an error
+// if the configuration is invalid.
+func newDispatcher(log *zap.SugaredLogger, database DatabaseConnector) (*webhook.Dispatcher, error) {
+	subscribers, err := webhook.LoadSubscribers(os.Getenv)
+	if err != nil || len(subscribers) == 0 {
+		return nil, err
+	}
+	if database.DBConn == nil {
+		return nil, errors.New("webhooks require the postgres storage backend")
+	}
+	maxAttempts, err := webhook.LoadMaxAttempts(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook.Dispatcher{
+		Queries:     database.DBConn,
+		HTTPClient:  &http.Client{Timeout: 10 * time.Second},
+		Subscribers: subscribers,
+		MaxAttempts: maxAttempts,
+		Logger:      log,
+	}, nil
+}
+
+// newAdminServer creates the admin API from the environment.
+// @param log - the logger to use for logging.
+// @param database - the database holding the retry queue and the audit log.
+// @param pandaClient - the client entities are fetched with.
+// @returns the admin API without its jobs, nil when admin_addr is unset, and
+// an error if admin_token is invalid.
+func newAdminServer(
+	log *zap.SugaredLogger,
+	database DatabaseConnector,
+	pandaClient *client.PandaClient,
+) (*admin.Server, error) {
+	if os.Getenv("admin_addr") == "" {
+		return nil, nil
+	}
+	if database.DBConn == nil {
+		return nil, errors.New("the admin API requires the postgres storage backend")
+	}
+	token, err := admin.LoadToken(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	return &admin.Server{
+		Queries: database.DBConn,
+		Token:   token,
+		Jobs:    nil,
+		// The fetch counts into its own tally, the jobs count into the shared
+		// one. The views pick the entity up with the next job run.
+		Fetch: func(id int, flag client.GetChoice) error {
+			fetch := pandaClient.Scoped()
+			if err := fetch.GetOne(id, flag); err != nil {
+				return err
+			}
+			fetch.LogWrites("admin fetch")
+			return nil
+		},
+		Budget: pandaClient.Budget,
+		Logger: log,
 	}, nil
 }
 
+// listen serves a handler on addr.
+// @param log - the logger to use for logging.
+// @param addr - the listen address.
+// @param handler - the API to serve.
+// @returns the error that stopped the server.
+func listen(log *zap.SugaredLogger, addr string, handler http.Handler) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Info("Serving the API on ", addr)
+	return httpServer.ListenAndServe()
+}
+
+// newRPCServer creates the gRPC API.
+// @param log - the logger to use for logging.
+// @param database - the database to read from.
+// @param broadcaster - the live events of WatchMatches, nil when this process
+// does not poll the lives.
+// @returns the gRPC API.
+func newRPCServer(log *zap.SugaredLogger, database DatabaseConnector, broadcaster *live.Broadcaster) *rpc.Server {
+	return &rpc.Server{
+		UnimplementedStalkaServer: stalkapb.UnimplementedStalkaServer{},
+		Queries:                   database.DBConn,
+		Live:                      broadcaster,
+		Logger:                    log,
+	}
+}
+
+// listenGRPC serves the gRPC API on addr.
+// @param log - the logger to use for logging.
+// @param addr - the listen address.
+// @param server - the gRPC API to serve.
+// @returns the error that stopped the server.
+func listenGRPC(log *zap.SugaredLogger, addr string, server *rpc.Server) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Info("Serving the gRPC API on ", addr)
+	return server.Register().Serve(listener)
+}
+
 func main() { //nolint:gocognit,funlen
 	ctx := context.Background()
 	config := zap.NewProductionConfig()
@@ -73,11 +333,29 @@ func main() { //nolint:gocognit,funlen
 	config.ErrorOutputPaths = []string{"stdout"}
 	logger, _ := config.Build()
 	sugar := logger.Sugar()
-	database, err := Init(ctx, sugar)
+	database, err := initStorage(ctx, sugar)
 	if err != nil {
 		sugar.Fatal(err)
 	}
-	defer database.DB.Close()
+	defer database.Close()
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		sugar.Fatal(serve(sugar, database))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "webhooks" {
+		if err = webhooks(ctx, database, os.Args[2:]); err != nil {
+			sugar.Fatal(err)
+		}
+		return
+	}
+	dispatcher, err := newDispatcher(sugar, database)
+	if err != nil {
+		sugar.Fatal(err)
+	}
+	retentionPolicies, err := client.LoadRetentionPolicies(os.Getenv)
+	if err != nil {
+		sugar.Fatal(err)
+	}
+	retentionDryRun := os.Getenv("retention_dry_run") == "true"
 
 	// Initialize the PandaClient with the database connector and logger.
 	// The PandaClient will be used to make requests to the Pandascore API.
@@ -86,10 +364,41 @@ func main() { //nolint:gocognit,funlen
 		Pandasecret: os.Getenv("pandascore_secret"),
 		Logger:      sugar,
 		HTTPClient:  &http.Client{},
-		DBConnector: database.DBConn,
+		Store:       database.Sink,
+		LiveFlags:   database.Sink,
+		Retries:     database.Sink,
+		Retention:   database.Sink,
+		Publisher:   database.Sink,
+		IDs:         client.NewIDCache(client.IDCacheSize),
 		Run:         0,
+		Budget:      client.NewBudget(),
+		Live:        nil,
+		Writes:      client.NewWriteTally(),
 		Ctx:         ctx,
 	}
+	adminServer, err := newAdminServer(sugar, database, &client)
+	if err != nil {
+		sugar.Fatal(err)
+	}
+	// The live event streams are pushed by the process that polls the lives.
+	grpcAddr := os.Getenv("grpc_addr")
+	if grpcAddr != "" && database.DBConn == nil {
+		sugar.Fatal("the gRPC API requires the postgres storage backend")
+	}
+	if os.Getenv("live_addr") != "" || grpcAddr != "" {
+		client.Live = live.NewBroadcaster(time.Now())
+	}
+	if addr := os.Getenv("live_addr"); addr != "" {
+		go func() {
+			liveServer := &api.Server{Queries: nil, Live: client.Live, SelectionToken: "", Logger: sugar}
+			sugar.Error(listen(sugar, addr, liveServer.Handler()))
+		}()
+	}
+	if grpcAddr != "" {
+		go func() {
+			sugar.Error(listenGRPC(sugar, grpcAddr, newRPCServer(sugar, database, client.Live)))
+		}()
+	}
 	if err != nil {
 		sugar.Fatal(err)
 	}
@@ -97,59 +406,88 @@ func main() { //nolint:gocognit,funlen
 	if err != nil {
 		sugar.Fatal(err)
 	}
-	day := 24
-	matchTicker := time.NewTicker(time.Hour)
-	setupTicker := time.NewTicker(time.Duration(day) * time.Hour)
-	defer matchTicker.Stop()
-	defer setupTicker.Stop()
-	livesTicker := time.NewTicker(LivesPollInterval)
-	defer livesTicker.Stop()
-	go func() {
-		for range livesTicker.C {
-			sugar.Info("Lives ticker fired")
-			err = client.GetLives()
-			if err != nil {
-				sugar.Error(err) // log but don't fatal
-			}
-			sugar.Infof("Done with lives update, made %d requests so far", client.Run)
+	client.RefreshViews("startup")
+	// Subscribers are registered after the startup sync, so they are not sent
+	// an event for every match of the initial backfill.
+	if dispatcher != nil {
+		if err = dispatcher.Register(ctx); err != nil {
+			sugar.Fatal(err)
 		}
-	}()
-	go func() {
-		for range matchTicker.C {
-			sugar.Info("Matchticker fired")
-			err = client.GetMatches(false)
-			if err != nil {
-				sugar.Fatal(err)
-			}
-			sugar.Infof("Done with run, made %d requests so far", client.Run)
+	}
+	scheduler := jobs.NewScheduler(sugar)
+	scheduler.Add(jobs.Job{Name: JobLives, Interval: LivesPollInterval, Run: func() error {
+		err := client.GetLives()
+		sugar.Infof("Done with lives update, made %d requests so far", client.Run)
+		client.RefreshViews("lives update")
+		return err
+	}})
+	scheduler.Add(jobs.Job{Name: JobMatches, Interval: MatchInterval, Run: func() error {
+		if retryErr := client.RetryUnresolved(); retryErr != nil {
+			sugar.Error(retryErr) // log but don't fatal
 		}
-	}()
-	go func() {
-		for range setupTicker.C {
-			sugar.Info("Setupticker fired")
-			err = client.UpdateGames()
-			if err != nil {
-				sugar.Fatal(err)
-			}
-			err = client.GetLeagues(false)
-			if err != nil {
-				sugar.Fatal(err)
-			}
-			err = client.GetSeries(false)
-			if err != nil {
-				sugar.Fatal(err)
-			}
-			err = client.GetTeams(false)
-			if err != nil {
-				sugar.Fatal(err)
+		// the scheduler logs and records the error, and the next run retries
+		if err := client.GetMatches(false); err != nil {
+			return err
+		}
+		sugar.Infof("Done with run, made %d requests so far", client.Run)
+		client.LogWrites("match update")
+		client.RefreshViews("match update")
+		return nil
+	}})
+	scheduler.Add(jobs.Job{Name: JobSetup, Interval: SetupInterval, Run: func() error {
+		for _, step := range []func() error{
+			client.UpdateGames,
+			func() error { return client.GetLeagues(false) },
+			func() error { return client.GetSeries(false) },
+			func() error { return client.GetTeams(false) },
+			func() error { return client.GetTournaments(false) },
+		} {
+			if err := step(); err != nil {
+				return err
 			}
-			err = client.GetTournaments(false)
-			if err != nil {
-				sugar.Fatal(err)
+		}
+		sugar.Infof("Done with setup, made %d requests so far", client.Run)
+		client.LogWrites("setup")
+		client.RefreshViews("setup")
+		return nil
+	}})
+	scheduler.Add(jobs.Job{Name: JobReconcile, Interval: ReconcileInterval, Run: func() error {
+		err := client.Reconcile()
+		sugar.Infof("Done with reconciliation, made %d requests so far", client.Run)
+		client.LogWrites("reconciliation")
+		client.RefreshViews("reconciliation")
+		return err
+	}})
+	scheduler.Add(jobs.Job{Name: JobRetention, Interval: RetentionInterval, Run: func() error {
+		_, err := client.ApplyRetention(retentionPolicies, retentionDryRun)
+		if !retentionDryRun {
+			client.RefreshViews("retention")
+		}
+		if dispatcher != nil && !retentionDryRun {
+			pruned, pruneErr := dispatcher.Prune(ctx, time.Now())
+			if pruneErr != nil {
+				sugar.Error(pruneErr) // log but don't fatal
 			}
-			sugar.Infof("Done with setup, made %d requests so far", client.Run)
+			sugar.Infof("Pruned %d old webhook events", pruned)
 		}
-	}()
+		return err
+	}})
+	if dispatcher != nil {
+		scheduler.Add(jobs.Job{Name: JobWebhooks, Interval: webhook.DispatchInterval, Run: func() error {
+			delivered, err := dispatcher.Dispatch(ctx, time.Now())
+			if delivered > 0 {
+				sugar.Infof("Delivered %d webhook events", delivered)
+			}
+			return err
+		}})
+	}
+	scheduler.Start(ctx)
+	if adminServer != nil {
+		adminServer.Jobs = scheduler
+		go func() {
+			sugar.Error(listen(sugar, os.Getenv("admin_addr"), adminServer.Handler()))
+		}()
+	}
 	for {
 		time.Sleep(time.Hour)
 	}
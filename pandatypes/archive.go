@@ -0,0 +1,68 @@
+package pandatypes
+
+import (
+	"context"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+)
+
+// keepArchived reports whether a match missing from MATCHES but found in the
+// archive stays there. Retention only archives finished matches, so one that is
+// still finished is left alone instead of being created again. One PandaScore
+// reopened goes back to MATCHES, and is archived again once it is over.
+// @param incoming - the parameters about to be upserted.
+// @returns whether the incoming match is skipped.
+func keepArchived(incoming dbtypes.InsertToMatchesParams) bool {
+	return incoming.Finished
+}
+
+// archivedMatch returns the match columns of an archived copy, so a match that
+// returns from the archive is diffed against it rather than created anew.
+func archivedMatch(archived dbtypes.MatchesArchive) dbtypes.Match {
+	return dbtypes.Match{
+		ID:                  archived.ID,
+		Name:                archived.Name,
+		Slug:                archived.Slug,
+		Finished:            archived.Finished,
+		ExpectedStartTime:   archived.ExpectedStartTime,
+		ActualGameTime:      archived.ActualGameTime,
+		Team1ID:             archived.Team1ID,
+		Team1Score:          archived.Team1Score,
+		Team2ID:             archived.Team2ID,
+		Team2Score:          archived.Team2Score,
+		AmountOfGames:       archived.AmountOfGames,
+		IsLive:              archived.IsLive,
+		StreamURL:           archived.StreamURL,
+		Status:              archived.Status,
+		Forfeit:             archived.Forfeit,
+		Draw:                archived.Draw,
+		Rescheduled:         archived.Rescheduled,
+		OriginalScheduledAt: archived.OriginalScheduledAt,
+		BeginAt:             archived.BeginAt,
+		EndAt:               archived.EndAt,
+		WinnerID:            archived.WinnerID,
+		WinnerType:          archived.WinnerType,
+		GameID:              archived.GameID,
+		LeagueID:            archived.LeagueID,
+		SeriesID:            archived.SeriesID,
+		TournamentID:        archived.TournamentID,
+		LastSeenAt:          archived.LastSeenAt,
+		DeletedAt:           archived.DeletedAt,
+		ModifiedAt:          archived.ModifiedAt,
+	}
+}
+
+// archivedMatches looks up the latest archived copy of each of the given matches.
+// @param ids - the IDs of matches missing from MATCHES.
+// @returns the archived matches by ID.
+func archivedMatches(ctx context.Context, db *dbtypes.Queries, ids []int32) (map[int32]dbtypes.Match, error) {
+	archived, err := db.GetArchivedMatchesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int32]dbtypes.Match, len(archived))
+	for _, match := range archived {
+		byID[match.ID] = archivedMatch(match)
+	}
+	return byID, nil
+}
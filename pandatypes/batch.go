@@ -0,0 +1,434 @@
+package pandatypes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RowError reports a single row of a page that could not be written.
+type RowError struct {
+	ID  int
+	Err error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.ID, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// batchResults is implemented by the results of every sqlc :batchexec query.
+type batchResults interface {
+	Exec(f func(int, error))
+}
+
+// execBatch drains a batch and returns the first error. A batch runs in one
+// implicit transaction, so after a failure none of its statements are applied.
+func execBatch(results batchResults) error {
+	var first error
+	results.Exec(func(_ int, err error) {
+		if err != nil && first == nil {
+			first = err
+		}
+	})
+	return first
+}
+
+// pendingMatch is a converted match row waiting for the batched write.
+type pendingMatch struct {
+	row       MatchRow
+	params    dbtypes.InsertToMatchesParams
+	opponents []dbtypes.InsertMatchOpponentParams
+	changes   []MatchChange
+	events    []OutboxEvent
+	// refreshed is set when the stored match is unchanged and only rewritten
+	// to refresh its last_seen_at.
+	refreshed bool
+}
+
+// WriteMatchPage writes a page of matches in a few round trips instead of a
+// handful per match: one lookup of the stored matches, then one batch each for
+// the matches, their changes, their outbox events, their opponents and the
+// opponent trims. The written matches are then notified on
+// ChannelMatchesChanged, which Postgres delivers once the page commits.
+// When a batch is rejected, its rows are retried one by one so that every
+// failing row is reported on its own while the others are still written.
+// Inside a transaction every batch and every retried row runs under its own
+// savepoint, so a rejected statement does not abort the rest of the page, and
+// a match whose changes, events or opponents fail is rolled back together with
+// its row. Outside of a transaction such a match stays stored without them.
+// Matches whose stored row is at least as new are skipped before any write,
+// and so are those the upsert guard turns away because a newer version was
+// stored since the lookup.
+// @param rows - the matches of one page.
+// @returns the updated, refreshed and skipped counts, and one RowError per match that could not be written.
+func (sink *PostgresSink) WriteMatchPage(ctx context.Context, rows []MatchRow) (WriteCounts, []RowError) {
+	db := sink.queries
+	var rowErrs []RowError
+	pending := make([]pendingMatch, 0, len(rows))
+	ids := make([]int32, 0, len(rows))
+	for _, row := range rows {
+		match, err := row.toPending()
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{ID: row.ID, Err: err})
+			continue
+		}
+		pending = append(pending, match)
+		ids = append(ids, match.params.ID)
+	}
+	if len(pending) == 0 {
+		return WriteCounts{}, rowErrs
+	}
+
+	var stored []dbtypes.Match
+	err := sink.Savepoint(ctx, func() error {
+		var lookupErr error
+		stored, lookupErr = db.GetMatchesByIDs(ctx, ids)
+		return lookupErr
+	})
+	if err != nil {
+		counts, oneByOneErrs := sink.writeMatchesOneByOne(ctx, pending)
+		return counts, append(rowErrs, oneByOneErrs...)
+	}
+	storedByID := make(map[int32]dbtypes.Match, len(stored))
+	for _, match := range stored {
+		storedByID[match.ID] = match
+	}
+	// matches missing from MATCHES may have been archived by retention
+	var missing []int32
+	for _, id := range ids {
+		if _, ok := storedByID[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	var archivedByID map[int32]dbtypes.Match
+	if len(missing) > 0 {
+		err = sink.Savepoint(ctx, func() error {
+			var lookupErr error
+			archivedByID, lookupErr = archivedMatches(ctx, db, missing)
+			return lookupErr
+		})
+		if err != nil {
+			counts, oneByOneErrs := sink.writeMatchesOneByOne(ctx, pending)
+			return counts, append(rowErrs, oneByOneErrs...)
+		}
+	}
+	var counts WriteCounts
+	now := time.Now()
+	changed := pending[:0]
+	for _, match := range pending {
+		if storedMatch, ok := storedByID[match.params.ID]; ok {
+			if matchUpToDate(storedMatch, match.params.ModifiedAt, now) {
+				counts.Skipped++
+				continue
+			}
+			match.refreshed = matchUnchanged(storedMatch, match.params.ModifiedAt)
+			match.changes = DiffMatch(storedMatch, match.params)
+			match.events = MatchEvents(&storedMatch, match.params, match.changes)
+		} else if archived, ok := archivedByID[match.params.ID]; ok {
+			if keepArchived(match.params) {
+				counts.Skipped++
+				continue
+			}
+			match.changes = DiffMatch(archived, match.params)
+			match.events = MatchEvents(&archived, match.params, match.changes)
+		} else {
+			match.events = MatchEvents(nil, match.params, nil)
+		}
+		changed = append(changed, match)
+	}
+	pending = changed
+	if len(pending) == 0 {
+		return counts, rowErrs
+	}
+
+	// The batched upsert and the writes that follow it share a savepoint. Inside
+	// a transaction, a match failing after its row was stored rolls the savepoint
+	// back, and the page is written again one match at a time, each match
+	// together with its changes, outbox events and opponents.
+	var upserted []pendingMatch
+	var upsertSkipped int
+	var followUpErrs []RowError
+	err = sink.Savepoint(ctx, func() error {
+		var upsertErr error
+		upserted, upsertSkipped, upsertErr = sink.upsertMatchesBatch(ctx, pending)
+		if upsertErr != nil {
+			return upsertErr
+		}
+		followUpErrs = sink.writeFollowUps(ctx, upserted)
+		if len(followUpErrs) > 0 && sink.tx != nil {
+			return followUpErrs[0]
+		}
+		return nil
+	})
+	if err != nil {
+		oneByOne, oneByOneErrs := sink.writeMatchesOneByOne(ctx, pending)
+		counts.Add(oneByOne)
+		return counts, append(rowErrs, oneByOneErrs...)
+	}
+	counts.Skipped += upsertSkipped
+	pending = upserted
+	if len(pending) == 0 {
+		return counts, rowErrs
+	}
+	rowErrs = append(rowErrs, followUpErrs...)
+	failed := make(map[int]bool, len(rowErrs))
+	for _, rowErr := range rowErrs {
+		failed[rowErr.ID] = true
+	}
+	var written []pendingMatch
+	var set matchChangeSet
+	for _, match := range pending {
+		if !failed[match.row.ID] {
+			written = append(written, match)
+			set.add(match.params.ID, match.changes, match.events)
+		}
+	}
+	if err = sink.Savepoint(ctx, func() error { return notifyMatches(ctx, db, set) }); err != nil {
+		for _, match := range written {
+			rowErrs = append(rowErrs, RowError{ID: match.row.ID, Err: err})
+		}
+		return counts, rowErrs
+	}
+	for _, match := range written {
+		counts.Add(countMatch(true, match.refreshed))
+	}
+	return counts, rowErrs
+}
+
+// upsertMatchesBatch upserts the matches in one batch under a savepoint.
+// @param pending - the matches to upsert.
+// @returns the upserted matches, the number the upsert guard turned away, and the error of the batch.
+func (sink *PostgresSink) upsertMatchesBatch(ctx context.Context, pending []pendingMatch) ([]pendingMatch, int, error) {
+	upserts := make([]dbtypes.UpsertMatchesBatchParams, 0, len(pending))
+	for _, match := range pending {
+		upserts = append(upserts, dbtypes.UpsertMatchesBatchParams(match.params))
+	}
+	skipped := make(map[int32]bool)
+	err := sink.Savepoint(ctx, func() error {
+		var first error
+		sink.queries.UpsertMatchesBatch(ctx, upserts).QueryRow(func(i int, _ int32, upsertErr error) {
+			switch {
+			case errors.Is(upsertErr, pgx.ErrNoRows):
+				// no row means a newer version was stored since the lookup
+				skipped[upserts[i].ID] = true
+			case upsertErr != nil && first == nil:
+				first = upsertErr
+			}
+		})
+		return first
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	upserted := make([]pendingMatch, 0, len(pending))
+	for _, match := range pending {
+		if !skipped[match.params.ID] {
+			upserted = append(upserted, match)
+		}
+	}
+	return upserted, len(pending) - len(upserted), nil
+}
+
+// writeFollowUps writes the changes, outbox events and opponents of upserted
+// matches, one batch each. When a batch is rejected, its part is redone match
+// by match so that only the failing matches are reported.
+// The matches are stored already, so the fallbacks never diff against the
+// freshly written rows.
+// @param pending - the upserted matches.
+// @returns one RowError per match whose follow-ups could not be written.
+func (sink *PostgresSink) writeFollowUps(ctx context.Context, pending []pendingMatch) []RowError {
+	db := sink.queries
+	var rowErrs []RowError
+	if changes := changeParams(pending); len(changes) > 0 {
+		err := sink.Savepoint(ctx, func() error { return execBatch(db.InsertMatchChangesBatch(ctx, changes)) })
+		if err != nil {
+			for _, match := range pending {
+				changeErr := sink.Savepoint(ctx, func() error {
+					return RecordMatchChanges(ctx, db, match.params.ID, match.changes)
+				})
+				if changeErr != nil {
+					rowErrs = append(rowErrs, RowError{ID: match.row.ID, Err: changeErr})
+				}
+			}
+		}
+	}
+	if events := eventParams(pending); len(events) > 0 {
+		err := sink.Savepoint(ctx, func() error { return execBatch(db.InsertOutboxEventsBatch(ctx, events)) })
+		if err != nil {
+			for _, match := range pending {
+				eventErr := sink.Savepoint(ctx, func() error {
+					return RecordOutboxEvents(ctx, db, match.params.ID, match.events)
+				})
+				if eventErr != nil {
+					rowErrs = append(rowErrs, RowError{ID: match.row.ID, Err: eventErr})
+				}
+			}
+		}
+	}
+	if err := sink.Savepoint(ctx, func() error { return writeOpponentsBatch(ctx, db, pending) }); err != nil {
+		for _, match := range pending {
+			opponentErr := sink.Savepoint(ctx, func() error {
+				return WriteMatchOpponents(ctx, db, match.params.ID, match.row.Opponents)
+			})
+			if opponentErr != nil {
+				rowErrs = append(rowErrs, RowError{ID: match.row.ID, Err: opponentErr})
+			}
+		}
+	}
+	return rowErrs
+}
+
+// toPending converts the row and its opponents into sqlc parameters.
+func (row MatchRow) toPending() (pendingMatch, error) {
+	params, err := row.ToParams()
+	if err != nil {
+		return pendingMatch{}, err
+	}
+	opponents := make([]dbtypes.InsertMatchOpponentParams, 0, len(row.Opponents))
+	for _, opponent := range row.Opponents {
+		opponentParams, opponentErr := opponent.toParams(params.ID)
+		if opponentErr != nil {
+			return pendingMatch{}, opponentErr
+		}
+		opponents = append(opponents, opponentParams)
+	}
+	return pendingMatch{
+		row: row, params: params, opponents: opponents, changes: nil, events: nil, refreshed: false,
+	}, nil
+}
+
+func (sink *PostgresSink) writeMatchesOneByOne(ctx context.Context, pending []pendingMatch) (WriteCounts, []RowError) {
+	var counts WriteCounts
+	var rowErrs []RowError
+	for _, match := range pending {
+		var written WriteCounts
+		err := sink.Savepoint(ctx, func() error {
+			var writeErr error
+			written, writeErr = sink.writeMatch(ctx, match.row)
+			return writeErr
+		})
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{ID: match.row.ID, Err: err})
+			continue
+		}
+		counts.Add(written)
+	}
+	return counts, rowErrs
+}
+
+// changeParams flattens the changes of every pending match into batch parameters.
+func changeParams(pending []pendingMatch) []dbtypes.InsertMatchChangesBatchParams {
+	var changes []dbtypes.InsertMatchChangesBatchParams
+	for _, match := range pending {
+		for _, change := range match.changes {
+			changes = append(changes, dbtypes.InsertMatchChangesBatchParams{
+				MatchID:  match.params.ID,
+				Field:    change.Field,
+				OldValue: pgtype.Text{String: change.OldValue, Valid: change.OldValue != ""},
+				NewValue: pgtype.Text{String: change.NewValue, Valid: change.NewValue != ""},
+			})
+		}
+	}
+	return changes
+}
+
+// eventParams flattens the outbox events of every pending match into batch parameters.
+func eventParams(pending []pendingMatch) []dbtypes.InsertOutboxEventsBatchParams {
+	var events []dbtypes.InsertOutboxEventsBatchParams
+	for _, match := range pending {
+		for _, event := range match.events {
+			events = append(events, dbtypes.InsertOutboxEventsBatchParams{
+				EventType: event.Type,
+				MatchID:   match.params.ID,
+				Data:      event.Data,
+			})
+		}
+	}
+	return events
+}
+
+func writeOpponentsBatch(ctx context.Context, db *dbtypes.Queries, pending []pendingMatch) error {
+	var opponents []dbtypes.InsertMatchOpponentsBatchParams
+	trims := make([]dbtypes.TrimMatchOpponentsBatchParams, 0, len(pending))
+	for _, match := range pending {
+		for _, opponent := range match.opponents {
+			opponents = append(opponents, dbtypes.InsertMatchOpponentsBatchParams(opponent))
+		}
+		count, err := SafeIntToInt32(len(match.opponents))
+		if err != nil {
+			return err
+		}
+		trims = append(trims, dbtypes.TrimMatchOpponentsBatchParams{MatchID: match.params.ID, Slot: count})
+	}
+	if len(opponents) > 0 {
+		if err := execBatch(db.InsertMatchOpponentsBatch(ctx, opponents)); err != nil {
+			return err
+		}
+	}
+	return execBatch(db.TrimMatchOpponentsBatch(ctx, trims))
+}
+
+// WriteMissingOpponents creates the given teams and players in two batches.
+// Of the ones that already exist only a tombstone is cleared and a last_seen_at
+// older than SeenRefreshInterval refreshed: their data is left to the teams and
+// players jobs, but reconciliation must not take them for stale or gone while
+// matches still refer to them. Rows of a rejected batch are retried one by one,
+// each under its own savepoint inside a transaction.
+// @param teams - the team opponents of a page.
+// @param players - the player opponents of a page.
+// @returns one RowError per team or player that could not be written.
+func (sink *PostgresSink) WriteMissingOpponents(ctx context.Context, teams []TeamRow, players []PlayerRow) []RowError {
+	db := sink.queries
+	var rowErrs []RowError
+	teamParams := make([]dbtypes.InsertMissingTeamsBatchParams, 0, len(teams))
+	for _, team := range teams {
+		params, err := team.ToParams()
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{ID: team.ID, Err: err})
+			continue
+		}
+		teamParams = append(teamParams, dbtypes.InsertMissingTeamsBatchParams(params))
+	}
+	playerParams := make([]dbtypes.InsertMissingPlayersBatchParams, 0, len(players))
+	for _, player := range players {
+		params, err := player.ToParams()
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{ID: player.ID, Err: err})
+			continue
+		}
+		playerParams = append(playerParams, dbtypes.InsertMissingPlayersBatchParams(params))
+	}
+
+	insertTeams := func(params []dbtypes.InsertMissingTeamsBatchParams) error {
+		return sink.Savepoint(ctx, func() error { return execBatch(db.InsertMissingTeamsBatch(ctx, params)) })
+	}
+	insertPlayers := func(params []dbtypes.InsertMissingPlayersBatchParams) error {
+		return sink.Savepoint(ctx, func() error { return execBatch(db.InsertMissingPlayersBatch(ctx, params)) })
+	}
+	if len(teamParams) > 0 && insertTeams(teamParams) != nil {
+		for _, params := range teamParams {
+			err := insertTeams([]dbtypes.InsertMissingTeamsBatchParams{params})
+			if err != nil {
+				rowErrs = append(rowErrs, RowError{ID: int(params.ID), Err: err})
+			}
+		}
+	}
+	if len(playerParams) > 0 && insertPlayers(playerParams) != nil {
+		for _, params := range playerParams {
+			err := insertPlayers([]dbtypes.InsertMissingPlayersBatchParams{params})
+			if err != nil {
+				rowErrs = append(rowErrs, RowError{ID: int(params.ID), Err: err})
+			}
+		}
+	}
+	return rowErrs
+}
@@ -0,0 +1,481 @@
+package pandatypes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+// pageRows returns two distinct matches built from the fixture.
+func pageRows(t *testing.T) (MatchRow, MatchRow) {
+	first := lobbyMatch(t, OpponentTypeTeam, 2, 0).ToRow().(MatchRow)
+	second := lobbyMatch(t, OpponentTypePlayer, 1, 3).ToRow().(MatchRow)
+	second.ID = first.ID + 1
+	return first, second
+}
+
+// upsertedID returns the row UpsertMatchesBatch reports for a written match.
+func upsertedID(id int) *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"id"}).AddRow(int32(id))
+}
+
+func TestWriteMatchPage(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	sink := NewPostgresSink(dbtypes.New(mockDB), nil)
+
+	first, second := pageRows(t)
+	firstParams, err := first.ToParams()
+	st.Assert(t, err, nil)
+
+	t.Run("Success - one round trip per statement type", func(t *testing.T) {
+		stored := storedMatch(firstParams)
+		stored.Status = pgtype.Text{String: MatchStatusRunning, Valid: true}
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs([]int32{int32(first.ID), int32(second.ID)}).
+			WillReturnRows(matchRows(stored))
+		expectArchived(mockDB, []int32{int32(second.ID)})
+		upserts := mockDB.ExpectBatch()
+		upserts.ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(upsertedID(first.ID))
+		upserts.ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(upsertedID(second.ID))
+		mockDB.ExpectBatch().ExpectExec("INSERT INTO match_changes").
+			WithArgs(
+				int32(first.ID),
+				FieldStatus,
+				pgtype.Text{String: MatchStatusRunning, Valid: true},
+				pgtype.Text{String: MatchStatusFinished, Valid: true},
+			).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectOutboxBatch(mockDB, int32(second.ID), EventMatchCreated, EventMatchFinished)
+		opponents := mockDB.ExpectBatch()
+		for range 4 {
+			opponents.ExpectExec("INSERT INTO match_opponents").WithArgs(anyArgs(6)...).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		trims := mockDB.ExpectBatch()
+		trims.ExpectExec("DELETE FROM match_opponents").WithArgs(int32(first.ID), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		trims.ExpectExec("DELETE FROM match_opponents").WithArgs(int32(second.ID), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+		expectNotify(mockDB, ChannelMatchesChanged, fmt.Sprintf(
+			`{"ids":[%d,%d],"changes":{"created":[%[2]d],"finished":[%[2]d],"status":[%[1]d]}}`,
+			first.ID, second.ID))
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{first, second})
+		st.Expect(t, len(rowErrs), 0)
+		st.Expect(t, counts, WriteCounts{Updated: 2, Skipped: 0, Refreshed: 0})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Up to date matches are not written", func(t *testing.T) {
+		stored := storedMatch(firstParams)
+		stored.ModifiedAt = firstParams.ModifiedAt
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs([]int32{int32(first.ID), int32(second.ID)}).
+			WillReturnRows(matchRows(stored))
+		expectArchived(mockDB, []int32{int32(second.ID)})
+		mockDB.ExpectBatch().ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(upsertedID(second.ID))
+		expectOutboxBatch(mockDB, int32(second.ID), EventMatchCreated, EventMatchFinished)
+		opponents := mockDB.ExpectBatch()
+		for range 2 {
+			opponents.ExpectExec("INSERT INTO match_opponents").WithArgs(anyArgs(6)...).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		mockDB.ExpectBatch().ExpectExec("DELETE FROM match_opponents").WithArgs(int32(second.ID), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		expectNotify(mockDB, ChannelMatchesChanged, fmt.Sprintf(
+			`{"ids":[%d],"changes":{"created":[%[1]d],"finished":[%[1]d]}}`, second.ID))
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{first, second})
+		st.Expect(t, len(rowErrs), 0)
+		st.Expect(t, counts, WriteCounts{Updated: 1, Skipped: 1, Refreshed: 0})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Tombstoned and long unseen matches are rewritten", func(t *testing.T) {
+		tombstoned := storedMatch(firstParams)
+		tombstoned.ModifiedAt = firstParams.ModifiedAt
+		tombstoned.DeletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true, InfinityModifier: 0}
+		secondParams, err := second.ToParams()
+		st.Assert(t, err, nil)
+		unseen := storedMatch(secondParams)
+		unseen.ModifiedAt = secondParams.ModifiedAt
+		unseen.LastSeenAt.Time = time.Now().Add(-2 * SeenRefreshInterval)
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs([]int32{int32(first.ID), int32(second.ID)}).
+			WillReturnRows(matchRows(tombstoned, unseen))
+		upserts := mockDB.ExpectBatch()
+		for _, id := range []int{first.ID, second.ID} {
+			upserts.ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+				WillReturnRows(upsertedID(id))
+		}
+		opponents := mockDB.ExpectBatch()
+		for range 4 {
+			opponents.ExpectExec("INSERT INTO match_opponents").WithArgs(anyArgs(6)...).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		trims := mockDB.ExpectBatch()
+		trims.ExpectExec("DELETE FROM match_opponents").WithArgs(int32(first.ID), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		trims.ExpectExec("DELETE FROM match_opponents").WithArgs(int32(second.ID), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		expectNotify(mockDB, ChannelMatchesChanged, fmt.Sprintf(`{"ids":[%d,%d]}`, first.ID, second.ID))
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{first, second})
+		st.Expect(t, len(rowErrs), 0)
+		// the unseen match is unchanged, so it only counts as refreshed
+		st.Expect(t, counts, WriteCounts{Updated: 1, Skipped: 0, Refreshed: 1})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Finished matches found in the archive are left there", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs([]int32{int32(first.ID)}).
+			WillReturnRows(pgxmock.NewRows(matchColumns))
+		expectArchived(mockDB, []int32{int32(first.ID)}, storedMatch(firstParams))
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{first})
+		st.Expect(t, len(rowErrs), 0)
+		st.Expect(t, counts, WriteCounts{Updated: 0, Skipped: 1, Refreshed: 0})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Reopened matches return from the archive without being created", func(t *testing.T) {
+		reopened := first
+		reopened.Finished = false
+		reopened.Status = MatchStatusRunning
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs([]int32{int32(first.ID)}).
+			WillReturnRows(pgxmock.NewRows(matchColumns))
+		expectArchived(mockDB, []int32{int32(first.ID)}, storedMatch(firstParams))
+		mockDB.ExpectBatch().ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(upsertedID(first.ID))
+		mockDB.ExpectBatch().ExpectExec("INSERT INTO match_changes").
+			WithArgs(
+				int32(first.ID),
+				FieldStatus,
+				pgtype.Text{String: MatchStatusFinished, Valid: true},
+				pgtype.Text{String: MatchStatusRunning, Valid: true},
+			).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		opponents := mockDB.ExpectBatch()
+		for range 2 {
+			opponents.ExpectExec("INSERT INTO match_opponents").WithArgs(anyArgs(6)...).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		mockDB.ExpectBatch().ExpectExec("DELETE FROM match_opponents").WithArgs(int32(first.ID), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		expectNotify(mockDB, ChannelMatchesChanged, fmt.Sprintf(
+			`{"ids":[%d],"changes":{"status":[%[1]d]}}`, first.ID))
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{reopened})
+		st.Expect(t, len(rowErrs), 0)
+		st.Expect(t, counts, WriteCounts{Updated: 1, Skipped: 0, Refreshed: 0})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Matches the upsert guard turns away are skipped", func(t *testing.T) {
+		// both look new at the lookup, but a newer second was stored since
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs([]int32{int32(first.ID), int32(second.ID)}).
+			WillReturnRows(pgxmock.NewRows(matchColumns))
+		expectArchived(mockDB, []int32{int32(first.ID), int32(second.ID)})
+		upserts := mockDB.ExpectBatch()
+		upserts.ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(upsertedID(first.ID))
+		upserts.ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+		expectOutboxBatch(mockDB, int32(first.ID), EventMatchCreated, EventMatchFinished)
+		opponents := mockDB.ExpectBatch()
+		for range 2 {
+			opponents.ExpectExec("INSERT INTO match_opponents").WithArgs(anyArgs(6)...).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		mockDB.ExpectBatch().ExpectExec("DELETE FROM match_opponents").WithArgs(int32(first.ID), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		expectNotify(mockDB, ChannelMatchesChanged, fmt.Sprintf(
+			`{"ids":[%d],"changes":{"created":[%[1]d],"finished":[%[1]d]}}`, first.ID))
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{first, second})
+		st.Expect(t, len(rowErrs), 0)
+		st.Expect(t, counts, WriteCounts{Updated: 1, Skipped: 1, Refreshed: 0})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("A page the upsert guard turns away entirely writes nothing else", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs([]int32{int32(first.ID)}).
+			WillReturnRows(pgxmock.NewRows(matchColumns))
+		expectArchived(mockDB, []int32{int32(first.ID)})
+		mockDB.ExpectBatch().ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{first})
+		st.Expect(t, len(rowErrs), 0)
+		st.Expect(t, counts, WriteCounts{Updated: 0, Skipped: 1, Refreshed: 0})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Unconvertible rows are reported without blocking the page", func(t *testing.T) {
+		broken := second
+		broken.Team1Score = math.MaxInt32 + 1
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs([]int32{int32(first.ID)}).
+			WillReturnRows(pgxmock.NewRows(matchColumns))
+		expectArchived(mockDB, []int32{int32(first.ID)})
+		mockDB.ExpectBatch().ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(upsertedID(first.ID))
+		expectOutboxBatch(mockDB, int32(first.ID), EventMatchCreated, EventMatchFinished)
+		opponents := mockDB.ExpectBatch()
+		for range 2 {
+			opponents.ExpectExec("INSERT INTO match_opponents").WithArgs(anyArgs(6)...).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		mockDB.ExpectBatch().ExpectExec("DELETE FROM match_opponents").WithArgs(int32(first.ID), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		expectNotify(mockDB, ChannelMatchesChanged, pgxmock.AnyArg())
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{first, broken})
+		st.Assert(t, len(rowErrs), 1)
+		st.Expect(t, counts.Updated, 1)
+		st.Expect(t, rowErrs[0].ID, broken.ID)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Rejected batch falls back to row by row", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows(matchColumns))
+		expectArchived(mockDB, pgxmock.AnyArg())
+		upserts := mockDB.ExpectBatch()
+		upserts.ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(upsertedID(first.ID))
+		upserts.ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnError(fmt.Errorf("foreign key violation"))
+		// first row succeeds on its own
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").WithArgs(int32(first.ID)).
+			WillReturnError(pgx.ErrNoRows)
+		expectArchived(mockDB, []int32{int32(first.ID)})
+		mockDB.ExpectExec("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectOpponents(mockDB, int32(first.ID), 2)
+		expectOutbox(mockDB, int32(first.ID), EventMatchCreated, EventMatchFinished)
+		expectNotify(mockDB, ChannelMatchesChanged, pgxmock.AnyArg())
+		// second row is the culprit
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").WithArgs(int32(second.ID)).
+			WillReturnError(pgx.ErrNoRows)
+		expectArchived(mockDB, []int32{int32(second.ID)})
+		mockDB.ExpectExec("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnError(fmt.Errorf("foreign key violation"))
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{first, second})
+		st.Assert(t, len(rowErrs), 1)
+		st.Expect(t, rowErrs[0].ID, second.ID)
+		st.Expect(t, counts.Updated, 1)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Rejected opponent batch only retries the opponents", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows(matchColumns))
+		expectArchived(mockDB, pgxmock.AnyArg())
+		mockDB.ExpectBatch().ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(upsertedID(first.ID))
+		expectOutboxBatch(mockDB, int32(first.ID), EventMatchCreated, EventMatchFinished)
+		opponents := mockDB.ExpectBatch()
+		opponents.ExpectExec("INSERT INTO match_opponents").WithArgs(anyArgs(6)...).
+			WillReturnError(fmt.Errorf("database error"))
+		opponents.ExpectExec("INSERT INTO match_opponents").WithArgs(anyArgs(6)...).
+			WillReturnError(fmt.Errorf("database error"))
+		expectOpponents(mockDB, int32(first.ID), 2)
+		expectNotify(mockDB, ChannelMatchesChanged, pgxmock.AnyArg())
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{first})
+		st.Expect(t, len(rowErrs), 0)
+		st.Expect(t, counts.Updated, 1)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("A failed notification reports the written matches", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows(matchColumns))
+		expectArchived(mockDB, pgxmock.AnyArg())
+		mockDB.ExpectBatch().ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(upsertedID(first.ID))
+		expectOutboxBatch(mockDB, int32(first.ID), EventMatchCreated, EventMatchFinished)
+		opponents := mockDB.ExpectBatch()
+		for range 2 {
+			opponents.ExpectExec("INSERT INTO match_opponents").WithArgs(anyArgs(6)...).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		mockDB.ExpectBatch().ExpectExec("DELETE FROM match_opponents").WithArgs(int32(first.ID), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		mockDB.ExpectExec("SELECT pg_notify").
+			WithArgs(ChannelMatchesChanged, pgxmock.AnyArg()).
+			WillReturnError(fmt.Errorf("too many notifications in the NOTIFY queue"))
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{first})
+		st.Expect(t, counts.Updated, 0)
+		st.Assert(t, len(rowErrs), 1)
+		st.Expect(t, rowErrs[0].ID, first.ID)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Inside a transaction a rejected batch only rolls back its savepoint", func(t *testing.T) {
+		mockDB.ExpectBegin()
+		txSink, err := NewPostgresSink(dbtypes.New(mockDB), mockDB).Begin(t.Context())
+		st.Assert(t, err, nil)
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows(matchColumns))
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		expectArchived(mockDB, pgxmock.AnyArg())
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		mockDB.ExpectBegin()
+		mockDB.ExpectBatch().ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnError(fmt.Errorf("foreign key violation"))
+		mockDB.ExpectRollback()
+		mockDB.ExpectRollback()
+		// the row is retried under a savepoint of its own
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").WithArgs(int32(first.ID)).
+			WillReturnError(pgx.ErrNoRows)
+		expectArchived(mockDB, []int32{int32(first.ID)})
+		mockDB.ExpectExec("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnError(fmt.Errorf("foreign key violation"))
+		mockDB.ExpectRollback()
+
+		counts, rowErrs := txSink.WriteMatchPage(t.Context(), []MatchRow{first})
+		st.Expect(t, counts.Updated, 0)
+		st.Assert(t, len(rowErrs), 1)
+		st.Expect(t, rowErrs[0].ID, first.ID)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Inside a transaction a match failing after its upsert is rolled back with it", func(t *testing.T) {
+		mockDB.ExpectBegin()
+		txSink, err := NewPostgresSink(dbtypes.New(mockDB), mockDB).Begin(t.Context())
+		st.Assert(t, err, nil)
+
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id = ANY").
+			WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows(matchColumns))
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		expectArchived(mockDB, pgxmock.AnyArg())
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		mockDB.ExpectBegin()
+		mockDB.ExpectBatch().ExpectQuery("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnRows(upsertedID(first.ID))
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		outbox := mockDB.ExpectBatch()
+		for range 2 {
+			outbox.ExpectExec("INSERT INTO outbox").WithArgs(anyArgs(3)...).
+				WillReturnError(fmt.Errorf("database error"))
+		}
+		mockDB.ExpectRollback()
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec("INSERT INTO outbox").WithArgs(anyArgs(3)...).
+			WillReturnError(fmt.Errorf("database error"))
+		mockDB.ExpectRollback()
+		mockDB.ExpectBegin()
+		opponents := mockDB.ExpectBatch()
+		for range 2 {
+			opponents.ExpectExec("INSERT INTO match_opponents").WithArgs(anyArgs(6)...).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		mockDB.ExpectBatch().ExpectExec("DELETE FROM match_opponents").WithArgs(int32(first.ID), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		mockDB.ExpectCommit()
+		// the stored match is rolled back and written again with its follow-ups
+		mockDB.ExpectRollback()
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").WithArgs(int32(first.ID)).
+			WillReturnError(pgx.ErrNoRows)
+		expectArchived(mockDB, []int32{int32(first.ID)})
+		mockDB.ExpectExec("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectOpponents(mockDB, int32(first.ID), 2)
+		mockDB.ExpectExec("INSERT INTO outbox").WithArgs(anyArgs(3)...).
+			WillReturnError(fmt.Errorf("database error"))
+		mockDB.ExpectRollback()
+
+		counts, rowErrs := txSink.WriteMatchPage(t.Context(), []MatchRow{first})
+		st.Expect(t, counts, WriteCounts{Updated: 0, Skipped: 0, Refreshed: 0})
+		st.Assert(t, len(rowErrs), 1)
+		st.Expect(t, rowErrs[0].ID, first.ID)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Empty page does nothing", func(t *testing.T) {
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), nil)
+		st.Expect(t, len(rowErrs), 0)
+		st.Expect(t, counts, WriteCounts{})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestWriteMissingOpponents(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	sink := NewPostgresSink(dbtypes.New(mockDB), nil)
+
+	teams := []TeamRow{{ID: 1, GameID: 1, Name: "T1"}, {ID: 2, GameID: 1, Name: "Gen.G"}}
+	players := []PlayerRow{{ID: 3, GameID: 14, Name: "Tokido"}}
+
+	t.Run("Success", func(t *testing.T) {
+		teamBatch := mockDB.ExpectBatch()
+		for range teams {
+			teamBatch.ExpectExec("INSERT INTO teams .+ DO UPDATE SET last_seen_at = .+, deleted_at = NULL").WithArgs(anyArgs(7)...).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		mockDB.ExpectBatch().ExpectExec("INSERT INTO players .+ DO UPDATE SET last_seen_at = .+, deleted_at = NULL").WithArgs(anyArgs(9)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+		st.Expect(t, len(sink.WriteMissingOpponents(t.Context(), teams, players)), 0)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Rejected batch reports the failing row", func(t *testing.T) {
+		teamBatch := mockDB.ExpectBatch()
+		teamBatch.ExpectExec("INSERT INTO teams").WithArgs(anyArgs(7)...).
+			WillReturnError(fmt.Errorf("game does not exist"))
+		teamBatch.ExpectExec("INSERT INTO teams").WithArgs(anyArgs(7)...).
+			WillReturnError(fmt.Errorf("game does not exist"))
+		mockDB.ExpectBatch().ExpectExec("INSERT INTO teams").WithArgs(anyArgs(7)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDB.ExpectBatch().ExpectExec("INSERT INTO teams").WithArgs(anyArgs(7)...).
+			WillReturnError(fmt.Errorf("game does not exist"))
+
+		overflow := PlayerRow{ID: math.MaxInt32 + 1, Name: "overflow"}
+		rowErrs := sink.WriteMissingOpponents(t.Context(), teams, []PlayerRow{overflow})
+		st.Assert(t, len(rowErrs), 2)
+		st.Expect(t, rowErrs[0].ID, overflow.ID)
+		st.Expect(t, rowErrs[1].ID, 2)
+		st.Expect(t, errors.Unwrap(rowErrs[1]).Error(), "game does not exist")
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
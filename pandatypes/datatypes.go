@@ -15,6 +15,15 @@ const (
 	twoTeams = 2
 )
 
+// Match statuses reported by PandaScore in the `status` field of a match.
+const (
+	MatchStatusNotStarted = "not_started"
+	MatchStatusRunning    = "running"
+	MatchStatusFinished   = "finished"
+	MatchStatusCanceled   = "canceled"
+	MatchStatusPostponed  = "postponed"
+)
+
 // StreamEntry mirrors a single entry of PandaScore's `streams_list` array.
 type StreamEntry struct {
 	Main     bool   `json:"main"`
@@ -29,7 +38,9 @@ type PandaDataLike interface {
 }
 
 type RowLike interface {
-	WriteToDB(ctx context.Context, db *dbtypes.Queries) error
+	// WriteToDB upserts the row into the sink. It reports false when the
+	// stored row was at least as new and was left untouched.
+	WriteToDB(ctx context.Context, sink Writer) (bool, error)
 }
 
 type GameLike struct {
@@ -182,10 +193,16 @@ type TournamentLike struct {
 	EndAt         time.Time `json:"end_at"`
 	WinnerID      any       `json:"winner_id"`
 	WinnerType    string    `json:"winner_type"`
-	Teams         []any     `json:"teams"`
-	Slug          string    `json:"slug"`
-	ModifiedAt    time.Time `json:"modified_at"`
-	Videogame     struct {
+	Teams         []struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		Acronym  string `json:"acronym"`
+		Slug     string `json:"slug"`
+		ImageURL string `json:"image_url"`
+	} `json:"teams"`
+	Slug       string    `json:"slug"`
+	ModifiedAt time.Time `json:"modified_at"`
+	Videogame  struct {
 		ID   int    `json:"id"`
 		Name string `json:"name"`
 		Slug string `json:"slug"`
@@ -225,8 +242,9 @@ type TournamentLike struct {
 
 type MatchLike struct {
 	Results []struct {
-		TeamID int `json:"team_id"`
-		Score  int `json:"score"`
+		TeamID   int `json:"team_id"`
+		PlayerID int `json:"player_id"`
+		Score    int `json:"score"`
 	} `json:"results"`
 	Tournament struct {
 		ID            int       `json:"id"`
@@ -287,6 +305,10 @@ type MatchLike struct {
 			ModifiedAt time.Time `json:"modified_at"`
 			Acronym    string    `json:"acronym"`
 			ImageURL   string    `json:"image_url"`
+			// Only set for player opponents.
+			FirstName   string `json:"first_name"`
+			LastName    string `json:"last_name"`
+			Nationality string `json:"nationality"`
 		} `json:"opponent"`
 	} `json:"opponents"`
 	Status        string    `json:"status"`
@@ -405,105 +427,84 @@ func (game GameLike) ToRow() RowLike {
 	}
 }
 
-func (row GameRow) WriteToDB(ctx context.Context, db *dbtypes.Queries) error {
-	id, err := SafeIntToInt32(row.ID)
-	if err != nil {
-		return err
-	}
-	err = db.InsertToGames(ctx, dbtypes.InsertToGamesParams{
-		ID:   id,
-		Name: row.Name,
-		Slug: pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
-	})
-	return err
+func (row GameRow) WriteToDB(ctx context.Context, sink Writer) (bool, error) {
+	return sink.UpsertGame(ctx, row)
 }
 
 type LeagueRow struct {
-	ID        int
-	Name      string
-	Slug      string
-	GameID    int
-	ImageLink string
+	ID         int
+	Name       string
+	Slug       string
+	GameID     int
+	ImageLink  string
+	ModifiedAt time.Time
 }
 
 func (league LeagueLike) ToRow() RowLike {
 	return LeagueRow{
-		ID:        league.ID,
-		Name:      league.Name,
-		Slug:      league.Slug,
-		GameID:    league.Videogame.ID,
-		ImageLink: league.ImageURL,
+		ID:         league.ID,
+		Name:       league.Name,
+		Slug:       league.Slug,
+		GameID:     league.Videogame.ID,
+		ImageLink:  league.ImageURL,
+		ModifiedAt: league.ModifiedAt,
 	}
 }
 
-func (row LeagueRow) WriteToDB(ctx context.Context, db *dbtypes.Queries) error {
-	id, err := SafeIntToInt32(row.ID)
-	if err != nil {
-		return err
-	}
-	gameID, err := SafeIntToInt32(row.GameID)
-	if err != nil {
-		return err
-	}
-	err = db.InsertToLeagues(ctx, dbtypes.InsertToLeaguesParams{
-		ID:        id,
-		Slug:      pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
-		Name:      row.Name,
-		GameID:    gameID,
-		ImageLink: pgtype.Text{String: row.ImageLink, Valid: row.ImageLink != ""},
-	})
-	return err
+func (row LeagueRow) WriteToDB(ctx context.Context, sink Writer) (bool, error) {
+	return sink.UpsertLeague(ctx, row)
 }
 
 type SeriesRow struct {
-	ID       int
-	Name     string
-	Slug     string
-	GameID   int
-	LeagueID int
+	ID         int
+	Name       string
+	Slug       string
+	GameID     int
+	LeagueID   int
+	ModifiedAt time.Time
 }
 
 func (series SeriesLike) ToRow() RowLike {
 	return SeriesRow{
-		ID:       series.ID,
-		Name:     series.Name,
-		Slug:     series.Slug,
-		GameID:   series.Videogame.ID,
-		LeagueID: series.League.ID,
+		ID:         series.ID,
+		Name:       series.Name,
+		Slug:       series.Slug,
+		GameID:     series.Videogame.ID,
+		LeagueID:   series.League.ID,
+		ModifiedAt: series.ModifiedAt,
 	}
 }
 
-func (row SeriesRow) WriteToDB(ctx context.Context, db *dbtypes.Queries) error {
-	id, err := SafeIntToInt32(row.ID)
-	if err != nil {
-		return err
-	}
-	gameID, err := SafeIntToInt32(row.GameID)
-	if err != nil {
-		return err
-	}
-	leagueID, err := SafeIntToInt32(row.LeagueID)
-	if err != nil {
-		return err
-	}
-	err = db.InsertToSeries(ctx, dbtypes.InsertToSeriesParams{
-		ID:       id,
-		Name:     row.Name,
-		Slug:     pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
-		GameID:   gameID,
-		LeagueID: leagueID,
-	})
-	return err
+func (row SeriesRow) WriteToDB(ctx context.Context, sink Writer) (bool, error) {
+	return sink.UpsertSeries(ctx, row)
 }
 
 type TournamentRow struct {
-	ID       int
-	Name     string
-	Slug     string
-	Tier     int
-	GameID   int
-	LeagueID int
-	SerieID  int
+	ID         int
+	Name       string
+	Slug       string
+	Tier       int
+	GameID     int
+	LeagueID   int
+	SerieID    int
+	ModifiedAt time.Time
+}
+
+// TeamRows converts the teams taking part in the tournament into team rows.
+// @returns one row per team, in PandaScore order.
+func (tournament TournamentLike) TeamRows() []TeamRow {
+	rows := make([]TeamRow, 0, len(tournament.Teams))
+	for _, team := range tournament.Teams {
+		rows = append(rows, TeamRow{
+			ID:        team.ID,
+			GameID:    tournament.Videogame.ID,
+			Name:      team.Name,
+			Acronym:   team.Acronym,
+			Slug:      team.Slug,
+			ImageLink: team.ImageURL,
+		})
+	}
+	return rows
 }
 
 func (tournament TournamentLike) ToRow() RowLike {
@@ -523,45 +524,19 @@ func (tournament TournamentLike) ToRow() RowLike {
 		tier = 6
 	}
 	return TournamentRow{
-		ID:       tournament.ID,
-		Name:     tournament.Name,
-		Slug:     tournament.Slug,
-		Tier:     tier,
-		GameID:   tournament.Videogame.ID,
-		SerieID:  tournament.Serie.ID,
-		LeagueID: tournament.League.ID,
+		ID:         tournament.ID,
+		Name:       tournament.Name,
+		Slug:       tournament.Slug,
+		Tier:       tier,
+		GameID:     tournament.Videogame.ID,
+		SerieID:    tournament.Serie.ID,
+		LeagueID:   tournament.League.ID,
+		ModifiedAt: tournament.ModifiedAt,
 	}
 }
 
-func (row TournamentRow) WriteToDB(ctx context.Context, db *dbtypes.Queries) error {
-	id, err := SafeIntToInt32(row.ID)
-	if err != nil {
-		return err
-	}
-	gameID, err := SafeIntToInt32(row.GameID)
-	if err != nil {
-		return err
-	}
-	leagueID, err := SafeIntToInt32(row.LeagueID)
-	if err != nil {
-		return err
-	}
-	serieID, err := SafeIntToInt32(row.SerieID)
-	if err != nil {
-		return err
-	}
-	//nolint:gosec // tier is fixed by the switch statement previously
-	tier := int32(row.Tier)
-	err = db.InsertToTournaments(ctx, dbtypes.InsertToTournamentsParams{
-		ID:       id,
-		Name:     row.Name,
-		Slug:     pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
-		Tier:     pgtype.Int4{Int32: tier, Valid: row.Tier != 0},
-		GameID:   gameID,
-		SerieID:  serieID,
-		LeagueID: leagueID,
-	})
-	return err
+func (row TournamentRow) WriteToDB(ctx context.Context, sink Writer) (bool, error) {
+	return sink.UpsertTournament(ctx, row)
 }
 
 type MatchRow struct {
@@ -581,6 +556,19 @@ type MatchRow struct {
 	SerieID           int
 	TournamentID      int
 	StreamURL         string
+	// Status is the raw PandaScore lifecycle status, see the MatchStatus* constants.
+	Status              string
+	Forfeit             bool
+	Draw                bool
+	Rescheduled         bool
+	OriginalScheduledAt time.Time
+	BeginAt             time.Time
+	EndAt               time.Time
+	WinnerID            int
+	WinnerType          string
+	ModifiedAt          time.Time
+	// Opponents lists every team or player of the match, in PandaScore order.
+	Opponents []MatchOpponentRow
 }
 
 func (match MatchLike) ToRow() RowLike {
@@ -588,141 +576,188 @@ func (match MatchLike) ToRow() RowLike {
 	if match.EndAt != (time.Time{}) {
 		actualGT = match.EndAt.Sub(match.BeginAt).Seconds() / float64(match.NumberOfGames)
 	}
+	opponents := match.opponentRows()
 	t1ID := 0
 	t1Score := 0
 	t2ID := 0
 	t2Score := 0
-	if len(match.Opponents) == twoTeams {
-		t1ID = match.Opponents[0].Opponent.ID
-		t1Score = match.Results[0].Score
-		t2ID = match.Opponents[1].Opponent.ID
-		t2Score = match.Results[1].Score
+	// team1/team2 keep serving plain team-vs-team matches, every other format
+	// is only described by MATCH_OPPONENTS.
+	if len(opponents) == twoTeams && opponents[0].Type == OpponentTypeTeam && opponents[1].Type == OpponentTypeTeam {
+		t1ID = opponents[0].ID
+		t1Score = opponents[0].Score
+		t2ID = opponents[1].ID
+		t2Score = opponents[1].Score
 	}
 	streamURL := ExtractPrimaryStreamURL(match.StreamsList)
 	return MatchRow{
-		ID:                match.ID,
-		Slug:              match.Slug,
-		Finished:          match.EndAt != time.Time{},
-		GameID:            match.Videogame.ID,
-		LeagueID:          match.League.ID,
-		SerieID:           match.Serie.ID,
-		TournamentID:      match.Tournament.ID,
-		Team1ID:           t1ID,
-		Team1Score:        t1Score,
-		Team2ID:           t2ID,
-		Team2Score:        t2Score,
-		Name:              match.Name,
-		ExpectedStartTime: match.ScheduledAt,
-		AmountOfGames:     match.NumberOfGames,
-		ActualGameTime:    actualGT,
-		StreamURL:         streamURL,
+		ID:                  match.ID,
+		Slug:                match.Slug,
+		Finished:            match.EndAt != time.Time{},
+		GameID:              match.Videogame.ID,
+		LeagueID:            match.League.ID,
+		SerieID:             match.Serie.ID,
+		TournamentID:        match.Tournament.ID,
+		Team1ID:             t1ID,
+		Team1Score:          t1Score,
+		Team2ID:             t2ID,
+		Team2Score:          t2Score,
+		Name:                match.Name,
+		ExpectedStartTime:   match.ScheduledAt,
+		AmountOfGames:       match.NumberOfGames,
+		ActualGameTime:      actualGT,
+		StreamURL:           streamURL,
+		Status:              match.Status,
+		Forfeit:             match.Forfeit,
+		Draw:                match.Draw,
+		Rescheduled:         match.Rescheduled,
+		OriginalScheduledAt: match.OriginalScheduledAt,
+		BeginAt:             match.BeginAt,
+		EndAt:               match.EndAt,
+		WinnerID:            match.WinnerID,
+		WinnerType:          match.WinnerType,
+		ModifiedAt:          match.ModifiedAt,
+		Opponents:           opponents,
 	}
 }
 
-func (row MatchRow) WriteToDB(ctx context.Context, db *dbtypes.Queries) error {
+func (row MatchRow) WriteToDB(ctx context.Context, sink Writer) (bool, error) {
+	return sink.UpsertMatch(ctx, row)
+}
+
+// ToParams converts the row into the sqlc parameters of InsertToMatches.
+// @returns the parameters and an error if any integer overflows int32.
+func (row MatchRow) ToParams() (dbtypes.InsertToMatchesParams, error) {
 	id, err := SafeIntToInt32(row.ID)
 	if err != nil {
-		return err
+		return dbtypes.InsertToMatchesParams{}, err
 	}
 	team1Id, err := SafeIntToInt32(row.Team1ID)
 	if err != nil {
-		return err
+		return dbtypes.InsertToMatchesParams{}, err
 	}
 	team2Id, err := SafeIntToInt32(row.Team2ID)
 	if err != nil {
-		return err
+		return dbtypes.InsertToMatchesParams{}, err
 	}
 	team1Score, err := SafeIntToInt32(row.Team1Score)
 	if err != nil {
-		return err
+		return dbtypes.InsertToMatchesParams{}, err
 	}
 	team2Score, err := SafeIntToInt32(row.Team2Score)
 	if err != nil {
-		return err
+		return dbtypes.InsertToMatchesParams{}, err
 	}
 	gameAmount, err := SafeIntToInt32(row.AmountOfGames)
 	if err != nil {
-		return err
+		return dbtypes.InsertToMatchesParams{}, err
 	}
 	gameID, err := SafeIntToInt32(row.GameID)
 	if err != nil {
-		return err
+		return dbtypes.InsertToMatchesParams{}, err
 	}
 	leagueID, err := SafeIntToInt32(row.LeagueID)
 	if err != nil {
-		return err
+		return dbtypes.InsertToMatchesParams{}, err
 	}
 	serieID, err := SafeIntToInt32(row.SerieID)
 	if err != nil {
-		return err
+		return dbtypes.InsertToMatchesParams{}, err
 	}
 	tournamentID, err := SafeIntToInt32(row.TournamentID)
 	if err != nil {
-		return err
+		return dbtypes.InsertToMatchesParams{}, err
+	}
+	winnerID, err := SafeIntToInt32(row.WinnerID)
+	if err != nil {
+		return dbtypes.InsertToMatchesParams{}, err
+	}
+	return dbtypes.InsertToMatchesParams{
+		ID:                  id,
+		Name:                row.Name,
+		Slug:                pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
+		Finished:            row.Finished,
+		ExpectedStartTime:   toTimestamptz(row.ExpectedStartTime),
+		ActualGameTime:      row.ActualGameTime,
+		Team1ID:             team1Id,
+		Team1Score:          team1Score,
+		Team2ID:             team2Id,
+		Team2Score:          team2Score,
+		AmountOfGames:       gameAmount,
+		GameID:              gameID,
+		LeagueID:            leagueID,
+		SeriesID:            serieID,
+		TournamentID:        tournamentID,
+		StreamURL:           pgtype.Text{String: row.StreamURL, Valid: row.StreamURL != ""},
+		Status:              pgtype.Text{String: row.Status, Valid: row.Status != ""},
+		Forfeit:             row.Forfeit,
+		Draw:                row.Draw,
+		Rescheduled:         row.Rescheduled,
+		OriginalScheduledAt: toTimestamptz(row.OriginalScheduledAt),
+		BeginAt:             toTimestamptz(row.BeginAt),
+		EndAt:               toTimestamptz(row.EndAt),
+		WinnerID:            pgtype.Int4{Int32: winnerID, Valid: row.WinnerID != 0},
+		WinnerType:          pgtype.Text{String: row.WinnerType, Valid: row.WinnerType != ""},
+		ModifiedAt:          toTimestamptz(row.ModifiedAt),
+	}, nil
+}
+
+// toTimestamptz converts a decoded PandaScore time into a nullable pgtype.Timestamptz.
+// A zero time (JSON null or a missing field) is stored as NULL.
+func toTimestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{
+		Time:             t,
+		Valid:            !t.IsZero(),
+		InfinityModifier: 0,
 	}
-	err = db.InsertToMatches(ctx, dbtypes.InsertToMatchesParams{
-		ID:       id,
-		Name:     row.Name,
-		Slug:     pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
-		Finished: row.Finished,
-		ExpectedStartTime: pgtype.Timestamp{
-			Time:             row.ExpectedStartTime,
-			Valid:            !row.ExpectedStartTime.IsZero(),
-			InfinityModifier: 0,
-		},
-		ActualGameTime: row.ActualGameTime,
-		Team1ID:        team1Id,
-		Team1Score:     team1Score,
-		Team2ID:        team2Id,
-		Team2Score:     team2Score,
-		AmountOfGames:  gameAmount,
-		GameID:         gameID,
-		LeagueID:       leagueID,
-		SeriesID:       serieID,
-		TournamentID:   tournamentID,
-		StreamURL:      pgtype.Text{String: row.StreamURL, Valid: row.StreamURL != ""},
-	})
-	return err
 }
 
 type TeamRow struct {
-	ID        int
-	GameID    int
-	Name      string
-	Acronym   string
-	Slug      string
-	ImageLink string
+	ID         int
+	GameID     int
+	Name       string
+	Acronym    string
+	Slug       string
+	ImageLink  string
+	ModifiedAt time.Time
 }
 
 func (team TeamLike) ToRow() RowLike {
 	return TeamRow{
-		ID:        team.ID,
-		GameID:    team.CurrentVideogame.ID,
-		Name:      team.Name,
-		Acronym:   team.Acronym,
-		Slug:      team.Slug,
-		ImageLink: team.ImageURL,
+		ID:         team.ID,
+		GameID:     team.CurrentVideogame.ID,
+		Name:       team.Name,
+		Acronym:    team.Acronym,
+		Slug:       team.Slug,
+		ImageLink:  team.ImageURL,
+		ModifiedAt: team.ModifiedAt,
 	}
 }
 
-func (row TeamRow) WriteToDB(ctx context.Context, db *dbtypes.Queries) error {
+func (row TeamRow) WriteToDB(ctx context.Context, sink Writer) (bool, error) {
+	return sink.UpsertTeam(ctx, row)
+}
+
+// ToParams converts the row into the sqlc parameters of InsertToTeams.
+// @returns the parameters and an error if an ID overflows int32.
+func (row TeamRow) ToParams() (dbtypes.InsertToTeamsParams, error) {
 	id, err := SafeIntToInt32(row.ID)
 	if err != nil {
-		return err
+		return dbtypes.InsertToTeamsParams{}, err
 	}
 	gameID, err := SafeIntToInt32(row.GameID)
 	if err != nil {
-		return err
-	}
-	err = db.InsertToTeams(ctx, dbtypes.InsertToTeamsParams{
-		ID:        id,
-		Name:      row.Name,
-		Slug:      pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
-		Acronym:   pgtype.Text{String: row.Acronym, Valid: row.Acronym != ""},
-		ImageLink: pgtype.Text{String: row.ImageLink, Valid: row.ImageLink != ""},
-		GameID:    gameID,
-	})
-	return err
+		return dbtypes.InsertToTeamsParams{}, err
+	}
+	return dbtypes.InsertToTeamsParams{
+		ID:         id,
+		Name:       row.Name,
+		Slug:       pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
+		Acronym:    pgtype.Text{String: row.Acronym, Valid: row.Acronym != ""},
+		ImageLink:  pgtype.Text{String: row.ImageLink, Valid: row.ImageLink != ""},
+		GameID:     gameID,
+		ModifiedAt: toTimestamptz(row.ModifiedAt),
+	}, nil
 }
 
 // ExtractPrimaryStreamURL returns the primary stream URL from a PandaScore match response.
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/nbio/st"
 
@@ -200,6 +201,26 @@ func TestToRow(t *testing.T) {
 		st.Assert(t, matchR.LeagueID, match.League.ID)
 		st.Assert(t, matchR.SerieID, match.Serie.ID)
 		st.Assert(t, matchR.TournamentID, match.Tournament.ID)
+
+		// Assert the lifecycle fields
+		st.Assert(t, matchR.Status, MatchStatusFinished)
+		st.Assert(t, matchR.Forfeit, false)
+		st.Assert(t, matchR.Draw, false)
+		st.Assert(t, matchR.Rescheduled, false)
+		st.Assert(t, matchR.OriginalScheduledAt, match.OriginalScheduledAt)
+		st.Assert(t, matchR.BeginAt, match.BeginAt)
+		st.Assert(t, matchR.EndAt, match.EndAt)
+		st.Assert(t, matchR.WinnerID, 1575)
+		st.Assert(t, matchR.WinnerType, "Team")
+
+		// a canceled, rescheduled match keeps its status and original schedule
+		match.Status = MatchStatusCanceled
+		match.Rescheduled = true
+		match.Forfeit = true
+		matchR = match.ToRow().(MatchRow)
+		st.Assert(t, matchR.Status, MatchStatusCanceled)
+		st.Assert(t, matchR.Rescheduled, true)
+		st.Assert(t, matchR.Forfeit, true)
 	})
 
 }
@@ -209,7 +230,7 @@ func TestWriteToDB(t *testing.T) {
 	mockDB, err := pgxmock.NewPool()
 	st.Assert(t, err, nil)
 	defer mockDB.Close()
-	mockQuery := dbtypes.New(mockDB)
+	sink := NewPostgresSink(dbtypes.New(mockDB), nil)
 	t.Run("Write Game", func(t *testing.T) {
 		// open the file static/fetch_data/videogames.json with os.readfile
 		data, err := os.ReadFile("../static/fetch_data/videogames.json")
@@ -230,13 +251,13 @@ func TestWriteToDB(t *testing.T) {
 			WillReturnResult(
 				pgxmock.NewResult("INSERT", 1),
 			)
-		err = row.WriteToDB(t.Context(), mockQuery)
+		_, err = row.WriteToDB(t.Context(), sink)
 		st.Expect(t, err, nil)
 		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
 
 		// this following test should fail because the game ID is out of range
 		game.ID = math.MaxInt32 + 1
-		err = game.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = game.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 	})
 
@@ -262,23 +283,24 @@ func TestWriteToDB(t *testing.T) {
 					Valid:  true,
 				},
 				int32(league.Videogame.ID),
+				toTimestamptz(league.ModifiedAt),
 			).
 			WillReturnResult(
 				pgxmock.NewResult("INSERT", 1),
 			)
-		err = row.WriteToDB(t.Context(), mockQuery)
+		_, err = row.WriteToDB(t.Context(), sink)
 		st.Expect(t, err, nil)
 		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
 
 		// this should fail because the game ID is out of range
 		league.Videogame.ID = math.MaxInt32 + 1
-		err = league.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = league.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		league.Videogame.ID = 1
 
 		//this should fail because the league ID is out of range
 		league.ID = math.MaxInt32 + 1
-		err = league.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = league.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		league.ID = 1
 	})
@@ -303,28 +325,29 @@ func TestWriteToDB(t *testing.T) {
 				},
 				int32(series.Videogame.ID),
 				int32(series.League.ID),
+				toTimestamptz(series.ModifiedAt),
 			).
 			WillReturnResult(
 				pgxmock.NewResult("INSERT", 1),
 			)
-		err = row.WriteToDB(t.Context(), mockQuery)
+		_, err = row.WriteToDB(t.Context(), sink)
 		st.Expect(t, err, nil)
 		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
 
 		//this should fail because gameID is out of range
 		series.Videogame.ID = math.MaxInt32 + 1
-		err = series.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = series.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		series.Videogame.ID = 1
 
 		// this should fail because the league ID is out of range
 		series.League.ID = math.MaxInt32 + 1
-		err = series.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = series.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		series.League.ID = 1
 		// this following test should fail because the series ID is out of range
 		series.ID = math.MaxInt32 + 1
-		err = series.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = series.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		series.ID = 1
 	})
@@ -353,37 +376,38 @@ func TestWriteToDB(t *testing.T) {
 				int32(tournament.Videogame.ID),
 				int32(tournament.League.ID),
 				int32(tournament.Serie.ID),
+				toTimestamptz(tournament.ModifiedAt),
 			).
 			WillReturnResult(
 				pgxmock.NewResult("INSERT", 1),
 			)
-		err = row.WriteToDB(t.Context(), mockQuery)
+		_, err = row.WriteToDB(t.Context(), sink)
 		st.Expect(t, err, nil)
 		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
 
 		// this following test should fail because the tournament ID is out of range
 		tournament.ID = math.MaxInt32 + 1
-		err = tournament.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = tournament.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		tournament.ID = 1
 
 		//this should fail because gameID is out of range
 		tournament.Videogame.ID = math.MaxInt32 + 1
-		err = tournament.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = tournament.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		tournament.Videogame.ID = 1
 		temp := tournament.League.ID
 
 		// this should fail because the league ID is out of range
 		tournament.League.ID = math.MaxInt32 + 1
-		err = tournament.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = tournament.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 
 		tournament.League.ID = temp
 
 		//this should fail because serie ID is out of range
 		tournament.Serie.ID = math.MaxInt32 + 1
-		err = tournament.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = tournament.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 	})
 
@@ -397,6 +421,11 @@ func TestWriteToDB(t *testing.T) {
 		st.Assert(t, err, nil)
 		row := match.ToRow()
 
+		// the match has never been stored, so there is nothing to diff against
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").
+			WithArgs(int32(match.ID)).
+			WillReturnError(pgx.ErrNoRows)
+		expectArchived(mockDB, []int32{int32(match.ID)})
 		mockDB.ExpectExec("INSERT INTO matches").
 			WithArgs(
 				int32(match.ID),
@@ -406,7 +435,7 @@ func TestWriteToDB(t *testing.T) {
 					Valid:  true,
 				},
 				match.EndAt != time.Time{},
-				pgtype.Timestamp{Time: match.BeginAt, Valid: true},
+				pgtype.Timestamptz{Time: match.BeginAt, Valid: true},
 				match.EndAt.Sub(match.BeginAt).Seconds()/float64(match.NumberOfGames),
 				int32(match.Opponents[0].Opponent.ID),
 				int32(match.Results[0].Score),
@@ -418,78 +447,107 @@ func TestWriteToDB(t *testing.T) {
 				int32(match.SerieID),
 				int32(match.TournamentID),
 				pgtype.Text{String: "", Valid: false},
+				pgtype.Text{String: MatchStatusFinished, Valid: true},
+				false,
+				false,
+				false,
+				pgtype.Timestamptz{Time: match.OriginalScheduledAt, Valid: true},
+				pgtype.Timestamptz{Time: match.BeginAt, Valid: true},
+				pgtype.Timestamptz{Time: match.EndAt, Valid: true},
+				pgtype.Int4{Int32: int32(match.WinnerID), Valid: true},
+				pgtype.Text{String: "Team", Valid: true},
+				pgtype.Timestamptz{Time: match.ModifiedAt, Valid: true},
 			).
 			WillReturnResult(
 				pgxmock.NewResult("INSERT", 1),
 			)
-
-		err = row.WriteToDB(t.Context(), mockQuery)
+		mockDB.ExpectExec("INSERT INTO match_opponents").
+			WithArgs(int32(match.ID), int32(0), OpponentTypeTeam, int32(match.Opponents[0].Opponent.ID),
+				int32(match.Results[0].Score), pgtype.Int4{Int32: 1, Valid: true}).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDB.ExpectExec("INSERT INTO match_opponents").
+			WithArgs(int32(match.ID), int32(1), OpponentTypeTeam, int32(match.Opponents[1].Opponent.ID),
+				int32(match.Results[1].Score), pgtype.Int4{Int32: 2, Valid: true}).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDB.ExpectExec("DELETE FROM match_opponents").
+			WithArgs(int32(match.ID), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+		expectOutbox(mockDB, int32(match.ID), EventMatchCreated, EventMatchFinished)
+		expectNotify(mockDB, ChannelMatchesChanged, pgxmock.AnyArg())
+
+		_, err = row.WriteToDB(t.Context(), sink)
 		st.Expect(t, err, nil)
 		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
 
 		//this should fail because the match id is out of range
 		temp := match.ID
 		match.ID = math.MaxInt32 + 1
-		err = match.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = match.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		match.ID = temp
 
 		// the following tests fail because the team id and score are out of range
 		temp = match.Opponents[0].Opponent.ID
 		match.Opponents[0].Opponent.ID = math.MaxInt32 + 1
-		err = match.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = match.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		match.Opponents[0].Opponent.ID = temp
 
 		temp = match.Results[0].Score
 		match.Results[0].Score = math.MaxInt32 + 1
-		err = match.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = match.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		match.Results[0].Score = temp
 
 		temp = match.Opponents[1].Opponent.ID
 		match.Opponents[1].Opponent.ID = math.MaxInt32 + 1
-		err = match.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = match.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		match.Opponents[1].Opponent.ID = temp
 
 		temp = match.Results[1].Score
 		match.Results[1].Score = math.MaxInt32 + 1
-		err = match.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = match.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		match.Results[1].Score = temp
 
 		//the following should fail because the amount of games is out of range
 		temp = match.NumberOfGames
 		match.NumberOfGames = math.MaxInt32 + 1
-		err = match.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = match.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		match.NumberOfGames = temp
 
 		// the following should fail because any of the IDs are out of ragne
 		temp = match.Videogame.ID
 		match.Videogame.ID = math.MaxInt32 + 1
-		err = match.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = match.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		match.Videogame.ID = temp
 
 		temp = match.League.ID
 		match.League.ID = math.MaxInt32 + 1
-		err = match.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = match.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		match.League.ID = temp
 
 		temp = match.Serie.ID
 		match.Serie.ID = math.MaxInt32 + 1
-		err = match.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = match.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		match.Serie.ID = temp
 
 		temp = match.Tournament.ID
 		match.Tournament.ID = math.MaxInt32 + 1
-		err = match.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = match.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		match.Tournament.ID = temp
+
+		temp = match.WinnerID
+		match.WinnerID = math.MaxInt32 + 1
+		_, err = match.ToRow().WriteToDB(t.Context(), sink)
+		st.Reject(t, err, nil)
+		match.WinnerID = temp
 	})
 
 	t.Run("Write Team", func(t *testing.T) {
@@ -518,23 +576,24 @@ func TestWriteToDB(t *testing.T) {
 					Valid:  team.ImageURL != "",
 				},
 				int32(team.CurrentVideogame.ID),
+				toTimestamptz(team.ModifiedAt),
 			).
 			WillReturnResult(
 				pgxmock.NewResult("INSERT", 1),
 			)
-		err = row.WriteToDB(t.Context(), mockQuery)
+		_, err = row.WriteToDB(t.Context(), sink)
 		st.Expect(t, err, nil)
 		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
 
 		// this following test should fail because the team ID is out of range
 		team.ID = math.MaxInt32 + 1
-		err = team.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = team.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 		team.ID = 1
 
 		//the following test should fail because the gameID is out of range
 		team.CurrentVideogame.ID = math.MaxInt32 + 1
-		err = team.ToRow().WriteToDB(t.Context(), mockQuery)
+		_, err = team.ToRow().WriteToDB(t.Context(), sink)
 		st.Reject(t, err, nil)
 	})
 }
@@ -0,0 +1,74 @@
+package pandatypes
+
+import (
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// SeenRefreshInterval is how long an unchanged row may go without being
+// rewritten. It matches the INTERVAL of the upserts in static/queries.sql and
+// keeps last_seen_at well inside the reconciliation window, so rows PandaScore
+// still serves are never mistaken for stale ones.
+const SeenRefreshInterval = 24 * time.Hour
+
+// WriteCounts tallies the rows of a write by outcome.
+type WriteCounts struct {
+	// Updated counts the rows that were inserted or updated.
+	Updated int
+	// Skipped counts the rows left untouched because the stored row was at least as new.
+	Skipped int
+	// Refreshed counts the unchanged rows that were rewritten only because they
+	// were not seen within SeenRefreshInterval.
+	Refreshed int
+}
+
+// Count records the outcome of a single row.
+// @param written - whether the row was inserted or updated.
+func (counts *WriteCounts) Count(written bool) {
+	if written {
+		counts.Updated++
+	} else {
+		counts.Skipped++
+	}
+}
+
+// Add merges other into counts.
+func (counts *WriteCounts) Add(other WriteCounts) {
+	counts.Updated += other.Updated
+	counts.Skipped += other.Skipped
+	counts.Refreshed += other.Refreshed
+}
+
+// countMatch returns the counts of a single match write.
+// @param written - whether the match was written.
+// @param refreshed - whether the stored match was unchanged and only rewritten to refresh last_seen_at.
+// @returns the counts holding the outcome.
+func countMatch(written, refreshed bool) WriteCounts {
+	var counts WriteCounts
+	switch {
+	case !written:
+		counts.Skipped++
+	case refreshed:
+		counts.Refreshed++
+	default:
+		counts.Updated++
+	}
+	return counts
+}
+
+// matchUnchanged reports whether the stored row is not tombstoned and at least
+// as new as the incoming row. Rows without a modified_at on either side always
+// count as changed.
+func matchUnchanged(stored dbtypes.Match, incoming pgtype.Timestamptz) bool {
+	return !stored.DeletedAt.Valid && stored.ModifiedAt.Valid && incoming.Valid &&
+		!incoming.Time.After(stored.ModifiedAt.Time)
+}
+
+// matchUpToDate mirrors the WHERE clause of the upserts: a stored row is left
+// untouched when it is unchanged and was seen within SeenRefreshInterval.
+func matchUpToDate(stored dbtypes.Match, incoming pgtype.Timestamptz, now time.Time) bool {
+	return matchUnchanged(stored, incoming) &&
+		stored.LastSeenAt.Valid && !stored.LastSeenAt.Time.Before(now.Add(-SeenRefreshInterval))
+}
@@ -0,0 +1,78 @@
+package pandatypes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestMatchUpToDate(t *testing.T) {
+	now := time.Now()
+	modified := pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true, InfinityModifier: 0}
+	stored := dbtypes.Match{
+		ModifiedAt: modified,
+		LastSeenAt: pgtype.Timestamptz{Time: now.Add(-time.Minute), Valid: true, InfinityModifier: 0},
+	}
+	newer := pgtype.Timestamptz{Time: now, Valid: true, InfinityModifier: 0}
+
+	st.Expect(t, matchUpToDate(stored, modified, now), true)
+	st.Expect(t, matchUpToDate(stored, newer, now), false)
+	st.Expect(t, matchUpToDate(stored, pgtype.Timestamptz{}, now), false)
+
+	t.Run("Rows without modified_at are always written", func(t *testing.T) {
+		legacy := stored
+		legacy.ModifiedAt = pgtype.Timestamptz{}
+		st.Expect(t, matchUpToDate(legacy, modified, now), false)
+	})
+
+	t.Run("Tombstoned rows are resurrected", func(t *testing.T) {
+		tombstoned := stored
+		tombstoned.DeletedAt = newer
+		st.Expect(t, matchUpToDate(tombstoned, modified, now), false)
+	})
+
+	t.Run("Rows unseen for too long are refreshed", func(t *testing.T) {
+		unseen := stored
+		unseen.LastSeenAt.Time = now.Add(-SeenRefreshInterval - time.Minute)
+		st.Expect(t, matchUpToDate(unseen, modified, now), false)
+		st.Expect(t, matchUnchanged(unseen, modified), true)
+		st.Expect(t, matchUnchanged(unseen, newer), false)
+	})
+}
+
+func TestWriteCounts(t *testing.T) {
+	var counts WriteCounts
+	counts.Count(true)
+	counts.Count(false)
+	counts.Count(false)
+	counts.Add(WriteCounts{Updated: 2, Skipped: 1, Refreshed: 0})
+	counts.Add(countMatch(true, true))
+	st.Expect(t, counts, WriteCounts{Updated: 3, Skipped: 3, Refreshed: 1})
+}
+
+func TestWriteToDBReportsSkippedRows(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	sink := NewPostgresSink(dbtypes.New(mockDB), nil)
+
+	// the upsert leaves a row that is at least as new untouched
+	mockDB.ExpectExec("INSERT INTO leagues .+ WHERE leagues.deleted_at IS NOT NULL").
+		WithArgs(anyArgs(6)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	written, err := LeagueRow{ID: 1, Name: "LCK", GameID: 1, ModifiedAt: time.Now()}.WriteToDB(t.Context(), sink)
+	st.Expect(t, err, nil)
+	st.Expect(t, written, false)
+
+	mockDB.ExpectExec("INSERT INTO teams").
+		WithArgs(anyArgs(7)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	written, err = TeamRow{ID: 1, Name: "T1", GameID: 1}.WriteToDB(t.Context(), sink)
+	st.Expect(t, err, nil)
+	st.Expect(t, written, true)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
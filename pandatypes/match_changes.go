@@ -0,0 +1,116 @@
+package pandatypes
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Tracked MATCH_CHANGES field names. They mirror the MATCHES column names so
+// consumers can map a change back onto the row.
+const (
+	FieldName              = "name"
+	FieldStatus            = "status"
+	FieldExpectedStartTime = "expected_start_time"
+	FieldTeam1ID           = "team1_id"
+	FieldTeam1Score        = "team1_score"
+	FieldTeam2ID           = "team2_id"
+	FieldTeam2Score        = "team2_score"
+	FieldAmountOfGames     = "amount_of_games"
+	FieldStreamURL         = "stream_url"
+	FieldForfeit           = "forfeit"
+	FieldDraw              = "draw"
+	FieldWinnerID          = "winner_id"
+)
+
+// MatchChange is a single tracked field that differs between the stored match
+// and the incoming one. An empty value stands for NULL.
+type MatchChange struct {
+	Field    string
+	OldValue string
+	NewValue string
+}
+
+// DiffMatch compares the stored match against the parameters about to be upserted.
+// @param stored - the match currently in the database.
+// @param incoming - the parameters that will overwrite it.
+// @returns the changed fields in a stable order, empty if nothing tracked changed.
+func DiffMatch(stored dbtypes.Match, incoming dbtypes.InsertToMatchesParams) []MatchChange {
+	pairs := []MatchChange{
+		{Field: FieldName, OldValue: stored.Name, NewValue: incoming.Name},
+		{Field: FieldStatus, OldValue: textValue(stored.Status), NewValue: textValue(incoming.Status)},
+		{
+			Field:    FieldExpectedStartTime,
+			OldValue: timestampValue(stored.ExpectedStartTime),
+			NewValue: timestampValue(incoming.ExpectedStartTime),
+		},
+		{Field: FieldTeam1ID, OldValue: int32Value(stored.Team1ID), NewValue: int32Value(incoming.Team1ID)},
+		{Field: FieldTeam1Score, OldValue: int32Value(stored.Team1Score), NewValue: int32Value(incoming.Team1Score)},
+		{Field: FieldTeam2ID, OldValue: int32Value(stored.Team2ID), NewValue: int32Value(incoming.Team2ID)},
+		{Field: FieldTeam2Score, OldValue: int32Value(stored.Team2Score), NewValue: int32Value(incoming.Team2Score)},
+		{
+			Field:    FieldAmountOfGames,
+			OldValue: int32Value(stored.AmountOfGames),
+			NewValue: int32Value(incoming.AmountOfGames),
+		},
+		{Field: FieldStreamURL, OldValue: textValue(stored.StreamURL), NewValue: textValue(incoming.StreamURL)},
+		{Field: FieldForfeit, OldValue: strconv.FormatBool(stored.Forfeit), NewValue: strconv.FormatBool(incoming.Forfeit)},
+		{Field: FieldDraw, OldValue: strconv.FormatBool(stored.Draw), NewValue: strconv.FormatBool(incoming.Draw)},
+		{Field: FieldWinnerID, OldValue: int4Value(stored.WinnerID), NewValue: int4Value(incoming.WinnerID)},
+	}
+	var changes []MatchChange
+	for _, pair := range pairs {
+		if pair.OldValue != pair.NewValue {
+			changes = append(changes, pair)
+		}
+	}
+	return changes
+}
+
+// RecordMatchChanges writes the detected changes of one match to MATCH_CHANGES.
+// @param matchID - the ID of the match the changes belong to.
+// @param changes - the changes returned by DiffMatch.
+// @returns an error if one of the inserts fails.
+func RecordMatchChanges(ctx context.Context, db *dbtypes.Queries, matchID int32, changes []MatchChange) error {
+	for _, change := range changes {
+		err := db.InsertMatchChange(ctx, dbtypes.InsertMatchChangeParams{
+			MatchID:  matchID,
+			Field:    change.Field,
+			OldValue: pgtype.Text{String: change.OldValue, Valid: change.OldValue != ""},
+			NewValue: pgtype.Text{String: change.NewValue, Valid: change.NewValue != ""},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func textValue(value pgtype.Text) string {
+	if !value.Valid {
+		return ""
+	}
+	return value.String
+}
+
+// timestampValue formats timestamps as RFC3339 in UTC so old and new values compare textually.
+func timestampValue(value pgtype.Timestamptz) string {
+	if !value.Valid {
+		return ""
+	}
+	return value.Time.UTC().Format(time.RFC3339)
+}
+
+func int32Value(value int32) string {
+	return strconv.FormatInt(int64(value), 10)
+}
+
+func int4Value(value pgtype.Int4) string {
+	if !value.Valid {
+		return ""
+	}
+	return int32Value(value.Int32)
+}
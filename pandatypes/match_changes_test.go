@@ -0,0 +1,297 @@
+package pandatypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var matchColumns = []string{
+	"id", "name", "slug", "finished", "expected_start_time", "actual_game_time",
+	"team1_id", "team1_score", "team2_id", "team2_score", "amount_of_games", "is_live",
+	"stream_url", "status", "forfeit", "draw", "rescheduled", "original_scheduled_at",
+	"begin_at", "end_at", "winner_id", "winner_type", "game_id", "league_id", "series_id", "tournament_id",
+	"last_seen_at", "deleted_at", "modified_at",
+}
+
+// matchInsertArgs is the number of parameters InsertToMatches binds.
+const matchInsertArgs = 26
+
+// storedMatch converts upsert parameters into the row that GetMatchByID would return.
+func storedMatch(params dbtypes.InsertToMatchesParams) dbtypes.Match {
+	return dbtypes.Match{
+		ID:                  params.ID,
+		Name:                params.Name,
+		Slug:                params.Slug,
+		Finished:            params.Finished,
+		ExpectedStartTime:   params.ExpectedStartTime,
+		ActualGameTime:      params.ActualGameTime,
+		Team1ID:             params.Team1ID,
+		Team1Score:          params.Team1Score,
+		Team2ID:             params.Team2ID,
+		Team2Score:          params.Team2Score,
+		AmountOfGames:       params.AmountOfGames,
+		IsLive:              false,
+		StreamURL:           params.StreamURL,
+		Status:              params.Status,
+		Forfeit:             params.Forfeit,
+		Draw:                params.Draw,
+		Rescheduled:         params.Rescheduled,
+		OriginalScheduledAt: params.OriginalScheduledAt,
+		BeginAt:             params.BeginAt,
+		EndAt:               params.EndAt,
+		WinnerID:            params.WinnerID,
+		WinnerType:          params.WinnerType,
+		GameID:              params.GameID,
+		LeagueID:            params.LeagueID,
+		SeriesID:            params.SeriesID,
+		TournamentID:        params.TournamentID,
+		LastSeenAt:          pgtype.Timestamptz{Time: time.Now(), Valid: true, InfinityModifier: 0},
+		DeletedAt:           pgtype.Timestamptz{Time: time.Time{}, Valid: false, InfinityModifier: 0},
+	}
+}
+
+func matchRows(matches ...dbtypes.Match) *pgxmock.Rows {
+	rows := pgxmock.NewRows(matchColumns)
+	for _, m := range matches {
+		rows.AddRow(
+			m.ID, m.Name, m.Slug, m.Finished, m.ExpectedStartTime, m.ActualGameTime,
+			m.Team1ID, m.Team1Score, m.Team2ID, m.Team2Score, m.AmountOfGames, m.IsLive,
+			m.StreamURL, m.Status, m.Forfeit, m.Draw, m.Rescheduled, m.OriginalScheduledAt,
+			m.BeginAt, m.EndAt, m.WinnerID, m.WinnerType, m.GameID, m.LeagueID, m.SeriesID, m.TournamentID,
+			m.LastSeenAt, m.DeletedAt, m.ModifiedAt,
+		)
+	}
+	return rows
+}
+
+// archivedColumns are the columns of MATCHES_ARCHIVE, the match columns first.
+var archivedColumns = append(append([]string{}, matchColumns...), "played_at", "opponents", "changes", "archived_at")
+
+// expectArchived registers the archive lookup of matches missing from MATCHES.
+// @param ids - the IDs looked up, or a matcher of them.
+// @param matches - the archived copies found.
+func expectArchived(mockDB pgxmock.PgxPoolIface, ids any, matches ...dbtypes.Match) {
+	rows := pgxmock.NewRows(archivedColumns)
+	for _, m := range matches {
+		rows.AddRow(
+			m.ID, m.Name, m.Slug, m.Finished, m.ExpectedStartTime, m.ActualGameTime,
+			m.Team1ID, m.Team1Score, m.Team2ID, m.Team2Score, m.AmountOfGames, m.IsLive,
+			m.StreamURL, m.Status, m.Forfeit, m.Draw, m.Rescheduled, m.OriginalScheduledAt,
+			m.BeginAt, m.EndAt, m.WinnerID, m.WinnerType, m.GameID, m.LeagueID, m.SeriesID, m.TournamentID,
+			m.LastSeenAt, m.DeletedAt, m.ModifiedAt, m.EndAt, []byte("[]"), []byte("[]"), m.LastSeenAt,
+		)
+	}
+	mockDB.ExpectQuery("SELECT DISTINCT ON \\(id\\) .+ FROM matches_archive").WithArgs(ids).WillReturnRows(rows)
+}
+
+// anyArgs builds n pgxmock.AnyArg matchers for wide inserts.
+func anyArgs(n int) []any {
+	args := make([]any, n)
+	for i := range args {
+		args[i] = pgxmock.AnyArg()
+	}
+	return args
+}
+
+// expectOpponents registers the MATCH_OPPONENTS writes of a match with n opponents.
+func expectOpponents(mockDB pgxmock.PgxPoolIface, matchID int32, n int) {
+	for range n {
+		mockDB.ExpectExec("INSERT INTO match_opponents").
+			WithArgs(anyArgs(6)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+	mockDB.ExpectExec("DELETE FROM match_opponents").
+		WithArgs(matchID, int32(n)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+}
+
+func loadMatchParams(t *testing.T) dbtypes.InsertToMatchesParams {
+	data, err := os.ReadFile("../static/fetch_data/matches.json")
+	st.Assert(t, err, nil)
+	var match MatchLike
+	err = json.Unmarshal(data, &match)
+	st.Assert(t, err, nil)
+	params, err := match.ToRow().(MatchRow).ToParams()
+	st.Assert(t, err, nil)
+	return params
+}
+
+func TestDiffMatch(t *testing.T) {
+	params := loadMatchParams(t)
+
+	t.Run("No changes", func(t *testing.T) {
+		st.Expect(t, len(DiffMatch(storedMatch(params), params)), 0)
+	})
+
+	t.Run("Untracked fields are ignored", func(t *testing.T) {
+		incoming := params
+		incoming.ActualGameTime = 1
+		incoming.Slug = pgtype.Text{String: "other", Valid: true}
+		st.Expect(t, len(DiffMatch(storedMatch(params), incoming)), 0)
+	})
+
+	t.Run("Reschedule and score change", func(t *testing.T) {
+		incoming := params
+		incoming.ExpectedStartTime = pgtype.Timestamptz{
+			Time:  params.ExpectedStartTime.Time.Add(2 * time.Hour),
+			Valid: true,
+		}
+		incoming.Team2Score = 1
+		changes := DiffMatch(storedMatch(params), incoming)
+		st.Assert(t, len(changes), 2)
+		st.Expect(t, changes[0], MatchChange{
+			Field:    FieldExpectedStartTime,
+			OldValue: "2014-06-01T14:00:00Z",
+			NewValue: "2014-06-01T16:00:00Z",
+		})
+		st.Expect(t, changes[1], MatchChange{Field: FieldTeam2Score, OldValue: "0", NewValue: "1"})
+	})
+
+	t.Run("NULL values become empty strings", func(t *testing.T) {
+		stored := storedMatch(params)
+		stored.StreamURL = pgtype.Text{String: "", Valid: false}
+		stored.WinnerID = pgtype.Int4{Int32: 0, Valid: false}
+		incoming := params
+		incoming.StreamURL = pgtype.Text{String: "https://twitch.tv/lcs", Valid: true}
+		changes := DiffMatch(stored, incoming)
+		st.Assert(t, len(changes), 2)
+		st.Expect(t, changes[0], MatchChange{Field: FieldStreamURL, OldValue: "", NewValue: "https://twitch.tv/lcs"})
+		st.Expect(t, changes[1], MatchChange{Field: FieldWinnerID, OldValue: "", NewValue: "1575"})
+	})
+}
+
+func TestMatchRowWriteToDBRecordsChanges(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	mockQuery := dbtypes.New(mockDB)
+	sink := NewPostgresSink(mockQuery, nil)
+
+	data, err := os.ReadFile("../static/fetch_data/matches.json")
+	st.Assert(t, err, nil)
+	var match MatchLike
+	err = json.Unmarshal(data, &match)
+	st.Assert(t, err, nil)
+	row := match.ToRow().(MatchRow)
+	params, err := row.ToParams()
+	st.Assert(t, err, nil)
+
+	t.Run("Status change is recorded", func(t *testing.T) {
+		stored := storedMatch(params)
+		stored.Status = pgtype.Text{String: MatchStatusRunning, Valid: true}
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").
+			WithArgs(params.ID).
+			WillReturnRows(matchRows(stored))
+		mockDB.ExpectExec("INSERT INTO matches").
+			WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectOpponents(mockDB, params.ID, 2)
+		mockDB.ExpectExec("INSERT INTO match_changes").
+			WithArgs(
+				params.ID,
+				FieldStatus,
+				pgtype.Text{String: MatchStatusRunning, Valid: true},
+				pgtype.Text{String: MatchStatusFinished, Valid: true},
+			).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectNotify(mockDB, ChannelMatchesChanged, fmt.Sprintf(`{"ids":[%d],"changes":{"status":[%[1]d]}}`, params.ID))
+
+		_, err = row.WriteToDB(t.Context(), sink)
+		st.Expect(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Unchanged match records nothing", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").
+			WithArgs(params.ID).
+			WillReturnRows(matchRows(storedMatch(params)))
+		mockDB.ExpectExec("INSERT INTO matches").
+			WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectOpponents(mockDB, params.ID, 2)
+		expectNotify(mockDB, ChannelMatchesChanged, fmt.Sprintf(`{"ids":[%d]}`, params.ID))
+
+		_, err = row.WriteToDB(t.Context(), sink)
+		st.Expect(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Up to date match is skipped", func(t *testing.T) {
+		stored := storedMatch(params)
+		stored.ModifiedAt = params.ModifiedAt
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").
+			WithArgs(params.ID).
+			WillReturnRows(matchRows(stored))
+
+		written, err := row.WriteToDB(t.Context(), sink)
+		st.Expect(t, err, nil)
+		st.Expect(t, written, false)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Newer version stored concurrently is not overwritten", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").
+			WithArgs(params.ID).
+			WillReturnError(pgx.ErrNoRows)
+		expectArchived(mockDB, []int32{params.ID})
+		mockDB.ExpectExec("INSERT INTO matches").
+			WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+		written, err := row.WriteToDB(t.Context(), sink)
+		st.Expect(t, err, nil)
+		st.Expect(t, written, false)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Finished match found in the archive is not created again", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").
+			WithArgs(params.ID).
+			WillReturnError(pgx.ErrNoRows)
+		expectArchived(mockDB, []int32{params.ID}, storedMatch(params))
+
+		written, err := row.WriteToDB(t.Context(), sink)
+		st.Expect(t, err, nil)
+		st.Expect(t, written, false)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Archive lookup failure aborts the write", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").
+			WithArgs(params.ID).
+			WillReturnError(pgx.ErrNoRows)
+		mockDB.ExpectQuery("FROM matches_archive").
+			WithArgs([]int32{params.ID}).
+			WillReturnError(fmt.Errorf("database error"))
+
+		_, err = row.WriteToDB(t.Context(), sink)
+		st.Reject(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Lookup failure aborts the write", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").
+			WithArgs(params.ID).
+			WillReturnError(fmt.Errorf("database error"))
+
+		_, err = row.WriteToDB(t.Context(), sink)
+		st.Reject(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Change insert failure is returned", func(t *testing.T) {
+		err = RecordMatchChanges(t.Context(), mockQuery, params.ID, []MatchChange{
+			{Field: FieldTeam1Score, OldValue: "1", NewValue: "2"},
+		})
+		st.Reject(t, err, nil)
+	})
+}
@@ -0,0 +1,154 @@
+package pandatypes
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+)
+
+// Channels stalka notifies on with pg_notify. NOTIFY is transactional, so a
+// listener only hears about writes once they are committed, and never about
+// the ones that were rolled back.
+const (
+	// ChannelMatchesChanged carries a MatchesChanged for every batch of written
+	// or tombstoned matches.
+	ChannelMatchesChanged = "matches_changed"
+	// ChannelLiveChanged carries a LiveChanged whenever matches start or stop being live.
+	ChannelLiveChanged = "live_changed"
+	// ChannelSyncCompleted carries a SyncCompleted after every sync job.
+	ChannelSyncCompleted = "sync_completed"
+)
+
+// MaxNotifyPayload keeps every payload below the 8000 byte limit of Postgres.
+// Longer ID lists are split over several notifications.
+const MaxNotifyPayload = 7900
+
+// Change kinds of MatchesChanged besides the tracked MATCH_CHANGES fields.
+const (
+	ChangeCreated  = "created"
+	ChangeFinished = "finished"
+	ChangeDeleted  = "deleted"
+)
+
+// MatchesChanged is the payload of ChannelMatchesChanged.
+type MatchesChanged struct {
+	// IDs lists every match that was written or tombstoned.
+	IDs []int32 `json:"ids"`
+	// Changes maps a change kind to the matches it applies to. Matches that
+	// were rewritten without a tracked change only appear in IDs.
+	Changes map[string][]int32 `json:"changes,omitempty"`
+}
+
+// LiveChanged is the payload of ChannelLiveChanged.
+type LiveChanged struct {
+	Started []int32 `json:"started,omitempty"`
+	Ended   []int32 `json:"ended,omitempty"`
+}
+
+// SyncCompleted is the payload of ChannelSyncCompleted.
+type SyncCompleted struct {
+	Job string `json:"job"`
+}
+
+// matchChangeSet collects the change kinds of the matches of one write.
+type matchChangeSet struct {
+	ids     []int32
+	changes map[string][]int32
+}
+
+// add records a written match together with the kinds of its changes.
+func (set *matchChangeSet) add(id int32, changes []MatchChange, events []OutboxEvent) {
+	set.ids = append(set.ids, id)
+	add := func(kind string) {
+		if set.changes == nil {
+			set.changes = make(map[string][]int32)
+		}
+		if !slices.Contains(set.changes[kind], id) {
+			set.changes[kind] = append(set.changes[kind], id)
+		}
+	}
+	for _, event := range events {
+		switch event.Type {
+		case EventMatchCreated:
+			add(ChangeCreated)
+		case EventMatchFinished:
+			add(ChangeFinished)
+		}
+	}
+	for _, change := range changes {
+		add(change.Field)
+	}
+}
+
+// payloads encodes the set as MatchesChanged payloads of at most MaxNotifyPayload bytes.
+func (set *matchChangeSet) payloads() [][]byte {
+	return chunkPayloads(set.ids, func(ids []int32) []byte {
+		payload := MatchesChanged{IDs: ids, Changes: nil}
+		for kind, kindIDs := range set.changes {
+			var inChunk []int32
+			for _, id := range kindIDs {
+				if slices.Contains(ids, id) {
+					inChunk = append(inChunk, id)
+				}
+			}
+			if len(inChunk) > 0 {
+				if payload.Changes == nil {
+					payload.Changes = make(map[string][]int32)
+				}
+				payload.Changes[kind] = inChunk
+			}
+		}
+		// Marshaling ID lists cannot fail.
+		data, _ := json.Marshal(payload)
+		return data
+	})
+}
+
+// chunkPayloads encodes ids, halving the list until every payload fits into
+// MaxNotifyPayload bytes.
+// @param ids - the IDs to notify about.
+// @param encode - encodes the payload of a part of ids.
+// @returns the payloads, none if ids is empty.
+func chunkPayloads(ids []int32, encode func([]int32) []byte) [][]byte {
+	if len(ids) == 0 {
+		return nil
+	}
+	data := encode(ids)
+	if len(data) <= MaxNotifyPayload || len(ids) == 1 {
+		return [][]byte{data}
+	}
+	half := len(ids) / 2
+	return append(chunkPayloads(ids[:half], encode), chunkPayloads(ids[half:], encode)...)
+}
+
+// notify sends the payloads on a channel, stopping at the first failure.
+func notify(ctx context.Context, db *dbtypes.Queries, channel string, payloads [][]byte) error {
+	for _, payload := range payloads {
+		err := db.Notify(ctx, dbtypes.NotifyParams{Channel: channel, Payload: string(payload)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyMatches sends the matches_changed notifications of a change set.
+func notifyMatches(ctx context.Context, db *dbtypes.Queries, set matchChangeSet) error {
+	return notify(ctx, db, ChannelMatchesChanged, set.payloads())
+}
+
+// liveChangedPayloads encodes the matches that started or stopped being live.
+func liveChangedPayloads(ids []int32, started bool) [][]byte {
+	return chunkPayloads(ids, func(chunk []int32) []byte {
+		payload := LiveChanged{Started: nil, Ended: nil}
+		if started {
+			payload.Started = chunk
+		} else {
+			payload.Ended = chunk
+		}
+		data, _ := json.Marshal(payload)
+		return data
+	})
+}
@@ -0,0 +1,118 @@
+package pandatypes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+// expectNotify registers a pg_notify call. payload is either the exact JSON or
+// pgxmock.AnyArg().
+func expectNotify(mockDB pgxmock.PgxPoolIface, channel string, payload any) {
+	mockDB.ExpectExec("SELECT pg_notify").
+		WithArgs(channel, payload).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+}
+
+func TestMatchChangeSetPayloads(t *testing.T) {
+	t.Run("Change kinds are grouped by kind", func(t *testing.T) {
+		var set matchChangeSet
+		set.add(1, []MatchChange{{Field: FieldStatus, OldValue: "running", NewValue: "finished"}},
+			[]OutboxEvent{{Type: EventMatchFinished, Data: nil}})
+		set.add(2, nil, []OutboxEvent{{Type: EventMatchCreated, Data: nil}})
+		set.add(3, nil, nil)
+		payloads := set.payloads()
+		st.Assert(t, len(payloads), 1)
+		st.Expect(t, string(payloads[0]),
+			`{"ids":[1,2,3],"changes":{"created":[2],"finished":[1],"status":[1]}}`)
+	})
+
+	t.Run("Rewrites without changes only list the IDs", func(t *testing.T) {
+		var set matchChangeSet
+		set.add(7, nil, nil)
+		st.Expect(t, string(set.payloads()[0]), `{"ids":[7]}`)
+	})
+
+	t.Run("Nothing written sends nothing", func(t *testing.T) {
+		var set matchChangeSet
+		st.Expect(t, len(set.payloads()), 0)
+	})
+
+	t.Run("Large sets are split below the payload limit", func(t *testing.T) {
+		var set matchChangeSet
+		for id := range int32(3000) {
+			set.add(1_000_000_000+id, []MatchChange{{Field: FieldStatus, OldValue: "", NewValue: "running"}}, nil)
+		}
+		payloads := set.payloads()
+		st.Expect(t, len(payloads) > 1, true)
+		seen := 0
+		for _, payload := range payloads {
+			st.Expect(t, len(payload) <= MaxNotifyPayload, true)
+			var decoded MatchesChanged
+			st.Assert(t, json.Unmarshal(payload, &decoded), nil)
+			st.Expect(t, decoded.Changes[FieldStatus], decoded.IDs)
+			seen += len(decoded.IDs)
+		}
+		st.Expect(t, seen, 3000)
+	})
+}
+
+func TestLiveChangedPayloads(t *testing.T) {
+	st.Expect(t, string(liveChangedPayloads([]int32{1, 2}, true)[0]), `{"started":[1,2]}`)
+	st.Expect(t, string(liveChangedPayloads([]int32{3}, false)[0]), `{"ended":[3]}`)
+	st.Expect(t, len(liveChangedPayloads(nil, true)), 0)
+}
+
+func TestPostgresSinkNotifications(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	sink := NewPostgresSink(dbtypes.New(mockDB), nil)
+
+	t.Run("Cleared live matches have ended", func(t *testing.T) {
+		mockDB.ExpectQuery("SET is_live = false WHERE is_live").
+			WithArgs([]int32{1}).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(4)).AddRow(int32(5)))
+		expectNotify(mockDB, ChannelLiveChanged, `{"ended":[4,5]}`)
+		st.Expect(t, sink.ClearLiveExcept(t.Context(), []int32{1}), nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("No change sends nothing", func(t *testing.T) {
+		mockDB.ExpectQuery("SET is_live = false WHERE is_live").
+			WithArgs([]int32{1}).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+		st.Expect(t, sink.ClearLiveExcept(t.Context(), []int32{1}), nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Tombstoned matches are deleted", func(t *testing.T) {
+		mockDB.ExpectExec("UPDATE matches SET deleted_at").
+			WithArgs(int32(9)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		expectNotify(mockDB, ChannelMatchesChanged, `{"ids":[9],"changes":{"deleted":[9]}}`)
+		st.Expect(t, sink.Tombstone(t.Context(), EntityMatch, 9), nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Completed syncs name their job", func(t *testing.T) {
+		expectNotify(mockDB, ChannelSyncCompleted, `{"job":"match update"}`)
+		st.Expect(t, sink.SyncCompleted(t.Context(), "match update"), nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - a failed notification fails the write", func(t *testing.T) {
+		mockDB.ExpectExec("SELECT pg_notify").
+			WithArgs(ChannelSyncCompleted, pgxmock.AnyArg()).
+			WillReturnError(errors.New("too many notifications in the NOTIFY queue"))
+		err := sink.SyncCompleted(t.Context(), "reconciliation")
+		st.Expect(t, strings.Contains(fmt.Sprint(err), "NOTIFY queue"), true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
@@ -0,0 +1,193 @@
+package pandatypes
+
+import (
+	"context"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Opponent types reported by PandaScore in the `type` field of a match opponent.
+const (
+	OpponentTypeTeam   = "Team"
+	OpponentTypePlayer = "Player"
+)
+
+// MatchOpponentRow is one opponent of a match as stored in MATCH_OPPONENTS.
+type MatchOpponentRow struct {
+	Type  string
+	ID    int
+	Slot  int
+	Score int
+	// Placement is the final rank of the opponent, 0 while the match is not finished.
+	Placement int
+}
+
+// PlayerLike mirrors the PandaScore player object.
+type PlayerLike struct {
+	ID               int       `json:"id"`
+	Name             string    `json:"name"`
+	FirstName        string    `json:"first_name"`
+	LastName         string    `json:"last_name"`
+	Nationality      string    `json:"nationality"`
+	Slug             string    `json:"slug"`
+	ModifiedAt       time.Time `json:"modified_at"`
+	ImageURL         string    `json:"image_url"`
+	CurrentVideogame struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	} `json:"current_videogame"`
+}
+
+type PlayerRow struct {
+	ID          int
+	GameID      int
+	Name        string
+	FirstName   string
+	LastName    string
+	Nationality string
+	Slug        string
+	ImageLink   string
+	ModifiedAt  time.Time
+}
+
+func (player PlayerLike) ToRow() RowLike {
+	return PlayerRow{
+		ID:          player.ID,
+		GameID:      player.CurrentVideogame.ID,
+		Name:        player.Name,
+		FirstName:   player.FirstName,
+		LastName:    player.LastName,
+		Nationality: player.Nationality,
+		Slug:        player.Slug,
+		ImageLink:   player.ImageURL,
+		ModifiedAt:  player.ModifiedAt,
+	}
+}
+
+func (row PlayerRow) WriteToDB(ctx context.Context, sink Writer) (bool, error) {
+	return sink.UpsertPlayer(ctx, row)
+}
+
+// ToParams converts the row into the sqlc parameters of InsertToPlayers.
+// @returns the parameters and an error if an ID overflows int32.
+func (row PlayerRow) ToParams() (dbtypes.InsertToPlayersParams, error) {
+	id, err := SafeIntToInt32(row.ID)
+	if err != nil {
+		return dbtypes.InsertToPlayersParams{}, err
+	}
+	gameID, err := SafeIntToInt32(row.GameID)
+	if err != nil {
+		return dbtypes.InsertToPlayersParams{}, err
+	}
+	return dbtypes.InsertToPlayersParams{
+		ID:          id,
+		Name:        row.Name,
+		Slug:        pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
+		FirstName:   pgtype.Text{String: row.FirstName, Valid: row.FirstName != ""},
+		LastName:    pgtype.Text{String: row.LastName, Valid: row.LastName != ""},
+		Nationality: pgtype.Text{String: row.Nationality, Valid: row.Nationality != ""},
+		ImageLink:   pgtype.Text{String: row.ImageLink, Valid: row.ImageLink != ""},
+		GameID:      gameID,
+		ModifiedAt:  toTimestamptz(row.ModifiedAt),
+	}, nil
+}
+
+// opponentRows converts the opponents of the match into MATCH_OPPONENTS rows.
+// Scores are looked up by opponent ID because PandaScore does not guarantee that
+// `results` is in the same order as `opponents`.
+func (match MatchLike) opponentRows() []MatchOpponentRow {
+	scores := make(map[int]int, len(match.Results))
+	for _, result := range match.Results {
+		if result.PlayerID != 0 {
+			scores[result.PlayerID] = result.Score
+		} else {
+			scores[result.TeamID] = result.Score
+		}
+	}
+	rows := make([]MatchOpponentRow, 0, len(match.Opponents))
+	for slot, opponent := range match.Opponents {
+		rows = append(rows, MatchOpponentRow{
+			Type:  opponent.Type,
+			ID:    opponent.Opponent.ID,
+			Slot:  slot,
+			Score: scores[opponent.Opponent.ID],
+		})
+	}
+	if !match.EndAt.IsZero() && match.Status != MatchStatusCanceled {
+		rankOpponents(rows, match.WinnerType, match.WinnerID)
+	}
+	return rows
+}
+
+// rankOpponents sets the placement of every opponent using competition ranking
+// ("1224"): higher scores rank first, and the declared winner breaks ties, which
+// covers forfeits that end 0-0.
+func rankOpponents(rows []MatchOpponentRow, winnerType string, winnerID int) {
+	isWinner := func(row MatchOpponentRow) bool {
+		return winnerID != 0 && row.ID == winnerID && row.Type == winnerType
+	}
+	for i := range rows {
+		placement := 1
+		for j := range rows {
+			ahead := rows[j].Score > rows[i].Score ||
+				(rows[j].Score == rows[i].Score && isWinner(rows[j]) && !isWinner(rows[i]))
+			if ahead {
+				placement++
+			}
+		}
+		rows[i].Placement = placement
+	}
+}
+
+// WriteMatchOpponents upserts the opponents of a match and drops slots that
+// PandaScore no longer reports, e.g. after a lobby shrank.
+// @param matchID - the ID of the match the opponents belong to.
+// @param opponents - the opponents in slot order.
+// @returns an error if one of the writes fails or an ID overflows int32.
+func WriteMatchOpponents(ctx context.Context, db *dbtypes.Queries, matchID int32, opponents []MatchOpponentRow) error {
+	for _, opponent := range opponents {
+		params, err := opponent.toParams(matchID)
+		if err != nil {
+			return err
+		}
+		err = db.InsertMatchOpponent(ctx, params)
+		if err != nil {
+			return err
+		}
+	}
+	count, err := SafeIntToInt32(len(opponents))
+	if err != nil {
+		return err
+	}
+	return db.TrimMatchOpponents(ctx, dbtypes.TrimMatchOpponentsParams{MatchID: matchID, Slot: count})
+}
+
+func (opponent MatchOpponentRow) toParams(matchID int32) (dbtypes.InsertMatchOpponentParams, error) {
+	slot, err := SafeIntToInt32(opponent.Slot)
+	if err != nil {
+		return dbtypes.InsertMatchOpponentParams{}, err
+	}
+	id, err := SafeIntToInt32(opponent.ID)
+	if err != nil {
+		return dbtypes.InsertMatchOpponentParams{}, err
+	}
+	score, err := SafeIntToInt32(opponent.Score)
+	if err != nil {
+		return dbtypes.InsertMatchOpponentParams{}, err
+	}
+	placement, err := SafeIntToInt32(opponent.Placement)
+	if err != nil {
+		return dbtypes.InsertMatchOpponentParams{}, err
+	}
+	return dbtypes.InsertMatchOpponentParams{
+		MatchID:      matchID,
+		Slot:         slot,
+		OpponentType: opponent.Type,
+		OpponentID:   id,
+		Score:        score,
+		Placement:    pgtype.Int4{Int32: placement, Valid: opponent.Placement != 0},
+	}, nil
+}
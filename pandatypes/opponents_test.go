@@ -0,0 +1,179 @@
+package pandatypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+// lobbyMatch builds a match with the given opponent type and scores in slot order.
+// Results are listed in reverse to check that scores are matched by ID.
+func lobbyMatch(t *testing.T, opponentType string, scores ...int) MatchLike {
+	data, err := os.ReadFile("../static/fetch_data/matches.json")
+	st.Assert(t, err, nil)
+	var match MatchLike
+	err = json.Unmarshal(data, &match)
+	st.Assert(t, err, nil)
+
+	opponent := match.Opponents[0]
+	result := match.Results[0]
+	match.Opponents = match.Opponents[:0]
+	match.Results = match.Results[:0]
+	for i := range scores {
+		opponent.Type = opponentType
+		opponent.Opponent.ID = 100 + i
+		match.Opponents = append(match.Opponents, opponent)
+	}
+	for i := len(scores) - 1; i >= 0; i-- {
+		result.TeamID = 0
+		result.PlayerID = 0
+		if opponentType == OpponentTypePlayer {
+			result.PlayerID = 100 + i
+		} else {
+			result.TeamID = 100 + i
+		}
+		result.Score = scores[i]
+		match.Results = append(match.Results, result)
+	}
+	match.WinnerType = opponentType
+	match.WinnerID = 0
+	return match
+}
+
+func placements(rows []MatchOpponentRow) []int {
+	out := make([]int, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, row.Placement)
+	}
+	return out
+}
+
+func TestMatchOpponentRows(t *testing.T) {
+	t.Run("Team match keeps team columns", func(t *testing.T) {
+		row := lobbyMatch(t, OpponentTypeTeam, 2, 1).ToRow().(MatchRow)
+		st.Expect(t, row.Team1ID, 100)
+		st.Expect(t, row.Team1Score, 2)
+		st.Expect(t, row.Team2ID, 101)
+		st.Expect(t, row.Team2Score, 1)
+		st.Expect(t, placements(row.Opponents), []int{1, 2})
+	})
+
+	t.Run("Player 1v1 uses opponents only", func(t *testing.T) {
+		match := lobbyMatch(t, OpponentTypePlayer, 1, 3)
+		match.WinnerID = 101
+		row := match.ToRow().(MatchRow)
+		st.Expect(t, row.Team1ID, 0)
+		st.Expect(t, row.Team2ID, 0)
+		st.Assert(t, len(row.Opponents), 2)
+		st.Expect(t, row.Opponents[0], MatchOpponentRow{Type: OpponentTypePlayer, ID: 100, Slot: 0, Score: 1, Placement: 2})
+		st.Expect(t, row.Opponents[1], MatchOpponentRow{Type: OpponentTypePlayer, ID: 101, Slot: 1, Score: 3, Placement: 1})
+	})
+
+	t.Run("Battle royale lobby shares tied placements", func(t *testing.T) {
+		row := lobbyMatch(t, OpponentTypeTeam, 10, 30, 10, 5).ToRow().(MatchRow)
+		st.Expect(t, row.Team1ID, 0)
+		st.Expect(t, placements(row.Opponents), []int{2, 1, 2, 4})
+	})
+
+	t.Run("Winner breaks a forfeit tie", func(t *testing.T) {
+		match := lobbyMatch(t, OpponentTypeTeam, 0, 0)
+		match.Forfeit = true
+		match.WinnerID = 101
+		st.Expect(t, placements(match.opponentRows()), []int{2, 1})
+	})
+
+	t.Run("Unfinished and canceled matches have no placement", func(t *testing.T) {
+		match := lobbyMatch(t, OpponentTypeTeam, 1, 0)
+		match.EndAt = time.Time{}
+		st.Expect(t, placements(match.opponentRows()), []int{0, 0})
+		match = lobbyMatch(t, OpponentTypeTeam, 1, 0)
+		match.Status = MatchStatusCanceled
+		st.Expect(t, placements(match.opponentRows()), []int{0, 0})
+	})
+}
+
+func TestWriteMatchOpponents(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	mockQuery := dbtypes.New(mockDB)
+
+	opponents := []MatchOpponentRow{
+		{Type: OpponentTypePlayer, ID: 7, Slot: 0, Score: 3, Placement: 1},
+		{Type: OpponentTypePlayer, ID: 8, Slot: 1, Score: 1, Placement: 0},
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockDB.ExpectExec("INSERT INTO match_opponents").
+			WithArgs(int32(5), int32(0), OpponentTypePlayer, int32(7), int32(3), pgtype.Int4{Int32: 1, Valid: true}).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDB.ExpectExec("INSERT INTO match_opponents").
+			WithArgs(int32(5), int32(1), OpponentTypePlayer, int32(8), int32(1), pgtype.Int4{Int32: 0, Valid: false}).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDB.ExpectExec("DELETE FROM match_opponents").
+			WithArgs(int32(5), int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		err = WriteMatchOpponents(t.Context(), mockQuery, 5, opponents)
+		st.Expect(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Insert failure stops the write", func(t *testing.T) {
+		mockDB.ExpectExec("INSERT INTO match_opponents").
+			WithArgs(anyArgs(6)...).
+			WillReturnError(fmt.Errorf("database error"))
+
+		err = WriteMatchOpponents(t.Context(), mockQuery, 5, opponents)
+		st.Reject(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Overflowing IDs are rejected", func(t *testing.T) {
+		err = WriteMatchOpponents(t.Context(), mockQuery, 5, []MatchOpponentRow{
+			{Type: OpponentTypeTeam, ID: math.MaxInt32 + 1},
+		})
+		st.Reject(t, err, nil)
+	})
+}
+
+func TestPlayerWriteToDB(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	sink := NewPostgresSink(dbtypes.New(mockDB), nil)
+
+	var player PlayerLike
+	err = json.Unmarshal([]byte(`{"id": 3, "name": "Tokido", "first_name": "Hajime", "last_name": "Taniguchi",
+		"nationality": "JP", "slug": "tokido", "image_url": null, "current_videogame": {"id": 30}}`), &player)
+	st.Assert(t, err, nil)
+
+	mockDB.ExpectExec("INSERT INTO players").
+		WithArgs(
+			int32(3),
+			"Tokido",
+			pgtype.Text{String: "tokido", Valid: true},
+			pgtype.Text{String: "Hajime", Valid: true},
+			pgtype.Text{String: "Taniguchi", Valid: true},
+			pgtype.Text{String: "JP", Valid: true},
+			pgtype.Text{String: "", Valid: false},
+			int32(30),
+			toTimestamptz(player.ModifiedAt),
+		).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	_, err = player.ToRow().WriteToDB(t.Context(), sink)
+	st.Expect(t, err, nil)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+
+	player.CurrentVideogame.ID = math.MaxInt32 + 1
+	_, err = player.ToRow().WriteToDB(t.Context(), sink)
+	st.Reject(t, err, nil)
+}
@@ -0,0 +1,80 @@
+package pandatypes
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+)
+
+// Event types of OUTBOX, see static/schema.sql. Live events are written by
+// MarkLive, the others by the match upserts.
+const (
+	EventMatchCreated     = "match_created"
+	EventMatchRescheduled = "match_rescheduled"
+	EventMatchLive        = "match_live"
+	EventMatchFinished    = "match_finished"
+)
+
+// OutboxEvent is an event about to be written to OUTBOX together with its match.
+type OutboxEvent struct {
+	Type string
+	// Data holds the details of the event as JSON, nil when there are none.
+	Data []byte
+}
+
+// Rescheduled is the data of a match_rescheduled event. A nil time means the
+// match had or has no expected start.
+type Rescheduled struct {
+	From *string `json:"from"`
+	To   *string `json:"to"`
+}
+
+// MatchEvents derives the outbox events of a match upsert.
+// @param stored - the stored match, nil if the match is new.
+// @param incoming - the parameters about to be upserted.
+// @param changes - the changes DiffMatch detected against stored.
+// @returns the events in the order they happened, empty if there are none.
+func MatchEvents(stored *dbtypes.Match, incoming dbtypes.InsertToMatchesParams, changes []MatchChange) []OutboxEvent {
+	var events []OutboxEvent
+	if stored == nil {
+		events = append(events, OutboxEvent{Type: EventMatchCreated, Data: nil})
+	}
+	for _, change := range changes {
+		if change.Field != FieldExpectedStartTime {
+			continue
+		}
+		// Marshaling two string pointers cannot fail.
+		data, _ := json.Marshal(Rescheduled{From: optional(change.OldValue), To: optional(change.NewValue)})
+		events = append(events, OutboxEvent{Type: EventMatchRescheduled, Data: data})
+	}
+	if incoming.Finished && (stored == nil || !stored.Finished) {
+		events = append(events, OutboxEvent{Type: EventMatchFinished, Data: nil})
+	}
+	return events
+}
+
+// RecordOutboxEvents writes the events of one match to OUTBOX.
+// @param matchID - the ID of the match the events belong to.
+// @param events - the events returned by MatchEvents.
+// @returns an error if one of the inserts fails.
+func RecordOutboxEvents(ctx context.Context, db *dbtypes.Queries, matchID int32, events []OutboxEvent) error {
+	for _, event := range events {
+		err := db.InsertOutboxEvent(ctx, dbtypes.InsertOutboxEventParams{
+			EventType: event.Type,
+			MatchID:   matchID,
+			Data:      event.Data,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
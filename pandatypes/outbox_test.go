@@ -0,0 +1,93 @@
+package pandatypes
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+// expectOutbox registers the OUTBOX writes of a match, one per event type.
+func expectOutbox(mockDB pgxmock.PgxPoolIface, matchID int32, eventTypes ...string) {
+	for _, eventType := range eventTypes {
+		mockDB.ExpectExec("INSERT INTO outbox").
+			WithArgs(eventType, matchID, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+}
+
+// expectOutboxBatch registers one OUTBOX batch holding the events of a match.
+func expectOutboxBatch(mockDB pgxmock.PgxPoolIface, matchID int32, eventTypes ...string) {
+	batch := mockDB.ExpectBatch()
+	for _, eventType := range eventTypes {
+		batch.ExpectExec("INSERT INTO outbox").
+			WithArgs(eventType, matchID, pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	}
+}
+
+func eventTypes(events []OutboxEvent) []string {
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestMatchEvents(t *testing.T) {
+	params := loadMatchParams(t)
+
+	t.Run("New matches are created", func(t *testing.T) {
+		upcoming := params
+		upcoming.Finished = false
+		st.Expect(t, eventTypes(MatchEvents(nil, upcoming, nil)), []string{EventMatchCreated})
+		st.Expect(t, eventTypes(MatchEvents(nil, params, nil)), []string{EventMatchCreated, EventMatchFinished})
+	})
+
+	t.Run("Rescheduled matches carry both start times", func(t *testing.T) {
+		stored := storedMatch(params)
+		stored.ExpectedStartTime = pgtype.Timestamptz{
+			Time: time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC), Valid: true, InfinityModifier: 0,
+		}
+		events := MatchEvents(&stored, params, DiffMatch(stored, params))
+		st.Assert(t, eventTypes(events), []string{EventMatchRescheduled})
+
+		var data Rescheduled
+		st.Assert(t, json.Unmarshal(events[0].Data, &data), nil)
+		st.Expect(t, *data.From, "2025-01-02T15:00:00Z")
+		st.Expect(t, *data.To, params.ExpectedStartTime.Time.UTC().Format(time.RFC3339))
+
+		stored.ExpectedStartTime = pgtype.Timestamptz{}
+		events = MatchEvents(&stored, params, DiffMatch(stored, params))
+		st.Assert(t, json.Unmarshal(events[0].Data, &data), nil)
+		st.Expect(t, data.From, (*string)(nil))
+	})
+
+	t.Run("Matches finish once", func(t *testing.T) {
+		stored := storedMatch(params)
+		stored.Finished = false
+		st.Expect(t, eventTypes(MatchEvents(&stored, params, nil)), []string{EventMatchFinished})
+
+		stored.Finished = true
+		st.Expect(t, len(MatchEvents(&stored, params, DiffMatch(stored, params))), 0)
+	})
+}
+
+func TestPostgresSinkMarkLive(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	sink := NewPostgresSink(dbtypes.New(mockDB), nil)
+
+	// match 1 was live already, so only match 2 gets an event and a notification
+	mockDB.ExpectQuery("(?s)UPDATE matches SET is_live = true.+INSERT INTO outbox").
+		WithArgs([]int32{1, 2}).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(2)))
+	expectNotify(mockDB, ChannelLiveChanged, `{"started":[2]}`)
+	st.Expect(t, sink.MarkLive(t.Context(), []int32{1, 2}), nil)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
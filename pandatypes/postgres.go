@@ -0,0 +1,517 @@
+package pandatypes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TxStarter begins the transaction a page is written in. *pgxpool.Pool satisfies it.
+type TxStarter interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// PostgresSink is the Sink writing through the sqlc queries of dbtypes.
+type PostgresSink struct {
+	queries *dbtypes.Queries
+	starter TxStarter
+	// tx is the transaction queries is bound to, nil outside of one.
+	tx pgx.Tx
+}
+
+// postgresTx is a PostgresSink bound to a transaction.
+type postgresTx struct {
+	*PostgresSink
+}
+
+// NewPostgresSink creates a sink on top of the given queries.
+// @param queries - the queries to write with.
+// @param starter - begins page transactions, pages are written in autocommit mode when nil.
+// @returns the sink.
+func NewPostgresSink(queries *dbtypes.Queries, starter TxStarter) *PostgresSink {
+	return &PostgresSink{queries: queries, starter: starter, tx: nil}
+}
+
+// Begin starts a transaction and returns a sink bound to it. Inside of a
+// transaction, or without a TxStarter, it returns ErrNoTransactions.
+func (sink *PostgresSink) Begin(ctx context.Context) (TxSink, error) {
+	tx, err := sink.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (sink *PostgresSink) begin(ctx context.Context) (postgresTx, error) {
+	if sink.starter == nil || sink.tx != nil {
+		return postgresTx{}, ErrNoTransactions
+	}
+	tx, err := sink.starter.Begin(ctx)
+	if err != nil {
+		return postgresTx{}, err
+	}
+	return postgresTx{&PostgresSink{queries: sink.queries.WithTx(tx), starter: nil, tx: tx}}, nil
+}
+
+func (sink postgresTx) Commit(ctx context.Context) error {
+	return sink.tx.Commit(ctx)
+}
+
+func (sink postgresTx) Rollback(ctx context.Context) error {
+	return sink.tx.Rollback(ctx)
+}
+
+// Savepoint runs fn under a savepoint of the transaction of the sink, see WithSavepoint.
+func (sink *PostgresSink) Savepoint(ctx context.Context, fn func() error) error {
+	return WithSavepoint(ctx, sink.tx, fn)
+}
+
+// UpsertGame upserts the game. PandaScore sends no modified_at for games, so
+// they are always written.
+func (sink *PostgresSink) UpsertGame(ctx context.Context, row GameRow) (bool, error) {
+	id, err := SafeIntToInt32(row.ID)
+	if err != nil {
+		return false, err
+	}
+	err = sink.queries.InsertToGames(ctx, dbtypes.InsertToGamesParams{
+		ID:   id,
+		Name: row.Name,
+		Slug: pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
+	})
+	return err == nil, err
+}
+
+func (sink *PostgresSink) UpsertLeague(ctx context.Context, row LeagueRow) (bool, error) {
+	id, err := SafeIntToInt32(row.ID)
+	if err != nil {
+		return false, err
+	}
+	gameID, err := SafeIntToInt32(row.GameID)
+	if err != nil {
+		return false, err
+	}
+	written, err := sink.queries.InsertToLeagues(ctx, dbtypes.InsertToLeaguesParams{
+		ID:         id,
+		Slug:       pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
+		Name:       row.Name,
+		GameID:     gameID,
+		ImageLink:  pgtype.Text{String: row.ImageLink, Valid: row.ImageLink != ""},
+		ModifiedAt: toTimestamptz(row.ModifiedAt),
+	})
+	return written > 0, err
+}
+
+func (sink *PostgresSink) UpsertSeries(ctx context.Context, row SeriesRow) (bool, error) {
+	id, err := SafeIntToInt32(row.ID)
+	if err != nil {
+		return false, err
+	}
+	gameID, err := SafeIntToInt32(row.GameID)
+	if err != nil {
+		return false, err
+	}
+	leagueID, err := SafeIntToInt32(row.LeagueID)
+	if err != nil {
+		return false, err
+	}
+	written, err := sink.queries.InsertToSeries(ctx, dbtypes.InsertToSeriesParams{
+		ID:         id,
+		Name:       row.Name,
+		Slug:       pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
+		GameID:     gameID,
+		LeagueID:   leagueID,
+		ModifiedAt: toTimestamptz(row.ModifiedAt),
+	})
+	return written > 0, err
+}
+
+func (sink *PostgresSink) UpsertTournament(ctx context.Context, row TournamentRow) (bool, error) {
+	id, err := SafeIntToInt32(row.ID)
+	if err != nil {
+		return false, err
+	}
+	gameID, err := SafeIntToInt32(row.GameID)
+	if err != nil {
+		return false, err
+	}
+	leagueID, err := SafeIntToInt32(row.LeagueID)
+	if err != nil {
+		return false, err
+	}
+	serieID, err := SafeIntToInt32(row.SerieID)
+	if err != nil {
+		return false, err
+	}
+	//nolint:gosec // tier is fixed by the switch statement of TournamentLike.ToRow
+	tier := int32(row.Tier)
+	written, err := sink.queries.InsertToTournaments(ctx, dbtypes.InsertToTournamentsParams{
+		ID:         id,
+		Name:       row.Name,
+		Slug:       pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
+		Tier:       pgtype.Int4{Int32: tier, Valid: row.Tier != 0},
+		GameID:     gameID,
+		SerieID:    serieID,
+		LeagueID:   leagueID,
+		ModifiedAt: toTimestamptz(row.ModifiedAt),
+	})
+	return written > 0, err
+}
+
+// UpsertMatch upserts the match and its opponents, records every tracked field
+// that changed compared to the stored row in MATCH_CHANGES and its events in
+// OUTBOX, and notifies ChannelMatchesChanged. A stored match that is at least
+// as new is left untouched together with its opponents. Outside of a page
+// transaction the writes run in a transaction of their own, so a match is
+// never stored without its opponents, changes and outbox events.
+// An unchanged match rewritten only to refresh its last_seen_at reports false,
+// like one left untouched. A match retention archived is skipped while it is
+// still finished, and diffed against its archived copy once it was reopened.
+func (sink *PostgresSink) UpsertMatch(ctx context.Context, row MatchRow) (bool, error) {
+	counts, err := sink.writeMatch(ctx, row)
+	return counts.Updated > 0, err
+}
+
+// writeMatch is UpsertMatch, reporting whether the match was updated,
+// refreshed or skipped.
+func (sink *PostgresSink) writeMatch(ctx context.Context, row MatchRow) (WriteCounts, error) {
+	if sink.tx == nil && sink.starter != nil {
+		return sink.writeMatchInTransaction(ctx, row)
+	}
+	params, err := row.ToParams()
+	if err != nil {
+		return WriteCounts{}, err
+	}
+	stored, err := sink.queries.GetMatchByID(ctx, params.ID)
+	var changes []MatchChange
+	var events []OutboxEvent
+	refreshed := false
+	switch {
+	case err == nil:
+		if matchUpToDate(stored, params.ModifiedAt, time.Now()) {
+			return countMatch(false, false), nil
+		}
+		refreshed = matchUnchanged(stored, params.ModifiedAt)
+		changes = DiffMatch(stored, params)
+		events = MatchEvents(&stored, params, changes)
+	case errors.Is(err, pgx.ErrNoRows):
+		archived, archiveErr := archivedMatches(ctx, sink.queries, []int32{params.ID})
+		if archiveErr != nil {
+			return WriteCounts{}, archiveErr
+		}
+		previous, ok := archived[params.ID]
+		if !ok {
+			// first time we see this match, there is no history to record
+			events = MatchEvents(nil, params, nil)
+			break
+		}
+		if keepArchived(params) {
+			return countMatch(false, false), nil
+		}
+		changes = DiffMatch(previous, params)
+		events = MatchEvents(&previous, params, changes)
+	default:
+		return WriteCounts{}, err
+	}
+	written, err := sink.queries.InsertToMatches(ctx, params)
+	if err != nil {
+		return WriteCounts{}, err
+	}
+	if written == 0 {
+		// zero rows means a newer version was stored since the lookup
+		return countMatch(false, false), nil
+	}
+	err = WriteMatchOpponents(ctx, sink.queries, params.ID, row.Opponents)
+	if err != nil {
+		return WriteCounts{}, err
+	}
+	err = RecordMatchChanges(ctx, sink.queries, params.ID, changes)
+	if err != nil {
+		return WriteCounts{}, err
+	}
+	err = RecordOutboxEvents(ctx, sink.queries, params.ID, events)
+	if err != nil {
+		return WriteCounts{}, err
+	}
+	var set matchChangeSet
+	set.add(params.ID, changes, events)
+	if err = notifyMatches(ctx, sink.queries, set); err != nil {
+		return WriteCounts{}, err
+	}
+	return countMatch(true, refreshed), nil
+}
+
+// writeMatchInTransaction runs writeMatch on a transaction of its own and
+// rolls it back when any of the writes fails.
+func (sink *PostgresSink) writeMatchInTransaction(ctx context.Context, row MatchRow) (WriteCounts, error) {
+	tx, err := sink.begin(ctx)
+	if err != nil {
+		return WriteCounts{}, err
+	}
+	counts, err := tx.writeMatch(ctx, row)
+	if err != nil {
+		if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+			return WriteCounts{}, errors.Join(err, rollbackErr)
+		}
+		return WriteCounts{}, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return WriteCounts{}, err
+	}
+	return counts, nil
+}
+
+func (sink *PostgresSink) UpsertTeam(ctx context.Context, row TeamRow) (bool, error) {
+	params, err := row.ToParams()
+	if err != nil {
+		return false, err
+	}
+	written, err := sink.queries.InsertToTeams(ctx, params)
+	return written > 0, err
+}
+
+func (sink *PostgresSink) UpsertPlayer(ctx context.Context, row PlayerRow) (bool, error) {
+	params, err := row.ToParams()
+	if err != nil {
+		return false, err
+	}
+	written, err := sink.queries.InsertToPlayers(ctx, params)
+	return written > 0, err
+}
+
+// Exists counts the live rows with the given ID.
+func (sink *PostgresSink) Exists(ctx context.Context, entity Entity, id int32) (bool, error) {
+	var count int64
+	var err error
+	switch entity {
+	case EntityGame:
+		count, err = sink.queries.GameExist(ctx, id)
+	case EntityLeague:
+		count, err = sink.queries.LeagueExist(ctx, id)
+	case EntitySeries:
+		count, err = sink.queries.SeriesExist(ctx, id)
+	case EntityTournament:
+		count, err = sink.queries.TournamentExist(ctx, id)
+	case EntityMatch:
+		count, err = sink.queries.MatchExist(ctx, id)
+	case EntityTeam:
+		count, err = sink.queries.TeamExist(ctx, id)
+	case EntityPlayer:
+		count, err = sink.queries.PlayerExist(ctx, id)
+	default:
+		return false, fmt.Errorf("invalid entity: %d", entity)
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (sink *PostgresSink) ExistingIDs(ctx context.Context, entity Entity, ids []int32) ([]int32, error) {
+	switch entity {
+	case EntityGame:
+		return sink.queries.GetExistingGameIDs(ctx, ids)
+	case EntityLeague:
+		return sink.queries.GetExistingLeagueIDs(ctx, ids)
+	case EntitySeries:
+		return sink.queries.GetExistingSeriesIDs(ctx, ids)
+	case EntityTournament:
+		return sink.queries.GetExistingTournamentIDs(ctx, ids)
+	case EntityTeam:
+		return sink.queries.GetExistingTeamIDs(ctx, ids)
+	case EntityPlayer:
+		return sink.queries.GetExistingPlayerIDs(ctx, ids)
+	case EntityMatch:
+		return nil, fmt.Errorf("entity %d has no bulk existence check", entity)
+	default:
+		return nil, fmt.Errorf("invalid entity: %d", entity)
+	}
+}
+
+func (sink *PostgresSink) KnownIDs(ctx context.Context, entity Entity, limit int32) ([]int32, error) {
+	switch entity {
+	case EntityGame:
+		return sink.queries.GetKnownGameIDs(ctx, limit)
+	case EntityLeague:
+		return sink.queries.GetKnownLeagueIDs(ctx, limit)
+	case EntitySeries:
+		return sink.queries.GetKnownSeriesIDs(ctx, limit)
+	case EntityTournament:
+		return sink.queries.GetKnownTournamentIDs(ctx, limit)
+	case EntityTeam:
+		return sink.queries.GetKnownTeamIDs(ctx, limit)
+	case EntityPlayer:
+		return sink.queries.GetKnownPlayerIDs(ctx, limit)
+	case EntityMatch:
+		return nil, fmt.Errorf("entity %d has no known ID lookup", entity)
+	default:
+		return nil, fmt.Errorf("invalid entity: %d", entity)
+	}
+}
+
+// StaleIDs only supports the reconciled tournaments, matches and teams.
+func (sink *PostgresSink) StaleIDs(ctx context.Context, entity Entity, cutoff time.Time, limit int32) ([]int32, error) {
+	lastSeen := toTimestamptz(cutoff)
+	switch entity {
+	case EntityTournament:
+		return sink.queries.GetStaleTournamentIDs(ctx, dbtypes.GetStaleTournamentIDsParams{
+			LastSeenAt: lastSeen,
+			Limit:      limit,
+		})
+	case EntityMatch:
+		return sink.queries.GetStaleMatchIDs(ctx, dbtypes.GetStaleMatchIDsParams{
+			LastSeenAt: lastSeen,
+			Limit:      limit,
+		})
+	case EntityTeam:
+		return sink.queries.GetStaleTeamIDs(ctx, dbtypes.GetStaleTeamIDsParams{
+			LastSeenAt: lastSeen,
+			Limit:      limit,
+		})
+	case EntityGame, EntityLeague, EntitySeries, EntityPlayer:
+		return nil, fmt.Errorf("entity %d is not reconciled", entity)
+	default:
+		return nil, fmt.Errorf("invalid entity: %d", entity)
+	}
+}
+
+// Tombstone only supports the reconciled tournaments, matches and teams.
+// Tombstoned matches are notified on ChannelMatchesChanged as deleted.
+func (sink *PostgresSink) Tombstone(ctx context.Context, entity Entity, id int32) error {
+	switch entity {
+	case EntityTournament:
+		return sink.queries.TombstoneTournament(ctx, id)
+	case EntityMatch:
+		if err := sink.queries.TombstoneMatch(ctx, id); err != nil {
+			return err
+		}
+		set := matchChangeSet{ids: []int32{id}, changes: map[string][]int32{ChangeDeleted: {id}}}
+		return notifyMatches(ctx, sink.queries, set)
+	case EntityTeam:
+		return sink.queries.TombstoneTeam(ctx, id)
+	case EntityGame, EntityLeague, EntitySeries, EntityPlayer:
+		return fmt.Errorf("entity %d cannot be tombstoned", entity)
+	default:
+		return fmt.Errorf("invalid entity: %d", entity)
+	}
+}
+
+// MarkLive flags the given matches as live and writes a match_live event to
+// OUTBOX for every one that was not live before, in the same statement. Those
+// are notified on ChannelLiveChanged as started.
+func (sink *PostgresSink) MarkLive(ctx context.Context, ids []int32) error {
+	started, err := sink.queries.MarkMatchesLive(ctx, ids)
+	if err != nil {
+		return err
+	}
+	return notify(ctx, sink.queries, ChannelLiveChanged, liveChangedPayloads(started, true))
+}
+
+// ClearLiveExcept notifies the matches it cleared on ChannelLiveChanged as ended.
+func (sink *PostgresSink) ClearLiveExcept(ctx context.Context, ids []int32) error {
+	ended, err := sink.queries.ClearMatchesIsLiveExceptIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	return notify(ctx, sink.queries, ChannelLiveChanged, liveChangedPayloads(ended, false))
+}
+
+func (sink *PostgresSink) EnqueueRetry(ctx context.Context, entityType string, id int32, lastError string) error {
+	return sink.queries.EnqueueResolveRetry(ctx, dbtypes.EnqueueResolveRetryParams{
+		EntityType: entityType,
+		EntityID:   id,
+		LastError:  pgtype.Text{String: lastError, Valid: true},
+	})
+}
+
+func (sink *PostgresSink) DueRetries(ctx context.Context, maxAttempts int32, limit int32) ([]Retry, error) {
+	rows, err := sink.queries.GetDueResolveRetries(ctx, dbtypes.GetDueResolveRetriesParams{
+		MaxAttempts: maxAttempts,
+		MaxRows:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	retries := make([]Retry, 0, len(rows))
+	for _, row := range rows {
+		retries = append(retries, Retry{
+			EntityType: row.EntityType,
+			EntityID:   row.EntityID,
+			Attempts:   row.Attempts,
+			LastError:  row.LastError.String,
+		})
+	}
+	return retries, nil
+}
+
+func (sink *PostgresSink) MarkRetryFailed(ctx context.Context, entityType string, id int32, lastError string) error {
+	return sink.queries.MarkResolveRetryFailed(ctx, dbtypes.MarkResolveRetryFailedParams{
+		EntityType: entityType,
+		EntityID:   id,
+		LastError:  pgtype.Text{String: lastError, Valid: true},
+	})
+}
+
+func (sink *PostgresSink) DeleteRetry(ctx context.Context, entityType string, id int32) error {
+	return sink.queries.DeleteResolveRetry(ctx, dbtypes.DeleteResolveRetryParams{EntityType: entityType, EntityID: id})
+}
+
+func (sink *PostgresSink) CountExpired(ctx context.Context, target RetentionTarget, cutoff time.Time) (int64, error) {
+	switch target {
+	case RetainMatches:
+		return sink.queries.CountArchivableMatches(ctx, toTimestamptz(cutoff))
+	case RetainURLMappings:
+		return sink.queries.CountExpiredURLMappings(ctx, toTimestamptz(cutoff))
+	default:
+		return 0, fmt.Errorf("invalid retention target: %d", target)
+	}
+}
+
+// Expire moves matches into MATCHES_ARCHIVE after creating the yearly
+// partitions they land in, and deletes URL mappings.
+func (sink *PostgresSink) Expire(ctx context.Context, target RetentionTarget, cutoff time.Time, limit int32) (int64, error) {
+	switch target {
+	case RetainMatches:
+		years, err := sink.queries.GetArchivableMatchYears(ctx, toTimestamptz(cutoff))
+		if err != nil {
+			return 0, err
+		}
+		for _, year := range years {
+			if err = sink.queries.EnsureMatchesArchivePartition(ctx, year); err != nil {
+				return 0, err
+			}
+		}
+		return sink.queries.ArchiveMatches(ctx, dbtypes.ArchiveMatchesParams{
+			Cutoff:  toTimestamptz(cutoff),
+			MaxRows: limit,
+		})
+	case RetainURLMappings:
+		return sink.queries.DeleteExpiredURLMappings(ctx, dbtypes.DeleteExpiredURLMappingsParams{
+			AccessedAt: toTimestamptz(cutoff),
+			Limit:      limit,
+		})
+	default:
+		return 0, fmt.Errorf("invalid retention target: %d", target)
+	}
+}
+
+// RefreshViews refreshes the materialized views concurrently, so readers keep
+// seeing the previous contents until the refresh commits.
+func (sink *PostgresSink) RefreshViews(ctx context.Context) error {
+	return errors.Join(
+		sink.queries.RefreshUpcomingMatchesView(ctx),
+		sink.queries.RefreshRankedLeaguesView(ctx),
+	)
+}
+
+// SyncCompleted notifies ChannelSyncCompleted.
+func (sink *PostgresSink) SyncCompleted(ctx context.Context, job string) error {
+	// Marshaling a string cannot fail.
+	data, _ := json.Marshal(SyncCompleted{Job: job})
+	return notify(ctx, sink.queries, ChannelSyncCompleted, [][]byte{data})
+}
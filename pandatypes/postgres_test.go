@@ -0,0 +1,174 @@
+package pandatypes
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestPostgresSinkTransactions(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+
+	t.Run("Without a TxStarter the sink writes in autocommit mode", func(t *testing.T) {
+		_, err := NewPostgresSink(dbtypes.New(mockDB), nil).Begin(t.Context())
+		st.Expect(t, errors.Is(err, ErrNoTransactions), true)
+	})
+
+	t.Run("Writes of a transaction run under savepoints and commit together", func(t *testing.T) {
+		mockDB.ExpectBegin()
+		mockDB.ExpectBegin()
+		mockDB.ExpectExec("INSERT INTO teams").WithArgs(anyArgs(7)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockDB.ExpectCommit()
+		mockDB.ExpectCommit()
+
+		tx, err := NewPostgresSink(dbtypes.New(mockDB), mockDB).Begin(t.Context())
+		st.Assert(t, err, nil)
+		// transactions do not nest, the writes land in the outer one
+		_, err = tx.Begin(t.Context())
+		st.Expect(t, errors.Is(err, ErrNoTransactions), true)
+		err = tx.Savepoint(t.Context(), func() error {
+			_, writeErr := tx.UpsertTeam(t.Context(), TeamRow{ID: 1, Name: "T1", GameID: 1})
+			return writeErr
+		})
+		st.Expect(t, err, nil)
+		st.Expect(t, tx.Commit(t.Context()), nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("A match outside of a page is rolled back with its outbox events", func(t *testing.T) {
+		row, _ := pageRows(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectQuery("SELECT .+ FROM matches WHERE id").WithArgs(int32(row.ID)).
+			WillReturnError(pgx.ErrNoRows)
+		expectArchived(mockDB, []int32{int32(row.ID)})
+		mockDB.ExpectExec("INSERT INTO matches").WithArgs(anyArgs(matchInsertArgs)...).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectOpponents(mockDB, int32(row.ID), 2)
+		mockDB.ExpectExec("INSERT INTO outbox").WithArgs(EventMatchCreated, int32(row.ID), pgxmock.AnyArg()).
+			WillReturnError(errors.New("database error"))
+		mockDB.ExpectRollback()
+
+		written, err := NewPostgresSink(dbtypes.New(mockDB), mockDB).UpsertMatch(t.Context(), row)
+		st.Reject(t, err, nil)
+		st.Expect(t, written, false)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestPostgresSinkLookups(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	sink := NewPostgresSink(dbtypes.New(mockDB), nil)
+
+	t.Run("Exists counts live rows", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT COUNT").WithArgs(int32(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(1)))
+		exists, err := sink.Exists(t.Context(), EntityTeam, 1)
+		st.Expect(t, err, nil)
+		st.Expect(t, exists, true)
+
+		mockDB.ExpectQuery("SELECT COUNT").WithArgs(int32(2)).WillReturnError(pgx.ErrNoRows)
+		exists, err = sink.Exists(t.Context(), EntityTeam, 2)
+		st.Expect(t, err, nil)
+		st.Expect(t, exists, false)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Queued retries are converted", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT .+ FROM resolve_retries").WithArgs(int32(5), int32(25)).
+			WillReturnRows(pgxmock.NewRows([]string{
+				"entity_type", "entity_id", "attempts", "last_error", "created_at", "last_attempt_at",
+			}).AddRow("tournaments", int32(9), int32(2), pgtype.Text{String: "boom", Valid: true},
+				pgtype.Timestamptz{}, pgtype.Timestamptz{}))
+		retries, err := sink.DueRetries(t.Context(), 5, 25)
+		st.Expect(t, err, nil)
+		st.Expect(t, retries, []Retry{{EntityType: "tournaments", EntityID: 9, Attempts: 2, LastError: "boom"}})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - unsupported entities", func(t *testing.T) {
+		_, err := sink.ExistingIDs(t.Context(), EntityMatch, []int32{1})
+		st.Reject(t, err, nil)
+		_, err = sink.KnownIDs(t.Context(), EntityMatch, 1)
+		st.Reject(t, err, nil)
+		_, err = sink.StaleIDs(t.Context(), EntityGame, time.Now(), 1)
+		st.Reject(t, err, nil)
+		st.Reject(t, sink.Tombstone(t.Context(), EntityPlayer, 1), nil)
+		_, err = sink.Exists(t.Context(), Entity(99), 1)
+		st.Reject(t, err, nil)
+		_, err = sink.CountExpired(t.Context(), RetentionTarget(99), time.Now())
+		st.Reject(t, err, nil)
+		_, err = sink.Expire(t.Context(), RetentionTarget(99), time.Now(), 1)
+		st.Reject(t, err, nil)
+	})
+}
+
+func TestPostgresSinkExpire(t *testing.T) {
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	defer mockDB.Close()
+	sink := NewPostgresSink(dbtypes.New(mockDB), nil)
+	cutoff := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Expired rows are counted", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT COUNT.+ FROM matches").WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(7)))
+		mockDB.ExpectQuery("SELECT COUNT.+ FROM url_mappings").WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(3)))
+
+		count, err := sink.CountExpired(t.Context(), RetainMatches, cutoff)
+		st.Expect(t, err, nil)
+		st.Expect(t, count, int64(7))
+		count, err = sink.CountExpired(t.Context(), RetainURLMappings, cutoff)
+		st.Expect(t, err, nil)
+		st.Expect(t, count, int64(3))
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Matches are archived into their yearly partitions", func(t *testing.T) {
+		// Matches can be archived twice, so the archive upserts.
+		mockDB.ExpectQuery("SELECT DISTINCT").WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"year"}).AddRow(int32(2024)))
+		mockDB.ExpectExec("SELECT ensure_matches_archive_partition").WithArgs(int32(2024)).
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+		mockDB.ExpectExec("DELETE FROM matches_archive .+ INSERT INTO matches_archive .+ ON CONFLICT \\(id, played_at\\) DO UPDATE").
+			WithArgs(pgxmock.AnyArg(), int32(10)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 3))
+
+		moved, err := sink.Expire(t.Context(), RetainMatches, cutoff, 10)
+		st.Expect(t, err, nil)
+		st.Expect(t, moved, int64(3))
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("URL mappings are deleted up to the limit", func(t *testing.T) {
+		mockDB.ExpectExec("DELETE FROM url_mappings").WithArgs(pgxmock.AnyArg(), int32(10)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 2))
+
+		deleted, err := sink.Expire(t.Context(), RetainURLMappings, cutoff, 10)
+		st.Expect(t, err, nil)
+		st.Expect(t, deleted, int64(2))
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - a failing partition stops the archive", func(t *testing.T) {
+		mockDB.ExpectQuery("SELECT DISTINCT").WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"year"}).AddRow(int32(2023)))
+		mockDB.ExpectExec("SELECT ensure_matches_archive_partition").WithArgs(int32(2023)).
+			WillReturnError(errors.New("database error"))
+
+		_, err := sink.Expire(t.Context(), RetainMatches, cutoff, 10)
+		st.Reject(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
@@ -0,0 +1,151 @@
+package pandatypes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nbio/st"
+)
+
+// baselineSchema is static/schema.sql as the first release shipped it; the
+// databases of existing deployments were created by it.
+const baselineSchema = "testdata/baseline_schema.sql"
+
+var (
+	createTable = regexp.MustCompile(`(?is)CREATE TABLE IF NOT EXISTS (\w+)\s*\((.*?)\n\);`)
+	addColumn   = regexp.MustCompile(`(?i)ALTER TABLE (\w+) ADD COLUMN IF NOT EXISTS (\w+)`)
+	createIndex = regexp.MustCompile(`(?i)CREATE (?:UNIQUE )?INDEX IF NOT EXISTS \w+ ON (\w+)(?: USING \w+)?\s*(\(.*);`)
+	identifier  = regexp.MustCompile(`\w+`)
+)
+
+// baselineColumns reads the columns of every table of a schema, and whether
+// each is a TIMESTAMP without time zone.
+func baselineColumns(schema string) map[string]map[string]bool {
+	tables := make(map[string]map[string]bool)
+	for _, match := range createTable.FindAllStringSubmatch(schema, -1) {
+		columns := make(map[string]bool)
+		for line := range strings.SplitSeq(match[2], "\n") {
+			fields := strings.Fields(strings.TrimSpace(line))
+			if len(fields) < 2 || strings.EqualFold(fields[0], "FOREIGN") || strings.EqualFold(fields[0], "PRIMARY") {
+				continue
+			}
+			columns[strings.ToLower(fields[0])] = strings.EqualFold(strings.TrimSuffix(fields[1], ","), "TIMESTAMP")
+		}
+		tables[strings.ToLower(match[1])] = columns
+	}
+	return tables
+}
+
+// TestSchemaUpgradesBaseline checks the order of static/schema.sql without a
+// database: an index on a table of the baseline may only read the columns the
+// appendix adds once they are added, and an expression index may only read its
+// TIMESTAMP columns once they are converted to TIMESTAMPTZ.
+func TestSchemaUpgradesBaseline(t *testing.T) {
+	baseline, err := os.ReadFile(baselineSchema)
+	st.Assert(t, err, nil)
+	current, err := os.ReadFile("../static/schema.sql")
+	st.Assert(t, err, nil)
+	schema := string(current)
+	tables := baselineColumns(string(baseline))
+	st.Assert(t, len(tables["matches"]) > 0, true)
+
+	added := make(map[string]int)
+	for _, match := range addColumn.FindAllStringSubmatchIndex(schema, -1) {
+		added[strings.ToLower(schema[match[2]:match[3]]+"."+schema[match[4]:match[5]])] = match[0]
+	}
+	converted := strings.Index(schema, "ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ")
+	st.Assert(t, converted > 0, true)
+
+	for _, match := range createIndex.FindAllStringSubmatchIndex(schema, -1) {
+		table := strings.ToLower(schema[match[2]:match[3]])
+		columns, existing := tables[table]
+		if !existing {
+			continue
+		}
+		definition := schema[match[4]:match[5]]
+		for _, name := range identifier.FindAllString(definition, -1) {
+			name = strings.ToLower(name)
+			at, isAdded := added[table+"."+name]
+			timestamp, isBaseline := columns[name]
+			switch {
+			case isAdded && !isBaseline && at > match[0]:
+				t.Errorf("%s reads %s.%s before the appendix adds it", schema[match[0]:match[1]], table, name)
+			case timestamp && strings.HasPrefix(definition, "((") && converted > match[0]:
+				t.Errorf("%s reads the TIMESTAMP %s.%s before its conversion", schema[match[0]:match[1]], table, name)
+			}
+		}
+	}
+}
+
+// TestSchemaAgainstBaselineServer applies static/schema.sql on top of the
+// baseline schema, as stalka does on startup against an existing deployment,
+// in a scratch schema of the database of tzDatabaseEnv. Its writes are rolled
+// back.
+func TestSchemaAgainstBaselineServer(t *testing.T) {
+	url := testDatabaseURL(t)
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, url)
+	st.Assert(t, err, nil)
+	defer conn.Close(ctx)
+
+	baseline, err := os.ReadFile(baselineSchema)
+	st.Assert(t, err, nil)
+	schema, err := os.ReadFile("../static/schema.sql")
+	st.Assert(t, err, nil)
+
+	tx, err := conn.Begin(ctx)
+	st.Assert(t, err, nil)
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// The extensions live in public, where the schema refers to them.
+	for _, statement := range []string{
+		"CREATE EXTENSION IF NOT EXISTS unaccent SCHEMA public",
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public",
+		"CREATE SCHEMA stalka_upgrade",
+		"SET LOCAL search_path TO stalka_upgrade, public",
+		string(baseline),
+		// A row written by the baseline, in the wall-clock UTC it stored.
+		`INSERT INTO GAMES VALUES (1, 'LoL', 'league-of-legends')`,
+		`INSERT INTO LEAGUES VALUES (1, 'LCK', 'lck', 1, NULL)`,
+		`INSERT INTO SERIES VALUES (1, 'Spring', NULL, 1, 1)`,
+		`INSERT INTO TOURNAMENTS VALUES (1, 'Playoffs', NULL, 1, 1, 1, 1)`,
+		`INSERT INTO MATCHES (id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score,
+			team2_id, team2_score, amount_of_games, game_id, league_id, series_id, tournament_id)
+			VALUES (1, 'T1 vs GEN', NULL, true, '2024-03-31 01:30:00', 0, 1, 3, 2, 1, 5, 1, 1, 1, 1)`,
+		"SET LOCAL TIME ZONE 'Europe/London'",
+	} {
+		_, err = tx.Exec(ctx, statement)
+		st.Assert(t, err, nil)
+	}
+
+	// Startup runs the schema every time, so it must apply twice.
+	for run := 1; run <= 2; run++ {
+		t.Run(fmt.Sprintf("Run %d", run), func(t *testing.T) {
+			_, err := tx.Exec(ctx, string(schema))
+			st.Assert(t, err, nil)
+
+			var naive int
+			err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM information_schema.columns
+				WHERE table_schema = current_schema() AND data_type = 'timestamp without time zone'`).Scan(&naive)
+			st.Assert(t, err, nil)
+			st.Expect(t, naive, 0)
+
+			var index int
+			err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM pg_indexes
+				WHERE schemaname = current_schema() AND indexname = 'matches_played_at_idx'`).Scan(&index)
+			st.Assert(t, err, nil)
+			st.Expect(t, index, 1)
+
+			var start string
+			err = tx.QueryRow(ctx, `SELECT to_char(expected_start_time AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+				FROM MATCHES WHERE id = 1`).Scan(&start)
+			st.Assert(t, err, nil)
+			st.Expect(t, start, "2024-03-31 01:30:00")
+		})
+	}
+}
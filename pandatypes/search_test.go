@@ -0,0 +1,74 @@
+package pandatypes
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5"
+	"github.com/nbio/st"
+)
+
+// TestSearchEntitiesAgainstServer checks SearchEntities against the scratch
+// database of tzDatabaseEnv. Its writes are rolled back.
+func TestSearchEntitiesAgainstServer(t *testing.T) {
+	url := testDatabaseURL(t)
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, url)
+	st.Assert(t, err, nil)
+	defer conn.Close(ctx)
+
+	schema, err := os.ReadFile("../static/schema.sql")
+	st.Assert(t, err, nil)
+
+	tx, err := conn.Begin(ctx)
+	st.Assert(t, err, nil)
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx, string(schema))
+	st.Assert(t, err, nil)
+
+	queries := dbtypes.New(tx)
+	sink := NewPostgresSink(queries, nil)
+	rows := []RowLike{
+		GameRow{ID: 990001, Name: "LoL", Slug: "league-of-legends"},
+		LeagueRow{ID: 990001, Name: "Worlds", Slug: "league-of-legends-world-championship", GameID: 990001},
+		TeamRow{ID: 990001, Name: "Fnatic", Acronym: "FNC", Slug: "fnatic", GameID: 990001},
+		TeamRow{ID: 990002, Name: "Team Héretics", Acronym: "TH", Slug: "team-heretics-lol", GameID: 990001},
+		PlayerRow{ID: 990001, Name: "Faker", FirstName: "Sang-hyeok", LastName: "Lee", Slug: "faker", GameID: 990001},
+	}
+	for _, row := range rows {
+		_, err = row.WriteToDB(ctx, sink)
+		st.Assert(t, err, nil)
+	}
+	search := func(query string) []dbtypes.SearchEntitiesRow {
+		hits, searchErr := queries.SearchEntities(ctx, dbtypes.SearchEntitiesParams{Query: query, MaxRows: 5})
+		st.Assert(t, searchErr, nil)
+		st.Assert(t, len(hits) > 0, true)
+		return hits
+	}
+
+	t.Run("Acronyms match full-text", func(t *testing.T) {
+		hits := search("FNC")
+		st.Expect(t, hits[0].EntityType, "teams")
+		st.Expect(t, hits[0].ID, int32(990001))
+		st.Expect(t, hits[0].Score > 1, true)
+	})
+
+	t.Run("Accents are ignored", func(t *testing.T) {
+		hits := search("heretics")
+		st.Expect(t, hits[0].ID, int32(990002))
+	})
+
+	t.Run("Typos fall back to trigrams", func(t *testing.T) {
+		hits := search("Wrolds")
+		st.Expect(t, hits[0].EntityType, "leagues")
+		st.Expect(t, hits[0].Score < 1, true)
+	})
+
+	t.Run("Players match by real name", func(t *testing.T) {
+		hits := search("Lee")
+		st.Expect(t, hits[0].EntityType, "players")
+	})
+}
@@ -0,0 +1,151 @@
+package pandatypes
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Entity identifies the type of a stored entity.
+type Entity int
+
+const (
+	EntityGame Entity = iota
+	EntityLeague
+	EntitySeries
+	EntityTournament
+	EntityMatch
+	EntityTeam
+	EntityPlayer
+)
+
+// RetentionTarget identifies the rows a retention policy expires.
+type RetentionTarget int
+
+const (
+	// RetainMatches moves finished matches into MATCHES_ARCHIVE.
+	RetainMatches RetentionTarget = iota
+	// RetainURLMappings deletes URL mappings.
+	RetainURLMappings
+)
+
+// ErrNoTransactions is returned by Tx.Begin when the store writes in autocommit mode.
+var ErrNoTransactions = errors.New("sink does not support transactions")
+
+// Retry is an entity queued for another resolution attempt.
+type Retry struct {
+	// EntityType is the PandaScore endpoint name of the entity, e.g. "tournaments".
+	EntityType string
+	EntityID   int32
+	Attempts   int32
+	LastError  string
+}
+
+// Writer writes the entities the client fetches.
+type Writer interface {
+	// UpsertGame and the other Upsert methods write a single row. They report
+	// false when the stored row was at least as new and was left untouched.
+	UpsertGame(ctx context.Context, row GameRow) (bool, error)
+	UpsertLeague(ctx context.Context, row LeagueRow) (bool, error)
+	UpsertSeries(ctx context.Context, row SeriesRow) (bool, error)
+	UpsertTournament(ctx context.Context, row TournamentRow) (bool, error)
+	UpsertMatch(ctx context.Context, row MatchRow) (bool, error)
+	UpsertTeam(ctx context.Context, row TeamRow) (bool, error)
+	UpsertPlayer(ctx context.Context, row PlayerRow) (bool, error)
+	// WriteMatchPage upserts a page of matches and reports every row that failed.
+	WriteMatchPage(ctx context.Context, rows []MatchRow) (WriteCounts, []RowError)
+	// WriteMissingOpponents creates the teams and players that do not exist yet
+	// and refreshes the last_seen_at of the others once it is older than
+	// SeenRefreshInterval, restoring tombstoned ones.
+	WriteMissingOpponents(ctx context.Context, teams []TeamRow, players []PlayerRow) []RowError
+	// Tombstone soft-deletes an entity.
+	Tombstone(ctx context.Context, entity Entity, id int32) error
+}
+
+// Lookup answers which entities are stored.
+type Lookup interface {
+	// Exists reports whether an entity is stored and not tombstoned.
+	Exists(ctx context.Context, entity Entity, id int32) (bool, error)
+	// ExistingIDs returns the subset of ids that is stored and not tombstoned.
+	ExistingIDs(ctx context.Context, entity Entity, ids []int32) ([]int32, error)
+	// KnownIDs returns at most limit IDs of an entity type, most recently seen first.
+	KnownIDs(ctx context.Context, entity Entity, limit int32) ([]int32, error)
+	// StaleIDs returns at most limit IDs of an entity type last seen before cutoff.
+	StaleIDs(ctx context.Context, entity Entity, cutoff time.Time, limit int32) ([]int32, error)
+}
+
+// Tx groups writes into transactions.
+type Tx interface {
+	// Begin starts a transaction. It returns ErrNoTransactions when the store
+	// writes in autocommit mode.
+	Begin(ctx context.Context) (TxSink, error)
+	// Savepoint runs fn so that its writes are undone when it fails, without
+	// aborting the surrounding transaction. Outside of one fn runs directly.
+	Savepoint(ctx context.Context, fn func() error) error
+}
+
+// Store is what a page is written through: the writes, the dependency lookups
+// and the transactions they run in.
+type Store interface {
+	Writer
+	Lookup
+	Tx
+}
+
+// TxSink is a Store whose writes are bound to a transaction.
+type TxSink interface {
+	Store
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// LiveFlags tracks which matches are live.
+type LiveFlags interface {
+	// MarkLive flags the given matches as live.
+	MarkLive(ctx context.Context, ids []int32) error
+	// ClearLiveExcept clears the live flag of every match not in ids.
+	ClearLiveExcept(ctx context.Context, ids []int32) error
+}
+
+// RetryQueue holds the entities whose dependencies could not be resolved.
+type RetryQueue interface {
+	// EnqueueRetry queues an entity for another attempt. An entity queued
+	// already only has its last error updated.
+	EnqueueRetry(ctx context.Context, entityType string, id int32, lastError string) error
+	// DueRetries returns at most limit queued entities with fewer than maxAttempts attempts.
+	DueRetries(ctx context.Context, maxAttempts int32, limit int32) ([]Retry, error)
+	// MarkRetryFailed records a failed attempt of a queued entity.
+	MarkRetryFailed(ctx context.Context, entityType string, id int32, lastError string) error
+	// DeleteRetry removes an entity from the queue.
+	DeleteRetry(ctx context.Context, entityType string, id int32) error
+}
+
+// Retention expires old rows.
+type Retention interface {
+	// CountExpired returns how many rows of target are older than cutoff: for
+	// matches the finished ones played before it, for URL mappings the ones last
+	// accessed before it.
+	CountExpired(ctx context.Context, target RetentionTarget, cutoff time.Time) (int64, error)
+	// Expire archives or deletes at most limit of the rows CountExpired counts,
+	// oldest first, and returns how many it removed.
+	Expire(ctx context.Context, target RetentionTarget, cutoff time.Time, limit int32) (int64, error)
+}
+
+// Publisher makes committed writes visible to the readers of the store.
+type Publisher interface {
+	// RefreshViews brings the read-side views up to date with the committed writes.
+	RefreshViews(ctx context.Context) error
+	// SyncCompleted tells the readers of the store that a sync job finished.
+	SyncCompleted(ctx context.Context, job string) error
+}
+
+// Sink is everything a storage backend provides. PostgresSink is the
+// production implementation; other stores implement the same interfaces, while
+// wrappers adding metrics or fanning writes out only need the roles they wrap.
+type Sink interface {
+	Store
+	LiveFlags
+	RetryQueue
+	Retention
+	Publisher
+}
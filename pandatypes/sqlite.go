@@ -0,0 +1,838 @@
+package pandatypes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// sqliteTimeFormat is the text form times are stored in. It matches the output
+// of CURRENT_TIMESTAMP, so stored values compare textually and with julianday.
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999"
+
+// sqliteFresh is the WHERE clause of the SQLite upserts: like the Postgres
+// upserts they skip rows whose stored modified_at is at least as new, unless the
+// row is tombstoned or was last seen more than SeenRefreshInterval ago.
+const sqliteFresh = `
+WHERE %[1]s.deleted_at IS NOT NULL
+    OR julianday(%[1]s.last_seen_at) < julianday('now', '-1 day')
+    OR COALESCE(julianday(excluded.modified_at) > julianday(%[1]s.modified_at), true)`
+
+// sqliteUpserts holds the upsert of every entity table, see static/queries.sql
+// for their Postgres counterparts.
+var sqliteUpserts = map[Entity]string{
+	EntityGame: `INSERT INTO games (id, name, slug) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET
+    name = excluded.name,
+    slug = excluded.slug,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL`,
+	EntityLeague: `INSERT INTO leagues (id, name, slug, image_link, game_id, modified_at) VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET
+    name = excluded.name,
+    slug = excluded.slug,
+    image_link = excluded.image_link,
+    game_id = excluded.game_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = excluded.modified_at` + fmt.Sprintf(sqliteFresh, "leagues"),
+	EntitySeries: `INSERT INTO series (id, name, slug, game_id, league_id, modified_at) VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET
+    name = excluded.name,
+    slug = excluded.slug,
+    game_id = excluded.game_id,
+    league_id = excluded.league_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = excluded.modified_at` + fmt.Sprintf(sqliteFresh, "series"),
+	EntityTournament: `INSERT INTO tournaments (id, name, slug, tier, game_id, league_id, serie_id, modified_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET
+    name = excluded.name,
+    slug = excluded.slug,
+    tier = excluded.tier,
+    game_id = excluded.game_id,
+    league_id = excluded.league_id,
+    serie_id = excluded.serie_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = excluded.modified_at` + fmt.Sprintf(sqliteFresh, "tournaments"),
+	EntityMatch: `INSERT INTO matches (id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score, team2_id, team2_score, amount_of_games, game_id, league_id, series_id, tournament_id, stream_url, status, forfeit, draw, rescheduled, original_scheduled_at, begin_at, end_at, winner_id, winner_type, modified_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET
+    name = excluded.name,
+    slug = excluded.slug,
+    finished = excluded.finished,
+    expected_start_time = excluded.expected_start_time,
+    actual_game_time = excluded.actual_game_time,
+    team1_id = excluded.team1_id,
+    team1_score = excluded.team1_score,
+    team2_id = excluded.team2_id,
+    team2_score = excluded.team2_score,
+    amount_of_games = excluded.amount_of_games,
+    game_id = excluded.game_id,
+    league_id = excluded.league_id,
+    series_id = excluded.series_id,
+    tournament_id = excluded.tournament_id,
+    stream_url = excluded.stream_url,
+    status = excluded.status,
+    forfeit = excluded.forfeit,
+    draw = excluded.draw,
+    rescheduled = excluded.rescheduled,
+    original_scheduled_at = excluded.original_scheduled_at,
+    begin_at = excluded.begin_at,
+    end_at = excluded.end_at,
+    winner_id = excluded.winner_id,
+    winner_type = excluded.winner_type,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = excluded.modified_at` + fmt.Sprintf(sqliteFresh, "matches"),
+	EntityTeam: `INSERT INTO teams (id, name, slug, acronym, image_link, game_id, modified_at) VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET
+    name = excluded.name,
+    slug = excluded.slug,
+    acronym = excluded.acronym,
+    image_link = excluded.image_link,
+    game_id = excluded.game_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = excluded.modified_at` + fmt.Sprintf(sqliteFresh, "teams"),
+	EntityPlayer: `INSERT INTO players (id, name, slug, first_name, last_name, nationality, image_link, game_id, modified_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET
+    name = excluded.name,
+    slug = excluded.slug,
+    first_name = excluded.first_name,
+    last_name = excluded.last_name,
+    nationality = excluded.nationality,
+    image_link = excluded.image_link,
+    game_id = excluded.game_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = excluded.modified_at` + fmt.Sprintf(sqliteFresh, "players"),
+}
+
+const (
+	sqliteMatchColumns = `id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score,
+    team2_id, team2_score, amount_of_games, is_live, stream_url, status, forfeit, draw, rescheduled,
+    original_scheduled_at, begin_at, end_at, winner_id, winner_type, game_id, league_id, series_id,
+    tournament_id, last_seen_at, deleted_at, modified_at`
+	sqliteInsertMatchOpponent = `INSERT INTO match_opponents (match_id, slot, opponent_type, opponent_id, score, placement) VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (match_id, slot) DO UPDATE SET
+    opponent_type = excluded.opponent_type,
+    opponent_id = excluded.opponent_id,
+    score = excluded.score,
+    placement = excluded.placement`
+	sqliteTrimMatchOpponents  = `DELETE FROM match_opponents WHERE match_id = ? AND slot >= ?`
+	sqliteInsertMatchChange   = `INSERT INTO match_changes (match_id, field, old_value, new_value) VALUES (?, ?, ?, ?)`
+	sqliteInsertMissingTeam   = `INSERT INTO teams (id, name, slug, acronym, image_link, game_id, modified_at) VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET last_seen_at = excluded.last_seen_at, deleted_at = NULL WHERE teams.deleted_at IS NOT NULL OR julianday(teams.last_seen_at) < julianday('now', '-1 day')`
+	sqliteInsertMissingPlayer = `INSERT INTO players (id, name, slug, first_name, last_name, nationality, image_link, game_id, modified_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET last_seen_at = excluded.last_seen_at, deleted_at = NULL WHERE players.deleted_at IS NOT NULL OR julianday(players.last_seen_at) < julianday('now', '-1 day')`
+	sqliteMarkLive            = `UPDATE matches SET is_live = true WHERE id IN (SELECT value FROM json_each(?))`
+	sqliteClearLiveExcept     = `UPDATE matches SET is_live = false WHERE id NOT IN (SELECT value FROM json_each(?))`
+	sqliteEnqueueRetry        = `INSERT INTO resolve_retries (entity_type, entity_id, last_error) VALUES (?, ?, ?)
+ON CONFLICT (entity_type, entity_id) DO UPDATE SET last_error = excluded.last_error`
+	sqliteDueRetries = `SELECT entity_type, entity_id, attempts, last_error FROM resolve_retries
+WHERE attempts < ?
+ORDER BY last_attempt_at ASC NULLS FIRST, created_at ASC
+LIMIT ?`
+	sqliteMarkRetryFailed = `UPDATE resolve_retries
+SET attempts = attempts + 1, last_error = ?, last_attempt_at = CURRENT_TIMESTAMP
+WHERE entity_type = ? AND entity_id = ?`
+	sqliteDeleteRetry = `DELETE FROM resolve_retries WHERE entity_type = ? AND entity_id = ?`
+	// sqlitePlayedAt is the played_at of MATCHES_ARCHIVE, see static/schema.sql.
+	sqlitePlayedAt       = `COALESCE(end_at, begin_at, expected_start_time)`
+	sqliteArchivable     = `finished AND julianday(` + sqlitePlayedAt + `) < julianday(?)`
+	sqliteArchivableIDs  = `SELECT id FROM matches WHERE ` + sqliteArchivable + ` ORDER BY julianday(` + sqlitePlayedAt + `) ASC LIMIT ?`
+	sqliteArchiveMatches = `INSERT INTO matches_archive (` + sqliteMatchColumns + `, played_at, opponents, changes)
+SELECT ` + sqliteMatchColumns + `, ` + sqlitePlayedAt + `,
+    (SELECT json_group_array(json_object('match_id', o.match_id, 'slot', o.slot, 'opponent_type', o.opponent_type,
+        'opponent_id', o.opponent_id, 'score', o.score, 'placement', o.placement))
+     FROM (SELECT * FROM match_opponents WHERE match_id = matches.id ORDER BY slot) o),
+    (SELECT json_group_array(json(c.value)) FROM (
+        SELECT value FROM (
+            SELECT value, json_extract(value, '$.detected_at') AS detected_at FROM json_each((
+                SELECT a.changes FROM matches_archive a WHERE a.id = matches.id ORDER BY a.archived_at DESC LIMIT 1
+            ))
+            UNION ALL
+            SELECT json_object('id', id, 'match_id', match_id, 'field', field, 'old_value', old_value,
+                'new_value', new_value, 'detected_at', detected_at), detected_at
+            FROM match_changes WHERE match_id = matches.id
+        ) ORDER BY julianday(detected_at)
+    ) c)
+FROM matches WHERE id IN (SELECT value FROM json_each(?))
+ON CONFLICT (id, played_at) DO UPDATE SET
+    name = excluded.name, slug = excluded.slug, finished = excluded.finished,
+    expected_start_time = excluded.expected_start_time, actual_game_time = excluded.actual_game_time,
+    team1_id = excluded.team1_id, team1_score = excluded.team1_score, team2_id = excluded.team2_id,
+    team2_score = excluded.team2_score, amount_of_games = excluded.amount_of_games,
+    is_live = excluded.is_live, stream_url = excluded.stream_url, status = excluded.status,
+    forfeit = excluded.forfeit, draw = excluded.draw, rescheduled = excluded.rescheduled,
+    original_scheduled_at = excluded.original_scheduled_at, begin_at = excluded.begin_at,
+    end_at = excluded.end_at, winner_id = excluded.winner_id, winner_type = excluded.winner_type,
+    game_id = excluded.game_id, league_id = excluded.league_id, series_id = excluded.series_id,
+    tournament_id = excluded.tournament_id, last_seen_at = excluded.last_seen_at,
+    deleted_at = excluded.deleted_at, modified_at = excluded.modified_at, opponents = excluded.opponents,
+    changes = excluded.changes, archived_at = CURRENT_TIMESTAMP`
+	// sqliteDeleteSupersededArchive deletes the archived copies a match left in
+	// other partitions before its times changed, see ArchiveMatches.
+	sqliteDeleteSupersededArchive = `DELETE FROM matches_archive
+WHERE id IN (SELECT value FROM json_each(?))
+    AND played_at IS NOT (SELECT COALESCE(m.end_at, m.begin_at, m.expected_start_time) FROM matches m WHERE m.id = matches_archive.id)`
+	sqliteDeleteMatches            = `DELETE FROM matches WHERE id IN (SELECT value FROM json_each(?))`
+	sqliteCountExpiredURLMappings  = `SELECT COUNT(*) FROM url_mappings WHERE julianday(accessed_at) < julianday(?)`
+	sqliteDeleteExpiredURLMappings = `DELETE FROM url_mappings WHERE hashed_key IN (
+    SELECT hashed_key FROM url_mappings WHERE julianday(accessed_at) < julianday(?) ORDER BY accessed_at ASC LIMIT ?
+)`
+)
+
+// sqliteTables maps every entity to its table.
+var sqliteTables = map[Entity]string{
+	EntityGame:       "games",
+	EntityLeague:     "leagues",
+	EntitySeries:     "series",
+	EntityTournament: "tournaments",
+	EntityMatch:      "matches",
+	EntityTeam:       "teams",
+	EntityPlayer:     "players",
+}
+
+// sqlConn is implemented by *sql.DB and *sql.Tx.
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SQLiteSink is the Sink writing to an embedded SQLite database with the schema
+// of static/sqlite_schema.sql. It mirrors the upsert behaviour of PostgresSink
+// without its batching, which buys nothing on a local database.
+type SQLiteSink struct {
+	db   *sql.DB
+	conn sqlConn
+	// tx is the transaction conn is, nil outside of one.
+	tx *sql.Tx
+	// savepoints numbers the savepoints of tx, so nested ones get distinct names.
+	savepoints *int
+}
+
+// sqliteTx is an SQLiteSink bound to a transaction.
+type sqliteTx struct {
+	*SQLiteSink
+}
+
+// OpenSQLite opens the SQLite database at path through the "sqlite" driver of
+// modernc.org/sqlite, which the caller registers, and applies schema to it.
+// Foreign keys are enforced like in Postgres, and writers wait for each other
+// instead of failing with SQLITE_BUSY.
+// @param path - the database file, created when missing.
+// @param schema - the contents of static/sqlite_schema.sql.
+// @returns the database and an error if it could not be opened or migrated.
+func OpenSQLite(ctx context.Context, path string, schema string) (*sql.DB, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(30000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = db.ExecContext(ctx, schema); err != nil {
+		return nil, errors.Join(err, db.Close())
+	}
+	return db, nil
+}
+
+// NewSQLiteSink creates a sink on top of the given database.
+// @param db - an SQLite database with the schema of static/sqlite_schema.sql applied.
+// @returns the sink.
+func NewSQLiteSink(db *sql.DB) *SQLiteSink {
+	return &SQLiteSink{db: db, conn: db, tx: nil, savepoints: nil}
+}
+
+// Begin starts a transaction and returns a sink bound to it. Inside of a
+// transaction it returns ErrNoTransactions.
+func (sink *SQLiteSink) Begin(ctx context.Context) (TxSink, error) {
+	if sink.tx != nil {
+		return nil, ErrNoTransactions
+	}
+	tx, err := sink.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteTx{&SQLiteSink{db: sink.db, conn: tx, tx: tx, savepoints: new(int)}}, nil
+}
+
+func (sink sqliteTx) Commit(context.Context) error {
+	return sink.tx.Commit()
+}
+
+func (sink sqliteTx) Rollback(context.Context) error {
+	return sink.tx.Rollback()
+}
+
+// Savepoint runs fn under a savepoint of the transaction of the sink and rolls
+// back to it when fn fails. Outside of a transaction fn runs directly.
+func (sink *SQLiteSink) Savepoint(ctx context.Context, fn func() error) error {
+	if sink.tx == nil {
+		return fn()
+	}
+	*sink.savepoints++
+	name := fmt.Sprintf("sp%d", *sink.savepoints)
+	if _, err := sink.conn.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		// ROLLBACK TO keeps the savepoint open, RELEASE drops it
+		_, rollbackErr := sink.conn.ExecContext(ctx, "ROLLBACK TO "+name)
+		if rollbackErr == nil {
+			_, rollbackErr = sink.conn.ExecContext(ctx, "RELEASE "+name)
+		}
+		if rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	_, err := sink.conn.ExecContext(ctx, "RELEASE "+name)
+	return err
+}
+
+// sqliteTime converts a timestamp into its stored text form, NULL when unset.
+func sqliteTime(value pgtype.Timestamptz) any {
+	if !value.Valid {
+		return nil
+	}
+	return value.Time.UTC().Format(sqliteTimeFormat)
+}
+
+// sqliteIDs encodes IDs as a JSON array for json_each, SQLite has no array parameters.
+func sqliteIDs(ids []int32) (string, error) {
+	if ids == nil {
+		ids = []int32{}
+	}
+	encoded, err := json.Marshal(ids)
+	return string(encoded), err
+}
+
+// upsert runs the upsert of entity and reports whether a row was written.
+func (sink *SQLiteSink) upsert(ctx context.Context, entity Entity, args ...any) (bool, error) {
+	result, err := sink.conn.ExecContext(ctx, sqliteUpserts[entity], args...)
+	if err != nil {
+		return false, err
+	}
+	written, err := result.RowsAffected()
+	return written > 0, err
+}
+
+// UpsertGame upserts the game. PandaScore sends no modified_at for games, so
+// they are always written.
+func (sink *SQLiteSink) UpsertGame(ctx context.Context, row GameRow) (bool, error) {
+	id, err := SafeIntToInt32(row.ID)
+	if err != nil {
+		return false, err
+	}
+	_, err = sink.upsert(ctx, EntityGame, id, row.Name, pgtype.Text{String: row.Slug, Valid: row.Slug != ""})
+	return err == nil, err
+}
+
+func (sink *SQLiteSink) UpsertLeague(ctx context.Context, row LeagueRow) (bool, error) {
+	id, err := SafeIntToInt32(row.ID)
+	if err != nil {
+		return false, err
+	}
+	gameID, err := SafeIntToInt32(row.GameID)
+	if err != nil {
+		return false, err
+	}
+	return sink.upsert(ctx, EntityLeague, id, row.Name,
+		pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
+		pgtype.Text{String: row.ImageLink, Valid: row.ImageLink != ""},
+		gameID, sqliteTime(toTimestamptz(row.ModifiedAt)))
+}
+
+func (sink *SQLiteSink) UpsertSeries(ctx context.Context, row SeriesRow) (bool, error) {
+	id, err := SafeIntToInt32(row.ID)
+	if err != nil {
+		return false, err
+	}
+	gameID, err := SafeIntToInt32(row.GameID)
+	if err != nil {
+		return false, err
+	}
+	leagueID, err := SafeIntToInt32(row.LeagueID)
+	if err != nil {
+		return false, err
+	}
+	return sink.upsert(ctx, EntitySeries, id, row.Name,
+		pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
+		gameID, leagueID, sqliteTime(toTimestamptz(row.ModifiedAt)))
+}
+
+func (sink *SQLiteSink) UpsertTournament(ctx context.Context, row TournamentRow) (bool, error) {
+	id, err := SafeIntToInt32(row.ID)
+	if err != nil {
+		return false, err
+	}
+	gameID, err := SafeIntToInt32(row.GameID)
+	if err != nil {
+		return false, err
+	}
+	leagueID, err := SafeIntToInt32(row.LeagueID)
+	if err != nil {
+		return false, err
+	}
+	serieID, err := SafeIntToInt32(row.SerieID)
+	if err != nil {
+		return false, err
+	}
+	//nolint:gosec // tier is fixed by the switch statement of TournamentLike.ToRow
+	tier := int32(row.Tier)
+	return sink.upsert(ctx, EntityTournament, id, row.Name,
+		pgtype.Text{String: row.Slug, Valid: row.Slug != ""},
+		pgtype.Int4{Int32: tier, Valid: row.Tier != 0},
+		gameID, leagueID, serieID, sqliteTime(toTimestamptz(row.ModifiedAt)))
+}
+
+// UpsertMatch upserts the match and its opponents and records every tracked field
+// that changed compared to the stored row in MATCH_CHANGES, like PostgresSink.
+func (sink *SQLiteSink) UpsertMatch(ctx context.Context, row MatchRow) (bool, error) {
+	counts, err := sink.writeMatch(ctx, row)
+	return counts.Updated > 0, err
+}
+
+// writeMatch is UpsertMatch, reporting whether the match was updated,
+// refreshed or skipped.
+func (sink *SQLiteSink) writeMatch(ctx context.Context, row MatchRow) (WriteCounts, error) {
+	params, err := row.ToParams()
+	if err != nil {
+		return WriteCounts{}, err
+	}
+	stored, err := sink.getMatch(ctx, params.ID)
+	var changes []MatchChange
+	refreshed := false
+	switch {
+	case err == nil:
+		if matchUpToDate(stored, params.ModifiedAt, time.Now()) {
+			return countMatch(false, false), nil
+		}
+		refreshed = matchUnchanged(stored, params.ModifiedAt)
+		changes = DiffMatch(stored, params)
+	case errors.Is(err, sql.ErrNoRows):
+		previous, archiveErr := sink.getArchivedMatch(ctx, params.ID)
+		if errors.Is(archiveErr, sql.ErrNoRows) {
+			// first time we see this match, there is no history to record
+			break
+		}
+		if archiveErr != nil {
+			return WriteCounts{}, archiveErr
+		}
+		if keepArchived(params) {
+			return countMatch(false, false), nil
+		}
+		changes = DiffMatch(previous, params)
+	default:
+		return WriteCounts{}, err
+	}
+	written, err := sink.upsert(ctx, EntityMatch,
+		params.ID, params.Name, params.Slug, params.Finished, sqliteTime(params.ExpectedStartTime),
+		params.ActualGameTime, params.Team1ID, params.Team1Score, params.Team2ID, params.Team2Score,
+		params.AmountOfGames, params.GameID, params.LeagueID, params.SeriesID, params.TournamentID,
+		params.StreamURL, params.Status, params.Forfeit, params.Draw, params.Rescheduled,
+		sqliteTime(params.OriginalScheduledAt), sqliteTime(params.BeginAt), sqliteTime(params.EndAt),
+		params.WinnerID, params.WinnerType, sqliteTime(params.ModifiedAt))
+	if err != nil {
+		return WriteCounts{}, err
+	}
+	if !written {
+		// nothing written means a newer version was stored since the lookup
+		return countMatch(false, false), nil
+	}
+	for _, opponent := range row.Opponents {
+		opponentParams, opponentErr := opponent.toParams(params.ID)
+		if opponentErr != nil {
+			return WriteCounts{}, opponentErr
+		}
+		_, err = sink.conn.ExecContext(ctx, sqliteInsertMatchOpponent, opponentParams.MatchID, opponentParams.Slot,
+			opponentParams.OpponentType, opponentParams.OpponentID, opponentParams.Score, opponentParams.Placement)
+		if err != nil {
+			return WriteCounts{}, err
+		}
+	}
+	_, err = sink.conn.ExecContext(ctx, sqliteTrimMatchOpponents, params.ID, len(row.Opponents))
+	if err != nil {
+		return WriteCounts{}, err
+	}
+	for _, change := range changes {
+		_, err = sink.conn.ExecContext(ctx, sqliteInsertMatchChange, params.ID, change.Field,
+			pgtype.Text{String: change.OldValue, Valid: change.OldValue != ""},
+			pgtype.Text{String: change.NewValue, Valid: change.NewValue != ""})
+		if err != nil {
+			return WriteCounts{}, err
+		}
+	}
+	return countMatch(true, refreshed), nil
+}
+
+// getMatch reads a stored match into the sqlc model, so DiffMatch and
+// matchUpToDate are shared with PostgresSink.
+func (sink *SQLiteSink) getMatch(ctx context.Context, id int32) (dbtypes.Match, error) {
+	return scanSQLiteMatch(sink.conn.QueryRowContext(ctx, "SELECT "+sqliteMatchColumns+" FROM matches WHERE id = ?", id))
+}
+
+// getArchivedMatch reads the latest archived copy of a match, like getMatch.
+func (sink *SQLiteSink) getArchivedMatch(ctx context.Context, id int32) (dbtypes.Match, error) {
+	return scanSQLiteMatch(sink.conn.QueryRowContext(ctx,
+		"SELECT "+sqliteMatchColumns+" FROM matches_archive WHERE id = ? ORDER BY archived_at DESC LIMIT 1", id))
+}
+
+// scanSQLiteMatch scans the match columns of row into the sqlc model.
+func scanSQLiteMatch(row *sql.Row) (dbtypes.Match, error) {
+	var i dbtypes.Match
+	var stamps [7]sql.NullTime
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Slug,
+		&i.Finished,
+		&stamps[0],
+		&i.ActualGameTime,
+		&i.Team1ID,
+		&i.Team1Score,
+		&i.Team2ID,
+		&i.Team2Score,
+		&i.AmountOfGames,
+		&i.IsLive,
+		&i.StreamURL,
+		&i.Status,
+		&i.Forfeit,
+		&i.Draw,
+		&i.Rescheduled,
+		&stamps[1],
+		&stamps[2],
+		&stamps[3],
+		&i.WinnerID,
+		&i.WinnerType,
+		&i.GameID,
+		&i.LeagueID,
+		&i.SeriesID,
+		&i.TournamentID,
+		&stamps[4],
+		&stamps[5],
+		&stamps[6],
+	)
+	targets := []*pgtype.Timestamptz{
+		&i.ExpectedStartTime, &i.OriginalScheduledAt, &i.BeginAt, &i.EndAt, &i.LastSeenAt, &i.DeletedAt, &i.ModifiedAt,
+	}
+	for n, stamp := range stamps {
+		*targets[n] = pgtype.Timestamptz{Time: stamp.Time, Valid: stamp.Valid, InfinityModifier: 0}
+	}
+	return i, err
+}
+
+func (sink *SQLiteSink) UpsertTeam(ctx context.Context, row TeamRow) (bool, error) {
+	params, err := row.ToParams()
+	if err != nil {
+		return false, err
+	}
+	return sink.upsert(ctx, EntityTeam, params.ID, params.Name, params.Slug, params.Acronym,
+		params.ImageLink, params.GameID, sqliteTime(params.ModifiedAt))
+}
+
+func (sink *SQLiteSink) UpsertPlayer(ctx context.Context, row PlayerRow) (bool, error) {
+	params, err := row.ToParams()
+	if err != nil {
+		return false, err
+	}
+	return sink.upsert(ctx, EntityPlayer, params.ID, params.Name, params.Slug, params.FirstName,
+		params.LastName, params.Nationality, params.ImageLink, params.GameID, sqliteTime(params.ModifiedAt))
+}
+
+// WriteMatchPage upserts the matches one by one, each under its own savepoint
+// inside a transaction.
+func (sink *SQLiteSink) WriteMatchPage(ctx context.Context, rows []MatchRow) (WriteCounts, []RowError) {
+	var counts WriteCounts
+	var rowErrs []RowError
+	for _, row := range rows {
+		var written WriteCounts
+		err := sink.Savepoint(ctx, func() error {
+			var writeErr error
+			written, writeErr = sink.writeMatch(ctx, row)
+			return writeErr
+		})
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{ID: row.ID, Err: err})
+			continue
+		}
+		counts.Add(written)
+	}
+	return counts, rowErrs
+}
+
+// WriteMissingOpponents creates the given teams and players. Of the ones that
+// already exist only a tombstone is cleared and a stale last_seen_at refreshed,
+// like PostgresSink.
+func (sink *SQLiteSink) WriteMissingOpponents(ctx context.Context, teams []TeamRow, players []PlayerRow) []RowError {
+	var rowErrs []RowError
+	for _, team := range teams {
+		err := sink.Savepoint(ctx, func() error {
+			params, err := team.ToParams()
+			if err != nil {
+				return err
+			}
+			_, err = sink.conn.ExecContext(ctx, sqliteInsertMissingTeam, params.ID, params.Name, params.Slug,
+				params.Acronym, params.ImageLink, params.GameID, sqliteTime(params.ModifiedAt))
+			return err
+		})
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{ID: team.ID, Err: err})
+		}
+	}
+	for _, player := range players {
+		err := sink.Savepoint(ctx, func() error {
+			params, err := player.ToParams()
+			if err != nil {
+				return err
+			}
+			_, err = sink.conn.ExecContext(ctx, sqliteInsertMissingPlayer, params.ID, params.Name, params.Slug,
+				params.FirstName, params.LastName, params.Nationality, params.ImageLink, params.GameID,
+				sqliteTime(params.ModifiedAt))
+			return err
+		})
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{ID: player.ID, Err: err})
+		}
+	}
+	return rowErrs
+}
+
+// table returns the table of entity.
+func (sink *SQLiteSink) table(entity Entity) (string, error) {
+	table, ok := sqliteTables[entity]
+	if !ok {
+		return "", fmt.Errorf("invalid entity: %d", entity)
+	}
+	return table, nil
+}
+
+// queryIDs runs a query returning a single ID column.
+func (sink *SQLiteSink) queryIDs(ctx context.Context, query string, args ...any) ([]int32, error) {
+	rows, err := sink.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int32
+	for rows.Next() {
+		var id int32
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (sink *SQLiteSink) Exists(ctx context.Context, entity Entity, id int32) (bool, error) {
+	table, err := sink.table(entity)
+	if err != nil {
+		return false, err
+	}
+	var count int64
+	err = sink.conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM "+table+" WHERE id = ? AND deleted_at IS NULL", id).Scan(&count)
+	return count > 0, err
+}
+
+// ExistingIDs supports the same entities as PostgresSink.ExistingIDs.
+func (sink *SQLiteSink) ExistingIDs(ctx context.Context, entity Entity, ids []int32) ([]int32, error) {
+	if entity == EntityMatch {
+		return nil, fmt.Errorf("entity %d has no bulk existence check", entity)
+	}
+	table, err := sink.table(entity)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := sqliteIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	return sink.queryIDs(ctx,
+		"SELECT id FROM "+table+" WHERE id IN (SELECT value FROM json_each(?)) AND deleted_at IS NULL", encoded)
+}
+
+// KnownIDs supports the same entities as PostgresSink.KnownIDs.
+func (sink *SQLiteSink) KnownIDs(ctx context.Context, entity Entity, limit int32) ([]int32, error) {
+	if entity == EntityMatch {
+		return nil, fmt.Errorf("entity %d has no known ID lookup", entity)
+	}
+	table, err := sink.table(entity)
+	if err != nil {
+		return nil, err
+	}
+	return sink.queryIDs(ctx,
+		"SELECT id FROM "+table+" WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT ?", limit)
+}
+
+// StaleIDs only supports the reconciled tournaments, matches and teams.
+func (sink *SQLiteSink) StaleIDs(ctx context.Context, entity Entity, cutoff time.Time, limit int32) ([]int32, error) {
+	filter := ""
+	switch entity {
+	case EntityMatch:
+		filter = " AND finished = false"
+	case EntityTournament, EntityTeam:
+	case EntityGame, EntityLeague, EntitySeries, EntityPlayer:
+		return nil, fmt.Errorf("entity %d is not reconciled", entity)
+	default:
+		return nil, fmt.Errorf("invalid entity: %d", entity)
+	}
+	return sink.queryIDs(ctx, "SELECT id FROM "+sqliteTables[entity]+
+		" WHERE deleted_at IS NULL"+filter+" AND julianday(last_seen_at) < julianday(?)"+
+		" ORDER BY last_seen_at ASC LIMIT ?", sqliteTime(toTimestamptz(cutoff)), limit)
+}
+
+// Tombstone only supports the reconciled tournaments, matches and teams.
+func (sink *SQLiteSink) Tombstone(ctx context.Context, entity Entity, id int32) error {
+	var err error
+	switch entity {
+	case EntityMatch:
+		_, err = sink.conn.ExecContext(ctx,
+			"UPDATE matches SET deleted_at = CURRENT_TIMESTAMP, is_live = false WHERE id = ?", id)
+	case EntityTournament, EntityTeam:
+		_, err = sink.conn.ExecContext(ctx,
+			"UPDATE "+sqliteTables[entity]+" SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?", id)
+	case EntityGame, EntityLeague, EntitySeries, EntityPlayer:
+		return fmt.Errorf("entity %d cannot be tombstoned", entity)
+	default:
+		return fmt.Errorf("invalid entity: %d", entity)
+	}
+	return err
+}
+
+func (sink *SQLiteSink) MarkLive(ctx context.Context, ids []int32) error {
+	encoded, err := sqliteIDs(ids)
+	if err != nil {
+		return err
+	}
+	_, err = sink.conn.ExecContext(ctx, sqliteMarkLive, encoded)
+	return err
+}
+
+func (sink *SQLiteSink) ClearLiveExcept(ctx context.Context, ids []int32) error {
+	encoded, err := sqliteIDs(ids)
+	if err != nil {
+		return err
+	}
+	_, err = sink.conn.ExecContext(ctx, sqliteClearLiveExcept, encoded)
+	return err
+}
+
+func (sink *SQLiteSink) EnqueueRetry(ctx context.Context, entityType string, id int32, lastError string) error {
+	_, err := sink.conn.ExecContext(ctx, sqliteEnqueueRetry, entityType, id, lastError)
+	return err
+}
+
+func (sink *SQLiteSink) DueRetries(ctx context.Context, maxAttempts int32, limit int32) ([]Retry, error) {
+	rows, err := sink.conn.QueryContext(ctx, sqliteDueRetries, maxAttempts, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var retries []Retry
+	for rows.Next() {
+		var retry Retry
+		var lastError sql.NullString
+		if err = rows.Scan(&retry.EntityType, &retry.EntityID, &retry.Attempts, &lastError); err != nil {
+			return nil, err
+		}
+		retry.LastError = lastError.String
+		retries = append(retries, retry)
+	}
+	return retries, rows.Err()
+}
+
+func (sink *SQLiteSink) MarkRetryFailed(ctx context.Context, entityType string, id int32, lastError string) error {
+	_, err := sink.conn.ExecContext(ctx, sqliteMarkRetryFailed, lastError, entityType, id)
+	return err
+}
+
+func (sink *SQLiteSink) DeleteRetry(ctx context.Context, entityType string, id int32) error {
+	_, err := sink.conn.ExecContext(ctx, sqliteDeleteRetry, entityType, id)
+	return err
+}
+
+func (sink *SQLiteSink) CountExpired(ctx context.Context, target RetentionTarget, cutoff time.Time) (int64, error) {
+	query := ""
+	switch target {
+	case RetainMatches:
+		query = "SELECT COUNT(*) FROM matches WHERE " + sqliteArchivable
+	case RetainURLMappings:
+		query = sqliteCountExpiredURLMappings
+	default:
+		return 0, fmt.Errorf("invalid retention target: %d", target)
+	}
+	var count int64
+	err := sink.conn.QueryRowContext(ctx, query, sqliteTime(toTimestamptz(cutoff))).Scan(&count)
+	return count, err
+}
+
+// Expire moves matches into MATCHES_ARCHIVE and deletes URL mappings, like
+// PostgresSink.Expire. SQLite has no data-modifying CTEs, so matches are copied,
+// their archived copies of other played_at dates deleted, and the matches
+// deleted by ID in one transaction.
+func (sink *SQLiteSink) Expire(ctx context.Context, target RetentionTarget, cutoff time.Time, limit int32) (int64, error) {
+	switch target {
+	case RetainMatches:
+		var moved int64
+		err := sink.atomically(ctx, func(tx *SQLiteSink) error {
+			ids, err := tx.queryIDs(ctx, sqliteArchivableIDs, sqliteTime(toTimestamptz(cutoff)), limit)
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+			encoded, err := sqliteIDs(ids)
+			if err != nil {
+				return err
+			}
+			if _, err = tx.conn.ExecContext(ctx, sqliteArchiveMatches, encoded); err != nil {
+				return err
+			}
+			if _, err = tx.conn.ExecContext(ctx, sqliteDeleteSupersededArchive, encoded); err != nil {
+				return err
+			}
+			// opponents and changes are removed by ON DELETE CASCADE
+			result, err := tx.conn.ExecContext(ctx, sqliteDeleteMatches, encoded)
+			if err != nil {
+				return err
+			}
+			moved, err = result.RowsAffected()
+			return err
+		})
+		return moved, err
+	case RetainURLMappings:
+		result, err := sink.conn.ExecContext(ctx, sqliteDeleteExpiredURLMappings,
+			sqliteTime(toTimestamptz(cutoff)), limit)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	default:
+		return 0, fmt.Errorf("invalid retention target: %d", target)
+	}
+}
+
+// atomically runs fn with a sink bound to a transaction: the one of the sink
+// when inside of one, a new one committed after fn otherwise.
+func (sink *SQLiteSink) atomically(ctx context.Context, fn func(tx *SQLiteSink) error) error {
+	if sink.tx != nil {
+		return fn(sink)
+	}
+	tx, err := sink.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx.(sqliteTx).SQLiteSink); err != nil {
+		return errors.Join(err, tx.Rollback(ctx))
+	}
+	return tx.Commit(ctx)
+}
+
+// RefreshViews does nothing: the views of static/sqlite_schema.sql are plain
+// views, SQLite has no materialized ones.
+func (sink *SQLiteSink) RefreshViews(context.Context) error {
+	return nil
+}
+
+// SyncCompleted does nothing: SQLite has no notifications, readers poll.
+func (sink *SQLiteSink) SyncCompleted(context.Context, string) error {
+	return nil
+}
@@ -0,0 +1,415 @@
+package pandatypes
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+	_ "modernc.org/sqlite"
+)
+
+// openTestSQLite opens an empty SQLite database with the schema of static/sqlite_schema.sql.
+func openTestSQLite(t *testing.T) *SQLiteSink {
+	schema, err := os.ReadFile("../static/sqlite_schema.sql")
+	st.Assert(t, err, nil)
+	db, err := OpenSQLite(t.Context(), filepath.Join(t.TempDir(), "stalka.db"), string(schema))
+	st.Assert(t, err, nil)
+	t.Cleanup(func() { _ = db.Close() })
+	return NewSQLiteSink(db)
+}
+
+// fixtureMatch returns the match of the fixture together with the rows it depends on.
+func fixtureMatch(t *testing.T) (MatchRow, []RowLike) {
+	data, err := os.ReadFile("../static/fetch_data/matches.json")
+	st.Assert(t, err, nil)
+	var match MatchLike
+	err = json.Unmarshal(data, &match)
+	st.Assert(t, err, nil)
+	row := match.ToRow().(MatchRow)
+	deps := []RowLike{
+		GameRow{ID: row.GameID, Name: "LoL", Slug: "league-of-legends"},
+		LeagueRow{ID: row.LeagueID, Name: "League", GameID: row.GameID},
+		SeriesRow{ID: row.SerieID, Name: "Series", GameID: row.GameID, LeagueID: row.LeagueID},
+		TournamentRow{
+			ID: row.TournamentID, Name: "Tournament", Tier: 1, GameID: row.GameID,
+			LeagueID: row.LeagueID, SerieID: row.SerieID,
+		},
+	}
+	return row, deps
+}
+
+func TestSQLiteSinkUpserts(t *testing.T) {
+	sink := openTestSQLite(t)
+	row, deps := fixtureMatch(t)
+	for _, dep := range deps {
+		written, err := dep.WriteToDB(t.Context(), sink)
+		st.Assert(t, err, nil)
+		st.Assert(t, written, true)
+	}
+
+	t.Run("Rows at least as new as the stored one are skipped", func(t *testing.T) {
+		modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		team := TeamRow{ID: 1, GameID: row.GameID, Name: "T1", ModifiedAt: modified}
+		written, err := sink.UpsertTeam(t.Context(), team)
+		st.Expect(t, err, nil)
+		st.Expect(t, written, true)
+
+		written, err = sink.UpsertTeam(t.Context(), team)
+		st.Expect(t, err, nil)
+		st.Expect(t, written, false)
+
+		team.ModifiedAt = modified.Add(time.Second)
+		written, err = sink.UpsertTeam(t.Context(), team)
+		st.Expect(t, err, nil)
+		st.Expect(t, written, true)
+	})
+
+	t.Run("Existing opponents keep their data but are seen again", func(t *testing.T) {
+		unseen := toTimestamptz(time.Now().Add(-2 * SeenRefreshInterval))
+		_, err := sink.db.ExecContext(t.Context(), "UPDATE teams SET last_seen_at = ? WHERE id = 1", sqliteTime(unseen))
+		st.Assert(t, err, nil)
+		cutoff := time.Now().Add(-SeenRefreshInterval)
+		stale, err := sink.StaleIDs(t.Context(), EntityTeam, cutoff, 10)
+		st.Expect(t, err, nil)
+		st.Expect(t, stale, []int32{1})
+
+		rowErrs := sink.WriteMissingOpponents(t.Context(), []TeamRow{{ID: 1, GameID: row.GameID, Name: "Renamed"}}, nil)
+		st.Expect(t, len(rowErrs), 0)
+		stale, err = sink.StaleIDs(t.Context(), EntityTeam, cutoff, 10)
+		st.Expect(t, err, nil)
+		st.Expect(t, len(stale), 0)
+		var name string
+		st.Expect(t, sink.db.QueryRowContext(t.Context(), "SELECT name FROM teams WHERE id = 1").Scan(&name), nil)
+		st.Expect(t, name, "T1")
+	})
+
+	t.Run("Opponents seen recently are not rewritten", func(t *testing.T) {
+		seen := toTimestamptz(time.Now().Add(-time.Hour).UTC())
+		_, err := sink.db.ExecContext(t.Context(), "UPDATE teams SET last_seen_at = ? WHERE id = 1", sqliteTime(seen))
+		st.Assert(t, err, nil)
+
+		rowErrs := sink.WriteMissingOpponents(t.Context(), []TeamRow{{ID: 1, GameID: row.GameID, Name: "T1"}}, nil)
+		st.Expect(t, len(rowErrs), 0)
+		var unchanged int
+		st.Expect(t, sink.db.QueryRowContext(t.Context(),
+			"SELECT COUNT(*) FROM teams WHERE id = 1 AND last_seen_at = ?", sqliteTime(seen)).Scan(&unchanged), nil)
+		st.Expect(t, unchanged, 1)
+	})
+
+	t.Run("Tombstoned opponents are restored by the backfill", func(t *testing.T) {
+		st.Assert(t, sink.Tombstone(t.Context(), EntityTeam, 1), nil)
+		existing, err := sink.ExistingIDs(t.Context(), EntityTeam, []int32{1})
+		st.Expect(t, err, nil)
+		st.Expect(t, len(existing), 0)
+
+		rowErrs := sink.WriteMissingOpponents(t.Context(), []TeamRow{{ID: 1, GameID: row.GameID, Name: "T1"}}, nil)
+		st.Expect(t, len(rowErrs), 0)
+		existing, err = sink.ExistingIDs(t.Context(), EntityTeam, []int32{1})
+		st.Expect(t, err, nil)
+		st.Expect(t, existing, []int32{1})
+	})
+
+	t.Run("Match changes and opponents are recorded", func(t *testing.T) {
+		written, err := sink.UpsertMatch(t.Context(), row)
+		st.Expect(t, err, nil)
+		st.Expect(t, written, true)
+		// the same version is skipped
+		written, err = sink.UpsertMatch(t.Context(), row)
+		st.Expect(t, err, nil)
+		st.Expect(t, written, false)
+
+		changed := row
+		changed.Status = MatchStatusRunning
+		changed.ModifiedAt = row.ModifiedAt.Add(time.Minute)
+		written, err = sink.UpsertMatch(t.Context(), changed)
+		st.Expect(t, err, nil)
+		st.Expect(t, written, true)
+
+		var oldValue, newValue string
+		err = sink.db.QueryRowContext(t.Context(),
+			"SELECT old_value, new_value FROM match_changes WHERE match_id = ? AND field = ?",
+			row.ID, FieldStatus).Scan(&oldValue, &newValue)
+		st.Expect(t, err, nil)
+		st.Expect(t, oldValue, MatchStatusFinished)
+		st.Expect(t, newValue, MatchStatusRunning)
+
+		var opponents int
+		err = sink.db.QueryRowContext(t.Context(),
+			"SELECT COUNT(*) FROM match_opponents WHERE match_id = ?", row.ID).Scan(&opponents)
+		st.Expect(t, err, nil)
+		st.Expect(t, opponents, len(row.Opponents))
+	})
+
+	t.Run("Unchanged matches unseen for too long are only refreshed", func(t *testing.T) {
+		changed := row
+		changed.Status = MatchStatusRunning
+		changed.ModifiedAt = row.ModifiedAt.Add(time.Minute)
+		unseen := toTimestamptz(time.Now().Add(-2 * SeenRefreshInterval))
+		_, err := sink.db.ExecContext(t.Context(),
+			"UPDATE matches SET last_seen_at = ? WHERE id = ?", sqliteTime(unseen), row.ID)
+		st.Assert(t, err, nil)
+
+		counts, rowErrs := sink.WriteMatchPage(t.Context(), []MatchRow{changed})
+		st.Expect(t, len(rowErrs), 0)
+		st.Expect(t, counts, WriteCounts{Updated: 0, Skipped: 0, Refreshed: 1})
+		// once refreshed, the match is skipped again
+		counts, rowErrs = sink.WriteMatchPage(t.Context(), []MatchRow{changed})
+		st.Expect(t, len(rowErrs), 0)
+		st.Expect(t, counts, WriteCounts{Updated: 0, Skipped: 1, Refreshed: 0})
+	})
+
+	t.Run("Tombstoned rows count as missing and come back when written", func(t *testing.T) {
+		st.Expect(t, sink.Tombstone(t.Context(), EntityTeam, 1), nil)
+		exists, err := sink.Exists(t.Context(), EntityTeam, 1)
+		st.Expect(t, err, nil)
+		st.Expect(t, exists, false)
+
+		written, err := sink.UpsertTeam(t.Context(), TeamRow{ID: 1, GameID: row.GameID, Name: "T1"})
+		st.Expect(t, err, nil)
+		st.Expect(t, written, true)
+		existing, err := sink.ExistingIDs(t.Context(), EntityTeam, []int32{1, 2})
+		st.Expect(t, err, nil)
+		st.Expect(t, existing, []int32{1})
+	})
+
+	t.Run("Error - foreign keys are enforced", func(t *testing.T) {
+		_, err := sink.UpsertLeague(t.Context(), LeagueRow{ID: 2, Name: "Orphan", GameID: 999})
+		st.Reject(t, err, nil)
+	})
+}
+
+func TestSQLiteSinkTransactions(t *testing.T) {
+	sink := openTestSQLite(t)
+	game := GameRow{ID: 1, Name: "LoL"}
+
+	t.Run("Failed savepoints roll back their writes only", func(t *testing.T) {
+		tx, err := sink.Begin(t.Context())
+		st.Assert(t, err, nil)
+		_, err = tx.Begin(t.Context())
+		st.Expect(t, errors.Is(err, ErrNoTransactions), true)
+
+		err = tx.Savepoint(t.Context(), func() error {
+			_, writeErr := tx.UpsertGame(t.Context(), game)
+			return writeErr
+		})
+		st.Expect(t, err, nil)
+		err = tx.Savepoint(t.Context(), func() error {
+			_, writeErr := tx.UpsertLeague(t.Context(), LeagueRow{ID: 1, Name: "League", GameID: 1})
+			if writeErr != nil {
+				return writeErr
+			}
+			return errors.New("row failed")
+		})
+		st.Reject(t, err, nil)
+		st.Expect(t, tx.Commit(t.Context()), nil)
+
+		exists, err := sink.Exists(t.Context(), EntityGame, 1)
+		st.Expect(t, err, nil)
+		st.Expect(t, exists, true)
+		exists, err = sink.Exists(t.Context(), EntityLeague, 1)
+		st.Expect(t, err, nil)
+		st.Expect(t, exists, false)
+	})
+
+	t.Run("Rolled back transactions write nothing", func(t *testing.T) {
+		tx, err := sink.Begin(t.Context())
+		st.Assert(t, err, nil)
+		_, err = tx.UpsertGame(t.Context(), GameRow{ID: 2, Name: "Dota 2"})
+		st.Expect(t, err, nil)
+		st.Expect(t, tx.Rollback(t.Context()), nil)
+
+		known, err := sink.KnownIDs(t.Context(), EntityGame, 10)
+		st.Expect(t, err, nil)
+		st.Expect(t, known, []int32{1})
+	})
+}
+
+func TestSQLiteSinkRetries(t *testing.T) {
+	sink := openTestSQLite(t)
+
+	st.Expect(t, sink.EnqueueRetry(t.Context(), "tournaments", 9, "series 3 not found"), nil)
+	// queueing again keeps a single entry
+	st.Expect(t, sink.EnqueueRetry(t.Context(), "tournaments", 9, "series 3 not found"), nil)
+	st.Expect(t, sink.MarkRetryFailed(t.Context(), "tournaments", 9, "boom"), nil)
+
+	retries, err := sink.DueRetries(t.Context(), 5, 25)
+	st.Expect(t, err, nil)
+	st.Expect(t, retries, []Retry{{EntityType: "tournaments", EntityID: 9, Attempts: 1, LastError: "boom"}})
+
+	retries, err = sink.DueRetries(t.Context(), 1, 25)
+	st.Expect(t, err, nil)
+	st.Expect(t, len(retries), 0)
+
+	st.Expect(t, sink.DeleteRetry(t.Context(), "tournaments", 9), nil)
+	retries, err = sink.DueRetries(t.Context(), 5, 25)
+	st.Expect(t, err, nil)
+	st.Expect(t, len(retries), 0)
+}
+
+func TestSQLiteSinkRetention(t *testing.T) {
+	sink := openTestSQLite(t)
+	row, deps := fixtureMatch(t)
+	for _, dep := range deps {
+		_, err := dep.WriteToDB(t.Context(), sink)
+		st.Assert(t, err, nil)
+	}
+	_, err := sink.UpsertMatch(t.Context(), row)
+	st.Assert(t, err, nil)
+	changed := row
+	changed.Status = MatchStatusRunning
+	changed.ModifiedAt = row.ModifiedAt.Add(time.Minute)
+	_, err = sink.UpsertMatch(t.Context(), changed)
+	st.Assert(t, err, nil)
+
+	t.Run("Finished matches are moved into the archive with their opponents and changes", func(t *testing.T) {
+		count, err := sink.CountExpired(t.Context(), RetainMatches, time.Now())
+		st.Expect(t, err, nil)
+		st.Expect(t, count, int64(1))
+		// nothing was played before the cutoff
+		count, err = sink.CountExpired(t.Context(), RetainMatches, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+		st.Expect(t, err, nil)
+		st.Expect(t, count, int64(0))
+
+		moved, err := sink.Expire(t.Context(), RetainMatches, time.Now(), 10)
+		st.Expect(t, err, nil)
+		st.Expect(t, moved, int64(1))
+		exists, err := sink.Exists(t.Context(), EntityMatch, int32(row.ID))
+		st.Expect(t, err, nil)
+		st.Expect(t, exists, false)
+
+		var opponents, changes string
+		err = sink.db.QueryRowContext(t.Context(),
+			"SELECT opponents, changes FROM matches_archive WHERE id = ?", row.ID).Scan(&opponents, &changes)
+		st.Expect(t, err, nil)
+		var archivedOpponents, archivedChanges []map[string]any
+		st.Expect(t, json.Unmarshal([]byte(opponents), &archivedOpponents), nil)
+		st.Expect(t, json.Unmarshal([]byte(changes), &archivedChanges), nil)
+		st.Expect(t, len(archivedOpponents), len(row.Opponents))
+		st.Expect(t, len(archivedChanges) > 0, true)
+
+		var leftover int
+		err = sink.db.QueryRowContext(t.Context(),
+			"SELECT COUNT(*) FROM match_opponents WHERE match_id = ?", row.ID).Scan(&leftover)
+		st.Expect(t, err, nil)
+		st.Expect(t, leftover, 0)
+	})
+
+	t.Run("A finished match synced again after it was archived stays in the archive", func(t *testing.T) {
+		resynced := changed
+		resynced.ModifiedAt = changed.ModifiedAt.Add(time.Minute)
+		written, err := sink.UpsertMatch(t.Context(), resynced)
+		st.Expect(t, err, nil)
+		st.Expect(t, written, false)
+		exists, err := sink.Exists(t.Context(), EntityMatch, int32(row.ID))
+		st.Expect(t, err, nil)
+		st.Expect(t, exists, false)
+	})
+
+	t.Run("A reopened match returns from the archive and replaces it when archived again", func(t *testing.T) {
+		var archivedChanges int
+		err := sink.db.QueryRowContext(t.Context(),
+			"SELECT json_array_length(changes) FROM matches_archive WHERE id = ?", row.ID).Scan(&archivedChanges)
+		st.Assert(t, err, nil)
+		// PandaScore reopened the archived match, so it is diffed against the archived copy
+		reopened := changed
+		reopened.Finished = false
+		reopened.Status = MatchStatusPostponed
+		reopened.ModifiedAt = changed.ModifiedAt.Add(2 * time.Minute)
+		written, err := sink.UpsertMatch(t.Context(), reopened)
+		st.Expect(t, err, nil)
+		st.Expect(t, written, true)
+		var oldStatus string
+		err = sink.db.QueryRowContext(t.Context(),
+			"SELECT old_value FROM match_changes WHERE match_id = ? AND field = ?", row.ID, FieldStatus).Scan(&oldStatus)
+		st.Expect(t, err, nil)
+		st.Expect(t, oldStatus, MatchStatusRunning)
+		// it ends later than archived, so its played_at moves
+		again := reopened
+		again.Finished = true
+		again.Status = MatchStatusFinished
+		again.EndAt = row.EndAt.Add(time.Hour)
+		again.ModifiedAt = reopened.ModifiedAt.Add(time.Minute)
+		_, err = sink.UpsertMatch(t.Context(), again)
+		st.Assert(t, err, nil)
+
+		moved, err := sink.Expire(t.Context(), RetainMatches, time.Now(), 10)
+		st.Expect(t, err, nil)
+		st.Expect(t, moved, int64(1))
+		var copies, changes int
+		var status string
+		err = sink.db.QueryRowContext(t.Context(),
+			"SELECT COUNT(*), MAX(status), MAX(json_array_length(changes)) FROM matches_archive WHERE id = ?",
+			row.ID).Scan(&copies, &status, &changes)
+		st.Expect(t, err, nil)
+		st.Expect(t, copies, 1)
+		st.Expect(t, status, MatchStatusFinished)
+		st.Expect(t, changes > archivedChanges, true)
+	})
+
+	t.Run("URL mappings not accessed since the cutoff are deleted", func(t *testing.T) {
+		_, err := sink.db.ExecContext(t.Context(), `INSERT INTO url_mappings (hashed_key, value_list, access_count, accessed_at)
+VALUES ('old', '[]', 1, '2020-01-01 00:00:00'), ('new', '[]', 1, CURRENT_TIMESTAMP)`)
+		st.Assert(t, err, nil)
+		cutoff := time.Now().AddDate(0, 0, -90)
+
+		count, err := sink.CountExpired(t.Context(), RetainURLMappings, cutoff)
+		st.Expect(t, err, nil)
+		st.Expect(t, count, int64(1))
+		deleted, err := sink.Expire(t.Context(), RetainURLMappings, cutoff, 10)
+		st.Expect(t, err, nil)
+		st.Expect(t, deleted, int64(1))
+		count, err = sink.CountExpired(t.Context(), RetainURLMappings, cutoff)
+		st.Expect(t, err, nil)
+		st.Expect(t, count, int64(0))
+	})
+
+	t.Run("Error - invalid target", func(t *testing.T) {
+		_, err := sink.Expire(t.Context(), RetentionTarget(99), time.Now(), 10)
+		st.Reject(t, err, nil)
+	})
+}
+
+func TestSQLiteSinkViews(t *testing.T) {
+	sink := openTestSQLite(t)
+	row, deps := fixtureMatch(t)
+	for _, dep := range deps {
+		_, err := dep.WriteToDB(t.Context(), sink)
+		st.Assert(t, err, nil)
+	}
+	upcoming := row
+	upcoming.Finished = false
+	upcoming.Status = MatchStatusNotStarted
+	_, err := sink.UpsertMatch(t.Context(), upcoming)
+	st.Assert(t, err, nil)
+	_, err = sink.UpsertTeam(t.Context(), TeamRow{ID: upcoming.Team1ID, GameID: row.GameID, Name: "T1"})
+	st.Assert(t, err, nil)
+	st.Expect(t, sink.RefreshViews(t.Context()), nil)
+
+	t.Run("Upcoming matches carry the names of their teams and league", func(t *testing.T) {
+		var team1, team2 sql.NullString
+		var league string
+		err := sink.db.QueryRowContext(t.Context(),
+			"SELECT team1_name, team2_name, league_name FROM upcoming_matches_view WHERE id = ?", row.ID).
+			Scan(&team1, &team2, &league)
+		st.Expect(t, err, nil)
+		st.Expect(t, team1.String, "T1")
+		// the second team is not stored
+		st.Expect(t, team2.Valid, false)
+		st.Expect(t, league, "League")
+	})
+
+	t.Run("Leagues are ranked per game", func(t *testing.T) {
+		_, err := sink.UpsertLeague(t.Context(), LeagueRow{ID: row.LeagueID + 1, Name: "Academy", GameID: row.GameID})
+		st.Assert(t, err, nil)
+		ids, err := sink.queryIDs(t.Context(),
+			"SELECT id FROM ranked_leagues_view WHERE game_id = ? ORDER BY game_rank", row.GameID)
+		st.Expect(t, err, nil)
+		// the league without a tiered tournament ranks last
+		st.Expect(t, ids, []int32{int32(row.LeagueID), int32(row.LeagueID + 1)})
+	})
+}
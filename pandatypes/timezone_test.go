@@ -0,0 +1,189 @@
+package pandatypes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+)
+
+// tzDatabaseEnv names the connection string of the scratch database the
+// timezone, schema and search suites run against, see testDatabaseURL.
+const tzDatabaseEnv = "STALKA_TEST_DATABASE_URL"
+
+// serverZones are the session time zones the regression suite runs under.
+var serverZones = []string{
+	"UTC",
+	"Europe/London",
+	"America/New_York",
+	"Australia/Sydney",
+	"Asia/Kolkata",
+}
+
+// dstInstants are UTC instants around daylight saving transitions, the hours the
+// calendar used to get wrong.
+var dstInstants = []time.Time{
+	// Europe/London springs forward at 01:00 UTC.
+	time.Date(2024, time.March, 31, 0, 30, 0, 0, time.UTC),
+	time.Date(2024, time.March, 31, 1, 30, 0, 0, time.UTC),
+	// 01:30 happens twice in America/New_York, once in EDT and once in EST.
+	time.Date(2024, time.November, 3, 5, 30, 0, 0, time.UTC),
+	time.Date(2024, time.November, 3, 6, 30, 0, 0, time.UTC),
+	// Australia/Sydney falls back at 16:00 UTC the day before.
+	time.Date(2024, time.April, 6, 15, 30, 0, 0, time.UTC),
+	time.Date(2024, time.April, 6, 16, 30, 0, 0, time.UTC),
+}
+
+// testDatabaseURL returns the connection string of tzDatabaseEnv. Locally the
+// test is skipped when it is unset; CI provides the database, so there a missing
+// one fails the test instead of silently skipping it.
+func testDatabaseURL(t *testing.T) string {
+	t.Helper()
+	url := os.Getenv(tzDatabaseEnv)
+	if url == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatalf("%s must be set in CI", tzDatabaseEnv)
+		}
+		t.Skipf("%s is not set", tzDatabaseEnv)
+	}
+	return url
+}
+
+func TestZeroTimeIsNull(t *testing.T) {
+	params, err := MatchRow{}.ToParams()
+	st.Assert(t, err, nil)
+	st.Expect(t, params.ExpectedStartTime.Valid, false)
+	st.Expect(t, params.BeginAt.Valid, false)
+	st.Expect(t, timestampValue(params.BeginAt), "")
+}
+
+func TestTimestamptzAgainstServer(t *testing.T) {
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, testDatabaseURL(t))
+	st.Assert(t, err, nil)
+	defer conn.Close(ctx)
+
+	schema, err := os.ReadFile("../static/schema.sql")
+	st.Assert(t, err, nil)
+
+	tx, err := conn.Begin(ctx)
+	st.Assert(t, err, nil)
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx, string(schema))
+	st.Assert(t, err, nil)
+
+	t.Run("No naive timestamps remain", func(t *testing.T) {
+		var naive int
+		err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_schema = current_schema() AND data_type = 'timestamp without time zone'`).Scan(&naive)
+		st.Assert(t, err, nil)
+		st.Expect(t, naive, 0)
+	})
+
+	for _, zone := range serverZones {
+		t.Run(zone, func(t *testing.T) {
+			_, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL TIME ZONE '%s'", zone))
+			st.Assert(t, err, nil)
+			for _, instant := range dstInstants {
+				params, err := MatchRow{ExpectedStartTime: instant}.ToParams()
+				st.Assert(t, err, nil)
+				var stored pgtype.Timestamptz
+				err = tx.QueryRow(ctx, "SELECT $1::TIMESTAMPTZ", params.ExpectedStartTime).Scan(&stored)
+				st.Assert(t, err, nil)
+				st.Expect(t, stored.Time.Equal(instant), true)
+
+				var rendered string
+				err = tx.QueryRow(ctx, "SELECT to_char($1::TIMESTAMPTZ AT TIME ZONE 'UTC', 'YYYY-MM-DD\"T\"HH24:MI:SS\"Z\"')",
+					params.ExpectedStartTime).Scan(&rendered)
+				st.Assert(t, err, nil)
+				st.Expect(t, rendered, instant.Format(time.RFC3339))
+			}
+		})
+	}
+
+	// The matches are written through both upserts and read back through the
+	// batch lookup, every time column under every session zone.
+	for _, statement := range []string{
+		"INSERT INTO GAMES (id, name) VALUES (1, 'LoL') ON CONFLICT DO NOTHING",
+		"INSERT INTO LEAGUES (id, name, game_id) VALUES (1, 'LCK', 1) ON CONFLICT DO NOTHING",
+		"INSERT INTO SERIES (id, name, game_id, league_id) VALUES (1, 'Spring', 1, 1) ON CONFLICT DO NOTHING",
+		`INSERT INTO TOURNAMENTS (id, name, game_id, league_id, serie_id)
+			VALUES (1, 'Playoffs', 1, 1, 1) ON CONFLICT DO NOTHING`,
+	} {
+		_, err = tx.Exec(ctx, statement)
+		st.Assert(t, err, nil)
+	}
+	db := dbtypes.New(tx)
+	// far above the IDs of PandaScore, in case the scratch database holds some
+	nextID := 2_000_000_000
+	for _, zone := range serverZones {
+		loc, err := time.LoadLocation(zone)
+		st.Assert(t, err, nil)
+
+		t.Run(zone+" match round trip", func(t *testing.T) {
+			_, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL TIME ZONE '%s'", zone))
+			st.Assert(t, err, nil)
+			rows := make(map[int32]MatchRow, len(dstInstants))
+			var batch []dbtypes.UpsertMatchesBatchParams
+			for i, instant := range dstInstants {
+				nextID++
+				// the decoded times carry the session zone, like a client running in it
+				row := MatchRow{
+					ID: nextID, Name: "T1 vs GEN", Finished: true, AmountOfGames: 3,
+					GameID: 1, LeagueID: 1, SerieID: 1, TournamentID: 1,
+					ExpectedStartTime:   instant.In(loc),
+					OriginalScheduledAt: instant.Add(-time.Hour).In(loc),
+					BeginAt:             instant.Add(time.Minute).In(loc),
+					EndAt:               instant.Add(time.Hour).In(loc),
+					ModifiedAt:          instant.Add(2 * time.Hour).In(loc),
+				}
+				params, err := row.ToParams()
+				st.Assert(t, err, nil)
+				rows[params.ID] = row
+				if i%2 == 0 {
+					written, err := db.InsertToMatches(ctx, params)
+					st.Assert(t, err, nil)
+					st.Expect(t, written, int64(1))
+					continue
+				}
+				batch = append(batch, dbtypes.UpsertMatchesBatchParams(params))
+			}
+			db.UpsertMatchesBatch(ctx, batch).QueryRow(func(i int, id int32, err error) {
+				st.Expect(t, err, nil)
+				st.Expect(t, id, batch[i].ID)
+			})
+
+			ids := make([]int32, 0, len(rows))
+			for id := range rows {
+				ids = append(ids, id)
+			}
+			stored, err := db.GetMatchesByIDs(ctx, ids)
+			st.Assert(t, err, nil)
+			st.Assert(t, len(stored), len(rows))
+			for _, match := range stored {
+				row := rows[match.ID]
+				for _, pair := range []struct {
+					stored pgtype.Timestamptz
+					want   time.Time
+				}{
+					{match.ExpectedStartTime, row.ExpectedStartTime},
+					{match.OriginalScheduledAt, row.OriginalScheduledAt},
+					{match.BeginAt, row.BeginAt},
+					{match.EndAt, row.EndAt},
+					{match.ModifiedAt, row.ModifiedAt},
+				} {
+					st.Expect(t, pair.stored.Valid, true)
+					st.Expect(t, pair.stored.Time.Equal(pair.want), true)
+					st.Expect(t, timestampValue(pair.stored), pair.want.UTC().Format(time.RFC3339))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package pandatypes
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WithSavepoint runs fn under a savepoint of tx and rolls back to it when fn
+// fails, so that a failed statement does not abort the surrounding transaction.
+// A nil tx runs fn directly, for callers that write in autocommit mode.
+// @param tx - the transaction to create the savepoint in, may be nil.
+// @param fn - the writes to run under the savepoint.
+// @returns the error of fn, or an error if the savepoint could not be created or released.
+func WithSavepoint(ctx context.Context, tx pgx.Tx, fn func() error) error {
+	if tx == nil {
+		return fn()
+	}
+	// Begin on a pgx.Tx creates a savepoint, Commit releases it.
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err = fn(); err != nil {
+		if rollbackErr := savepoint.Rollback(ctx); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return savepoint.Commit(ctx)
+}
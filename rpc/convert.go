@@ -0,0 +1,151 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/live"
+	"github.com/feimaomiao/stalka/stalkapb"
+)
+
+// gameRow converts a stored game.
+func gameRow(row dbtypes.Game) *stalkapb.GameRow {
+	return &stalkapb.GameRow{Id: row.ID, Name: row.Name, Slug: row.Slug.String}
+}
+
+// leagueRow converts a stored league.
+func leagueRow(row dbtypes.League) *stalkapb.LeagueRow {
+	return &stalkapb.LeagueRow{
+		Id: row.ID, Name: row.Name, Slug: row.Slug.String, GameId: row.GameID,
+		ImageLink: row.ImageLink.String, ModifiedAt: timestamp(row.ModifiedAt),
+	}
+}
+
+// seriesRow converts a stored series.
+func seriesRow(row dbtypes.Series) *stalkapb.SeriesRow {
+	return &stalkapb.SeriesRow{
+		Id: row.ID, Name: row.Name, Slug: row.Slug.String, GameId: row.GameID, LeagueId: row.LeagueID,
+		ModifiedAt: timestamp(row.ModifiedAt),
+	}
+}
+
+// tournamentRow converts a stored tournament.
+func tournamentRow(row dbtypes.Tournament) *stalkapb.TournamentRow {
+	return &stalkapb.TournamentRow{
+		Id: row.ID, Name: row.Name, Slug: row.Slug.String, Tier: row.Tier.Int32, GameId: row.GameID,
+		LeagueId: row.LeagueID, SerieId: row.SerieID, ModifiedAt: timestamp(row.ModifiedAt),
+	}
+}
+
+// teamRow converts a stored team.
+func teamRow(row dbtypes.Team) *stalkapb.TeamRow {
+	return &stalkapb.TeamRow{
+		Id: row.ID, GameId: row.GameID, Name: row.Name, Acronym: row.Acronym.String, Slug: row.Slug.String,
+		ImageLink: row.ImageLink.String, ModifiedAt: timestamp(row.ModifiedAt),
+	}
+}
+
+// playerRow converts a stored player.
+func playerRow(row dbtypes.Player) *stalkapb.PlayerRow {
+	return &stalkapb.PlayerRow{
+		Id: row.ID, GameId: row.GameID, Name: row.Name, FirstName: row.FirstName.String,
+		LastName: row.LastName.String, Nationality: row.Nationality.String, Slug: row.Slug.String,
+		ImageLink: row.ImageLink.String, ModifiedAt: timestamp(row.ModifiedAt),
+	}
+}
+
+// matchRow converts a stored match without its opponents.
+func matchRow(row dbtypes.Match) *stalkapb.MatchRow {
+	return &stalkapb.MatchRow{
+		Id:                  row.ID,
+		Name:                row.Name,
+		Slug:                row.Slug.String,
+		Finished:            row.Finished,
+		ExpectedStartTime:   timestamp(row.ExpectedStartTime),
+		Team1Id:             row.Team1ID,
+		Team1Score:          row.Team1Score,
+		Team2Id:             row.Team2ID,
+		Team2Score:          row.Team2Score,
+		AmountOfGames:       row.AmountOfGames,
+		ActualGameTime:      row.ActualGameTime,
+		GameId:              row.GameID,
+		LeagueId:            row.LeagueID,
+		SerieId:             row.SeriesID,
+		TournamentId:        row.TournamentID,
+		StreamUrl:           row.StreamURL.String,
+		Status:              row.Status.String,
+		Forfeit:             row.Forfeit,
+		Draw:                row.Draw,
+		Rescheduled:         row.Rescheduled,
+		OriginalScheduledAt: timestamp(row.OriginalScheduledAt),
+		BeginAt:             timestamp(row.BeginAt),
+		EndAt:               timestamp(row.EndAt),
+		WinnerId:            row.WinnerID.Int32,
+		WinnerType:          row.WinnerType.String,
+		ModifiedAt:          timestamp(row.ModifiedAt),
+		IsLive:              row.IsLive,
+		Opponents:           nil,
+	}
+}
+
+// matchOpponentRow converts a stored opponent of a match.
+func matchOpponentRow(row dbtypes.MatchOpponent) *stalkapb.MatchOpponentRow {
+	return &stalkapb.MatchOpponentRow{
+		Type: row.OpponentType, Id: row.OpponentID, Slot: row.Slot, Score: row.Score,
+		Placement: row.Placement.Int32,
+	}
+}
+
+// matchEvent converts a live event.
+func matchEvent(event live.Event) *stalkapb.MatchEvent {
+	scores := make([]*stalkapb.Score, 0, len(event.Scores))
+	for _, score := range event.Scores {
+		scores = append(scores, &stalkapb.Score{OpponentId: score.OpponentID, Score: score.Score})
+	}
+	return &stalkapb.MatchEvent{
+		Id:           event.ID,
+		Type:         event.Type,
+		MatchId:      event.MatchID,
+		GameId:       event.GameID,
+		At:           timestamppb.New(event.At),
+		OpponentIds:  event.OpponentIDs,
+		Scores:       scores,
+		GamePosition: event.GamePosition,
+		WinnerId:     event.WinnerID,
+	}
+}
+
+// timestamp converts a nullable time column, nil when NULL.
+func timestamp(value pgtype.Timestamptz) *timestamppb.Timestamp {
+	if !value.Valid {
+		return nil
+	}
+	return timestamppb.New(value.Time)
+}
+
+// timestamptz converts an optional time filter, NULL when unset.
+func timestamptz(value *timestamppb.Timestamp) pgtype.Timestamptz {
+	if value == nil {
+		return pgtype.Timestamptz{Time: time.Time{}, InfinityModifier: pgtype.Finite, Valid: false}
+	}
+	return pgtype.Timestamptz{Time: value.AsTime(), InfinityModifier: pgtype.Finite, Valid: true}
+}
+
+// optionalInt4 converts an optional integer filter, NULL when unset.
+func optionalInt4(value *int32) pgtype.Int4 {
+	if value == nil {
+		return pgtype.Int4{Int32: 0, Valid: false}
+	}
+	return pgtype.Int4{Int32: *value, Valid: true}
+}
+
+// optionalBool converts an optional boolean filter, NULL when unset.
+func optionalBool(value *bool) pgtype.Bool {
+	if value == nil {
+		return pgtype.Bool{Bool: false, Valid: false}
+	}
+	return pgtype.Bool{Bool: *value, Valid: true}
+}
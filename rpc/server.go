@@ -0,0 +1,340 @@
+// Package rpc serves the gRPC API of stalka, defined in
+// proto/stalka/v1/stalka.proto.
+package rpc
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/feimaomiao/stalka/api"
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/live"
+	"github.com/feimaomiao/stalka/stalkapb"
+)
+
+// Server implements the Stalka service for internal consumers that want typed
+// access to the synced entities.
+type Server struct {
+	stalkapb.UnimplementedStalkaServer
+
+	// Queries reads the entities.
+	Queries dbtypes.Querier
+	// Live streams the running matches. Nil answers WatchMatches with Unavailable.
+	Live   *live.Broadcaster
+	Logger *zap.SugaredLogger
+}
+
+// Register creates a gRPC server serving the Stalka service.
+// @returns the gRPC server, ready to Serve a listener.
+func (server *Server) Register() *grpc.Server {
+	grpcServer := grpc.NewServer()
+	stalkapb.RegisterStalkaServer(grpcServer, server)
+	return grpcServer
+}
+
+// GetGame returns a game by ID.
+func (server *Server) GetGame(ctx context.Context, request *stalkapb.GetRequest) (*stalkapb.GameRow, error) {
+	return get(ctx, server, "game", request.GetId(), server.Queries.GetGamesByIDs, gameRow)
+}
+
+// GetLeague returns a league by ID.
+func (server *Server) GetLeague(ctx context.Context, request *stalkapb.GetRequest) (*stalkapb.LeagueRow, error) {
+	return get(ctx, server, "league", request.GetId(), server.Queries.GetLeaguesByIDs, leagueRow)
+}
+
+// GetSeries returns a series by ID.
+func (server *Server) GetSeries(ctx context.Context, request *stalkapb.GetRequest) (*stalkapb.SeriesRow, error) {
+	return get(ctx, server, "series", request.GetId(), server.Queries.GetSeriesByIDs, seriesRow)
+}
+
+// GetTournament returns a tournament by ID.
+func (server *Server) GetTournament(
+	ctx context.Context,
+	request *stalkapb.GetRequest,
+) (*stalkapb.TournamentRow, error) {
+	return get(ctx, server, "tournament", request.GetId(), server.Queries.GetTournamentsByIDs, tournamentRow)
+}
+
+// GetTeam returns a team by ID.
+func (server *Server) GetTeam(ctx context.Context, request *stalkapb.GetRequest) (*stalkapb.TeamRow, error) {
+	return get(ctx, server, "team", request.GetId(), server.Queries.GetTeamsByIDs, teamRow)
+}
+
+// GetPlayer returns a player by ID.
+func (server *Server) GetPlayer(ctx context.Context, request *stalkapb.GetRequest) (*stalkapb.PlayerRow, error) {
+	return get(ctx, server, "player", request.GetId(), server.Queries.GetPlayersByIDs, playerRow)
+}
+
+// GetMatch returns a match by ID with its opponents.
+func (server *Server) GetMatch(ctx context.Context, request *stalkapb.GetRequest) (*stalkapb.MatchRow, error) {
+	if request.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "id must be positive")
+	}
+	rows, err := server.Queries.GetMatchesByIDs(ctx, []int32{request.GetId()})
+	if err != nil {
+		return nil, server.internalError("match", err)
+	}
+	// GetMatchesByIDs also returns tombstoned matches.
+	if len(rows) == 0 || rows[0].DeletedAt.Valid {
+		return nil, status.Errorf(codes.NotFound, "match %d not found", request.GetId())
+	}
+	matches, err := server.matchRows(ctx, rows[:1])
+	if err != nil {
+		return nil, err
+	}
+	return matches[0], nil
+}
+
+// ListGames returns the games by ID.
+func (server *Server) ListGames(
+	ctx context.Context,
+	request *stalkapb.ListGamesRequest,
+) (*stalkapb.ListGamesResponse, error) {
+	limit, offset, err := window(request.GetPage())
+	if err != nil {
+		return nil, err
+	}
+	rows, err := server.Queries.GetAllGames(ctx)
+	if err != nil {
+		return nil, server.internalError("games", err)
+	}
+	games, hasMore := paginate(rows, limit, offset, func(row dbtypes.Game) *stalkapb.GameRow {
+		return &stalkapb.GameRow{Id: row.ID, Name: row.Name, Slug: row.Slug.String}
+	})
+	return &stalkapb.ListGamesResponse{Games: games, HasMore: hasMore}, nil
+}
+
+// ListLeagues returns the leagues of a game by name.
+func (server *Server) ListLeagues(
+	ctx context.Context,
+	request *stalkapb.ListLeaguesRequest,
+) (*stalkapb.ListLeaguesResponse, error) {
+	rows, limit, offset, err := list(ctx, server, "leagues", request.GetGameId(), request.GetPage(),
+		server.Queries.GetLeaguesByGameIDs)
+	if err != nil {
+		return nil, err
+	}
+	leagues, hasMore := paginate(rows, limit, offset, leagueRow)
+	return &stalkapb.ListLeaguesResponse{Leagues: leagues, HasMore: hasMore}, nil
+}
+
+// ListSeries returns the series of a league by name.
+func (server *Server) ListSeries(
+	ctx context.Context,
+	request *stalkapb.ListSeriesRequest,
+) (*stalkapb.ListSeriesResponse, error) {
+	rows, limit, offset, err := list(ctx, server, "series", request.GetLeagueId(), request.GetPage(),
+		server.Queries.GetSeriesByLeagueIDs)
+	if err != nil {
+		return nil, err
+	}
+	series, hasMore := paginate(rows, limit, offset, seriesRow)
+	return &stalkapb.ListSeriesResponse{Series: series, HasMore: hasMore}, nil
+}
+
+// ListTournaments returns the tournaments of a series by name.
+func (server *Server) ListTournaments(
+	ctx context.Context,
+	request *stalkapb.ListTournamentsRequest,
+) (*stalkapb.ListTournamentsResponse, error) {
+	rows, limit, offset, err := list(ctx, server, "tournaments", request.GetSerieId(), request.GetPage(),
+		server.Queries.GetTournamentsBySeriesIDs)
+	if err != nil {
+		return nil, err
+	}
+	tournaments, hasMore := paginate(rows, limit, offset, tournamentRow)
+	return &stalkapb.ListTournamentsResponse{Tournaments: tournaments, HasMore: hasMore}, nil
+}
+
+// ListMatches returns the matches selected by the filters of the request by
+// expected start time, like GET /api/v1/matches.
+func (server *Server) ListMatches(
+	ctx context.Context,
+	request *stalkapb.ListMatchesRequest,
+) (*stalkapb.ListMatchesResponse, error) {
+	limit, offset, err := window(request.GetPage())
+	if err != nil {
+		return nil, err
+	}
+	params := dbtypes.ListMatchesParams{
+		StartsAfter:  timestamptz(request.GetStartsAfter()),
+		StartsBefore: timestamptz(request.GetStartsBefore()),
+		GameID:       optionalInt4(request.GameId),
+		LeagueID:     optionalInt4(request.LeagueId),
+		TeamID:       optionalInt4(request.TeamId),
+		IsLive:       optionalBool(request.IsLive),
+		// One row more than the page tells whether another page follows.
+		MaxRows:  limit + 1,
+		SkipRows: offset,
+	}
+	rows, err := server.Queries.ListMatches(ctx, params)
+	if err != nil {
+		return nil, server.internalError("matches", err)
+	}
+	hasMore := len(rows) > int(limit)
+	matches, err := server.matchRows(ctx, rows[:min(len(rows), int(limit))])
+	if err != nil {
+		return nil, err
+	}
+	return &stalkapb.ListMatchesResponse{Matches: matches, HasMore: hasMore}, nil
+}
+
+// WatchMatches streams the live events selected by the request until the
+// client cancels, after replaying the kept events following last_event_id.
+func (server *Server) WatchMatches(
+	request *stalkapb.WatchMatchesRequest,
+	stream grpc.ServerStreamingServer[stalkapb.MatchEvent],
+) error {
+	if server.Live == nil {
+		return status.Error(codes.Unavailable, "live events are not served by this process")
+	}
+	filter := live.Filter{GameIDs: request.GetGameIds(), TeamIDs: request.GetTeamIds()}
+	replay, events, unsubscribe := server.Live.Subscribe(filter, request.GetLastEventId())
+	defer unsubscribe()
+	for _, event := range replay {
+		if err := stream.Send(matchEvent(event)); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "stream fell behind, resume with the last event ID")
+			}
+			if err := stream.Send(matchEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// matchRows converts matches and attaches their opponents, read with one query.
+// @param ctx - the context of the call.
+// @param rows - the matches.
+// @returns the converted matches and a status error if the query fails.
+func (server *Server) matchRows(ctx context.Context, rows []dbtypes.Match) ([]*stalkapb.MatchRow, error) {
+	matches := make([]*stalkapb.MatchRow, 0, len(rows))
+	if len(rows) == 0 {
+		return matches, nil
+	}
+	ids := make([]int32, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	opponents, err := server.Queries.GetMatchOpponentsByMatchIDs(ctx, ids)
+	if err != nil {
+		return nil, server.internalError("match opponents", err)
+	}
+	byMatch := make(map[int32][]*stalkapb.MatchOpponentRow, len(rows))
+	for _, opponent := range opponents {
+		byMatch[opponent.MatchID] = append(byMatch[opponent.MatchID], matchOpponentRow(opponent))
+	}
+	for _, row := range rows {
+		match := matchRow(row)
+		match.Opponents = byMatch[row.ID]
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// internalError logs a failed query and returns Internal without its details.
+func (server *Server) internalError(resource string, err error) error {
+	server.Logger.Errorf("Error serving %s over gRPC: %v", resource, err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// get looks up one entity with a batch query.
+// @param ctx - the context of the call.
+// @param server - the server, to log failed queries.
+// @param resource - the name of the entity in errors.
+// @param id - the ID of the entity.
+// @param fetch - the batch query of the entity.
+// @param convert - converts a row.
+// @returns the converted row, or InvalidArgument, NotFound or Internal.
+func get[R any, V any](
+	ctx context.Context,
+	server *Server,
+	resource string,
+	id int32,
+	fetch func(context.Context, []int32) ([]R, error),
+	convert func(R) V,
+) (V, error) {
+	var zero V
+	if id <= 0 {
+		return zero, status.Error(codes.InvalidArgument, "id must be positive")
+	}
+	rows, err := fetch(ctx, []int32{id})
+	if err != nil {
+		return zero, server.internalError(resource, err)
+	}
+	if len(rows) == 0 {
+		return zero, status.Errorf(codes.NotFound, "%s %d not found", resource, id)
+	}
+	return convert(rows[0]), nil
+}
+
+// list reads the children of a parent entity for a list RPC.
+// @param ctx - the context of the call.
+// @param server - the server, to log failed queries.
+// @param resource - the name of the children in errors.
+// @param parentID - the ID of the parent.
+// @param page - the window of the request.
+// @param fetch - the batch query of the children.
+// @returns every child, the window and InvalidArgument or Internal.
+func list[R any](
+	ctx context.Context,
+	server *Server,
+	resource string,
+	parentID int32,
+	page *stalkapb.Page,
+	fetch func(context.Context, []int32) ([]R, error),
+) ([]R, int32, int32, error) {
+	if parentID <= 0 {
+		return nil, 0, 0, status.Error(codes.InvalidArgument, "the parent id must be positive")
+	}
+	limit, offset, err := window(page)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	rows, err := fetch(ctx, []int32{parentID})
+	if err != nil {
+		return nil, 0, 0, server.internalError(resource, err)
+	}
+	return rows, limit, offset, nil
+}
+
+// window reads the window of a list RPC.
+// @param page - the window of the request, nil for the first page.
+// @returns the limit, the offset and InvalidArgument if either is out of range.
+func window(page *stalkapb.Page) (int32, int32, error) {
+	limit := page.GetLimit()
+	if limit == 0 {
+		limit = api.DefaultPageSize
+	}
+	if limit < 0 || limit > api.MaxPageSize {
+		return 0, 0, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", api.MaxPageSize)
+	}
+	if page.GetOffset() < 0 {
+		return 0, 0, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+	return limit, page.GetOffset(), nil
+}
+
+// paginate cuts the window out of every row and converts it.
+// @returns the converted rows of the window and whether more rows follow.
+func paginate[R any, V any](rows []R, limit int32, offset int32, convert func(R) V) ([]V, bool) {
+	start := min(int(offset), len(rows))
+	end := min(start+int(limit), len(rows))
+	values := make([]V, 0, end-start)
+	for _, row := range rows[start:end] {
+		values = append(values, convert(row))
+	}
+	return values, end < len(rows)
+}
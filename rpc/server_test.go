@@ -0,0 +1,302 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/feimaomiao/stalka/live"
+	"github.com/feimaomiao/stalka/pandatypes"
+	"github.com/feimaomiao/stalka/stalkapb"
+)
+
+var (
+	matchColumns = []string{
+		"id", "name", "slug", "finished", "expected_start_time", "actual_game_time",
+		"team1_id", "team1_score", "team2_id", "team2_score", "amount_of_games", "is_live",
+		"stream_url", "status", "forfeit", "draw", "rescheduled", "original_scheduled_at",
+		"begin_at", "end_at", "winner_id", "winner_type", "game_id", "league_id", "series_id", "tournament_id",
+		"last_seen_at", "deleted_at", "modified_at",
+	}
+	opponentColumns = []string{"match_id", "slot", "opponent_type", "opponent_id", "score", "placement"}
+	gameColumns     = []string{"id", "name", "slug", "last_seen_at", "deleted_at"}
+	leagueColumns   = []string{
+		"id", "name", "slug", "game_id", "image_link", "last_seen_at", "deleted_at", "modified_at", "search_vector",
+	}
+	teamColumns = []string{
+		"id", "name", "slug", "acronym", "image_link", "game_id",
+		"last_seen_at", "deleted_at", "modified_at", "search_vector",
+	}
+)
+
+var start = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestClient serves a Server over an in-memory connection.
+func newTestClient(t *testing.T, broadcaster *live.Broadcaster) (stalkapb.StalkaClient, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	t.Cleanup(mockDB.Close)
+	server := &Server{
+		UnimplementedStalkaServer: stalkapb.UnimplementedStalkaServer{},
+		Queries:                   dbtypes.New(mockDB),
+		Live:                      broadcaster,
+		Logger:                    zaptest.NewLogger(t).Sugar(),
+	}
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := server.Register()
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	st.Assert(t, err, nil)
+	t.Cleanup(func() { _ = conn.Close() })
+	return stalkapb.NewStalkaClient(conn), mockDB
+}
+
+func matchRows(matches ...dbtypes.Match) *pgxmock.Rows {
+	rows := pgxmock.NewRows(matchColumns)
+	for _, m := range matches {
+		rows.AddRow(
+			m.ID, m.Name, m.Slug, m.Finished, m.ExpectedStartTime, m.ActualGameTime,
+			m.Team1ID, m.Team1Score, m.Team2ID, m.Team2Score, m.AmountOfGames, m.IsLive,
+			m.StreamURL, m.Status, m.Forfeit, m.Draw, m.Rescheduled, m.OriginalScheduledAt,
+			m.BeginAt, m.EndAt, m.WinnerID, m.WinnerType, m.GameID, m.LeagueID, m.SeriesID, m.TournamentID,
+			m.LastSeenAt, m.DeletedAt, m.ModifiedAt,
+		)
+	}
+	return rows
+}
+
+// fixtureMatch is a scheduled two-team match.
+func fixtureMatch(id int32) dbtypes.Match {
+	return dbtypes.Match{
+		ID:                id,
+		Name:              "T1 vs GEN",
+		Slug:              pgtype.Text{String: "t1-vs-gen", Valid: true},
+		ExpectedStartTime: pgtype.Timestamptz{Time: start, Valid: true, InfinityModifier: pgtype.Finite},
+		Team1ID:           1,
+		Team2ID:           2,
+		AmountOfGames:     5,
+		Status:            pgtype.Text{String: "not_started", Valid: true},
+		GameID:            1,
+		LeagueID:          3,
+		SeriesID:          4,
+		TournamentID:      5,
+	}
+}
+
+func code(err error) codes.Code {
+	return status.Code(err)
+}
+
+func TestGetMatch(t *testing.T) {
+	client, mockDB := newTestClient(t, nil)
+	mockDB.ExpectQuery("FROM matches WHERE id = ANY").
+		WithArgs([]int32{7}).
+		WillReturnRows(matchRows(fixtureMatch(7)))
+	mockDB.ExpectQuery("FROM match_opponents WHERE match_id = ANY").
+		WithArgs([]int32{7}).
+		WillReturnRows(pgxmock.NewRows(opponentColumns).
+			AddRow(int32(7), int32(0), "Team", int32(1), int32(2), pgtype.Int4{}).
+			AddRow(int32(7), int32(1), "Team", int32(2), int32(1), pgtype.Int4{}))
+
+	match, err := client.GetMatch(t.Context(), &stalkapb.GetRequest{Id: 7})
+	st.Assert(t, err, nil)
+	st.Expect(t, proto.Equal(match, &stalkapb.MatchRow{
+		Id: 7, Name: "T1 vs GEN", Slug: "t1-vs-gen", ExpectedStartTime: timestamppb.New(start),
+		Team1Id: 1, Team2Id: 2, AmountOfGames: 5, Status: "not_started",
+		GameId: 1, LeagueId: 3, SerieId: 4, TournamentId: 5,
+		Opponents: []*stalkapb.MatchOpponentRow{
+			{Type: "Team", Id: 1, Slot: 0, Score: 2},
+			{Type: "Team", Id: 2, Slot: 1, Score: 1},
+		},
+	}), true)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestGetMatchNotFound(t *testing.T) {
+	client, mockDB := newTestClient(t, nil)
+	deleted := fixtureMatch(7)
+	deleted.DeletedAt = pgtype.Timestamptz{Time: start, Valid: true, InfinityModifier: pgtype.Finite}
+	mockDB.ExpectQuery("FROM matches WHERE id = ANY").WithArgs([]int32{7}).WillReturnRows(matchRows(deleted))
+	mockDB.ExpectQuery("FROM matches WHERE id = ANY").WithArgs([]int32{7}).WillReturnRows(matchRows())
+
+	for range 2 {
+		_, err := client.GetMatch(t.Context(), &stalkapb.GetRequest{Id: 7})
+		st.Expect(t, code(err), codes.NotFound)
+	}
+	_, err := client.GetMatch(t.Context(), &stalkapb.GetRequest{Id: 0})
+	st.Expect(t, code(err), codes.InvalidArgument)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestGetTeam(t *testing.T) {
+	client, mockDB := newTestClient(t, nil)
+	mockDB.ExpectQuery("FROM teams WHERE id = ANY").
+		WithArgs([]int32{1}).
+		WillReturnRows(pgxmock.NewRows(teamColumns).AddRow(
+			int32(1), "T1", pgtype.Text{String: "t1", Valid: true}, pgtype.Text{String: "T1", Valid: true},
+			pgtype.Text{}, int32(1), pgtype.Timestamptz{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}, nil,
+		))
+	mockDB.ExpectQuery("FROM teams WHERE id = ANY").WithArgs([]int32{2}).WillReturnRows(pgxmock.NewRows(teamColumns))
+	mockDB.ExpectQuery("FROM teams WHERE id = ANY").WithArgs([]int32{3}).WillReturnError(errors.New("connection reset"))
+
+	team, err := client.GetTeam(t.Context(), &stalkapb.GetRequest{Id: 1})
+	st.Assert(t, err, nil)
+	st.Expect(t, proto.Equal(team, &stalkapb.TeamRow{Id: 1, GameId: 1, Name: "T1", Acronym: "T1", Slug: "t1"}), true)
+
+	_, err = client.GetTeam(t.Context(), &stalkapb.GetRequest{Id: 2})
+	st.Expect(t, code(err), codes.NotFound)
+
+	// Query errors are logged, not returned.
+	_, err = client.GetTeam(t.Context(), &stalkapb.GetRequest{Id: 3})
+	st.Expect(t, code(err), codes.Internal)
+	st.Expect(t, status.Convert(err).Message(), "internal error")
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestListLeagues(t *testing.T) {
+	client, mockDB := newTestClient(t, nil)
+	rows := pgxmock.NewRows(leagueColumns)
+	for i, name := range []string{"LCK", "LEC", "LPL"} {
+		rows.AddRow(int32(i+1), name, pgtype.Text{}, int32(1), pgtype.Text{},
+			pgtype.Timestamptz{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}, nil)
+	}
+	mockDB.ExpectQuery("FROM leagues\\s+WHERE game_id = ANY").WithArgs([]int32{1}).WillReturnRows(rows)
+
+	response, err := client.ListLeagues(t.Context(), &stalkapb.ListLeaguesRequest{
+		GameId: 1, Page: &stalkapb.Page{Limit: 1, Offset: 1},
+	})
+	st.Assert(t, err, nil)
+	st.Expect(t, len(response.GetLeagues()), 1)
+	st.Expect(t, response.GetLeagues()[0].GetName(), "LEC")
+	st.Expect(t, response.GetHasMore(), true)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestListInvalidArguments(t *testing.T) {
+	client, mockDB := newTestClient(t, nil)
+	for name, request := range map[string]*stalkapb.ListLeaguesRequest{
+		"missing game":    {GameId: 0, Page: nil},
+		"limit too large": {GameId: 1, Page: &stalkapb.Page{Limit: 201, Offset: 0}},
+		"negative limit":  {GameId: 1, Page: &stalkapb.Page{Limit: -1, Offset: 0}},
+		"negative offset": {GameId: 1, Page: &stalkapb.Page{Limit: 10, Offset: -1}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := client.ListLeagues(t.Context(), request)
+			st.Expect(t, code(err), codes.InvalidArgument)
+		})
+	}
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestListMatches(t *testing.T) {
+	client, mockDB := newTestClient(t, nil)
+	gameID := int32(1)
+	isLive := true
+	mockDB.ExpectQuery("FROM matches m").
+		WithArgs(
+			pgtype.Timestamptz{Time: start, InfinityModifier: pgtype.Finite, Valid: true},
+			pgtype.Timestamptz{Time: time.Time{}, InfinityModifier: pgtype.Finite, Valid: false},
+			pgtype.Int4{Int32: 1, Valid: true}, pgtype.Int4{}, pgtype.Int4{}, pgtype.Bool{Bool: true, Valid: true},
+			int32(2), int32(0),
+		).
+		WillReturnRows(matchRows(fixtureMatch(7), fixtureMatch(8)))
+	mockDB.ExpectQuery("FROM match_opponents WHERE match_id = ANY").
+		WithArgs([]int32{7}).
+		WillReturnRows(pgxmock.NewRows(opponentColumns))
+
+	response, err := client.ListMatches(t.Context(), &stalkapb.ListMatchesRequest{
+		Page:        &stalkapb.Page{Limit: 1, Offset: 0},
+		StartsAfter: timestamppb.New(start),
+		GameId:      &gameID,
+		IsLive:      &isLive,
+	})
+	st.Assert(t, err, nil)
+	st.Expect(t, len(response.GetMatches()), 1)
+	st.Expect(t, response.GetMatches()[0].GetId(), int32(7))
+	st.Expect(t, response.GetHasMore(), true)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestListGames(t *testing.T) {
+	client, mockDB := newTestClient(t, nil)
+	mockDB.ExpectQuery("SELECT id, name, slug, last_seen_at, deleted_at FROM games").
+		WillReturnRows(pgxmock.NewRows(gameColumns).
+			AddRow(int32(1), "LoL", pgtype.Text{String: "league-of-legends", Valid: true},
+				pgtype.Timestamptz{}, pgtype.Timestamptz{}).
+			AddRow(int32(3), "CS2", pgtype.Text{}, pgtype.Timestamptz{}, pgtype.Timestamptz{}))
+
+	response, err := client.ListGames(t.Context(), &stalkapb.ListGamesRequest{Page: nil})
+	st.Assert(t, err, nil)
+	st.Expect(t, len(response.GetGames()), 2)
+	st.Expect(t, proto.Equal(response.GetGames()[1], &stalkapb.GameRow{Id: 3, Name: "CS2", Slug: ""}), true)
+	st.Expect(t, response.GetHasMore(), false)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestWatchMatches(t *testing.T) {
+	broadcaster := live.NewBroadcaster(start)
+	client, _ := newTestClient(t, broadcaster)
+	snapshot := live.Snapshot{
+		MatchID: 7, GameID: 1, OpponentType: pandatypes.OpponentTypeTeam, OpponentIDs: []int32{1, 2},
+		Scores: []live.Score{{OpponentID: 1, Score: 0}, {OpponentID: 2, Score: 0}},
+		Games:  nil,
+	}
+	broadcaster.Observe(nil, nil, start)
+	first := broadcaster.Observe([]live.Snapshot{snapshot}, nil, start)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	// The replayed event comes first, the events of later polls follow.
+	stream, err := client.WatchMatches(ctx, &stalkapb.WatchMatchesRequest{
+		GameIds: []int32{1}, TeamIds: nil, LastEventId: first[0].ID - 1,
+	})
+	st.Assert(t, err, nil)
+	event, err := stream.Recv()
+	st.Assert(t, err, nil)
+	st.Expect(t, event.GetType(), live.MatchStarted)
+	st.Expect(t, event.GetId(), first[0].ID)
+
+	// Wait until the stream subscribed before the next poll.
+	for broadcaster.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	snapshot.Scores = []live.Score{{OpponentID: 1, Score: 1}, {OpponentID: 2, Score: 0}}
+	broadcaster.Observe([]live.Snapshot{snapshot}, nil, start.Add(time.Minute))
+	event, err = stream.Recv()
+	st.Assert(t, err, nil)
+	st.Expect(t, proto.Equal(event, &stalkapb.MatchEvent{
+		Id: first[0].ID + 1, Type: live.ScoreChanged, MatchId: 7, GameId: 1,
+		At: timestamppb.New(start.Add(time.Minute)), OpponentIds: []int32{1, 2},
+		Scores: []*stalkapb.Score{{OpponentId: 1, Score: 1}, {OpponentId: 2, Score: 0}},
+	}), true)
+}
+
+func TestWatchMatchesWithoutLive(t *testing.T) {
+	client, _ := newTestClient(t, nil)
+	stream, err := client.WatchMatches(t.Context(), &stalkapb.WatchMatchesRequest{})
+	st.Assert(t, err, nil)
+	_, err = stream.Recv()
+	st.Expect(t, code(err), codes.Unavailable)
+}
@@ -0,0 +1,225 @@
+// Package shortlink saves selections of games, leagues and teams in
+// URL_MAPPINGS under short hashed keys, so a calendar selection made in one
+// service can be shared and subscribed to through another.
+package shortlink
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+)
+
+// Selection kinds of a saved URL mapping.
+const (
+	TypeGame   = "game"
+	TypeLeague = "league"
+	TypeTeam   = "team"
+)
+
+const (
+	// KeyLength is the length of a hashed key, the width of URL_MAPPINGS.hashed_key.
+	KeyLength = 16
+	// MaxItems caps the entries of a selection.
+	MaxItems = 100
+)
+
+var (
+	// ErrNotFound is returned for a key without a URL mapping.
+	ErrNotFound = errors.New("unknown selection")
+	// ErrCollision is returned when the key of a selection is taken by another
+	// selection. With 64 bits of SHA-256 it is not expected to happen.
+	ErrCollision = errors.New("the key of the selection is taken by another selection")
+)
+
+// Item is one entry of the value_list of a URL mapping, e.g.
+// {"type": "team", "id": 126061}. A saved selection is a JSON array of them.
+type Item struct {
+	Type string `json:"type"`
+	ID   int32  `json:"id"`
+}
+
+// String formats an item as "team 126061".
+// @returns the type and the ID.
+func (item Item) String() string {
+	return fmt.Sprintf("%s %d", item.Type, item.ID)
+}
+
+// MissingError lists the entries of a selection whose game, league or team
+// does not exist or was tombstoned.
+type MissingError struct {
+	Missing []Item
+}
+
+func (err *MissingError) Error() string {
+	names := make([]string, 0, len(err.Missing))
+	for _, item := range err.Missing {
+		names = append(names, item.String())
+	}
+	return "unknown " + strings.Join(names, ", ")
+}
+
+// Normalize checks a selection and puts it into its canonical form: sorted by
+// type and ID, without duplicates. Equal selections normalize to the same
+// items however they were listed, so they share a key.
+// @param items - the selection.
+// @returns the canonical selection and an error naming the first invalid entry.
+func Normalize(items []Item) ([]Item, error) {
+	if len(items) == 0 {
+		return nil, errors.New("a selection needs at least one entry")
+	}
+	if len(items) > MaxItems {
+		return nil, fmt.Errorf("a selection has at most %d entries", MaxItems)
+	}
+	for _, item := range items {
+		if item.Type != TypeGame && item.Type != TypeLeague && item.Type != TypeTeam {
+			return nil, fmt.Errorf("type must be %s, %s or %s, not %q", TypeGame, TypeLeague, TypeTeam, item.Type)
+		}
+		if item.ID <= 0 {
+			return nil, fmt.Errorf("the ID of %s must be positive", item)
+		}
+	}
+	normalized := slices.Clone(items)
+	slices.SortFunc(normalized, func(a, b Item) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(a.ID, b.ID))
+	})
+	return slices.Compact(normalized), nil
+}
+
+// Key derives the hashed key of a selection: the first KeyLength hex digits of
+// the SHA-256 of the JSON of its canonical form, e.g. of
+// [{"type":"game","id":1},{"type":"team","id":126061}]. Other services
+// computing the key the same way find the selections saved by stalka.
+// @param items - the selection.
+// @returns the key and an error if the selection is invalid.
+func Key(items []Item) (string, error) {
+	normalized, err := Normalize(items)
+	if err != nil {
+		return "", err
+	}
+	key, _ := encode(normalized)
+	return key, nil
+}
+
+// encode returns the key and the value_list of a normalized selection.
+func encode(normalized []Item) (string, []byte) {
+	// Items always marshal.
+	valueList, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(valueList)
+	return hex.EncodeToString(sum[:])[:KeyLength], valueList
+}
+
+// Store saves and resolves selections in URL_MAPPINGS.
+type Store struct {
+	Queries dbtypes.Querier
+}
+
+// Save stores a selection under its key. Saving a stored selection again
+// only refreshes its accessed_at.
+// @param ctx - the context of the queries.
+// @param items - the selection.
+// @returns the key, the canonical selection and an error: invalid selections
+// are refused with the error of Normalize and selections naming unknown IDs
+// with a *MissingError.
+func (store Store) Save(ctx context.Context, items []Item) (string, []Item, error) {
+	normalized, err := Normalize(items)
+	if err != nil {
+		return "", nil, err
+	}
+	missing, err := store.Missing(ctx, normalized)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(missing) > 0 {
+		return "", nil, &MissingError{Missing: missing}
+	}
+	key, valueList := encode(normalized)
+	stored, err := store.Queries.SaveURLMapping(ctx, dbtypes.SaveURLMappingParams{
+		HashedKey: key,
+		ValueList: valueList,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	// The row was saved before, possibly by another service: it must hold
+	// the same selection, in whatever layout.
+	var storedItems []Item
+	if err = json.Unmarshal(stored, &storedItems); err != nil {
+		return "", nil, fmt.Errorf("invalid value_list of %s: %w", key, err)
+	}
+	if storedNormalized, normErr := Normalize(storedItems); normErr != nil ||
+		!slices.Equal(storedNormalized, normalized) {
+		return "", nil, ErrCollision
+	}
+	return key, normalized, nil
+}
+
+// Resolve reads the selection saved under a key and counts the access, which
+// keeps the mapping from being expired by retention.
+// @param ctx - the context of the query.
+// @param key - the hashed key.
+// @returns the selection as saved, ErrNotFound for an unknown key, or the error
+// of the query.
+func (store Store) Resolve(ctx context.Context, key string) ([]Item, error) {
+	valueList, err := store.Queries.TouchURLMapping(ctx, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []Item
+	if err = json.Unmarshal(valueList, &items); err != nil {
+		return nil, fmt.Errorf("invalid value_list of %s: %w", key, err)
+	}
+	return items, nil
+}
+
+// Missing checks that the games, leagues and teams of a selection still exist.
+// Entries of other types are not checked.
+// @param ctx - the context of the queries.
+// @param items - the selection.
+// @returns the entries that do not exist or were tombstoned, in the order of
+// items, and the error of a query.
+func (store Store) Missing(ctx context.Context, items []Item) ([]Item, error) {
+	lookups := map[string]func(context.Context, []int32) ([]int32, error){
+		TypeGame:   store.Queries.GetExistingGameIDs,
+		TypeLeague: store.Queries.GetExistingLeagueIDs,
+		TypeTeam:   store.Queries.GetExistingTeamIDs,
+	}
+	existing := make(map[Item]bool, len(items))
+	for _, itemType := range []string{TypeGame, TypeLeague, TypeTeam} {
+		var ids []int32
+		for _, item := range items {
+			if item.Type == itemType {
+				ids = append(ids, item.ID)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		found, err := lookups[itemType](ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range found {
+			existing[Item{Type: itemType, ID: id}] = true
+		}
+	}
+	var missing []Item
+	for _, item := range items {
+		if _, checked := lookups[item.Type]; checked && !existing[item] {
+			missing = append(missing, item)
+		}
+	}
+	return missing, nil
+}
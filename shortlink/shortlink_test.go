@@ -0,0 +1,180 @@
+package shortlink
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+)
+
+func newTestStore(t *testing.T) (Store, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	t.Cleanup(mockDB.Close)
+	return Store{Queries: dbtypes.New(mockDB)}, mockDB
+}
+
+func idRows(ids ...int32) *pgxmock.Rows {
+	rows := pgxmock.NewRows([]string{"id"})
+	for _, id := range ids {
+		rows.AddRow(id)
+	}
+	return rows
+}
+
+func TestNormalize(t *testing.T) {
+	t.Run("Sorts by type and ID and drops duplicates", func(t *testing.T) {
+		items := []Item{{Type: TypeTeam, ID: 2}, {Type: TypeGame, ID: 1}, {Type: TypeTeam, ID: 1}, {Type: TypeGame, ID: 1}}
+		normalized, err := Normalize(items)
+		st.Assert(t, err, nil)
+		st.Expect(t, normalized, []Item{{Type: TypeGame, ID: 1}, {Type: TypeTeam, ID: 1}, {Type: TypeTeam, ID: 2}})
+		// The input is left alone.
+		st.Expect(t, items[0], Item{Type: TypeTeam, ID: 2})
+	})
+
+	for name, items := range map[string][]Item{
+		"empty":        {},
+		"unknown type": {{Type: "player", ID: 1}},
+		"zero ID":      {{Type: TypeGame, ID: 0}},
+		"negative ID":  {{Type: TypeTeam, ID: -4}},
+		"too many":     make([]Item, MaxItems+1),
+	} {
+		t.Run("Error - "+name, func(t *testing.T) {
+			_, err := Normalize(items)
+			st.Reject(t, err, nil)
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	// The key is the SHA-256 of [{"type":"game","id":1},{"type":"team","id":126061}].
+	key, err := Key([]Item{{Type: TypeTeam, ID: 126061}, {Type: TypeGame, ID: 1}})
+	st.Assert(t, err, nil)
+	st.Expect(t, key, "21380158dc3c4474")
+	st.Expect(t, len(key), KeyLength)
+
+	again, err := Key([]Item{{Type: TypeGame, ID: 1}, {Type: TypeTeam, ID: 126061}, {Type: TypeGame, ID: 1}})
+	st.Assert(t, err, nil)
+	st.Expect(t, again, key)
+
+	other, err := Key([]Item{{Type: TypeGame, ID: 1}})
+	st.Assert(t, err, nil)
+	st.Reject(t, other, key)
+
+	_, err = Key(nil)
+	st.Reject(t, err, nil)
+}
+
+func TestSave(t *testing.T) {
+	items := []Item{{Type: TypeTeam, ID: 2}, {Type: TypeGame, ID: 1}, {Type: TypeLeague, ID: 293}}
+	valueList := `[{"type":"game","id":1},{"type":"league","id":293},{"type":"team","id":2}]`
+
+	t.Run("Saves the canonical selection", func(t *testing.T) {
+		store, mockDB := newTestStore(t)
+		mockDB.ExpectQuery("SELECT id FROM games").WithArgs([]int32{1}).WillReturnRows(idRows(1))
+		mockDB.ExpectQuery("SELECT id FROM leagues").WithArgs([]int32{293}).WillReturnRows(idRows(293))
+		mockDB.ExpectQuery("SELECT id FROM teams").WithArgs([]int32{2}).WillReturnRows(idRows(2))
+		mockDB.ExpectQuery("INSERT INTO url_mappings").
+			WithArgs("6c124f949cfa4ce8", []byte(valueList)).
+			WillReturnRows(pgxmock.NewRows([]string{"value_list"}).AddRow([]byte(valueList)))
+
+		key, saved, err := store.Save(t.Context(), items)
+		st.Assert(t, err, nil)
+		st.Expect(t, key, "6c124f949cfa4ce8")
+		st.Expect(t, saved, []Item{{Type: TypeGame, ID: 1}, {Type: TypeLeague, ID: 293}, {Type: TypeTeam, ID: 2}})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Accepts the same selection saved in another layout", func(t *testing.T) {
+		store, mockDB := newTestStore(t)
+		mockDB.ExpectQuery("SELECT id FROM games").WithArgs([]int32{1}).WillReturnRows(idRows(1))
+		mockDB.ExpectQuery("INSERT INTO url_mappings").WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"value_list"}).AddRow([]byte(`[ {"id": 1, "type": "game"} ]`)))
+
+		_, _, err := store.Save(t.Context(), []Item{{Type: TypeGame, ID: 1}})
+		st.Expect(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - unknown IDs", func(t *testing.T) {
+		store, mockDB := newTestStore(t)
+		mockDB.ExpectQuery("SELECT id FROM games").WithArgs([]int32{1}).WillReturnRows(idRows(1))
+		mockDB.ExpectQuery("SELECT id FROM leagues").WithArgs([]int32{293}).WillReturnRows(idRows())
+		mockDB.ExpectQuery("SELECT id FROM teams").WithArgs([]int32{2}).WillReturnRows(idRows())
+
+		_, _, err := store.Save(t.Context(), items)
+		var missing *MissingError
+		st.Assert(t, errors.As(err, &missing), true)
+		st.Expect(t, missing.Missing, []Item{{Type: TypeLeague, ID: 293}, {Type: TypeTeam, ID: 2}})
+		st.Expect(t, err.Error(), "unknown league 293, team 2")
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - key taken by another selection", func(t *testing.T) {
+		store, mockDB := newTestStore(t)
+		mockDB.ExpectQuery("SELECT id FROM games").WithArgs([]int32{1}).WillReturnRows(idRows(1))
+		mockDB.ExpectQuery("INSERT INTO url_mappings").WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"value_list"}).AddRow([]byte(`[{"type":"game","id":3}]`)))
+
+		_, _, err := store.Save(t.Context(), []Item{{Type: TypeGame, ID: 1}})
+		st.Expect(t, err, ErrCollision)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - invalid selection", func(t *testing.T) {
+		store, mockDB := newTestStore(t)
+		_, _, err := store.Save(t.Context(), []Item{{Type: "player", ID: 1}})
+		st.Reject(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestResolve(t *testing.T) {
+	t.Run("Counts the access and returns the selection", func(t *testing.T) {
+		store, mockDB := newTestStore(t)
+		mockDB.ExpectQuery("UPDATE url_mappings SET access_count = access_count \\+ 1").
+			WithArgs("21380158dc3c4474").
+			WillReturnRows(pgxmock.NewRows([]string{"value_list"}).
+				AddRow([]byte(`[{"type":"game","id":1},{"type":"team","id":126061}]`)))
+
+		items, err := store.Resolve(t.Context(), "21380158dc3c4474")
+		st.Assert(t, err, nil)
+		st.Expect(t, items, []Item{{Type: TypeGame, ID: 1}, {Type: TypeTeam, ID: 126061}})
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - unknown key", func(t *testing.T) {
+		store, mockDB := newTestStore(t)
+		mockDB.ExpectQuery("UPDATE url_mappings").WithArgs("missing").WillReturnError(pgx.ErrNoRows)
+
+		_, err := store.Resolve(t.Context(), "missing")
+		st.Expect(t, err, ErrNotFound)
+	})
+
+	t.Run("Error - invalid value_list", func(t *testing.T) {
+		store, mockDB := newTestStore(t)
+		mockDB.ExpectQuery("UPDATE url_mappings").WithArgs("broken").
+			WillReturnRows(pgxmock.NewRows([]string{"value_list"}).AddRow([]byte(`{"type":"game"}`)))
+
+		_, err := store.Resolve(t.Context(), "broken")
+		st.Reject(t, err, nil)
+	})
+}
+
+func TestMissing(t *testing.T) {
+	store, mockDB := newTestStore(t)
+	mockDB.ExpectQuery("SELECT id FROM teams").WithArgs([]int32{2, 3}).WillReturnRows(idRows(3))
+
+	// Entries of unknown types are not checked.
+	missing, err := store.Missing(t.Context(), []Item{
+		{Type: TypeTeam, ID: 2}, {Type: "player", ID: 9}, {Type: TypeTeam, ID: 3},
+	})
+	st.Assert(t, err, nil)
+	st.Expect(t, missing, []Item{{Type: TypeTeam, ID: 2}})
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
@@ -1,33 +1,53 @@
 -- name: InsertToGames :exec
 INSERT INTO games (id, name, slug) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET
     name = EXCLUDED.name,
-    slug = EXCLUDED.slug;
+    slug = EXCLUDED.slug,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL;
 
--- name: InsertToLeagues :exec
-INSERT INTO leagues (id, name, slug, image_link, game_id) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (id) DO UPDATE SET
+-- name: InsertToLeagues :execrows
+INSERT INTO leagues (id, name, slug, image_link, game_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (id) DO UPDATE SET
     name = EXCLUDED.name,
     slug = EXCLUDED.slug,
     image_link = EXCLUDED.image_link,
-    game_id = EXCLUDED.game_id;
+    game_id = EXCLUDED.game_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE leagues.deleted_at IS NOT NULL
+    OR leagues.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > leagues.modified_at, true);
 
--- name: InsertToSeries :exec
-INSERT INTO series (id, name, slug, game_id, league_id) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (id) DO UPDATE SET
+-- name: InsertToSeries :execrows
+INSERT INTO series (id, name, slug, game_id, league_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (id) DO UPDATE SET
     name = EXCLUDED.name,
     slug = EXCLUDED.slug,
     game_id = EXCLUDED.game_id,
-    league_id = EXCLUDED.league_id;
+    league_id = EXCLUDED.league_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE series.deleted_at IS NOT NULL
+    OR series.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > series.modified_at, true);
 
--- name: InsertToTournaments :exec
-INSERT INTO tournaments (id,name, slug,tier, game_id, league_id, serie_id) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO UPDATE SET
+-- name: InsertToTournaments :execrows
+INSERT INTO tournaments (id,name, slug,tier, game_id, league_id, serie_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (id) DO UPDATE SET
     name = EXCLUDED.name,
     slug = EXCLUDED.slug,
     tier = EXCLUDED.tier,
     game_id = EXCLUDED.game_id,
     league_id = EXCLUDED.league_id,
-    serie_id = EXCLUDED.serie_id;
+    serie_id = EXCLUDED.serie_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE tournaments.deleted_at IS NOT NULL
+    OR tournaments.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > tournaments.modified_at, true);
 
--- name: InsertToMatches :exec
-INSERT INTO matches (id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score, team2_id, team2_score, amount_of_games, game_id, league_id, series_id, tournament_id, stream_url) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) ON CONFLICT (id) DO UPDATE SET
+-- name: InsertToMatches :execrows
+INSERT INTO matches (id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score, team2_id, team2_score, amount_of_games, game_id, league_id, series_id, tournament_id, stream_url, status, forfeit, draw, rescheduled, original_scheduled_at, begin_at, end_at, winner_id, winner_type, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26) ON CONFLICT (id) DO UPDATE SET
     name = EXCLUDED.name,
     slug = EXCLUDED.slug,
     finished = EXCLUDED.finished,
@@ -42,51 +62,634 @@ INSERT INTO matches (id, name, slug, finished, expected_start_time, actual_game_
     league_id = EXCLUDED.league_id,
     series_id = EXCLUDED.series_id,
     tournament_id = EXCLUDED.tournament_id,
-    stream_url = EXCLUDED.stream_url;
+    stream_url = EXCLUDED.stream_url,
+    status = EXCLUDED.status,
+    forfeit = EXCLUDED.forfeit,
+    draw = EXCLUDED.draw,
+    rescheduled = EXCLUDED.rescheduled,
+    original_scheduled_at = EXCLUDED.original_scheduled_at,
+    begin_at = EXCLUDED.begin_at,
+    end_at = EXCLUDED.end_at,
+    winner_id = EXCLUDED.winner_id,
+    winner_type = EXCLUDED.winner_type,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE matches.deleted_at IS NOT NULL
+    OR matches.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > matches.modified_at, true);
 
--- name: InsertToTeams :exec
-INSERT INTO teams (id, name, slug, acronym, image_link, game_id) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (id) DO UPDATE SET
+-- name: InsertToTeams :execrows
+INSERT INTO teams (id, name, slug, acronym, image_link, game_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO UPDATE SET
     name = EXCLUDED.name,
     slug = EXCLUDED.slug,
     acronym = EXCLUDED.acronym,
     image_link = EXCLUDED.image_link,
-    game_id = EXCLUDED.game_id;
+    game_id = EXCLUDED.game_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE teams.deleted_at IS NOT NULL
+    OR teams.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > teams.modified_at, true);
+
+-- name: InsertToPlayers :execrows
+INSERT INTO players (id, name, slug, first_name, last_name, nationality, image_link, game_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    slug = EXCLUDED.slug,
+    first_name = EXCLUDED.first_name,
+    last_name = EXCLUDED.last_name,
+    nationality = EXCLUDED.nationality,
+    image_link = EXCLUDED.image_link,
+    game_id = EXCLUDED.game_id,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE players.deleted_at IS NOT NULL
+    OR players.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > players.modified_at, true);
+
+-- name: InsertMatchOpponent :exec
+INSERT INTO match_opponents (match_id, slot, opponent_type, opponent_id, score, placement) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (match_id, slot) DO UPDATE SET
+    opponent_type = EXCLUDED.opponent_type,
+    opponent_id = EXCLUDED.opponent_id,
+    score = EXCLUDED.score,
+    placement = EXCLUDED.placement;
+
+-- name: TrimMatchOpponents :exec
+DELETE FROM match_opponents WHERE match_id = $1 AND slot >= $2;
+
+-- name: GetMatchOpponentsByMatchID :many
+SELECT * FROM match_opponents WHERE match_id = $1 ORDER BY slot ASC;
+
+-- name: GetMatchIDsByOpponent :many
+SELECT match_id FROM match_opponents WHERE opponent_type = $1 AND opponent_id = $2 ORDER BY match_id DESC LIMIT $3;
+
+-- name: GetMatchByID :one
+SELECT * FROM matches WHERE id = $1;
+
+-- name: InsertMatchChange :exec
+INSERT INTO match_changes (match_id, field, old_value, new_value) VALUES ($1, $2, $3, $4);
+
+-- name: GetMatchChangesByMatchID :many
+SELECT * FROM match_changes WHERE match_id = $1 ORDER BY detected_at DESC, id DESC;
+
+-- name: GetMatchChangesSince :many
+SELECT * FROM match_changes WHERE detected_at >= $1 ORDER BY detected_at ASC, id ASC;
+
+-- name: GetMatchChangesByFieldSince :many
+SELECT * FROM match_changes WHERE field = $1 AND detected_at >= $2 ORDER BY detected_at ASC, id ASC;
 
 -- name: GameExist :one
-SELECT COUNT(*) FROM games WHERE id = $1;
+SELECT COUNT(*) FROM games WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: LeagueExist :one
-SELECT COUNT(*) FROM leagues WHERE id = $1;
+SELECT COUNT(*) FROM leagues WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: SeriesExist :one
-SELECT COUNT(*) FROM series WHERE id = $1;
+SELECT COUNT(*) FROM series WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: TournamentExist :one
-SELECT COUNT(*) FROM tournaments WHERE id = $1;
+SELECT COUNT(*) FROM tournaments WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: MatchExist :one
-SELECT COUNT(*) FROM matches WHERE id = $1;
+SELECT COUNT(*) FROM matches WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: TeamExist :one
-SELECT COUNT(*) FROM teams WHERE id = $1;
+SELECT COUNT(*) FROM teams WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: PlayerExist :one
+SELECT COUNT(*) FROM players WHERE id = $1 AND deleted_at IS NULL;
 
 
 -- name: GetAllGames :many
-SELECT id, name, slug FROM games WHERE (id != 14) ORDER BY id ASC;
+SELECT * FROM games WHERE (id != 14) AND deleted_at IS NULL ORDER BY id ASC;
 
 -- name: GetSeriesByGameID :many
-SELECT id, name, slug, game_id, league_id FROM series WHERE game_id = $1 ORDER BY name ASC;
+SELECT * FROM series WHERE game_id = $1 AND deleted_at IS NULL ORDER BY name ASC;
 
 -- name: GetLeaguesByGameID :many
 SELECT l.*
 FROM LEAGUES l
-LEFT JOIN TOURNAMENTS t ON l.id = t.league_id
-WHERE l.game_id = $1
-GROUP BY l.id, l.name, l.slug, l.game_id, l.image_link
+LEFT JOIN TOURNAMENTS t ON l.id = t.league_id AND t.deleted_at IS NULL
+WHERE l.game_id = $1 AND l.deleted_at IS NULL
+GROUP BY l.id, l.name, l.slug, l.game_id, l.image_link, l.last_seen_at, l.deleted_at, l.modified_at
 ORDER BY MIN(t.tier) ASC, l.name ASC;
 
+-- name: GetTournamentsByGameID :many
+SELECT * FROM tournaments WHERE game_id = $1 AND deleted_at IS NULL ORDER BY name ASC;
+
 -- name: UpdateMatchesIsLiveByIDs :exec
 UPDATE MATCHES SET is_live = $1 WHERE id = ANY($2::int[]);
 
--- name: ClearMatchesIsLiveExceptIDs :exec
-UPDATE MATCHES SET is_live = false WHERE id != ALL($1::int[]);
+-- name: ClearMatchesIsLiveExceptIDs :many
+UPDATE MATCHES SET is_live = false WHERE is_live AND id != ALL($1::int[])
+RETURNING id;
+
+-- name: GetStaleMatchIDs :many
+SELECT id FROM matches
+WHERE deleted_at IS NULL AND finished = false AND last_seen_at < $1
+ORDER BY last_seen_at ASC
+LIMIT $2;
+
+-- name: GetStaleTournamentIDs :many
+SELECT id FROM tournaments
+WHERE deleted_at IS NULL AND last_seen_at < $1
+ORDER BY last_seen_at ASC
+LIMIT $2;
+
+-- name: GetStaleTeamIDs :many
+SELECT id FROM teams
+WHERE deleted_at IS NULL AND last_seen_at < $1
+ORDER BY last_seen_at ASC
+LIMIT $2;
+
+-- name: TombstoneMatch :exec
+UPDATE matches SET deleted_at = CURRENT_TIMESTAMP, is_live = false WHERE id = $1;
+
+-- name: TombstoneTournament :exec
+UPDATE tournaments SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1;
+
+-- name: TombstoneTeam :exec
+UPDATE teams SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1;
+
+-- name: GetMatchesByIDs :many
+SELECT * FROM matches WHERE id = ANY(@ids::int[]);
+
+-- name: GetExistingGameIDs :many
+SELECT id FROM games WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetKnownGameIDs :many
+SELECT id FROM games WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1;
+
+-- name: GetExistingLeagueIDs :many
+SELECT id FROM leagues WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetKnownLeagueIDs :many
+SELECT id FROM leagues WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1;
+
+-- name: GetExistingSeriesIDs :many
+SELECT id FROM series WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetKnownSeriesIDs :many
+SELECT id FROM series WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1;
+
+-- name: GetExistingTournamentIDs :many
+SELECT id FROM tournaments WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetKnownTournamentIDs :many
+SELECT id FROM tournaments WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1;
+
+-- name: GetExistingTeamIDs :many
+SELECT id FROM teams WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetKnownTeamIDs :many
+SELECT id FROM teams WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1;
+
+-- name: GetExistingPlayerIDs :many
+SELECT id FROM players WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetKnownPlayerIDs :many
+SELECT id FROM players WHERE deleted_at IS NULL ORDER BY last_seen_at DESC LIMIT $1;
+
+-- name: UpsertMatchesBatch :batchone
+INSERT INTO matches (id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score, team2_id, team2_score, amount_of_games, game_id, league_id, series_id, tournament_id, stream_url, status, forfeit, draw, rescheduled, original_scheduled_at, begin_at, end_at, winner_id, winner_type, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26) ON CONFLICT (id) DO UPDATE SET
+    name = EXCLUDED.name,
+    slug = EXCLUDED.slug,
+    finished = EXCLUDED.finished,
+    expected_start_time = EXCLUDED.expected_start_time,
+    actual_game_time = EXCLUDED.actual_game_time,
+    team1_id = EXCLUDED.team1_id,
+    team1_score = EXCLUDED.team1_score,
+    team2_id = EXCLUDED.team2_id,
+    team2_score = EXCLUDED.team2_score,
+    amount_of_games = EXCLUDED.amount_of_games,
+    game_id = EXCLUDED.game_id,
+    league_id = EXCLUDED.league_id,
+    series_id = EXCLUDED.series_id,
+    tournament_id = EXCLUDED.tournament_id,
+    stream_url = EXCLUDED.stream_url,
+    status = EXCLUDED.status,
+    forfeit = EXCLUDED.forfeit,
+    draw = EXCLUDED.draw,
+    rescheduled = EXCLUDED.rescheduled,
+    original_scheduled_at = EXCLUDED.original_scheduled_at,
+    begin_at = EXCLUDED.begin_at,
+    end_at = EXCLUDED.end_at,
+    winner_id = EXCLUDED.winner_id,
+    winner_type = EXCLUDED.winner_type,
+    last_seen_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL,
+    modified_at = EXCLUDED.modified_at
+WHERE matches.deleted_at IS NOT NULL
+    OR matches.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day'
+    OR COALESCE(EXCLUDED.modified_at > matches.modified_at, true)
+RETURNING id;
+
+-- name: InsertMatchOpponentsBatch :batchexec
+INSERT INTO match_opponents (match_id, slot, opponent_type, opponent_id, score, placement) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (match_id, slot) DO UPDATE SET
+    opponent_type = EXCLUDED.opponent_type,
+    opponent_id = EXCLUDED.opponent_id,
+    score = EXCLUDED.score,
+    placement = EXCLUDED.placement;
+
+-- name: TrimMatchOpponentsBatch :batchexec
+DELETE FROM match_opponents WHERE match_id = $1 AND slot >= $2;
+
+-- name: InsertMatchChangesBatch :batchexec
+INSERT INTO match_changes (match_id, field, old_value, new_value) VALUES ($1, $2, $3, $4);
+
+-- name: InsertMissingTeamsBatch :batchexec
+INSERT INTO teams (id, name, slug, acronym, image_link, game_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO UPDATE SET last_seen_at = EXCLUDED.last_seen_at, deleted_at = NULL
+WHERE teams.deleted_at IS NOT NULL OR teams.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day';
+
+-- name: InsertMissingPlayersBatch :batchexec
+INSERT INTO players (id, name, slug, first_name, last_name, nationality, image_link, game_id, modified_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (id) DO UPDATE SET last_seen_at = EXCLUDED.last_seen_at, deleted_at = NULL
+WHERE players.deleted_at IS NOT NULL OR players.last_seen_at < CURRENT_TIMESTAMP - INTERVAL '1 day';
+
+-- name: EnqueueResolveRetry :exec
+INSERT INTO resolve_retries (entity_type, entity_id, last_error) VALUES ($1, $2, $3)
+ON CONFLICT (entity_type, entity_id) DO UPDATE SET last_error = EXCLUDED.last_error;
+
+-- name: GetDueResolveRetries :many
+SELECT * FROM resolve_retries
+WHERE attempts < @max_attempts
+ORDER BY last_attempt_at ASC NULLS FIRST, created_at ASC
+LIMIT @max_rows;
+
+-- name: MarkResolveRetryFailed :exec
+UPDATE resolve_retries
+SET attempts = attempts + 1, last_error = $3, last_attempt_at = CURRENT_TIMESTAMP
+WHERE entity_type = $1 AND entity_id = $2;
+
+-- name: DeleteResolveRetry :exec
+DELETE FROM resolve_retries WHERE entity_type = $1 AND entity_id = $2;
+
+-- name: CountArchivableMatches :one
+SELECT COUNT(*) FROM matches
+WHERE finished AND COALESCE(end_at, begin_at, expected_start_time) < @cutoff::timestamptz;
+
+-- name: GetArchivableMatchYears :many
+SELECT DISTINCT EXTRACT(YEAR FROM COALESCE(end_at, begin_at, expected_start_time) AT TIME ZONE 'UTC')::int AS archive_year
+FROM matches
+WHERE finished AND COALESCE(end_at, begin_at, expected_start_time) < @cutoff::timestamptz;
+
+-- name: EnsureMatchesArchivePartition :exec
+SELECT ensure_matches_archive_partition(@archive_year::int);
+
+-- name: GetArchivedMatchesByIDs :many
+SELECT DISTINCT ON (id) * FROM matches_archive
+WHERE id = ANY(@ids::int[])
+ORDER BY id, archived_at DESC;
+
+-- name: ArchiveMatches :execrows
+WITH moved AS (
+    DELETE FROM matches
+    WHERE id IN (
+        SELECT id FROM matches
+        WHERE finished AND COALESCE(end_at, begin_at, expected_start_time) < @cutoff::timestamptz
+        ORDER BY COALESCE(end_at, begin_at, expected_start_time) ASC
+        LIMIT @max_rows
+    )
+    RETURNING
+        id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score,
+        team2_id, team2_score, amount_of_games, is_live, stream_url, status, forfeit, draw, rescheduled,
+        original_scheduled_at, begin_at, end_at, winner_id, winner_type, game_id, league_id, series_id,
+        tournament_id, last_seen_at, deleted_at, modified_at
+),
+-- A match archived before is only synced back into MATCHES once it is no
+-- longer finished. Archiving it again replaces every previous copy: the one of
+-- the same played_at through ON CONFLICT, the ones left in other partitions
+-- when its times changed here. Their changes are carried over below, as every
+-- statement of the query reads the archive as it was before the deletion.
+superseded AS (
+    DELETE FROM matches_archive a
+    USING moved m
+    WHERE a.id = m.id AND a.played_at <> COALESCE(m.end_at, m.begin_at, m.expected_start_time)
+)
+INSERT INTO matches_archive (
+    id, name, slug, finished, expected_start_time, actual_game_time, team1_id, team1_score,
+    team2_id, team2_score, amount_of_games, is_live, stream_url, status, forfeit, draw, rescheduled,
+    original_scheduled_at, begin_at, end_at, winner_id, winner_type, game_id, league_id, series_id,
+    tournament_id, last_seen_at, deleted_at, modified_at, played_at, opponents, changes
+)
+SELECT
+    m.id, m.name, m.slug, m.finished, m.expected_start_time, m.actual_game_time, m.team1_id, m.team1_score,
+    m.team2_id, m.team2_score, m.amount_of_games, m.is_live, m.stream_url, m.status, m.forfeit, m.draw, m.rescheduled,
+    m.original_scheduled_at, m.begin_at, m.end_at, m.winner_id, m.winner_type, m.game_id, m.league_id, m.series_id,
+    m.tournament_id, m.last_seen_at, m.deleted_at, m.modified_at,
+    COALESCE(m.end_at, m.begin_at, m.expected_start_time),
+    COALESCE((SELECT json_agg(o ORDER BY o.slot) FROM match_opponents o WHERE o.match_id = m.id), '[]'),
+    COALESCE((
+        SELECT json_agg(merged.c ORDER BY (merged.c->>'detected_at')::timestamptz) FROM (
+            SELECT json_array_elements(p.changes) AS c FROM (
+                SELECT a.changes FROM matches_archive a WHERE a.id = m.id ORDER BY a.archived_at DESC LIMIT 1
+            ) p
+            UNION ALL
+            SELECT row_to_json(c) FROM match_changes c WHERE c.match_id = m.id
+        ) merged
+    ), '[]')
+FROM moved m
+ON CONFLICT (id, played_at) DO UPDATE SET
+    name = EXCLUDED.name, slug = EXCLUDED.slug, finished = EXCLUDED.finished,
+    expected_start_time = EXCLUDED.expected_start_time, actual_game_time = EXCLUDED.actual_game_time,
+    team1_id = EXCLUDED.team1_id, team1_score = EXCLUDED.team1_score, team2_id = EXCLUDED.team2_id,
+    team2_score = EXCLUDED.team2_score, amount_of_games = EXCLUDED.amount_of_games,
+    is_live = EXCLUDED.is_live, stream_url = EXCLUDED.stream_url, status = EXCLUDED.status,
+    forfeit = EXCLUDED.forfeit, draw = EXCLUDED.draw, rescheduled = EXCLUDED.rescheduled,
+    original_scheduled_at = EXCLUDED.original_scheduled_at, begin_at = EXCLUDED.begin_at,
+    end_at = EXCLUDED.end_at, winner_id = EXCLUDED.winner_id, winner_type = EXCLUDED.winner_type,
+    game_id = EXCLUDED.game_id, league_id = EXCLUDED.league_id, series_id = EXCLUDED.series_id,
+    tournament_id = EXCLUDED.tournament_id, last_seen_at = EXCLUDED.last_seen_at,
+    deleted_at = EXCLUDED.deleted_at, modified_at = EXCLUDED.modified_at, opponents = EXCLUDED.opponents,
+    changes = EXCLUDED.changes, archived_at = CURRENT_TIMESTAMP;
+
+-- name: CountExpiredURLMappings :one
+SELECT COUNT(*) FROM url_mappings WHERE accessed_at < $1;
+
+-- name: DeleteExpiredURLMappings :execrows
+DELETE FROM url_mappings
+WHERE hashed_key IN (
+    SELECT hashed_key FROM url_mappings
+    WHERE accessed_at < $1
+    ORDER BY accessed_at ASC
+    LIMIT $2
+);
+
+-- name: RefreshUpcomingMatchesView :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY upcoming_matches_view;
+
+-- name: RefreshRankedLeaguesView :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY ranked_leagues_view;
+
+-- name: GetUpcomingMatchesByGameID :many
+SELECT
+    id, name, slug, status, is_live, rescheduled, expected_start_time, begin_at, stream_url, amount_of_games,
+    team1_id, team1_name, team1_acronym, team1_image_link, team1_score,
+    team2_id, team2_name, team2_acronym, team2_image_link, team2_score,
+    game_id, game_name, game_slug, league_id, league_name, league_image_link,
+    series_id, series_name, tournament_id, tournament_name, tournament_tier
+FROM upcoming_matches_view
+WHERE game_id = $1
+ORDER BY expected_start_time ASC NULLS LAST, id ASC
+LIMIT $2;
+
+-- name: GetRankedLeaguesByGameID :many
+SELECT id, name, slug, game_id, image_link, best_tier, tournament_count, game_rank
+FROM ranked_leagues_view
+WHERE game_id = $1
+ORDER BY game_rank ASC;
+
+-- name: SearchEntities :many
+WITH search AS (
+    SELECT websearch_to_tsquery('simple', stalka_unaccent(@query::text)) AS tsq, stalka_unaccent(@query::text) AS plain
+),
+hits AS (
+    SELECT 'teams'::text AS entity_type, t.id, t.name, t.slug, t.game_id, t.image_link,
+        CASE WHEN t.search_vector @@ s.tsq THEN 1 + ts_rank(t.search_vector, s.tsq)
+        ELSE similarity(stalka_unaccent(t.name), s.plain) END AS score
+    FROM teams t, search s
+    WHERE t.deleted_at IS NULL AND (t.search_vector @@ s.tsq OR stalka_unaccent(t.name) % s.plain)
+    UNION ALL
+    SELECT 'leagues'::text, l.id, l.name, l.slug, l.game_id, l.image_link,
+        CASE WHEN l.search_vector @@ s.tsq THEN 1 + ts_rank(l.search_vector, s.tsq)
+        ELSE similarity(stalka_unaccent(l.name), s.plain) END
+    FROM leagues l, search s
+    WHERE l.deleted_at IS NULL AND (l.search_vector @@ s.tsq OR stalka_unaccent(l.name) % s.plain)
+    UNION ALL
+    SELECT 'tournaments'::text, tr.id, tr.name, tr.slug, tr.game_id, NULL::varchar,
+        CASE WHEN tr.search_vector @@ s.tsq THEN 1 + ts_rank(tr.search_vector, s.tsq)
+        ELSE similarity(stalka_unaccent(tr.name), s.plain) END
+    FROM tournaments tr, search s
+    WHERE tr.deleted_at IS NULL AND (tr.search_vector @@ s.tsq OR stalka_unaccent(tr.name) % s.plain)
+    UNION ALL
+    SELECT 'players'::text, p.id, p.name, p.slug, p.game_id, p.image_link,
+        CASE WHEN p.search_vector @@ s.tsq THEN 1 + ts_rank(p.search_vector, s.tsq)
+        ELSE similarity(stalka_unaccent(p.name), s.plain) END
+    FROM players p, search s
+    WHERE p.deleted_at IS NULL AND (p.search_vector @@ s.tsq OR stalka_unaccent(p.name) % s.plain)
+)
+SELECT entity_type, id, name, slug, game_id, image_link, score::real AS score
+FROM hits
+ORDER BY score DESC, name ASC, id ASC
+LIMIT @max_rows;
+
+-- name: ListGames :many
+SELECT * FROM games WHERE (id != 14) AND deleted_at IS NULL ORDER BY id ASC
+LIMIT @max_rows OFFSET @skip_rows;
+
+-- name: ListSeriesByGameID :many
+SELECT * FROM series WHERE game_id = @game_id AND deleted_at IS NULL ORDER BY name ASC, id ASC
+LIMIT @max_rows OFFSET @skip_rows;
+
+-- name: ListTournamentsByGameID :many
+SELECT * FROM tournaments WHERE game_id = @game_id AND deleted_at IS NULL ORDER BY name ASC, id ASC
+LIMIT @max_rows OFFSET @skip_rows;
+
+-- name: ListMatches :many
+SELECT m.*
+FROM matches m
+WHERE m.deleted_at IS NULL
+    AND (sqlc.narg(starts_after)::timestamptz IS NULL OR m.expected_start_time >= sqlc.narg(starts_after))
+    AND (sqlc.narg(starts_before)::timestamptz IS NULL OR m.expected_start_time < sqlc.narg(starts_before))
+    AND (sqlc.narg(game_id)::int IS NULL OR m.game_id = sqlc.narg(game_id))
+    AND (sqlc.narg(league_id)::int IS NULL OR m.league_id = sqlc.narg(league_id))
+    AND (sqlc.narg(team_id)::int IS NULL OR EXISTS (
+        SELECT 1 FROM match_opponents mo
+        WHERE mo.match_id = m.id AND mo.opponent_type = 'Team' AND mo.opponent_id = sqlc.narg(team_id)
+    ))
+    AND (sqlc.narg(is_live)::boolean IS NULL OR m.is_live = sqlc.narg(is_live))
+ORDER BY m.expected_start_time ASC NULLS LAST, m.id ASC
+LIMIT @max_rows OFFSET @skip_rows;
+
+-- name: GetGamesByIDs :many
+SELECT * FROM games WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetLeaguesByIDs :many
+SELECT * FROM leagues WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetLeaguesByGameIDs :many
+SELECT * FROM leagues
+WHERE game_id = ANY(@game_ids::int[]) AND deleted_at IS NULL
+ORDER BY name ASC, id ASC;
+
+-- name: GetSeriesByIDs :many
+SELECT * FROM series WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetSeriesByLeagueIDs :many
+SELECT * FROM series
+WHERE league_id = ANY(@league_ids::int[]) AND deleted_at IS NULL
+ORDER BY name ASC, id ASC;
+
+-- name: GetTournamentsByIDs :many
+SELECT * FROM tournaments WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetTournamentsBySeriesIDs :many
+SELECT * FROM tournaments
+WHERE serie_id = ANY(@series_ids::int[]) AND deleted_at IS NULL
+ORDER BY name ASC, id ASC;
+
+-- name: GetMatchesByTournamentIDs :many
+SELECT * FROM matches
+WHERE tournament_id = ANY(@tournament_ids::int[]) AND deleted_at IS NULL
+ORDER BY expected_start_time ASC NULLS LAST, id ASC;
+
+-- name: GetTeamsByIDs :many
+SELECT * FROM teams WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetPlayersByIDs :many
+SELECT * FROM players WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
+
+-- name: GetMatchOpponentsByMatchIDs :many
+SELECT * FROM match_opponents WHERE match_id = ANY(@match_ids::int[]) ORDER BY match_id ASC, slot ASC;
+
+-- name: GetCalendarMatches :many
+SELECT
+    m.id, m.name, m.status, m.expected_start_time, m.begin_at, m.end_at, m.amount_of_games, m.stream_url, m.modified_at,
+    m.team1_id, t1.name AS team1_name, m.team2_id, t2.name AS team2_name,
+    g.name AS game_name, l.name AS league_name, tr.name AS tournament_name,
+    (
+        SELECT COUNT(*) FROM match_changes c
+        WHERE c.match_id = m.id AND c.field IN ('expected_start_time', 'status', 'amount_of_games')
+    )::int AS sequence
+FROM matches m
+JOIN games g ON g.id = m.game_id
+JOIN leagues l ON l.id = m.league_id
+JOIN tournaments tr ON tr.id = m.tournament_id
+LEFT JOIN teams t1 ON t1.id = m.team1_id
+LEFT JOIN teams t2 ON t2.id = m.team2_id
+WHERE m.deleted_at IS NULL
+    AND COALESCE(m.begin_at, m.expected_start_time) >= @since::timestamptz
+    AND (
+        m.game_id = ANY(@game_ids::int[])
+        OR m.league_id = ANY(@league_ids::int[])
+        OR EXISTS (
+            SELECT 1 FROM match_opponents mo
+            WHERE mo.match_id = m.id AND mo.opponent_type = 'Team' AND mo.opponent_id = ANY(@team_ids::int[])
+        )
+    )
+ORDER BY COALESCE(m.begin_at, m.expected_start_time) ASC, m.id ASC
+LIMIT @max_rows;
+
+-- name: TouchURLMapping :one
+UPDATE url_mappings SET access_count = access_count + 1, accessed_at = CURRENT_TIMESTAMP
+WHERE hashed_key = $1
+RETURNING value_list;
+
+-- name: SaveURLMapping :one
+INSERT INTO url_mappings (hashed_key, value_list, access_count)
+VALUES (@hashed_key, @value_list, 0)
+ON CONFLICT (hashed_key) DO UPDATE SET accessed_at = CURRENT_TIMESTAMP
+RETURNING value_list;
+
+-- name: InsertOutboxEvent :exec
+WITH event AS (
+    INSERT INTO outbox (event_type, match_id, data)
+    SELECT @event_type::varchar, @match_id::int, sqlc.narg(data)::json
+    WHERE EXISTS (SELECT 1 FROM webhook_subscribers)
+    RETURNING id
+)
+INSERT INTO webhook_deliveries (subscriber, event_id)
+SELECT s.name, event.id FROM webhook_subscribers s CROSS JOIN event;
+
+-- name: InsertOutboxEventsBatch :batchexec
+WITH event AS (
+    INSERT INTO outbox (event_type, match_id, data)
+    SELECT @event_type::varchar, @match_id::int, sqlc.narg(data)::json
+    WHERE EXISTS (SELECT 1 FROM webhook_subscribers)
+    RETURNING id
+)
+INSERT INTO webhook_deliveries (subscriber, event_id)
+SELECT s.name, event.id FROM webhook_subscribers s CROSS JOIN event;
+
+-- name: MarkMatchesLive :many
+WITH started AS (
+    UPDATE matches SET is_live = true WHERE id = ANY(@ids::int[]) AND NOT is_live
+    RETURNING id
+), event AS (
+    INSERT INTO outbox (event_type, match_id)
+    SELECT 'match_live', started.id FROM started
+    WHERE EXISTS (SELECT 1 FROM webhook_subscribers)
+    RETURNING id
+), delivery AS (
+    INSERT INTO webhook_deliveries (subscriber, event_id)
+    SELECT s.name, event.id FROM webhook_subscribers s CROSS JOIN event
+)
+SELECT id FROM started;
+
+-- name: UpsertWebhookSubscriber :exec
+INSERT INTO webhook_subscribers (name, url) VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET url = EXCLUDED.url;
+
+-- name: DeleteWebhookSubscribersExcept :exec
+DELETE FROM webhook_subscribers WHERE name != ALL(@names::varchar[]);
+
+-- name: GetDueWebhookDeliveries :many
+SELECT
+    d.event_id, d.attempts, o.event_type, o.match_id, o.data, o.created_at,
+    m.name, m.status, m.game_id, m.league_id, m.tournament_id, m.team1_id, m.team2_id,
+    m.expected_start_time, m.begin_at, m.end_at, m.winner_id, m.stream_url
+FROM webhook_deliveries d
+JOIN outbox o ON o.id = d.event_id
+LEFT JOIN matches m ON m.id = o.match_id
+WHERE d.subscriber = @subscriber AND d.status = 'pending' AND d.next_attempt_at <= @now::timestamptz
+ORDER BY d.event_id ASC
+LIMIT @max_rows;
+
+-- name: MarkWebhookDelivered :exec
+UPDATE webhook_deliveries
+SET status = 'delivered', attempts = attempts + 1, last_error = NULL, last_attempt_at = @now, delivered_at = @now
+WHERE subscriber = @subscriber AND event_id = @event_id;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET attempts = attempts + 1, last_error = @last_error, last_attempt_at = @now, next_attempt_at = @next_attempt_at,
+    status = CASE WHEN attempts + 1 >= @max_attempts::int THEN 'dead' ELSE 'pending' END
+WHERE subscriber = @subscriber AND event_id = @event_id;
+
+-- name: RequeueDeadWebhookDeliveries :execrows
+UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP
+WHERE subscriber = $1 AND status = 'dead';
+
+-- name: GetWebhookDeliveryStatus :many
+SELECT
+    s.name, s.url,
+    COUNT(d.event_id) FILTER (WHERE d.status = 'pending') AS pending,
+    COUNT(d.event_id) FILTER (WHERE d.status = 'delivered') AS delivered,
+    COUNT(d.event_id) FILTER (WHERE d.status = 'dead') AS dead,
+    MAX(d.delivered_at)::timestamptz AS last_delivered_at,
+    (
+        SELECT f.last_error FROM webhook_deliveries f
+        WHERE f.subscriber = s.name AND f.last_error IS NOT NULL
+        ORDER BY f.last_attempt_at DESC LIMIT 1
+    ) AS last_error
+FROM webhook_subscribers s
+LEFT JOIN webhook_deliveries d ON d.subscriber = s.name
+GROUP BY s.name, s.url
+ORDER BY s.name ASC;
+
+-- name: DeleteOldOutboxEvents :execrows
+DELETE FROM outbox o
+WHERE o.created_at < @cutoff
+    AND NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.event_id = o.id AND d.status = 'pending');
+
+-- name: Notify :exec
+SELECT pg_notify(@channel::text, @payload::text);
+
+-- name: GetResolveRetries :many
+SELECT * FROM resolve_retries
+ORDER BY created_at ASC, entity_type ASC, entity_id ASC
+LIMIT @max_rows;
+
+-- name: CountResolveRetries :one
+SELECT COUNT(*) AS total, COUNT(*) FILTER (WHERE attempts >= @max_attempts) AS exhausted
+FROM resolve_retries;
+
+-- name: InsertAdminAudit :exec
+INSERT INTO admin_audit (actor, remote_addr, action, target, outcome, error)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetAdminAudit :many
+SELECT * FROM admin_audit
+ORDER BY id DESC
+LIMIT @max_rows;
@@ -10,7 +10,9 @@
 CREATE TABLE IF NOT EXISTS GAMES(
     id INTEGER PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
-    slug VARCHAR(255)
+    slug VARCHAR(255),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ
 );
 
 CREATE TABLE IF NOT EXISTS LEAGUES(
@@ -19,6 +21,9 @@ CREATE TABLE IF NOT EXISTS LEAGUES(
     slug VARCHAR(255),
     game_id INT NOT NULL,
     image_link VARCHAR(255),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+    modified_at TIMESTAMPTZ,
     FOREIGN KEY (game_id) REFERENCES GAMES(id)
 );
 
@@ -28,6 +33,9 @@ CREATE TABLE IF NOT EXISTS SERIES(
     slug VARCHAR(255),
     game_id INT NOT NULL,
     league_id INT NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+    modified_at TIMESTAMPTZ,
     FOREIGN KEY (game_id) REFERENCES GAMES(id),
     FOREIGN KEY (league_id) REFERENCES LEAGUES(id)
 );
@@ -40,6 +48,9 @@ CREATE TABLE IF NOT EXISTS TOURNAMENTS(
     game_id INT NOT NULL,
     league_id INT NOT NULL,
     serie_id INT NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+    modified_at TIMESTAMPTZ,
     FOREIGN KEY (game_id) REFERENCES GAMES(id),
     FOREIGN KEY (serie_id) REFERENCES SERIES(id),
     FOREIGN KEY (league_id) REFERENCES LEAGUES(id)
@@ -51,7 +62,7 @@ CREATE TABLE IF NOT EXISTS MATCHES(
     name VARCHAR(255) NOT NULL,
     slug VARCHAR(255),
     finished BOOLEAN NOT NULL,
-    expected_start_time TIMESTAMP,
+    expected_start_time TIMESTAMPTZ,
     actual_game_time FLOAT NOT NULL,
     team1_id INT NOT NULL,
     team1_score INT NOT NULL,
@@ -60,10 +71,22 @@ CREATE TABLE IF NOT EXISTS MATCHES(
     amount_of_games INT NOT NULL,
     is_live BOOLEAN NOT NULL DEFAULT false,
     stream_url TEXT,
+    status VARCHAR(32),
+    forfeit BOOLEAN NOT NULL DEFAULT false,
+    draw BOOLEAN NOT NULL DEFAULT false,
+    rescheduled BOOLEAN NOT NULL DEFAULT false,
+    original_scheduled_at TIMESTAMPTZ,
+    begin_at TIMESTAMPTZ,
+    end_at TIMESTAMPTZ,
+    winner_id INT,
+    winner_type VARCHAR(32),
     game_id INT NOT NULL,
     league_id INT NOT NULL,
     series_id INT NOT NULL,
     tournament_id INT NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+    modified_at TIMESTAMPTZ,
     FOREIGN KEY (game_id) REFERENCES GAMES(id),
     FOREIGN KEY (league_id) REFERENCES LEAGUES(id),
     FOREIGN KEY (series_id) REFERENCES SERIES(id),
@@ -77,15 +100,190 @@ CREATE TABLE IF NOT EXISTS TEAMS(
     acronym VARCHAR(255),
     image_link VARCHAR(255),
     game_id INT NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+    modified_at TIMESTAMPTZ,
+    FOREIGN KEY (game_id) REFERENCES GAMES(id)
+);
+
+-- MATCH_CHANGES keeps a history of field changes detected when a match is
+-- re-written, e.g. reschedules, score changes or stream swaps.
+CREATE TABLE IF NOT EXISTS MATCH_CHANGES(
+    id BIGSERIAL PRIMARY KEY,
+    match_id INT NOT NULL,
+    field VARCHAR(64) NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    detected_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (match_id) REFERENCES MATCHES(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS match_changes_match_id_idx ON MATCH_CHANGES(match_id, detected_at);
+CREATE INDEX IF NOT EXISTS match_changes_detected_at_idx ON MATCH_CHANGES(detected_at);
+
+-- PLAYERS holds individual competitors of player-vs-player and battle royale
+-- matches. They are created from match opponents the same way TEAMS are.
+CREATE TABLE IF NOT EXISTS PLAYERS(
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255),
+    first_name VARCHAR(255),
+    last_name VARCHAR(255),
+    nationality VARCHAR(8),
+    image_link VARCHAR(255),
+    game_id INT NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMPTZ,
+    modified_at TIMESTAMPTZ,
     FOREIGN KEY (game_id) REFERENCES GAMES(id)
 );
 
+-- MATCH_OPPONENTS lists every opponent of a match in PandaScore order.
+-- opponent_id points into TEAMS or PLAYERS depending on opponent_type, so it
+-- carries no foreign key. placement is only known once the match finished.
+CREATE TABLE IF NOT EXISTS MATCH_OPPONENTS(
+    match_id INT NOT NULL,
+    slot INT NOT NULL,
+    opponent_type VARCHAR(16) NOT NULL,
+    opponent_id INT NOT NULL,
+    score INT NOT NULL DEFAULT 0,
+    placement INT,
+    PRIMARY KEY (match_id, slot),
+    FOREIGN KEY (match_id) REFERENCES MATCHES(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS match_opponents_opponent_idx ON MATCH_OPPONENTS(opponent_type, opponent_id);
+
+-- RESOLVE_RETRIES queues entities that were skipped because one of their
+-- dependencies could not be fetched. They are re-attempted on the next run.
+-- entity_type is the PandaScore endpoint name, e.g. series or matches.
+CREATE TABLE IF NOT EXISTS RESOLVE_RETRIES(
+    entity_type VARCHAR(16) NOT NULL,
+    entity_id INT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMPTZ,
+    PRIMARY KEY (entity_type, entity_id)
+);
+
 CREATE TABLE IF NOT EXISTS URL_MAPPINGS(
     hashed_key VARCHAR(16) NOT NULL PRIMARY KEY,
     value_list JSON NOT NULL,
     access_count INT NOT NULL,
-    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    accessed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    accessed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- MATCHES_ARCHIVE holds the finished matches the retention job moved out of
+-- MATCHES. played_at is the first of end_at, begin_at and expected_start_time;
+-- the table is partitioned by its year, see ensure_matches_archive_partition.
+-- The MATCH_OPPONENTS and MATCH_CHANGES rows of a match are deleted with it, so
+-- they are kept as JSON arrays in opponents and changes.
+CREATE TABLE IF NOT EXISTS MATCHES_ARCHIVE(
+    id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255),
+    finished BOOLEAN NOT NULL,
+    expected_start_time TIMESTAMPTZ,
+    actual_game_time FLOAT NOT NULL,
+    team1_id INT NOT NULL,
+    team1_score INT NOT NULL,
+    team2_id INT NOT NULL,
+    team2_score INT NOT NULL,
+    amount_of_games INT NOT NULL,
+    is_live BOOLEAN NOT NULL DEFAULT false,
+    stream_url TEXT,
+    status VARCHAR(32),
+    forfeit BOOLEAN NOT NULL DEFAULT false,
+    draw BOOLEAN NOT NULL DEFAULT false,
+    rescheduled BOOLEAN NOT NULL DEFAULT false,
+    original_scheduled_at TIMESTAMPTZ,
+    begin_at TIMESTAMPTZ,
+    end_at TIMESTAMPTZ,
+    winner_id INT,
+    winner_type VARCHAR(32),
+    game_id INT NOT NULL,
+    league_id INT NOT NULL,
+    series_id INT NOT NULL,
+    tournament_id INT NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    deleted_at TIMESTAMPTZ,
+    modified_at TIMESTAMPTZ,
+    played_at TIMESTAMPTZ NOT NULL,
+    opponents JSON NOT NULL,
+    changes JSON NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id, played_at)
+) PARTITION BY RANGE (played_at);
+
+-- ensure_matches_archive_partition creates the MATCHES_ARCHIVE partition of a
+-- UTC year. The retention job calls it for every year it is about to archive.
+CREATE OR REPLACE FUNCTION ensure_matches_archive_partition(archive_year INT) RETURNS VOID AS $$
+BEGIN
+    EXECUTE format(
+        'CREATE TABLE IF NOT EXISTS %I PARTITION OF MATCHES_ARCHIVE FOR VALUES FROM (%L) TO (%L)',
+        'matches_archive_y' || archive_year,
+        make_timestamptz(archive_year, 1, 1, 0, 0, 0, 'UTC'),
+        make_timestamptz(archive_year + 1, 1, 1, 0, 0, 0, 'UTC')
+    );
+END $$ LANGUAGE plpgsql;
+
+CREATE INDEX IF NOT EXISTS url_mappings_accessed_at_idx ON URL_MAPPINGS(accessed_at);
+
+-- WEBHOOK_SUBSCRIBERS are the webhook endpoints the dispatcher delivers to. The
+-- dispatcher registers the configured ones on startup and deletes the others.
+CREATE TABLE IF NOT EXISTS WEBHOOK_SUBSCRIBERS(
+    name VARCHAR(64) NOT NULL PRIMARY KEY,
+    url TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- OUTBOX holds the match events for the webhook subscribers. An event is written
+-- by the statement that upserts its match, so it is committed exactly when the
+-- change is; nothing is written while there are no subscribers.
+-- event_type is one of match_created, match_rescheduled, match_live and
+-- match_finished; data holds the details of the event, e.g. the old and new
+-- start of a rescheduled match.
+CREATE TABLE IF NOT EXISTS OUTBOX(
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(32) NOT NULL,
+    match_id INT NOT NULL,
+    data JSON,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- WEBHOOK_DELIVERIES tracks every outbox event per subscriber. status is
+-- pending until the subscriber accepts the event, delivered afterwards and dead
+-- once it failed the maximum number of attempts.
+CREATE TABLE IF NOT EXISTS WEBHOOK_DELIVERIES(
+    subscriber VARCHAR(64) NOT NULL REFERENCES WEBHOOK_SUBSCRIBERS(name) ON DELETE CASCADE,
+    event_id BIGINT NOT NULL REFERENCES OUTBOX(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    PRIMARY KEY (subscriber, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS outbox_created_at_idx ON OUTBOX(created_at);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON WEBHOOK_DELIVERIES(subscriber, next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_event_id_idx ON WEBHOOK_DELIVERIES(event_id);
+
+-- ADMIN_AUDIT records every action taken through the admin API. actor is the
+-- X-Admin-Actor header of the request, outcome is ok, queued or failed, and
+-- error holds the reason of a failed action.
+CREATE TABLE IF NOT EXISTS ADMIN_AUDIT(
+    id BIGSERIAL PRIMARY KEY,
+    actor VARCHAR(64) NOT NULL,
+    remote_addr TEXT NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    target TEXT NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
 -- =============================================================================
@@ -96,3 +294,177 @@ CREATE TABLE IF NOT EXISTS URL_MAPPINGS(
 -- =============================================================================
 ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS is_live BOOLEAN NOT NULL DEFAULT false;
 ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS stream_url TEXT;
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS status VARCHAR(32);
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS forfeit BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS draw BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS rescheduled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS original_scheduled_at TIMESTAMPTZ;
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS begin_at TIMESTAMPTZ;
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS end_at TIMESTAMPTZ;
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS winner_id INT;
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS winner_type VARCHAR(32);
+ALTER TABLE GAMES ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE GAMES ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE LEAGUES ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE LEAGUES ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE SERIES ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE SERIES ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE TOURNAMENTS ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE TOURNAMENTS ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE TEAMS ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE TEAMS ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+-- modified_at is the PandaScore modification time of the row. Upserts skip rows
+-- whose stored modified_at is at least as new, see static/queries.sql.
+ALTER TABLE LEAGUES ADD COLUMN IF NOT EXISTS modified_at TIMESTAMPTZ;
+ALTER TABLE SERIES ADD COLUMN IF NOT EXISTS modified_at TIMESTAMPTZ;
+ALTER TABLE TOURNAMENTS ADD COLUMN IF NOT EXISTS modified_at TIMESTAMPTZ;
+ALTER TABLE MATCHES ADD COLUMN IF NOT EXISTS modified_at TIMESTAMPTZ;
+ALTER TABLE TEAMS ADD COLUMN IF NOT EXISTS modified_at TIMESTAMPTZ;
+ALTER TABLE PLAYERS ADD COLUMN IF NOT EXISTS modified_at TIMESTAMPTZ;
+
+-- search_vector is the full-text document of a team, league, tournament or
+-- player: unaccented names and acronyms weigh A, real names and slugs B. It is a
+-- generated column, so every upsert keeps it current. The trigram indexes on the
+-- unaccented names back the typo fallback of SearchEntities.
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+-- unaccent() is only STABLE because its dictionary could change; pinning the
+-- dictionary makes it usable in generated columns and index expressions.
+CREATE OR REPLACE FUNCTION stalka_unaccent(value TEXT) RETURNS TEXT AS $$
+    SELECT public.unaccent('public.unaccent'::regdictionary, value)
+$$ LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT;
+ALTER TABLE TEAMS ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', stalka_unaccent(COALESCE(name, ''))), 'A') ||
+    setweight(to_tsvector('simple', stalka_unaccent(COALESCE(acronym, ''))), 'A') ||
+    setweight(to_tsvector('simple', stalka_unaccent(COALESCE(slug, ''))), 'B')
+) STORED;
+ALTER TABLE LEAGUES ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', stalka_unaccent(COALESCE(name, ''))), 'A') ||
+    setweight(to_tsvector('simple', stalka_unaccent(COALESCE(slug, ''))), 'B')
+) STORED;
+ALTER TABLE TOURNAMENTS ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', stalka_unaccent(COALESCE(name, ''))), 'A') ||
+    setweight(to_tsvector('simple', stalka_unaccent(COALESCE(slug, ''))), 'B')
+) STORED;
+ALTER TABLE PLAYERS ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', stalka_unaccent(COALESCE(name, ''))), 'A') ||
+    setweight(to_tsvector('simple', stalka_unaccent(COALESCE(first_name, ''))), 'B') ||
+    setweight(to_tsvector('simple', stalka_unaccent(COALESCE(last_name, ''))), 'B') ||
+    setweight(to_tsvector('simple', stalka_unaccent(COALESCE(slug, ''))), 'B')
+) STORED;
+CREATE INDEX IF NOT EXISTS teams_search_idx ON TEAMS USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS teams_name_trgm_idx ON TEAMS USING GIN (stalka_unaccent(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS leagues_search_idx ON LEAGUES USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS leagues_name_trgm_idx ON LEAGUES USING GIN (stalka_unaccent(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS tournaments_search_idx ON TOURNAMENTS USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS tournaments_name_trgm_idx ON TOURNAMENTS USING GIN (stalka_unaccent(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS players_search_idx ON PLAYERS USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS players_name_trgm_idx ON PLAYERS USING GIN (stalka_unaccent(name) gin_trgm_ops);
+
+-- Convert every time column that pre-dates TIMESTAMPTZ. Existing values were
+-- written as UTC wall-clock times, so they are reinterpreted AT TIME ZONE 'UTC'.
+-- The data_type guard keeps this idempotent: converted columns are skipped, so
+-- re-running the schema never shifts values by the session time zone.
+DO $$
+DECLARE
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT table_name, column_name
+        FROM information_schema.columns
+        WHERE table_schema = current_schema()
+          AND data_type = 'timestamp without time zone'
+          AND table_name IN ('games', 'leagues', 'series', 'tournaments', 'matches', 'teams', 'match_changes', 'url_mappings', 'players')
+    LOOP
+        EXECUTE format(
+            'ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ USING %I AT TIME ZONE ''UTC''',
+            col.table_name, col.column_name, col.column_name
+        );
+    END LOOP;
+END $$;
+
+-- matches_played_at_idx backs the match retention policy. It reads begin_at and
+-- end_at, which older deployments only get from the appendix above, and its
+-- COALESCE is only IMMUTABLE once every column it reads is TIMESTAMPTZ, so it
+-- must come after the conversion.
+CREATE INDEX IF NOT EXISTS matches_played_at_idx ON MATCHES((COALESCE(end_at, begin_at, expected_start_time))) WHERE finished;
+
+-- =============================================================================
+-- Read side: materialized views the calendar reads instead of joining on every
+-- page load. They are refreshed concurrently after every sync job, see
+-- PandaClient.RefreshViews, which needs the unique index on id. They come last
+-- because a view pins the types of the columns it reads, which the conversions
+-- above may still change on older deployments. CREATE ... IF NOT EXISTS never
+-- replaces a view, so changing one needs a DROP MATERIALIZED VIEW here first.
+-- =============================================================================
+
+-- UPCOMING_MATCHES_VIEW holds every match that is not finished or canceled,
+-- with the names and logos of its teams, game, league, series and tournament.
+-- The team columns are NULL for matches that are not played by two teams.
+CREATE MATERIALIZED VIEW IF NOT EXISTS UPCOMING_MATCHES_VIEW AS
+SELECT
+    m.id,
+    m.name,
+    m.slug,
+    m.status,
+    m.is_live,
+    m.rescheduled,
+    m.expected_start_time,
+    m.begin_at,
+    m.stream_url,
+    m.amount_of_games,
+    m.team1_id,
+    t1.name AS team1_name,
+    t1.acronym AS team1_acronym,
+    t1.image_link AS team1_image_link,
+    m.team1_score,
+    m.team2_id,
+    t2.name AS team2_name,
+    t2.acronym AS team2_acronym,
+    t2.image_link AS team2_image_link,
+    m.team2_score,
+    m.game_id,
+    g.name AS game_name,
+    g.slug AS game_slug,
+    m.league_id,
+    l.name AS league_name,
+    l.image_link AS league_image_link,
+    m.series_id,
+    s.name AS series_name,
+    m.tournament_id,
+    tr.name AS tournament_name,
+    tr.tier AS tournament_tier
+FROM MATCHES m
+JOIN GAMES g ON g.id = m.game_id
+JOIN LEAGUES l ON l.id = m.league_id
+JOIN SERIES s ON s.id = m.series_id
+JOIN TOURNAMENTS tr ON tr.id = m.tournament_id
+LEFT JOIN TEAMS t1 ON t1.id = m.team1_id
+LEFT JOIN TEAMS t2 ON t2.id = m.team2_id
+WHERE NOT m.finished AND m.deleted_at IS NULL AND m.status IS DISTINCT FROM 'canceled';
+
+CREATE UNIQUE INDEX IF NOT EXISTS upcoming_matches_view_id_idx ON UPCOMING_MATCHES_VIEW(id);
+CREATE INDEX IF NOT EXISTS upcoming_matches_view_game_idx ON UPCOMING_MATCHES_VIEW(game_id, expected_start_time);
+
+-- RANKED_LEAGUES_VIEW ranks the leagues of every game by the best tier of their
+-- tournaments, then by name, like GetLeaguesByGameID. Leagues without a tiered
+-- tournament rank last.
+CREATE MATERIALIZED VIEW IF NOT EXISTS RANKED_LEAGUES_VIEW AS
+SELECT
+    l.id,
+    l.name,
+    l.slug,
+    l.game_id,
+    l.image_link,
+    MIN(t.tier) AS best_tier,
+    COUNT(t.id) AS tournament_count,
+    ROW_NUMBER() OVER (PARTITION BY l.game_id ORDER BY MIN(t.tier) ASC NULLS LAST, l.name ASC, l.id ASC) AS game_rank
+FROM LEAGUES l
+LEFT JOIN TOURNAMENTS t ON l.id = t.league_id AND t.deleted_at IS NULL
+WHERE l.deleted_at IS NULL
+GROUP BY l.id, l.name, l.slug, l.game_id, l.image_link;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ranked_leagues_view_id_idx ON RANKED_LEAGUES_VIEW(id);
+CREATE INDEX IF NOT EXISTS ranked_leagues_view_game_idx ON RANKED_LEAGUES_VIEW(game_id, game_rank);
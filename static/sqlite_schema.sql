@@ -0,0 +1,283 @@
+-- =============================================================================
+-- SQLite flavour of static/schema.sql for local development and CI, used when
+-- storage_backend=sqlite (see pandatypes.SQLiteSink).
+-- Tables, columns and keys mirror static/schema.sql. Times are stored as UTC
+-- text ('YYYY-MM-DD HH:MM:SS'), so they sort and compare like TIMESTAMPTZ.
+-- SQLite has no ADD COLUMN IF NOT EXISTS: there is no migration appendix, and
+-- a local database created before a schema change has to be recreated.
+-- =============================================================================
+
+CREATE TABLE IF NOT EXISTS GAMES(
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS LEAGUES(
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255),
+    game_id INT NOT NULL,
+    image_link VARCHAR(255),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    modified_at TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES GAMES(id)
+);
+
+CREATE TABLE IF NOT EXISTS SERIES(
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255),
+    game_id INT NOT NULL,
+    league_id INT NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    modified_at TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES GAMES(id),
+    FOREIGN KEY (league_id) REFERENCES LEAGUES(id)
+);
+
+CREATE TABLE IF NOT EXISTS TOURNAMENTS(
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255),
+    tier INT,
+    game_id INT NOT NULL,
+    league_id INT NOT NULL,
+    serie_id INT NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    modified_at TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES GAMES(id),
+    FOREIGN KEY (serie_id) REFERENCES SERIES(id),
+    FOREIGN KEY (league_id) REFERENCES LEAGUES(id)
+);
+
+CREATE TABLE IF NOT EXISTS MATCHES(
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255),
+    finished BOOLEAN NOT NULL,
+    expected_start_time TIMESTAMP,
+    actual_game_time FLOAT NOT NULL,
+    team1_id INT NOT NULL,
+    team1_score INT NOT NULL,
+    team2_id INT NOT NULL,
+    team2_score INT NOT NULL,
+    amount_of_games INT NOT NULL,
+    is_live BOOLEAN NOT NULL DEFAULT 0,
+    stream_url TEXT,
+    status VARCHAR(32),
+    forfeit BOOLEAN NOT NULL DEFAULT 0,
+    draw BOOLEAN NOT NULL DEFAULT 0,
+    rescheduled BOOLEAN NOT NULL DEFAULT 0,
+    original_scheduled_at TIMESTAMP,
+    begin_at TIMESTAMP,
+    end_at TIMESTAMP,
+    winner_id INT,
+    winner_type VARCHAR(32),
+    game_id INT NOT NULL,
+    league_id INT NOT NULL,
+    series_id INT NOT NULL,
+    tournament_id INT NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    modified_at TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES GAMES(id),
+    FOREIGN KEY (league_id) REFERENCES LEAGUES(id),
+    FOREIGN KEY (series_id) REFERENCES SERIES(id),
+    FOREIGN KEY (tournament_id) REFERENCES TOURNAMENTS(id)
+);
+
+CREATE TABLE IF NOT EXISTS TEAMS(
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255),
+    acronym VARCHAR(255),
+    image_link VARCHAR(255),
+    game_id INT NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    modified_at TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES GAMES(id)
+);
+
+-- MATCH_CHANGES keeps a history of field changes detected when a match is
+-- re-written, e.g. reschedules, score changes or stream swaps.
+CREATE TABLE IF NOT EXISTS MATCH_CHANGES(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    match_id INT NOT NULL,
+    field VARCHAR(64) NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (match_id) REFERENCES MATCHES(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS match_changes_match_id_idx ON MATCH_CHANGES(match_id, detected_at);
+CREATE INDEX IF NOT EXISTS match_changes_detected_at_idx ON MATCH_CHANGES(detected_at);
+
+-- PLAYERS holds individual competitors of player-vs-player and battle royale
+-- matches. They are created from match opponents the same way TEAMS are.
+CREATE TABLE IF NOT EXISTS PLAYERS(
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255),
+    first_name VARCHAR(255),
+    last_name VARCHAR(255),
+    nationality VARCHAR(8),
+    image_link VARCHAR(255),
+    game_id INT NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    modified_at TIMESTAMP,
+    FOREIGN KEY (game_id) REFERENCES GAMES(id)
+);
+
+-- MATCH_OPPONENTS lists every opponent of a match in PandaScore order.
+-- opponent_id points into TEAMS or PLAYERS depending on opponent_type, so it
+-- carries no foreign key. placement is only known once the match finished.
+CREATE TABLE IF NOT EXISTS MATCH_OPPONENTS(
+    match_id INT NOT NULL,
+    slot INT NOT NULL,
+    opponent_type VARCHAR(16) NOT NULL,
+    opponent_id INT NOT NULL,
+    score INT NOT NULL DEFAULT 0,
+    placement INT,
+    PRIMARY KEY (match_id, slot),
+    FOREIGN KEY (match_id) REFERENCES MATCHES(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS match_opponents_opponent_idx ON MATCH_OPPONENTS(opponent_type, opponent_id);
+
+-- RESOLVE_RETRIES queues entities that were skipped because one of their
+-- dependencies could not be fetched. They are re-attempted on the next run.
+-- entity_type is the PandaScore endpoint name, e.g. series or matches.
+CREATE TABLE IF NOT EXISTS RESOLVE_RETRIES(
+    entity_type VARCHAR(16) NOT NULL,
+    entity_id INT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMP,
+    PRIMARY KEY (entity_type, entity_id)
+);
+
+CREATE TABLE IF NOT EXISTS URL_MAPPINGS(
+    hashed_key VARCHAR(16) NOT NULL PRIMARY KEY,
+    value_list TEXT NOT NULL,
+    access_count INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    accessed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- MATCHES_ARCHIVE mirrors the Postgres table without its partitions, see
+-- static/schema.sql.
+CREATE TABLE IF NOT EXISTS MATCHES_ARCHIVE(
+    id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255),
+    finished BOOLEAN NOT NULL,
+    expected_start_time TIMESTAMP,
+    actual_game_time FLOAT NOT NULL,
+    team1_id INT NOT NULL,
+    team1_score INT NOT NULL,
+    team2_id INT NOT NULL,
+    team2_score INT NOT NULL,
+    amount_of_games INT NOT NULL,
+    is_live BOOLEAN NOT NULL DEFAULT 0,
+    stream_url TEXT,
+    status VARCHAR(32),
+    forfeit BOOLEAN NOT NULL DEFAULT 0,
+    draw BOOLEAN NOT NULL DEFAULT 0,
+    rescheduled BOOLEAN NOT NULL DEFAULT 0,
+    original_scheduled_at TIMESTAMP,
+    begin_at TIMESTAMP,
+    end_at TIMESTAMP,
+    winner_id INT,
+    winner_type VARCHAR(32),
+    game_id INT NOT NULL,
+    league_id INT NOT NULL,
+    series_id INT NOT NULL,
+    tournament_id INT NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+    modified_at TIMESTAMP,
+    played_at TIMESTAMP NOT NULL,
+    opponents TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    archived_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id, played_at)
+);
+
+CREATE INDEX IF NOT EXISTS url_mappings_accessed_at_idx ON URL_MAPPINGS(accessed_at);
+
+-- The read-side views of static/schema.sql. SQLite has no materialized views,
+-- so these are computed on every read and need no refresh.
+
+-- UPCOMING_MATCHES_VIEW holds every match that is not finished or canceled,
+-- with the names and logos of its teams, game, league, series and tournament.
+-- The team columns are NULL for matches that are not played by two teams.
+CREATE VIEW IF NOT EXISTS UPCOMING_MATCHES_VIEW AS
+SELECT
+    m.id,
+    m.name,
+    m.slug,
+    m.status,
+    m.is_live,
+    m.rescheduled,
+    m.expected_start_time,
+    m.begin_at,
+    m.stream_url,
+    m.amount_of_games,
+    m.team1_id,
+    t1.name AS team1_name,
+    t1.acronym AS team1_acronym,
+    t1.image_link AS team1_image_link,
+    m.team1_score,
+    m.team2_id,
+    t2.name AS team2_name,
+    t2.acronym AS team2_acronym,
+    t2.image_link AS team2_image_link,
+    m.team2_score,
+    m.game_id,
+    g.name AS game_name,
+    g.slug AS game_slug,
+    m.league_id,
+    l.name AS league_name,
+    l.image_link AS league_image_link,
+    m.series_id,
+    s.name AS series_name,
+    m.tournament_id,
+    tr.name AS tournament_name,
+    tr.tier AS tournament_tier
+FROM MATCHES m
+JOIN GAMES g ON g.id = m.game_id
+JOIN LEAGUES l ON l.id = m.league_id
+JOIN SERIES s ON s.id = m.series_id
+JOIN TOURNAMENTS tr ON tr.id = m.tournament_id
+LEFT JOIN TEAMS t1 ON t1.id = m.team1_id
+LEFT JOIN TEAMS t2 ON t2.id = m.team2_id
+WHERE NOT m.finished AND m.deleted_at IS NULL AND m.status IS NOT 'canceled';
+
+-- RANKED_LEAGUES_VIEW ranks the leagues of every game by the best tier of their
+-- tournaments, then by name, like GetLeaguesByGameID. Leagues without a tiered
+-- tournament rank last.
+CREATE VIEW IF NOT EXISTS RANKED_LEAGUES_VIEW AS
+SELECT
+    l.id,
+    l.name,
+    l.slug,
+    l.game_id,
+    l.image_link,
+    MIN(t.tier) AS best_tier,
+    COUNT(t.id) AS tournament_count,
+    ROW_NUMBER() OVER (PARTITION BY l.game_id ORDER BY MIN(t.tier) ASC NULLS LAST, l.name ASC, l.id ASC) AS game_rank
+FROM LEAGUES l
+LEFT JOIN TOURNAMENTS t ON l.id = t.league_id AND t.deleted_at IS NULL
+WHERE l.deleted_at IS NULL
+GROUP BY l.id, l.name, l.slug, l.game_id, l.image_link;
+
@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+)
+
+const (
+	// DispatchInterval is how often the outbox is delivered.
+	DispatchInterval = 30 * time.Second
+	// DeliveryBatch caps the events delivered to one subscriber per pass.
+	DeliveryBatch = 100
+	// DefaultMaxAttempts is how often an event is attempted before it is dead
+	// lettered, unless webhook_max_attempts says otherwise.
+	DefaultMaxAttempts = 10
+	// BaseBackoff is the wait after the first failed attempt. It doubles with
+	// every further attempt up to MaxBackoff.
+	BaseBackoff = 30 * time.Second
+	MaxBackoff  = time.Hour
+	// OutboxRetention is how long events that are no longer pending anywhere,
+	// including the dead lettered ones, are kept for inspection.
+	OutboxRetention = 30 * 24 * time.Hour
+)
+
+// Dispatcher delivers the events of OUTBOX to the webhook subscribers.
+type Dispatcher struct {
+	Queries     dbtypes.Querier
+	HTTPClient  *http.Client
+	Subscribers []Subscriber
+	// MaxAttempts is how often an event is attempted before it is dead lettered.
+	MaxAttempts int32
+	Logger      *zap.SugaredLogger
+}
+
+// LoadMaxAttempts reads webhook_max_attempts, DefaultMaxAttempts when unset.
+// @param getenv - looks up an environment variable, usually os.Getenv.
+// @returns the maximum number of attempts and an error if it is not a positive integer.
+func LoadMaxAttempts(getenv func(string) string) (int32, error) {
+	value := getenv("webhook_max_attempts")
+	if value == "" {
+		return DefaultMaxAttempts, nil
+	}
+	attempts, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || attempts < 1 {
+		return 0, fmt.Errorf("webhook_max_attempts must be a positive integer, got %q", value)
+	}
+	return int32(attempts), nil
+}
+
+// Register stores the configured subscribers in WEBHOOK_SUBSCRIBERS and
+// deletes the ones no longer configured together with their deliveries.
+// Events are only queued for registered subscribers, so a subscriber receives
+// the events written after it was first registered.
+// @returns an error if one of the writes fails.
+func (dispatcher *Dispatcher) Register(ctx context.Context) error {
+	names := make([]string, 0, len(dispatcher.Subscribers))
+	for _, subscriber := range dispatcher.Subscribers {
+		err := dispatcher.Queries.UpsertWebhookSubscriber(ctx, dbtypes.UpsertWebhookSubscriberParams{
+			Name: subscriber.Name,
+			URL:  subscriber.URL,
+		})
+		if err != nil {
+			return err
+		}
+		names = append(names, subscriber.Name)
+	}
+	return dispatcher.Queries.DeleteWebhookSubscribersExcept(ctx, names)
+}
+
+// Dispatch delivers at most DeliveryBatch due events to every subscriber, in
+// the order they were written. The first failure of a subscriber ends its
+// pass, so an unreachable endpoint neither uses up the attempts of every queued
+// event nor holds up the other subscribers for long.
+// @param now - the time of the pass.
+// @returns the number of delivered events and an error if the outbox could not be read or updated.
+func (dispatcher *Dispatcher) Dispatch(ctx context.Context, now time.Time) (int, error) {
+	delivered := 0
+	for _, subscriber := range dispatcher.Subscribers {
+		rows, err := dispatcher.Queries.GetDueWebhookDeliveries(ctx, dbtypes.GetDueWebhookDeliveriesParams{
+			Subscriber: subscriber.Name,
+			Now:        pgtype.Timestamptz{Time: now, Valid: true, InfinityModifier: 0},
+			MaxRows:    DeliveryBatch,
+		})
+		if err != nil {
+			return delivered, err
+		}
+		for _, row := range rows {
+			deliverErr := dispatcher.deliver(ctx, subscriber, row, now)
+			if deliverErr == nil {
+				delivered++
+				err = dispatcher.Queries.MarkWebhookDelivered(ctx, dbtypes.MarkWebhookDeliveredParams{
+					Now:        pgtype.Timestamptz{Time: now, Valid: true, InfinityModifier: 0},
+					Subscriber: subscriber.Name,
+					EventID:    row.EventID,
+				})
+				if err != nil {
+					return delivered, err
+				}
+				continue
+			}
+			if err = dispatcher.recordFailure(ctx, subscriber, row, deliverErr, now); err != nil {
+				return delivered, err
+			}
+			break
+		}
+	}
+	return delivered, nil
+}
+
+// deliver POSTs one event to a subscriber.
+// @returns nil if the subscriber answered with a 2xx status.
+func (dispatcher *Dispatcher) deliver(
+	ctx context.Context,
+	subscriber Subscriber,
+	row dbtypes.GetDueWebhookDeliveriesRow,
+	now time.Time,
+) error {
+	body, err := json.Marshal(payloadFromRow(row))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscriber.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, row.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(row.EventID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(subscriber.Secret, now.Unix(), body))
+	resp, err := dispatcher.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection is reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// recordFailure schedules the next attempt of a failed delivery, or dead
+// letters it when it used up its attempts.
+func (dispatcher *Dispatcher) recordFailure(
+	ctx context.Context,
+	subscriber Subscriber,
+	row dbtypes.GetDueWebhookDeliveriesRow,
+	deliverErr error,
+	now time.Time,
+) error {
+	attempt := row.Attempts + 1
+	if attempt >= dispatcher.MaxAttempts {
+		dispatcher.Logger.Warnf("Dead lettering event %d for webhook %s after %d attempts: %v",
+			row.EventID, subscriber.Name, attempt, deliverErr)
+	} else {
+		dispatcher.Logger.Infof("Delivering event %d to webhook %s failed, attempt %d of %d: %v",
+			row.EventID, subscriber.Name, attempt, dispatcher.MaxAttempts, deliverErr)
+	}
+	return dispatcher.Queries.MarkWebhookDeliveryFailed(ctx, dbtypes.MarkWebhookDeliveryFailedParams{
+		LastError:     pgtype.Text{String: deliverErr.Error(), Valid: true},
+		Now:           pgtype.Timestamptz{Time: now, Valid: true, InfinityModifier: 0},
+		NextAttemptAt: pgtype.Timestamptz{Time: now.Add(Backoff(attempt)), Valid: true, InfinityModifier: 0},
+		MaxAttempts:   dispatcher.MaxAttempts,
+		Subscriber:    subscriber.Name,
+		EventID:       row.EventID,
+	})
+}
+
+// Backoff returns the wait after a failed attempt.
+// @param attempt - the number of the failed attempt, starting at 1.
+// @returns BaseBackoff doubled for every attempt after the first, at most MaxBackoff.
+func Backoff(attempt int32) time.Duration {
+	wait := BaseBackoff
+	for range attempt - 1 {
+		wait *= 2
+		if wait >= MaxBackoff {
+			return MaxBackoff
+		}
+	}
+	return wait
+}
+
+// Prune deletes the events older than OutboxRetention that are no longer
+// pending for any subscriber.
+// @param now - the time of the run.
+// @returns the number of deleted events and an error if the delete fails.
+func (dispatcher *Dispatcher) Prune(ctx context.Context, now time.Time) (int64, error) {
+	return dispatcher.Queries.DeleteOldOutboxEvents(ctx, pgtype.Timestamptz{
+		Time:             now.Add(-OutboxRetention),
+		Valid:            true,
+		InfinityModifier: 0,
+	})
+}
+
+// WriteStatus prints the delivery status of every subscriber as a table.
+// @param w - where to print.
+// @param rows - the rows of GetWebhookDeliveryStatus.
+// @returns an error if writing fails.
+func WriteStatus(w io.Writer, rows []dbtypes.GetWebhookDeliveryStatusRow) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "SUBSCRIBER\tURL\tPENDING\tDELIVERED\tDEAD\tLAST DELIVERED\tLAST ERROR")
+	for _, row := range rows {
+		lastDelivered := "-"
+		if row.LastDeliveredAt.Valid {
+			lastDelivered = row.LastDeliveredAt.Time.UTC().Format(time.RFC3339)
+		}
+		lastError := "-"
+		if row.LastError.Valid {
+			lastError = row.LastError.String
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+			row.Name, row.URL, row.Pending, row.Delivered, row.Dead, lastDelivered, lastError)
+	}
+	return table.Flush()
+}
@@ -0,0 +1,198 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/h2non/gock"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nbio/st"
+	"github.com/pashagolub/pgxmock/v4"
+	"go.uber.org/zap/zaptest"
+)
+
+var (
+	dispatchTime = time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+	dueColumns   = []string{
+		"event_id", "attempts", "event_type", "match_id", "data", "created_at",
+		"name", "status", "game_id", "league_id", "tournament_id", "team1_id", "team2_id",
+		"expected_start_time", "begin_at", "end_at", "winner_id", "stream_url",
+	}
+)
+
+func newDispatcher(t *testing.T) (*Dispatcher, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockDB, err := pgxmock.NewPool()
+	st.Assert(t, err, nil)
+	t.Cleanup(mockDB.Close)
+	httpClient := &http.Client{}
+	gock.InterceptClient(httpClient)
+	t.Cleanup(gock.Off)
+	return &Dispatcher{
+		Queries:     dbtypes.New(mockDB),
+		HTTPClient:  httpClient,
+		Subscribers: []Subscriber{{Name: "discord", URL: "https://bot.internal/hooks", Secret: []byte("secret")}},
+		MaxAttempts: 3,
+		Logger:      zaptest.NewLogger(t).Sugar(),
+	}, mockDB
+}
+
+// dueRow is a due event of a match that is still stored.
+func dueRow(rows *pgxmock.Rows, eventID int64, attempts int32, eventType string, data []byte) *pgxmock.Rows {
+	start := pgtype.Timestamptz{Time: dispatchTime.Add(time.Hour), Valid: true, InfinityModifier: 0}
+	return rows.AddRow(
+		eventID, attempts, eventType, int32(7), data,
+		pgtype.Timestamptz{Time: dispatchTime.Add(-time.Minute), Valid: true, InfinityModifier: 0},
+		pgtype.Text{String: "T1 vs Gen.G", Valid: true}, pgtype.Text{String: "not_started", Valid: true},
+		pgtype.Int4{Int32: 1, Valid: true}, pgtype.Int4{Int32: 2, Valid: true}, pgtype.Int4{Int32: 3, Valid: true},
+		pgtype.Int4{Int32: 101, Valid: true}, pgtype.Int4{Int32: 102, Valid: true},
+		start, pgtype.Timestamptz{}, pgtype.Timestamptz{}, pgtype.Int4{}, pgtype.Text{},
+	)
+}
+
+func TestRegister(t *testing.T) {
+	dispatcher, mockDB := newDispatcher(t)
+	mockDB.ExpectExec("INSERT INTO webhook_subscribers").
+		WithArgs("discord", "https://bot.internal/hooks").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockDB.ExpectExec("DELETE FROM webhook_subscribers").
+		WithArgs([]string{"discord"}).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	st.Expect(t, dispatcher.Register(t.Context()), nil)
+	st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+}
+
+func TestDispatch(t *testing.T) {
+	t.Run("Events are signed and marked delivered", func(t *testing.T) {
+		dispatcher, mockDB := newDispatcher(t)
+		rows := dueRow(pgxmock.NewRows(dueColumns), 1, 0, "match_created", nil)
+		rows = dueRow(rows, 2, 0, "match_rescheduled", []byte(`{"from":null,"to":"2025-10-01T09:00:00Z"}`))
+		mockDB.ExpectQuery("SELECT .+ FROM webhook_deliveries").
+			WithArgs("discord", pgxmock.AnyArg(), int32(DeliveryBatch)).
+			WillReturnRows(rows)
+		var bodies [][]byte
+		for range 2 {
+			gock.New("https://bot.internal").
+				Post("/hooks").
+				MatchHeader(HeaderTimestamp, "1759305600").
+				AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+					body, err := io.ReadAll(req.Body)
+					req.Body = io.NopCloser(bytes.NewReader(body))
+					bodies = append(bodies, body)
+					valid := Verify([]byte("secret"), req.Header.Get(HeaderTimestamp), body, req.Header.Get(HeaderSignature))
+					return valid, err
+				}).
+				Reply(http.StatusNoContent)
+		}
+		for _, eventID := range []int64{1, 2} {
+			mockDB.ExpectExec("UPDATE webhook_deliveries SET status = 'delivered'").
+				WithArgs(pgxmock.AnyArg(), "discord", eventID).
+				WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		}
+
+		delivered, err := dispatcher.Dispatch(t.Context(), dispatchTime)
+		st.Expect(t, err, nil)
+		st.Expect(t, delivered, 2)
+		st.Expect(t, gock.IsDone(), true)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+
+		st.Assert(t, len(bodies), 2)
+		var payload Payload
+		st.Assert(t, json.Unmarshal(bodies[1], &payload), nil)
+		st.Expect(t, payload.ID, int64(2))
+		st.Expect(t, payload.Type, "match_rescheduled")
+		st.Expect(t, string(payload.Data), `{"from":null,"to":"2025-10-01T09:00:00Z"}`)
+		st.Assert(t, payload.Match != nil, true)
+		st.Expect(t, payload.Match.Name, "T1 vs Gen.G")
+		st.Expect(t, payload.Match.WinnerID, (*int32)(nil))
+	})
+
+	t.Run("A failure schedules a retry and ends the pass of the subscriber", func(t *testing.T) {
+		dispatcher, mockDB := newDispatcher(t)
+		rows := dueRow(pgxmock.NewRows(dueColumns), 1, 1, "match_live", nil)
+		rows = dueRow(rows, 2, 0, "match_finished", nil)
+		mockDB.ExpectQuery("SELECT .+ FROM webhook_deliveries").
+			WithArgs("discord", pgxmock.AnyArg(), int32(DeliveryBatch)).
+			WillReturnRows(rows)
+		gock.New("https://bot.internal").Post("/hooks").Reply(http.StatusBadGateway)
+		mockDB.ExpectExec("UPDATE webhook_deliveries").
+			WithArgs(
+				pgtype.Text{String: "status 502", Valid: true},
+				pgtype.Timestamptz{Time: dispatchTime, Valid: true, InfinityModifier: 0},
+				pgtype.Timestamptz{Time: dispatchTime.Add(Backoff(2)), Valid: true, InfinityModifier: 0},
+				int32(3),
+				"discord",
+				int64(1),
+			).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		delivered, err := dispatcher.Dispatch(t.Context(), dispatchTime)
+		st.Expect(t, err, nil)
+		st.Expect(t, delivered, 0)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("Error - the outbox cannot be read", func(t *testing.T) {
+		dispatcher, mockDB := newDispatcher(t)
+		mockDB.ExpectQuery("SELECT .+ FROM webhook_deliveries").
+			WithArgs("discord", pgxmock.AnyArg(), int32(DeliveryBatch)).
+			WillReturnError(errors.New("connection refused"))
+
+		_, err := dispatcher.Dispatch(t.Context(), dispatchTime)
+		st.Reject(t, err, nil)
+		st.Expect(t, mockDB.ExpectationsWereMet(), nil)
+	})
+}
+
+func TestPayloadOfArchivedMatch(t *testing.T) {
+	payload := payloadFromRow(dbtypes.GetDueWebhookDeliveriesRow{
+		EventID:           3,
+		Attempts:          0,
+		EventType:         "match_finished",
+		MatchID:           7,
+		Data:              nil,
+		CreatedAt:         pgtype.Timestamptz{Time: dispatchTime, Valid: true, InfinityModifier: 0},
+		Name:              pgtype.Text{},
+		Status:            pgtype.Text{},
+		GameID:            pgtype.Int4{},
+		LeagueID:          pgtype.Int4{},
+		TournamentID:      pgtype.Int4{},
+		Team1ID:           pgtype.Int4{},
+		Team2ID:           pgtype.Int4{},
+		ExpectedStartTime: pgtype.Timestamptz{},
+		BeginAt:           pgtype.Timestamptz{},
+		EndAt:             pgtype.Timestamptz{},
+		WinnerID:          pgtype.Int4{},
+		StreamURL:         pgtype.Text{},
+	})
+	body, err := json.Marshal(payload)
+	st.Assert(t, err, nil)
+	st.Expect(t, string(body), `{"id":3,"type":"match_finished","created_at":"2025-10-01T08:00:00Z","match_id":7,"match":null}`)
+}
+
+func TestWriteStatus(t *testing.T) {
+	var out strings.Builder
+	err := WriteStatus(&out, []dbtypes.GetWebhookDeliveryStatusRow{{
+		Name:            "discord",
+		URL:             "https://bot.internal/hooks",
+		Pending:         2,
+		Delivered:       40,
+		Dead:            1,
+		LastDeliveredAt: pgtype.Timestamptz{Time: dispatchTime, Valid: true, InfinityModifier: 0},
+		LastError:       pgtype.Text{String: "status 502", Valid: true},
+	}})
+	st.Expect(t, err, nil)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	st.Assert(t, len(lines), 2)
+	st.Expect(t, strings.Fields(lines[1]), []string{
+		"discord", "https://bot.internal/hooks", "2", "40", "1", "2025-10-01T08:00:00Z", "status", "502",
+	})
+}
@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/feimaomiao/stalka/dbtypes"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Headers of every delivery. Subscribers verify HeaderSignature with Verify.
+const (
+	HeaderEvent     = "X-Stalka-Event"
+	HeaderDelivery  = "X-Stalka-Delivery"
+	HeaderTimestamp = "X-Stalka-Timestamp"
+	HeaderSignature = "X-Stalka-Signature"
+)
+
+// signaturePrefix names the algorithm of a signature, like GitHub webhooks do.
+const signaturePrefix = "sha256="
+
+// subscriberName is what WEBHOOK_SUBSCRIBERS.name and the secret variables accept.
+var subscriberName = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+
+// Subscriber is a webhook endpoint that receives every outbox event.
+type Subscriber struct {
+	Name   string
+	URL    string
+	Secret []byte
+}
+
+// LoadSubscribers reads the subscribers from the environment: webhooks is a
+// comma separated list of name=url pairs. Every subscriber signs with
+// webhook_secret_<name>, or with webhook_secret when that is unset.
+// @param getenv - looks up an environment variable, usually os.Getenv.
+// @returns the subscribers, none when webhooks is unset, and an error naming
+// the first invalid entry or a subscriber without a secret.
+func LoadSubscribers(getenv func(string) string) ([]Subscriber, error) {
+	value := strings.TrimSpace(getenv("webhooks"))
+	if value == "" {
+		return nil, nil
+	}
+	var subscribers []Subscriber
+	seen := make(map[string]bool)
+	for entry := range strings.SplitSeq(value, ",") {
+		name, rawURL, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || !subscriberName.MatchString(name) {
+			return nil, fmt.Errorf("invalid webhook %q: expected name=url with a lowercase name", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate webhook %s", name)
+		}
+		seen[name] = true
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid URL of webhook %s", name)
+		}
+		secret := getenv("webhook_secret_" + name)
+		if secret == "" {
+			secret = getenv("webhook_secret")
+		}
+		if secret == "" {
+			return nil, fmt.Errorf("webhook %s has no secret, set webhook_secret_%s or webhook_secret", name, name)
+		}
+		subscribers = append(subscribers, Subscriber{Name: name, URL: rawURL, Secret: []byte(secret)})
+	}
+	return subscribers, nil
+}
+
+// Sign signs a payload for HeaderSignature. The timestamp is signed with the
+// body, so a captured delivery cannot be replayed later with a new timestamp.
+// @param secret - the secret of the subscriber.
+// @param timestamp - the Unix time of HeaderTimestamp.
+// @param body - the payload.
+// @returns "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>".
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery in constant time. Subscribers
+// should also reject timestamps that are too old.
+// @param secret - the secret of the subscriber.
+// @param timestamp - the value of HeaderTimestamp.
+// @param body - the payload as received.
+// @param signature - the value of HeaderSignature.
+// @returns true if the signature matches.
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, unix, body)), []byte(signature))
+}
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	// ID is the ID of the outbox event; a redelivered event keeps it, so
+	// subscribers can drop duplicates.
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	MatchID   int32           `json:"match_id"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	// Match is the match at the time of the delivery, nil once it was archived.
+	Match *Match `json:"match"`
+}
+
+// Match is the state of the match of an event.
+type Match struct {
+	ID                int32      `json:"id"`
+	Name              string     `json:"name"`
+	Status            *string    `json:"status"`
+	GameID            int32      `json:"game_id"`
+	LeagueID          int32      `json:"league_id"`
+	TournamentID      int32      `json:"tournament_id"`
+	Team1ID           int32      `json:"team1_id"`
+	Team2ID           int32      `json:"team2_id"`
+	ExpectedStartTime *time.Time `json:"expected_start_time"`
+	BeginAt           *time.Time `json:"begin_at"`
+	EndAt             *time.Time `json:"end_at"`
+	WinnerID          *int32     `json:"winner_id"`
+	StreamURL         *string    `json:"stream_url"`
+}
+
+// payloadFromRow converts a due delivery into its payload.
+func payloadFromRow(row dbtypes.GetDueWebhookDeliveriesRow) Payload {
+	payload := Payload{
+		ID:        row.EventID,
+		Type:      row.EventType,
+		CreatedAt: row.CreatedAt.Time.UTC(),
+		MatchID:   row.MatchID,
+		Data:      row.Data,
+		Match:     nil,
+	}
+	// The match columns come from a LEFT JOIN; its name is only NULL when the
+	// match is gone.
+	if row.Name.Valid {
+		payload.Match = &Match{
+			ID:                row.MatchID,
+			Name:              row.Name.String,
+			Status:            text(row.Status),
+			GameID:            row.GameID.Int32,
+			LeagueID:          row.LeagueID.Int32,
+			TournamentID:      row.TournamentID.Int32,
+			Team1ID:           row.Team1ID.Int32,
+			Team2ID:           row.Team2ID.Int32,
+			ExpectedStartTime: timestamp(row.ExpectedStartTime),
+			BeginAt:           timestamp(row.BeginAt),
+			EndAt:             timestamp(row.EndAt),
+			WinnerID:          int4(row.WinnerID),
+			StreamURL:         text(row.StreamURL),
+		}
+	}
+	return payload
+}
+
+func text(value pgtype.Text) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func int4(value pgtype.Int4) *int32 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Int32
+}
+
+func timestamp(value pgtype.Timestamptz) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	utc := value.Time.UTC()
+	return &utc
+}
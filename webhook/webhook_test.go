@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nbio/st"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestLoadSubscribers(t *testing.T) {
+	t.Run("No webhooks configured", func(t *testing.T) {
+		subscribers, err := LoadSubscribers(env(nil))
+		st.Expect(t, err, nil)
+		st.Expect(t, len(subscribers), 0)
+	})
+
+	t.Run("Secrets fall back to the shared one", func(t *testing.T) {
+		subscribers, err := LoadSubscribers(env(map[string]string{
+			"webhooks":               "discord=https://bot.internal/hooks, alerts=http://alerts:8080/stalka",
+			"webhook_secret":         "shared",
+			"webhook_secret_discord": "own",
+		}))
+		st.Expect(t, err, nil)
+		st.Assert(t, len(subscribers), 2)
+		st.Expect(t, subscribers[0], Subscriber{Name: "discord", URL: "https://bot.internal/hooks", Secret: []byte("own")})
+		st.Expect(t, subscribers[1].Secret, []byte("shared"))
+	})
+
+	for name, value := range map[string]string{
+		"Error - missing URL":    "discord",
+		"Error - invalid name":   "Discord Bot=https://bot.internal",
+		"Error - invalid URL":    "discord=ftp://bot.internal",
+		"Error - duplicate name": "discord=https://a.internal,discord=https://b.internal",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadSubscribers(env(map[string]string{"webhooks": value, "webhook_secret": "shared"}))
+			st.Reject(t, err, nil)
+		})
+	}
+
+	t.Run("Error - no secret", func(t *testing.T) {
+		_, err := LoadSubscribers(env(map[string]string{"webhooks": "discord=https://bot.internal"}))
+		st.Reject(t, err, nil)
+	})
+}
+
+func TestLoadMaxAttempts(t *testing.T) {
+	attempts, err := LoadMaxAttempts(env(nil))
+	st.Expect(t, err, nil)
+	st.Expect(t, attempts, int32(DefaultMaxAttempts))
+
+	attempts, err = LoadMaxAttempts(env(map[string]string{"webhook_max_attempts": "3"}))
+	st.Expect(t, err, nil)
+	st.Expect(t, attempts, int32(3))
+
+	_, err = LoadMaxAttempts(env(map[string]string{"webhook_max_attempts": "0"}))
+	st.Reject(t, err, nil)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"id":1}`)
+	now := time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC).Unix()
+	signature := Sign(secret, now, body)
+	// echo -n '1759305600.{"id":1}' | openssl dgst -sha256 -hmac secret
+	st.Expect(t, signature, "sha256=ebd6c6f8dc6c6448c0f5eb2f2a6d82c3d15ddc9651ce63784750c5600f228c97")
+
+	st.Expect(t, Verify(secret, strconv.FormatInt(now, 10), body, signature), true)
+	st.Expect(t, Verify(secret, strconv.FormatInt(now+1, 10), body, signature), false)
+	st.Expect(t, Verify([]byte("other"), strconv.FormatInt(now, 10), body, signature), false)
+	st.Expect(t, Verify(secret, "yesterday", body, signature), false)
+}
+
+func TestBackoff(t *testing.T) {
+	st.Expect(t, Backoff(1), BaseBackoff)
+	st.Expect(t, Backoff(2), 2*BaseBackoff)
+	st.Expect(t, Backoff(4), 8*BaseBackoff)
+	st.Expect(t, Backoff(20), MaxBackoff)
+}